@@ -0,0 +1,319 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Arenas that have grown past this many objects are not returned to the pool,
+// so that one huge batch does not pin its memory forever.
+const maxArenaObjects = 4096
+
+var arenaPool = sync.Pool{
+	New: func() interface{} {
+		a := &arena{}
+		a.encoder = json.NewEncoder(&a.buffer)
+		a.scope.arena = a
+		return a
+	},
+}
+
+// arena holds every object that is decoded or created while handling one
+// payload. When the payload is finished the objects are cleared and kept for
+// the next payload rather than being left for the garbage collector.
+//
+// Methods on a nil arena allocate from the heap as normal.
+type arena struct {
+	raw       []json.RawMessage
+	fields    map[string]interface{}
+	requests  []request
+	responses []response
+	errors    []errorResponse
+	out       Responses
+
+	nRequests, nResponses, nErrors int
+
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+
+	// The requests of the payload have ctx, which holds scope, so that the
+	// arena (and the memory reserved for the payload, which reserved gives
+	// back) is only released once every handler has returned.
+	ctx      context.Context
+	scope    payloadScope
+	reserved func()
+
+	// shared is set by share once a handler may allocate requests or
+	// responses from another goroutine, after which responses are allocated
+	// under the mutex and requests from the heap.
+	shared bool
+	mutex  sync.Mutex
+}
+
+// share is called before a handler of the payload is run in another
+// goroutine (see runWithin), which may outlive the handling of the payload,
+// and before the members of a batch are handled concurrently.
+func (a *arena) share() {
+	// Only the goroutine handling the payload writes shared, and only before
+	// it starts another one.
+	if !a.shared {
+		a.shared = true
+	}
+}
+
+func (a *arena) newRequest(version string, id interface{}, method string,
+	params interface{}, state State) *request {
+	if a == nil {
+		return NewRequestResponderWithState(version, id, method, params,
+			state).(*request)
+	}
+
+	// Requests are written to after they are created, so once the arena is
+	// shared they cannot be in a slice that may be copied while it grows.
+	var r *request
+	if a.shared {
+		r = &request{}
+	} else {
+		if a.nRequests == len(a.requests) {
+			a.requests = append(a.requests, request{})
+		}
+
+		r = &a.requests[a.nRequests]
+		a.nRequests++
+	}
+
+	*r = request{
+		RequestVersion: version,
+		RequestID:      id,
+		RequestMethod:  method,
+		RequestParams:  params,
+		requestState:   state,
+		notification:   id == nil,
+		arena:          a,
+		ctx:            a.ctx,
+	}
+
+	return r
+}
+
+func (a *arena) newSuccessResponse(id interface{}, result interface{}) Response {
+	if a == nil {
+		return NewSuccessResponse(id, result)
+	}
+
+	if a.shared {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+	}
+
+	r := a.newResponse()
+	r.ResponseID = id
+	r.ResponseResult = result
+
+	return r
+}
+
+func (a *arena) newErrorResponse(id interface{}, code int, message string,
+	data interface{}) Response {
+	if a == nil {
+		return NewErrorResponseWithData(id, code, message, data)
+	}
+
+	if message == "" {
+		message = ErrorMessageForCode(code)
+	}
+
+	if a.shared {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+	}
+
+	if a.nErrors == len(a.errors) {
+		a.errors = append(a.errors, errorResponse{})
+	}
+
+	e := &a.errors[a.nErrors]
+	a.nErrors++
+	*e = errorResponse{Code: code, Message: message, Data: data}
+
+	r := a.newResponse()
+	r.ResponseID = id
+	r.ResponseError = e
+
+	return r
+}
+
+func (a *arena) newResponse() *response {
+	if a.nResponses == len(a.responses) {
+		a.responses = append(a.responses, response{})
+	}
+
+	r := &a.responses[a.nResponses]
+	a.nResponses++
+	*r = response{ResponseVersion: "2.0"}
+
+	return r
+}
+
+// decodeFields decodes a single request object into the reused map. Once the
+// arena is shared each request gets its own map.
+func (a *arena) decodeFields(data []byte) (map[string]interface{}, error) {
+	if a.shared {
+		var fields map[string]interface{}
+		err := json.Unmarshal(data, &fields)
+
+		return fields, err
+	}
+
+	if a.fields == nil {
+		a.fields = map[string]interface{}{}
+	}
+
+	for key := range a.fields {
+		delete(a.fields, key)
+	}
+
+	fields := a.fields
+	err := json.Unmarshal(data, &fields)
+
+	return fields, err
+}
+
+// release gives back the memory reserved for the payload, clears every object
+// so that nothing from the payload is kept alive, and returns the arena to the
+// pool.
+func (a *arena) release() {
+	if a.reserved != nil {
+		a.reserved()
+	}
+
+	for i := 0; i < a.nRequests; i++ {
+		a.requests[i] = request{}
+	}
+	for i := 0; i < a.nResponses; i++ {
+		a.responses[i] = response{}
+	}
+	for i := 0; i < a.nErrors; i++ {
+		a.errors[i] = errorResponse{}
+	}
+	for i := range a.out {
+		a.out[i] = nil
+	}
+
+	a.nRequests, a.nResponses, a.nErrors = 0, 0, 0
+	a.ctx, a.reserved, a.shared = nil, nil, false
+	a.scope.finished = false
+	a.raw = a.raw[:0]
+	a.out = a.out[:0]
+	a.buffer.Reset()
+
+	if len(a.requests) > maxArenaObjects || len(a.responses) > maxArenaObjects ||
+		cap(a.raw) > maxArenaObjects {
+		return
+	}
+
+	arenaPool.Put(a)
+}
+
+// HandleArena is an EXPERIMENTAL alternative to HandleWithState for servers
+// that handle thousands of batches per second. The requests and responses of
+// the payload are allocated from a reusable arena, the encoded response is
+// written to w and then the arena is released for the next payload.
+//
+// A single request is answered with a single response object and a batch with
+// an array, as the JSON-RPC specification requires. Nothing is written if
+// there are no responses (the payload only contained notifications). The
+// members of a batch are handled like in HandleWithState (see
+// SetBatchWorkers).
+//
+// Handlers must not keep a reference to the request, its state or the response
+// after they return, since the memory will be reused. Params and results are
+// not reused and are safe to keep. A handler that is still running after its
+// time limit (see Timeout and MethodLimits.MaxTime) has not returned, so the
+// arena is only reused once it has.
+func (server *SimpleServer) HandleArena(w io.Writer, jsonRequest []byte, state State) error {
+	atomic.AddUint64(&server.totalPayloads, 1)
+
+	accepted := server.requests.tryAdd()
+	if accepted {
+		defer server.requests.done()
+	}
+
+	a := arenaPool.Get().(*arena)
+	if a.scope.release == nil {
+		a.scope.release = a.release
+	}
+	defer a.scope.finish()
+
+	isBatch := false
+	response, release := server.checkRequestLimits(jsonRequest, state, a)
+	a.reserved = release
+	a.ctx = a.scope.context(state)
+
+	if response != nil {
+		a.out = append(a.out, response)
+	} else if err := json.Unmarshal(jsonRequest, &a.raw); err == nil {
+		isBatch = true
+
+		// See HandleWithState. The error is not sent as an array.
+		if len(a.raw) == 0 {
+			atomic.AddUint64(&server.totalErrorResponses, 1)
+
+			isBatch = false
+			a.out = append(a.out, server.respond(nil,
+				a.newErrorResponse(nil, InvalidRequest, "Batch is empty.", nil)))
+		}
+
+		server.mutex.RLock()
+		executor := BatchExecutor{
+			Workers:         server.batchWorkers,
+			OrderPreserving: server.batchOrderPreserving,
+		}
+		server.mutex.RUnlock()
+
+		// Members handled one after the other are already in order, so they
+		// do not need an assembler (or the memory it takes).
+		if executor.Workers <= 1 || len(a.raw) == 1 {
+			for _, rawRequest := range a.raw {
+				a.out = append(a.out, server.handleSingle(rawRequest, true, state, a, accepted)...)
+			}
+		} else {
+			a.share()
+
+			assembler := NewBatchAssembler(len(a.raw), executor.OrderPreserving)
+			executor.run(len(a.raw), func(i int) {
+				assembler.Add(i, server.handleSingle(a.raw[i], true, state, a, accepted)...)
+			})
+			a.out = append(a.out, assembler.Responses()...)
+		}
+	} else {
+		a.out = append(a.out, server.handleSingle(jsonRequest, false, state, a, accepted)...)
+	}
+
+	server.recordExchange(state, jsonRequest, a.out)
+
+	var err error
+	switch {
+	case len(a.out) == 0:
+		return nil
+
+	case isBatch:
+		err = a.encoder.Encode(a.out)
+
+	default:
+		err = a.encoder.Encode(a.out[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Encode always adds a newline, which is not part of the response.
+	_, err = w.Write(bytes.TrimSuffix(a.buffer.Bytes(), []byte("\n")))
+
+	return err
+}
@@ -0,0 +1,221 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func handleArena(server *jsonrpc.SimpleServer, payload string) string {
+	var buffer bytes.Buffer
+	if err := server.HandleArena(&buffer, []byte(payload), jsonrpc.State{}); err != nil {
+		panic(err)
+	}
+
+	return buffer.String()
+}
+
+func TestSimpleServer_HandleArena(t *testing.T) {
+	for _, test := range []struct {
+		name, payload, expected string
+	}{
+		{
+			"single",
+			`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			`{"jsonrpc":"2.0","id":1,"result":19}`,
+		},
+		{
+			"batch",
+			`[
+				{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+				{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+				{"jsonrpc": "2.0", "method": "foo.get", "id": "5"},
+				1
+			]`,
+			`[{"jsonrpc":"2.0","id":"1","result":7},` +
+				`{"jsonrpc":"2.0","id":"5","error":{"code":-32601,"message":"Method not found"}},` +
+				`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid request"}}]`,
+		},
+		{
+			"notifications",
+			`[{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}]`,
+			``,
+		},
+		{
+			"empty batch",
+			`[]`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Batch is empty."}}`,
+		},
+		{
+			"panic",
+			`{"jsonrpc": "2.0", "method": "panic", "id": 2}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"Internal error"}}`,
+		},
+		{
+			"invalid version",
+			`{"jsonrpc": "1.0", "method": "sum", "id": 3}`,
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32600,"message":"Version is not 2.0."}}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer()
+			assert.Equal(t, test.expected, handleArena(server, test.payload))
+
+			// The arena must not change what is sent.
+			if test.expected != "" && test.expected[0] == '[' {
+				expected := server.Handle([]byte(test.payload))
+				assert.JSONEq(t, expected.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestSimpleServer_HandleArenaStats(t *testing.T) {
+	server := newTestServer()
+	handleArena(server, `[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+		{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+		{"jsonrpc": "2.0", "method": "foo.get", "id": "5"}
+	]`)
+
+	assert.Equal(t, uint64(1), server.TotalPayloads())
+	assert.Equal(t, uint64(2), server.TotalRequests())
+	assert.Equal(t, uint64(1), server.TotalSuccessResponses())
+	assert.Equal(t, uint64(1), server.TotalErrorResponses())
+	assert.Equal(t, uint64(1), server.TotalNotificationSuccesses())
+}
+
+func TestSimpleServer_HandleArenaReuse(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("params", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params())
+	})
+
+	// A smaller payload after a larger one must not see any of its values.
+	handleArena(server, `[
+		{"jsonrpc": "2.0", "method": "params", "params": {"a": 1}, "id": 1},
+		{"jsonrpc": "2.0", "method": "params", "params": {"b": 2}, "id": 2}
+	]`)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":3,"result":{"c":3}}`, handleArena(server,
+		`{"jsonrpc": "2.0", "method": "params", "params": {"c": 3}, "id": 3}`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":4}`, handleArena(server,
+		`{"jsonrpc": "2.0", "method": "params", "id": 4}`))
+}
+
+func TestSimpleServer_HandleArenaConcurrent(t *testing.T) {
+	server := newTestServer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations; j++ {
+				var responses []map[string]interface{}
+				output := handleArena(server, `[
+					{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+					{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "2"}
+				]`)
+				if !assert.NoError(t, json.Unmarshal([]byte(output), &responses)) {
+					return
+				}
+				assert.Equal(t, 7.0, responses[0]["result"])
+				assert.Equal(t, 19.0, responses[1]["result"])
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSimpleServer_HandleArenaBatchWorkers(t *testing.T) {
+	server := newTestServer()
+	counter := &concurrencyCounter{}
+	server.SetHandler("count", counter.handler)
+	server.SetBatchWorkers(4)
+	server.SetBatchOrderPreserving(true)
+
+	var responses []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(handleArena(server, batchOfTen)), &responses))
+
+	results := []interface{}{}
+	for _, response := range responses {
+		results = append(results, response["result"])
+	}
+	assert.Equal(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}, results)
+	assert.True(t, counter.most > 1 && counter.most <= 4, "most: %d", counter.most)
+
+	// The arena is reused for a payload that is not shared.
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}`, handleArena(server,
+		`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`))
+}
+
+func TestSimpleServer_HandleArenaAllocations(t *testing.T) {
+	server := newTestServer()
+	payload := []byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+		{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+		{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "2"}
+	]`)
+
+	heap := testing.AllocsPerRun(100, func() {
+		ioutil.Discard.Write(server.Handle(payload).Bytes())
+	})
+	arena := testing.AllocsPerRun(100, func() {
+		server.HandleArena(ioutil.Discard, payload, nil)
+	})
+
+	assert.True(t, arena < heap/2, "arena: %.1f, heap: %.1f", arena, heap)
+}
+
+func TestSimpleServer_HandleArenaTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestServer()
+	server.SetHandler("slow", jsonrpc.Timeout(time.Millisecond)(
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			<-release
+
+			// The request is still in use, and the response is allocated,
+			// after the time limit.
+			return request.NewSuccessResponse(request.Params())
+		}))
+
+	for i := 0; i < 10; i++ {
+		var responses []map[string]interface{}
+		output := handleArena(server, `[
+			{"jsonrpc": "2.0", "method": "slow", "params": [1], "id": "1"},
+			{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "2"}
+		]`)
+		assert.NoError(t, json.Unmarshal([]byte(output), &responses))
+		assert.Equal(t, float64(jsonrpc.TimeLimitExceeded),
+			responses[0]["error"].(map[string]interface{})["code"])
+		assert.Equal(t, 19.0, responses[1]["result"])
+	}
+
+	// The abandoned handlers finish while other payloads are handled.
+	close(release)
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations; j++ {
+				assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}`, handleArena(server,
+					`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`))
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.NoError(t, server.Wait(context.Background()))
+}
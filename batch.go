@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"sync"
+)
+
+// BatchAssembler collects the responses to the members of a batch as they are
+// handled, possibly out of order. By default the responses are in the order
+// they were added, which is allowed by JSON-RPC 2.0 as clients match them by
+// id. An order preserving assembler keeps them in the order of the members
+// instead, as some strict clients require:
+//
+//     assembler := jsonrpc.NewBatchAssembler(len(requests), true)
+//     for i, request := range requests {
+//         go func(i int, request jsonrpc.RequestResponder) {
+//             assembler.Add(i, handler(request))
+//         }(i, request)
+//     }
+//
+// It is safe for concurrent use.
+type BatchAssembler struct {
+	orderPreserving bool
+
+	mutex     sync.Mutex
+	members   []Responses
+	responses Responses
+}
+
+// NewBatchAssembler creates a BatchAssembler for a batch of size members.
+func NewBatchAssembler(size int, orderPreserving bool) *BatchAssembler {
+	assembler := &BatchAssembler{orderPreserving: orderPreserving}
+	if orderPreserving {
+		assembler.members = make([]Responses, size)
+	} else {
+		assembler.responses = make(Responses, 0, size)
+	}
+
+	return assembler
+}
+
+// Add records the responses to the member at index. Nil responses are
+// ignored. A member may be added more than once, and its responses are kept
+// together.
+func (assembler *BatchAssembler) Add(index int, responses ...Response) {
+	assembler.mutex.Lock()
+	defer assembler.mutex.Unlock()
+
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+
+		if assembler.orderPreserving {
+			assembler.members[index] = append(assembler.members[index], response)
+		} else {
+			assembler.responses = append(assembler.responses, response)
+		}
+	}
+}
+
+// Responses returns the responses that have been added.
+func (assembler *BatchAssembler) Responses() Responses {
+	assembler.mutex.Lock()
+	defer assembler.mutex.Unlock()
+
+	if !assembler.orderPreserving {
+		return append(Responses{}, assembler.responses...)
+	}
+
+	responses := Responses{}
+	for _, member := range assembler.members {
+		responses = append(responses, member...)
+	}
+
+	return responses
+}
+
+// BatchExecutor handles the members of a batch concurrently, with at most
+// Workers of them running at the same time:
+//
+//     requests, err := jsonrpc.NewRequestsFromJSON(data)
+//     if err != nil {
+//         return err
+//     }
+//
+//     executor := jsonrpc.BatchExecutor{Workers: 8, OrderPreserving: true}
+//     responses := executor.Execute(requests, server.GetHandler("sayHello"))
+//
+// The handlers must be safe to call from several goroutines at once. See also
+// SimpleServer.SetBatchWorkers.
+type BatchExecutor struct {
+	// Workers is the maximum number of members that are handled at once. Zero
+	// (or one) handles them one after the other.
+	Workers int
+
+	// OrderPreserving returns the responses in the order of the requests.
+	// Otherwise they are in the order the handlers finish in, which is only
+	// different when there are several Workers. See BatchAssembler.
+	OrderPreserving bool
+}
+
+// Execute calls handler for every request and returns the responses.
+// Notifications, and handlers that return nil, do not produce a response.
+func (executor BatchExecutor) Execute(requests []RequestResponder,
+	handler RequestHandler) Responses {
+	assembler := NewBatchAssembler(len(requests), executor.OrderPreserving)
+	executor.run(len(requests), func(i int) {
+		response := handler(requests[i])
+		if !requests[i].IsNotification() {
+			assembler.Add(i, response)
+		}
+	})
+
+	return assembler.Responses()
+}
+
+// run calls fn for each index from 0 to n-1 and returns when every call has
+// finished.
+func (executor BatchExecutor) run(n int, fn func(i int)) {
+	workers := executor.Workers
+	if workers > n {
+		workers = n
+	}
+
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// SetBatchWorkers sets how many members of a batch sent to Handle,
+// HandleWithState or HandleArena may be handled at the same time. Zero (the
+// default) handles them one after the other.
+//
+// The responses are in the order the members finish in, unless
+// SetBatchOrderPreserving is used.
+func (server *SimpleServer) SetBatchWorkers(workers int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.batchWorkers = workers
+}
+
+// SetBatchOrderPreserving controls if the responses to a batch are in the
+// order of its members, even when they are handled concurrently and finish out
+// of order. This is off by default, as JSON-RPC 2.0 clients must match the
+// responses by id, but some strict clients require it.
+func (server *SimpleServer) SetBatchOrderPreserving(orderPreserving bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.batchOrderPreserving = orderPreserving
+}
@@ -0,0 +1,157 @@
+package jsonrpc_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// concurrencyCounter records the most handlers that were running at once. The
+// handler finishes sooner for larger params, so that they finish out of order.
+type concurrencyCounter struct {
+	running, most int32
+}
+
+func (counter *concurrencyCounter) handler(request jsonrpc.RequestResponder) jsonrpc.Response {
+	running := atomic.AddInt32(&counter.running, 1)
+	defer atomic.AddInt32(&counter.running, -1)
+
+	for {
+		most := atomic.LoadInt32(&counter.most)
+		if running <= most || atomic.CompareAndSwapInt32(&counter.most, most, running) {
+			break
+		}
+	}
+
+	n := request.Params().([]interface{})[0].(float64)
+	time.Sleep(time.Duration(10-n) * 2 * time.Millisecond)
+
+	return request.NewSuccessResponse(n)
+}
+
+const batchOfTen = `[
+	{"jsonrpc":"2.0","method":"count","params":[0],"id":0},
+	{"jsonrpc":"2.0","method":"count","params":[1],"id":1},
+	{"jsonrpc":"2.0","method":"count","params":[2]},
+	{"jsonrpc":"2.0","method":"count","params":[3],"id":3},
+	{"jsonrpc":"2.0","method":"count","params":[4],"id":4},
+	{"jsonrpc":"2.0","method":"count","params":[5],"id":5},
+	{"jsonrpc":"2.0","method":"count","params":[6],"id":6},
+	{"jsonrpc":"2.0","method":"count","params":[7],"id":7},
+	{"jsonrpc":"2.0","method":"count","params":[8],"id":8},
+	{"jsonrpc":"2.0","method":"count","params":[9],"id":9}
+]`
+
+func resultsOf(responses jsonrpc.Responses) []interface{} {
+	results := []interface{}{}
+	for _, response := range responses {
+		results = append(results, response.Result())
+	}
+
+	return results
+}
+
+func TestBatchExecutor_Execute(t *testing.T) {
+	requests, err := jsonrpc.NewRequestsFromJSON([]byte(batchOfTen))
+	assert.NoError(t, err)
+
+	for workers, most := range map[int]int32{0: 1, 1: 1, 3: 3, 20: 10} {
+		counter := &concurrencyCounter{}
+		executor := jsonrpc.BatchExecutor{Workers: workers, OrderPreserving: true}
+		responses := executor.Execute(requests, counter.handler)
+
+		assert.Equal(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0},
+			resultsOf(responses), "workers: %d", workers)
+		if workers <= 1 {
+			assert.Equal(t, most, counter.most, "workers: %d", workers)
+		} else {
+			assert.True(t, counter.most > 1 && counter.most <= most,
+				"workers: %d, most: %d", workers, counter.most)
+		}
+	}
+}
+
+func TestBatchExecutor_CompletionOrder(t *testing.T) {
+	requests, err := jsonrpc.NewRequestsFromJSON([]byte(batchOfTen))
+	assert.NoError(t, err)
+
+	// The last members finish first.
+	executor := jsonrpc.BatchExecutor{Workers: 10}
+	responses := executor.Execute(requests, (&concurrencyCounter{}).handler)
+
+	results := resultsOf(responses)
+	assert.ElementsMatch(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}, results)
+	assert.NotEqual(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0}, results)
+}
+
+func TestBatchAssembler(t *testing.T) {
+	response := func(id int) jsonrpc.Response {
+		return jsonrpc.NewSuccessResponse(id, id)
+	}
+
+	ordered := jsonrpc.NewBatchAssembler(4, true)
+	unordered := jsonrpc.NewBatchAssembler(4, false)
+	for _, assembler := range []*jsonrpc.BatchAssembler{ordered, unordered} {
+		assembler.Add(3, response(3))
+		assembler.Add(0, response(0), nil)
+		assembler.Add(2)
+		assembler.Add(1, response(1))
+		assembler.Add(0, response(10))
+	}
+
+	assert.Equal(t, jsonrpc.Responses{response(0), response(10), response(1), response(3)},
+		ordered.Responses())
+	assert.Equal(t, jsonrpc.Responses{response(3), response(0), response(1), response(10)},
+		unordered.Responses())
+	assert.Equal(t, jsonrpc.Responses{}, jsonrpc.NewBatchAssembler(2, true).Responses())
+}
+
+func TestBatchExecutor_NilResponse(t *testing.T) {
+	requests, err := jsonrpc.NewRequestsFromJSON([]byte(`[
+		{"jsonrpc":"2.0","method":"a","id":1},
+		{"jsonrpc":"2.0","method":"b","id":2}
+	]`))
+	assert.NoError(t, err)
+
+	executor := jsonrpc.BatchExecutor{Workers: 2}
+	responses := executor.Execute(requests, func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		if request.Method() == "a" {
+			return nil
+		}
+
+		return request.NewSuccessResponse(request.Method())
+	})
+
+	assert.Equal(t, []interface{}{"b"}, resultsOf(responses))
+}
+
+func TestSimpleServer_SetBatchWorkers(t *testing.T) {
+	server := newTestServer()
+	counter := &concurrencyCounter{}
+	server.SetHandler("count", counter.handler)
+	server.SetBatchWorkers(4)
+
+	responses := server.Handle([]byte(batchOfTen))
+	assert.ElementsMatch(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0},
+		resultsOf(responses))
+	assert.True(t, counter.most > 1 && counter.most <= 4, "most: %d", counter.most)
+
+	server.SetBatchOrderPreserving(true)
+	responses = server.Handle([]byte(batchOfTen))
+	assert.Equal(t, []interface{}{0.0, 1.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0},
+		resultsOf(responses))
+
+	// Invalid members are still answered in order.
+	responses = server.Handle([]byte(`[
+		{"jsonrpc":"2.0","method":"count","params":[9],"id":1},
+		{"jsonrpc":"2.0","method":1,"id":2},
+		{"jsonrpc":"2.0","method":"count","params":[0],"id":3}
+	]`))
+	assert.Len(t, responses, 3)
+	assert.Equal(t, 9.0, responses[0].Result())
+	assert.Equal(t, jsonrpc.InvalidRequest, responses[1].ErrorCode())
+	assert.Equal(t, 0.0, responses[2].Result())
+}
@@ -0,0 +1,324 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// BatchCall is a member of a batch sent by a client. See BatchInvoker.
+type BatchCall struct {
+	Method string
+	Params interface{}
+
+	// Notification sends the call without an id, so it does not have a
+	// response.
+	Notification bool
+}
+
+// BatchInvoker is an Invoker that can send several calls as a single batch.
+//
+// InvokeBatch returns a response for each call, in the order of the calls.
+// The response of a notification is nil. As with Invoke, an error is only
+// returned when the responses could not be obtained.
+type BatchInvoker interface {
+	Invoker
+	InvokeBatch(ctx context.Context, calls []BatchCall) ([]Response, error)
+}
+
+// InvokeBatch sends the calls as a single batch and waits for all of their
+// responses, for ctx to be done or for the connection to be closed.
+func (client *TCPClient) InvokeBatch(ctx context.Context,
+	calls []BatchCall) ([]Response, error) {
+	messages := make([]map[string]interface{}, len(calls))
+	keys := make([]string, len(calls))
+	channels := make([]chan Response, len(calls))
+
+	client.mutex.Lock()
+	for i, call := range calls {
+		var id interface{}
+		if !call.Notification {
+			id = client.ids.NextID()
+			keys[i] = idKey(id)
+			channels[i] = make(chan Response, 1)
+			client.pending[keys[i]] = channels[i]
+		}
+
+		messages[i] = requestMessage(ctx, call.Method, call.Params, id)
+	}
+	client.mutex.Unlock()
+
+	defer func() {
+		client.mutex.Lock()
+		for i, key := range keys {
+			if channels[i] != nil {
+				delete(client.pending, key)
+			}
+		}
+		client.mutex.Unlock()
+	}()
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.write(data); err != nil {
+		return nil, err
+	}
+
+	responses := make([]Response, len(calls))
+	for i, channel := range channels {
+		if channel == nil {
+			continue
+		}
+
+		select {
+		case responses[i] = <-channel:
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-client.done:
+			return nil, client.err
+		}
+	}
+
+	return responses, nil
+}
+
+// BatchClient sends batches to a peer that may not support them, such as a
+// JSON-RPC 1.0 server. When the peer does not support batches (or the invoker
+// cannot send them) the batch is split into single calls, and their responses
+// are put back together in the order of the calls:
+//
+//     negotiated, err := jsonrpc.ClientHandshake(reader, conn, jsonrpc.Capabilities{
+//         Batch: true,
+//     })
+//     if err != nil {
+//         return err
+//     }
+//
+//     client := jsonrpc.NewBatchClient(tcpClient, negotiated.Batch, 4)
+//     responses, err := client.InvokeBatch(ctx, []jsonrpc.BatchCall{
+//         {Method: "user.get", Params: []int{1}},
+//         {Method: "user.get", Params: []int{2}},
+//     })
+//
+// Split calls are sent one after the other, or up to workers of them at once.
+// Split notifications are sent with Notify if the invoker has it, otherwise
+// they are sent as calls and the response is discarded. It is safe for
+// concurrent use.
+type BatchClient struct {
+	invoker Invoker
+	workers int
+	batch   int32
+}
+
+// NewBatchClient creates a BatchClient. batch is true if the peer supports
+// batches, see Negotiated.Batch.
+func NewBatchClient(invoker Invoker, batch bool, workers int) *BatchClient {
+	client := &BatchClient{invoker: invoker, workers: workers}
+	client.SetBatch(batch)
+
+	return client
+}
+
+// SetBatch changes whether the peer supports batches, such as after the
+// client has reconnected to a different peer.
+func (client *BatchClient) SetBatch(batch bool) {
+	var value int32
+	if batch {
+		value = 1
+	}
+
+	atomic.StoreInt32(&client.batch, value)
+}
+
+// Invoke sends a single call with the invoker.
+func (client *BatchClient) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	return client.invoker.Invoke(ctx, method, params)
+}
+
+// InvokeBatch sends the calls as a batch, or as single calls if the peer does
+// not support batches. If a single call fails the calls that have not been
+// sent yet are cancelled and its error is returned.
+func (client *BatchClient) InvokeBatch(ctx context.Context,
+	calls []BatchCall) ([]Response, error) {
+	if batchInvoker, ok := client.invoker.(BatchInvoker); ok &&
+		atomic.LoadInt32(&client.batch) == 1 {
+		return batchInvoker.InvokeBatch(ctx, calls)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	notifier, _ := client.invoker.(interface {
+		Notify(ctx context.Context, method string, params interface{}) error
+	})
+
+	var mutex sync.Mutex
+	var firstErr error
+	responses := make([]Response, len(calls))
+	BatchExecutor{Workers: client.workers}.run(len(calls), func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var response Response
+		var err error
+		call := calls[i]
+		switch {
+		case call.Notification && notifier != nil:
+			err = notifier.Notify(ctx, call.Method, call.Params)
+
+		default:
+			response, err = client.invoker.Invoke(ctx, call.Method, call.Params)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+
+			return
+		}
+
+		if !call.Notification {
+			responses[i] = response
+		}
+	})
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
+
+// Batch queues calls and notifications so they can be sent together, as a
+// single JSON array, with any BatchInvoker (such as a Client):
+//
+//     batch := client.NewBatch()
+//     user := batch.Call("user.get", []int{1})
+//     batch.Notify("audit.log", "viewed user 1")
+//
+//     if err := batch.Send(ctx); err != nil {
+//         return err
+//     }
+//
+//     var name string
+//     if err := user.Decode(&name); err != nil {
+//         return err
+//     }
+//
+// Each call has its own result, which can be read once the batch has been
+// sent. A Batch is sent once and is not safe for concurrent use.
+type Batch struct {
+	invoker BatchInvoker
+	calls   []BatchCall
+	results []*BatchResult
+	sent    bool
+}
+
+// BatchResult is the outcome of a call in a Batch.
+type BatchResult struct {
+	response Response
+	sent     bool
+}
+
+// NewBatch creates an empty Batch that is sent with invoker.
+func NewBatch(invoker BatchInvoker) *Batch {
+	return &Batch{invoker: invoker}
+}
+
+// NewBatch creates an empty Batch that is sent with the client.
+func (client *Client) NewBatch() *Batch {
+	return NewBatch(client)
+}
+
+// Call queues a call and returns its result, which is filled in by Send.
+func (batch *Batch) Call(method string, params interface{}) *BatchResult {
+	result := &BatchResult{}
+	batch.calls = append(batch.calls, BatchCall{Method: method, Params: params})
+	batch.results = append(batch.results, result)
+
+	return result
+}
+
+// Notify queues a notification.
+func (batch *Batch) Notify(method string, params interface{}) {
+	batch.calls = append(batch.calls,
+		BatchCall{Method: method, Params: params, Notification: true})
+	batch.results = append(batch.results, nil)
+}
+
+// Len returns the number of calls and notifications that are queued.
+func (batch *Batch) Len() int {
+	return len(batch.calls)
+}
+
+// Send sends the batch and waits for the responses. An error is only returned
+// if the batch could not be sent or its responses could not be read; the
+// error responses of calls are returned by the Err of their result.
+func (batch *Batch) Send(ctx context.Context) error {
+	if batch.sent {
+		return errors.New("Batch has already been sent.")
+	}
+
+	if len(batch.calls) == 0 {
+		return errors.New("Batch is empty.")
+	}
+
+	batch.sent = true
+	responses, err := batch.invoker.InvokeBatch(ctx, batch.calls)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range batch.results {
+		if result != nil {
+			result.response = responses[i]
+			result.sent = true
+		}
+	}
+
+	return nil
+}
+
+// Response returns the response to the call, or nil if the batch has not been
+// sent successfully.
+func (result *BatchResult) Response() Response {
+	return result.response
+}
+
+// Err returns an *RPCError if the server responded to the call with an error,
+// or an error if the batch has not been sent successfully.
+func (result *BatchResult) Err() error {
+	if !result.sent {
+		return errors.New("Batch has not been sent.")
+	}
+
+	return ErrorFromResponse(result.response)
+}
+
+// Decode decodes the result of the call into target, which must be a pointer.
+// It returns the same errors as Err.
+func (result *BatchResult) Decode(target interface{}) error {
+	if !result.sent {
+		return errors.New("Batch has not been sent.")
+	}
+
+	return decodeResult(result.response, target)
+}
@@ -0,0 +1,264 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// notifyingInvoker is an Invoker with a Notify method, which records the
+// methods it is called with.
+type notifyingInvoker struct {
+	jsonrpc.InvokerFunc
+
+	mutex    sync.Mutex
+	notified []string
+}
+
+func (invoker *notifyingInvoker) Notify(ctx context.Context, method string,
+	params interface{}) error {
+	invoker.mutex.Lock()
+	defer invoker.mutex.Unlock()
+
+	invoker.notified = append(invoker.notified, method)
+
+	return nil
+}
+
+var batchCalls = []jsonrpc.BatchCall{
+	{Method: "subtract", Params: []int{42, 23}},
+	{Method: "notify_hello", Params: []int{7}, Notification: true},
+	{Method: "sum", Params: []int{1, 2, 4}},
+	{Method: "foo"},
+}
+
+func assertBatchResponses(t *testing.T, responses []jsonrpc.Response) {
+	if assert.Len(t, responses, 4) {
+		assert.Equal(t, float64(19), responses[0].Result())
+		assert.Nil(t, responses[1])
+		assert.Equal(t, float64(7), responses[2].Result())
+		assert.Equal(t, jsonrpc.MethodNotFound, responses[3].ErrorCode())
+	}
+}
+
+func TestTCPClient_InvokeBatch(t *testing.T) {
+	server := newTestServer()
+	_, address := newTCPServer(t, server)
+
+	client, err := jsonrpc.DialTCP(context.Background(), address, jsonrpc.TCPOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	responses, err := client.InvokeBatch(context.Background(), batchCalls)
+	assert.NoError(t, err)
+	assertBatchResponses(t, responses)
+
+	// A batch of notifications does not wait for responses.
+	responses, err = client.InvokeBatch(context.Background(), []jsonrpc.BatchCall{
+		{Method: "notify_hello", Notification: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []jsonrpc.Response{nil}, responses)
+}
+
+func TestBatchClient(t *testing.T) {
+	server := newTestServer()
+	_, address := newTCPServer(t, server)
+
+	client, err := jsonrpc.DialTCP(context.Background(), address, jsonrpc.TCPOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	for _, batch := range []bool{true, false} {
+		for _, workers := range []int{0, 4} {
+			batchClient := jsonrpc.NewBatchClient(client, batch, workers)
+			responses, err := batchClient.InvokeBatch(context.Background(), batchCalls)
+			assert.NoError(t, err)
+			assertBatchResponses(t, responses)
+		}
+	}
+}
+
+func TestBatchClient_Split(t *testing.T) {
+	server := newTestServer()
+	invoker := &notifyingInvoker{}
+	var mutex sync.Mutex
+	var invoked []string
+	invoker.InvokerFunc = func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		mutex.Lock()
+		invoked = append(invoked, method)
+		mutex.Unlock()
+
+		return serverInvoker(server).Invoke(ctx, method, params)
+	}
+
+	client := jsonrpc.NewBatchClient(invoker, true, 0)
+	responses, err := client.InvokeBatch(context.Background(), batchCalls)
+	assert.NoError(t, err)
+	assertBatchResponses(t, responses)
+	assert.Equal(t, []string{"subtract", "sum", "foo"}, invoked)
+	assert.Equal(t, []string{"notify_hello"}, invoker.notified)
+
+	// Without Notify, notifications are sent as calls.
+	invoked = nil
+	client = jsonrpc.NewBatchClient(invoker.InvokerFunc, false, 0)
+	responses, err = client.InvokeBatch(context.Background(), batchCalls)
+	assert.NoError(t, err)
+	assertBatchResponses(t, responses)
+	assert.Equal(t, []string{"subtract", "notify_hello", "sum", "foo"}, invoked)
+}
+
+func TestBatchClient_Workers(t *testing.T) {
+	running := make(chan struct{}, 3)
+	release := make(chan struct{})
+	invoker := jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		running <- struct{}{}
+		<-release
+
+		return jsonrpc.NewSuccessResponse(1, method), nil
+	})
+
+	client := jsonrpc.NewBatchClient(invoker, false, 3)
+	done := make(chan []jsonrpc.Response)
+	go func() {
+		responses, err := client.InvokeBatch(context.Background(), []jsonrpc.BatchCall{
+			{Method: "a"}, {Method: "b"}, {Method: "c"},
+		})
+		assert.NoError(t, err)
+		done <- responses
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-running:
+		case <-time.After(5 * time.Second):
+			t.Fatal("calls were not sent in parallel")
+		}
+	}
+	close(release)
+
+	responses := <-done
+	if assert.Len(t, responses, 3) {
+		assert.Equal(t, "a", responses[0].Result())
+		assert.Equal(t, "b", responses[1].Result())
+		assert.Equal(t, "c", responses[2].Result())
+	}
+}
+
+func TestBatchClient_Error(t *testing.T) {
+	calls := 0
+	invoker := jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		calls++
+		if method == "fail" {
+			return nil, errors.New("Connection is closed.")
+		}
+
+		return jsonrpc.NewSuccessResponse(1, method), nil
+	})
+
+	client := jsonrpc.NewBatchClient(invoker, false, 0)
+	responses, err := client.InvokeBatch(context.Background(), []jsonrpc.BatchCall{
+		{Method: "a"}, {Method: "fail"}, {Method: "c"},
+	})
+	assert.EqualError(t, err, "Connection is closed.")
+	assert.Nil(t, responses)
+	assert.Equal(t, 2, calls)
+}
+
+func TestBatch(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewHTTPServer(newTestServer()))
+	defer server.Close()
+
+	client := jsonrpc.NewClient(&jsonrpc.HTTPTransport{URL: server.URL})
+	batch := client.NewBatch()
+	subtract := batch.Call("subtract", []int{42, 23})
+	batch.Notify("notify_hello", []int{7})
+	missing := batch.Call("foo", nil)
+	assert.Equal(t, 3, batch.Len())
+
+	var difference int
+	assert.EqualError(t, subtract.Decode(&difference), "Batch has not been sent.")
+	assert.EqualError(t, missing.Err(), "Batch has not been sent.")
+
+	assert.NoError(t, batch.Send(context.Background()))
+	assert.EqualError(t, batch.Send(context.Background()), "Batch has already been sent.")
+
+	assert.NoError(t, subtract.Decode(&difference))
+	assert.Equal(t, 19, difference)
+	assert.NoError(t, subtract.Err())
+	assert.Equal(t, &jsonrpc.RPCError{Code: jsonrpc.MethodNotFound, Message: "Method not found"},
+		missing.Err())
+	assert.Equal(t, jsonrpc.MethodNotFound, missing.Response().ErrorCode())
+
+	// A batch of notifications has no responses.
+	batch = client.NewBatch()
+	batch.Notify("notify_hello", nil)
+	assert.NoError(t, batch.Send(context.Background()))
+
+	assert.EqualError(t, client.NewBatch().Send(context.Background()), "Batch is empty.")
+}
+
+func TestClient_InvokeBatch(t *testing.T) {
+	for name, test := range map[string]struct {
+		reply   string
+		codes   []int
+		results []interface{}
+		err     string
+	}{
+		"out of order": {
+			reply:   `[{"jsonrpc":"2.0","id":2,"result":2},{"jsonrpc":"2.0","id":1,"result":1}]`,
+			codes:   []int{jsonrpc.Success, jsonrpc.Success},
+			results: []interface{}{float64(1), float64(2)},
+		},
+		"rejected": {
+			reply:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid request"}}`,
+			codes:   []int{jsonrpc.InvalidRequest, jsonrpc.InvalidRequest},
+			results: []interface{}{nil, nil},
+		},
+		"missing": {
+			reply: `[{"jsonrpc":"2.0","id":1,"result":1}]`,
+			err:   "Response does not match the request.",
+		},
+		"empty": {
+			err: "Server did not respond.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+				message []byte, notification bool) ([]byte, error) {
+				assert.Equal(t, `[{"id":1,"jsonrpc":"2.0","method":"a"},`+
+					`{"jsonrpc":"2.0","method":"b"},{"id":2,"jsonrpc":"2.0","method":"c"}]`,
+					string(message))
+				assert.False(t, notification)
+
+				return []byte(test.reply), nil
+			}))
+
+			responses, err := client.InvokeBatch(context.Background(), []jsonrpc.BatchCall{
+				{Method: "a"}, {Method: "b", Notification: true}, {Method: "c"},
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Nil(t, responses[1])
+			assert.Equal(t, test.codes, []int{responses[0].ErrorCode(), responses[2].ErrorCode()})
+			assert.Equal(t, test.results, []interface{}{responses[0].Result(), responses[2].Result()})
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// CallGroup runs several calls concurrently with a shared context. The first
+// call to fail cancels the context of the others, and its error is returned
+// by Wait. It is similar to errgroup.Group for calls:
+//
+//     group, ctx := jsonrpc.NewCallGroup(ctx, invoker)
+//
+//     var user User
+//     var orders []Order
+//     group.Call("user.get", userParams, &user)
+//     group.Call("orders.list", ordersParams, &orders)
+//
+//     // Calls that depend on each other are run in sequence with Go.
+//     var invoice Invoice
+//     group.Go(func(ctx context.Context) error {
+//         var cart Cart
+//         if err := jsonrpc.CallWith(ctx, invoker, "cart.get", nil, &cart); err != nil {
+//             return err
+//         }
+//
+//         return jsonrpc.CallWith(ctx, invoker, "invoice.create", cart, &invoice)
+//     })
+//
+//     if err := group.Wait(); err != nil {
+//         return err
+//     }
+//
+// A CallGroup must not be reused after Wait has returned.
+type CallGroup struct {
+	invoker Invoker
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewCallGroup creates a CallGroup that sends requests with invoker. The
+// returned context is cancelled when a call fails or Wait returns.
+func NewCallGroup(ctx context.Context, invoker Invoker) (*CallGroup, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &CallGroup{
+		invoker: invoker,
+		ctx:     ctx,
+		cancel:  cancel,
+	}, ctx
+}
+
+// Call sends a request in a new goroutine and decodes the result into result,
+// which must be a pointer or nil to ignore the result. The result must not be
+// read until Wait has returned.
+func (group *CallGroup) Call(method string, params interface{}, result interface{}) {
+	group.Go(func(ctx context.Context) error {
+		return CallWith(ctx, group.invoker, method, params, result)
+	})
+}
+
+// Go runs fn in a new goroutine with the context of the group.
+func (group *CallGroup) Go(fn func(ctx context.Context) error) {
+	group.wg.Add(1)
+
+	go func() {
+		defer group.wg.Done()
+
+		if err := fn(group.ctx); err != nil {
+			group.errOnce.Do(func() {
+				group.err = err
+				group.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until every call has finished and returns the first error.
+func (group *CallGroup) Wait() error {
+	group.wg.Wait()
+	group.cancel()
+
+	return group.err
+}
+
+// CallWith sends a single request with invoker and decodes the result into
+// result, which must be a pointer or nil to ignore the result. An *RPCError is
+// returned if the server responds with an error:
+//
+//     var user struct {
+//         ID   int64       `json:"id"`
+//         Name string      `json:"name"`
+//         Size json.Number `json:"size"`
+//     }
+//     err := jsonrpc.CallWith(ctx, client, "user.get", []int{1}, &user)
+//
+//     var rpcErr *jsonrpc.RPCError
+//     if errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc.MethodNotFound {
+//         // ...
+//     }
+//
+// Numbers are decoded from the response as it was received, so an int64 or a
+// json.Number keeps every digit of a large number. See Call for DefaultClient.
+func CallWith(ctx context.Context, invoker Invoker, method string, params interface{},
+	result interface{}) error {
+	response, err := invoker.Invoke(ctx, method, params)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return ErrorFromResponse(response)
+	}
+
+	return decodeResult(response, result)
+}
@@ -0,0 +1,117 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestCallWith(t *testing.T) {
+	invoker := serverInvoker(newTestServer())
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), invoker, "subtract",
+		[]int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), invoker, "sum",
+		[]int{1, 2}, nil))
+
+	err := jsonrpc.CallWith(context.Background(), invoker, "missing", nil, &difference)
+	assert.EqualError(t, err, "Method not found (-32601)")
+	_, ok := err.(*jsonrpc.RPCError)
+	assert.True(t, ok)
+}
+
+func TestCall_Decode(t *testing.T) {
+	reply := `{"jsonrpc":"2.0","id":1,"result":{"id":9007199254740993,"size":12345678901234567890}}`
+	client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+		message []byte, notification bool) ([]byte, error) {
+		return []byte(reply), nil
+	}))
+
+	var user struct {
+		ID   int64       `json:"id"`
+		Size json.Number `json:"size"`
+	}
+	assert.NoError(t, client.Call(context.Background(), "user.get", nil, &user))
+	assert.Equal(t, int64(9007199254740993), user.ID)
+	assert.Equal(t, json.Number("12345678901234567890"), user.Size)
+
+	var wrong []string
+	assert.Error(t, client.Call(context.Background(), "user.get", nil, &wrong))
+
+	reply = `{"jsonrpc":"2.0","id":3,"error":{"code":-32001,"message":"No such user","data":{"id":1}}}`
+	err := client.Call(context.Background(), "user.get", nil, &user)
+
+	var rpcErr *jsonrpc.RPCError
+	if assert.True(t, errors.As(err, &rpcErr)) {
+		assert.Equal(t, -32001, rpcErr.Code)
+		assert.Equal(t, "No such user", rpcErr.Message)
+		assert.Equal(t, map[string]interface{}{"id": 1.0}, rpcErr.Data)
+	}
+}
+
+func TestCallGroup(t *testing.T) {
+	invoker := serverInvoker(newTestServer())
+	group, _ := jsonrpc.NewCallGroup(context.Background(), invoker)
+
+	var difference, total, doubled int
+	var data []interface{}
+	group.Call("subtract", []int{42, 23}, &difference)
+	group.Call("get_data", nil, &data)
+	group.Go(func(ctx context.Context) error {
+		if err := jsonrpc.CallWith(ctx, invoker, "sum", []int{1, 2, 4}, &total); err != nil {
+			return err
+		}
+
+		return jsonrpc.CallWith(ctx, invoker, "sum", []int{total, total}, &doubled)
+	})
+
+	assert.NoError(t, group.Wait())
+	assert.Equal(t, 19, difference)
+	assert.Equal(t, []interface{}{"hello", 5.0}, data)
+	assert.Equal(t, 7, total)
+	assert.Equal(t, 14, doubled)
+}
+
+func TestCallGroup_FirstErrorCancels(t *testing.T) {
+	started := make(chan struct{})
+	invoker := jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		if method == "fail" {
+			<-started
+			return jsonrpc.NewErrorResponse(1, jsonrpc.InvalidParams, ""), nil
+		}
+
+		// Waits until another call fails.
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	group, ctx := jsonrpc.NewCallGroup(context.Background(), invoker)
+	group.Call("wait", nil, nil)
+	group.Call("fail", nil, nil)
+
+	err := group.Wait()
+	assert.EqualError(t, err, "Invalid params (-32602)")
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestCallGroup_ParentContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	group, _ := jsonrpc.NewCallGroup(parent, nil)
+
+	cancel()
+	group.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("cancelled")
+	})
+
+	assert.EqualError(t, group.Wait(), "cancelled")
+}
@@ -0,0 +1,219 @@
+package jsonrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCertificateCheckInterval is how often a CertificateFile checks
+// whether its files have changed when CheckInterval is zero.
+const DefaultCertificateCheckInterval = time.Minute
+
+// CertificateSource provides the certificate for each TLS handshake. The
+// autocert.Manager of golang.org/x/crypto/acme/autocert is a CertificateSource,
+// so certificates can be provisioned with ACME (such as Let's Encrypt):
+//
+//     manager := &autocert.Manager{
+//         Prompt:     autocert.AcceptTOS,
+//         HostPolicy: autocert.HostWhitelist("rpc.example.com"),
+//         Cache:      autocert.DirCache("/var/lib/myservice/certs"),
+//     }
+//
+//     config := jsonrpc.TLSConfig(manager)
+//     config.NextProtos = append(config.NextProtos, acme.ALPNProto)
+//     listener = tls.NewListener(listener, config)
+//
+type CertificateSource interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// TLSConfig returns a TLS configuration for a transport that gets its
+// certificate from the source for every handshake, so a new certificate is
+// used without restarting the server.
+func TLSConfig(source CertificateSource) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: source.GetCertificate,
+	}
+}
+
+// CertificateFile is a CertificateSource that reads a PEM encoded certificate
+// and key from disk and reloads them when either file changes, such as when
+// they are renewed by certbot. If the new files cannot be loaded the previous
+// certificate is kept and the error is returned by LastError.
+//
+// It is also a ConfigApplier, so a ConfigReloader can reload the certificate
+// together with the rest of the configuration, for example on SIGHUP.
+//
+// It is safe for concurrent use.
+type CertificateFile struct {
+	// CheckInterval is the minimum time between checks for changed files. Zero
+	// uses DefaultCertificateCheckInterval.
+	CheckInterval time.Duration
+
+	// Clock is used to decide when to check the files. SystemClock is used if
+	// it is nil.
+	Clock Clock
+
+	certFile, keyFile string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	pending     *tls.Certificate
+	certInfo    os.FileInfo
+	keyInfo     os.FileInfo
+	checked     time.Time
+	lastError   error
+}
+
+// NewCertificateFile loads the certificate and key. An error is returned if
+// they cannot be loaded.
+func NewCertificateFile(certFile, keyFile string) (*CertificateFile, error) {
+	source := &CertificateFile{certFile: certFile, keyFile: keyFile}
+	if err := source.Reload(); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// Reload loads the files now, even if they have not changed.
+func (source *CertificateFile) Reload() error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	return source.reload()
+}
+
+func (source *CertificateFile) reload() error {
+	certInfo, keyInfo, err := source.stat()
+	if err != nil {
+		source.lastError = err
+		return err
+	}
+
+	certificate, err := loadCertificate(source.certFile, source.keyFile)
+	if err != nil {
+		source.lastError = err
+		return err
+	}
+
+	source.certificate = certificate
+	source.certInfo = certInfo
+	source.keyInfo = keyInfo
+	source.lastError = nil
+
+	return nil
+}
+
+func (source *CertificateFile) stat() (os.FileInfo, os.FileInfo, error) {
+	certInfo, err := os.Stat(source.certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyInfo, err := os.Stat(source.keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certInfo, keyInfo, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if the
+// files have changed.
+func (source *CertificateFile) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	interval := source.CheckInterval
+	if interval == 0 {
+		interval = DefaultCertificateCheckInterval
+	}
+
+	now := clockOrSystem(source.Clock).Now()
+	if now.Sub(source.checked) >= interval {
+		source.checked = now
+
+		certInfo, keyInfo, err := source.stat()
+		if err != nil {
+			source.lastError = err
+		} else if changed(source.certInfo, certInfo) || changed(source.keyInfo, keyInfo) {
+			// On error the previous certificate is kept.
+			source.reload()
+		}
+	}
+
+	return source.certificate, nil
+}
+
+// Certificate returns the certificate that is currently being served.
+func (source *CertificateFile) Certificate() *tls.Certificate {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	return source.certificate
+}
+
+// LastError returns the error of the last attempt to reload the files, or nil
+// if it succeeded.
+func (source *CertificateFile) LastError() error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	return source.lastError
+}
+
+// ValidateConfig loads the files so that the configuration is rejected if they
+// are not valid.
+func (source *CertificateFile) ValidateConfig(config *Config) error {
+	certificate, err := loadCertificate(source.certFile, source.keyFile)
+	if err != nil {
+		return err
+	}
+
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	source.pending = certificate
+
+	return nil
+}
+
+// ApplyConfig starts serving the certificate that was loaded by
+// ValidateConfig.
+func (source *CertificateFile) ApplyConfig(config *Config) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if source.pending != nil {
+		source.certificate = source.pending
+		source.pending = nil
+		source.lastError = nil
+	}
+}
+
+func loadCertificate(certFile, keyFile string) (*tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the leaf once rather than on every handshake.
+	if certificate.Leaf == nil {
+		certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &certificate, nil
+}
+
+func changed(previous, current os.FileInfo) bool {
+	return previous == nil || !previous.ModTime().Equal(current.ModTime()) ||
+		previous.Size() != current.Size()
+}
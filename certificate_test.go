@@ -0,0 +1,153 @@
+package jsonrpc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// writeCertificate writes a self-signed certificate with the serial number.
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeConfig(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+}
+
+func servedSerial(t *testing.T, source jsonrpc.CertificateSource) int64 {
+	certificate, err := source.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate.Leaf.SerialNumber.Int64()
+}
+
+func TestCertificateFile(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1)
+
+	source, err := jsonrpc.NewCertificateFile(certFile, keyFile)
+	assert.NoError(t, err)
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	source.Clock = clock
+	source.CheckInterval = time.Minute
+	assert.Equal(t, int64(1), servedSerial(t, source))
+
+	// The files are not checked again until the interval has passed.
+	writeCertificate(t, certFile, keyFile, 2)
+	assert.Equal(t, int64(1), servedSerial(t, source))
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(2), servedSerial(t, source))
+	assert.NoError(t, source.LastError())
+
+	// A broken renewal keeps the previous certificate.
+	writeConfig(t, keyFile, "broken")
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(2), servedSerial(t, source))
+	assert.Error(t, source.LastError())
+}
+
+func TestNewCertificateFile_Missing(t *testing.T) {
+	_, err := jsonrpc.NewCertificateFile("missing.pem", "missing.key")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCertificateFile_ConfigReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1)
+
+	source, err := jsonrpc.NewCertificateFile(certFile, keyFile)
+	assert.NoError(t, err)
+
+	server := newTestServer()
+	reloader := &jsonrpc.ConfigReloader{
+		Load: func() (*jsonrpc.Config, error) {
+			return &jsonrpc.Config{DisabledMethods: []string{"sum"}}, nil
+		},
+		Appliers: []jsonrpc.ConfigApplier{source, server},
+	}
+
+	// A broken certificate rejects the whole configuration.
+	writeConfig(t, keyFile, "broken")
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, 3.0, server.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))[0].Result())
+
+	writeCertificate(t, certFile, keyFile, 2)
+	assert.NoError(t, reloader.Reload())
+	assert.Equal(t, int64(2), source.Certificate().Leaf.SerialNumber.Int64())
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 7)
+
+	source, err := jsonrpc.NewCertificateFile(certFile, keyFile)
+	assert.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", jsonrpc.TLSConfig(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	peer := conn.ConnectionState().PeerCertificates[0]
+	assert.Equal(t, int64(7), peer.SerialNumber.Int64())
+}
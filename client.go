@@ -0,0 +1,363 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+)
+
+// Transport carries the messages of a Client. RoundTrip sends a request and
+// returns the reply of the server. The reply to a notification is ignored, and
+// may be nil.
+type Transport interface {
+	RoundTrip(ctx context.Context, message []byte, notification bool) ([]byte, error)
+}
+
+// TransportFunc allows an ordinary function to be used as a Transport.
+type TransportFunc func(ctx context.Context, message []byte, notification bool) ([]byte, error)
+
+// RoundTrip calls f(ctx, message, notification).
+func (f TransportFunc) RoundTrip(ctx context.Context, message []byte,
+	notification bool) ([]byte, error) {
+	return f(ctx, message, notification)
+}
+
+// HTTPTransport is a Transport that posts each message to a URL, such as an
+// HTTPServer. The http.Client can be replaced to use a proxy, a custom dialer
+// or a timeout:
+//
+//     transport := jsonrpc.NewHTTPTransport("https://example.com/rpc", &http.Client{
+//         Timeout: 10 * time.Second,
+//     })
+//     transport.Header.Set("Authorization", "Bearer "+token)
+//
+//     client := jsonrpc.NewClient(transport)
+//
+type HTTPTransport struct {
+	URL string
+
+	// Client sends the requests. http.DefaultClient is used if it is nil.
+	Client *http.Client
+
+	// Header is added to every request, such as for authorization.
+	Header http.Header
+
+	// MaxBodySize is the largest response body in bytes. Zero uses
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+}
+
+// HTTPStatusError is returned by an HTTPTransport when the server responds
+// with a status that is not a success and without a JSON-RPC response, such as
+// a 401 Unauthorized from a proxy in front of it.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error returns the status, such as "Server responded with 404 Not Found.".
+func (err *HTTPStatusError) Error() string {
+	return "Server responded with " + err.Status + "."
+}
+
+// NewHTTPTransport creates an HTTPTransport that sends requests with client,
+// which may be nil to use http.DefaultClient.
+func NewHTTPTransport(url string, client *http.Client) *HTTPTransport {
+	return &HTTPTransport{URL: url, Client: client, Header: http.Header{}}
+}
+
+// RoundTrip posts the message and returns the body of the response.
+//
+// A body that is JSON is returned whatever the status, as servers send errors
+// such as a ParseError with a 400 Bad Request. A 202 Accepted or 204 No
+// Content has no body. Any other response is an *HTTPStatusError if its status
+// is not a success, or an error if it is not JSON. A body that is larger than
+// MaxBodySize is an error.
+func (transport *HTTPTransport) RoundTrip(ctx context.Context, message []byte,
+	notification bool) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, transport.URL,
+		bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range transport.Header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	client := transport.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	maxBodySize := transport.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > maxBodySize {
+		return nil, errors.New("Response is too large.")
+	}
+
+	if response.StatusCode == http.StatusNoContent ||
+		response.StatusCode == http.StatusAccepted {
+		return nil, nil
+	}
+
+	if isJSONContentType(response.Header.Get("Content-Type")) && len(body) > 0 {
+		return body, nil
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &HTTPStatusError{StatusCode: response.StatusCode, Status: response.Status}
+	}
+
+	if notification {
+		return nil, nil
+	}
+
+	return nil, errors.New("Response is not JSON.")
+}
+
+// isJSONContentType returns true for the content types that an HTTPServer
+// accepts.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && (mediaType == "application/json" ||
+		mediaType == "application/json-rpc" || mediaType == "application/jsonrequest")
+}
+
+// Client sends requests over a Transport. It creates the ids, encodes the
+// requests and decodes the responses, so the caller only deals with Go values:
+//
+//     client := jsonrpc.NewClient(&jsonrpc.HTTPTransport{URL: "https://example.com/rpc"})
+//
+//     var difference int
+//     err := client.Call(ctx, "subtract", []int{42, 23}, &difference)
+//
+// A Client is an Invoker, so it can be used with the other client helpers. It
+// is safe for concurrent use if its Transport is.
+type Client struct {
+	mutex     sync.RWMutex
+	transport Transport
+	ids       IDGenerator
+
+	// See SetRetryPolicy
+	retryPolicy *RetryPolicy
+}
+
+// NewClient creates a Client that uses sequential ids. The transport may be
+// nil if it is set later with SetTransport.
+func NewClient(transport Transport) *Client {
+	return &Client{
+		transport: transport,
+		ids:       &SequentialIDGenerator{},
+	}
+}
+
+// SetTransport replaces the transport. Requests that have already been sent
+// finish on the old transport.
+func (client *Client) SetTransport(transport Transport) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.transport = transport
+}
+
+// SetIDGenerator replaces the generator of the ids. A nil generator uses
+// RandomIDGenerator.
+func (client *Client) SetIDGenerator(generator IDGenerator) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.ids = generator
+}
+
+// config returns the transport and id generator, or an error if the client
+// has no transport.
+func (client *Client) config() (Transport, IDGenerator, error) {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	if client.transport == nil {
+		return nil, nil, errors.New("Client has no transport.")
+	}
+
+	if client.ids == nil {
+		return client.transport, RandomIDGenerator, nil
+	}
+
+	return client.transport, client.ids, nil
+}
+
+// Call sends a request and decodes its result into result, which must be a
+// pointer or nil to ignore the result. An *RPCError is returned if the server
+// responds with an error.
+func (client *Client) Call(ctx context.Context, method string, params interface{},
+	result interface{}) error {
+	return CallWith(ctx, client, method, params, result)
+}
+
+// Notify sends a notification. It returns once the transport has sent it.
+func (client *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	transport, _, err := client.config()
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(requestMessage(ctx, method, params, nil))
+	if err != nil {
+		return err
+	}
+
+	_, err = transport.RoundTrip(ctx, message, true)
+
+	return err
+}
+
+// Invoke sends a request and returns its response. The RequestExtensions of
+// ctx are sent with the request. An error is returned if the reply of the
+// server is not the response to the request. The request is sent again, with
+// the same id, as the RetryPolicy allows.
+func (client *Client) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	transport, ids, err := client.config()
+	if err != nil {
+		return nil, err
+	}
+
+	id := ids.NextID()
+	message, err := json.Marshal(requestMessage(ctx, method, params, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return client.retryPolicyFor(ctx).retry(ctx, method, func() (Response, error) {
+		reply, err := transport.RoundTrip(ctx, message, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return matchResponse(reply, id)
+	})
+}
+
+// matchResponse returns the response to the request with the id from the
+// reply of a server.
+func matchResponse(reply []byte, id interface{}) (Response, error) {
+	if len(bytes.TrimSpace(reply)) == 0 {
+		return nil, errors.New("Server did not respond.")
+	}
+
+	responses, err := NewResponsesFromJSON(bytes.TrimSpace(reply))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		// An error without an id, such as a ParseError, is the answer of a
+		// server that could not read the request.
+		if idKey(response.ID()) == idKey(id) ||
+			(response.ID() == nil && response.ErrorCode() != Success) {
+			return response, nil
+		}
+	}
+
+	return nil, errors.New("Response does not match the request.")
+}
+
+// InvokeBatch sends the calls as a single batch. The responses are in the
+// order of the calls, and the response of a notification is nil. An error
+// without an id, which a server sends when it cannot read the batch, is the
+// response of every call.
+func (client *Client) InvokeBatch(ctx context.Context,
+	calls []BatchCall) ([]Response, error) {
+	transport, ids, err := client.config()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(calls))
+	messages := make([]map[string]interface{}, len(calls))
+	notifications := true
+	for i, call := range calls {
+		var id interface{}
+		if !call.Notification {
+			id = ids.NextID()
+			keys[i] = idKey(id)
+			notifications = false
+		}
+
+		messages[i] = requestMessage(ctx, call.Method, call.Params, id)
+	}
+
+	message, err := json.Marshal(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := transport.RoundTrip(ctx, message, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]Response, len(calls))
+	if notifications {
+		return responses, nil
+	}
+
+	if len(bytes.TrimSpace(reply)) == 0 {
+		return nil, errors.New("Server did not respond.")
+	}
+
+	received, err := NewResponsesFromJSON(bytes.TrimSpace(reply))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]Response{}
+	var failed Response
+	for _, response := range received {
+		if response.ID() == nil && response.ErrorCode() != Success {
+			failed = response
+			continue
+		}
+
+		byID[idKey(response.ID())] = response
+	}
+
+	for i, key := range keys {
+		if calls[i].Notification {
+			continue
+		}
+
+		if responses[i] = byID[key]; responses[i] == nil {
+			if failed == nil {
+				return nil, errors.New("Response does not match the request.")
+			}
+
+			responses[i] = failed
+		}
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,153 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestClient(t *testing.T) {
+	server := newTestServer()
+	var authorization string
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		jsonrpc.NewHTTPServer(server).ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	client := jsonrpc.NewClient(&jsonrpc.HTTPTransport{
+		URL:    httpServer.URL,
+		Header: http.Header{"Authorization": {"Bearer token"}},
+	})
+	ctx := context.Background()
+
+	var difference int
+	assert.NoError(t, client.Call(ctx, "subtract", []int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+	assert.Equal(t, "Bearer token", authorization)
+
+	assert.NoError(t, client.Call(ctx, "sum", []int{1, 2}, nil))
+	assert.NoError(t, client.Notify(ctx, "notify_hello", []int{7}))
+
+	err := client.Call(ctx, "missing", nil, nil)
+	assert.Equal(t, &jsonrpc.RPCError{Code: jsonrpc.MethodNotFound, Message: "Method not found"}, err)
+
+	response, err := client.Invoke(ctx, "subtract", []int{1, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(4), response.ID())
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	for name, test := range map[string]struct {
+		reply string
+		err   string
+	}{
+		"empty":     {``, "Server did not respond."},
+		"wrong id":  {`{"jsonrpc":"2.0","id":99,"result":1}`, "Response does not match the request."},
+		"not json":  {`oops`, "invalid character 'o' looking for beginning of value"},
+		"parse err": {`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`, "Parse error (-32700)"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+				message []byte, notification bool) ([]byte, error) {
+				return []byte(test.reply), nil
+			}))
+
+			assert.EqualError(t, client.Call(ctx, "foo", nil, nil), test.err)
+		})
+	}
+
+	client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+		message []byte, notification bool) ([]byte, error) {
+		return nil, errors.New("Connection refused.")
+	}))
+	assert.EqualError(t, client.Call(ctx, "foo", nil, nil), "Connection refused.")
+	assert.EqualError(t, client.Notify(ctx, "foo", nil), "Connection refused.")
+
+	httpServer := httptest.NewServer(http.NotFoundHandler())
+	defer httpServer.Close()
+
+	client = jsonrpc.NewClient(&jsonrpc.HTTPTransport{URL: httpServer.URL})
+	assert.EqualError(t, client.Call(ctx, "foo", nil, nil),
+		"Server responded with 404 Not Found.")
+}
+
+func TestClient_SetIDGenerator(t *testing.T) {
+	var sent string
+	client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+		message []byte, notification bool) ([]byte, error) {
+		sent = string(message)
+		return []byte(`{"jsonrpc":"2.0","id":"abc","result":true}`), nil
+	}))
+	client.SetIDGenerator(jsonrpc.IDGeneratorFunc(func() interface{} {
+		return "abc"
+	}))
+
+	var result bool
+	assert.NoError(t, client.Call(context.Background(), "foo", []int{1}, &result))
+	assert.True(t, result)
+	assert.Equal(t, `{"id":"abc","jsonrpc":"2.0","method":"foo","params":[1]}`, sent)
+}
+
+func TestHTTPTransport(t *testing.T) {
+	ctx := context.Background()
+	for name, test := range map[string]struct {
+		status      int
+		contentType string
+		body        string
+		reply       string
+		err         string
+	}{
+		"ok":               {http.StatusOK, "application/json", `{"a":1}`, `{"a":1}`, ""},
+		"json-rpc":         {http.StatusOK, "application/json-rpc; charset=utf-8", `{}`, `{}`, ""},
+		"bad request":      {http.StatusBadRequest, "application/json", `{"error":1}`, `{"error":1}`, ""},
+		"no content":       {http.StatusNoContent, "", ``, ``, ""},
+		"accepted":         {http.StatusAccepted, "text/plain", `queued`, ``, ""},
+		"unauthorized":     {http.StatusUnauthorized, "text/plain", `no`, ``, "Server responded with 401 Unauthorized."},
+		"empty json error": {http.StatusBadGateway, "application/json", ``, ``, "Server responded with 502 Bad Gateway."},
+		"not json":         {http.StatusOK, "text/html", `<html>`, ``, "Response is not JSON."},
+		"too large":        {http.StatusOK, "application/json", `"` + strings.Repeat("a", 20) + `"`, ``, "Response is too large."},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(test.status)
+				io.WriteString(w, test.body)
+			}))
+			defer server.Close()
+
+			transport := jsonrpc.NewHTTPTransport(server.URL, server.Client())
+			transport.Header.Set("Authorization", "Bearer token")
+			transport.MaxBodySize = 16
+
+			reply, err := transport.RoundTrip(ctx, []byte(`{}`), false)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.reply, string(reply))
+			assert.Equal(t, "Bearer token", header.Get("Authorization"))
+			assert.Equal(t, "application/json", header.Get("Content-Type"))
+		})
+	}
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := jsonrpc.NewHTTPTransport(server.URL, nil).RoundTrip(ctx, []byte(`{}`), true)
+	var statusErr *jsonrpc.HTTPStatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	}
+}
@@ -0,0 +1,160 @@
+package jsonrpc
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// The State key that holds the ClientInfo of the request.
+const clientInfoStateKey = "jsonrpc.client"
+
+// ClientInfo identifies the client that sent a request. It is extracted once
+// by the transport (see ClientExtractor) and passed to every handler in the
+// State, so that rate limits, quotas and logs all agree on who the client is.
+type ClientInfo struct {
+	// Address is the IP address of the client. When the request came through
+	// a trusted proxy it is the address the proxy received it from.
+	Address string
+
+	// TLSSubject is the subject of the client certificate, if one was sent.
+	TLSSubject string
+
+	// Principal is the authenticated user or service, if any.
+	Principal string
+}
+
+// ID is the most specific identity of the client: the Principal, the
+// TLSSubject or the Address, in that order.
+func (info *ClientInfo) ID() string {
+	switch {
+	case info.Principal != "":
+		return info.Principal
+
+	case info.TLSSubject != "":
+		return info.TLSSubject
+	}
+
+	return info.Address
+}
+
+// ClientInfoFromRequest returns the ClientInfo that was put into the State of
+// the request by a ClientExtractor, or nil if there is none.
+func ClientInfoFromRequest(request Request) *ClientInfo {
+	info, _ := request.State(clientInfoStateKey).(*ClientInfo)
+	return info
+}
+
+// ClientExtractor finds the ClientInfo of an HTTP request.
+//
+//     extractor, err := jsonrpc.NewClientExtractor("10.0.0.0/8")
+//
+//     http.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+//         body, _ := io.ReadAll(r.Body)
+//         responses := server.HandleWithState(body, extractor.State(r))
+//         w.Write(responses.Bytes())
+//     })
+//
+// X-Forwarded-For is only believed when the request comes from a trusted
+// proxy, otherwise any client could claim to be anyone.
+type ClientExtractor struct {
+	trustedProxies []*net.IPNet
+
+	// Principal returns the authenticated user of the request, such as the
+	// subject of a verified bearer token. It may be nil.
+	Principal func(r *http.Request) string
+}
+
+// NewClientExtractor creates a ClientExtractor that trusts X-Forwarded-For
+// headers added by the proxies, which are IP addresses or CIDR ranges.
+func NewClientExtractor(trustedProxies ...string) (*ClientExtractor, error) {
+	extractor := &ClientExtractor{}
+
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New("Invalid trusted proxy " + proxy + ".")
+			}
+
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			extractor.trustedProxies = append(extractor.trustedProxies,
+				&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.New("Invalid trusted proxy " + proxy + ".")
+		}
+		extractor.trustedProxies = append(extractor.trustedProxies, network)
+	}
+
+	return extractor, nil
+}
+
+func (extractor *ClientExtractor) trusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range extractor.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Extract returns the ClientInfo of the request.
+//
+// The Address is found by walking X-Forwarded-For from the right (the address
+// added by the nearest proxy) while each hop is a trusted proxy. The first
+// address that is not trusted is the client.
+func (extractor *ClientExtractor) Extract(r *http.Request) *ClientInfo {
+	info := &ClientInfo{Address: r.RemoteAddr}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		info.Address = host
+	}
+
+	if extractor.trusted(info.Address) {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(header, ",")...)
+		}
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+
+			info.Address = hop
+			if !extractor.trusted(hop) {
+				break
+			}
+		}
+	}
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		info.TLSSubject = r.TLS.PeerCertificates[0].Subject.String()
+	}
+
+	if extractor.Principal != nil {
+		info.Principal = extractor.Principal(r)
+	}
+
+	return info
+}
+
+// State returns a State holding the ClientInfo of the request, to be passed to
+// HandleWithState.
+func (extractor *ClientExtractor) State(r *http.Request) State {
+	return State{clientInfoStateKey: extractor.Extract(r)}
+}
@@ -0,0 +1,99 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestNewClientExtractor_Invalid(t *testing.T) {
+	_, err := jsonrpc.NewClientExtractor("10.0.0.0/8", "proxy.local")
+	assert.EqualError(t, err, "Invalid trusted proxy proxy.local.")
+
+	_, err = jsonrpc.NewClientExtractor("10.0.0.0/33")
+	assert.EqualError(t, err, "Invalid trusted proxy 10.0.0.0/33.")
+}
+
+func TestClientExtractor_Extract(t *testing.T) {
+	extractor, err := jsonrpc.NewClientExtractor("10.0.0.0/8", "192.168.1.1", "::1")
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		"direct":             {"203.0.113.5:4000", nil, "203.0.113.5"},
+		"untrusted proxy":    {"203.0.113.5:4000", []string{"198.51.100.1"}, "203.0.113.5"},
+		"trusted proxy":      {"10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		"ipv6 proxy":         {"[::1]:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		"chain of proxies":   {"10.1.2.3:4000", []string{"198.51.100.1, 192.168.1.1"}, "198.51.100.1"},
+		"spoofed":            {"10.1.2.3:4000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		"multiple headers":   {"10.1.2.3:4000", []string{"198.51.100.1", "10.9.9.9"}, "198.51.100.1"},
+		"only proxies":       {"10.1.2.3:4000", []string{"10.9.9.9"}, "10.9.9.9"},
+		"garbage":            {"10.1.2.3:4000", []string{"198.51.100.1, unknown"}, "10.1.2.3"},
+		"missing forwarding": {"10.1.2.3:4000", nil, "10.1.2.3"},
+	}
+
+	for name, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, header := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+
+		info := extractor.Extract(r)
+		assert.Equal(t, test.expected, info.Address, name)
+		assert.Equal(t, test.expected, info.ID(), name)
+	}
+}
+
+func TestClientExtractor_Identity(t *testing.T) {
+	extractor, _ := jsonrpc.NewClientExtractor()
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{Subject: pkix.Name{CommonName: "billing", Organization: []string{"Acme"}}},
+	}}
+
+	info := extractor.Extract(r)
+	assert.Equal(t, "CN=billing,O=Acme", info.TLSSubject)
+	assert.Equal(t, "CN=billing,O=Acme", info.ID())
+
+	extractor.Principal = func(r *http.Request) string {
+		return "alice"
+	}
+	assert.Equal(t, "alice", extractor.Extract(r).ID())
+}
+
+func TestClientExtractor_State(t *testing.T) {
+	extractor, _ := jsonrpc.NewClientExtractor()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	var output bytes.Buffer
+	server := newTestServer()
+	server.SetLogger(slog.New(newTestLogger(&output)))
+	server.SetHandler("whoami", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(jsonrpc.ClientInfoFromRequest(request).ID())
+	})
+
+	responses := server.HandleWithState([]byte(
+		`[{"jsonrpc":"2.0","method":"whoami","id":1},{"jsonrpc":"2.0","id":2}]`),
+		extractor.State(r))
+	assert.Equal(t, "192.0.2.1", responses[0].Result())
+
+	assert.Equal(t, []string{
+		`level=WARN msg="Invalid request" code=-32600 error="Method must be a string." client=192.0.2.1`,
+	}, logLines(&output))
+
+	assert.Nil(t, jsonrpc.ClientInfoFromRequest(
+		jsonrpc.NewRequestResponder("2.0", 1, "whoami", nil)))
+}
@@ -0,0 +1,184 @@
+package jsonrpc
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time and waits for durations. Everything in the package that
+// depends on time accepts a Clock so that tests can control time with a
+// FakeClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the real clock. It is used whenever a Clock is nil.
+var SystemClock Clock = systemClock{}
+
+// clockOrSystem returns clock, or SystemClock if clock is nil.
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+
+	return clock
+}
+
+// FakeClock is a Clock that only moves when it is told to. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+// NewFakeClock creates a FakeClock that is stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+// Now returns the current fake time.
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// After returns a channel that receives the fake time once the clock has been
+// advanced by at least d.
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- clock.now
+		return c
+	}
+
+	clock.waiters = append(clock.waiters, fakeWaiter{clock.now.Add(d), c})
+	sort.SliceStable(clock.waiters, func(i, j int) bool {
+		return clock.waiters[i].until.Before(clock.waiters[j].until)
+	})
+
+	return c
+}
+
+// Advance moves the clock forward by d, firing any channels from After that
+// are due.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+	for len(clock.waiters) > 0 && !clock.waiters[0].until.After(clock.now) {
+		clock.waiters[0].c <- clock.now
+		clock.waiters = clock.waiters[1:]
+	}
+}
+
+// Waiters returns the number of After channels that have not fired yet. Tests
+// can use this to know when the code under test is waiting on the clock.
+func (clock *FakeClock) Waiters() int {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return len(clock.waiters)
+}
+
+// IDGenerator creates the ids of new requests.
+type IDGenerator interface {
+	NextID() interface{}
+}
+
+// IDGeneratorFunc allows an ordinary function to be used as an IDGenerator.
+type IDGeneratorFunc func() interface{}
+
+// NextID calls f()
+func (f IDGeneratorFunc) NextID() interface{} {
+	return f()
+}
+
+// RandomIDGenerator uses GenerateRequestID. It is used whenever an IDGenerator
+// is nil.
+var RandomIDGenerator IDGenerator = IDGeneratorFunc(func() interface{} {
+	return GenerateRequestID()
+})
+
+// SequentialIDGenerator generates the ids 1, 2, 3, etc. It is safe for
+// concurrent use. The zero value is ready to use.
+type SequentialIDGenerator struct {
+	last uint64
+}
+
+// NextID returns the next id as an int64.
+func (generator *SequentialIDGenerator) NextID() interface{} {
+	return int64(atomic.AddUint64(&generator.last, 1))
+}
+
+// SetClock replaces the clock used by the server. This affects Uptime, method
+// sunsets and the time of the records logged at a level set with SetLogLevel.
+func (server *SimpleServer) SetClock(clock Clock) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.clock = clockOrSystem(clock)
+	server.startTime = server.clock.Now()
+}
+
+// SetIDGenerator replaces the generator used for requests that are created by
+// the server, such as by the WebhookAdapter and GraphQLBridge.
+func (server *SimpleServer) SetIDGenerator(generator IDGenerator) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.idGenerator = generator
+}
+
+func (server *SimpleServer) nextID() interface{} {
+	server.mutex.RLock()
+	generator := server.idGenerator
+	server.mutex.RUnlock()
+
+	if generator == nil {
+		return RandomIDGenerator.NextID()
+	}
+
+	return generator.NextID()
+}
+
+// call handles a request that the server makes up, such as for the
+// WebhookAdapter and GraphQLBridge, as if it had been sent by a client, so it
+// goes through the same limits and hooks. The response is nil if the
+// IDGenerator returned a nil id, which makes the request a notification.
+func (server *SimpleServer) call(method string, params interface{}, state State) Response {
+	request := NewRequestResponder("2.0", server.nextID(), method, params)
+
+	responses := server.HandleWithState(request.Bytes(), state)
+	if len(responses) == 0 {
+		return nil
+	}
+
+	return responses[0]
+}
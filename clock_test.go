@@ -0,0 +1,94 @@
+package jsonrpc_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	assert.Equal(t, epoch, clock.Now())
+
+	later := clock.After(2 * time.Second)
+	sooner := clock.After(time.Second)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, epoch.Add(time.Second), <-sooner)
+	assert.Equal(t, 1, clock.Waiters())
+
+	select {
+	case <-later:
+		t.Error("fired too early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	assert.Equal(t, epoch.Add(2*time.Second), <-later)
+	assert.Equal(t, 0, clock.Waiters())
+
+	assert.Equal(t, epoch.Add(2*time.Second), <-clock.After(0))
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	generator := &jsonrpc.SequentialIDGenerator{}
+
+	assert.Equal(t, int64(1), generator.NextID())
+	assert.Equal(t, int64(2), generator.NextID())
+}
+
+func TestSimpleServer_SetClock(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	server := newTestServer()
+	server.SetClock(clock)
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:   "get_data",
+		Sunset: epoch.Add(time.Hour),
+	})
+	r := []byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`)
+
+	assert.Equal(t, time.Duration(0), server.Uptime())
+	assert.Equal(t, jsonrpc.Success, server.Handle(r)[0].ErrorCode())
+
+	clock.Advance(time.Hour)
+
+	assert.Equal(t, time.Hour, server.Uptime())
+	assert.Equal(t, jsonrpc.MethodRetired, server.Handle(r)[0].ErrorCode())
+}
+
+func TestSimpleServer_SetIDGenerator(t *testing.T) {
+	server := newTestServer()
+	server.SetIDGenerator(&jsonrpc.SequentialIDGenerator{})
+	adapter := jsonrpc.NewWebhookAdapter(server)
+	adapter.AddRule(jsonrpc.WebhookRule{Method: "get_data"})
+
+	recorder := sendWebhook(adapter, "/", ``, nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":["hello",5]}`,
+		recorder.Body.String())
+}
+
+func TestLongPoller_Clock(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	poller := jsonrpc.NewLongPoller(time.Minute)
+	poller.Clock = clock
+
+	done := make(chan struct{})
+	go func() {
+		pollSession(poller, "a")
+		close(done)
+	}()
+
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+	<-done
+}
@@ -0,0 +1,62 @@
+// Command jsonrpc-fixtures validates a directory of JSON-RPC request and
+// response fixtures against an OpenRPC document, for use in contract test
+// pipelines:
+//
+//     jsonrpc-fixtures -openrpc openrpc.json ./testdata/fixtures
+//
+// Every problem is printed and the exit status is 1 if there were any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thiagozs/jsonrpc"
+)
+
+func main() {
+	openRPC := flag.String("openrpc", "", "the OpenRPC document describing the methods")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(),
+			"Usage: jsonrpc-fixtures -openrpc openrpc.json dir...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *openRPC == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*openRPC)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	methods, err := jsonrpc.LoadOpenRPC(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *openRPC, err)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, dir := range flag.Args() {
+		problems, err := jsonrpc.ValidateFixtures(dir, methods)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
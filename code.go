@@ -0,0 +1,55 @@
+package jsonrpc
+
+import (
+	"strconv"
+)
+
+const (
+	// The lowest error code reserved by the JSON-RPC spec for pre-defined
+	// errors. Application errors should use codes outside of the reserved
+	// range.
+	ReservedCodeMin = -32768
+
+	// The highest error code reserved by the JSON-RPC spec.
+	ReservedCodeMax = -32000
+)
+
+// Code is a JSON-RPC error code. The error code constants (such as ParseError)
+// are untyped so they can be used as an int or a Code:
+//
+//     jsonrpc.Code(response.ErrorCode()) == jsonrpc.MethodNotFound
+//
+type Code int
+
+// String returns the generic error message and the code, such as
+// "Method not found (-32601)".
+func (code Code) String() string {
+	if code == Success {
+		return "Success (0)"
+	}
+
+	return ErrorMessageForCode(int(code)) + " (" + strconv.Itoa(int(code)) + ")"
+}
+
+// IsServerError returns true if the code is in the implementation-defined
+// server error range.
+func (code Code) IsServerError() bool {
+	return IsServerError(int(code))
+}
+
+// IsReserved returns true if the code is reserved by the JSON-RPC spec.
+func (code Code) IsReserved() bool {
+	return IsReservedCode(int(code))
+}
+
+// IsServerError returns true if code is between ServerErrorMin and ServerError.
+func IsServerError(code int) bool {
+	return code >= ServerErrorMin && code <= ServerError
+}
+
+// IsReservedCode returns true if code is between ReservedCodeMin and
+// ReservedCodeMax. This includes the pre-defined errors and the server error
+// range.
+func IsReservedCode(code int) bool {
+	return code >= ReservedCodeMin && code <= ReservedCodeMax
+}
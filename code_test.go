@@ -0,0 +1,48 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestCode_String(t *testing.T) {
+	assert.Equal(t, "Success (0)", jsonrpc.Code(jsonrpc.Success).String())
+	assert.Equal(t, "Method not found (-32601)", jsonrpc.Code(jsonrpc.MethodNotFound).String())
+	assert.Equal(t, "Server error (-32050)", jsonrpc.Code(-32050).String())
+	assert.Equal(t, "Unknown error (42)", jsonrpc.Code(42).String())
+}
+
+func TestIsServerError(t *testing.T) {
+	tests := map[int]bool{
+		jsonrpc.ServerError:    true,
+		jsonrpc.ServerErrorMin: true,
+		-32050:                 true,
+		-32100:                 false,
+		jsonrpc.InternalError:  false,
+		-31999:                 false,
+	}
+
+	for code, expected := range tests {
+		assert.Equal(t, expected, jsonrpc.IsServerError(code), "%d", code)
+		assert.Equal(t, expected, jsonrpc.Code(code).IsServerError(), "%d", code)
+	}
+}
+
+func TestIsReservedCode(t *testing.T) {
+	tests := map[int]bool{
+		jsonrpc.ParseError:      true,
+		jsonrpc.ReservedCodeMin: true,
+		jsonrpc.ReservedCodeMax: true,
+		-32769:                  false,
+		-31999:                  false,
+		jsonrpc.Success:         false,
+		100:                     false,
+	}
+
+	for code, expected := range tests {
+		assert.Equal(t, expected, jsonrpc.IsReservedCode(code), "%d", code)
+		assert.Equal(t, expected, jsonrpc.Code(code).IsReserved(), "%d", code)
+	}
+}
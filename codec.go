@@ -0,0 +1,89 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Codec encodes and decodes the params and result of a CodecHandler. The
+// encoded value must be valid JSON as it is embedded in the JSON-RPC envelope.
+//
+// Protobuf messages can be used as params and results with ProtoJSONCodec, or
+// ProtoHandler, when built with the protojson tag.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec that uses encoding/json.
+type JSONCodec struct{}
+
+// Marshal calls json.Marshal
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal calls json.Unmarshal
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// CodecHandler creates a handler that decodes the params with codec into the
+// value returned by newParams (which must be a pointer) before calling fn. The
+// result of fn is encoded with the same codec.
+//
+// Params that cannot be decoded receive an InvalidParams response. If fn
+// returns an *RPCError it is sent as is, any other error is sent as a
+// ServerError.
+//
+// The params a handler is given have already been decoded by the server, so
+// they are encoded again for the codec, and numbers that do not fit in a
+// float64 (such as an int64 above 2^53) lose precision. Register the handler
+// with SetCodecHandler to decode the params from the request that was received.
+func CodecHandler(codec Codec, newParams func() interface{},
+	fn func(request RequestResponder, params interface{}) (interface{}, error)) RequestHandler {
+	return codecHandler(codec, newParams, fn).RequestHandler()
+}
+
+// SetCodecHandler will register (or replace) a CodecHandler for a method. The
+// params are decoded by the codec straight from the request that was received
+// (see SetStreamHandler), so none of their precision is lost.
+func (server *SimpleServer) SetCodecHandler(methodName string, codec Codec,
+	newParams func() interface{},
+	fn func(request RequestResponder, params interface{}) (interface{}, error)) {
+	server.SetStreamHandler(methodName, codecHandler(codec, newParams, fn))
+}
+
+func codecHandler(codec Codec, newParams func() interface{},
+	fn func(request RequestResponder, params interface{}) (interface{}, error)) StreamHandler {
+	return func(request RequestResponder, decoder *json.Decoder) Response {
+		params := newParams()
+
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == nil && string(raw) != "null" {
+			err = codec.Unmarshal(raw, params)
+		}
+		if err != nil {
+			return request.NewErrorResponse(InvalidParams, err.Error())
+		}
+
+		result, err := fn(request, params)
+		if err != nil {
+			var rpcErr *RPCError
+			if errors.As(err, &rpcErr) {
+				return request.NewErrorResponseWithData(rpcErr.Code,
+					rpcErr.Message, rpcErr.Data)
+			}
+
+			return request.NewServerErrorResponse(err)
+		}
+
+		b, err := codec.Marshal(result)
+		if err != nil {
+			return request.NewErrorResponse(InternalError, err.Error())
+		}
+
+		return request.NewSuccessResponse(json.RawMessage(b))
+	}
+}
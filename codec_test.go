@@ -0,0 +1,101 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// upperCodec behaves like protojson in that it produces different JSON than
+// encoding/json for the same value.
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return []byte(strings.ToUpper(string(b))), err
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type greetParams struct {
+	Name string `json:"name"`
+}
+
+func greet(request jsonrpc.RequestResponder, params interface{}) (interface{}, error) {
+	p := params.(*greetParams)
+	switch p.Name {
+	case "":
+		return nil, &jsonrpc.RPCError{Code: jsonrpc.InvalidParams, Message: "Missing name"}
+	case "error":
+		return nil, errors.New("bad stuff")
+	}
+
+	return map[string]string{"greeting": "Hello, " + p.Name}, nil
+}
+
+func TestCodecHandler(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	newParams := func() interface{} { return new(greetParams) }
+	server.SetHandler("json", jsonrpc.CodecHandler(jsonrpc.JSONCodec{}, newParams, greet))
+	server.SetHandler("upper", jsonrpc.CodecHandler(upperCodec{}, newParams, greet))
+
+	tests := map[string]struct {
+		request  string
+		expected string
+	}{
+		"json codec": {
+			`{"jsonrpc":"2.0","method":"json","params":{"name":"Bob"},"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":{"greeting":"Hello, Bob"}}]`,
+		},
+		"custom codec": {
+			`{"jsonrpc":"2.0","method":"upper","params":{"name":"Bob"},"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":{"GREETING":"HELLO, BOB"}}]`,
+		},
+		"invalid params": {
+			`{"jsonrpc":"2.0","method":"json","params":[1],"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"json: cannot unmarshal array into Go value of type jsonrpc_test.greetParams"}}]`,
+		},
+		"rpc error": {
+			`{"jsonrpc":"2.0","method":"json","id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Missing name"}}]`,
+		},
+		"error": {
+			`{"jsonrpc":"2.0","method":"json","params":{"name":"error"},"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"bad stuff"}}]`,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.expected, server.Handle([]byte(test.request)).String())
+		})
+	}
+}
+
+func TestSimpleServer_SetCodecHandler(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetCodecHandler("echo", jsonrpc.JSONCodec{},
+		func() interface{} { return new(struct{ ID int64 }) },
+		func(request jsonrpc.RequestResponder, params interface{}) (interface{}, error) {
+			return params, nil
+		})
+	server.SetHandler("lossy", jsonrpc.CodecHandler(jsonrpc.JSONCodec{},
+		func() interface{} { return new(struct{ ID int64 }) },
+		func(request jsonrpc.RequestResponder, params interface{}) (interface{}, error) {
+			return params, nil
+		}))
+
+	// The params are decoded from the request, so the int64 is exact.
+	assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":{"ID":9007199254740993}}]`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"echo","params":{"ID":9007199254740993},"id":1}`)).String())
+	assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":{"ID":0}}]`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"echo","id":1}`)).String())
+	assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":{"ID":9007199254740992}}]`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"lossy","params":{"ID":9007199254740993},"id":1}`)).String())
+}
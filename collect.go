@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"time"
+)
+
+// DefaultCollectRetries is the number of times a Collector will retry a page
+// that was rejected with a retryable error when MaxRetries is zero.
+const DefaultCollectRetries = 5
+
+// DefaultCollectMaxBackoff is the longest delay a Collector waits before a
+// retry when MaxBackoff is zero.
+const DefaultCollectMaxBackoff = time.Minute
+
+// Collector fetches every page of a method that follows the pagination
+// convention (see ForEachPage). Pages that fail with a retryable error (see
+// ErrorDetails), such as a RateLimitedErrorType, are retried after the delay
+// requested by the server.
+type Collector struct {
+	Invoker Invoker
+
+	// Limit is the page size to ask for. Zero lets the server decide.
+	Limit int
+
+	// MaxRetries is the number of times a single page may be retried. Zero
+	// uses DefaultCollectRetries and a negative value disables retries.
+	MaxRetries int
+
+	// Backoff is the delay before a retry when the server does not send a
+	// RetryAfter. It doubles for each retry of the same page. Zero uses one
+	// second.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay that Backoff doubles to. Zero uses
+	// DefaultCollectMaxBackoff. The RetryAfter of the server is not capped.
+	MaxBackoff time.Duration
+
+	// Clock is used to wait between retries. SystemClock is used if it is nil.
+	Clock Clock
+}
+
+// CollectAll fetches every page of method with the default Collector. See
+// Collector.CollectAll.
+func CollectAll(ctx context.Context, invoker Invoker, method string,
+	params interface{}, results interface{}) error {
+	return (&Collector{Invoker: invoker}).CollectAll(ctx, method, params, results)
+}
+
+// CollectAll fetches every page of method with a Collector that sends the
+// calls with the client. See Collector.CollectAll.
+func (client *Client) CollectAll(ctx context.Context, method string,
+	params interface{}, results interface{}) error {
+	return (&Collector{Invoker: client}).CollectAll(ctx, method, params, results)
+}
+
+// CollectAll fetches every page of method and appends the items of each page
+// to results, which must be a pointer to a slice:
+//
+//     var users []User
+//     err := client.CollectAll(ctx, "listUsers", filter, &users)
+//
+// The items that were collected before an error are left in results.
+func (collector *Collector) CollectAll(ctx context.Context, method string,
+	params interface{}, results interface{}) error {
+	slice := reflect.ValueOf(results)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("Results must be a pointer to a slice.")
+	}
+	slice = slice.Elem()
+
+	return ForEachPage(ctx, InvokerFunc(collector.invoke), method, params,
+		collector.Limit, func(response Response) error {
+			page := reflect.New(slice.Type())
+			if err := decodeResult(response, page.Interface()); err != nil {
+				return err
+			}
+
+			slice.Set(reflect.AppendSlice(slice, page.Elem()))
+
+			return nil
+		})
+}
+
+// invoke sends a single page, retrying while the server says it may.
+func (collector *Collector) invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	maxRetries := collector.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultCollectRetries
+	}
+
+	backoff := collector.Backoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+
+	maxBackoff := collector.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultCollectMaxBackoff
+	}
+
+	for retries := 0; ; retries++ {
+		response, err := collector.Invoker.Invoke(ctx, method, params)
+		if err != nil || response.ErrorCode() == Success || retries >= maxRetries {
+			return response, err
+		}
+
+		delay, ok := retryDelay(response)
+		if !ok {
+			return response, nil
+		}
+
+		// The delay the server asks for is used first.
+		if delay == 0 {
+			delay = backoff
+			for i := 0; i < retries && delay < maxBackoff && delay <= math.MaxInt64/2; i++ {
+				delay *= 2
+			}
+
+			if delay > maxBackoff {
+				delay = maxBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-clockOrSystem(collector.Clock).After(delay):
+		}
+	}
+}
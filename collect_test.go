@@ -0,0 +1,200 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// rateLimited rejects the first calls to the handler with the details.
+func rateLimited(handler jsonrpc.RequestHandler, rejections int,
+	details *jsonrpc.ErrorDetails) jsonrpc.RequestHandler {
+	return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		if rejections > 0 {
+			rejections--
+			return request.NewErrorResponseWithData(jsonrpc.ServerError,
+				"Too many requests", details)
+		}
+
+		return handler(request)
+	}
+}
+
+func newPagedServer() *jsonrpc.SimpleServer {
+	server := jsonrpc.NewSimpleServer()
+	server.SetLenient(true)
+	server.SetHandler("list", listNumbers)
+
+	return server
+}
+
+func TestCollectAll(t *testing.T) {
+	var numbers []int
+	err := jsonrpc.CollectAll(context.Background(), serverInvoker(newPagedServer()),
+		"list", nil, &numbers)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, numbers)
+}
+
+func TestClient_CollectAll(t *testing.T) {
+	server := newPagedServer()
+	client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+		message []byte, notification bool) ([]byte, error) {
+		return server.Handle(message).Bytes(), nil
+	}))
+
+	var numbers []int
+	err := client.CollectAll(context.Background(), "list", nil, &numbers)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, numbers)
+}
+
+func TestCollectAll_NotASlice(t *testing.T) {
+	var numbers []int
+	err := jsonrpc.CollectAll(context.Background(), serverInvoker(newPagedServer()),
+		"list", nil, numbers)
+
+	assert.EqualError(t, err, "Results must be a pointer to a slice.")
+}
+
+func TestCollector_RetryAfter(t *testing.T) {
+	server := newPagedServer()
+	server.SetHandler("list", rateLimited(listNumbers, 1,
+		jsonrpc.NewErrorDetails(jsonrpc.RateLimitedErrorType).
+			WithRetryAfter(2*time.Second)))
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	collector := &jsonrpc.Collector{
+		Invoker: serverInvoker(server),
+		Limit:   5,
+		Clock:   clock,
+	}
+
+	var numbers []int
+	done := make(chan error)
+	go func() {
+		done <- collector.CollectAll(context.Background(), "list", nil, &numbers)
+	}()
+
+	waitForWaiter(clock)
+	clock.Advance(2 * time.Second)
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, numbers)
+}
+
+func TestCollector_Backoff(t *testing.T) {
+	server := newPagedServer()
+	server.SetHandler("list", rateLimited(listNumbers, 2,
+		jsonrpc.NewErrorDetails("").WithRetryable(true)))
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	collector := &jsonrpc.Collector{
+		Invoker: serverInvoker(server),
+		Backoff: time.Second,
+		Clock:   clock,
+	}
+
+	var numbers []int
+	done := make(chan error)
+	go func() {
+		done <- collector.CollectAll(context.Background(), "list", nil, &numbers)
+	}()
+
+	waitForWaiter(clock)
+	clock.Advance(time.Second)
+
+	// The second retry waits twice as long.
+	waitForWaiter(clock)
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, clock.Waiters())
+	clock.Advance(time.Second)
+
+	assert.NoError(t, <-done)
+	assert.Len(t, numbers, 10)
+}
+
+func TestCollector_MaxBackoff(t *testing.T) {
+	server := newPagedServer()
+	server.SetHandler("list", rateLimited(listNumbers, 40,
+		jsonrpc.NewErrorDetails("").WithRetryable(true)))
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	collector := &jsonrpc.Collector{
+		Invoker:    serverInvoker(server),
+		MaxRetries: 40,
+		Backoff:    time.Second,
+		MaxBackoff: 3 * time.Second,
+		Clock:      clock,
+	}
+
+	done := make(chan error)
+	go func() {
+		var numbers []int
+		done <- collector.CollectAll(context.Background(), "list", nil, &numbers)
+	}()
+
+	// The delay doubles up to the MaxBackoff and stays there, long after
+	// doubling it would have overflowed.
+	for i := 0; i < 40; i++ {
+		delay := 3 * time.Second
+		if i < 2 {
+			delay = time.Second << uint(i)
+		}
+
+		waitForWaiter(clock)
+		clock.Advance(delay - time.Millisecond)
+		assert.Equal(t, 1, clock.Waiters(), i)
+		clock.Advance(time.Millisecond)
+	}
+
+	assert.NoError(t, <-done)
+}
+
+func TestCollector_GiveUp(t *testing.T) {
+	details := jsonrpc.NewErrorDetails(jsonrpc.RateLimitedErrorType).
+		WithRetryAfter(time.Millisecond)
+
+	for name, test := range map[string]struct {
+		collector *jsonrpc.Collector
+		details   *jsonrpc.ErrorDetails
+	}{
+		"not retryable": {&jsonrpc.Collector{}, jsonrpc.NewErrorDetails("")},
+		"no retries":    {&jsonrpc.Collector{MaxRetries: -1}, details},
+		"max retries":   {&jsonrpc.Collector{MaxRetries: 2}, details},
+	} {
+		server := newPagedServer()
+		server.SetHandler("list", rateLimited(listNumbers, 3, test.details))
+		test.collector.Invoker = serverInvoker(server)
+
+		var numbers []int
+		err := test.collector.CollectAll(context.Background(), "list", nil, &numbers)
+		assert.EqualError(t, err, "Too many requests (-32000)", name)
+		assert.Empty(t, numbers, name)
+	}
+}
+
+func TestCollector_Context(t *testing.T) {
+	server := newPagedServer()
+	server.SetHandler("list", rateLimited(listNumbers, 1,
+		jsonrpc.NewErrorDetails("").WithRetryAfter(time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := jsonrpc.NewFakeClock(epoch)
+	collector := &jsonrpc.Collector{Invoker: serverInvoker(server), Clock: clock}
+
+	done := make(chan error)
+	go func() {
+		var numbers []int
+		done <- collector.CollectAll(ctx, "list", nil, &numbers)
+	}()
+
+	waitForWaiter(clock)
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
@@ -0,0 +1,170 @@
+package jsonrpc
+
+import (
+	"sort"
+	"strings"
+)
+
+// FieldRenames maps the legacy name of a member to its new name. The key is a
+// path of member names separated by dots, using the legacy names, and the
+// value is the new name of the last member:
+//
+//     jsonrpc.FieldRenames{
+//         "user_name":        "userName",
+//         "address.zip_code": "postalCode",
+//     }
+//
+// A path passes through arrays, so it applies to every element.
+type FieldRenames map[string]string
+
+// CompatRules describe how the members of a method have been renamed. See
+// Compat.
+type CompatRules struct {
+	// Params are renamed from their legacy name to their new name before the
+	// handler is called. A member that is sent with both names keeps the new
+	// one.
+	Params FieldRenames
+
+	// Result members are sent under both their new and their legacy name, so
+	// that old clients keep working while new clients move to the new name.
+	Result FieldRenames
+}
+
+// Compat returns a middleware that applies the rules at the boundary of the
+// server, so the handler (and its params and result structs) only uses the
+// new names while clients that send or expect the legacy names keep working.
+// Use it with Use for rules that apply to every method, or with SetHandler or
+// a HandlerGroup for a single method:
+//
+//     server.Use(jsonrpc.Compat(jsonrpc.CompatRules{
+//         Params: jsonrpc.FieldRenames{"api_key": "apiKey"},
+//     }))
+//
+//     server.SetHandler("user.update", jsonrpc.Compat(jsonrpc.CompatRules{
+//         Params: jsonrpc.FieldRenames{"user_name": "userName"},
+//         Result: jsonrpc.FieldRenames{"user_name": "userName"},
+//     })(updateUser))
+//
+// Params that are an array are treated as an array of objects. A result that
+// is not a JSON object (or array of objects) is sent unchanged.
+func Compat(rules CompatRules) Middleware {
+	params := sortedRenames(rules.Params)
+	result := sortedRenames(rules.Result)
+
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			if len(params) > 0 && request.Params() != nil {
+				if value, err := toJSONValue(request.Params()); err == nil {
+					for _, rename := range params {
+						renameMember(value, rename.path, rename.name, false)
+					}
+
+					request = &paramsRequest{request, value}
+				}
+			}
+
+			response := next(request)
+			if len(result) == 0 || response == nil || response.ErrorCode() != Success ||
+				response.Result() == nil {
+				return response
+			}
+
+			value, err := toJSONValue(response.Result())
+			if err != nil {
+				return response
+			}
+
+			for _, rename := range result {
+				renameMember(value, rename.newPath, rename.path[len(rename.path)-1], true)
+			}
+
+			copied := copyResponse(response)
+			copied.ResponseResult = value
+			copied.extensions = response.Extensions()
+
+			return copied
+		}
+	}
+}
+
+// fieldRename is a single rule of FieldRenames.
+type fieldRename struct {
+	// path uses the legacy names and newPath the new names.
+	path, newPath []string
+	name          string
+}
+
+// sortedRenames returns the renames with the deepest paths first, so that the
+// members of an object are renamed before the object itself.
+func sortedRenames(renames FieldRenames) []fieldRename {
+	sorted := make([]fieldRename, 0, len(renames))
+	for path, name := range renames {
+		rename := fieldRename{path: strings.Split(path, "."), name: name}
+
+		for i := range rename.path {
+			segment := rename.path[i]
+			if i == len(rename.path)-1 {
+				segment = name
+			} else if parent, ok := renames[strings.Join(rename.path[:i+1], ".")]; ok {
+				segment = parent
+			}
+			rename.newPath = append(rename.newPath, segment)
+		}
+
+		sorted = append(sorted, rename)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i].path) != len(sorted[j].path) {
+			return len(sorted[i].path) > len(sorted[j].path)
+		}
+
+		return strings.Join(sorted[i].path, ".") < strings.Join(sorted[j].path, ".")
+	})
+
+	return sorted
+}
+
+// renameMember gives the member at path the name, which is in the same object.
+// If keep is true the member keeps its original name as well.
+func renameMember(value interface{}, path []string, name string, keep bool) {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, element := range value {
+			renameMember(element, path, name, keep)
+		}
+
+	case map[string]interface{}:
+		if len(path) > 1 {
+			renameMember(value[path[0]], path[1:], name, keep)
+			return
+		}
+
+		member, ok := value[path[0]]
+		if !ok || path[0] == name {
+			return
+		}
+
+		if _, exists := value[name]; !exists {
+			value[name] = member
+		}
+		if !keep {
+			delete(value, path[0])
+		}
+	}
+}
+
+// paramsRequest replaces the params of a request.
+type paramsRequest struct {
+	RequestResponder
+	params interface{}
+}
+
+func (request *paramsRequest) Params() interface{} {
+	return request.params
+}
+
+func (request *paramsRequest) Hash() string {
+	hash, _ := RequestHash(request.Method(), request.params)
+	return hash
+}
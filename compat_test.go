@@ -0,0 +1,80 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type updateUserParams struct {
+	UserName string `json:"userName"`
+	Address  struct {
+		PostalCode string `json:"postalCode"`
+	} `json:"address"`
+}
+
+func updateUser(request jsonrpc.RequestResponder) jsonrpc.Response {
+	var params updateUserParams
+	if response := jsonrpc.BindParams(request, &params); response != nil {
+		return response
+	}
+
+	return request.NewSuccessResponse(params)
+}
+
+func TestCompat(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("user.update", jsonrpc.Compat(jsonrpc.CompatRules{
+		Params: jsonrpc.FieldRenames{
+			"user_name":        "userName",
+			"address.zip_code": "postalCode",
+		},
+		Result: jsonrpc.FieldRenames{
+			"user_name":        "userName",
+			"address.zip_code": "postalCode",
+		},
+	})(updateUser))
+
+	for name, params := range map[string]string{
+		"legacy": `{"user_name": "bob", "address": {"zip_code": "1234"}}`,
+		"new":    `{"userName": "bob", "address": {"postalCode": "1234"}}`,
+		"both":   `{"user_name": "alice", "userName": "bob", "address": {"postalCode": "1234"}}`,
+	} {
+		responses := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "user.update", "params": ` +
+			params + `, "id": 1}`))
+
+		assert.Equal(t, map[string]interface{}{
+			"userName":  "bob",
+			"user_name": "bob",
+			"address": map[string]interface{}{
+				"postalCode": "1234",
+				"zip_code":   "1234",
+			},
+		}, responses[0].Result(), name)
+	}
+}
+
+func TestCompat_Global(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("echo", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params())
+	})
+	server.Use(jsonrpc.Compat(jsonrpc.CompatRules{
+		Params: jsonrpc.FieldRenames{
+			"customer":      "user",
+			"customer.mail": "email",
+		},
+	}))
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "echo", "params": [{"customer": {"mail": "a@b.c"}}, 5], "id": 1},
+		{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 2}
+	]`))
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"user": map[string]interface{}{"email": "a@b.c"}},
+		5.0,
+	}, responses[0].Result())
+	assert.Equal(t, 19.0, responses[1].Result())
+}
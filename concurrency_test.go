@@ -0,0 +1,272 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// These tests are most useful when run with the race detector:
+//
+//     go test -race -run Concurrent ./...
+
+const (
+	stressWorkers    = 16
+	stressIterations = 200
+)
+
+func TestSimpleServer_ConcurrentHandle(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "get_data", Deprecated: "use sum"})
+	server.SetResponseSigner(jsonrpc.HMACSigner("secret"))
+	server.SetExchangeBuffer(jsonrpc.NewExchangeBuffer(8, nil))
+
+	batch := []byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "1"},
+		{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+		{"jsonrpc": "2.0", "method": "get_data", "id": "9"},
+		{"jsonrpc": "2.0", "method": "panic", "id": "10"}
+	]`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations; j++ {
+				responses := server.Handle(batch)
+				if !assert.Len(t, responses, 3) {
+					return
+				}
+			}
+		}()
+	}
+
+	// Mutate and inspect the server while it is busy.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for j := 0; j < stressIterations; j++ {
+			name := fmt.Sprintf("dynamic_%d", j)
+			server.SetHandler(name, getData)
+			server.SetMethodInfo(jsonrpc.MethodInfo{Name: name})
+			server.GetHandler("sum")
+			server.Methods()
+			server.DeprecatedCalls()
+			server.Uptime()
+			server.SetLenient(j%2 == 0)
+		}
+	}()
+
+	wg.Wait()
+
+	total := uint64(stressWorkers * stressIterations)
+	assert.Equal(t, total, server.TotalPayloads())
+	assert.Equal(t, 4*total, server.TotalRequests())
+	assert.Equal(t, 2*total, server.TotalSuccessResponses())
+	assert.Equal(t, total, server.TotalErrorResponses())
+	assert.Equal(t, total, server.TotalNotificationSuccesses())
+	assert.Equal(t, uint64(0), server.CurrentActiveRequests())
+	assert.Equal(t, map[string]uint64{"get_data": total}, server.DeprecatedCalls())
+	assert.Len(t, server.Methods(), stressIterations+1)
+}
+
+func TestSimpleServer_ConcurrentHandlerReplacement(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("value", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(0)
+	})
+	r := []byte(`{"jsonrpc": "2.0", "method": "value", "id": 1}`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations; j++ {
+				if i == 0 {
+					value := j
+					server.SetHandler("value", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+						return request.NewSuccessResponse(value)
+					})
+					continue
+				}
+
+				responses := server.Handle(r)
+				assert.Equal(t, jsonrpc.Success, responses[0].ErrorCode())
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	responses := server.Handle(r)
+	assert.Equal(t, stressIterations-1, responses[0].Result())
+}
+
+func TestSimpleServer_HandlerMayCallServer(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("register", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		// This would deadlock if the server held its lock during a handler.
+		server.SetHandler("registered", getData)
+		return request.NewSuccessResponse(server.GetHandler("registered") != nil)
+	})
+
+	responses := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "register", "id": 1}`))
+	assert.Equal(t, true, responses[0].Result())
+}
+
+func TestTCPClient_Concurrent(t *testing.T) {
+	_, address := newTCPServer(t, newTestServer())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	shared, err := jsonrpc.DialTCP(ctx, address, jsonrpc.TCPOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer shared.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations; j++ {
+				var sum int
+				if !assert.NoError(t, jsonrpc.CallWith(ctx, shared, "sum", []int{i, j}, &sum)) {
+					return
+				}
+				assert.Equal(t, i+j, sum)
+			}
+		}(i)
+	}
+
+	// Other clients connect, call and disconnect while it is busy, some of
+	// them with a call still in flight.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations/10; j++ {
+				client, err := jsonrpc.DialTCP(ctx, address, jsonrpc.TCPOptions{})
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				var sum int
+				assert.NoError(t, jsonrpc.CallWith(ctx, client, "sum", []int{1, j}, &sum))
+				assert.Equal(t, 1+j, sum)
+
+				done := make(chan error, 1)
+				go func() {
+					_, err := client.Invoke(ctx, "sum", []int{2, j})
+					done <- err
+				}()
+				client.Close()
+				<-done
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestWebSocketConn_Concurrent(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+
+	var ids int64
+	handler.Server.SetHandler("ticks.subscribe", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		conn := jsonrpc.WebSocketConnFromRequest(request)
+		id := atomic.AddInt64(&ids, 1)
+		go func() {
+			for tick := 0; tick < 10; tick++ {
+				conn.Notify(jsonrpc.SubscriptionMethod,
+					jsonrpc.SubscriptionEvent{Subscription: id, Result: tick})
+			}
+		}()
+
+		return request.NewSuccessResponse(id)
+	})
+	handler.Server.SetHandler(jsonrpc.UnsubscribeMethod, func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	wsURL := strings.Replace(url, "http", "ws", 1) + "/ws"
+	shared, err := jsonrpc.DialWebSocket(ctx, wsURL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer shared.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < stressWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations/10; j++ {
+				var sum int
+				if !assert.NoError(t, jsonrpc.CallWith(ctx, shared, "sum", []int{i, j}, &sum)) {
+					return
+				}
+				assert.Equal(t, i+j, sum)
+
+				// Every subscription gets its own events, in order.
+				ticks, subscription, err := jsonrpc.Subscribe[int](ctx, shared, "ticks.subscribe",
+					nil, jsonrpc.SubscriptionBuffer(10))
+				if !assert.NoError(t, err) {
+					return
+				}
+				for tick := 0; tick < 10; tick++ {
+					assert.Equal(t, tick, <-ticks)
+				}
+				assert.NoError(t, subscription.Unsubscribe(ctx))
+			}
+		}(i)
+	}
+
+	// Other clients reconnect while it is busy, and their subscriptions end
+	// with the connection.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < stressIterations/20; j++ {
+				client, err := jsonrpc.DialWebSocket(ctx, wsURL, nil)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				_, subscription, err := jsonrpc.Subscribe[int](ctx, client, "ticks.subscribe", nil)
+				if !assert.NoError(t, err) {
+					client.Close()
+					return
+				}
+
+				client.Close()
+				<-subscription.Done()
+				assert.Error(t, subscription.Err())
+			}
+		}()
+	}
+
+	wg.Wait()
+}
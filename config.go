@@ -0,0 +1,280 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// MethodDisabledErrorType is the ErrorDetails type of the MethodNotFound error
+// sent for a method that has been disabled by the configuration.
+const MethodDisabledErrorType = "urn:jsonrpc:error:method-disabled"
+
+// Config holds the settings that can be changed while the server is running,
+// see ConfigReloader. It is usually read from a JSON file:
+//
+//     {
+//       "lenient": true,
+//       "disabledMethods": ["report.generate"],
+//       "sizeBuckets": [1024, 65536],
+//       "logLevels": {"billing.*": "trace"},
+//       "settings": {"rateLimit": 100}
+//     }
+//
+type Config struct {
+	// Lenient is passed to SetLenient.
+	Lenient bool `json:"lenient"`
+
+	// DisabledMethods are answered with a MethodNotFound error (with the
+	// MethodDisabledErrorType) without calling their handler.
+	DisabledMethods []string `json:"disabledMethods,omitempty"`
+
+	// SizeBuckets is passed to SetSizeBuckets. The histograms are only reset
+	// if the buckets change.
+	SizeBuckets []int `json:"sizeBuckets,omitempty"`
+
+	// LogLevels replace the levels set with SetLogLevel, by pattern. The
+	// levels are names such as "trace" or "debug", see ParseLogLevel.
+	LogLevels map[string]string `json:"logLevels,omitempty"`
+
+	// Settings are for the application. They are not used by this package and
+	// are usually decoded by a ConfigApplier.
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// Validate checks that the configuration can be applied.
+func (config *Config) Validate() error {
+	for _, method := range config.DisabledMethods {
+		if method == "" {
+			return errors.New("Disabled methods must not be empty.")
+		}
+	}
+
+	for i, bound := range config.SizeBuckets {
+		if bound <= 0 || (i > 0 && bound <= config.SizeBuckets[i-1]) {
+			return errors.New("Size buckets must be positive and increasing.")
+		}
+	}
+
+	return validateLogLevels(config.LogLevels)
+}
+
+// LoadConfigFile reads a Config from a JSON file. Unknown fields are an error
+// to catch typos in the file.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	config := new(Config)
+	if err := decoder.Decode(config); err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+
+	return config, nil
+}
+
+// ConfigApplier is something that is configured by a Config. When the
+// configuration is reloaded, every applier validates the new configuration
+// before it is applied to any of them, so that a bad configuration is not
+// partly applied.
+type ConfigApplier interface {
+	ValidateConfig(config *Config) error
+	ApplyConfig(config *Config)
+}
+
+// ValidateConfig allows any configuration that passes Config.Validate.
+func (server *SimpleServer) ValidateConfig(config *Config) error {
+	return config.Validate()
+}
+
+// ApplyConfig changes the settings of the server. All of the settings are
+// changed at once, so a request will see either the old or the new
+// configuration.
+func (server *SimpleServer) ApplyConfig(config *Config) {
+	disabled := map[string]bool{}
+	for _, method := range config.DisabledMethods {
+		disabled[method] = true
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.lenient = config.Lenient
+	server.disabledMethods = disabled
+	server.applyLogLevels(config.LogLevels)
+
+	if !equalInts(server.sizeBounds, config.SizeBuckets) {
+		server.sizeMutex.Lock()
+		defer server.sizeMutex.Unlock()
+
+		server.sizeBounds = config.SizeBuckets
+		if len(config.SizeBuckets) == 0 {
+			server.sizeBounds = nil
+		}
+		server.methodSizes = map[string]*MethodSizes{}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ConfigReloader loads the configuration and applies it to the server and the
+// rest of the application without a restart:
+//
+//     reloader := &jsonrpc.ConfigReloader{
+//         Load: func() (*jsonrpc.Config, error) {
+//             return jsonrpc.LoadConfigFile("/etc/myservice.json")
+//         },
+//         Appliers: []jsonrpc.ConfigApplier{server, rateLimiter},
+//         OnError: func(err error) {
+//             log.Printf("config not reloaded: %v", err)
+//         },
+//     }
+//
+//     if err := reloader.Reload(); err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     go reloader.ReloadOnSignal(ctx, syscall.SIGHUP)
+//
+// It is safe for concurrent use.
+type ConfigReloader struct {
+	// Load reads the new configuration.
+	Load func() (*Config, error)
+
+	// Appliers are given each new configuration in order.
+	Appliers []ConfigApplier
+
+	// OnError is called when a reload that was triggered by a signal or a
+	// file change fails. The previous configuration stays in effect.
+	OnError func(err error)
+
+	// Clock is used by WatchFile. SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex   sync.Mutex
+	current *Config
+}
+
+// Reload loads the configuration, validates it with every applier and then
+// applies it. If an error is returned nothing has been applied.
+func (reloader *ConfigReloader) Reload() error {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	config, err := reloader.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	for _, applier := range reloader.Appliers {
+		if err := applier.ValidateConfig(config); err != nil {
+			return err
+		}
+	}
+
+	for _, applier := range reloader.Appliers {
+		applier.ApplyConfig(config)
+	}
+
+	reloader.current = config
+
+	return nil
+}
+
+// Config returns the configuration that was applied last, or nil if none has
+// been applied.
+func (reloader *ConfigReloader) Config() *Config {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	return reloader.current
+}
+
+// ReloadOnSignal reloads the configuration each time one of the signals is
+// received, until the context is done.
+func (reloader *ConfigReloader) ReloadOnSignal(ctx context.Context, signals ...os.Signal) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	defer signal.Stop(received)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-received:
+			reloader.reload()
+		}
+	}
+}
+
+// WatchFile reloads the configuration when the modification time or size of
+// the file changes, until the context is done. The file is checked once every
+// interval.
+func (reloader *ConfigReloader) WatchFile(ctx context.Context, path string,
+	interval time.Duration) {
+	clock := clockOrSystem(reloader.Clock)
+	last, _ := os.Stat(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-clock.After(interval):
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			reloader.onError(err)
+			continue
+		}
+
+		if last != nil && info.ModTime().Equal(last.ModTime()) &&
+			info.Size() == last.Size() {
+			continue
+		}
+
+		last = info
+		reloader.reload()
+	}
+}
+
+func (reloader *ConfigReloader) reload() {
+	if err := reloader.Reload(); err != nil {
+		reloader.onError(err)
+	}
+}
+
+func (reloader *ConfigReloader) onError(err error) {
+	if reloader.OnError != nil {
+		reloader.OnError(err)
+	}
+}
+
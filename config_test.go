@@ -0,0 +1,176 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// recordingApplier remembers the configurations it was given.
+type recordingApplier struct {
+	invalid bool
+	applied []*jsonrpc.Config
+}
+
+func (applier *recordingApplier) ValidateConfig(config *jsonrpc.Config) error {
+	if applier.invalid {
+		return errors.New("Invalid settings.")
+	}
+
+	return nil
+}
+
+func (applier *recordingApplier) ApplyConfig(config *jsonrpc.Config) {
+	applier.applied = append(applier.applied, config)
+}
+
+func writeConfig(t *testing.T, path, contents string) {
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for config, expected := range map[*jsonrpc.Config]string{
+		{}: "",
+		{DisabledMethods: []string{"sum"}, SizeBuckets: []int{10, 100}}:   "",
+		{DisabledMethods: []string{""}}:                                   "Disabled methods must not be empty.",
+		{SizeBuckets: []int{0, 10}}:                                       "Size buckets must be positive and increasing.",
+		{SizeBuckets: []int{100, 10}}:                                     "Size buckets must be positive and increasing.",
+		{LogLevels: map[string]string{"billing.*": "trace", "*": "warn"}}: "",
+		{LogLevels: map[string]string{"billing*": "debug"}}:               "Log level pattern billing* is not valid.",
+		{LogLevels: map[string]string{"billing.*": "loud"}}:               "Log level loud is not valid.",
+	} {
+		err := config.Validate()
+		if expected == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, expected)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{"lenient": true, "disabledMethods": ["sum"],
+		"settings": {"rateLimit": 100}}`)
+
+	config, err := jsonrpc.LoadConfigFile(path)
+	assert.NoError(t, err)
+	assert.True(t, config.Lenient)
+	assert.Equal(t, []string{"sum"}, config.DisabledMethods)
+	assert.Equal(t, `{"rateLimit": 100}`, string(config.Settings))
+
+	writeConfig(t, path, `{"lenent": true}`)
+	_, err = jsonrpc.LoadConfigFile(path)
+	assert.EqualError(t, err, path+`: json: unknown field "lenent"`)
+}
+
+func TestSimpleServer_ApplyConfig(t *testing.T) {
+	server := newTestServer()
+	server.ApplyConfig(&jsonrpc.Config{
+		DisabledMethods: []string{"sum"},
+		SizeBuckets:     []int{10},
+	})
+
+	responses := server.Handle([]byte(
+		`[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},` +
+			`{"jsonrpc":"2.0","method":"subtract","params":[2,1],"id":2}]`))
+	assert.Equal(t, jsonrpc.MethodNotFound, responses[0].ErrorCode())
+	assert.Equal(t, jsonrpc.MethodDisabledErrorType,
+		responses[0].ErrorData().(*jsonrpc.ErrorDetails).Type)
+	assert.Equal(t, 1.0, responses[1].Result())
+	assert.Len(t, server.MethodSizes(), 1)
+
+	// The histograms are kept when the buckets do not change.
+	server.ApplyConfig(&jsonrpc.Config{SizeBuckets: []int{10}})
+	assert.Equal(t, 3.0, server.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))[0].Result())
+	assert.Len(t, server.MethodSizes(), 2)
+
+	server.ApplyConfig(&jsonrpc.Config{})
+	assert.Empty(t, server.MethodSizes())
+}
+
+func TestConfigReloader_Reload(t *testing.T) {
+	server := newTestServer()
+	applier := &recordingApplier{}
+	loaded := &jsonrpc.Config{Lenient: true}
+	reloader := &jsonrpc.ConfigReloader{
+		Load: func() (*jsonrpc.Config, error) {
+			return loaded, nil
+		},
+		Appliers: []jsonrpc.ConfigApplier{server, applier},
+	}
+
+	assert.Nil(t, reloader.Config())
+	assert.NoError(t, reloader.Reload())
+	assert.Equal(t, loaded, reloader.Config())
+	assert.Equal(t, []*jsonrpc.Config{loaded}, applier.applied)
+
+	// Nothing is applied when any applier rejects the configuration.
+	previous := loaded
+	loaded = &jsonrpc.Config{DisabledMethods: []string{"sum"}}
+	applier.invalid = true
+	assert.EqualError(t, reloader.Reload(), "Invalid settings.")
+	assert.Equal(t, previous, reloader.Config())
+	assert.Len(t, applier.applied, 1)
+	assert.Equal(t, 3.0, server.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))[0].Result())
+
+	loaded = &jsonrpc.Config{SizeBuckets: []int{5, 1}}
+	applier.invalid = false
+	assert.EqualError(t, reloader.Reload(),
+		"Size buckets must be positive and increasing.")
+	assert.Len(t, applier.applied, 1)
+}
+
+func TestConfigReloader_WatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{}`)
+
+	server := newTestServer()
+	clock := jsonrpc.NewFakeClock(epoch)
+	errs := make(chan error, 1)
+	reloader := &jsonrpc.ConfigReloader{
+		Load: func() (*jsonrpc.Config, error) {
+			return jsonrpc.LoadConfigFile(path)
+		},
+		Appliers: []jsonrpc.ConfigApplier{server},
+		OnError: func(err error) {
+			errs <- err
+		},
+		Clock: clock,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloader.WatchFile(ctx, path, time.Second)
+		close(done)
+	}()
+
+	// Unchanged files are not reloaded.
+	waitForWaiter(clock)
+	clock.Advance(time.Second)
+	waitForWaiter(clock)
+	assert.Nil(t, reloader.Config())
+
+	writeConfig(t, path, `{"disabledMethods": ["sum"]}`)
+	clock.Advance(time.Second)
+	waitForWaiter(clock)
+	assert.Equal(t, []string{"sum"}, reloader.Config().DisabledMethods)
+
+	writeConfig(t, path, `{"disabledMethods": [""], "lenient": true}`)
+	clock.Advance(time.Second)
+	assert.EqualError(t, <-errs, "Disabled methods must not be empty.")
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,94 @@
+package jsonrpc
+
+import (
+	"context"
+	"io"
+)
+
+// contextStateKey is the State key of the context given to the requests of a
+// payload by StateWithContext. State also returns the context of a request for
+// it, so requests that wrap another one keep its context.
+const contextStateKey = "jsonrpc.context"
+
+// ContextRequest is implemented by the requests created by the server (and by
+// NewRequestResponder), which carry a context. It is not part of Request so
+// that other implementations of it keep working; ContextFromRequest and
+// WithContext accept any request. A request that wraps another one by
+// embedding it does not need to forward these methods, as the context is also
+// returned by State.
+type ContextRequest interface {
+	RequestResponder
+
+	// Context returns the context of the request. It is never nil.
+	Context() context.Context
+
+	// WithContext returns a copy of the request with a different context.
+	WithContext(ctx context.Context) RequestResponder
+}
+
+// ContextFromRequest returns the context of the request, which carries the
+// deadline and cancellation of the transport (such as the HTTP request) and of
+// middleware such as Timeout. context.Background() is returned if the request
+// does not have one.
+//
+// Handlers should pass it on to anything that blocks:
+//
+//     rows, err := db.QueryContext(jsonrpc.ContextFromRequest(request), query)
+func ContextFromRequest(request Request) context.Context {
+	if request, ok := request.(ContextRequest); ok {
+		return request.Context()
+	}
+
+	if ctx, ok := request.State(contextStateKey).(context.Context); ok {
+		return ctx
+	}
+
+	return context.Background()
+}
+
+// WithContext returns a copy of the request with a different context. Like
+// WithState, the original request is not modified.
+func WithContext(request RequestResponder, ctx context.Context) RequestResponder {
+	if request, ok := request.(ContextRequest); ok {
+		return request.WithContext(ctx)
+	}
+
+	return WithState(request, contextStateKey, ctx)
+}
+
+// StateWithContext returns a copy of state that gives every request handled
+// with it the context ctx, for methods that only take a State such as
+// HandleNDJSON. HandleWithContext, ServeFramerWithContext and
+// HandleArenaWithContext take the context themselves.
+func StateWithContext(state State, ctx context.Context) State {
+	copied := make(State, len(state)+1)
+	for key, value := range state {
+		copied[key] = value
+	}
+	copied[contextStateKey] = ctx
+
+	return copied
+}
+
+// HandleWithContext is HandleWithState for a payload whose requests should be
+// cancelled along with ctx, such as when the client disconnects. See
+// ContextFromRequest.
+func (server *SimpleServer) HandleWithContext(ctx context.Context, jsonRequest []byte,
+	state State) Responses {
+	return server.HandleWithState(jsonRequest, StateWithContext(state, ctx))
+}
+
+// ServeFramerWithContext is ServeFramer for a stream whose requests should be
+// cancelled along with ctx. It does not stop reading when ctx is done; close
+// the stream for that.
+func (server *SimpleServer) ServeFramerWithContext(ctx context.Context, framer Framer,
+	state State) error {
+	return server.ServeFramer(framer, StateWithContext(state, ctx))
+}
+
+// HandleArenaWithContext is HandleArena for a payload whose requests should be
+// cancelled along with ctx.
+func (server *SimpleServer) HandleArenaWithContext(ctx context.Context, w io.Writer,
+	jsonRequest []byte, state State) error {
+	return server.HandleArena(w, jsonRequest, StateWithContext(state, ctx))
+}
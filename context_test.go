@@ -0,0 +1,195 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type contextKey struct{}
+
+func TestContextFromRequest(t *testing.T) {
+	request := jsonrpc.NewRequestResponder("2.0", 1, "a", nil)
+	assert.Equal(t, context.Background(), jsonrpc.ContextFromRequest(request))
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	assert.Equal(t, ctx, jsonrpc.ContextFromRequest(jsonrpc.WithContext(request, ctx)))
+	assert.Equal(t, context.Background(), jsonrpc.ContextFromRequest(request))
+}
+
+func TestSimpleServer_HandleWithContext(t *testing.T) {
+	var values []interface{}
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("value", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		values = append(values, jsonrpc.ContextFromRequest(request).Value(contextKey{}),
+			request.State("other"))
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	server.HandleWithContext(ctx, []byte(`[{"jsonrpc":"2.0","method":"value"}]`),
+		jsonrpc.State{"other": 1})
+
+	assert.Equal(t, []interface{}{"value", 1}, values)
+}
+
+func TestTimeout_CancelsContext(t *testing.T) {
+	cancelled := make(chan error, 1)
+	handler := jsonrpc.Timeout(10 * time.Millisecond)(
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			ctx := jsonrpc.ContextFromRequest(request)
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return nil
+		})
+
+	response := handler(jsonrpc.NewRequestResponder("2.0", 1, "slow", nil))
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, response.ErrorCode())
+	assert.Equal(t, context.DeadlineExceeded, <-cancelled)
+}
+
+func TestHTTPServer_Context(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("value", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(
+			jsonrpc.ContextFromRequest(request).Value(contextKey{}))
+	})
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"value","id":1}`)).WithContext(ctx)
+	jsonrpc.NewHTTPServer(server).ServeHTTP(recorder, request)
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"value"}`, recorder.Body.String())
+}
+
+func TestDelegate_Context(t *testing.T) {
+	var value interface{}
+	handler := jsonrpc.Delegate(jsonrpc.InvokerFunc(func(ctx context.Context,
+		method string, params interface{}) (jsonrpc.Response, error) {
+		value = ctx.Value(contextKey{})
+		return jsonrpc.NewSuccessResponse(1, nil), nil
+	}))
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	handler(jsonrpc.WithContext(jsonrpc.NewRequestResponder("2.0", 1, "a", nil), ctx))
+	assert.Equal(t, "value", value)
+}
+
+func TestContextRequest(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	request, ok := jsonrpc.NewRequestResponder("2.0", 1, "a", nil).(jsonrpc.ContextRequest)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, context.Background(), request.Context())
+
+	withContext := request.WithContext(ctx)
+	assert.Equal(t, ctx, jsonrpc.ContextFromRequest(withContext))
+	assert.Equal(t, context.Background(), request.Context())
+
+	// Requests that wrap another one keep its context.
+	wrapped := jsonrpc.WithState(withContext, "other", 1)
+	assert.Equal(t, ctx, jsonrpc.ContextFromRequest(wrapped))
+	other := context.WithValue(context.Background(), contextKey{}, "other")
+	assert.Equal(t, other, jsonrpc.ContextFromRequest(jsonrpc.WithContext(wrapped, other)))
+}
+
+// TestContextFromRequest_Wrapped checks that every request that wraps another
+// one, whether it is made by middleware or by the server, keeps the
+// cancellation of its context.
+func TestContextFromRequest_Wrapped(t *testing.T) {
+	cancelled := func(t *testing.T, name string) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			assert.Equal(t, context.Canceled, jsonrpc.ContextFromRequest(request).Err(), name)
+			return request.NewSuccessResponse(true)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := jsonrpc.WithContext(jsonrpc.NewRequestResponder("2.0", 1, "api.foo",
+		map[string]interface{}{"a": 1}), ctx)
+
+	t.Run("WithState", func(t *testing.T) {
+		cancelled(t, "state")(jsonrpc.WithState(request, "other", 1))
+	})
+
+	t.Run("Compat", func(t *testing.T) {
+		jsonrpc.Compat(jsonrpc.CompatRules{
+			Params: jsonrpc.FieldRenames{"a": "b"},
+		})(cancelled(t, "compat"))(request)
+	})
+
+	t.Run("Route", func(t *testing.T) {
+		api, other := jsonrpc.NewRouter(), jsonrpc.NewRouter()
+		other.Handle("foo", cancelled(t, "route"))
+		assert.NoError(t, api.Route("api", other))
+
+		assert.Equal(t, true, api.Dispatch(request).Result())
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		jsonrpc.Transaction(jsonrpc.TxBeginnerFunc(func() (jsonrpc.Tx, error) {
+			return &fakeTx{}, nil
+		}))(cancelled(t, "transaction"))(request)
+	})
+
+	// The server wraps the request for the id policy, a group and the next
+	// track.
+	for name, setup := range map[string]func(server *jsonrpc.SimpleServer){
+		"id policy": func(server *jsonrpc.SimpleServer) {
+			server.SetIDPolicy(jsonrpc.NormalizedID)
+			server.SetHandler("api.foo", cancelled(t, "id policy"))
+		},
+		"group": func(server *jsonrpc.SimpleServer) {
+			group := server.Group(jsonrpc.Compat(jsonrpc.CompatRules{
+				Params: jsonrpc.FieldRenames{"a": "b"},
+			}))
+			group.SetHandler("api.foo", cancelled(t, "group"))
+		},
+		"track": func(server *jsonrpc.SimpleServer) {
+			server.SetHandler("api.foo", getData)
+			server.SetNextHandler("api.foo", cancelled(t, "track"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := jsonrpc.NewSimpleServer()
+			setup(server)
+
+			responses := server.HandleWithContext(ctx, []byte(
+				`{"jsonrpc":"2.0","method":"api.foo","params":{"a":1},"id":1,"track":"next"}`),
+				nil)
+			if assert.Len(t, responses, 1) {
+				assert.Equal(t, true, responses[0].Result())
+			}
+		})
+	}
+}
+
+func TestSimpleServer_ServeFramerWithContext(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("value", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(
+			jsonrpc.ContextFromRequest(request).Value(contextKey{}))
+	})
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	s := newStream(`{"jsonrpc":"2.0","method":"value","id":1}` + "\n")
+	assert.NoError(t, server.ServeFramerWithContext(ctx,
+		jsonrpc.NewLineFramer(s, jsonrpc.RecoverySkip, 0), nil))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"value"}`+"\n", s.String())
+
+	var buf bytes.Buffer
+	assert.NoError(t, server.HandleArenaWithContext(ctx, &buf,
+		[]byte(`{"jsonrpc":"2.0","method":"value","id":2}`), nil))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":2,"result":"value"}`, buf.String())
+}
@@ -0,0 +1,123 @@
+package jsonrpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ContractViolationError is returned by a ResponseValidator when the result of
+// a successful response does not match the schema expected for the method. It
+// means the server is misbehaving, rather than the request being wrong.
+type ContractViolationError struct {
+	Method     string
+	Violations []FieldViolation
+
+	// Response is the response that was rejected.
+	Response Response
+}
+
+// Error describes every violation, such as
+// `Result of "user.get" violates its schema: name is required`.
+func (err *ContractViolationError) Error() string {
+	return `Result of "` + err.Method + `" violates its schema: ` +
+		joinViolations(err.Violations)
+}
+
+// joinViolations describes the violations as a comma separated list.
+func joinViolations(violations []FieldViolation) string {
+	problems := make([]string, len(violations))
+	for i, violation := range violations {
+		if violation.Field == "" {
+			problems[i] = violation.Message
+		} else {
+			problems[i] = violation.Field + " " + violation.Message
+		}
+	}
+
+	return strings.Join(problems, ", ")
+}
+
+// ResponseValidator is an Invoker that checks the result of every successful
+// response against the schema expected for the method before it is returned.
+// This protects the client from third-party servers that do not honour their
+// contract. Error responses and methods without a schema are not checked.
+//
+//     client := jsonrpc.NewResponseValidator(invoker)
+//     client.SetResultSchema("user.get", &jsonrpc.Schema{
+//         Type:     "object",
+//         Required: []string{"id", "name"},
+//     })
+//
+//     response, err := client.Invoke(ctx, "user.get", params)
+//     if violation, ok := err.(*jsonrpc.ContractViolationError); ok {
+//         // The server is broken.
+//     }
+//
+// It is safe for concurrent use.
+type ResponseValidator struct {
+	invoker Invoker
+	mutex   sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewResponseValidator creates a ResponseValidator that sends requests with
+// invoker.
+func NewResponseValidator(invoker Invoker) *ResponseValidator {
+	return &ResponseValidator{
+		invoker: invoker,
+		schemas: map[string]*Schema{},
+	}
+}
+
+// SetResultSchema sets (or replaces) the expected result of a method. A nil
+// schema stops the method from being checked.
+func (validator *ResponseValidator) SetResultSchema(method string, schema *Schema) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	if schema == nil {
+		delete(validator.schemas, method)
+		return
+	}
+
+	validator.schemas[method] = schema
+}
+
+// SetMethodInfo sets the expected result of each method that has a Result
+// schema, such as the methods published by a server.
+func (validator *ResponseValidator) SetMethodInfo(methods ...MethodInfo) {
+	for _, info := range methods {
+		if info.Result != nil {
+			validator.SetResultSchema(info.Name, info.Result)
+		}
+	}
+}
+
+// Invoke sends the request and returns the response if its result is valid. A
+// *ContractViolationError is returned if the result does not match the schema.
+func (validator *ResponseValidator) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	response, err := validator.invoker.Invoke(ctx, method, params)
+	if err != nil || response.ErrorCode() != Success {
+		return response, err
+	}
+
+	validator.mutex.RLock()
+	schema := validator.schemas[method]
+	validator.mutex.RUnlock()
+
+	if schema == nil {
+		return response, nil
+	}
+
+	if violations := schema.Validate(response.Result()); len(violations) > 0 {
+		return nil, &ContractViolationError{
+			Method:     method,
+			Violations: violations,
+			Response:   response,
+		}
+	}
+
+	return response, nil
+}
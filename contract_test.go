@@ -0,0 +1,61 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestResponseValidator(t *testing.T) {
+	validator := jsonrpc.NewResponseValidator(serverInvoker(newTestServer()))
+	validator.SetMethodInfo(
+		jsonrpc.MethodInfo{Name: "sum", Result: &jsonrpc.Schema{Type: "integer"}},
+		jsonrpc.MethodInfo{Name: "notify_hello"},
+	)
+	validator.SetResultSchema("get_data", &jsonrpc.Schema{
+		Type:  "array",
+		Items: &jsonrpc.Schema{Type: "string"},
+	})
+
+	response, err := validator.Invoke(context.Background(), "sum", []int{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, response.Result())
+
+	// ["hello", 5] does not match.
+	response, err = validator.Invoke(context.Background(), "get_data", nil)
+	assert.Nil(t, response)
+	assert.EqualError(t, err, `Result of "get_data" violates its schema: 1 must be of type string`)
+
+	violation, ok := err.(*jsonrpc.ContractViolationError)
+	if assert.True(t, ok) {
+		assert.Equal(t, "get_data", violation.Method)
+		assert.Equal(t, []jsonrpc.FieldViolation{
+			{Field: "1", Message: "must be of type string"},
+		}, violation.Violations)
+		assert.Equal(t, []interface{}{"hello", 5.0}, violation.Response.Result())
+	}
+
+	// Methods without a schema and errors are not checked.
+	validator.SetResultSchema("get_data", nil)
+	_, err = validator.Invoke(context.Background(), "get_data", nil)
+	assert.NoError(t, err)
+
+	response, err = validator.Invoke(context.Background(), "missing", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.MethodNotFound, response.ErrorCode())
+}
+
+func TestContractViolationError_Error(t *testing.T) {
+	err := &jsonrpc.ContractViolationError{
+		Method: "user.get",
+		Violations: []jsonrpc.FieldViolation{
+			{Field: "", Message: "must be of type object"},
+			{Field: "name", Message: "is required"},
+		},
+	}
+
+	assert.Equal(t, `Result of "user.get" violates its schema: must be of type object, name is required`,
+		err.Error())
+}
@@ -0,0 +1,50 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultServer is the server used by Register and ListenAndServe, for small
+// tools that only need a single server:
+//
+//     jsonrpc.Register("sum", sum)
+//     log.Fatal(jsonrpc.ListenAndServe(":8080"))
+//
+// It is an ordinary SimpleServer, so it can be configured (and is safe for
+// concurrent use) like any other.
+var DefaultServer = NewSimpleServer()
+
+// DefaultClient is the client used by Call and Notify.
+// It has no transport until one is set:
+//
+//     jsonrpc.DefaultClient.SetTransport(&jsonrpc.HTTPTransport{
+//         URL: "http://localhost:8080",
+//     })
+//
+//     var total int
+//     err := jsonrpc.Call(ctx, "sum", []int{1, 2, 4}, &total)
+//
+var DefaultClient = NewClient(nil)
+
+// Register sets the handler of a method on DefaultServer.
+func Register(method string, handler RequestHandler) {
+	DefaultServer.SetHandler(method, handler)
+}
+
+// ListenAndServe serves DefaultServer over HTTP (see HTTPServer) on the
+// address. It always returns a non-nil error.
+func ListenAndServe(address string) error {
+	return http.ListenAndServe(address, NewHTTPServer(DefaultServer))
+}
+
+// Call sends a request with DefaultClient and decodes its result. See
+// CallWith.
+func Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	return CallWith(ctx, DefaultClient, method, params, result)
+}
+
+// Notify sends a notification with DefaultClient.
+func Notify(ctx context.Context, method string, params interface{}) error {
+	return DefaultClient.Notify(ctx, method, params)
+}
@@ -0,0 +1,41 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestDefaults(t *testing.T) {
+	ctx := context.Background()
+	assert.EqualError(t, jsonrpc.Call(ctx, "double", 2, nil), "Client has no transport.")
+
+	notified := make(chan interface{}, 1)
+	jsonrpc.Register("double", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params().(float64) * 2)
+	})
+	jsonrpc.Register("notify", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		notified <- request.Params()
+		return nil
+	})
+	defer jsonrpc.DefaultServer.SetHandler("double", nil)
+	defer jsonrpc.DefaultServer.SetHandler("notify", nil)
+
+	server := httptest.NewServer(jsonrpc.NewHTTPServer(jsonrpc.DefaultServer))
+	defer server.Close()
+
+	jsonrpc.DefaultClient.SetTransport(&jsonrpc.HTTPTransport{URL: server.URL})
+	defer jsonrpc.DefaultClient.SetTransport(nil)
+
+	var result int
+	assert.NoError(t, jsonrpc.Call(ctx, "double", 21, &result))
+	assert.Equal(t, 42, result)
+
+	assert.NoError(t, jsonrpc.Notify(ctx, "notify", "hi"))
+	assert.Equal(t, "hi", <-notified)
+
+	assert.Error(t, jsonrpc.ListenAndServe("invalid address"))
+}
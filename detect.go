@@ -0,0 +1,198 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+)
+
+// FramerDetector recognises a kind of framing from the first byte of a stream
+// and creates the Framer for it. Only the first byte is used, as a client that
+// sends a short message (such as "{}\n") will wait for the response before it
+// sends anything else.
+type FramerDetector struct {
+	// Name describes the framing, such as "json".
+	Name string
+
+	// Match reports whether a stream that starts with first uses this framing.
+	Match func(first byte) bool
+
+	// NewFramer creates the Framer for the stream.
+	NewFramer func(rw io.ReadWriter, recovery Recovery, maxSize int) Framer
+}
+
+// ContentLengthDetector detects a ContentLengthFramer, from the "C" of
+// "Content-Length".
+var ContentLengthDetector = FramerDetector{
+	Name: "content-length",
+	Match: func(first byte) bool {
+		return first == 'C' || first == 'c'
+	},
+	NewFramer: func(rw io.ReadWriter, recovery Recovery, maxSize int) Framer {
+		return NewContentLengthFramer(rw, recovery, maxSize)
+	},
+}
+
+// LengthPrefixDetector detects a LengthPrefixFramer, from the first byte of
+// the length being zero. This is true of every message smaller than 16 MiB.
+var LengthPrefixDetector = FramerDetector{
+	Name: "length-prefix",
+	Match: func(first byte) bool {
+		return first == 0
+	},
+	NewFramer: func(rw io.ReadWriter, recovery Recovery, maxSize int) Framer {
+		return NewLengthPrefixFramer(rw, recovery, maxSize)
+	},
+}
+
+// MessagePackDetector detects a MessagePackFramer, from the first byte being a
+// MessagePack map (a request or response) or array (a batch).
+var MessagePackDetector = FramerDetector{
+	Name: "msgpack",
+	Match: func(first byte) bool {
+		return first >= 0x80 && first <= 0x9f ||
+			first == 0xdc || first == 0xdd || first == 0xde || first == 0xdf
+	},
+	NewFramer: func(rw io.ReadWriter, recovery Recovery, maxSize int) Framer {
+		return NewMessagePackFramer(rw, recovery, maxSize)
+	},
+}
+
+// LineDetector detects a LineFramer, from the first byte being the start of a
+// JSON object or array, or whitespace.
+var LineDetector = FramerDetector{
+	Name: "json",
+	Match: func(first byte) bool {
+		switch first {
+		case '{', '[', ' ', '\t', '\r', '\n':
+			return true
+		}
+
+		return false
+	},
+	NewFramer: func(rw io.ReadWriter, recovery Recovery, maxSize int) Framer {
+		return NewLineFramer(rw, recovery, maxSize)
+	},
+}
+
+// DefaultFramerDetectors are the detectors used by NewDetectingFramer when
+// none are given. They do not overlap, so their order does not matter.
+var DefaultFramerDetectors = []FramerDetector{
+	LineDetector,
+	ContentLengthDetector,
+	LengthPrefixDetector,
+	MessagePackDetector,
+}
+
+// DetectingFramer chooses its framing from the first byte of the stream, so
+// that one port can serve clients that speak different framings:
+//
+//     framer := jsonrpc.NewDetectingFramer(conn, jsonrpc.RecoverySkip, 0)
+//     server.ServeFramer(framer, nil)
+//
+// The framing is detected by the first ReadFrame. Each detector is tried in
+// order and the first that matches is used. A stream that does not match any
+// of them is malformed and cannot be read.
+type DetectingFramer struct {
+	rw        io.ReadWriter
+	reader    *bufio.Reader
+	recovery  Recovery
+	maxSize   int
+	detectors []FramerDetector
+
+	mutex    sync.Mutex
+	framer   Framer
+	detected string
+	failed   *FrameError
+}
+
+// NewDetectingFramer creates a DetectingFramer on the stream. With no
+// detectors DefaultFramerDetectors are used. A maxSize of zero uses
+// DefaultMaxFrameSize.
+func NewDetectingFramer(rw io.ReadWriter, recovery Recovery, maxSize int,
+	detectors ...FramerDetector) *DetectingFramer {
+	if len(detectors) == 0 {
+		detectors = DefaultFramerDetectors
+	}
+
+	return &DetectingFramer{
+		rw:        rw,
+		reader:    bufio.NewReader(rw),
+		recovery:  recovery,
+		maxSize:   maxSize,
+		detectors: detectors,
+	}
+}
+
+// Detected returns the Name of the detected framing, or an empty string if it
+// has not been detected yet.
+func (framer *DetectingFramer) Detected() string {
+	framer.mutex.Lock()
+	defer framer.mutex.Unlock()
+
+	return framer.detected
+}
+
+// ReadFrame detects the framing, if that has not been done yet, and returns
+// the next message.
+func (framer *DetectingFramer) ReadFrame() ([]byte, error) {
+	framer.mutex.Lock()
+	detected, failed := framer.framer, framer.failed
+	framer.mutex.Unlock()
+
+	if failed != nil {
+		return nil, failed
+	}
+
+	if detected == nil {
+		var err error
+		if detected, err = framer.detect(); err != nil {
+			return nil, err
+		}
+	}
+
+	return detected.ReadFrame()
+}
+
+func (framer *DetectingFramer) detect() (Framer, error) {
+	first, err := framer.reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	framer.mutex.Lock()
+	defer framer.mutex.Unlock()
+
+	for _, detector := range framer.detectors {
+		if detector.Match(first[0]) {
+			// The byte that was peeked must still be read by the framer.
+			rw := struct {
+				io.Reader
+				io.Writer
+			}{framer.reader, framer.rw}
+
+			framer.framer = detector.NewFramer(rw, framer.recovery, framer.maxSize)
+			framer.detected = detector.Name
+
+			return framer.framer, nil
+		}
+	}
+
+	framer.failed = &FrameError{Message: "Message framing is not supported."}
+	return nil, framer.failed
+}
+
+// WriteFrame writes the message with the detected framing. It fails if the
+// framing has not been detected yet.
+func (framer *DetectingFramer) WriteFrame(frame []byte) error {
+	framer.mutex.Lock()
+	detected := framer.framer
+	framer.mutex.Unlock()
+
+	if detected == nil {
+		return errors.New("Framing has not been detected yet.")
+	}
+
+	return detected.WriteFrame(frame)
+}
@@ -0,0 +1,92 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestDetectingFramer(t *testing.T) {
+	request := `{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`
+	encoded, err := jsonrpc.JSONToMessagePack([]byte(request))
+	assert.NoError(t, err)
+
+	for input, expected := range map[string]string{
+		"\n" + request + "\n":                  "json",
+		"Content-Length: 54\r\n\r\n" + request: "content-length",
+		lengthPrefixed(request):                "length-prefix",
+		string(encoded):                        "msgpack",
+	} {
+		framer := jsonrpc.NewDetectingFramer(newStream(input), jsonrpc.RecoverySkip, 0)
+		assert.Equal(t, "", framer.Detected())
+		assert.EqualError(t, framer.WriteFrame([]byte(`{}`)),
+			"Framing has not been detected yet.")
+
+		frame, err := framer.ReadFrame()
+		assert.NoError(t, err, expected)
+		assert.JSONEq(t, request, string(frame), expected)
+		assert.Equal(t, expected, framer.Detected())
+
+		_, err = framer.ReadFrame()
+		assert.Equal(t, io.EOF, err, expected)
+	}
+}
+
+func TestDetectingFramer_Unsupported(t *testing.T) {
+	framer := jsonrpc.NewDetectingFramer(newStream(lengthPrefixed(`{}`)), jsonrpc.RecoverySkip, 0,
+		jsonrpc.LineDetector, jsonrpc.ContentLengthDetector)
+
+	assert.Equal(t, []string{"error: Message framing is not supported."}, readFrames(framer))
+	assert.Equal(t, "", framer.Detected())
+
+	// Nothing is read from an empty stream.
+	_, err := jsonrpc.NewDetectingFramer(newStream(""), jsonrpc.RecoverySkip, 0).ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTCPServer_Detectors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpServer := jsonrpc.NewTCPServer(newTestServer())
+	tcpServer.Detectors = jsonrpc.DefaultFramerDetectors
+	go tcpServer.Serve(listener)
+	defer tcpServer.Close()
+
+	request := `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		return conn
+	}
+
+	// Every client uses a different framing on the same port.
+	line := dial()
+	line.Write([]byte(request + "\n"))
+	response, err := bufio.NewReader(line).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}`+"\n", response)
+
+	prefixed := dial()
+	prefixed.Write([]byte(lengthPrefixed(request)))
+	frame, err := jsonrpc.NewLengthPrefixFramer(prefixed, jsonrpc.RecoverySkip, 0).ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}`, string(frame))
+
+	msgpack := dial()
+	framer := jsonrpc.NewMessagePackFramer(msgpack, jsonrpc.RecoverySkip, 0)
+	assert.NoError(t, framer.WriteFrame([]byte(request)))
+	frame, err = framer.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"jsonrpc":"2.0","result":19}`, string(frame))
+}
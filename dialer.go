@@ -0,0 +1,93 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// Dialer makes the connections of a client. *net.Dialer, *tls.Dialer,
+// TCPOptions and the SOCKS5 dialer of golang.org/x/net/proxy all implement it,
+// so a client can be connected through a proxy, with a custom resolver or with
+// any other dialing strategy:
+//
+//     dialer := jsonrpc.DialerFunc(func(ctx context.Context, network,
+//         address string) (net.Conn, error) {
+//         return socks5.DialContext(ctx, network, address)
+//     })
+//
+//     client, err := jsonrpc.Dial(ctx, jsonrpc.NewTLSDialer(dialer, nil),
+//         "tcp", "rpc.example.com:4000")
+//
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DialerFunc is a function that implements Dialer.
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialContext calls f(ctx, network, address).
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// TLSDialer is a Dialer that starts TLS on the connections of another Dialer.
+// Unlike tls.Dialer the connections do not have to be made by a net.Dialer.
+type TLSDialer struct {
+	dialer Dialer
+	config *tls.Config
+}
+
+// NewTLSDialer creates a TLSDialer. A nil dialer uses a net.Dialer. A nil
+// config uses the default configuration. If the config has no ServerName the
+// host of the address is used.
+func NewTLSDialer(dialer Dialer, config *tls.Config) *TLSDialer {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+
+	return &TLSDialer{dialer: dialer, config: config}
+}
+
+// DialContext connects to the address and completes the TLS handshake before
+// it returns. The context is used for both.
+func (dialer *TLSDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := dialer.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	config := dialer.config
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// Dial connects to the address with the dialer and returns a client for the
+// connection. DialTCP and DialUnix are shortcuts for the usual dialers.
+func Dial(ctx context.Context, dialer Dialer, network, address string) (*TCPClient, error) {
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTCPClient(conn), nil
+}
@@ -0,0 +1,87 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestTCPOptions_Dialer(t *testing.T) {
+	_, address := newTCPServer(t, newTestServer())
+
+	var dialed []string
+	options := jsonrpc.TCPOptions{Dialer: jsonrpc.DialerFunc(func(ctx context.Context,
+		network, address string) (net.Conn, error) {
+		dialed = append(dialed, network+" "+address)
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	})}
+
+	client, err := jsonrpc.DialTCP(context.Background(), address, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), client, "subtract",
+		[]int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+	assert.Equal(t, []string{"tcp " + address}, dialed)
+}
+
+func TestTLSDialer(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1)
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0",
+		&tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpServer := jsonrpc.NewTCPServer(newTestServer())
+	go tcpServer.Serve(listener)
+	defer tcpServer.Close()
+
+	// The name in the certificate is resolved by the dialer.
+	resolver := jsonrpc.DialerFunc(func(ctx context.Context, network,
+		address string) (net.Conn, error) {
+		assert.Equal(t, "localhost:4000", address)
+		return (&net.Dialer{}).DialContext(ctx, network, listener.Addr().String())
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	client, err := jsonrpc.Dial(context.Background(),
+		jsonrpc.NewTLSDialer(resolver, &tls.Config{RootCAs: roots}), "tcp", "localhost:4000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), client, "subtract",
+		[]int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+
+	// The certificate is not trusted by default.
+	_, err = jsonrpc.Dial(context.Background(), jsonrpc.NewTLSDialer(resolver, nil),
+		"tcp", "localhost:4000")
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// encryptedRecordPrefix starts every record sealed by an Encryptor.
+const encryptedRecordPrefix = "enc1."
+
+// KeyProvider supplies the keys that encrypt data at rest. Keys are AES keys of
+// 16, 24 or 32 bytes. Each key has an id that is stored with the data, so the
+// current key can be rotated while data written with older keys can still be
+// read.
+type KeyProvider interface {
+	// CurrentKey returns the key that new data is encrypted with.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with the id.
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is a KeyProvider that holds its keys in memory, such as keys read
+// from a secret store when the process starts.
+type KeyRing struct {
+	// Current is the id of the key that new data is encrypted with.
+	Current string
+
+	// Keys are every key that may have been used, by id.
+	Keys map[string][]byte
+}
+
+// CurrentKey returns the Current key.
+func (ring *KeyRing) CurrentKey() (string, []byte, error) {
+	key, err := ring.Key(ring.Current)
+	return ring.Current, key, err
+}
+
+// Key returns one of the Keys.
+func (ring *KeyRing) Key(id string) ([]byte, error) {
+	key, ok := ring.Keys[id]
+	if !ok {
+		return nil, errors.New(`Unknown key "` + id + `".`)
+	}
+
+	return key, nil
+}
+
+// Encryptor seals records, such as the lines of a Journal, with AES-GCM so that
+// files containing customer payloads are encrypted at rest. A sealed record is
+// a single line of text that includes the id of its key.
+type Encryptor struct {
+	keys KeyProvider
+}
+
+// NewEncryptor creates an Encryptor that uses the keys.
+func NewEncryptor(keys KeyProvider) *Encryptor {
+	return &Encryptor{keys: keys}
+}
+
+// Seal encrypts a record with the current key.
+func (encryptor *Encryptor) Seal(record []byte) ([]byte, error) {
+	id, key, err := encryptor.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(id, ".") {
+		return nil, errors.New("Key ids must not contain a '.'.")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, aead.NonceSize(), aead.NonceSize()+len(record)+aead.Overhead())
+	if _, err := rand.Read(sealed); err != nil {
+		return nil, err
+	}
+	sealed = aead.Seal(sealed, sealed, record, []byte(id))
+
+	return []byte(encryptedRecordPrefix + id + "." +
+		base64.RawStdEncoding.EncodeToString(sealed)), nil
+}
+
+// Open decrypts a record that was sealed with Seal.
+func (encryptor *Encryptor) Open(record []byte) ([]byte, error) {
+	if !IsEncryptedRecord(record) {
+		return nil, errors.New("Record is not encrypted.")
+	}
+
+	id, encoded, ok := strings.Cut(string(record[len(encryptedRecordPrefix):]), ".")
+	if !ok {
+		return nil, errors.New("Record cannot be decrypted.")
+	}
+
+	key, err := encryptor.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("Record cannot be decrypted.")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, errors.New("Record cannot be decrypted.")
+	}
+
+	return plain, nil
+}
+
+// IsEncryptedRecord returns true if the record was sealed by an Encryptor.
+func IsEncryptedRecord(record []byte) bool {
+	return bytes.HasPrefix(record, []byte(encryptedRecordPrefix))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Writer returns a writer that seals each Write as a record on its own line,
+// such as the dumps written to the PanicOutput of an ExchangeBuffer:
+//
+//     buffer.PanicOutput = encryptor.Writer(file)
+//
+// Read the records back with Reader.
+func (encryptor *Encryptor) Writer(w io.Writer) io.Writer {
+	return &encryptingWriter{encryptor, w}
+}
+
+type encryptingWriter struct {
+	encryptor *Encryptor
+	w         io.Writer
+}
+
+func (writer *encryptingWriter) Write(p []byte) (int, error) {
+	sealed, err := writer.encryptor.Seal(p)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := writer.w.Write(append(sealed, '\n')); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Reader returns a reader of the records written by Writer, decrypted and
+// joined back together.
+func (encryptor *Encryptor) Reader(r io.Reader) io.Reader {
+	return &decryptingReader{encryptor: encryptor, r: bufio.NewReader(r)}
+}
+
+type decryptingReader struct {
+	encryptor *Encryptor
+	r         *bufio.Reader
+	buffered  []byte
+}
+
+func (reader *decryptingReader) Read(p []byte) (int, error) {
+	for len(reader.buffered) == 0 {
+		line, err := reader.r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			plain, openErr := reader.encryptor.Open(line)
+			if openErr != nil {
+				return 0, openErr
+			}
+			reader.buffered = plain
+		}
+
+		if err != nil && len(reader.buffered) == 0 {
+			return 0, err
+		}
+	}
+
+	n := copy(p, reader.buffered)
+	reader.buffered = reader.buffered[n:]
+
+	return n, nil
+}
@@ -0,0 +1,110 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func newKeyRing() *jsonrpc.KeyRing {
+	return &jsonrpc.KeyRing{
+		Current: "2024-01",
+		Keys: map[string][]byte{
+			"2023-12": bytes.Repeat([]byte{1}, 32),
+			"2024-01": bytes.Repeat([]byte{2}, 32),
+		},
+	}
+}
+
+func TestEncryptor(t *testing.T) {
+	keys := newKeyRing()
+	encryptor := jsonrpc.NewEncryptor(keys)
+
+	sealed, err := encryptor.Seal([]byte(`{"card":"4111"}`))
+	assert.NoError(t, err)
+	assert.True(t, jsonrpc.IsEncryptedRecord(sealed))
+	assert.True(t, strings.HasPrefix(string(sealed), "enc1.2024-01."))
+	assert.NotContains(t, string(sealed), "4111")
+
+	// Records sealed with an older key can still be opened.
+	keys.Current = "2023-12"
+	old, _ := encryptor.Seal([]byte("old"))
+	keys.Current = "2024-01"
+
+	for record, expected := range map[string]string{
+		string(sealed): `{"card":"4111"}`,
+		string(old):    "old",
+	} {
+		plain, err := encryptor.Open([]byte(record))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(plain))
+	}
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-2] ^= 1
+	_, err = encryptor.Open(tampered)
+	assert.EqualError(t, err, "Record cannot be decrypted.")
+
+	_, err = encryptor.Open([]byte(`{"card":"4111"}`))
+	assert.EqualError(t, err, "Record is not encrypted.")
+
+	delete(keys.Keys, "2023-12")
+	_, err = encryptor.Open(old)
+	assert.EqualError(t, err, `Unknown key "2023-12".`)
+}
+
+func TestEncryptor_WriterReader(t *testing.T) {
+	encryptor := jsonrpc.NewEncryptor(newKeyRing())
+
+	var file bytes.Buffer
+	writer := encryptor.Writer(&file)
+	io.WriteString(writer, "first\n")
+	io.WriteString(writer, "second\n")
+	assert.Equal(t, 2, strings.Count(file.String(), "\n"))
+	assert.NotContains(t, file.String(), "first")
+
+	plain, err := io.ReadAll(encryptor.Reader(&file))
+	assert.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(plain))
+}
+
+func TestOpenEncryptedJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.journal")
+	encryptor := jsonrpc.NewEncryptor(newKeyRing())
+
+	// A journal that was not encrypted is encrypted when it is opened.
+	journal := openJournal(t, path)
+	journal.Begin(jsonrpc.NewRequestResponder("2.0", 1, "pay", map[string]interface{}{
+		"card": "first-card",
+	}))
+	journal.Close()
+
+	journal, err := jsonrpc.OpenEncryptedJournal(path, encryptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.Begin(jsonrpc.NewRequestResponder("2.0", 2, "pay", map[string]interface{}{
+		"card": "second-card",
+	}))
+	journal.Close()
+
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	assert.NotContains(t, string(data), "card")
+
+	journal, err = jsonrpc.OpenEncryptedJournal(path, encryptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, journal.InFlight(), 2)
+	journal.Close()
+
+	_, err = jsonrpc.OpenJournal(path)
+	assert.EqualError(t, err, "Journal is encrypted.")
+}
@@ -0,0 +1,142 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RateLimitedErrorType is the ErrorDetails type for requests that were
+// rejected because the client is sending too many requests. The RetryAfter
+// says when the client may try again.
+const RateLimitedErrorType = "urn:jsonrpc:error:rate-limited"
+
+// ErrorDetails is the standard structure for the data member of an error
+// response. It is plain JSON so that clients written in any language can act
+// on an error without parsing the message:
+//
+//     {
+//       "type": "https://example.com/errors/out-of-stock",
+//       "violations": [{"field": "quantity", "message": "must be positive"}],
+//       "retryable": false
+//     }
+//
+type ErrorDetails struct {
+	// Type is a URI that identifies the type of error. It should not change
+	// between occurrences of the same error.
+	Type string `json:"type,omitempty"`
+
+	// Detail is a human-readable explanation specific to this occurrence of
+	// the error.
+	Detail string `json:"detail,omitempty"`
+
+	// Violations describes the individual fields that caused the error.
+	Violations []FieldViolation `json:"violations,omitempty"`
+
+	// Retryable is true when sending the same request again may succeed.
+	Retryable bool `json:"retryable"`
+
+	// RetryAfter is the number of seconds the client should wait before
+	// retrying. Zero means the client may choose.
+	RetryAfter float64 `json:"retryAfter,omitempty"`
+
+	// Stack is the stack trace of a handler that panicked. It is only sent in
+	// debug mode, see SetDebug.
+	Stack string `json:"stack,omitempty"`
+}
+
+// FieldViolation describes a problem with a single field. Field uses dot
+// notation for nested values, such as "address.city" or "items.2.quantity".
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewErrorDetails creates ErrorDetails for the error type URI.
+func NewErrorDetails(typeURI string) *ErrorDetails {
+	return &ErrorDetails{
+		Type: typeURI,
+	}
+}
+
+// WithDetail sets the human-readable explanation of the error.
+func (details *ErrorDetails) WithDetail(detail string) *ErrorDetails {
+	details.Detail = detail
+
+	return details
+}
+
+// WithViolation adds a violation for the field.
+func (details *ErrorDetails) WithViolation(field, message string) *ErrorDetails {
+	details.Violations = append(details.Violations, FieldViolation{
+		Field:   field,
+		Message: message,
+	})
+
+	return details
+}
+
+// WithRetryable sets if the request may be retried.
+func (details *ErrorDetails) WithRetryable(retryable bool) *ErrorDetails {
+	details.Retryable = retryable
+
+	return details
+}
+
+// WithRetryAfter marks the request as retryable after the delay.
+func (details *ErrorDetails) WithRetryAfter(delay time.Duration) *ErrorDetails {
+	details.Retryable = true
+	details.RetryAfter = delay.Seconds()
+
+	return details
+}
+
+// NewResponse creates an error response that contains the details as the
+// error data.
+func (details *ErrorDetails) NewResponse(id interface{}, code int,
+	message string) Response {
+	return NewErrorResponseWithData(id, code, message, details)
+}
+
+// ErrorDetailsFromResponse reads the ErrorDetails from the data of an error
+// response. This works for responses created on the server and responses that
+// have been decoded with NewResponsesFromJSON.
+//
+// nil is returned without an error if the response does not contain any error
+// data.
+func ErrorDetailsFromResponse(response Response) (*ErrorDetails, error) {
+	switch data := response.ErrorData().(type) {
+	case nil:
+		return nil, nil
+
+	case *ErrorDetails:
+		return data, nil
+
+	case ErrorDetails:
+		return &data, nil
+	}
+
+	b, err := json.Marshal(response.ErrorData())
+	if err != nil {
+		return nil, err
+	}
+
+	var details *ErrorDetails
+	err = json.Unmarshal(b, &details)
+	if err != nil {
+		return nil, errors.New("Error data is not ErrorDetails.")
+	}
+
+	return details, nil
+}
+
+// retryDelay returns the RetryAfter of a response, and whether the response
+// says the request may be retried at all.
+func retryDelay(response Response) (time.Duration, bool) {
+	details, err := ErrorDetailsFromResponse(response)
+	if err != nil || details == nil || !details.Retryable {
+		return 0, false
+	}
+
+	return time.Duration(details.RetryAfter * float64(time.Second)), true
+}
@@ -0,0 +1,58 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestErrorDetails(t *testing.T) {
+	details := jsonrpc.NewErrorDetails("https://example.com/errors/invalid").
+		WithViolation("name", "is required").
+		WithViolation("age", "must be positive").
+		WithRetryable(true)
+
+	response := details.NewResponse(1, jsonrpc.InvalidParams, "")
+
+	t.Run("Serialization", func(t *testing.T) {
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,`+
+			`"message":"Invalid params","data":{"type":"https://example.com/errors/invalid",`+
+			`"violations":[{"field":"name","message":"is required"},`+
+			`{"field":"age","message":"must be positive"}],"retryable":true}}}`,
+			response.String())
+	})
+
+	t.Run("FromServerResponse", func(t *testing.T) {
+		actual, err := jsonrpc.ErrorDetailsFromResponse(response)
+
+		assert.NoError(t, err)
+		assert.Equal(t, details, actual)
+	})
+
+	t.Run("FromJSON", func(t *testing.T) {
+		responses, err := jsonrpc.NewResponsesFromJSON(response.Bytes())
+		assert.NoError(t, err)
+
+		actual, err := jsonrpc.ErrorDetailsFromResponse(responses[0])
+
+		assert.NoError(t, err)
+		assert.Equal(t, details, actual)
+	})
+
+	t.Run("NoData", func(t *testing.T) {
+		actual, err := jsonrpc.ErrorDetailsFromResponse(
+			jsonrpc.NewErrorResponse(1, jsonrpc.InternalError, ""))
+
+		assert.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run("OtherData", func(t *testing.T) {
+		actual, err := jsonrpc.ErrorDetailsFromResponse(
+			jsonrpc.NewErrorResponseWithData(1, jsonrpc.InternalError, "", "foo"))
+
+		assert.EqualError(t, err, "Error data is not ErrorDetails.")
+		assert.Nil(t, actual)
+	})
+}
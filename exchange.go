@@ -0,0 +1,287 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Exchange is a payload that was received and the responses sent for it. The
+// request and responses are decoded JSON with the redaction already applied.
+type Exchange struct {
+	Time      time.Time   `json:"time"`
+	Request   interface{} `json:"request"`
+	Responses interface{} `json:"responses"`
+}
+
+// The State key that holds the ExchangeBuffer of a connection.
+const exchangeBufferStateKey = "jsonrpc.exchanges"
+
+// ExchangesMethod is the conventional name of the admin method returned by
+// SimpleServer.ExchangesHandler.
+const ExchangesMethod = "rpc.exchanges"
+
+// ExchangeBuffer keeps the most recent exchanges to help debug what a client
+// actually sent. It is safe for concurrent use. A buffer is created for each
+// connection with SetConnectionExchangeBuffers, or one buffer can be shared by
+// the whole server with SetExchangeBuffer.
+type ExchangeBuffer struct {
+	// PanicOutput, if set, receives a dump of the buffer when a handler
+	// panics. The dump includes the request that caused the panic.
+	PanicOutput io.Writer
+
+	// Clock is used to timestamp exchanges. SystemClock is used if it is nil.
+	Clock Clock
+
+	redactor  Redactor
+	mutex     sync.Mutex
+	exchanges []Exchange
+	next      int
+	full      bool
+}
+
+// NewExchangeBuffer creates a buffer that keeps the last size exchanges. The
+// redactor is applied to every request and response before it is stored, it
+// may be nil.
+func NewExchangeBuffer(size int, redactor Redactor) *ExchangeBuffer {
+	return &ExchangeBuffer{
+		redactor:  redactor,
+		exchanges: make([]Exchange, size),
+	}
+}
+
+// Record adds an exchange to the buffer, replacing the oldest exchange if the
+// buffer is full.
+func (buffer *ExchangeBuffer) Record(request []byte, responses Responses) {
+	exchange := Exchange{
+		Time:      clockOrSystem(buffer.Clock).Now(),
+		Request:   redactJSON(buffer.redactor, request),
+		Responses: redactJSON(buffer.redactor, responses.Bytes()),
+	}
+
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if len(buffer.exchanges) == 0 {
+		return
+	}
+
+	buffer.exchanges[buffer.next] = exchange
+	buffer.next = (buffer.next + 1) % len(buffer.exchanges)
+	if buffer.next == 0 {
+		buffer.full = true
+	}
+}
+
+// Exchanges returns the recorded exchanges, oldest first.
+func (buffer *ExchangeBuffer) Exchanges() []Exchange {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if !buffer.full {
+		return append([]Exchange{}, buffer.exchanges[:buffer.next]...)
+	}
+
+	return append(append([]Exchange{}, buffer.exchanges[buffer.next:]...),
+		buffer.exchanges[:buffer.next]...)
+}
+
+// ServeHTTP writes the recorded exchanges as JSON. It should only be exposed on
+// an admin endpoint.
+func (buffer *ExchangeBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buffer.Exchanges())
+}
+
+// dumpPanic writes the buffer and the request that caused a panic to the
+// PanicOutput.
+func (buffer *ExchangeBuffer) dumpPanic(request Request, recovered interface{}) {
+	if buffer.PanicOutput == nil {
+		return
+	}
+
+	json.NewEncoder(buffer.PanicOutput).Encode(map[string]interface{}{
+		"panic":     fmt.Sprint(recovered),
+		"request":   redactJSON(buffer.redactor, request.Bytes()),
+		"exchanges": buffer.Exchanges(),
+	})
+}
+
+// SetExchangeBuffer will record every payload handled by Handle and
+// HandleWithState in the buffer. Use nil to stop recording.
+func (server *SimpleServer) SetExchangeBuffer(buffer *ExchangeBuffer) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.exchangeBuffer = buffer
+}
+
+// ConnectionExchanges are the exchanges of a connection that is open.
+type ConnectionExchanges struct {
+	// Connection numbers the connections in the order they were opened.
+	Connection uint64 `json:"connection"`
+
+	// Client is the ID of the ClientInfo of the connection, if it has one.
+	Client    string     `json:"client,omitempty"`
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// connectionBuffer is the buffer of an open connection.
+type connectionBuffer struct {
+	connection uint64
+	client     *ClientInfo
+}
+
+// SetConnectionExchangeBuffers will create a buffer with newBuffer for every
+// connection served by ServeFramer, which includes the TCP, Unix, WebSocket and
+// stdio transports. Each buffer only records the exchanges of its connection,
+// so that what a single client sent can be seen, and is dumped to its
+// PanicOutput when a handler called by that client panics:
+//
+//     server.SetConnectionExchangeBuffers(func() *jsonrpc.ExchangeBuffer {
+//         buffer := jsonrpc.NewExchangeBuffer(50, jsonrpc.RedactKeys("password"))
+//         buffer.PanicOutput = os.Stderr
+//         return buffer
+//     })
+//
+// The buffers of the open connections are returned by the ExchangesHandler.
+// Use nil to stop creating buffers for new connections.
+func (server *SimpleServer) SetConnectionExchangeBuffers(newBuffer func() *ExchangeBuffer) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.newConnectionBuffer = newBuffer
+}
+
+// ExchangeBufferFromRequest returns the ExchangeBuffer of the connection that
+// the request was received on, or nil if there is none.
+func ExchangeBufferFromRequest(request Request) *ExchangeBuffer {
+	buffer, _ := request.State(exchangeBufferStateKey).(*ExchangeBuffer)
+	return buffer
+}
+
+// ConnectionExchanges returns the exchanges of every open connection that has a
+// buffer, in the order the connections were opened.
+func (server *SimpleServer) ConnectionExchanges() []ConnectionExchanges {
+	server.mutex.RLock()
+	connections := make([]ConnectionExchanges, 0, len(server.connectionBuffers))
+	buffers := make([]*ExchangeBuffer, 0, len(server.connectionBuffers))
+	for buffer, connection := range server.connectionBuffers {
+		exchanges := ConnectionExchanges{Connection: connection.connection}
+		if connection.client != nil {
+			exchanges.Client = connection.client.ID()
+		}
+
+		connections = append(connections, exchanges)
+		buffers = append(buffers, buffer)
+	}
+	server.mutex.RUnlock()
+
+	for i, buffer := range buffers {
+		connections[i].Exchanges = buffer.Exchanges()
+	}
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].Connection < connections[j].Connection
+	})
+
+	return connections
+}
+
+// ExchangesHandler returns the handler of an admin method, usually registered
+// as ExchangesMethod, that returns the ConnectionExchanges of the open
+// connections. The params may be an object with a "client" to only return the
+// connections of that client:
+//
+//     {"jsonrpc": "2.0", "method": "rpc.exchanges",
+//      "params": {"client": "10.0.0.7"}, "id": 1}
+//
+// The exchanges are redacted by their buffers, but they are still what clients
+// sent, so the method should be registered on a group that requires
+// authorization:
+//
+//     admin.SetHandler(jsonrpc.ExchangesMethod, server.ExchangesHandler())
+func (server *SimpleServer) ExchangesHandler() RequestHandler {
+	return func(request RequestResponder) Response {
+		var client string
+		if request.Params() != nil {
+			params, _ := request.Params().(map[string]interface{})
+			var ok bool
+			if client, ok = params["client"].(string); !ok {
+				return request.NewErrorResponse(InvalidParams, "Client must be a string.")
+			}
+		}
+
+		connections := server.ConnectionExchanges()
+		if client != "" {
+			matching := connections[:0]
+			for _, connection := range connections {
+				if connection.Client == client {
+					matching = append(matching, connection)
+				}
+			}
+			connections = matching
+		}
+
+		return request.NewSuccessResponse(connections)
+	}
+}
+
+// connectionExchanges adds a buffer for a connection to a copy of its state, if
+// the server creates them. The returned function must be called when the
+// connection is closed.
+func (server *SimpleServer) connectionExchanges(state State) (State, func()) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.newConnectionBuffer == nil || state[exchangeBufferStateKey] != nil {
+		return state, func() {}
+	}
+
+	buffer := server.newConnectionBuffer()
+	if buffer == nil {
+		return state, func() {}
+	}
+
+	copied := make(State, len(state)+1)
+	for key, value := range state {
+		copied[key] = value
+	}
+	copied[exchangeBufferStateKey] = buffer
+
+	if server.connectionBuffers == nil {
+		server.connectionBuffers = map[*ExchangeBuffer]connectionBuffer{}
+	}
+	server.connections++
+	client, _ := state[clientInfoStateKey].(*ClientInfo)
+	server.connectionBuffers[buffer] = connectionBuffer{server.connections, client}
+
+	return copied, func() {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		delete(server.connectionBuffers, buffer)
+	}
+}
+
+// recordExchange records a payload and its responses in the buffer of the
+// server and in the buffer of the connection in the state.
+func (server *SimpleServer) recordExchange(state State, jsonRequest []byte,
+	responses Responses) {
+	server.mutex.RLock()
+	exchangeBuffer := server.exchangeBuffer
+	server.mutex.RUnlock()
+
+	if exchangeBuffer != nil {
+		exchangeBuffer.Record(jsonRequest, responses)
+	}
+
+	if buffer, ok := state[exchangeBufferStateKey].(*ExchangeBuffer); ok {
+		buffer.Record(jsonRequest, responses)
+	}
+}
+
@@ -0,0 +1,127 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestExchangeBuffer(t *testing.T) {
+	t.Run("KeepsLatest", func(t *testing.T) {
+		buffer := jsonrpc.NewExchangeBuffer(2, nil)
+		buffer.Record([]byte(`1`), jsonrpc.Responses{})
+		buffer.Record([]byte(`2`), jsonrpc.Responses{})
+		buffer.Record([]byte(`3`), jsonrpc.Responses{})
+
+		exchanges := buffer.Exchanges()
+		assert.Len(t, exchanges, 2)
+		assert.Equal(t, 2.0, exchanges[0].Request)
+		assert.Equal(t, 3.0, exchanges[1].Request)
+	})
+
+	t.Run("NotFull", func(t *testing.T) {
+		buffer := jsonrpc.NewExchangeBuffer(5, nil)
+		buffer.Record([]byte(`{`), jsonrpc.Responses{})
+
+		exchanges := buffer.Exchanges()
+		assert.Len(t, exchanges, 1)
+		assert.Equal(t, "{", exchanges[0].Request)
+		assert.Equal(t, []interface{}{}, exchanges[0].Responses)
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		buffer := jsonrpc.NewExchangeBuffer(5, jsonrpc.RedactKeys("password"))
+		server := newTestServer()
+		server.SetExchangeBuffer(buffer)
+		server.Handle([]byte(
+			`{"jsonrpc": "2.0", "method": "get_data", "params": {"password": "x"}, "id": 1}`))
+
+		exchanges := buffer.Exchanges()
+		assert.Len(t, exchanges, 1)
+		assert.Equal(t, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "get_data",
+			"params":  map[string]interface{}{"password": jsonrpc.Redacted},
+			"id":      1.0,
+		}, exchanges[0].Request)
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1.0,
+			"result":  []interface{}{"hello", 5.0},
+		}}, exchanges[0].Responses)
+	})
+
+	t.Run("PanicDump", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		buffer := jsonrpc.NewExchangeBuffer(5, nil)
+		buffer.PanicOutput = output
+		server := newTestServer()
+		server.SetExchangeBuffer(buffer)
+		server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`))
+		server.Handle([]byte(`{"jsonrpc": "2.0", "method": "panic", "id": 2}`))
+
+		var dump map[string]interface{}
+		assert.NoError(t, json.Unmarshal(output.Bytes(), &dump))
+		assert.Equal(t, "uh-oh!", dump["panic"])
+		assert.Equal(t, "panic", dump["request"].(map[string]interface{})["method"])
+		assert.Len(t, dump["exchanges"], 1)
+	})
+
+	t.Run("ServeHTTP", func(t *testing.T) {
+		buffer := jsonrpc.NewExchangeBuffer(5, nil)
+		buffer.Record([]byte(`1`), jsonrpc.Responses{})
+		recorder := httptest.NewRecorder()
+		buffer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var exchanges []jsonrpc.Exchange
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &exchanges))
+		assert.Len(t, exchanges, 1)
+	})
+	t.Run("Connection", func(t *testing.T) {
+		server := newTestServer()
+		server.SetHandler(jsonrpc.ExchangesMethod, server.ExchangesHandler())
+		server.SetConnectionExchangeBuffers(func() *jsonrpc.ExchangeBuffer {
+			return jsonrpc.NewExchangeBuffer(5, nil)
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.7:1234"
+		extractor, _ := jsonrpc.NewClientExtractor()
+
+		// Payloads that are not part of the connection are not in its buffer.
+		server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`))
+
+		s := newStream(strings.Join([]string{
+			`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 2}`,
+			`{"jsonrpc": "2.0", "method": "rpc.exchanges", "params": {"client": "10.0.0.7"}, "id": 3}`,
+			`{"jsonrpc": "2.0", "method": "rpc.exchanges", "params": {"client": "10.0.0.8"}, "id": 4}`,
+		}, "\n"))
+		err := server.ServeFramer(jsonrpc.NewLineFramer(s, jsonrpc.RecoverySkip, 0),
+			extractor.State(r))
+		assert.NoError(t, err)
+
+		responses := strings.Split(strings.TrimSpace(s.String()), "\n")
+		assert.Len(t, responses, 3)
+
+		var response struct {
+			Result []jsonrpc.ConnectionExchanges
+		}
+		assert.NoError(t, json.Unmarshal([]byte(responses[1]), &response))
+		assert.Len(t, response.Result, 1)
+		assert.Equal(t, "10.0.0.7", response.Result[0].Client)
+		assert.Len(t, response.Result[0].Exchanges, 1)
+		assert.Equal(t, "sum",
+			response.Result[0].Exchanges[0].Request.(map[string]interface{})["method"])
+
+		assert.Equal(t, `{"jsonrpc":"2.0","id":4,"result":[]}`, responses[2])
+
+		// The buffer is dropped when the connection is closed.
+		assert.Empty(t, server.ConnectionExchanges())
+	})
+}
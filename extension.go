@@ -0,0 +1,200 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// The members of a response that are defined by the JSON-RPC spec. These
+// cannot be used as extensions.
+var standardResponseMembers = map[string]bool{
+	"jsonrpc": true,
+	"id":      true,
+	"result":  true,
+	"error":   true,
+}
+
+// The members of a request that are defined by the JSON-RPC spec. Any other
+// member of a decoded request is an extension.
+var standardRequestMembers = map[string]bool{
+	"jsonrpc": true,
+	"method":  true,
+	"params":  true,
+	"id":      true,
+}
+
+// requestExtensionsStateKey holds the extension members of a decoded request.
+const requestExtensionsStateKey = "jsonrpc.extensions"
+
+// WithExtension returns a copy of the response that includes the extra
+// top-level member key. Extensions are not part of the JSON-RPC spec but are
+// commonly used for envelope metadata:
+//
+//     response = jsonrpc.WithExtension(response, "meta", map[string]interface{}{
+//         "elapsedMs": 12,
+//     })
+//
+//     // {"jsonrpc":"2.0","id":1,"result":"foo","meta":{"elapsedMs":12}}
+//
+// A SimpleServer will only send extensions when it is in lenient mode. See
+// SetLenient.
+//
+// The standard members (jsonrpc, id, result and error) cannot be replaced and
+// will be ignored.
+func WithExtension(r Response, key string, value interface{}) Response {
+	if standardResponseMembers[key] {
+		return r
+	}
+
+	extensions := map[string]interface{}{}
+	for k, v := range r.Extensions() {
+		extensions[k] = v
+	}
+	extensions[key] = value
+
+	copied := copyResponse(r)
+	copied.extensions = extensions
+
+	return copied
+}
+
+// Extension returns a single extension member of the response, or nil if it
+// does not exist.
+func Extension(r Response, key string) interface{} {
+	return r.Extensions()[key]
+}
+
+// RequestExtension returns a single extension member of a request that was
+// decoded from JSON, or nil if it does not exist. Clients add extension
+// members with WithRequestExtension.
+func RequestExtension(request Request, key string) interface{} {
+	extensions, _ := request.State(requestExtensionsStateKey).(map[string]interface{})
+
+	return extensions[key]
+}
+
+// withRequestExtensions returns a copy of state that holds the non-standard
+// members of a decoded request, or state itself if there are none.
+func withRequestExtensions(state State, members map[string]interface{}) State {
+	var extensions map[string]interface{}
+	for key, value := range members {
+		if standardRequestMembers[key] {
+			continue
+		}
+
+		if extensions == nil {
+			extensions = map[string]interface{}{}
+		}
+		extensions[key] = value
+	}
+
+	if extensions == nil {
+		return state
+	}
+
+	copied := make(State, len(state)+1)
+	for key, value := range state {
+		copied[key] = value
+	}
+	copied[requestExtensionsStateKey] = extensions
+
+	return copied
+}
+
+// withoutExtensions returns a response that is safe to send to a strict
+// JSON-RPC client.
+func withoutExtensions(r Response) Response {
+	if len(r.Extensions()) == 0 {
+		return r
+	}
+
+	return copyResponse(r)
+}
+
+func copyResponse(r Response) *response {
+	copied := &response{
+		ResponseVersion: r.Version(),
+		ResponseID:      r.ID(),
+		ResponseResult:  r.Result(),
+	}
+
+	if r.ErrorCode() != Success {
+		copied.ResponseError = &errorResponse{
+			Code:    r.ErrorCode(),
+			Message: r.ErrorMessage(),
+			Data:    r.ErrorData(),
+		}
+	}
+
+	return copied
+}
+
+func (response *response) Extensions() map[string]interface{} {
+	return response.extensions
+}
+
+// The response type without any methods so it can be encoded and decoded
+// without recursing into MarshalJSON and UnmarshalJSON.
+type plainResponse response
+
+func (response *response) MarshalJSON() ([]byte, error) {
+	response, err := encodeLazyResult(response)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal((*plainResponse)(response))
+	if err != nil || len(response.extensions) == 0 {
+		return b, err
+	}
+
+	extensions, err := json.Marshal(response.extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both are JSON objects so the extensions can be appended to the end of
+	// the response.
+	var buf bytes.Buffer
+	buf.Write(b[:len(b)-1])
+	buf.WriteByte(',')
+	buf.Write(extensions[1:])
+
+	return buf.Bytes(), nil
+}
+
+func (response *response) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, (*plainResponse)(response))
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	for key, raw := range members {
+		if key == "result" {
+			response.rawResult = raw
+		}
+
+		if standardResponseMembers[key] {
+			continue
+		}
+
+		var value interface{}
+		err = json.Unmarshal(raw, &value)
+		if err != nil {
+			return err
+		}
+
+		if response.extensions == nil {
+			response.extensions = map[string]interface{}{}
+		}
+		response.extensions[key] = value
+	}
+
+	return nil
+}
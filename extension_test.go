@@ -0,0 +1,82 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func handlerWithMeta(request jsonrpc.RequestResponder) jsonrpc.Response {
+	return jsonrpc.WithExtension(request.NewSuccessResponse("foo"), "meta",
+		map[string]interface{}{"page": 2})
+}
+
+func TestWithExtension(t *testing.T) {
+	response := jsonrpc.NewSuccessResponse(1, "foo")
+
+	t.Run("Serialization", func(t *testing.T) {
+		r := jsonrpc.WithExtension(response, "meta", map[string]int{"page": 2})
+		r = jsonrpc.WithExtension(r, "cursor", "abc")
+
+		assert.Equal(t,
+			`{"jsonrpc":"2.0","id":1,"result":"foo","cursor":"abc","meta":{"page":2}}`,
+			r.String())
+	})
+
+	t.Run("OriginalIsNotModified", func(t *testing.T) {
+		jsonrpc.WithExtension(response, "meta", 123)
+
+		assert.Nil(t, response.Extensions())
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"foo"}`, response.String())
+	})
+
+	t.Run("StandardMembersAreIgnored", func(t *testing.T) {
+		r := jsonrpc.WithExtension(response, "result", "bar")
+
+		assert.Equal(t, "foo", r.Result())
+		assert.Nil(t, r.Extensions())
+	})
+
+	t.Run("ErrorResponse", func(t *testing.T) {
+		r := jsonrpc.WithExtension(
+			jsonrpc.NewErrorResponseWithData(1, jsonrpc.InvalidParams, "", "bar"),
+			"meta", true)
+
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,`+
+			`"message":"Invalid params","data":"bar"},"meta":true}`, r.String())
+	})
+
+	t.Run("FromJSON", func(t *testing.T) {
+		responses, err := jsonrpc.NewResponsesFromJSON(
+			[]byte(`{"jsonrpc":"2.0","id":1,"result":"foo","meta":{"page":2}}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", responses[0].Result())
+		assert.Equal(t, map[string]interface{}{"page": 2.0},
+			jsonrpc.Extension(responses[0], "meta"))
+	})
+}
+
+func TestSimpleServer_SetLenient(t *testing.T) {
+	r := `{"jsonrpc": "2.0", "method": "meta", "id": 1}`
+
+	t.Run("Strict", func(t *testing.T) {
+		server := jsonrpc.NewSimpleServer()
+		server.SetHandler("meta", handlerWithMeta)
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":"foo"}]`,
+			responses.String())
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		server := jsonrpc.NewSimpleServer()
+		server.SetLenient(true)
+		server.SetHandler("meta", handlerWithMeta)
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":"foo","meta":{"page":2}}]`,
+			responses.String())
+	})
+}
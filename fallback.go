@@ -0,0 +1,52 @@
+package jsonrpc
+
+// UpstreamErrorType is the ErrorDetails type of the error sent when a request
+// could not be delegated to its upstream server.
+const UpstreamErrorType = "urn:jsonrpc:error:upstream"
+
+// Delegate returns a handler that forwards each request to another server with
+// invoker and sends back the response of that server, with the id of the
+// original request. If the request could not be forwarded a ServerError is
+// sent with the UpstreamErrorType.
+//
+// This allows methods to be moved into a new service without changing any
+// clients:
+//
+//     server.SetHandler("invoice.create", jsonrpc.Delegate(billingService))
+//
+// Notifications are forwarded as calls and the response is discarded. The
+// request is forwarded with its context (see ContextFromRequest).
+func Delegate(invoker Invoker) RequestHandler {
+	return func(request RequestResponder) Response {
+		response, err := invoker.Invoke(ContextFromRequest(request), request.Method(),
+			request.Params())
+		if err != nil {
+			// The error is not sent, as it may describe the internal network.
+			return request.NewErrorResponseWithData(ServerError, "Upstream unavailable",
+				NewErrorDetails(UpstreamErrorType))
+		}
+
+		if response.ErrorCode() != Success {
+			return request.NewErrorResponseWithData(response.ErrorCode(),
+				response.ErrorMessage(), response.ErrorData())
+		}
+
+		return request.NewSuccessResponse(response.Result())
+	}
+}
+
+// SetFallback delegates every request for a method that does not have a
+// handler to another server (see Delegate), rather than responding with
+// MethodNotFound. Handlers always take priority, so methods can be
+// reimplemented locally one at a time. A nil invoker removes the fallback.
+//
+// Methods that have been disabled by ApplyConfig are not delegated.
+func (server *SimpleServer) SetFallback(invoker Invoker) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.fallback = nil
+	if invoker != nil {
+		server.fallback = Delegate(invoker)
+	}
+}
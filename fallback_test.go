@@ -0,0 +1,56 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_SetFallback(t *testing.T) {
+	upstream := jsonrpc.NewSimpleServer()
+	upstream.SetHandler("multiply", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		params := request.Params().([]interface{})
+		return request.NewSuccessResponse(params[0].(float64) * params[1].(float64))
+	})
+	upstream.SetHandler("subtract", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse("upstream")
+	})
+
+	server := newTestServer()
+	server.SetFallback(serverInvoker(upstream))
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc":"2.0","method":"multiply","params":[6,7],"id":"a"},
+		{"jsonrpc":"2.0","method":"subtract","params":[6,7],"id":"b"},
+		{"jsonrpc":"2.0","method":"missing","id":"c"}
+	]`))
+
+	assert.Equal(t, "a", responses[0].ID())
+	assert.Equal(t, 42.0, responses[0].Result())
+
+	// Local handlers take priority.
+	assert.Equal(t, -1.0, responses[1].Result())
+
+	assert.Equal(t, "c", responses[2].ID())
+	assert.Equal(t, jsonrpc.MethodNotFound, responses[2].ErrorCode())
+
+	server.SetFallback(nil)
+	assert.Equal(t, jsonrpc.MethodNotFound, server.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"multiply","params":[6,7],"id":1}`))[0].ErrorCode())
+}
+
+func TestDelegate_Unavailable(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("remote", jsonrpc.Delegate(jsonrpc.InvokerFunc(
+		func(ctx context.Context, method string, params interface{}) (jsonrpc.Response, error) {
+			return nil, errors.New("dial tcp 10.0.0.7:80: connection refused")
+		})))
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"remote","id":1}`))[0]
+	assert.Equal(t, jsonrpc.ServerError, response.ErrorCode())
+	assert.Equal(t, "Upstream unavailable", response.ErrorMessage())
+	assert.Equal(t, jsonrpc.NewErrorDetails(jsonrpc.UpstreamErrorType), response.ErrorData())
+}
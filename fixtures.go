@@ -0,0 +1,199 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FixtureProblem is something wrong with a fixture found by ValidateFixtures.
+type FixtureProblem struct {
+	// File is the path of the fixture.
+	File string
+
+	// Message describes the problem, such as
+	// `request 2: params.name is required`.
+	Message string
+}
+
+// String returns "file: message".
+func (problem FixtureProblem) String() string {
+	return problem.File + ": " + problem.Message
+}
+
+// ValidateFixtures checks every .json file in dir (and its subdirectories)
+// against the methods, such as those returned by Methods or LoadOpenRPC. This
+// catches fixtures that have drifted from the contract of the server, without
+// running it.
+//
+// A fixture is either a request (or batch) or an Exchange, as recorded by an
+// ExchangeBuffer:
+//
+//     {
+//       "request": {"jsonrpc": "2.0", "method": "user.get", "params": {"id": 5}, "id": 1},
+//       "responses": {"jsonrpc": "2.0", "result": {"id": 5, "name": "Bob"}, "id": 1}
+//     }
+//
+// Each request must be valid JSON-RPC 2.0 for a known method, with named params
+// that match the Params schema. Each response must be valid JSON-RPC 2.0 and
+// the result of a successful response must match the Result schema of the
+// method of the request with the same id. Positional params are not checked.
+//
+// An error is only returned if the fixtures cannot be read.
+func ValidateFixtures(dir string, methods []MethodInfo) ([]FixtureProblem, error) {
+	known := map[string]MethodInfo{}
+	for _, info := range methods {
+		known[info.Name] = info
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && filepath.Ext(path) == ".json" {
+			files = append(files, path)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	problems := []FixtureProblem{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range validateFixture(data, known) {
+			problems = append(problems, FixtureProblem{File: file, Message: message})
+		}
+	}
+
+	return problems, nil
+}
+
+func validateFixture(data []byte, methods map[string]MethodInfo) []string {
+	var fixture interface{}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return []string{err.Error()}
+	}
+
+	requests, responses := fixture, interface{}(nil)
+	if exchange, ok := fixture.(map[string]interface{}); ok {
+		if request, ok := exchange["request"]; ok {
+			requests, responses = request, exchange["responses"]
+		}
+	}
+
+	var problems []string
+	methodOfID := map[string]string{}
+
+	for i, value := range fixtureList(requests) {
+		prefix := "request"
+		if _, isBatch := requests.([]interface{}); isBatch {
+			prefix = fmt.Sprintf("request %d", i+1)
+		}
+
+		request, ok := value.(map[string]interface{})
+		if !ok {
+			problems = append(problems, prefix+": must be an object")
+			continue
+		}
+
+		if request["jsonrpc"] != "2.0" {
+			problems = append(problems, prefix+`: jsonrpc must be "2.0"`)
+		}
+
+		method, _ := request["method"].(string)
+		info, known := methods[method]
+		if !known {
+			problems = append(problems, fmt.Sprintf("%s: unknown method %q", prefix, method))
+			continue
+		}
+
+		if id, ok := request["id"]; ok && id != nil {
+			methodOfID[fixtureID(id)] = method
+		}
+
+		if _, positional := request["params"].([]interface{}); positional {
+			continue
+		}
+
+		params := request["params"]
+		if params == nil {
+			params = map[string]interface{}{}
+		}
+
+		for _, violation := range info.Params.Validate(params) {
+			problems = append(problems, prefix+": "+describeViolation("params", violation))
+		}
+	}
+
+	for i, value := range fixtureList(responses) {
+		prefix := "response"
+		if _, isBatch := responses.([]interface{}); isBatch {
+			prefix = fmt.Sprintf("response %d", i+1)
+		}
+
+		response, ok := value.(map[string]interface{})
+		if !ok {
+			problems = append(problems, prefix+": must be an object")
+			continue
+		}
+
+		if response["jsonrpc"] != "2.0" {
+			problems = append(problems, prefix+`: jsonrpc must be "2.0"`)
+		}
+
+		result, hasResult := response["result"]
+		_, hasError := response["error"]
+		if hasResult == hasError {
+			problems = append(problems, prefix+": must have either a result or an error")
+			continue
+		}
+
+		method, ok := methodOfID[fixtureID(response["id"])]
+		if !ok || !hasResult {
+			continue
+		}
+
+		for _, violation := range methods[method].Result.Validate(result) {
+			problems = append(problems, prefix+": "+describeViolation("result", violation))
+		}
+	}
+
+	return problems
+}
+
+// fixtureList returns the members of a batch, or a single value as a list.
+func fixtureList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+
+	case []interface{}:
+		return v
+	}
+
+	return []interface{}{value}
+}
+
+// fixtureID allows ids to be compared whatever their type.
+func fixtureID(id interface{}) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
+
+func describeViolation(root string, violation FieldViolation) string {
+	if violation.Field == "" {
+		return root + " " + violation.Message
+	}
+
+	return root + "." + violation.Field + " " + violation.Message
+}
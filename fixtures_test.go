@@ -0,0 +1,72 @@
+package jsonrpc_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestValidateFixtures(t *testing.T) {
+	methods, err := jsonrpc.LoadOpenRPC(strings.NewReader(testOpenRPC))
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"valid.json": `{
+			"request": {"jsonrpc": "2.0", "method": "user.get", "params": {"id": 5}, "id": 1},
+			"responses": {"jsonrpc": "2.0", "result": {"id": 5, "name": "Bob"}, "id": 1}
+		}`,
+		"positional.json": `{"jsonrpc": "2.0", "method": "user.get", "params": ["x"], "id": 1}`,
+		"notes.txt":       `not a fixture`,
+		"params.json":     `{"jsonrpc": "2.0", "method": "user.get", "params": {"fields": [1]}}`,
+		"batch/mixed.json": `{
+			"request": [
+				{"jsonrpc": "2.0", "method": "user.get", "params": {"id": 5}, "id": "a"},
+				{"jsonrpc": "1.0", "method": "user.delete", "id": "b"},
+				{"jsonrpc": "2.0", "method": "user.get", "params": {"id": 6}, "id": "c"}
+			],
+			"responses": [
+				{"jsonrpc": "2.0", "result": {"id": "5"}, "id": "a"},
+				{"jsonrpc": "2.0", "result": {}, "error": {"code": 1}, "id": "b"},
+				{"jsonrpc": "2.0", "error": {"code": -32000, "message": "Nope"}, "id": "c"}
+			]
+		}`,
+		"broken.json": `{"jsonrpc": `,
+	}
+
+	for name, contents := range fixtures {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		writeConfig(t, path, contents)
+	}
+
+	problems, err := jsonrpc.ValidateFixtures(dir, methods)
+	assert.NoError(t, err)
+
+	var messages []string
+	for _, problem := range problems {
+		rel, _ := filepath.Rel(dir, problem.File)
+		messages = append(messages, jsonrpc.FixtureProblem{File: rel,
+			Message: problem.Message}.String())
+	}
+
+	assert.Equal(t, []string{
+		`batch/mixed.json: request 2: jsonrpc must be "2.0"`,
+		`batch/mixed.json: request 2: unknown method "user.delete"`,
+		`batch/mixed.json: response 1: result.name is required`,
+		`batch/mixed.json: response 1: result.id must be of type integer`,
+		`batch/mixed.json: response 2: must have either a result or an error`,
+		`broken.json: unexpected end of JSON input`,
+		`params.json: request: params.id is required`,
+		`params.json: request: params.fields.0 must be of type string`,
+	}, messages)
+}
+
+func TestValidateFixtures_MissingDir(t *testing.T) {
+	_, err := jsonrpc.ValidateFixtures(filepath.Join(t.TempDir(), "missing"), nil)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,229 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// DefaultStateMember is the extension member that a StateForwarder uses when
+// its member is empty.
+const DefaultStateMember = "state"
+
+// StateCodec serializes a single State value so that it can be sent to
+// another process.
+type StateCodec interface {
+	EncodeState(value interface{}) (json.RawMessage, error)
+	DecodeState(data json.RawMessage) (interface{}, error)
+}
+
+// JSONStateCodec returns a StateCodec that encodes values as JSON and decodes
+// them into a new value of the same type as example. If example is a pointer
+// the decoded value is a pointer as well:
+//
+//     forwarder.Forward(correlationKey, jsonrpc.JSONStateCodec(""))
+//     forwarder.Forward(principalKey, jsonrpc.JSONStateCodec(&Principal{}))
+func JSONStateCodec(example interface{}) StateCodec {
+	return jsonStateCodec{reflect.TypeOf(example)}
+}
+
+type jsonStateCodec struct {
+	t reflect.Type
+}
+
+func (codec jsonStateCodec) EncodeState(value interface{}) (json.RawMessage, error) {
+	return json.Marshal(value)
+}
+
+func (codec jsonStateCodec) DecodeState(data json.RawMessage) (interface{}, error) {
+	if codec.t.Kind() == reflect.Ptr {
+		value := reflect.New(codec.t.Elem())
+		err := json.Unmarshal(data, value.Interface())
+
+		return value.Interface(), err
+	}
+
+	value := reflect.New(codec.t)
+	err := json.Unmarshal(data, value.Interface())
+
+	return value.Elem().Interface(), err
+}
+
+// StateForwarder carries selected State entries, such as the authenticated
+// principal or a correlation id, across a hop to another server. The sending
+// side serializes them into an extension member of the forwarded request and
+// the receiving side restores them into the State of the request:
+//
+//     forwarder := jsonrpc.NewStateForwarder("")
+//     forwarder.Forward(correlationKey, jsonrpc.JSONStateCodec(""))
+//
+//     // On the proxy.
+//     proxy.SetHandler("invoice.create", forwarder.Delegate(billingService))
+//
+//     // On the upstream.
+//     billing.SetStateForwarder(forwarder)
+//
+// Restoring state lets the sender decide what the handlers of the receiver
+// see, so the receiver must only accept forwarded state from trusted peers.
+// Entries that the receiving transport already put in the State take
+// priority over forwarded ones.
+//
+// It is safe for concurrent use.
+type StateForwarder struct {
+	member string
+	mutex  sync.RWMutex
+	codecs map[string]StateCodec
+}
+
+// NewStateForwarder creates a StateForwarder that uses member as the name of
+// the extension member. An empty member uses DefaultStateMember.
+func NewStateForwarder(member string) *StateForwarder {
+	if member == "" {
+		member = DefaultStateMember
+	}
+
+	return &StateForwarder{
+		member: member,
+		codecs: map[string]StateCodec{},
+	}
+}
+
+// Member is the name of the extension member.
+func (forwarder *StateForwarder) Member() string {
+	return forwarder.member
+}
+
+// Forward sets (or replaces) the codec of a State key so that it is forwarded.
+// A nil codec stops the key from being forwarded.
+func (forwarder *StateForwarder) Forward(key string, codec StateCodec) {
+	forwarder.mutex.Lock()
+	defer forwarder.mutex.Unlock()
+
+	if codec == nil {
+		delete(forwarder.codecs, key)
+		return
+	}
+
+	forwarder.codecs[key] = codec
+}
+
+// Encode serializes the forwarded entries that the request has. Nil is
+// returned if it has none.
+func (forwarder *StateForwarder) Encode(request Request) (map[string]json.RawMessage, error) {
+	forwarder.mutex.RLock()
+	defer forwarder.mutex.RUnlock()
+
+	var members map[string]json.RawMessage
+	for key, codec := range forwarder.codecs {
+		value := request.State(key)
+		if value == nil {
+			continue
+		}
+
+		data, err := codec.EncodeState(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if members == nil {
+			members = map[string]json.RawMessage{}
+		}
+		members[key] = data
+	}
+
+	return members, nil
+}
+
+// Restore returns a copy of state with the entries found in the extension
+// member of the raw request. Entries that are not forwarded, or cannot be
+// decoded, are ignored. The state is returned unchanged if there is nothing to
+// restore.
+func (forwarder *StateForwarder) Restore(jsonRequest []byte, state State) State {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(jsonRequest, &envelope); err != nil {
+		return state
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(envelope[forwarder.member], &members); err != nil ||
+		len(members) == 0 {
+		return state
+	}
+
+	forwarder.mutex.RLock()
+	defer forwarder.mutex.RUnlock()
+
+	restored := make(State, len(state)+len(members))
+	for key, data := range members {
+		codec := forwarder.codecs[key]
+		if codec == nil {
+			continue
+		}
+
+		if value, err := codec.DecodeState(data); err == nil {
+			restored[key] = value
+		}
+	}
+
+	for key, value := range state {
+		restored[key] = value
+	}
+
+	return restored
+}
+
+// Delegate returns a handler that forwards requests like the Delegate
+// function, and also sends the forwarded entries of the State of each request
+// in the extension member. See WithRequestExtension for how
+// an Invoker learns about the member.
+func (forwarder *StateForwarder) Delegate(invoker Invoker) RequestHandler {
+	return func(request RequestResponder) Response {
+		members, err := forwarder.Encode(request)
+		if err != nil {
+			return request.NewErrorResponse(InternalError, "")
+		}
+
+		return Delegate(InvokerFunc(func(ctx context.Context, method string,
+			params interface{}) (Response, error) {
+			if members != nil {
+				ctx = WithRequestExtension(ctx, forwarder.member, members)
+			}
+
+			return invoker.Invoke(ctx, method, params)
+		}))(request)
+	}
+}
+
+// SetStateForwarder restores the forwarded State entries of every request that
+// is parsed by the server (see StateForwarder). A nil forwarder disables it.
+func (server *SimpleServer) SetStateForwarder(forwarder *StateForwarder) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.stateForwarder = forwarder
+}
+
+type requestExtensionsKey struct{}
+
+// WithRequestExtension returns a context that asks the Invoker to add the
+// extension member key to the request that it sends. An Invoker that encodes
+// requests itself must include the members returned by RequestExtensions.
+func WithRequestExtension(ctx context.Context, key string, value interface{}) context.Context {
+	extensions := map[string]interface{}{key: value}
+	for k, v := range RequestExtensions(ctx) {
+		if k != key {
+			extensions[k] = v
+		}
+	}
+
+	return context.WithValue(ctx, requestExtensionsKey{}, extensions)
+}
+
+// RequestExtensions returns the extension members added to the context with
+// WithRequestExtension, or nil.
+func RequestExtensions(ctx context.Context) map[string]interface{} {
+	extensions, _ := ctx.Value(requestExtensionsKey{}).(map[string]interface{})
+
+	return extensions
+}
@@ -0,0 +1,112 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// wireInvoker encodes each call as JSON, including the extension members of
+// the context, and sends it to the server, as a transport between processes
+// would.
+func wireInvoker(server *jsonrpc.SimpleServer) jsonrpc.Invoker {
+	return jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		message := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  method,
+			"params":  params,
+			"id":      1,
+		}
+		for key, value := range jsonrpc.RequestExtensions(ctx) {
+			message[key] = value
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+
+		return server.Handle(data)[0], nil
+	})
+}
+
+func newForwarder() *jsonrpc.StateForwarder {
+	forwarder := jsonrpc.NewStateForwarder("")
+	forwarder.Forward("correlation", jsonrpc.JSONStateCodec(""))
+	forwarder.Forward("jsonrpc.client", jsonrpc.JSONStateCodec(&jsonrpc.ClientInfo{}))
+
+	return forwarder
+}
+
+func TestStateForwarder(t *testing.T) {
+	forwarder := newForwarder()
+
+	upstream := jsonrpc.NewSimpleServer()
+	upstream.SetStateForwarder(forwarder)
+	upstream.SetHandler("whoami", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse([]interface{}{
+			request.State("correlation"),
+			jsonrpc.ClientInfoFromRequest(request).ID(),
+			request.State("secret"),
+		})
+	})
+
+	proxy := jsonrpc.NewSimpleServer()
+	proxy.SetHandler("whoami", forwarder.Delegate(wireInvoker(upstream)))
+
+	responses := proxy.HandleWithState(
+		[]byte(`{"jsonrpc":"2.0","method":"whoami","id":7}`),
+		jsonrpc.State{
+			"correlation":    "abc123",
+			"jsonrpc.client": &jsonrpc.ClientInfo{Principal: "alice"},
+			"secret":         "not forwarded",
+		})
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":["abc123","alice",null]}`,
+		responses[0].String())
+}
+
+func TestStateForwarder_Restore(t *testing.T) {
+	forwarder := newForwarder()
+	request := []byte(`{"jsonrpc":"2.0","method":"a","id":1,
+		"state":{"correlation":"abc","jsonrpc.client":{"principal":"mallory"},
+		"other":"ignored"}}`)
+
+	// The receiving transport takes priority.
+	state := forwarder.Restore(request, jsonrpc.State{
+		"jsonrpc.client": &jsonrpc.ClientInfo{Principal: "alice"},
+	})
+	assert.Equal(t, "abc", state["correlation"])
+	assert.Equal(t, "alice", state["jsonrpc.client"].(*jsonrpc.ClientInfo).Principal)
+	assert.Nil(t, state["other"])
+
+	state = forwarder.Restore([]byte(`{"jsonrpc":"2.0","method":"a"}`), nil)
+	assert.Nil(t, state)
+}
+
+type failingCodec struct{}
+
+func (failingCodec) EncodeState(interface{}) (json.RawMessage, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func (failingCodec) DecodeState(json.RawMessage) (interface{}, error) {
+	return nil, errors.New("cannot decode")
+}
+
+func TestStateForwarder_EncodeError(t *testing.T) {
+	forwarder := jsonrpc.NewStateForwarder("meta")
+	forwarder.Forward("key", failingCodec{})
+
+	handler := forwarder.Delegate(wireInvoker(jsonrpc.NewSimpleServer()))
+	response := handler(jsonrpc.NewRequestResponderWithState("2.0", 1, "a", nil,
+		jsonrpc.State{"key": 1}))
+
+	assert.Equal(t, jsonrpc.InternalError, response.ErrorCode())
+	assert.Equal(t, "meta", forwarder.Member())
+}
@@ -0,0 +1,409 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultMaxFrameSize is the largest frame a Framer will read when
+// MaxFrameSize is zero.
+const DefaultMaxFrameSize = 4 << 20
+
+// Framer reads and writes whole JSON-RPC messages on a stream, such as a TCP
+// connection or stdin and stdout. WriteFrame is safe for concurrent use so
+// that notifications can be sent while a response is being written.
+type Framer interface {
+	// ReadFrame returns the next message. A *FrameError is returned for a
+	// message that is malformed. Whether the stream can still be read after
+	// that depends on the Recovery of the framer.
+	ReadFrame() ([]byte, error)
+
+	WriteFrame(frame []byte) error
+}
+
+// Recovery is what a Framer does after it reads a malformed message.
+type Recovery int
+
+const (
+	// RecoverySkip discards the malformed message and continues with the
+	// next one. The peer is sent a ParseError.
+	RecoverySkip Recovery = iota
+
+	// RecoveryClose stops reading, as nothing after a malformed message can
+	// be trusted. Every later ReadFrame returns the same error.
+	RecoveryClose
+)
+
+// FrameError is returned by ReadFrame for a malformed message.
+type FrameError struct {
+	Message string
+
+	// Recovered is true if the framer has skipped to the next message and
+	// ReadFrame may be called again.
+	Recovered bool
+}
+
+func (err *FrameError) Error() string {
+	return err.Message
+}
+
+// frameReader holds what is common to every Framer.
+type frameReader struct {
+	reader   *bufio.Reader
+	recovery Recovery
+	maxSize  int
+	failed   *FrameError
+
+	writeMutex sync.Mutex
+	writer     io.Writer
+}
+
+func newFrameReader(rw io.ReadWriter, recovery Recovery, maxSize int) frameReader {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+
+	return frameReader{
+		reader:   bufio.NewReader(rw),
+		recovery: recovery,
+		maxSize:  maxSize,
+		writer:   rw,
+	}
+}
+
+// fail returns the error for a malformed frame. With RecoverySkip the caller
+// must have already skipped past the frame.
+func (framer *frameReader) fail(message string) error {
+	err := &FrameError{Message: message, Recovered: framer.recovery == RecoverySkip}
+	if !err.Recovered {
+		framer.failed = err
+	}
+
+	return err
+}
+
+// checkFrame returns an error if the frame is not JSON.
+func (framer *frameReader) checkFrame(frame []byte) ([]byte, error) {
+	if !json.Valid(frame) {
+		return nil, framer.fail("Message is not valid JSON.")
+	}
+
+	return frame, nil
+}
+
+func (framer *frameReader) write(parts ...[]byte) error {
+	framer.writeMutex.Lock()
+	defer framer.writeMutex.Unlock()
+
+	for _, part := range parts {
+		if _, err := framer.writer.Write(part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LineFramer reads and writes messages that are each on a single line, also
+// known as NDJSON. Blank lines are ignored. A line that is too long or is not
+// JSON is malformed; skipping it resumes at the next line.
+type LineFramer struct {
+	frameReader
+}
+
+// NewLineFramer creates a LineFramer on the stream. A maxSize of zero uses
+// DefaultMaxFrameSize.
+func NewLineFramer(rw io.ReadWriter, recovery Recovery, maxSize int) *LineFramer {
+	return &LineFramer{newFrameReader(rw, recovery, maxSize)}
+}
+
+// ReadFrame returns the next line.
+func (framer *LineFramer) ReadFrame() ([]byte, error) {
+	if framer.failed != nil {
+		return nil, framer.failed
+	}
+
+	for {
+		line, tooLong, err := framer.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if tooLong {
+			return nil, framer.fail("Message is too large.")
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return framer.checkFrame(line)
+		}
+	}
+}
+
+// readLine reads up to the next newline, discarding anything past the maximum
+// size. The newline at the end of the stream is optional.
+func (framer *LineFramer) readLine() ([]byte, bool, error) {
+	return readLine(framer.reader, framer.maxSize)
+}
+
+// readLine reads up to the next newline from reader. If the line is longer than
+// maxSize it is skipped and true is returned with a nil line.
+func readLine(reader *bufio.Reader, maxSize int) ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(line) > maxSize+1 {
+				line, tooLong = nil, true
+			}
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+
+		case err == io.EOF && (len(line) > 0 || tooLong):
+			return line, tooLong, nil
+
+		case err != nil:
+			return nil, false, err
+		}
+
+		return line, tooLong, nil
+	}
+}
+
+// WriteFrame writes the message followed by a newline. The message must not
+// contain a newline, which is true of anything encoded by encoding/json.
+func (framer *LineFramer) WriteFrame(frame []byte) error {
+	return framer.write(frame, []byte("\n"))
+}
+
+// maxHeaderSize is the longest header line a ContentLengthFramer reads.
+const maxHeaderSize = 8 << 10
+
+// ContentLengthFramer reads and writes messages with a header giving their
+// length, as used by the Language Server Protocol:
+//
+//     Content-Length: 52\r\n
+//     \r\n
+//     {"jsonrpc":"2.0","method":"initialized","params":{}}
+//
+// A message without a valid Content-Length header, with a header line longer
+// than 8 KB or with a body that is not JSON, is malformed. Skipping a bad
+// header resumes at the next line that starts with "Content-Length:".
+type ContentLengthFramer struct {
+	frameReader
+
+	// resync is set after a bad header, so the next read scans for a header.
+	resync bool
+}
+
+// NewContentLengthFramer creates a ContentLengthFramer on the stream. A
+// maxSize of zero uses DefaultMaxFrameSize.
+func NewContentLengthFramer(rw io.ReadWriter, recovery Recovery, maxSize int) *ContentLengthFramer {
+	return &ContentLengthFramer{frameReader: newFrameReader(rw, recovery, maxSize)}
+}
+
+// ReadFrame returns the body of the next message.
+func (framer *ContentLengthFramer) ReadFrame() ([]byte, error) {
+	if framer.failed != nil {
+		return nil, framer.failed
+	}
+
+	length := -1
+	for {
+		data, tooLong, err := readLine(framer.reader, maxHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if tooLong {
+			framer.resync = true
+			return nil, framer.fail("Header too large.")
+		}
+
+		if !bytes.HasSuffix(data, []byte{'\n'}) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		line := strings.TrimRight(string(data), "\r\n")
+		name, value, isHeader := strings.Cut(line, ":")
+
+		if framer.resync {
+			if !isHeader || !strings.EqualFold(name, "Content-Length") {
+				continue
+			}
+			framer.resync = false
+		}
+
+		if line == "" {
+			break
+		}
+
+		if !isHeader {
+			framer.resync = true
+			return nil, framer.fail("Malformed header.")
+		}
+
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				framer.resync = true
+				return nil, framer.fail("Invalid Content-Length.")
+			}
+		}
+	}
+
+	if length < 0 {
+		framer.resync = true
+		return nil, framer.fail("Missing Content-Length.")
+	}
+
+	if length > framer.maxSize {
+		if _, err := framer.reader.Discard(length); err != nil {
+			return nil, err
+		}
+
+		return nil, framer.fail("Message is too large.")
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(framer.reader, frame); err != nil {
+		return nil, err
+	}
+
+	return framer.checkFrame(frame)
+}
+
+// WriteFrame writes the message with a Content-Length header.
+func (framer *ContentLengthFramer) WriteFrame(frame []byte) error {
+	header := "Content-Length: " + strconv.Itoa(len(frame)) + "\r\n\r\n"
+	return framer.write([]byte(header), frame)
+}
+
+// LengthPrefixFramer reads and writes messages that are each preceded by their
+// length in bytes, as a 4 byte big-endian integer. This is cheaper to parse
+// than a line and allows newlines in the message. A message that is too large
+// or is not JSON is malformed; skipping it resumes at the next length.
+type LengthPrefixFramer struct {
+	frameReader
+}
+
+// NewLengthPrefixFramer creates a LengthPrefixFramer on the stream. A maxSize
+// of zero uses DefaultMaxFrameSize.
+func NewLengthPrefixFramer(rw io.ReadWriter, recovery Recovery, maxSize int) *LengthPrefixFramer {
+	return &LengthPrefixFramer{newFrameReader(rw, recovery, maxSize)}
+}
+
+// ReadFrame returns the next message.
+func (framer *LengthPrefixFramer) ReadFrame() ([]byte, error) {
+	if framer.failed != nil {
+		return nil, framer.failed
+	}
+
+	var prefix [4]byte
+	if _, err := io.ReadFull(framer.reader, prefix[:]); err != nil {
+		return nil, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(prefix[:]))
+	if length > int64(framer.maxSize) {
+		if _, err := io.CopyN(io.Discard, framer.reader, length); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		return nil, framer.fail("Message is too large.")
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(framer.reader, frame); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return framer.checkFrame(frame)
+}
+
+// WriteFrame writes the length of the message followed by the message.
+func (framer *LengthPrefixFramer) WriteFrame(frame []byte) error {
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(frame)))
+	return framer.write(prefix[:], frame)
+}
+
+// unexpectedEOF is err, unless the stream ended part way through a message.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// ServeFramer reads messages from the framer and writes the response to each
+// one, until the stream ends. Each message is handled like a payload given to
+// HandleWithState. A malformed message is answered with a ParseError (with a
+// null id) and, if the framer recovered, the next message is read.
+//
+// The exchanges of the stream are recorded in a buffer of its own if the server
+// has SetConnectionExchangeBuffers.
+//
+// It returns nil when the stream ends, otherwise the error that stopped it.
+func (server *SimpleServer) ServeFramer(framer Framer, state State) error {
+	state, closed := server.connectionExchanges(state)
+	defer closed()
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			frameErr, ok := err.(*FrameError)
+			if !ok {
+				return err
+			}
+
+			atomic.AddUint64(&server.totalErrorResponses, 1)
+			response := server.respond(nil, NewErrorResponse(nil, ParseError, frameErr.Message))
+			if err := framer.WriteFrame(response.Bytes()); err != nil {
+				return err
+			}
+
+			if !frameErr.Recovered {
+				return frameErr
+			}
+
+			continue
+		}
+
+		responses := server.HandleWithState(frame, state)
+		if len(responses) == 0 {
+			continue
+		}
+
+		if err := framer.WriteFrame(responseFrame(frame, responses)); err != nil {
+			return err
+		}
+	}
+}
+
+// responseFrame encodes the responses to a frame: as an array if the frame
+// was a batch, otherwise as a single response.
+func responseFrame(frame []byte, responses Responses) []byte {
+	if bytes.TrimSpace(frame)[0] == '[' {
+		return responses.Bytes()
+	}
+
+	return responses[0].Bytes()
+}
@@ -0,0 +1,197 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// stream reads from input and records everything written.
+type stream struct {
+	io.Reader
+	output bytes.Buffer
+}
+
+func newStream(input string) *stream {
+	return &stream{Reader: strings.NewReader(input)}
+}
+
+func (s *stream) Write(p []byte) (int, error) {
+	return s.output.Write(p)
+}
+
+func (s *stream) String() string {
+	return s.output.String()
+}
+
+func readFrames(framer jsonrpc.Framer) []string {
+	var frames []string
+	for {
+		frame, err := framer.ReadFrame()
+		switch err := err.(type) {
+		case nil:
+			frames = append(frames, string(frame))
+
+		case *jsonrpc.FrameError:
+			frames = append(frames, "error: "+err.Message)
+			if !err.Recovered {
+				return frames
+			}
+
+		default:
+			if err != io.EOF {
+				frames = append(frames, "error: "+err.Error())
+			}
+			return frames
+		}
+	}
+}
+
+func TestLineFramer(t *testing.T) {
+	input := "{\"a\":1}\n\n  {\"b\":2}\r\n{\"c\":\n" + strings.Repeat("x", 40) + "\n[3]"
+
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		`{"b":2}`,
+		"error: Message is not valid JSON.",
+		"error: Message is too large.",
+		`[3]`,
+	}, readFrames(jsonrpc.NewLineFramer(newStream(input), jsonrpc.RecoverySkip, 32)))
+
+	framer := jsonrpc.NewLineFramer(newStream(input), jsonrpc.RecoveryClose, 32)
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		`{"b":2}`,
+		"error: Message is not valid JSON.",
+	}, readFrames(framer))
+
+	_, err := framer.ReadFrame()
+	assert.EqualError(t, err, "Message is not valid JSON.")
+}
+
+func TestLineFramer_WriteFrame(t *testing.T) {
+	s := newStream("")
+	framer := jsonrpc.NewLineFramer(s, jsonrpc.RecoverySkip, 0)
+
+	assert.NoError(t, framer.WriteFrame([]byte(`{"a":1}`)))
+	assert.Equal(t, "{\"a\":1}\n", s.String())
+}
+
+func TestContentLengthFramer(t *testing.T) {
+	input := "Content-Length: 7\r\n\r\n{\"a\":1}" +
+		"Content-Type: application/vscode-jsonrpc\r\ncontent-length:7\r\n\r\n{\"b\":2}" +
+		"garbage\r\n{\"lost\":true}\r\nContent-Length: 7\r\n\r\n{\"c\":3}" +
+		"Content-Length: 7\r\n\r\nnotjson" +
+		"Content-Length: x\r\n\r\nContent-Length: 3\r\n\r\n[4]" +
+		"Content-Length: 40\r\n\r\n" + strings.Repeat(" ", 40) +
+		"Content-Length: 10\r\n\r\n{}"
+
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		`{"b":2}`,
+		"error: Malformed header.",
+		`{"c":3}`,
+		"error: Message is not valid JSON.",
+		"error: Invalid Content-Length.",
+		`[4]`,
+		"error: Message is too large.",
+		"error: unexpected EOF",
+	}, readFrames(jsonrpc.NewContentLengthFramer(newStream(input), jsonrpc.RecoverySkip, 32)))
+
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		`{"b":2}`,
+		"error: Malformed header.",
+	}, readFrames(jsonrpc.NewContentLengthFramer(newStream(input), jsonrpc.RecoveryClose, 32)))
+}
+
+func TestContentLengthFramer_WriteFrame(t *testing.T) {
+	s := newStream("")
+	framer := jsonrpc.NewContentLengthFramer(s, jsonrpc.RecoverySkip, 0)
+
+	assert.NoError(t, framer.WriteFrame([]byte(`{"a":1}`)))
+	assert.Equal(t, "Content-Length: 7\r\n\r\n{\"a\":1}", s.String())
+}
+
+// lengthPrefixed prefixes each message with its length.
+func lengthPrefixed(messages ...string) string {
+	var out strings.Builder
+	for _, message := range messages {
+		n := len(message)
+		out.Write([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+		out.WriteString(message)
+	}
+
+	return out.String()
+}
+
+func TestLengthPrefixFramer(t *testing.T) {
+	input := lengthPrefixed(`{"a":1}`, "{\n\"b\":\n2}", "notjson", strings.Repeat(" ", 40), `[3]`) +
+		"\x00\x00\x00\x0a{}"
+
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		"{\n\"b\":\n2}",
+		"error: Message is not valid JSON.",
+		"error: Message is too large.",
+		`[3]`,
+		"error: unexpected EOF",
+	}, readFrames(jsonrpc.NewLengthPrefixFramer(newStream(input), jsonrpc.RecoverySkip, 32)))
+
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		"{\n\"b\":\n2}",
+		"error: Message is not valid JSON.",
+	}, readFrames(jsonrpc.NewLengthPrefixFramer(newStream(input), jsonrpc.RecoveryClose, 32)))
+}
+
+func TestLengthPrefixFramer_WriteFrame(t *testing.T) {
+	s := newStream("")
+	framer := jsonrpc.NewLengthPrefixFramer(s, jsonrpc.RecoverySkip, 0)
+
+	assert.NoError(t, framer.WriteFrame([]byte(`{"a":1}`)))
+	assert.Equal(t, lengthPrefixed(`{"a":1}`), s.String())
+}
+
+func TestSimpleServer_ServeFramer(t *testing.T) {
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+		`{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`,
+		`{"jsonrpc":"2.0","method"`,
+		`[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":2}]`,
+	}, "\n")
+
+	s := newStream(input)
+	err := newTestServer().ServeFramer(jsonrpc.NewLineFramer(s, jsonrpc.RecoverySkip, 0), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}
+{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Message is not valid JSON."}}
+[{"jsonrpc":"2.0","id":2,"result":3}]
+`, s.String())
+
+	s = newStream(input)
+	err = newTestServer().ServeFramer(jsonrpc.NewLineFramer(s, jsonrpc.RecoveryClose, 0), nil)
+	assert.EqualError(t, err, "Message is not valid JSON.")
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":19}
+{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Message is not valid JSON."}}
+`, s.String())
+}
+
+func TestContentLengthFramer_HeaderTooLarge(t *testing.T) {
+	input := "X-Padding: " + strings.Repeat("x", 10<<10) + "\r\n\r\n" +
+		"Content-Length: 7\r\n\r\n{\"a\":1}"
+
+	assert.Equal(t, []string{
+		"error: Header too large.",
+		`{"a":1}`,
+	}, readFrames(jsonrpc.NewContentLengthFramer(newStream(input), jsonrpc.RecoverySkip, 0)))
+
+	assert.Equal(t, []string{
+		"error: Header too large.",
+	}, readFrames(jsonrpc.NewContentLengthFramer(newStream(input), jsonrpc.RecoveryClose, 0)))
+}
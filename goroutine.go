@@ -0,0 +1,202 @@
+package jsonrpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// goScopeKey is the context key of the server that tracks the goroutines
+// started with Go.
+type goScopeKey struct{}
+
+// payloadScopeKey is the context key of the payloadScope of a payload.
+type payloadScopeKey struct{}
+
+// payloadScope keeps track of the handlers of one payload that runWithin
+// stopped waiting for, so that what the payload holds (its memory reservation
+// and arena) is only given back once they have returned.
+type payloadScope struct {
+	mutex    sync.Mutex
+	running  int
+	finished bool
+
+	// release gives back what the payload holds. It is called by finish, or
+	// by the last handler to return after that.
+	release func()
+
+	// arena is the arena of a payload handled by HandleArena.
+	arena *arena
+}
+
+// withPayloadScope returns a copy of state whose requests belong to a new
+// payloadScope.
+func withPayloadScope(state State, release func()) (State, *payloadScope) {
+	scope := &payloadScope{release: release}
+
+	return StateWithContext(state, scope.context(state)), scope
+}
+
+// context returns the context given to the requests of the payload by state,
+// with the scope added.
+func (scope *payloadScope) context(state State) context.Context {
+	ctx, _ := state[contextStateKey].(context.Context)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, payloadScopeKey{}, scope)
+}
+
+func (scope *payloadScope) add() {
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+
+	scope.running++
+}
+
+// done is called when a handler returns.
+func (scope *payloadScope) done() {
+	scope.mutex.Lock()
+	scope.running--
+	last := scope.running == 0 && scope.finished
+	scope.mutex.Unlock()
+
+	if last {
+		scope.release()
+	}
+}
+
+// finish is called once the payload has been handled. release is called now,
+// unless handlers are still running.
+func (scope *payloadScope) finish() {
+	scope.mutex.Lock()
+	scope.finished = true
+	last := scope.running == 0
+	scope.mutex.Unlock()
+
+	if last {
+		scope.release()
+	}
+}
+
+// goroutineGroup counts running goroutines (or requests). Unlike a
+// sync.WaitGroup it may be waited on while goroutines are still being added.
+type goroutineGroup struct {
+	mutex   sync.Mutex
+	running int
+	stopped bool
+
+	// idle is created by wait and closed when nothing is running.
+	idle chan struct{}
+}
+
+func (group *goroutineGroup) add() {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	group.running++
+}
+
+// tryAdd is add, unless stop has been called.
+func (group *goroutineGroup) tryAdd() bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.stopped {
+		return false
+	}
+	group.running++
+
+	return true
+}
+
+// stop makes every tryAdd fail from now on.
+func (group *goroutineGroup) stop() {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	group.stopped = true
+}
+
+func (group *goroutineGroup) done() {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	group.running--
+	if group.running == 0 && group.idle != nil {
+		close(group.idle)
+		group.idle = nil
+	}
+}
+
+// wait returns a channel that is closed once nothing is running.
+func (group *goroutineGroup) wait() <-chan struct{} {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.running == 0 {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+
+	if group.idle == nil {
+		group.idle = make(chan struct{})
+	}
+
+	return group.idle
+}
+
+// Go runs fn in a new goroutine that is tied to the request of ctx, which
+// must come from ContextFromRequest. The context passed to fn is cancelled
+// when the handler returns (or the request is cancelled), so work that fn
+// starts does not outlive the request unless it ignores its context:
+//
+//     jsonrpc.Go(jsonrpc.ContextFromRequest(request), func(ctx context.Context) {
+//         warmCache(ctx, userID)
+//     })
+//
+// The server keeps track of the goroutine so that Wait, and therefore a
+// graceful shutdown, waits for it to return. A panic in fn is logged and
+// recovered rather than crashing the process. If ctx does not belong to a
+// request of a SimpleServer, fn is run in a goroutine that is not tracked and
+// a panic is logged with slog.Default.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	server, _ := ctx.Value(goScopeKey{}).(*SimpleServer)
+	if server != nil {
+		server.goroutines.add()
+	}
+
+	go func() {
+		defer func() {
+			if server != nil {
+				server.goroutines.done()
+			}
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				if server != nil {
+					server.log(slog.LevelError, "Goroutine panicked", nil, "panic", r)
+				} else {
+					slog.Default().Error("Goroutine panicked", "panic", r)
+				}
+			}
+		}()
+
+		fn(ctx)
+	}()
+}
+
+// Wait blocks until every goroutine started with Go has returned, or ctx is
+// done, in which case its error is returned. It should be called during a
+// graceful shutdown once no more requests are being handled.
+func (server *SimpleServer) Wait(ctx context.Context) error {
+	select {
+	case <-server.goroutines.wait():
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestGo(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("background", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		jsonrpc.Go(jsonrpc.ContextFromRequest(request), func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			<-release
+		})
+
+		<-started
+		return request.NewSuccessResponse(true)
+	})
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"background","id":1}`))
+
+	// The goroutine is cancelled when the handler returns.
+	assert.Equal(t, context.Canceled, <-cancelled)
+
+	// Wait does not return until the goroutine has.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.Wait(ctx))
+
+	close(release)
+	assert.NoError(t, server.Wait(context.Background()))
+}
+
+func TestGo_Panic(t *testing.T) {
+	var logs bytes.Buffer
+	server := jsonrpc.NewSimpleServer()
+	server.SetLogger(slog.New(newTestLogger(&logs)))
+	server.SetHandler("panic", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		jsonrpc.Go(jsonrpc.ContextFromRequest(request), func(context.Context) {
+			panic("uh-oh!")
+		})
+
+		return nil
+	})
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"panic"}`))
+	assert.NoError(t, server.Wait(context.Background()))
+	assert.Contains(t, logs.String(), `msg="Goroutine panicked" panic=uh-oh!`)
+}
+
+func TestGo_Untracked(t *testing.T) {
+	done := make(chan struct{})
+	jsonrpc.Go(context.Background(), func(context.Context) {
+		close(done)
+	})
+
+	<-done
+}
+
+// logWriter sends every record that is logged to it.
+type logWriter chan string
+
+func (writer logWriter) Write(p []byte) (int, error) {
+	writer <- string(p)
+	return len(p), nil
+}
+
+func TestGo_UntrackedPanic(t *testing.T) {
+	logged := make(logWriter, 1)
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logged, nil)))
+	defer slog.SetDefault(previous)
+
+	jsonrpc.Go(context.Background(), func(context.Context) {
+		panic("uh-oh!")
+	})
+
+	assert.Contains(t, <-logged, `msg="Goroutine panicked" panic=uh-oh!`)
+}
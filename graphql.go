@@ -0,0 +1,1013 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// GraphQLBridge exposes the methods of a server as GraphQL fields so that they
+// can be consumed by GraphQL clients without a separate gateway.
+//
+// Only methods that have MethodInfo are exposed. ReadOnly methods are fields of
+// the Query type and all other methods are fields of the Mutation type. The
+// named params of the method become the arguments of the field. Names that are
+// not valid in GraphQL have the invalid characters replaced with "_", so
+// "user.get" becomes "user_get". The arguments of a field are sent to the
+// method with the names of its params.
+//
+// The bridge supports a practical subset of GraphQL: operations with
+// variables, aliases, arguments and nested selections. Fragments, directives,
+// subscriptions and introspection are not supported. Schema returns the schema
+// in SDL for tooling that needs it.
+//
+// Like an HTTPServer, the bridge does not read a body larger than
+// DefaultMaxBodySize, and it refuses documents that are nested more than
+// MaxGraphQLDepth deep.
+type GraphQLBridge struct {
+	// ClientExtractor puts the ClientInfo of every HTTP request in the State
+	// of the requests made for its fields. A ClientExtractor without trusted
+	// proxies is used if it is nil.
+	ClientExtractor *ClientExtractor
+
+	server *SimpleServer
+}
+
+// NewGraphQLBridge creates a GraphQL bridge for the methods of server.
+func NewGraphQLBridge(server *SimpleServer) *GraphQLBridge {
+	return &GraphQLBridge{
+		server: server,
+	}
+}
+
+// GraphQLError is a single error in a GraphQL response.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLResponse is the result of executing a GraphQL document.
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// MaxGraphQLDepth is how deeply selections, lists and objects may be nested in
+// a document sent to a GraphQLBridge.
+const MaxGraphQLDepth = 32
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// ServeHTTP handles GraphQL requests sent as a POST with a JSON body, or as a
+// GET with the query in the query string. Mutations must be sent as a POST, so
+// that they cannot be made by a link or an image on another site.
+func (bridge *GraphQLBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request graphQLRequest
+
+	switch r.Method {
+	case http.MethodGet:
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeGraphQL(w, http.StatusBadRequest, GraphQLResponse{
+					Errors: []GraphQLError{{Message: err.Error()}},
+				})
+				return
+			}
+		}
+
+		operation, err := parseGraphQL(request.Query, request.Variables,
+			request.OperationName)
+		if err == nil && operation.kind != "query" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Mutations must be sent as a POST.", http.StatusMethodNotAllowed)
+			return
+		}
+
+	case http.MethodPost:
+		body := http.MaxBytesReader(w, r.Body, DefaultMaxBodySize)
+		if err := json.NewDecoder(body).Decode(&request); err != nil {
+			status := http.StatusBadRequest
+			if errors.As(err, new(*http.MaxBytesError)) {
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			writeGraphQL(w, status, GraphQLResponse{
+				Errors: []GraphQLError{{Message: err.Error()}},
+			})
+			return
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	extractor := bridge.ClientExtractor
+	if extractor == nil {
+		extractor = &ClientExtractor{}
+	}
+
+	response := bridge.execute(request.Query, request.Variables,
+		request.OperationName, StateWithContext(extractor.State(r), r.Context()))
+
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	writeGraphQL(w, status, response)
+}
+
+func writeGraphQL(w http.ResponseWriter, status int, response GraphQLResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Execute runs a GraphQL document. If the document is not valid the Data of the
+// response will be nil.
+func (bridge *GraphQLBridge) Execute(query string,
+	variables map[string]interface{}, operationName string) GraphQLResponse {
+	return bridge.execute(query, variables, operationName, State{})
+}
+
+// execute is Execute with the State for the requests of the fields.
+func (bridge *GraphQLBridge) execute(query string,
+	variables map[string]interface{}, operationName string, state State) GraphQLResponse {
+	operation, err := parseGraphQL(query, variables, operationName)
+	if err != nil {
+		return GraphQLResponse{
+			Errors: []GraphQLError{{Message: err.Error()}},
+		}
+	}
+
+	fields, err := bridge.fields(operation.kind == "query")
+	if err != nil {
+		return GraphQLResponse{
+			Errors: []GraphQLError{{Message: err.Error()}},
+		}
+	}
+
+	data := graphQLObject{}
+	var errs []GraphQLError
+
+	for _, field := range operation.selections {
+		key := field.key()
+		info, ok := fields[field.name]
+		if !ok {
+			data = append(data, graphQLMember{key, nil})
+			errs = append(errs, GraphQLError{
+				Message: fmt.Sprintf("Cannot query field %q on type %q.",
+					field.name, upperFirst(operation.kind)),
+				Path: []interface{}{key},
+			})
+			continue
+		}
+
+		var params interface{}
+		if len(field.args) > 0 {
+			params = graphQLParams(info, field.args)
+		}
+
+		response := bridge.server.call(info.Name, params, state)
+		if response == nil {
+			data = append(data, graphQLMember{key, nil})
+			errs = append(errs, GraphQLError{
+				Message: "No response, the request was sent as a notification.",
+				Path:    []interface{}{key},
+			})
+			continue
+		}
+
+		if response.ErrorCode() != Success {
+			data = append(data, graphQLMember{key, nil})
+			errs = append(errs, GraphQLError{
+				Message: response.ErrorMessage(),
+				Path:    []interface{}{key},
+				Extensions: map[string]interface{}{
+					"code": response.ErrorCode(),
+				},
+			})
+			continue
+		}
+
+		// Normalize the result so that structs can have fields selected.
+		var result interface{}
+		b, err := json.Marshal(response.Result())
+		if err == nil {
+			err = json.Unmarshal(b, &result)
+		}
+		if err != nil {
+			data = append(data, graphQLMember{key, nil})
+			errs = append(errs, GraphQLError{
+				Message: err.Error(),
+				Path:    []interface{}{key},
+			})
+			continue
+		}
+
+		data = append(data, graphQLMember{key, selectGraphQL(result, field.selections)})
+	}
+
+	return GraphQLResponse{
+		Data:   data,
+		Errors: errs,
+	}
+}
+
+// fields returns the GraphQL field names for the Query (or Mutation) type
+// mapped to their methods.
+func (bridge *GraphQLBridge) fields(query bool) (map[string]MethodInfo, error) {
+	var methods []MethodInfo
+	for _, info := range bridge.server.Methods() {
+		if info.ReadOnly == query {
+			methods = append(methods, info)
+		}
+	}
+
+	return graphQLFields(methods)
+}
+
+// graphQLFields maps the GraphQL field names of the methods to the methods. It
+// fails if two of them have the same field name, such as "user.get" and
+// "user_get", or if two params of a method have the same argument name, since
+// one of them could not be called (or set).
+func graphQLFields(methods []MethodInfo) (map[string]MethodInfo, error) {
+	fields := map[string]MethodInfo{}
+	for _, info := range methods {
+		field := graphQLName(info.Name)
+		if other, ok := fields[field]; ok {
+			return nil, fmt.Errorf("Methods %q and %q are both called %q in GraphQL.",
+				other.Name, info.Name, field)
+		}
+
+		if _, err := graphQLArgs(info); err != nil {
+			return nil, err
+		}
+
+		fields[field] = info
+	}
+
+	return fields, nil
+}
+
+// graphQLArgs maps the GraphQL argument names of the params of the method to
+// the names of the params.
+func graphQLArgs(info MethodInfo) (map[string]string, error) {
+	args := map[string]string{}
+	if info.Params == nil {
+		return args, nil
+	}
+
+	for _, name := range sortedProperties(info.Params) {
+		arg := graphQLName(name)
+		if other, ok := args[arg]; ok {
+			return nil, fmt.Errorf("Params %q and %q of %q are both called %q in GraphQL.",
+				other, name, info.Name, arg)
+		}
+
+		args[arg] = name
+	}
+
+	return args, nil
+}
+
+// graphQLParams returns the arguments of a field with the names of the params
+// of the method. Arguments that are not params of the method keep their name.
+func graphQLParams(info MethodInfo, args map[string]interface{}) map[string]interface{} {
+	names, _ := graphQLArgs(info)
+
+	params := map[string]interface{}{}
+	for arg, value := range args {
+		if name, ok := names[arg]; ok {
+			arg = name
+		}
+
+		params[arg] = value
+	}
+
+	return params
+}
+
+// Schema returns the GraphQL schema of the bridge in SDL. Params and results
+// that are not described in detail use the JSON scalar. It fails if two
+// methods have the same name in GraphQL.
+func (bridge *GraphQLBridge) Schema() (string, error) {
+	var queries, mutations []MethodInfo
+	for _, info := range bridge.server.Methods() {
+		if info.ReadOnly {
+			queries = append(queries, info)
+		} else {
+			mutations = append(mutations, info)
+		}
+	}
+
+	for _, methods := range [][]MethodInfo{queries, mutations} {
+		if _, err := graphQLFields(methods); err != nil {
+			return "", err
+		}
+	}
+
+	types := &bytes.Buffer{}
+	buf := &bytes.Buffer{}
+	buf.WriteString("scalar JSON\n")
+
+	writeRoot := func(name string, methods []MethodInfo) {
+		buf.WriteString("\ntype " + name + " {\n")
+		if len(methods) == 0 {
+			// The Query type must have at least one field.
+			buf.WriteString("  _: Boolean\n")
+		}
+
+		for _, info := range methods {
+			field := graphQLName(info.Name)
+			if info.Description != "" {
+				buf.WriteString("  " + strconv.Quote(info.Description) + "\n")
+			}
+
+			buf.WriteString("  " + field)
+			if info.Params != nil && len(info.Params.Properties) > 0 {
+				args := []string{}
+				for _, name := range sortedProperties(info.Params) {
+					args = append(args, graphQLName(name)+": "+
+						graphQLInputType(info.Params.Properties[name],
+							isRequired(info.Params, name)))
+				}
+
+				buf.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+
+			buf.WriteString(": " + graphQLOutputType(types,
+				upperFirst(field)+"Result", info.Result, false) + "\n")
+		}
+
+		buf.WriteString("}\n")
+	}
+
+	writeRoot("Query", queries)
+	if len(mutations) > 0 {
+		writeRoot("Mutation", mutations)
+	}
+
+	buf.Write(types.Bytes())
+
+	return buf.String(), nil
+}
+
+func sortedProperties(schema *Schema) []string {
+	names := []string{}
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func isRequired(schema *Schema, name string) bool {
+	for _, required := range schema.Required {
+		if required == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func graphQLScalar(schema *Schema) string {
+	if schema == nil {
+		return "JSON"
+	}
+
+	switch schema.Type {
+	case "string":
+		return "String"
+	case "integer":
+		return "Int"
+	case "number":
+		return "Float"
+	case "boolean":
+		return "Boolean"
+	}
+
+	return "JSON"
+}
+
+func graphQLInputType(schema *Schema, required bool) string {
+	t := graphQLScalar(schema)
+	if schema != nil && schema.Type == "array" {
+		t = "[" + graphQLInputType(schema.Items, false) + "]"
+	}
+
+	if required {
+		t += "!"
+	}
+
+	return t
+}
+
+// graphQLOutputType returns the type for the schema, writing any object types
+// that it needs to types.
+func graphQLOutputType(types *bytes.Buffer, name string, schema *Schema,
+	required bool) string {
+	t := graphQLScalar(schema)
+
+	if schema != nil && schema.Type == "array" {
+		t = "[" + graphQLOutputType(types, name, schema.Items, false) + "]"
+	}
+
+	if schema != nil && schema.Type == "object" && len(schema.Properties) > 0 {
+		t = name
+		fields := &bytes.Buffer{}
+		for _, property := range sortedProperties(schema) {
+			fields.WriteString("  " + graphQLName(property) + ": " +
+				graphQLOutputType(types, name+upperFirst(property),
+					schema.Properties[property], isRequired(schema, property)) +
+				"\n")
+		}
+
+		types.WriteString("\ntype " + name + " {\n")
+		types.Write(fields.Bytes())
+		types.WriteString("}\n")
+	}
+
+	if required {
+		t += "!"
+	}
+
+	return t
+}
+
+// graphQLName replaces the characters that are not allowed in a GraphQL name.
+func graphQLName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+// selectGraphQL returns the selected fields of value.
+func selectGraphQL(value interface{}, selections []graphQLField) interface{} {
+	if len(selections) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i := range v {
+			items[i] = selectGraphQL(v[i], selections)
+		}
+
+		return items
+
+	case map[string]interface{}:
+		object := graphQLObject{}
+		for _, field := range selections {
+			object = append(object, graphQLMember{
+				field.key(), selectGraphQL(v[field.name], field.selections)})
+		}
+
+		return object
+	}
+
+	return value
+}
+
+// graphQLObject is a JSON object that keeps the order of its members, since
+// GraphQL responses are in the same order as the query.
+type graphQLObject []graphQLMember
+
+type graphQLMember struct {
+	key   string
+	value interface{}
+}
+
+func (object graphQLObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, member := range object {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(member.key)
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+type graphQLOperation struct {
+	kind       string
+	name       string
+	selections []graphQLField
+}
+
+type graphQLField struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	selections []graphQLField
+}
+
+func (field graphQLField) key() string {
+	if field.alias != "" {
+		return field.alias
+	}
+
+	return field.name
+}
+
+type graphQLToken struct {
+	kind  byte // 'n'ame, 'p'unctuator, '0' number, 's'tring or 0 for EOF
+	value string
+}
+
+type graphQLParser struct {
+	tokens    []graphQLToken
+	pos       int
+	variables map[string]interface{}
+
+	// depth is how deeply nested the parser is, see enter.
+	depth int
+}
+
+func lexGraphQL(src string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+		case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+			tokens = append(tokens, graphQLToken{'p', string(c)})
+			i++
+
+		case c == '.' && strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, graphQLToken{'p', "..."})
+			i += 3
+
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'a' && src[i] <= 'z' ||
+				src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, graphQLToken{'n', src[start:i]})
+
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			i++
+			for i < len(src) && strings.IndexByte("0123456789.eE+-", src[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, graphQLToken{'0', src[start:i]})
+
+		case c == '"':
+			start := i
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, errors.New("Unterminated string.")
+			}
+			i++
+
+			value, err := strconv.Unquote(src[start:i])
+			if err != nil {
+				// GraphQL escapes are the same as JSON escapes.
+				err = json.Unmarshal([]byte(src[start:i]), &value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Invalid string %s.", src[start:i])
+			}
+			tokens = append(tokens, graphQLToken{'s', value})
+
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("Unexpected character %q.", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+func parseGraphQL(src string, variables map[string]interface{},
+	operationName string) (*graphQLOperation, error) {
+	tokens, err := lexGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []*graphQLOperation
+	for {
+		parser := &graphQLParser{
+			tokens:    tokens,
+			variables: map[string]interface{}{},
+		}
+		for name, value := range variables {
+			parser.variables[name] = value
+		}
+
+		operation, rest, err := parser.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, operation)
+		if len(rest) == 0 {
+			break
+		}
+		tokens = rest
+	}
+
+	if operationName == "" {
+		if len(operations) > 1 {
+			return nil, errors.New("Must provide operation name if query contains multiple operations.")
+		}
+
+		return operations[0], nil
+	}
+
+	for _, operation := range operations {
+		if operation.name == operationName {
+			return operation, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown operation named %q.", operationName)
+}
+
+func (parser *graphQLParser) peek() graphQLToken {
+	if parser.pos < len(parser.tokens) {
+		return parser.tokens[parser.pos]
+	}
+
+	return graphQLToken{}
+}
+
+func (parser *graphQLParser) next() graphQLToken {
+	token := parser.peek()
+	parser.pos++
+
+	return token
+}
+
+func (parser *graphQLParser) expect(punctuator string) error {
+	token := parser.next()
+	if token.kind != 'p' || token.value != punctuator {
+		return parser.unexpected(token)
+	}
+
+	return nil
+}
+
+// enter is called before parsing something that may be nested, and must be
+// followed by leave. It fails if the document is nested too deeply.
+func (parser *graphQLParser) enter() error {
+	parser.depth++
+	if parser.depth > MaxGraphQLDepth {
+		return errors.New("Document is nested too deeply.")
+	}
+
+	return nil
+}
+
+func (parser *graphQLParser) leave() {
+	parser.depth--
+}
+
+func (parser *graphQLParser) unexpected(token graphQLToken) error {
+	if token.kind == 0 {
+		return errors.New("Unexpected end of document.")
+	}
+
+	return fmt.Errorf("Unexpected %q.", token.value)
+}
+
+// parseOperation parses a single operation and returns the tokens after it.
+func (parser *graphQLParser) parseOperation() (*graphQLOperation, []graphQLToken, error) {
+	operation := &graphQLOperation{kind: "query"}
+
+	if token := parser.peek(); token.kind == 'n' {
+		switch token.value {
+		case "query", "mutation":
+			operation.kind = token.value
+
+		case "subscription", "fragment":
+			return nil, nil, fmt.Errorf("%s is not supported.", upperFirst(token.value))
+
+		default:
+			return nil, nil, parser.unexpected(token)
+		}
+		parser.next()
+
+		if parser.peek().kind == 'n' {
+			operation.name = parser.next().value
+		}
+
+		if token := parser.peek(); token.kind == 'p' && token.value == "(" {
+			if err := parser.parseVariableDefinitions(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	selections, err := parser.parseSelectionSet()
+	if err != nil {
+		return nil, nil, err
+	}
+	operation.selections = selections
+
+	return operation, parser.tokens[parser.pos:], nil
+}
+
+func (parser *graphQLParser) parseVariableDefinitions() error {
+	parser.next()
+
+	for {
+		token := parser.next()
+		if token.kind == 'p' && token.value == ")" {
+			return nil
+		}
+
+		if token.kind != 'p' || token.value != "$" {
+			return parser.unexpected(token)
+		}
+
+		name := parser.next()
+		if name.kind != 'n' {
+			return parser.unexpected(name)
+		}
+
+		if err := parser.expect(":"); err != nil {
+			return err
+		}
+
+		if err := parser.skipType(); err != nil {
+			return err
+		}
+
+		if token := parser.peek(); token.kind == 'p' && token.value == "=" {
+			parser.next()
+			value, err := parser.parseValue()
+			if err != nil {
+				return err
+			}
+
+			if _, ok := parser.variables[name.value]; !ok {
+				parser.variables[name.value] = value
+			}
+		}
+	}
+}
+
+// skipType skips over a type reference, such as "[Int!]!". Types are not
+// checked by the bridge, the handler is responsible for validating params.
+func (parser *graphQLParser) skipType() error {
+	if err := parser.enter(); err != nil {
+		return err
+	}
+	defer parser.leave()
+
+	token := parser.next()
+	switch {
+	case token.kind == 'n':
+
+	case token.kind == 'p' && token.value == "[":
+		if err := parser.skipType(); err != nil {
+			return err
+		}
+		if err := parser.expect("]"); err != nil {
+			return err
+		}
+
+	default:
+		return parser.unexpected(token)
+	}
+
+	if token := parser.peek(); token.kind == 'p' && token.value == "!" {
+		parser.next()
+	}
+
+	return nil
+}
+
+func (parser *graphQLParser) parseSelectionSet() ([]graphQLField, error) {
+	if err := parser.enter(); err != nil {
+		return nil, err
+	}
+	defer parser.leave()
+
+	if err := parser.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var fields []graphQLField
+	for {
+		token := parser.next()
+		if token.kind == 'p' && token.value == "}" {
+			if len(fields) == 0 {
+				return nil, errors.New("Selection set must not be empty.")
+			}
+
+			return fields, nil
+		}
+
+		if token.kind == 'p' && (token.value == "..." || token.value == "@") {
+			return nil, errors.New("Fragments and directives are not supported.")
+		}
+
+		if token.kind != 'n' {
+			return nil, parser.unexpected(token)
+		}
+
+		field := graphQLField{name: token.value}
+		if next := parser.peek(); next.kind == 'p' && next.value == ":" {
+			parser.next()
+			name := parser.next()
+			if name.kind != 'n' {
+				return nil, parser.unexpected(name)
+			}
+
+			field.alias = field.name
+			field.name = name.value
+		}
+
+		if next := parser.peek(); next.kind == 'p' && next.value == "(" {
+			args, err := parser.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			field.args = args
+		}
+
+		if next := parser.peek(); next.kind == 'p' && next.value == "{" {
+			selections, err := parser.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			field.selections = selections
+		}
+
+		fields = append(fields, field)
+	}
+}
+
+func (parser *graphQLParser) parseArguments() (map[string]interface{}, error) {
+	parser.next()
+
+	args := map[string]interface{}{}
+	for {
+		token := parser.next()
+		if token.kind == 'p' && token.value == ")" {
+			return args, nil
+		}
+
+		if token.kind != 'n' {
+			return nil, parser.unexpected(token)
+		}
+
+		if err := parser.expect(":"); err != nil {
+			return nil, err
+		}
+
+		value, err := parser.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		args[token.value] = value
+	}
+}
+
+func (parser *graphQLParser) parseValue() (interface{}, error) {
+	if err := parser.enter(); err != nil {
+		return nil, err
+	}
+	defer parser.leave()
+
+	token := parser.next()
+
+	switch token.kind {
+	case '0':
+		// Numbers are float64 so that handlers receive the same values as
+		// they would for params decoded from JSON.
+		return strconv.ParseFloat(token.value, 64)
+
+	case 's':
+		return token.value, nil
+
+	case 'n':
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		// Enum values are sent as strings.
+		return token.value, nil
+
+	case 'p':
+		switch token.value {
+		case "$":
+			name := parser.next()
+			if name.kind != 'n' {
+				return nil, parser.unexpected(name)
+			}
+
+			return parser.variables[name.value], nil
+
+		case "[":
+			list := []interface{}{}
+			for {
+				if next := parser.peek(); next.kind == 'p' && next.value == "]" {
+					parser.next()
+					return list, nil
+				}
+
+				value, err := parser.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+
+		case "{":
+			object := map[string]interface{}{}
+			for {
+				name := parser.next()
+				if name.kind == 'p' && name.value == "}" {
+					return object, nil
+				}
+
+				if name.kind != 'n' {
+					return nil, parser.unexpected(name)
+				}
+
+				if err := parser.expect(":"); err != nil {
+					return nil, err
+				}
+
+				value, err := parser.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				object[name.value] = value
+			}
+		}
+	}
+
+	return nil, parser.unexpected(token)
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,322 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func getUser(request jsonrpc.RequestResponder) jsonrpc.Response {
+	params, _ := request.Params().(map[string]interface{})
+
+	return request.NewSuccessResponse(map[string]interface{}{
+		"id":   params["id"],
+		"name": "Bob",
+		"address": map[string]interface{}{
+			"city":    "Porto Alegre",
+			"country": "Brazil",
+		},
+	})
+}
+
+func newTestGraphQLBridge() *jsonrpc.GraphQLBridge {
+	server := newTestServer()
+	server.SetHandler("user.get", getUser)
+
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:        "user.get",
+		Description: "Get a user by id.",
+		ReadOnly:    true,
+		Params: &jsonrpc.Schema{
+			Type: "object",
+			Properties: map[string]*jsonrpc.Schema{
+				"id": {Type: "integer"},
+			},
+			Required: []string{"id"},
+		},
+		Result: &jsonrpc.Schema{
+			Type: "object",
+			Properties: map[string]*jsonrpc.Schema{
+				"id":   {Type: "integer"},
+				"name": {Type: "string"},
+				"address": {
+					Type: "object",
+					Properties: map[string]*jsonrpc.Schema{
+						"city": {Type: "string"},
+					},
+				},
+			},
+			Required: []string{"id"},
+		},
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:     "get_data",
+		ReadOnly: true,
+		Result:   &jsonrpc.Schema{Type: "array"},
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name: "subtract",
+		Params: &jsonrpc.Schema{
+			Type: "object",
+			Properties: map[string]*jsonrpc.Schema{
+				"minuend":    {Type: "number"},
+				"subtrahend": {Type: "number"},
+			},
+		},
+		Result: &jsonrpc.Schema{Type: "number"},
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "panic"})
+
+	return jsonrpc.NewGraphQLBridge(server)
+}
+
+func graphQLJSON(response jsonrpc.GraphQLResponse) string {
+	b, _ := json.Marshal(response)
+	return string(b)
+}
+
+func TestGraphQLBridge_Execute(t *testing.T) {
+	bridge := newTestGraphQLBridge()
+
+	tests := map[string]struct {
+		query     string
+		variables map[string]interface{}
+		operation string
+		expected  string
+	}{
+		"shorthand query": {
+			query:    `{ get_data }`,
+			expected: `{"data":{"get_data":["hello",5]}}`,
+		},
+		"selection and alias": {
+			query:    `query { bob: user_get(id: 7) { name id address { city } } }`,
+			expected: `{"data":{"bob":{"name":"Bob","id":7,"address":{"city":"Porto Alegre"}}}}`,
+		},
+		"variables": {
+			query:     `query GetUser($id: Int! = 3) { user_get(id: $id) { id } }`,
+			variables: map[string]interface{}{"id": 9},
+			expected:  `{"data":{"user_get":{"id":9}}}`,
+		},
+		"default variable": {
+			query:    `query GetUser($id: Int! = 3) { user_get(id: $id) { id } }`,
+			expected: `{"data":{"user_get":{"id":3}}}`,
+		},
+		"mutation": {
+			query:    `mutation { subtract(minuend: 42, subtrahend: 23.5) }`,
+			expected: `{"data":{"subtract":18.5}}`,
+		},
+		"operation name": {
+			query:     `query A { get_data } mutation B { subtract(minuend: 1, subtrahend: 1) }`,
+			operation: "B",
+			expected:  `{"data":{"subtract":0}}`,
+		},
+		"mutation is not a query": {
+			query:    `{ subtract }`,
+			expected: `{"data":{"subtract":null},"errors":[{"message":"Cannot query field \"subtract\" on type \"Query\".","path":["subtract"]}]}`,
+		},
+		"handler error": {
+			query:    `mutation { panic }`,
+			expected: `{"data":{"panic":null},"errors":[{"message":"Internal error","path":["panic"],"extensions":{"code":-32603}}]}`,
+		},
+		"syntax error": {
+			query:    `{ user_get(id: ) }`,
+			expected: `{"data":null,"errors":[{"message":"Unexpected \")\"."}]}`,
+		},
+		"fragments": {
+			query:    `{ ...UserFields }`,
+			expected: `{"data":null,"errors":[{"message":"Fragments and directives are not supported."}]}`,
+		},
+		"ambiguous operation": {
+			query:    `query A { get_data } query B { get_data }`,
+			expected: `{"data":null,"errors":[{"message":"Must provide operation name if query contains multiple operations."}]}`,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			response := bridge.Execute(test.query, test.variables, test.operation)
+
+			assert.Equal(t, test.expected, graphQLJSON(response))
+		})
+	}
+}
+
+func TestGraphQLBridge_ServeHTTP(t *testing.T) {
+	bridge := newTestGraphQLBridge()
+
+	t.Run("POST", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query": "query($id: Int) { user_get(id: $id) { id } }", "variables": {"id": 1}}`)))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"data":{"user_get":{"id":1}}}`+"\n", recorder.Body.String())
+	})
+
+	t.Run("GET", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/graphql?query="+url.QueryEscape(`{ get_data }`), nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `{"data":{"get_data":["hello",5]}}`+"\n", recorder.Body.String())
+	})
+
+	t.Run("GETMutation", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/graphql?query="+url.QueryEscape(`mutation { panic }`), nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+		assert.Equal(t, "POST", recorder.Header().Get("Allow"))
+	})
+
+	t.Run("InvalidDocument", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query": "{"}`)))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query": "`+strings.Repeat(" ", jsonrpc.DefaultMaxBodySize)+`{ get_data }"}`)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("TooDeep", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		bridge.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+			"/graphql?query="+url.QueryEscape(`{ user_get(id: `+strings.Repeat("[", 100000)+`) }`), nil))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, `{"data":null,"errors":[{"message":"Document is nested too deeply."}]}`+"\n",
+			recorder.Body.String())
+	})
+}
+
+func TestGraphQLBridge_ServeHTTPState(t *testing.T) {
+	type contextKey struct{}
+
+	server := newTestServer()
+	server.SetHandler("whoami", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse([]interface{}{
+			jsonrpc.ClientInfoFromRequest(request).Address,
+			jsonrpc.ContextFromRequest(request).Value(contextKey{}),
+		})
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "whoami", ReadOnly: true})
+	bridge := jsonrpc.NewGraphQLBridge(server)
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	r := httptest.NewRequest(http.MethodGet,
+		"/graphql?query="+url.QueryEscape(`{ whoami }`), nil).WithContext(ctx)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	recorder := httptest.NewRecorder()
+	bridge.ServeHTTP(recorder, r)
+	assert.Equal(t, `{"data":{"whoami":["192.0.2.1","value"]}}`+"\n", recorder.Body.String())
+
+	// A nil id makes the request a notification, which has no response.
+	server.SetIDGenerator(jsonrpc.IDGeneratorFunc(func() interface{} {
+		return nil
+	}))
+
+	recorder = httptest.NewRecorder()
+	bridge.ServeHTTP(recorder, r)
+	assert.Equal(t, `{"data":{"whoami":null},"errors":[{"message":"No response, the request was sent as a notification.","path":["whoami"]}]}`+"\n",
+		recorder.Body.String())
+}
+
+func TestGraphQLBridge_Schema(t *testing.T) {
+	bridge := newTestGraphQLBridge()
+
+	schema, err := bridge.Schema()
+	assert.NoError(t, err)
+	assert.Equal(t, `scalar JSON
+
+type Query {
+  get_data: [JSON]
+  "Get a user by id."
+  user_get(id: Int!): User_getResult
+}
+
+type Mutation {
+  panic: JSON
+  subtract(minuend: Float, subtrahend: Float): Float
+}
+
+type User_getResultAddress {
+  city: String
+}
+
+type User_getResult {
+  address: User_getResultAddress
+  id: Int!
+  name: String
+}
+`, schema)
+}
+
+func TestGraphQLBridge_SameName(t *testing.T) {
+	server := newTestServer()
+	for _, name := range []string{"user.get", "user_get"} {
+		server.SetHandler(name, getUser)
+		server.SetMethodInfo(jsonrpc.MethodInfo{Name: name, ReadOnly: true})
+	}
+	bridge := jsonrpc.NewGraphQLBridge(server)
+
+	message := `Methods "user.get" and "user_get" are both called "user_get" in GraphQL.`
+
+	_, err := bridge.Schema()
+	assert.EqualError(t, err, message)
+	assert.Equal(t, `{"data":null,"errors":[{"message":"`+strings.ReplaceAll(message, `"`, `\"`)+`"}]}`,
+		graphQLJSON(bridge.Execute(`{ user_get(id: 1) { id } }`, nil, "")))
+}
+
+func TestGraphQLBridge_ParamNames(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("user.get", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params())
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:     "user.get",
+		ReadOnly: true,
+		Params: &jsonrpc.Schema{
+			Type: "object",
+			Properties: map[string]*jsonrpc.Schema{
+				"user-id": {Type: "string"},
+			},
+		},
+	})
+	bridge := jsonrpc.NewGraphQLBridge(server)
+
+	assert.Equal(t, `{"data":{"user_get":{"user-id":"a"}}}`,
+		graphQLJSON(bridge.Execute(`{ user_get(user_id: "a") }`, nil, "")))
+
+	// Params that have the same name in GraphQL could not both be set.
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:     "user.get",
+		ReadOnly: true,
+		Params: &jsonrpc.Schema{
+			Type: "object",
+			Properties: map[string]*jsonrpc.Schema{
+				"user-id": {Type: "string"},
+				"user_id": {Type: "string"},
+			},
+		},
+	})
+
+	_, err := bridge.Schema()
+	assert.EqualError(t, err, `Params "user-id" and "user_id" of "user.get" are both called "user_id" in GraphQL.`)
+}
@@ -0,0 +1,80 @@
+package jsonrpc
+
+import "sync"
+
+// HandlerGroup registers handlers on a server with middleware that only
+// applies to the handlers of the group. This allows a set of methods to share
+// behaviour, such as an authorization scope or a rate limit, without applying
+// it to the rest of the server:
+//
+//     admin := server.Group(requireScope("admin"))
+//     admin.SetHandler("user.delete", deleteUser)
+//     admin.SetHandler("user.ban", banUser)
+//
+//     // Nested groups run the middleware of their parents first.
+//     billing := admin.Group(jsonrpc.Transaction(db))
+//     billing.SetHandler("invoice.void", voidInvoice)
+//
+// The middleware is applied when the handler is registered, so middleware
+// added to a group does not change the handlers it already contains. A group
+// is safe for concurrent use.
+type HandlerGroup struct {
+	server *SimpleServer
+
+	// middleware is replaced, never changed, by Use so that it can be read
+	// while holding the mutex only long enough to copy the slice header.
+	mutex      sync.Mutex
+	middleware []Middleware
+}
+
+// Group creates a group of handlers. The middleware is called in the order it
+// is given, so the first middleware is the outermost.
+func (server *SimpleServer) Group(middleware ...Middleware) *HandlerGroup {
+	return &HandlerGroup{
+		server:     server,
+		middleware: append([]Middleware(nil), middleware...),
+	}
+}
+
+// Group creates a nested group. Its handlers are wrapped with the middleware of
+// this group followed by the middleware given here.
+func (group *HandlerGroup) Group(middleware ...Middleware) *HandlerGroup {
+	parent := group.getMiddleware()
+	combined := make([]Middleware, 0, len(parent)+len(middleware))
+	combined = append(combined, parent...)
+	combined = append(combined, middleware...)
+
+	return &HandlerGroup{
+		server:     group.server,
+		middleware: combined,
+	}
+}
+
+// Use adds middleware to the group. It only applies to handlers that are
+// registered after it has been added.
+func (group *HandlerGroup) Use(middleware ...Middleware) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	combined := make([]Middleware, 0, len(group.middleware)+len(middleware))
+	combined = append(combined, group.middleware...)
+	group.middleware = append(combined, middleware...)
+}
+
+func (group *HandlerGroup) getMiddleware() []Middleware {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	return group.middleware
+}
+
+// SetHandler wraps the handler with the middleware of the group and registers
+// (or replaces) it on the server.
+func (group *HandlerGroup) SetHandler(methodName string, handler RequestHandler) {
+	group.server.SetHandler(methodName, group.wrap(handler))
+}
+
+// wrap applies the middleware of the group to the handler.
+func (group *HandlerGroup) wrap(handler RequestHandler) RequestHandler {
+	return Chain(group.getMiddleware()...)(handler)
+}
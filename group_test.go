@@ -0,0 +1,102 @@
+package jsonrpc_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// trace is a middleware that records the name when the handler is called.
+func trace(calls *[]string, name string) jsonrpc.Middleware {
+	return func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			*calls = append(*calls, name)
+			return next(request)
+		}
+	}
+}
+
+// deny is a middleware that never calls the handler.
+func deny(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+	return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewErrorResponse(jsonrpc.ServerError, "Denied")
+	}
+}
+
+func TestSimpleServer_Group(t *testing.T) {
+	var calls []string
+	server := newTestServer()
+
+	admin := server.Group(trace(&calls, "auth"), trace(&calls, "audit"))
+	admin.SetHandler("admin.data", getData)
+
+	billing := admin.Group(trace(&calls, "tx"))
+	billing.SetHandler("billing.sum", sum)
+
+	// The parent is not affected by the nested group.
+	admin.SetHandler("admin.sum", sum)
+
+	handle := func(method string) jsonrpc.Response {
+		return server.Handle([]byte(`{"jsonrpc": "2.0", "method": "` + method +
+			`", "params": [1, 2], "id": 1}`))[0]
+	}
+
+	assert.Equal(t, jsonrpc.Success, handle("admin.data").ErrorCode())
+	assert.Equal(t, []string{"auth", "audit"}, calls)
+
+	calls = nil
+	assert.Equal(t, 3.0, handle("billing.sum").Result())
+	assert.Equal(t, []string{"auth", "audit", "tx"}, calls)
+
+	calls = nil
+	assert.Equal(t, 3.0, handle("admin.sum").Result())
+	assert.Equal(t, []string{"auth", "audit"}, calls)
+
+	// Handlers outside of the group have no middleware.
+	calls = nil
+	assert.Equal(t, 3.0, handle("sum").Result())
+	assert.Empty(t, calls)
+}
+
+func TestHandlerGroup_Use(t *testing.T) {
+	server := newTestServer()
+	group := server.Group()
+	group.SetHandler("before", getData)
+	group.Use(deny)
+	group.SetHandler("after", getData)
+
+	before := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "before", "id": 1}`))
+	assert.Equal(t, jsonrpc.Success, before[0].ErrorCode())
+
+	after := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "after", "id": 1}`))
+	assert.Equal(t, "Denied", after[0].ErrorMessage())
+}
+
+func TestHandlerGroup_UseConcurrently(t *testing.T) {
+	server := newTestServer()
+	group := server.Group()
+	group.SetHandler("data", getData)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			group.Use(func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+				return next
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			group.Group().SetHandler("data", getData)
+		}()
+		go func() {
+			defer wg.Done()
+			response := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "data", "id": 1}`))
+			assert.Equal(t, jsonrpc.Success, response[0].ErrorCode())
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,130 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// HandoffEnv is the environment variable that tells a process how many
+// listeners it has inherited from the process that started it with Handoff.
+const HandoffEnv = "JSONRPC_HANDOFF_FDS"
+
+// The first file descriptor after stdin, stdout and stderr.
+const firstInheritedFD = 3
+
+// Handoff passes the listeners to a new process so that it can continue to
+// accept connections while this process drains and exits. Since the listening
+// sockets are never closed, no connections are refused during the restart.
+//
+// The cmd is started with the listeners as its first extra files and
+// HandoffEnv set. If cmd is nil the current executable is started again with
+// the same arguments. A typical restart on SIGUSR2 would be:
+//
+//     signals := make(chan os.Signal, 1)
+//     signal.Notify(signals, syscall.SIGUSR2)
+//     <-signals
+//
+//     if _, err := jsonrpc.Handoff(nil, listeners); err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     // Stop accepting and wait for the active requests to finish.
+//     httpServer.Shutdown(ctx)
+//
+// The new process gets the listeners with InheritedListeners.
+func Handoff(cmd *exec.Cmd, listeners []net.Listener) (*exec.Cmd, error) {
+	if cmd == nil {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+
+		cmd = exec.Command(executable, os.Args[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	}
+
+	files := make([]*os.File, 0, len(listeners))
+	defer func() {
+		// The child has its own copies once it has started.
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	for _, listener := range listeners {
+		filer, ok := listener.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("Cannot hand off a %T.", listener)
+		}
+
+		file, err := filer.File()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	cmd.Env = append(cmd.Env, HandoffEnv+"="+strconv.Itoa(len(files)))
+	cmd.ExtraFiles = append(append([]*os.File{}, files...), cmd.ExtraFiles...)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+// InheritedListeners returns the listeners that were passed to this process
+// with Handoff. It returns no listeners if the process was not started by
+// Handoff, in which case the process should open its own listeners.
+//
+// HandoffEnv is removed from the environment so that it is not passed on to
+// other processes.
+func InheritedListeners() ([]net.Listener, error) {
+	value, ok := os.LookupEnv(HandoffEnv)
+	if !ok {
+		return nil, nil
+	}
+
+	os.Unsetenv(HandoffEnv)
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, errors.New("Invalid " + HandoffEnv + ": " + value)
+	}
+
+	return listenersFromFDs(firstInheritedFD, n)
+}
+
+// listenersFromFDs creates a listener for each of the n file descriptors
+// starting at first.
+func listenersFromFDs(first, n int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, n)
+	for fd := first; fd < first+n; fd++ {
+		file := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+
+		// FileListener makes its own copy of the file descriptor.
+		file.Close()
+
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
@@ -0,0 +1,121 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// TestHandoffHelperProcess is not a real test. It is the process that is
+// started by TestHandoff. It serves a single request on the inherited listener
+// and exits.
+func TestHandoffHelperProcess(t *testing.T) {
+	if os.Getenv("JSONRPC_HANDOFF_HELPER") != "1" {
+		return
+	}
+
+	listeners, err := jsonrpc.InheritedListeners()
+	if err != nil || len(listeners) != 1 {
+		os.Exit(2)
+	}
+
+	os.Exit(serveOneHTTPRequest(listeners[0]))
+}
+
+// serveOneHTTPRequest handles a single JSON-RPC request over HTTP with the
+// test server and returns an exit code for the helper process.
+func serveOneHTTPRequest(listener net.Listener) int {
+	connection, err := listener.Accept()
+	if err != nil {
+		return 3
+	}
+
+	request, err := http.ReadRequest(bufio.NewReader(connection))
+	if err != nil {
+		return 4
+	}
+
+	body, _ := ioutil.ReadAll(request.Body)
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Body:       ioutil.NopCloser(bytes.NewReader(newTestServer().Handle(body).Bytes())),
+		Close:      true,
+	}
+	response.Write(connection)
+	connection.Close()
+
+	return 0
+}
+
+func TestHandoff(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listeners cannot be handed off on windows")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHandoffHelperProcess$")
+	cmd.Env = append(os.Environ(), "JSONRPC_HANDOFF_HELPER=1")
+
+	cmd, err = jsonrpc.Handoff(cmd, []net.Listener{listener})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The old process stops accepting, the socket stays open in the child.
+	address := listener.Addr().String()
+	listener.Close()
+
+	assertSubtractOverHTTP(t, address)
+	assert.NoError(t, cmd.Wait())
+}
+
+func assertSubtractOverHTTP(t *testing.T, address string) {
+	response, err := http.Post("http://"+address, "application/json",
+		bytes.NewBufferString(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`))
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":19}]`, string(body))
+	}
+}
+
+func TestInheritedListeners_NotHandedOff(t *testing.T) {
+	listeners, err := jsonrpc.InheritedListeners()
+	assert.NoError(t, err)
+	assert.Empty(t, listeners)
+}
+
+func TestInheritedListeners_Invalid(t *testing.T) {
+	os.Setenv(jsonrpc.HandoffEnv, "x")
+
+	_, err := jsonrpc.InheritedListeners()
+	assert.EqualError(t, err, "Invalid JSONRPC_HANDOFF_FDS: x")
+
+	_, ok := os.LookupEnv(jsonrpc.HandoffEnv)
+	assert.False(t, ok)
+}
+
+func TestHandoff_UnsupportedListener(t *testing.T) {
+	_, err := jsonrpc.Handoff(exec.Command("true"), []net.Listener{fakeListener{}})
+	assert.EqualError(t, err, "Cannot hand off a jsonrpc_test.fakeListener.")
+}
+
+// fakeListener is a listener that does not have a file.
+type fakeListener struct {
+	net.Listener
+}
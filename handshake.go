@@ -0,0 +1,193 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// HandshakeMethod is the method of the request a client sends to negotiate
+// Capabilities when it connects.
+const HandshakeMethod = "rpc.handshake"
+
+// Capabilities are the features a peer supports. Lists are in order of
+// preference. The zero value is a peer that only supports plain JSON without
+// any extensions, which is what every peer understands.
+type Capabilities struct {
+	Codecs      []string `json:"codecs,omitempty"`
+	Compression []string `json:"compression,omitempty"`
+
+	// MaxMessageSize is the largest message in bytes that the peer accepts.
+	// Zero means there is no limit.
+	MaxMessageSize int `json:"maxMessageSize,omitempty"`
+
+	Extensions []string `json:"extensions,omitempty"`
+
+	// Batch is true if the peer handles batches. JSON-RPC 1.0 peers do not,
+	// see BatchClient.
+	Batch bool `json:"batch,omitempty"`
+}
+
+// Negotiated is the outcome of a handshake that both peers use for the rest
+// of the connection. An empty Codec or Compression means plain JSON without
+// compression.
+type Negotiated struct {
+	Codec          string   `json:"codec,omitempty"`
+	Compression    string   `json:"compression,omitempty"`
+	MaxMessageSize int      `json:"maxMessageSize,omitempty"`
+	Extensions     []string `json:"extensions,omitempty"`
+	Batch          bool     `json:"batch,omitempty"`
+}
+
+// Negotiate chooses the features supported by both peers, preferring the
+// order of the local capabilities. The smaller MaxMessageSize is used, and
+// batches are only used if both peers handle them.
+func (local Capabilities) Negotiate(peer Capabilities) Negotiated {
+	negotiated := Negotiated{
+		Codec:          firstCommon(local.Codecs, peer.Codecs),
+		Compression:    firstCommon(local.Compression, peer.Compression),
+		MaxMessageSize: local.MaxMessageSize,
+		Batch:          local.Batch && peer.Batch,
+	}
+
+	if negotiated.MaxMessageSize == 0 ||
+		(peer.MaxMessageSize != 0 && peer.MaxMessageSize < negotiated.MaxMessageSize) {
+		negotiated.MaxMessageSize = peer.MaxMessageSize
+	}
+
+	for _, extension := range local.Extensions {
+		if contains(peer.Extensions, extension) {
+			negotiated.Extensions = append(negotiated.Extensions, extension)
+		}
+	}
+
+	return negotiated
+}
+
+func firstCommon(preferred, supported []string) string {
+	for _, value := range preferred {
+		if contains(supported, value) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientHandshake sends the local capabilities as the first message on a
+// newline delimited stream and returns what the server chose. A server that
+// does not support handshakes responds with MethodNotFound, in which case the
+// zero Negotiated is returned so that the connection continues with plain
+// JSON.
+//
+// The reader must be used for the rest of the connection, as it may have
+// buffered data after the handshake response.
+func ClientHandshake(reader *bufio.Reader, w io.Writer, local Capabilities) (Negotiated, error) {
+	request := NewRequestResponder("2.0", HandshakeMethod, HandshakeMethod, local)
+	if _, err := w.Write(append(request.Bytes(), '\n')); err != nil {
+		return Negotiated{}, err
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return Negotiated{}, err
+	}
+
+	responses, err := NewResponsesFromJSON(bytes.TrimSpace(line))
+	if err != nil {
+		return Negotiated{}, err
+	}
+
+	response := responses[0]
+	if response.ErrorCode() == MethodNotFound {
+		return Negotiated{}, nil
+	}
+
+	var negotiated Negotiated
+	if err := decodeResult(response, &negotiated); err != nil {
+		return Negotiated{}, err
+	}
+
+	return negotiated, nil
+}
+
+// ServerHandshake waits for the first message on a newline delimited stream
+// and, if it is a handshake, responds with the negotiated features and
+// returns them. If the client sent an ordinary request instead (because it
+// does not support handshakes) the message is left unread in the reader and
+// the zero Negotiated is returned, so the connection continues with plain
+// JSON.
+//
+// A handshake must fit into the buffer of the reader.
+func ServerHandshake(reader *bufio.Reader, w io.Writer, local Capabilities) (Negotiated, error) {
+	line, err := peekLine(reader)
+	if err != nil || line == nil {
+		return Negotiated{}, err
+	}
+
+	var request struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		ID     interface{}     `json:"id"`
+	}
+	if json.Unmarshal(line, &request) != nil || request.Method != HandshakeMethod {
+		return Negotiated{}, nil
+	}
+
+	reader.Discard(len(line))
+
+	var peer Capabilities
+	var response Response
+	if len(request.Params) > 0 && json.Unmarshal(request.Params, &peer) != nil {
+		response = NewErrorResponse(request.ID, InvalidParams, "")
+	} else {
+		response = NewSuccessResponse(request.ID, local.Negotiate(peer))
+	}
+
+	if _, err := w.Write(append(response.Bytes(), '\n')); err != nil {
+		return Negotiated{}, err
+	}
+
+	if response.ErrorCode() != Success {
+		return Negotiated{}, errors.New("Invalid handshake.")
+	}
+
+	return response.Result().(Negotiated), nil
+}
+
+// peekLine returns the next line without consuming it, waiting for more data
+// as needed. It returns nil if the line is longer than the buffer of the
+// reader, or the stream ends without a newline.
+func peekLine(reader *bufio.Reader) ([]byte, error) {
+	for {
+		buffered, _ := reader.Peek(reader.Buffered())
+		if i := bytes.IndexByte(buffered, '\n'); i >= 0 {
+			return buffered[:i+1], nil
+		}
+
+		if len(buffered) >= reader.Size() {
+			return nil, nil
+		}
+
+		// Wait for at least one more byte.
+		if _, err := reader.Peek(len(buffered) + 1); err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+	}
+}
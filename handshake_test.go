@@ -0,0 +1,119 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestCapabilities_Negotiate(t *testing.T) {
+	server := jsonrpc.Capabilities{
+		Codecs:         []string{"msgpack", "json"},
+		Compression:    []string{"zstd", "gzip"},
+		MaxMessageSize: 1 << 20,
+		Extensions:     []string{"trace", "cancel"},
+		Batch:          true,
+	}
+
+	assert.Equal(t, jsonrpc.Negotiated{
+		Codec:          "msgpack",
+		Compression:    "gzip",
+		MaxMessageSize: 1024,
+		Extensions:     []string{"cancel"},
+		Batch:          true,
+	}, server.Negotiate(jsonrpc.Capabilities{
+		Codecs:         []string{"json", "msgpack"},
+		Compression:    []string{"gzip"},
+		MaxMessageSize: 1024,
+		Extensions:     []string{"cancel", "stream"},
+		Batch:          true,
+	}))
+
+	// An old peer gets the baseline, with the limit of the server.
+	assert.Equal(t, jsonrpc.Negotiated{MaxMessageSize: 1 << 20},
+		server.Negotiate(jsonrpc.Capabilities{}))
+	assert.Equal(t, jsonrpc.Negotiated{MaxMessageSize: 10},
+		jsonrpc.Capabilities{}.Negotiate(jsonrpc.Capabilities{MaxMessageSize: 10}))
+}
+
+func TestHandshake(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	type result struct {
+		negotiated jsonrpc.Negotiated
+		err        error
+	}
+	serverDone := make(chan result)
+	go func() {
+		negotiated, err := jsonrpc.ServerHandshake(bufio.NewReader(serverConn),
+			serverConn, jsonrpc.Capabilities{Codecs: []string{"cbor", "json"}})
+		serverDone <- result{negotiated, err}
+	}()
+
+	negotiated, err := jsonrpc.ClientHandshake(bufio.NewReader(clientConn), clientConn,
+		jsonrpc.Capabilities{Codecs: []string{"json", "cbor"}, Extensions: []string{"x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.Negotiated{Codec: "cbor"}, negotiated)
+
+	server := <-serverDone
+	assert.NoError(t, server.err)
+	assert.Equal(t, negotiated, server.negotiated)
+}
+
+func TestServerHandshake_OldClient(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	go clientConn.Write([]byte(
+		"{\"jsonrpc\":\"2.0\",\"method\":\"sum\",\"params\":[1,2],\"id\":1}\n"))
+
+	reader := bufio.NewReader(serverConn)
+	negotiated, err := jsonrpc.ServerHandshake(reader, serverConn,
+		jsonrpc.Capabilities{Codecs: []string{"cbor"}})
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.Negotiated{}, negotiated)
+
+	// The request is still there to be handled.
+	line, err := reader.ReadBytes('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, newTestServer().Handle(line)[0].Result())
+}
+
+func TestClientHandshake_OldServer(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	// The server does not know about handshakes and handles it as a request.
+	go func() {
+		line, _ := bufio.NewReader(serverConn).ReadBytes('\n')
+		serverConn.Write(append(newTestServer().Handle(line)[0].Bytes(), '\n'))
+	}()
+
+	negotiated, err := jsonrpc.ClientHandshake(bufio.NewReader(clientConn), clientConn,
+		jsonrpc.Capabilities{Codecs: []string{"cbor"}})
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.Negotiated{}, negotiated)
+}
+
+func TestServerHandshake_InvalidParams(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	go func() {
+		clientConn.Write([]byte(
+			"{\"jsonrpc\":\"2.0\",\"method\":\"rpc.handshake\",\"params\":{\"codecs\":5},\"id\":1}\n"))
+		bufio.NewReader(clientConn).ReadBytes('\n')
+	}()
+
+	_, err := jsonrpc.ServerHandshake(bufio.NewReader(serverConn), serverConn,
+		jsonrpc.Capabilities{})
+	assert.EqualError(t, err, "Invalid handshake.")
+}
@@ -0,0 +1,145 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// DefaultSizeBuckets are reasonable bucket bounds, in bytes, for
+// SetSizeBuckets.
+var DefaultSizeBuckets = []int{128, 512, 2048, 8192, 32768, 131072, 524288}
+
+// SizeHistogram is the distribution of payload sizes in bytes.
+type SizeHistogram struct {
+	// Bounds are the inclusive upper bounds of each bucket.
+	Bounds []int `json:"bounds"`
+
+	// Counts has one more element than Bounds. The last element counts the
+	// sizes that are larger than every bound.
+	Counts []uint64 `json:"counts"`
+
+	// Count is the number of sizes observed and Sum is their total.
+	Count uint64 `json:"count"`
+	Sum   uint64 `json:"sum"`
+}
+
+// NewSizeHistogram creates an empty histogram. The bounds must be sorted.
+func NewSizeHistogram(bounds []int) SizeHistogram {
+	return SizeHistogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe adds a size to the histogram.
+func (histogram *SizeHistogram) Observe(size int) {
+	i := sort.SearchInts(histogram.Bounds, size)
+	histogram.Counts[i]++
+	histogram.Count++
+	histogram.Sum += uint64(size)
+}
+
+// Mean is the average size, or zero if nothing has been observed.
+func (histogram SizeHistogram) Mean() float64 {
+	if histogram.Count == 0 {
+		return 0
+	}
+
+	return float64(histogram.Sum) / float64(histogram.Count)
+}
+
+func (histogram SizeHistogram) copy() SizeHistogram {
+	histogram.Counts = append([]uint64{}, histogram.Counts...)
+	return histogram
+}
+
+// MethodSizes are the size distributions of a single method.
+type MethodSizes struct {
+	// Params is the size of the encoded params of every request. Requests
+	// without params have a size of zero.
+	Params SizeHistogram `json:"params"`
+
+	// Result is the size of the encoded result of every successful response.
+	// Notifications and errors are not included.
+	Result SizeHistogram `json:"result"`
+}
+
+// SetSizeBuckets enables the tracking of params and result sizes for each
+// method, see MethodSizes. The bounds must be sorted. Tracking is disabled by
+// default because the params and result need to be encoded an extra time to
+// measure them, use nil to disable it again.
+//
+// Changing the buckets will reset the existing histograms.
+func (server *SimpleServer) SetSizeBuckets(bounds []int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.sizeMutex.Lock()
+	defer server.sizeMutex.Unlock()
+
+	server.sizeBounds = bounds
+	server.methodSizes = map[string]*MethodSizes{}
+}
+
+// MethodSizes returns the size distributions of each method that has been
+// called since SetSizeBuckets. Only methods that have a handler are tracked.
+func (server *SimpleServer) MethodSizes() map[string]MethodSizes {
+	server.sizeMutex.Lock()
+	defer server.sizeMutex.Unlock()
+
+	sizes := map[string]MethodSizes{}
+	for method, methodSizes := range server.methodSizes {
+		sizes[method] = MethodSizes{
+			Params: methodSizes.Params.copy(),
+			Result: methodSizes.Result.copy(),
+		}
+	}
+
+	return sizes
+}
+
+// observeSizes records the sizes of a handled request.
+func (server *SimpleServer) observeSizes(request Request, response Response) {
+	paramsSize := encodedSize(request.Params())
+
+	resultSize := -1
+	if !request.IsNotification() && response.ErrorCode() == Success {
+		resultSize = encodedSize(response.Result())
+	}
+
+	server.sizeMutex.Lock()
+	defer server.sizeMutex.Unlock()
+
+	// Tracking may have been disabled while the request was handled.
+	bounds := server.sizeBounds
+	if bounds == nil {
+		return
+	}
+
+	methodSizes := server.methodSizes[request.Method()]
+	if methodSizes == nil {
+		methodSizes = &MethodSizes{
+			Params: NewSizeHistogram(bounds),
+			Result: NewSizeHistogram(bounds),
+		}
+		server.methodSizes[request.Method()] = methodSizes
+	}
+
+	methodSizes.Params.Observe(paramsSize)
+	if resultSize >= 0 {
+		methodSizes.Result.Observe(resultSize)
+	}
+}
+
+func encodedSize(value interface{}) int {
+	if value == nil {
+		return 0
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+
+	return len(encoded)
+}
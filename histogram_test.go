@@ -0,0 +1,67 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	histogram := jsonrpc.NewSizeHistogram([]int{10, 100})
+	assert.Equal(t, 0.0, histogram.Mean())
+
+	histogram.Observe(0)
+	histogram.Observe(10)
+	histogram.Observe(11)
+	histogram.Observe(1000)
+
+	assert.Equal(t, []uint64{2, 1, 1}, histogram.Counts)
+	assert.Equal(t, uint64(4), histogram.Count)
+	assert.Equal(t, uint64(1021), histogram.Sum)
+	assert.Equal(t, 255.25, histogram.Mean())
+}
+
+func TestSimpleServer_MethodSizes(t *testing.T) {
+	server := newTestServer()
+	server.Handle([]byte(`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1}`))
+	assert.Empty(t, server.MethodSizes())
+
+	server.SetSizeBuckets([]int{4, 16})
+	server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1},
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 3, 4, 5, 6, 7, 8, 9], "id": 2},
+		{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+		{"jsonrpc": "2.0", "method": "get_data", "id": 3},
+		{"jsonrpc": "2.0", "method": "panic", "id": 4},
+		{"jsonrpc": "2.0", "method": "missing", "id": 5}
+	]`))
+
+	sizes := server.MethodSizes()
+	assert.Len(t, sizes, 3)
+
+	// [1,2] is 5 bytes and [1,2,...,9] is 19 bytes. The results are "3" and
+	// "45".
+	assert.Equal(t, []uint64{0, 1, 1}, sizes["sum"].Params.Counts)
+	assert.Equal(t, uint64(24), sizes["sum"].Params.Sum)
+	assert.Equal(t, []uint64{2, 0, 0}, sizes["sum"].Result.Counts)
+	assert.Equal(t, uint64(3), sizes["sum"].Result.Sum)
+
+	// Notifications do not send a result.
+	assert.Equal(t, uint64(1), sizes["notify_hello"].Params.Count)
+	assert.Equal(t, uint64(0), sizes["notify_hello"].Result.Count)
+
+	// Requests without params are zero bytes. ["hello",5] is 11 bytes.
+	assert.Equal(t, []uint64{1, 0, 0}, sizes["get_data"].Params.Counts)
+	assert.Equal(t, []uint64{0, 1, 0}, sizes["get_data"].Result.Counts)
+
+	// The copy is not affected by later requests.
+	server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 6}`))
+	assert.Equal(t, uint64(1), sizes["get_data"].Params.Count)
+	assert.Equal(t, uint64(2), server.MethodSizes()["get_data"].Params.Count)
+
+	// Changing the buckets resets the histograms.
+	server.SetSizeBuckets(nil)
+	server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 7}`))
+	assert.Empty(t, server.MethodSizes())
+}
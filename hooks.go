@@ -0,0 +1,110 @@
+package jsonrpc
+
+// RequestHook observes a request, see OnRequest.
+type RequestHook func(request RequestResponder)
+
+// ResponseHook observes a response, see OnResponse and OnError. The request is
+// nil if the payload could not be parsed into a request, such as for a
+// ParseError.
+type ResponseHook func(request RequestResponder, response Response)
+
+// serverHooks are the hooks of a server. They are replaced rather than
+// changed, so they can be used without holding the mutex.
+type serverHooks struct {
+	requests  []RequestHook
+	responses []ResponseHook
+	errors    []ResponseHook
+}
+
+// OnRequest adds a hook that is called with every request that is parsed,
+// including notifications, before it is handled. Hooks observe the server
+// without wrapping any handler, such as for metrics or billing:
+//
+//     server.OnRequest(func(request jsonrpc.RequestResponder) {
+//         calls.WithLabelValues(request.Method()).Inc()
+//     })
+//
+// Hooks are called in the order they were added, on the goroutine that
+// handles the request, so they must be quick and safe for concurrent use.
+// The requests and responses of HandleArena are reused, so hooks must not keep
+// them. Middleware added with Use is a better fit for changing a request.
+func (server *SimpleServer) OnRequest(hook RequestHook) {
+	server.changeHooks(func(hooks *serverHooks) {
+		hooks.requests = append(hooks.requests, hook)
+	})
+}
+
+// OnResponse adds a hook that is called with every response the server
+// sends, after it has been signed. Notifications do not have a response.
+func (server *SimpleServer) OnResponse(hook ResponseHook) {
+	server.changeHooks(func(hooks *serverHooks) {
+		hooks.responses = append(hooks.responses, hook)
+	})
+}
+
+// OnError adds a hook that is called with every error response, before the
+// OnResponse hooks. This includes payloads that cannot be parsed, invalid
+// requests, and notifications that fail (whose error is not sent).
+func (server *SimpleServer) OnError(hook ResponseHook) {
+	server.changeHooks(func(hooks *serverHooks) {
+		hooks.errors = append(hooks.errors, hook)
+	})
+}
+
+func (server *SimpleServer) changeHooks(change func(hooks *serverHooks)) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	hooks := &serverHooks{}
+	if server.hooks != nil {
+		hooks.requests = append(hooks.requests, server.hooks.requests...)
+		hooks.responses = append(hooks.responses, server.hooks.responses...)
+		hooks.errors = append(hooks.errors, server.hooks.errors...)
+	}
+
+	change(hooks)
+	server.hooks = hooks
+}
+
+// respond signs the response and calls the hooks. The request is nil if
+// there is none.
+func (server *SimpleServer) respond(request RequestResponder, response Response) Response {
+	response = server.signResponse(response)
+
+	server.mutex.RLock()
+	hooks := server.hooks
+	server.mutex.RUnlock()
+
+	hooks.observe(request, response, true)
+
+	return response
+}
+
+func (hooks *serverHooks) request(request RequestResponder) {
+	if hooks == nil {
+		return
+	}
+
+	for _, hook := range hooks.requests {
+		hook(request)
+	}
+}
+
+// observe calls the hooks for a response, which is only sent if sent is true.
+func (hooks *serverHooks) observe(request RequestResponder, response Response, sent bool) {
+	if hooks == nil || response == nil {
+		return
+	}
+
+	if response.ErrorCode() != Success {
+		for _, hook := range hooks.errors {
+			hook(request, response)
+		}
+	}
+
+	if sent {
+		for _, hook := range hooks.responses {
+			hook(request, response)
+		}
+	}
+}
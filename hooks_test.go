@@ -0,0 +1,87 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// hookRecorder records the calls to the hooks of a server.
+type hookRecorder struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (recorder *hookRecorder) record(format string, args ...interface{}) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.calls = append(recorder.calls, fmt.Sprintf(format, args...))
+}
+
+func (recorder *hookRecorder) install(server *jsonrpc.SimpleServer) {
+	server.OnRequest(func(request jsonrpc.RequestResponder) {
+		recorder.record("request %s", request.Method())
+	})
+	server.OnResponse(func(request jsonrpc.RequestResponder, response jsonrpc.Response) {
+		recorder.record("response %v %d", response.ID(), response.ErrorCode())
+	})
+	server.OnError(func(request jsonrpc.RequestResponder, response jsonrpc.Response) {
+		method := "-"
+		if request != nil {
+			method = request.Method()
+		}
+		recorder.record("error %s %d", method, response.ErrorCode())
+	})
+}
+
+func TestSimpleServer_Hooks(t *testing.T) {
+	server := newTestServer()
+	recorder := &hookRecorder{}
+	recorder.install(server)
+
+	server.Handle([]byte(`[
+		{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1},
+		{"jsonrpc":"2.0","method":"missing"},
+		{"jsonrpc":"2.0","method":"missing","id":2},
+		{"jsonrpc":"2.0","method":1,"id":3}
+	]`))
+	server.Handle([]byte(`{"jsonrpc"`))
+	server.Handle([]byte(`[]`))
+
+	assert.Equal(t, []string{
+		"request subtract",
+		"response 1 0",
+		"request missing",
+		"error missing -32601",
+		"request missing",
+		"error missing -32601",
+		"response 2 -32601",
+		"error - -32600",
+		"response 3 -32600",
+		"error - -32700",
+		"response <nil> -32700",
+		"error - -32600",
+		"response <nil> -32600",
+	}, recorder.calls)
+}
+
+func TestSimpleServer_HooksAfterShutdown(t *testing.T) {
+	server := newTestServer()
+	recorder := &hookRecorder{}
+	recorder.install(server)
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"notify_hello"}`))
+
+	assert.Equal(t, []string{
+		"error subtract -32000",
+		"response 1 -32000",
+		"error notify_hello -32000",
+	}, recorder.calls)
+}
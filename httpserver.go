@@ -0,0 +1,181 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the largest request body that an HTTPServer reads when
+// MaxBodySize is zero.
+const DefaultMaxBodySize = 1 << 20
+
+// HTTPServer is an http.Handler that serves a single request or a batch sent
+// as the body of a POST:
+//
+//     server := jsonrpc.NewSimpleServer()
+//     server.SetHandler("sayHello", sayHello)
+//
+//     http.Handle("/rpc", jsonrpc.NewHTTPServer(server))
+//
+// The response to a single request is sent as a JSON object and the responses
+// to a batch as an array, with the status 200 OK even if they are errors. The
+// other status codes are:
+//
+//   - 204 No Content when there is nothing to respond with, because the body
+//     only contained notifications.
+//   - 400 Bad Request with a ParseError or InvalidRequest when the body is not
+//     JSON or is an empty batch, or without a JSON-RPC response when the
+//     body cannot be read.
+//   - 405 Method Not Allowed for anything but a POST, or a GET for the
+//     Events.
+//   - 413 Request Entity Too Large with an InvalidRequest when the body is
+//     larger than MaxBodySize.
+//   - 415 Unsupported Media Type when the Content-Type is not JSON. A request
+//     without a Content-Type is accepted.
+//
+// The context of the HTTP request is passed on to the handlers, see
+// ContextFromRequest.
+type HTTPServer struct {
+	Server Server
+
+	// MaxBodySize is the largest body in bytes. Zero uses DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// ClientExtractor puts the ClientInfo of every request in its State. A
+	// ClientExtractor without trusted proxies is used if it is nil.
+	ClientExtractor *ClientExtractor
+
+	// Events, if it is set, serves a GET that accepts text/event-stream, so
+	// clients can receive notifications on the same URL they post calls to.
+	Events *EventStream
+
+	// Receipts, if it is true, sends the root of the Receipt of every
+	// exchange that is answered with 200 OK or 204 No Content in the
+	// ReceiptHeader. The client calculates the receipt with NewReceipt and
+	// compares the roots.
+	Receipts bool
+}
+
+// NewHTTPServer creates an HTTPServer with the default options.
+func NewHTTPServer(server Server) *HTTPServer {
+	return &HTTPServer{Server: server}
+}
+
+// ServeHTTP handles the body of a POST as described by HTTPServer.
+func (server *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if server.Events != nil && r.Method == http.MethodGet &&
+		acceptsEventStream(r) {
+		server.Events.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		allow := http.MethodPost
+		if server.Events != nil {
+			allow = "GET, POST"
+		}
+
+		w.Header().Set("Allow", allow)
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		if !isJSONContentType(contentType) {
+			http.Error(w, "Content-Type must be application/json.",
+				http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	maxBodySize := server.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if errors.As(err, new(*http.MaxBytesError)) {
+		writeHTTPResponse(w, http.StatusRequestEntityTooLarge,
+			NewErrorResponse(nil, InvalidRequest, "Request is too large.").Bytes())
+		return
+	}
+
+	if err != nil {
+		http.Error(w, "Could not read body.", http.StatusBadRequest)
+		return
+	}
+
+	extractor := server.ClientExtractor
+	if extractor == nil {
+		extractor = &ClientExtractor{}
+	}
+
+	responses := server.Server.HandleWithState(body,
+		StateWithContext(withHeaderTrack(extractor.State(r), r), r.Context()))
+
+	// The server does not respond without an id, which includes a body that
+	// cannot be parsed at all. It has still been counted and logged.
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		writeHTTPResponse(w, http.StatusBadRequest,
+			NewErrorResponse(nil, ParseError, "").Bytes())
+		return
+	}
+
+	var batch []json.RawMessage
+	isBatch := body[0] == '['
+	if isBatch && json.Unmarshal(body, &batch) == nil && len(batch) == 0 {
+		writeHTTPResponse(w, http.StatusBadRequest,
+			NewErrorResponse(nil, InvalidRequest, "Batch is empty.").Bytes())
+		return
+	}
+
+	if server.Receipts {
+		if receipt, err := NewReceiptFromJSON(body, responses); err == nil {
+			w.Header().Set(ReceiptHeader, receiptHeaderValue(receipt))
+		}
+	}
+
+	switch {
+	case len(responses) == 0:
+		w.WriteHeader(http.StatusNoContent)
+
+	case isBatch:
+		writeHTTPHeader(w, http.StatusOK)
+		responses.WriteTo(w)
+
+	default:
+		writeHTTPHeader(w, http.StatusOK)
+		WriteResponse(w, responses[0])
+	}
+}
+
+// acceptsEventStream returns true if the Accept header of r includes
+// text/event-stream.
+func acceptsEventStream(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && mediaType == EventStreamContentType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeHTTPResponse(w http.ResponseWriter, status int, body []byte) {
+	writeHTTPHeader(w, status)
+	w.Write(body)
+}
+
+func writeHTTPHeader(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+}
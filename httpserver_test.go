@@ -0,0 +1,112 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestHTTPServer(t *testing.T) {
+	server := httptest.NewServer(jsonrpc.NewHTTPServer(newTestServer()))
+	defer server.Close()
+
+	for name, test := range map[string]struct {
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		"single": {
+			"application/json", `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+			http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":19}`,
+		},
+		"batch": {
+			"application/json", `[{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}]`,
+			http.StatusOK, `[{"jsonrpc":"2.0","id":1,"result":19}]`,
+		},
+		"error": {
+			"application/json; charset=utf-8", `{"jsonrpc":"2.0","method":"missing","id":1}`,
+			http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`,
+		},
+		"notification": {
+			"", `{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`,
+			http.StatusNoContent, ``,
+		},
+		"parse error": {
+			"application/json", `{"jsonrpc"`,
+			http.StatusBadRequest, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+		"empty batch": {
+			"application/json", ` [ ] `,
+			http.StatusBadRequest, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Batch is empty."}}`,
+		},
+		"content type": {
+			"text/plain", `{}`,
+			http.StatusUnsupportedMediaType, "Content-Type must be application/json.\n",
+		},
+		"too large": {
+			"application/json", `{"jsonrpc":"2.0","method":"sum","params":[` +
+				strings.Repeat("1,", 1<<20) + `1],"id":1}`,
+			http.StatusRequestEntityTooLarge,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Request is too large."}}`,
+		},
+	} {
+		request, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(test.body))
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		assert.Equal(t, test.status, response.StatusCode, name)
+		assert.Equal(t, test.response, string(body), name)
+		if test.response != "" && test.status != http.StatusUnsupportedMediaType {
+			assert.Equal(t, "application/json", response.Header.Get("Content-Type"), name)
+		}
+	}
+
+	response, err := http.Get(server.URL)
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+		assert.Equal(t, "POST", response.Header.Get("Allow"))
+	}
+}
+
+func TestHTTPServer_ClientInfo(t *testing.T) {
+	rpc := jsonrpc.NewSimpleServer()
+	rpc.SetHandler("whoami", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(jsonrpc.ClientInfoFromRequest(request).Address)
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"whoami","id":1}`))
+	request.RemoteAddr = "192.0.2.1:1234"
+	jsonrpc.NewHTTPServer(rpc).ServeHTTP(recorder, request)
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"192.0.2.1"}`, recorder.Body.String())
+}
+
+func TestHTTPServer_ReadError(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/",
+		iotest.ErrReader(errors.New("Connection reset.")))
+	recorder := httptest.NewRecorder()
+	jsonrpc.NewHTTPServer(newTestServer()).ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "Could not read body.\n", recorder.Body.String())
+}
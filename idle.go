@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// IdleTimeoutMethod is the method of the notification sent to a client before
+// its connection is closed for being idle. The params are an object with the
+// "timeout" in seconds.
+const IdleTimeoutMethod = "rpc.idleTimeout"
+
+// IdleReaper closes connections that have not sent a request for Timeout, so
+// that connections leaked by clients do not accumulate. A connection is only
+// idle while it is waiting for its next message; time spent in a handler does
+// not count.
+//
+//     reaper := &jsonrpc.IdleReaper{Timeout: 10 * time.Minute}
+//
+//     for {
+//         conn, err := listener.Accept()
+//         if err != nil {
+//             return err
+//         }
+//
+//         go reaper.Serve(server, conn,
+//             jsonrpc.NewLineFramer(conn, jsonrpc.RecoverySkip, 0), nil)
+//     }
+//
+// It is safe for concurrent use.
+type IdleReaper struct {
+	// Timeout is how long a connection may be idle. Zero, the default, does
+	// not close idle connections.
+	Timeout time.Duration
+
+	// Clock is used to time the connections. SystemClock is used if it is nil.
+	Clock Clock
+
+	open   int64
+	reaped uint64
+}
+
+// idleFramer reports when the server starts and stops waiting for a message.
+type idleFramer struct {
+	Framer
+	waiting chan bool
+}
+
+func (framer *idleFramer) ReadFrame() ([]byte, error) {
+	framer.waiting <- true
+	defer func() {
+		framer.waiting <- false
+	}()
+
+	return framer.Framer.ReadFrame()
+}
+
+// Serve is ServeFramer, except that the connection is closed if it is idle for
+// Timeout. Before closing it, an IdleTimeoutMethod notification is sent. A
+// connection that was closed for being idle returns nil.
+func (reaper *IdleReaper) Serve(server *SimpleServer, conn io.Closer, framer Framer,
+	state State) error {
+	atomic.AddInt64(&reaper.open, 1)
+	defer atomic.AddInt64(&reaper.open, -1)
+
+	if reaper.Timeout <= 0 {
+		return server.ServeFramer(framer, state)
+	}
+
+	watched := &idleFramer{framer, make(chan bool)}
+	done := make(chan struct{})
+	reaped := make(chan struct{})
+
+	go func() {
+		var timeout <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+
+			case waiting := <-watched.waiting:
+				timeout = nil
+				if waiting {
+					timeout = clockOrSystem(reaper.Clock).After(reaper.Timeout)
+				}
+
+			case <-timeout:
+				close(reaped)
+				atomic.AddUint64(&reaper.reaped, 1)
+
+				notification := NewRequestResponder("2.0", nil, IdleTimeoutMethod,
+					map[string]interface{}{"timeout": reaper.Timeout.Seconds()})
+				// A client that is not reading must not stop the connection
+				// from being closed.
+				if c, ok := conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+					c.SetWriteDeadline(time.Now().Add(time.Second))
+				}
+
+				framer.WriteFrame(notification.Bytes())
+				conn.Close()
+
+				// Let the reading goroutine finish.
+				for {
+					select {
+					case <-watched.waiting:
+					case <-done:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	err := server.ServeFramer(watched, state)
+	close(done)
+
+	select {
+	case <-reaped:
+		return nil
+
+	default:
+		return err
+	}
+}
+
+// Open returns the number of connections being served.
+func (reaper *IdleReaper) Open() int64 {
+	return atomic.LoadInt64(&reaper.open)
+}
+
+// Reaped returns the number of connections that have been closed for being
+// idle.
+func (reaper *IdleReaper) Reaped() uint64 {
+	return atomic.LoadUint64(&reaper.reaped)
+}
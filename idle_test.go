@@ -0,0 +1,93 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestIdleReaper(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	reaper := &jsonrpc.IdleReaper{Timeout: time.Minute, Clock: clock}
+
+	done := make(chan error)
+	go func() {
+		done <- reaper.Serve(newTestServer(), serverConn,
+			jsonrpc.NewLineFramer(serverConn, jsonrpc.RecoverySkip, 0), nil)
+	}()
+
+	client := bufio.NewReader(clientConn)
+
+	// Activity restarts the timeout.
+	waitForWaiter(clock)
+	clock.Advance(50 * time.Second)
+	clientConn.Write([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}` + "\n"))
+	line, err := client.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":3}`+"\n", line)
+	assert.Equal(t, int64(1), reaper.Open())
+
+	// The timer from before the request has not fired yet, so wait for the
+	// new one as well.
+	for clock.Waiters() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, uint64(0), reaper.Reaped())
+	clock.Advance(10 * time.Second)
+
+	line, err = client.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","method":"rpc.idleTimeout","params":{"timeout":60}}`+"\n", line)
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint64(1), reaper.Reaped())
+	assert.Equal(t, int64(0), reaper.Open())
+}
+
+func TestIdleReaper_ClientCloses(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	reaper := &jsonrpc.IdleReaper{Timeout: time.Minute, Clock: jsonrpc.NewFakeClock(epoch)}
+
+	done := make(chan error)
+	go func() {
+		done <- reaper.Serve(newTestServer(), serverConn,
+			jsonrpc.NewLineFramer(serverConn, jsonrpc.RecoverySkip, 0), nil)
+	}()
+
+	clientConn.Close()
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint64(0), reaper.Reaped())
+}
+
+func TestIdleReaper_NoTimeout(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	clock := jsonrpc.NewFakeClock(epoch)
+	reaper := &jsonrpc.IdleReaper{Clock: clock}
+
+	done := make(chan error)
+	go func() {
+		done <- reaper.Serve(newTestServer(), serverConn,
+			jsonrpc.NewLineFramer(serverConn, jsonrpc.RecoverySkip, 0), nil)
+	}()
+
+	client := bufio.NewReader(clientConn)
+
+	clock.Advance(time.Hour)
+	clientConn.Write([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}` + "\n"))
+	line, err := client.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":3}`+"\n", line)
+	assert.Equal(t, 0, clock.Waiters())
+
+	clientConn.Close()
+	assert.NoError(t, <-done)
+	assert.Equal(t, uint64(0), reaper.Reaped())
+}
@@ -0,0 +1,134 @@
+package jsonrpc
+
+import "math"
+
+// IDPolicy restricts the type of request ids the server accepts. Requests
+// with an id that breaks the policy are answered with InvalidRequest without
+// calling the handler. Notifications are not affected, but a null id is a
+// request (see Request.IsNotification) and is only accepted by AnyID and
+// NormalizedID.
+type IDPolicy int
+
+const (
+	// AnyID accepts any id and leaves it as it was decoded. This is the
+	// default.
+	AnyID IDPolicy = iota
+
+	// NormalizedID accepts any id, but converts numbers that are whole into
+	// int64 so that handlers, stores and logs see consistent types.
+	NormalizedID
+
+	// StringID only accepts string ids.
+	StringID
+
+	// IntegerID only accepts whole numbers, which are converted into int64.
+	IntegerID
+)
+
+// SetIDPolicy sets the policy for the ids of requests.
+func (server *SimpleServer) SetIDPolicy(policy IDPolicy) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.idPolicy = policy
+}
+
+// apply returns the id normalized by the policy, or a message explaining why
+// it is not allowed.
+func (policy IDPolicy) apply(id interface{}, notification bool) (interface{}, string) {
+	if notification || policy == AnyID {
+		return id, ""
+	}
+
+	switch id := id.(type) {
+	case nil:
+		switch policy {
+		case StringID:
+			return nil, "ID must be a string."
+
+		case IntegerID:
+			return nil, "ID must be an integer."
+		}
+
+		return nil, ""
+
+	case string:
+		if policy == IntegerID {
+			return nil, "ID must be an integer."
+		}
+
+		return id, ""
+
+	case float64:
+		if policy == StringID {
+			return nil, "ID must be a string."
+		}
+
+		if id == math.Trunc(id) && math.Abs(id) <= 1<<53 {
+			return int64(id), ""
+		}
+
+		if policy == IntegerID {
+			return nil, "ID must be an integer."
+		}
+
+		return id, ""
+
+	case int, int32, int64, uint, uint32, uint64:
+		if policy == StringID {
+			return nil, "ID must be a string."
+		}
+
+		return toInt64(id), ""
+	}
+
+	return nil, "ID must be a string or a number."
+}
+
+func toInt64(id interface{}) int64 {
+	switch id := id.(type) {
+	case int:
+		return int64(id)
+
+	case int32:
+		return int64(id)
+
+	case uint:
+		return int64(id)
+
+	case uint32:
+		return int64(id)
+
+	case uint64:
+		return int64(id)
+	}
+
+	return id.(int64)
+}
+
+// idRequest replaces the id of a request, including in its responses.
+type idRequest struct {
+	RequestResponder
+	id interface{}
+}
+
+func (request *idRequest) ID() interface{} {
+	return request.id
+}
+
+func (request *idRequest) NewSuccessResponse(result interface{}) Response {
+	return NewSuccessResponse(request.id, result)
+}
+
+func (request *idRequest) NewErrorResponse(code int, message string) Response {
+	return NewErrorResponse(request.id, code, message)
+}
+
+func (request *idRequest) NewErrorResponseWithData(code int, message string,
+	data interface{}) Response {
+	return NewErrorResponseWithData(request.id, code, message, data)
+}
+
+func (request *idRequest) NewServerErrorResponse(err error) Response {
+	return NewServerErrorResponse(request.id, err)
+}
@@ -0,0 +1,55 @@
+package jsonrpc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_SetIDPolicy(t *testing.T) {
+	for name, test := range map[string]struct {
+		policy jsonrpc.IDPolicy
+		id     string
+		result string
+	}{
+		"any keeps float":       {jsonrpc.AnyID, `1`, `{"jsonrpc":"2.0","id":1,"result":"float64"}`},
+		"normalized integer":    {jsonrpc.NormalizedID, `1`, `{"jsonrpc":"2.0","id":1,"result":"int64"}`},
+		"normalized fraction":   {jsonrpc.NormalizedID, `1.5`, `{"jsonrpc":"2.0","id":1.5,"result":"float64"}`},
+		"normalized string":     {jsonrpc.NormalizedID, `"a"`, `{"jsonrpc":"2.0","id":"a","result":"string"}`},
+		"string accepted":       {jsonrpc.StringID, `"a"`, `{"jsonrpc":"2.0","id":"a","result":"string"}`},
+		"string rejects number": {jsonrpc.StringID, `1`, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"ID must be a string."}}`},
+		"integer accepted":      {jsonrpc.IntegerID, `7`, `{"jsonrpc":"2.0","id":7,"result":"int64"}`},
+		"integer rejects float": {jsonrpc.IntegerID, `1.5`, `{"jsonrpc":"2.0","id":1.5,"error":{"code":-32600,"message":"ID must be an integer."}}`},
+		"integer rejects str":   {jsonrpc.IntegerID, `"a"`, `{"jsonrpc":"2.0","id":"a","error":{"code":-32600,"message":"ID must be an integer."}}`},
+		"normalized null":       {jsonrpc.NormalizedID, `null`, `{"jsonrpc":"2.0","id":null,"result":"\u003cnil\u003e"}`},
+		"string rejects null":   {jsonrpc.StringID, `null`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"ID must be a string."}}`},
+		"integer rejects null":  {jsonrpc.IntegerID, `null`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"ID must be an integer."}}`},
+	} {
+		server := jsonrpc.NewSimpleServer()
+		server.SetIDPolicy(test.policy)
+		server.SetHandler("type", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			return request.NewSuccessResponse(fmt.Sprintf("%T", request.ID()))
+		})
+
+		responses := server.Handle([]byte(`{"jsonrpc":"2.0","method":"type","id":` +
+			test.id + `}`))
+		if assert.Len(t, responses, 1, name) {
+			assert.Equal(t, test.result, responses[0].String(), name)
+		}
+	}
+}
+
+func TestSimpleServer_SetIDPolicy_Notification(t *testing.T) {
+	called := false
+	server := jsonrpc.NewSimpleServer()
+	server.SetIDPolicy(jsonrpc.StringID)
+	server.SetHandler("hello", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		called = true
+		return nil
+	})
+
+	assert.Empty(t, server.Handle([]byte(`{"jsonrpc":"2.0","method":"hello"}`)))
+	assert.True(t, called)
+}
@@ -0,0 +1,239 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// EventHandler handles a notification received by an Ingester. The params are
+// passed as they were received so that the handler only pays for decoding what
+// it needs. They must not be retained after the handler returns.
+type EventHandler func(method string, params json.RawMessage, state State)
+
+// Ingester is a server for "fire and forget" notifications, such as telemetry
+// or events sent to a collector. It skips the request and response machinery
+// of a SimpleServer entirely: messages are decoded into their method and raw
+// params, the handler is called and nothing is ever sent back.
+//
+// Messages that are not notifications (they have an id, even a null one, see
+// Request.IsNotification), are not valid JSON-RPC 2.0 or have no handler are
+// dropped and counted by Rejected.
+//
+//     ingester := jsonrpc.NewIngester()
+//     ingester.SetHandler("event", func(method string, params json.RawMessage,
+//         state jsonrpc.State) {
+//         queue <- append([]byte(nil), params...)
+//     })
+//
+//     http.Handle("/ingest", jsonrpc.IngestHandler(ingester))
+//
+// It is safe for concurrent use.
+type Ingester struct {
+	mutex    sync.RWMutex
+	handlers map[string]EventHandler
+	fallback EventHandler
+
+	received uint64
+	rejected uint64
+}
+
+// event is the subset of a request that an Ingester decodes.
+type event struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+
+	// notification is true if the message does not have an id member.
+	notification bool
+}
+
+// NewIngester creates an Ingester without any handlers.
+func NewIngester() *Ingester {
+	return &Ingester{handlers: map[string]EventHandler{}}
+}
+
+// SetHandler sets (or replaces) the handler for a method. A nil handler
+// removes it.
+func (ingester *Ingester) SetHandler(method string, handler EventHandler) {
+	ingester.mutex.Lock()
+	defer ingester.mutex.Unlock()
+
+	if handler == nil {
+		delete(ingester.handlers, method)
+		return
+	}
+
+	ingester.handlers[method] = handler
+}
+
+// SetFallback sets the handler for methods that do not have their own. A nil
+// handler removes it, so that those notifications are rejected.
+func (ingester *Ingester) SetFallback(handler EventHandler) {
+	ingester.mutex.Lock()
+	defer ingester.mutex.Unlock()
+
+	ingester.fallback = handler
+}
+
+// Received is the number of notifications that were passed to a handler.
+func (ingester *Ingester) Received() uint64 {
+	return atomic.LoadUint64(&ingester.received)
+}
+
+// Rejected is the number of messages that were dropped, including those that
+// could not be parsed and those whose handler panicked.
+func (ingester *Ingester) Rejected() uint64 {
+	return atomic.LoadUint64(&ingester.rejected)
+}
+
+// Ingest handles a single notification or a batch of them. An error is only
+// returned if data is not valid JSON; invalid members of a batch are rejected
+// without affecting the others.
+func (ingester *Ingester) Ingest(data []byte, state State) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var members []json.RawMessage
+		if err := json.Unmarshal(data, &members); err != nil {
+			return ingester.parseError(data)
+		}
+
+		for _, member := range members {
+			ingester.ingest(member, state)
+		}
+
+		return nil
+	}
+
+	return ingester.ingest(data, state)
+}
+
+func (ingester *Ingester) ingest(data []byte, state State) error {
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return ingester.parseError(data)
+	}
+	e.notification = !hasMember(data, "id")
+
+	ingester.dispatch(&e, state)
+
+	return nil
+}
+
+// IngestStream handles newline delimited notifications from r until it has
+// been read to the end. Lines that cannot be parsed or are longer than
+// DefaultMaxFrameSize are rejected and the stream continues. It only returns
+// an error if reading fails.
+func (ingester *Ingester) IngestStream(r io.Reader, state State) error {
+	reader := bufio.NewReader(r)
+
+	for {
+		line, tooLong, err := readLine(reader, DefaultMaxFrameSize)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if tooLong {
+			atomic.AddUint64(&ingester.rejected, 1)
+			continue
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			ingester.Ingest(line, state)
+		}
+	}
+}
+
+// parseError counts a message that could not be decoded. Valid JSON that does
+// not have the shape of a request is only rejected, not a parse error.
+func (ingester *Ingester) parseError(data []byte) error {
+	atomic.AddUint64(&ingester.rejected, 1)
+
+	if json.Valid(data) {
+		return nil
+	}
+
+	return &RPCError{Code: ParseError, Message: ErrorMessageForCode(ParseError)}
+}
+
+func (ingester *Ingester) dispatch(e *event, state State) {
+	if e.Version != "2.0" || e.Method == "" || !e.notification {
+		atomic.AddUint64(&ingester.rejected, 1)
+		return
+	}
+
+	ingester.mutex.RLock()
+	handler := ingester.handlers[e.Method]
+	if handler == nil {
+		handler = ingester.fallback
+	}
+	ingester.mutex.RUnlock()
+
+	if handler == nil {
+		atomic.AddUint64(&ingester.rejected, 1)
+		return
+	}
+
+	defer func() {
+		if recover() != nil {
+			atomic.AddUint64(&ingester.rejected, 1)
+		}
+	}()
+
+	handler(e.Method, e.Params, state)
+	atomic.AddUint64(&ingester.received, 1)
+}
+
+// IngestHandler returns an HTTP handler that accepts a POST of a notification
+// or a batch of them and responds with 202 Accepted and no body. A body with
+// the NDJSONContentType is read as a stream, one notification per line, so a
+// client can send events for as long as it keeps the request open. A body that
+// is not valid JSON is answered with 400 Bad Request, and any other body larger
+// than DefaultMaxBodySize with 413 Request Entity Too Large.
+func IngestHandler(ingester *Ingester) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), NDJSONContentType) {
+			if err := ingester.IngestStream(r.Body, nil); err != nil {
+				http.Error(w, "Could not read body.", http.StatusBadRequest)
+				return
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBodySize))
+		if errors.As(err, new(*http.MaxBytesError)) {
+			http.Error(w, "Request is too large.", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, "Could not read body.", http.StatusBadRequest)
+			return
+		}
+
+		if err := ingester.Ingest(data, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,138 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// eventRecorder collects the notifications passed to its handler.
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (recorder *eventRecorder) handle(method string, params json.RawMessage,
+	state jsonrpc.State) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.events = append(recorder.events, method+" "+string(params))
+}
+
+func TestIngester_Ingest(t *testing.T) {
+	recorder := &eventRecorder{}
+	ingester := jsonrpc.NewIngester()
+	ingester.SetHandler("event", recorder.handle)
+	ingester.SetHandler("panic", func(string, json.RawMessage, jsonrpc.State) {
+		panic("uh-oh!")
+	})
+
+	assert.NoError(t, ingester.Ingest([]byte(`{"jsonrpc":"2.0","method":"event","params":{"a":1}}`), nil))
+	assert.NoError(t, ingester.Ingest([]byte(`[
+		{"jsonrpc":"2.0","method":"event","params":[1, 2]},
+		{"jsonrpc":"2.0","method":"event","id":1},
+		{"jsonrpc":"1.0","method":"event"},
+		{"jsonrpc":"2.0","method":"missing"},
+		{"jsonrpc":"2.0","method":"panic"},
+		{"jsonrpc":"2.0","method":"event","id":null},
+		1
+	]`), nil))
+
+	err := ingester.Ingest([]byte(`{"jsonrpc":`), nil)
+	assert.EqualError(t, err, "Parse error (-32700)")
+
+	assert.Equal(t, []string{
+		`event {"a":1}`,
+		`event [1, 2]`,
+	}, recorder.events)
+	assert.Equal(t, uint64(2), ingester.Received())
+	assert.Equal(t, uint64(7), ingester.Rejected())
+}
+
+func TestIngester_SetFallback(t *testing.T) {
+	recorder := &eventRecorder{}
+	ingester := jsonrpc.NewIngester()
+	ingester.SetFallback(recorder.handle)
+
+	ingester.Ingest([]byte(`{"jsonrpc":"2.0","method":"anything"}`), nil)
+	assert.Equal(t, []string{"anything "}, recorder.events)
+
+	ingester.SetFallback(nil)
+	ingester.Ingest([]byte(`{"jsonrpc":"2.0","method":"anything"}`), nil)
+	assert.Equal(t, uint64(1), ingester.Rejected())
+}
+
+func TestIngestHandler(t *testing.T) {
+	recorder := &eventRecorder{}
+	ingester := jsonrpc.NewIngester()
+	ingester.SetHandler("event", recorder.handle)
+
+	server := httptest.NewServer(jsonrpc.IngestHandler(ingester))
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"event","params":1}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		response.Body.Close()
+	}
+
+	response, err = http.Post(server.URL, jsonrpc.NDJSONContentType,
+		strings.NewReader("{\"jsonrpc\":\"2.0\",\"method\":\"event\",\"params\":2}\n"+
+			"not json\n\n"+
+			"{\"jsonrpc\":\"2.0\",\"method\":\"event\",\"params\":3}"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		response.Body.Close()
+	}
+
+	response, err = http.Post(server.URL, "application/json", strings.NewReader(`{`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		response.Body.Close()
+	}
+
+	response, err = http.Get(server.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+		response.Body.Close()
+	}
+
+	assert.Equal(t, []string{"event 1", "event 2", "event 3"}, recorder.events)
+	assert.Equal(t, uint64(2), ingester.Rejected())
+}
+
+func TestIngestHandler_TooLarge(t *testing.T) {
+	recorder := &eventRecorder{}
+	ingester := jsonrpc.NewIngester()
+	ingester.SetHandler("event", recorder.handle)
+
+	server := httptest.NewServer(jsonrpc.IngestHandler(ingester))
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`"`+strings.Repeat("a", jsonrpc.DefaultMaxBodySize)+`"`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+		response.Body.Close()
+	}
+
+	// A line that is too long is rejected and the stream continues.
+	response, err = http.Post(server.URL, jsonrpc.NDJSONContentType,
+		strings.NewReader(`"`+strings.Repeat("a", jsonrpc.DefaultMaxFrameSize)+"\"\n"+
+			`{"jsonrpc":"2.0","method":"event","params":1}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+		response.Body.Close()
+	}
+
+	assert.Equal(t, []string{"event 1"}, recorder.events)
+	assert.Equal(t, uint64(1), ingester.Rejected())
+}
@@ -0,0 +1,119 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CallInfo describes the outcome of a single call made through an
+// InstrumentedInvoker.
+type CallInfo struct {
+	Method string
+
+	// Attempts is the number of times the request was sent, which is more
+	// than one if it was retried. It is one if the transport did not call
+	// RecordAttempt.
+	Attempts int
+
+	// Duration is the time from the start of the call until the final
+	// response, including any retries.
+	Duration time.Duration
+
+	// BytesSent and BytesReceived are the sizes of every request and response
+	// that the transport reported with RecordAttempt.
+	BytesSent     int
+	BytesReceived int
+
+	// Endpoint is the endpoint of the last attempt, if the transport reported
+	// one.
+	Endpoint string
+
+	// Err is the error returned by the call, or an *RPCError if the server
+	// sent an error response. It is nil if the call succeeded.
+	Err error
+}
+
+type callInfoKey struct{}
+
+// callRecord collects the attempts of a call. Transports may send attempts
+// concurrently, such as when a request is hedged.
+type callRecord struct {
+	mutex sync.Mutex
+	info  CallInfo
+}
+
+// RecordAttempt is called by transports (and layers that retry) each time a
+// request is sent, so that the attempt is included in the CallInfo of the
+// call. It does nothing if the call is not being instrumented.
+func RecordAttempt(ctx context.Context, endpoint string, bytesSent, bytesReceived int) {
+	record, ok := ctx.Value(callInfoKey{}).(*callRecord)
+	if !ok {
+		return
+	}
+
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	record.info.Attempts++
+	record.info.BytesSent += bytesSent
+	record.info.BytesReceived += bytesReceived
+	if endpoint != "" {
+		record.info.Endpoint = endpoint
+	}
+}
+
+// InstrumentedInvoker is an Invoker that reports the outcome of every call to
+// OnCallDone, so that applications can feed their own metrics or logging
+// systems:
+//
+//     invoker := &jsonrpc.InstrumentedInvoker{
+//         Invoker: transport,
+//         OnCallDone: func(info jsonrpc.CallInfo) {
+//             callDuration.WithLabelValues(info.Method).
+//                 Observe(info.Duration.Seconds())
+//         },
+//     }
+//
+type InstrumentedInvoker struct {
+	Invoker Invoker
+
+	// OnCallDone is called once for every call, after the final response (or
+	// error) has been received. It must be safe for concurrent use.
+	OnCallDone func(info CallInfo)
+
+	// Clock is used to time calls. SystemClock is used if it is nil.
+	Clock Clock
+}
+
+// Invoke sends the request with the wrapped Invoker and reports the outcome.
+func (invoker *InstrumentedInvoker) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	clock := clockOrSystem(invoker.Clock)
+	record := &callRecord{}
+	start := clock.Now()
+
+	response, err := invoker.Invoker.Invoke(
+		context.WithValue(ctx, callInfoKey{}, record), method, params)
+
+	record.mutex.Lock()
+	info := record.info
+	record.mutex.Unlock()
+
+	info.Method = method
+	info.Duration = clock.Now().Sub(start)
+	if info.Attempts == 0 {
+		info.Attempts = 1
+	}
+
+	info.Err = err
+	if err == nil && response != nil {
+		info.Err = ErrorFromResponse(response)
+	}
+
+	if invoker.OnCallDone != nil {
+		invoker.OnCallDone(info)
+	}
+
+	return response, err
+}
@@ -0,0 +1,67 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestInstrumentedInvoker(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	var calls []jsonrpc.CallInfo
+	invoker := &jsonrpc.InstrumentedInvoker{
+		Invoker: jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+			params interface{}) (jsonrpc.Response, error) {
+			clock.Advance(3 * time.Millisecond)
+			return serverInvoker(newTestServer()).Invoke(ctx, method, params)
+		}),
+		OnCallDone: func(info jsonrpc.CallInfo) {
+			calls = append(calls, info)
+		},
+		Clock: clock,
+	}
+
+	response, err := invoker.Invoke(context.Background(), "subtract", []int{42, 23})
+	assert.NoError(t, err)
+	assert.Equal(t, 19.0, response.Result())
+
+	_, err = invoker.Invoke(context.Background(), "missing", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []jsonrpc.CallInfo{
+		{Method: "subtract", Attempts: 1, Duration: 3 * time.Millisecond},
+		{Method: "missing", Attempts: 1, Duration: 3 * time.Millisecond,
+			Err: &jsonrpc.RPCError{Code: jsonrpc.MethodNotFound, Message: "Method not found"}},
+	}, calls)
+}
+
+func TestInstrumentedInvoker_RecordAttempt(t *testing.T) {
+	var info jsonrpc.CallInfo
+	failure := errors.New("connection refused")
+	invoker := &jsonrpc.InstrumentedInvoker{
+		Invoker: jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+			params interface{}) (jsonrpc.Response, error) {
+			jsonrpc.RecordAttempt(ctx, "10.0.0.1:8080", 50, 0)
+			jsonrpc.RecordAttempt(ctx, "10.0.0.2:8080", 50, 0)
+			return nil, failure
+		}),
+		OnCallDone: func(i jsonrpc.CallInfo) {
+			info = i
+		},
+	}
+
+	_, err := invoker.Invoke(context.Background(), "sum", []int{1})
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 2, info.Attempts)
+	assert.Equal(t, 100, info.BytesSent)
+	assert.Equal(t, 0, info.BytesReceived)
+	assert.Equal(t, "10.0.0.2:8080", info.Endpoint)
+	assert.Equal(t, failure, info.Err)
+
+	// Recording outside of an instrumented call is ignored.
+	jsonrpc.RecordAttempt(context.Background(), "", 1, 1)
+}
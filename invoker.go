@@ -0,0 +1,77 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// Invoker sends a single request and returns the response. It is the
+// client-side counterpart to a RequestHandler and allows client helpers to be
+// used with any transport.
+//
+// An error is only returned when a response could not be obtained. A response
+// that contains a JSON-RPC error is not an error for an Invoker, use
+// ErrorFromResponse to convert it.
+type Invoker interface {
+	Invoke(ctx context.Context, method string, params interface{}) (Response, error)
+}
+
+// InvokerFunc allows an ordinary function to be used as an Invoker.
+type InvokerFunc func(ctx context.Context, method string,
+	params interface{}) (Response, error)
+
+// Invoke calls f(ctx, method, params)
+func (f InvokerFunc) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	return f(ctx, method, params)
+}
+
+// RPCError is a JSON-RPC error object that has been returned as a Go error.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+// Error returns the message and code, such as "Method not found (-32601)".
+func (err *RPCError) Error() string {
+	return err.Message + " (" + strconv.Itoa(err.Code) + ")"
+}
+
+// ErrorFromResponse returns an *RPCError if the response contains an error,
+// otherwise nil is returned.
+func ErrorFromResponse(response Response) error {
+	if response.ErrorCode() == Success {
+		return nil
+	}
+
+	return &RPCError{
+		Code:    response.ErrorCode(),
+		Message: response.ErrorMessage(),
+		Data:    response.ErrorData(),
+	}
+}
+
+// decodeResult decodes the result of a successful response into target, which
+// must be a pointer. An *RPCError is returned for an error response.
+//
+// A result that was received as JSON is decoded as it was received, so an
+// int64 or a json.Number gets every digit of a number that a float64 cannot
+// hold. Numbers decoded into an interface{} are still float64.
+func decodeResult(r Response, target interface{}) error {
+	if err := ErrorFromResponse(r); err != nil {
+		return err
+	}
+
+	if received, ok := r.(*response); ok && received.rawResult != nil {
+		return json.Unmarshal(received.rawResult, target)
+	}
+
+	b, err := json.Marshal(r.Result())
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
@@ -0,0 +1,32 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestErrorFromResponse(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		err := jsonrpc.ErrorFromResponse(jsonrpc.NewSuccessResponse(1, "foo"))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		err := jsonrpc.ErrorFromResponse(
+			jsonrpc.NewErrorResponseWithData(1, jsonrpc.MethodNotFound, "", "foo"))
+
+		assert.EqualError(t, err, "Method not found (-32601)")
+
+		var rpcErr *jsonrpc.RPCError
+		assert.True(t, errors.As(err, &rpcErr))
+		assert.Equal(t, &jsonrpc.RPCError{
+			Code:    jsonrpc.MethodNotFound,
+			Message: "Method not found",
+			Data:    "foo",
+		}, rpcErr)
+	})
+}
@@ -0,0 +1,325 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a request that was written to a Journal.
+type JournalEntry struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request"`
+}
+
+// journalLine is a line of the journal file. It is either an entry or the
+// completion marker of an entry.
+type journalLine struct {
+	JournalEntry
+	Done bool `json:"done,omitempty"`
+}
+
+// Journal is a write-ahead log of the requests a server has accepted, so that
+// after a crash the server can report which requests were in flight and run
+// the ones that are safe to repeat again:
+//
+//     journal, err := jsonrpc.OpenJournal("/var/lib/app/requests.journal")
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     for _, entry := range journal.InFlight() {
+//         log.Printf("request %d (%s) did not finish", entry.Seq, entry.Method)
+//     }
+//     journal.Replay(ctx, server, nil)
+//     journal.Discard()
+//
+//     payments := server.Group(journal.Middleware())
+//
+// Every request (but not notifications) is written to the journal before its
+// handler is called, and a completion marker is written once the handler has
+// returned. Each is a single write, so they survive the process crashing. Set
+// Sync to also survive the machine crashing, at the cost of an fsync for each.
+// The journal holds the params of every request, so it can be encrypted at
+// rest with OpenEncryptedJournal.
+//
+// It is safe for concurrent use.
+type Journal struct {
+	// Sync flushes every write to disk. It must be set before the journal is
+	// used.
+	Sync bool
+
+	// Clock is used to timestamp entries. SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex     sync.Mutex
+	file      *os.File
+	encryptor *Encryptor
+	seq       uint64
+	inFlight  map[uint64]JournalEntry
+}
+
+// OpenJournal opens (or creates) the journal at path. The entries that were
+// not completed when the journal was last used are available from InFlight.
+// The journal is compacted so that it only contains those entries.
+func OpenJournal(path string) (*Journal, error) {
+	return OpenEncryptedJournal(path, nil)
+}
+
+// OpenEncryptedJournal is OpenJournal for a journal whose entries are
+// encrypted at rest with the encryptor. Entries of a journal that was not
+// encrypted are still read, and are encrypted when it is compacted. A nil
+// encryptor does not encrypt.
+func OpenEncryptedJournal(path string, encryptor *Encryptor) (*Journal, error) {
+	journal := &Journal{
+		encryptor: encryptor,
+		inFlight:  map[uint64]JournalEntry{},
+	}
+
+	if file, err := os.Open(path); err == nil {
+		err = journal.read(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// The remaining entries are written to a new file that replaces the old
+	// one, so that a crash while compacting loses nothing.
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(temp)
+	for _, entry := range journal.InFlight() {
+		data, err := journal.encode(journalLine{JournalEntry: entry})
+		if err != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+			return nil, err
+		}
+
+		writer.Write(data)
+	}
+
+	err = writer.Flush()
+	if err == nil {
+		err = temp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+
+	journal.file = temp
+
+	return journal, nil
+}
+
+// read collects the entries without a completion marker. A line that cannot
+// be decoded, such as one that was being written during a crash, is ignored.
+// It is an error if an encrypted line cannot be decrypted.
+func (journal *Journal) read(file *os.File) error {
+	reader := bufio.NewReader(file)
+
+	for {
+		data, err := reader.ReadBytes('\n')
+		if err != nil {
+			// The last line is incomplete if it has no newline.
+			return nil
+		}
+
+		data = bytes.TrimSpace(data)
+		if IsEncryptedRecord(data) {
+			if journal.encryptor == nil {
+				return errors.New("Journal is encrypted.")
+			}
+
+			if data, err = journal.encryptor.Open(data); err != nil {
+				return err
+			}
+		}
+
+		var line journalLine
+		if json.Unmarshal(data, &line) != nil {
+			continue
+		}
+
+		if line.Seq > journal.seq {
+			journal.seq = line.Seq
+		}
+
+		if line.Done {
+			delete(journal.inFlight, line.Seq)
+		} else {
+			journal.inFlight[line.Seq] = line.JournalEntry
+		}
+	}
+}
+
+// InFlight returns the entries that were not completed when the journal was
+// opened, and have not been completed since, in the order they were accepted.
+func (journal *Journal) InFlight() []JournalEntry {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	entries := make([]JournalEntry, 0, len(journal.inFlight))
+	for _, entry := range journal.inFlight {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Seq < entries[j].Seq
+	})
+
+	return entries
+}
+
+// Begin writes a request to the journal and returns its sequence number, to be
+// passed to Complete.
+func (journal *Journal) Begin(request Request) (uint64, error) {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	journal.seq++
+	entry := JournalEntry{
+		Seq:     journal.seq,
+		Time:    clockOrSystem(journal.Clock).Now(),
+		Method:  request.Method(),
+		Request: request.Bytes(),
+	}
+
+	return entry.Seq, journal.write(journalLine{JournalEntry: entry})
+}
+
+// Complete writes the completion marker of an entry.
+func (journal *Journal) Complete(seq uint64) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	delete(journal.inFlight, seq)
+
+	return journal.write(journalLine{JournalEntry: JournalEntry{Seq: seq}, Done: true})
+}
+
+// encode returns the line as it is written to the file.
+func (journal *Journal) encode(line journalLine) ([]byte, error) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if journal.encryptor != nil {
+		if data, err = journal.encryptor.Seal(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(data, '\n'), nil
+}
+
+func (journal *Journal) write(line journalLine) error {
+	data, err := journal.encode(line)
+	if err != nil {
+		return err
+	}
+
+	if _, err := journal.file.Write(data); err != nil {
+		return err
+	}
+
+	if journal.Sync {
+		return journal.file.Sync()
+	}
+
+	return nil
+}
+
+// Middleware returns a middleware that writes every request to the journal
+// before calling the handler, and completes it when the handler returns or
+// panics. A request that cannot be written to the journal is not handled, and
+// is answered with a ServerError.
+func (journal *Journal) Middleware() Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			if request.IsNotification() {
+				return next(request)
+			}
+
+			seq, err := journal.Begin(request)
+			if err != nil {
+				return request.NewServerErrorResponse(err)
+			}
+			defer journal.Complete(seq)
+
+			return next(request)
+		}
+	}
+}
+
+// Replay handles the in-flight entries that are safe to repeat again, in the
+// order they were accepted, and completes them. The responses are returned,
+// as there is no client waiting for them. A nil idempotent only repeats the
+// methods that the server describes as ReadOnly (see MethodInfo).
+//
+// The other entries are left in InFlight.
+func (journal *Journal) Replay(ctx context.Context, server *SimpleServer,
+	idempotent func(method string) bool) (Responses, error) {
+	if idempotent == nil {
+		idempotent = func(method string) bool {
+			info, ok := server.GetMethodInfo(method)
+			return ok && info.ReadOnly
+		}
+	}
+
+	var responses Responses
+	for _, entry := range journal.InFlight() {
+		if !idempotent(entry.Method) {
+			continue
+		}
+
+		responses = append(responses, server.HandleWithContext(ctx, entry.Request, nil)...)
+		if err := journal.Complete(entry.Seq); err != nil {
+			return responses, err
+		}
+	}
+
+	return responses, nil
+}
+
+// Discard completes every entry that is still in InFlight, once they have been
+// reported or dealt with.
+func (journal *Journal) Discard() error {
+	for _, entry := range journal.InFlight() {
+		if err := journal.Complete(entry.Seq); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the journal file. Requests that are still being handled will not
+// be completed, so they will be in flight when the journal is opened again.
+func (journal *Journal) Close() error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	return journal.file.Close()
+}
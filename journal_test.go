@@ -0,0 +1,108 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func openJournal(t *testing.T, path string) *jsonrpc.Journal {
+	journal, err := jsonrpc.OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return journal
+}
+
+func TestJournal_Middleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.journal")
+	journal := openJournal(t, path)
+	defer journal.Close()
+
+	server := newTestServer()
+	group := server.Group(journal.Middleware())
+	group.SetHandler("subtract", subtract)
+	group.SetHandler("panic", forcePanic)
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1},
+		{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23]},
+		{"jsonrpc": "2.0", "method": "panic", "id": 2}
+	]`))
+	assert.Len(t, responses, 2)
+	assert.Empty(t, journal.InFlight())
+
+	// Two entries and their completion markers, the notification is not
+	// journaled.
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"))
+}
+
+func TestJournal_Recovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.journal")
+	journal := openJournal(t, path)
+
+	server := newTestServer()
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "get_data", ReadOnly: true})
+
+	for _, request := range []jsonrpc.RequestResponder{
+		jsonrpc.NewRequestResponder("2.0", 1, "subtract", []interface{}{42.0, 23.0}),
+		jsonrpc.NewRequestResponder("2.0", 2, "get_data", nil),
+		jsonrpc.NewRequestResponder("2.0", 3, "sum", []interface{}{1.0}),
+	} {
+		_, err := journal.Begin(request)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, journal.Complete(3))
+	assert.NoError(t, journal.Close())
+
+	// A line that was being written during a crash.
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	file.WriteString(`{"seq":4,"time":`)
+	file.Close()
+
+	journal = openJournal(t, path)
+	defer journal.Close()
+
+	inFlight := journal.InFlight()
+	if assert.Len(t, inFlight, 2) {
+		assert.Equal(t, uint64(1), inFlight[0].Seq)
+		assert.Equal(t, "subtract", inFlight[0].Method)
+		assert.Equal(t, uint64(2), inFlight[1].Seq)
+		assert.Equal(t, "get_data", inFlight[1].Method)
+	}
+
+	// Only the in-flight entries are kept.
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	responses, err := journal.Replay(context.Background(), server, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, responses, 1) {
+		assert.Equal(t, []interface{}{"hello", 5.0}, responses[0].Result())
+	}
+
+	inFlight = journal.InFlight()
+	if assert.Len(t, inFlight, 1) {
+		assert.Equal(t, "subtract", inFlight[0].Method)
+	}
+
+	assert.NoError(t, journal.Discard())
+	assert.Empty(t, journal.InFlight())
+	assert.NoError(t, journal.Close())
+
+	// Sequence numbers continue from the entries left in the journal.
+	journal = openJournal(t, path)
+	defer journal.Close()
+	assert.Empty(t, journal.InFlight())
+
+	seq, err := journal.Begin(jsonrpc.NewRequestResponder("2.0", 5, "sum", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), seq)
+}
@@ -0,0 +1,113 @@
+// Package jsonrpctest provides helpers for testing code that is built on the
+// jsonrpc package.
+package jsonrpctest
+
+import (
+	"testing"
+
+	"github.com/thiagozs/jsonrpc"
+)
+
+// AllocRuns is the number of times each function is run when averaging
+// allocations.
+var AllocRuns = 100
+
+// Budget is the maximum number of allocations allowed for each of the hot
+// paths of a payload. A zero value for any path means it is not checked.
+type Budget struct {
+	// Parse is the budget for jsonrpc.NewRequestsFromJSON.
+	Parse float64
+
+	// Serialize is the budget for encoding the responses with Bytes.
+	Serialize float64
+
+	// Dispatch is the budget for the server to Handle the payload. This
+	// includes parsing and running the handlers.
+	Dispatch float64
+}
+
+// ParseAllocs returns the average number of allocations needed to parse
+// payload into requests.
+func ParseAllocs(payload []byte) float64 {
+	return testing.AllocsPerRun(AllocRuns, func() {
+		_, _ = jsonrpc.NewRequestsFromJSON(payload)
+	})
+}
+
+// SerializeAllocs returns the average number of allocations needed to encode
+// responses.
+func SerializeAllocs(responses jsonrpc.Responses) float64 {
+	return testing.AllocsPerRun(AllocRuns, func() {
+		_ = responses.Bytes()
+	})
+}
+
+// DispatchAllocs returns the average number of allocations needed for server
+// to handle payload.
+func DispatchAllocs(server jsonrpc.Server, payload []byte) float64 {
+	return testing.AllocsPerRun(AllocRuns, func() {
+		_ = server.Handle(payload)
+	})
+}
+
+// AssertAllocs fails the test if fn allocates more than max times on average.
+//
+//     jsonrpctest.AssertAllocs(t, "encode", 3, func() {
+//         encode(value)
+//     })
+func AssertAllocs(t testing.TB, name string, max float64, fn func()) bool {
+	t.Helper()
+
+	return assertBudget(t, name, max, testing.AllocsPerRun(AllocRuns, fn))
+}
+
+// AssertBudget fails the test if parsing, serializing or dispatching payload
+// on server allocates more than budget allows. It is intended to be called
+// from the test suites of services, so that a regression in this package (or
+// in their own handlers) is caught:
+//
+//     func TestAllocations(t *testing.T) {
+//         payload := []byte(`{"jsonrpc": "2.0", "method": "sayHello", "id": 1}`)
+//         jsonrpctest.AssertBudget(t, newServer(), payload, jsonrpctest.Budget{
+//             Parse:     25,
+//             Serialize: 10,
+//             Dispatch:  40,
+//         })
+//     }
+//
+// The handlers will be called many times, so they should not have side
+// effects that would change the result of the test.
+func AssertBudget(t testing.TB, server jsonrpc.Server, payload []byte, budget Budget) bool {
+	t.Helper()
+
+	ok := true
+
+	if budget.Parse != 0 {
+		ok = assertBudget(t, "parse", budget.Parse, ParseAllocs(payload)) && ok
+	}
+
+	if budget.Serialize != 0 {
+		responses := server.Handle(payload)
+		ok = assertBudget(t, "serialize", budget.Serialize,
+			SerializeAllocs(responses)) && ok
+	}
+
+	if budget.Dispatch != 0 {
+		ok = assertBudget(t, "dispatch", budget.Dispatch,
+			DispatchAllocs(server, payload)) && ok
+	}
+
+	return ok
+}
+
+func assertBudget(t testing.TB, name string, max, allocs float64) bool {
+	t.Helper()
+
+	if allocs > max {
+		t.Errorf("%s: %.1f allocations per run exceeds the budget of %.1f",
+			name, allocs, max)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+package jsonrpctest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+	"github.com/thiagozs/jsonrpc/jsonrpctest"
+)
+
+// recorder captures failures instead of failing the real test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newServer() *jsonrpc.SimpleServer {
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("echo", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params())
+	})
+	server.SetHandler("notify", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(nil)
+	})
+
+	return server
+}
+
+// These are the budgets for the package itself. If a change makes one of
+// these fail it must be justified before the budget is raised.
+func TestBudget_Single(t *testing.T) {
+	payload := []byte(`{"jsonrpc": "2.0", "method": "echo", "params": [42, 23], "id": 1}`)
+
+	jsonrpctest.AssertBudget(t, newServer(), payload, jsonrpctest.Budget{
+		Parse:     30,
+		Serialize: 12,
+		Dispatch:  45,
+	})
+}
+
+func TestBudget_Batch(t *testing.T) {
+	payload := []byte(`[
+		{"jsonrpc": "2.0", "method": "echo", "params": [1, 2, 4], "id": "1"},
+		{"jsonrpc": "2.0", "method": "notify", "params": [7]},
+		{"jsonrpc": "2.0", "method": "echo", "params": [42, 23], "id": "2"}
+	]`)
+
+	jsonrpctest.AssertBudget(t, newServer(), payload, jsonrpctest.Budget{
+		Parse:     50,
+		Serialize: 20,
+		Dispatch:  220,
+	})
+}
+
+func TestAssertBudget_Exceeded(t *testing.T) {
+	r := &recorder{}
+	payload := []byte(`{"jsonrpc": "2.0", "method": "echo", "params": [1], "id": 1}`)
+
+	ok := jsonrpctest.AssertBudget(r, newServer(), payload, jsonrpctest.Budget{
+		Parse:    0.5,
+		Dispatch: 0.5,
+	})
+
+	assert.False(t, ok)
+	assert.Len(t, r.errors, 2)
+	assert.Contains(t, r.errors[0], "parse: ")
+	assert.Contains(t, r.errors[1], "dispatch: ")
+}
+
+func TestAssertAllocs(t *testing.T) {
+	r := &recorder{}
+	var sink []byte
+
+	assert.True(t, jsonrpctest.AssertAllocs(r, "none", 1, func() {}))
+	assert.False(t, jsonrpctest.AssertAllocs(r, "some", 1, func() {
+		sink = make([]byte, 64)
+		sink = append(sink, make([]byte, 64)...)
+	}))
+	assert.Equal(t, []string{
+		"some: 2.0 allocations per run exceeds the budget of 1.0",
+	}, r.errors)
+	_ = sink
+}
@@ -0,0 +1,238 @@
+package jsonrpctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// CompareOption changes how messages are compared.
+type CompareOption func(*compareOptions)
+
+type compareOptions struct {
+	ignored map[string]bool
+}
+
+// IgnoreID does not compare the "id" of messages (or of each message in a
+// batch), which is useful when ids are generated.
+func IgnoreID() CompareOption {
+	return IgnoreFields("id")
+}
+
+// IgnoreFields does not compare the fields, given in dot notation relative to
+// each message, such as "result.createdAt" or "error.data".
+func IgnoreFields(fields ...string) CompareOption {
+	return func(options *compareOptions) {
+		for _, field := range fields {
+			options.ignored[field] = true
+		}
+	}
+}
+
+// Diff compares two JSON-RPC messages (or batches) semantically, ignoring the
+// order of keys and whitespace. It returns a line for each difference, such as
+// `result.name: expected "Bob", got "Alice"`, which is empty if they are
+// equal.
+//
+// Each message may be JSON as a []byte or string, anything with a Bytes method
+// (such as a jsonrpc.Request, Response or Responses), or any other value that
+// can be encoded to JSON.
+func Diff(expected, actual interface{}, options ...CompareOption) ([]string, error) {
+	compare := compareOptions{ignored: map[string]bool{}}
+	for _, option := range options {
+		option(&compare)
+	}
+
+	expectedValue, err := decodeMessage(expected)
+	if err != nil {
+		return nil, fmt.Errorf("expected: %v", err)
+	}
+
+	actualValue, err := decodeMessage(actual)
+	if err != nil {
+		return nil, fmt.Errorf("actual: %v", err)
+	}
+
+	var differences []string
+	diffMessages(expectedValue, actualValue, compare, &differences)
+
+	return differences, nil
+}
+
+// AssertRequestEqual fails the test if the requests are not semantically
+// equal, listing every difference. See Diff.
+//
+//     jsonrpctest.AssertRequestEqual(t,
+//         `{"jsonrpc": "2.0", "method": "sum", "params": [1, 2]}`,
+//         request, jsonrpctest.IgnoreID())
+func AssertRequestEqual(t testing.TB, expected, actual interface{},
+	options ...CompareOption) bool {
+	t.Helper()
+
+	return assertEqual(t, "Requests", expected, actual, options)
+}
+
+// AssertResponseEqual fails the test if the responses are not semantically
+// equal, listing every difference. See Diff.
+func AssertResponseEqual(t testing.TB, expected, actual interface{},
+	options ...CompareOption) bool {
+	t.Helper()
+
+	return assertEqual(t, "Responses", expected, actual, options)
+}
+
+func assertEqual(t testing.TB, kind string, expected, actual interface{},
+	options []CompareOption) bool {
+	t.Helper()
+
+	differences, err := Diff(expected, actual, options...)
+	if err != nil {
+		t.Errorf("%s cannot be compared: %v", kind, err)
+		return false
+	}
+
+	if len(differences) > 0 {
+		t.Errorf("%s are not equal:\n    %s", kind, strings.Join(differences, "\n    "))
+		return false
+	}
+
+	return true
+}
+
+func decodeMessage(message interface{}) (interface{}, error) {
+	var data []byte
+	switch m := message.(type) {
+	case []byte:
+		data = m
+
+	case string:
+		data = []byte(m)
+
+	case interface{ Bytes() []byte }:
+		data = m.Bytes()
+
+	default:
+		var err error
+		if data, err = json.Marshal(message); err != nil {
+			return nil, err
+		}
+	}
+
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+
+	return value, err
+}
+
+// diffMessages compares a message or the members of a batch.
+func diffMessages(expected, actual interface{}, options compareOptions,
+	differences *[]string) {
+	expectedBatch, isBatch := expected.([]interface{})
+	actualBatch, actualIsBatch := actual.([]interface{})
+	if !isBatch || !actualIsBatch {
+		diffValues("", expected, actual, options, differences)
+		return
+	}
+
+	for i := 0; i < len(expectedBatch) || i < len(actualBatch); i++ {
+		prefix := "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i >= len(actualBatch):
+			*differences = append(*differences, prefix+": missing")
+
+		case i >= len(expectedBatch):
+			*differences = append(*differences, prefix+": unexpected "+encode(actualBatch[i]))
+
+		default:
+			var member []string
+			diffValues("", expectedBatch[i], actualBatch[i], options, &member)
+			for _, difference := range member {
+				*differences = append(*differences, prefix+" "+difference)
+			}
+		}
+	}
+}
+
+func diffValues(path string, expected, actual interface{}, options compareOptions,
+	differences *[]string) {
+	if options.ignored[path] {
+		return
+	}
+
+	label := path
+	if label == "" {
+		label = "message"
+	}
+
+	expectedObject, isObject := expected.(map[string]interface{})
+	actualObject, actualIsObject := actual.(map[string]interface{})
+	if isObject && actualIsObject {
+		keys := map[string]bool{}
+		for key := range expectedObject {
+			keys[key] = true
+		}
+		for key := range actualObject {
+			keys[key] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			field := joinPath(path, key)
+			expectedValue, inExpected := expectedObject[key]
+			actualValue, inActual := actualObject[key]
+
+			switch {
+			case options.ignored[field]:
+
+			case !inActual:
+				*differences = append(*differences, field+": missing")
+
+			case !inExpected:
+				*differences = append(*differences, field+": unexpected "+encode(actualValue))
+
+			default:
+				diffValues(field, expectedValue, actualValue, options, differences)
+			}
+		}
+
+		return
+	}
+
+	expectedArray, isArray := expected.([]interface{})
+	actualArray, actualIsArray := actual.([]interface{})
+	if isArray && actualIsArray && len(expectedArray) == len(actualArray) {
+		for i := range expectedArray {
+			diffValues(joinPath(path, strconv.Itoa(i)), expectedArray[i], actualArray[i],
+				options, differences)
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		*differences = append(*differences, fmt.Sprintf("%s: expected %s, got %s",
+			label, encode(expected), encode(actual)))
+	}
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+
+	return parent + "." + child
+}
+
+func encode(value interface{}) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
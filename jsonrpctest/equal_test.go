@@ -0,0 +1,101 @@
+package jsonrpctest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+	"github.com/thiagozs/jsonrpc/jsonrpctest"
+)
+
+func TestDiff(t *testing.T) {
+	tests := map[string]struct {
+		expected, actual interface{}
+		options          []jsonrpctest.CompareOption
+		differences      []string
+	}{
+		"key order and whitespace": {
+			`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1}`,
+			`{"id":1,"params":[1,2],"method":"sum","jsonrpc":"2.0"}`,
+			nil, nil,
+		},
+		"request": {
+			`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1}`,
+			jsonrpc.NewRequestResponder("2.0", 1, "sum", []int{1, 2}),
+			nil, nil,
+		},
+		"different": {
+			`{"jsonrpc": "2.0", "result": {"name": "Bob", "tags": ["a"], "age": 5}, "id": 1}`,
+			`{"jsonrpc": "2.0", "result": {"name": "Alice", "tags": ["a", "b"], "admin": true}, "id": 2}`,
+			nil,
+			[]string{
+				`id: expected 1, got 2`,
+				`result.admin: unexpected true`,
+				`result.age: missing`,
+				`result.name: expected "Bob", got "Alice"`,
+				`result.tags: expected ["a"], got ["a","b"]`,
+			},
+		},
+		"ignore id": {
+			`{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+			jsonrpc.NewSuccessResponse("generated", 19),
+			[]jsonrpctest.CompareOption{jsonrpctest.IgnoreID()},
+			nil,
+		},
+		"ignore fields": {
+			`{"jsonrpc": "2.0", "result": {"name": "Bob", "at": 1}, "id": 1}`,
+			`{"jsonrpc": "2.0", "result": {"name": "Bob", "at": 2}, "id": 1}`,
+			[]jsonrpctest.CompareOption{jsonrpctest.IgnoreFields("result.at")},
+			nil,
+		},
+		"batch": {
+			`[{"jsonrpc": "2.0", "result": 1, "id": 1}, {"jsonrpc": "2.0", "result": 2, "id": 2}]`,
+			jsonrpc.Responses{
+				jsonrpc.NewSuccessResponse(7, 1),
+				jsonrpc.NewSuccessResponse(8, 3),
+				jsonrpc.NewSuccessResponse(9, 4),
+			},
+			[]jsonrpctest.CompareOption{jsonrpctest.IgnoreID()},
+			[]string{
+				`[1] result: expected 2, got 3`,
+				`[2]: unexpected {"id":9,"jsonrpc":"2.0","result":4}`,
+			},
+		},
+		"single and batch": {
+			`{"jsonrpc": "2.0", "result": 1, "id": 1}`,
+			`[{"jsonrpc": "2.0", "result": 1, "id": 1}]`,
+			nil,
+			[]string{`message: expected {"id":1,"jsonrpc":"2.0","result":1}, got [{"id":1,"jsonrpc":"2.0","result":1}]`},
+		},
+	}
+
+	for name, test := range tests {
+		differences, err := jsonrpctest.Diff(test.expected, test.actual, test.options...)
+		assert.NoError(t, err, name)
+		assert.Equal(t, test.differences, differences, name)
+	}
+
+	_, err := jsonrpctest.Diff(`{`, `{}`)
+	assert.EqualError(t, err, "expected: unexpected end of JSON input")
+}
+
+func TestAssertResponseEqual(t *testing.T) {
+	r := &recorder{}
+	assert.True(t, jsonrpctest.AssertResponseEqual(r,
+		`{"jsonrpc": "2.0", "result": 19, "id": 1}`, jsonrpc.NewSuccessResponse(1, 19)))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, jsonrpctest.AssertResponseEqual(r,
+		`{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+		jsonrpc.NewErrorResponse(1, jsonrpc.InvalidParams, "")))
+	assert.Equal(t, []string{"Responses are not equal:\n" +
+		`    error: unexpected {"code":-32602,"message":"Invalid params"}` + "\n" +
+		"    result: missing"}, r.errors)
+}
+
+func TestAssertRequestEqual(t *testing.T) {
+	r := &recorder{}
+	assert.False(t, jsonrpctest.AssertRequestEqual(r, `{"method": "sum"}`, `nope`))
+	assert.Equal(t, []string{"Requests cannot be compared: actual: " +
+		"invalid character 'o' in literal null (expecting 'u')"}, r.errors)
+}
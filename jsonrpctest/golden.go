@@ -0,0 +1,121 @@
+package jsonrpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thiagozs/jsonrpc"
+)
+
+// Update makes snapshots write the golden files instead of comparing against
+// them. It is set with the -update-golden flag:
+//
+//     go test ./... -update-golden
+//
+var Update = flag.Bool("update-golden", false, "write golden files for jsonrpctest snapshots")
+
+// Snapshotter compares messages with golden files. The messages are redacted
+// and then encoded canonically (sorted keys and indented) so the golden files
+// are stable and easy to review, and secrets never reach them.
+//
+//     var snapshots = &jsonrpctest.Snapshotter{
+//         Dir:      "testdata",
+//         Redactor: jsonrpc.RedactKeys("password", "token"),
+//     }
+//
+//     func TestLogin(t *testing.T) {
+//         responses := server.Handle(loginRequest)
+//         snapshots.Assert(t, "login", responses, jsonrpctest.IgnoreID())
+//     }
+//
+type Snapshotter struct {
+	// Dir holds the golden files, which are named after each snapshot with a
+	// ".golden.json" extension. An empty Dir is "testdata".
+	Dir string
+
+	// Redactor, if set, is applied to every message before it is compared or
+	// written.
+	Redactor jsonrpc.Redactor
+}
+
+// AssertSnapshot compares the message with a golden file in testdata. See
+// Snapshotter.
+func AssertSnapshot(t testing.TB, name string, message interface{},
+	options ...CompareOption) bool {
+	t.Helper()
+
+	return (&Snapshotter{}).Assert(t, name, message, options...)
+}
+
+// Assert fails the test if the message does not match the golden file of the
+// snapshot, listing every difference (see Diff). When Update is set the golden
+// file is written instead.
+func (snapshotter *Snapshotter) Assert(t testing.TB, name string, message interface{},
+	options ...CompareOption) bool {
+	t.Helper()
+
+	snapshot, err := snapshotter.encode(message)
+	if err != nil {
+		t.Errorf("Snapshot %s cannot be encoded: %v", name, err)
+		return false
+	}
+
+	dir := snapshotter.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name+".golden.json")
+
+	if *Update {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, snapshot, 0644)
+		}
+		if err != nil {
+			t.Errorf("Snapshot %s cannot be written: %v", name, err)
+			return false
+		}
+
+		return true
+	}
+
+	golden, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Errorf("Snapshot %s does not exist, run the test with -update-golden to create %s",
+			name, path)
+		return false
+	}
+	if err != nil {
+		t.Errorf("Snapshot %s cannot be read: %v", name, err)
+		return false
+	}
+
+	return assertEqual(t, "Snapshot "+name+" and message", golden, snapshot, options)
+}
+
+// encode redacts the message and encodes it canonically.
+func (snapshotter *Snapshotter) encode(message interface{}) ([]byte, error) {
+	value, err := decodeMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshotter.Redactor != nil {
+		value = snapshotter.Redactor.Redact(value)
+	}
+
+	// Maps are encoded with sorted keys.
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
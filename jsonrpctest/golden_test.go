@@ -0,0 +1,60 @@
+package jsonrpctest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+	"github.com/thiagozs/jsonrpc/jsonrpctest"
+)
+
+func TestSnapshotter(t *testing.T) {
+	snapshots := &jsonrpctest.Snapshotter{
+		Dir:      t.TempDir(),
+		Redactor: jsonrpc.RedactKeys("token"),
+	}
+	response := jsonrpc.NewSuccessResponse(1, map[string]interface{}{
+		"user":  "bob",
+		"token": "s3cr3t",
+	})
+
+	// Without a golden file the snapshot fails.
+	r := &recorder{}
+	assert.False(t, snapshots.Assert(r, "login", response))
+	assert.Contains(t, r.errors[0], "run the test with -update-golden")
+
+	*jsonrpctest.Update = true
+	assert.True(t, snapshots.Assert(r, "login", response))
+	*jsonrpctest.Update = false
+
+	golden, err := os.ReadFile(filepath.Join(snapshots.Dir, "login.golden.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "id": 1,
+  "jsonrpc": "2.0",
+  "result": {
+    "token": "[REDACTED]",
+    "user": "bob"
+  }
+}
+`, string(golden))
+
+	// A different token still matches, as it is redacted.
+	r = &recorder{}
+	assert.True(t, snapshots.Assert(r, "login", jsonrpc.NewSuccessResponse(1,
+		map[string]interface{}{"user": "bob", "token": "other"})))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, snapshots.Assert(r, "login", jsonrpc.NewSuccessResponse(2,
+		map[string]interface{}{"user": "alice"})))
+	assert.Equal(t, []string{"Snapshot login and message are not equal:\n" +
+		"    id: expected 1, got 2\n" +
+		"    result.token: missing\n" +
+		`    result.user: expected "bob", got "alice"`}, r.errors)
+
+	r = &recorder{}
+	assert.True(t, snapshots.Assert(r, "login", jsonrpc.NewSuccessResponse(2,
+		map[string]interface{}{"user": "bob", "token": "x"}), jsonrpctest.IgnoreID()))
+}
@@ -0,0 +1,187 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// ResultFunc is a result that writes its own JSON encoding when the response
+// is written, rather than being built as a value when the handler returns.
+// This avoids holding a large result in memory twice, once as Go values and
+// once as JSON:
+//
+//     server.SetHandler("export", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+//         rows, err := db.Query("SELECT * FROM events")
+//         if err != nil {
+//             return request.NewServerErrorResponse(err)
+//         }
+//
+//         return request.NewSuccessResponse(jsonrpc.ResultFunc(func(w io.Writer) error {
+//             defer rows.Close()
+//             return writeRowsAsJSONArray(w, rows)
+//         }))
+//     })
+//
+// The function must write exactly one JSON value, and is called once for each
+// time the response is written.
+//
+// The result is streamed by transports that write responses with
+// WriteResponse, such as HTTPServer and HandleNDJSON. Other transports, and
+// Bytes, encode it into memory first, which checks that it is valid JSON. A
+// streamed result that fails part way through leaves the response cut short.
+//
+// The result is encoded into memory once, when the handler returns, if the
+// server signs responses (see SetResponseSigner), tracks sizes (see
+// SetSizeBuckets) or has a MaxResultSize for the method. The signature, the
+// size and the response that is sent then all come from the same call.
+type ResultFunc func(w io.Writer) error
+
+// WriteTo calls f(w).
+func (f ResultFunc) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	err := f(counter)
+
+	return counter.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+
+	return n, err
+}
+
+// lazyResult returns the result if it is a ResultFunc. Other values, even if
+// they are an io.WriterTo, are encoded with encoding/json.
+func lazyResult(result interface{}) (ResultFunc, bool) {
+	f, ok := result.(ResultFunc)
+
+	return f, ok
+}
+
+// encodeLazyResult returns a copy of the response with a lazy result replaced
+// by its encoding, or the response itself if the result is not lazy.
+func encodeLazyResult(r *response) (*response, error) {
+	writer, ok := lazyResult(r.ResponseResult)
+	if !ok {
+		return r, nil
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	copied := *r
+	copied.ResponseResult = json.RawMessage(buf.Bytes())
+
+	return &copied, nil
+}
+
+// encodeResult returns a copy of the response with a ResultFunc result encoded
+// into memory, so that it is only called once however many times the response
+// is encoded. An InternalError is returned if the function fails.
+func encodeResult(request RequestResponder, r Response) Response {
+	encoded, ok := r.(*response)
+	if !ok {
+		return r
+	}
+
+	if _, lazy := lazyResult(encoded.ResponseResult); !lazy {
+		return r
+	}
+
+	copied, err := encodeLazyResult(encoded)
+	if err != nil || !json.Valid(copied.ResponseResult.(json.RawMessage)) {
+		return request.NewErrorResponse(InternalError, "")
+	}
+
+	return copied
+}
+
+// WriteTo writes the JSON encoding of the response to w. A result that writes
+// its own encoding (see ResultFunc) is streamed to w after the other members.
+func (response *response) WriteTo(w io.Writer) (int64, error) {
+	writer, ok := lazyResult(response.ResponseResult)
+	if !ok {
+		b, err := response.MarshalJSON()
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := w.Write(b)
+
+		return int64(n), err
+	}
+
+	// The other members are encoded without the result, which is then
+	// written as the last member.
+	copied := *response
+	copied.ResponseResult = nil
+	b, err := copied.MarshalJSON()
+	if err != nil {
+		return 0, err
+	}
+
+	counter := &countingWriter{w: w}
+	if _, err := counter.Write(append(b[:len(b)-1], `,"result":`...)); err != nil {
+		return counter.n, err
+	}
+
+	if _, err := writer.WriteTo(counter); err != nil {
+		return counter.n, err
+	}
+
+	_, err = counter.Write([]byte{'}'})
+
+	return counter.n, err
+}
+
+// WriteResponse writes the JSON encoding of the response to w, streaming a
+// result that writes its own encoding (see ResultFunc).
+func WriteResponse(w io.Writer, r Response) (int64, error) {
+	if encoded, ok := r.(*response); ok {
+		return encoded.WriteTo(w)
+	}
+
+	n, err := w.Write(r.Bytes())
+
+	return int64(n), err
+}
+
+// WriteTo writes the responses as a JSON array, streaming the results that
+// write their own encoding (see ResultFunc).
+func (responses Responses) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	for i, response := range responses {
+		separator := byte(',')
+		if i == 0 {
+			separator = '['
+		}
+
+		if _, err := counter.Write([]byte{separator}); err != nil {
+			return counter.n, err
+		}
+
+		if _, err := WriteResponse(counter, response); err != nil {
+			return counter.n, err
+		}
+	}
+
+	if len(responses) == 0 {
+		_, err := counter.Write([]byte{'['})
+		if err != nil {
+			return counter.n, err
+		}
+	}
+
+	_, err := counter.Write([]byte{']'})
+
+	return counter.n, err
+}
@@ -0,0 +1,137 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func numbers(n int) jsonrpc.ResultFunc {
+	return func(w io.Writer) error {
+		io.WriteString(w, "[")
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			io.WriteString(w, strings.Repeat("1", i+1))
+		}
+		_, err := io.WriteString(w, "]")
+
+		return err
+	}
+}
+
+func TestResultFunc(t *testing.T) {
+	response := jsonrpc.NewSuccessResponse(1, numbers(3))
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":[1,11,111]}`, response.String())
+
+	var buf bytes.Buffer
+	n, err := jsonrpc.WriteResponse(&buf, response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":[1,11,111]}`, buf.String())
+
+	// Extensions come before the streamed result.
+	buf.Reset()
+	jsonrpc.WriteResponse(&buf, jsonrpc.WithExtension(response, "x-trace", "abc"))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"x-trace":"abc","result":[1,11,111]}`, buf.String())
+
+	// Other io.WriterTo values are encoded like any other value.
+	buf.Reset()
+	jsonrpc.WriteResponse(&buf, jsonrpc.NewSuccessResponse(1, bytes.NewBufferString("hello")))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{}}`, buf.String())
+
+	// Invalid JSON is caught when the result is encoded into memory.
+	assert.Nil(t, jsonrpc.NewSuccessResponse(1, jsonrpc.ResultFunc(func(w io.Writer) error {
+		_, err := io.WriteString(w, "{")
+		return err
+	})).Bytes())
+
+	failing := jsonrpc.NewSuccessResponse(1, jsonrpc.ResultFunc(func(w io.Writer) error {
+		return errors.New("Query failed.")
+	}))
+	assert.Nil(t, failing.Bytes())
+	_, err = jsonrpc.WriteResponse(&buf, failing)
+	assert.EqualError(t, err, "Query failed.")
+}
+
+func TestResponses_WriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	jsonrpc.Responses{
+		jsonrpc.NewSuccessResponse(1, numbers(2)),
+		jsonrpc.NewErrorResponse(2, jsonrpc.MethodNotFound, ""),
+	}.WriteTo(&buf)
+
+	assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":[1,11]},`+
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}}]`,
+		buf.String())
+
+	buf.Reset()
+	jsonrpc.Responses{}.WriteTo(&buf)
+	assert.Equal(t, `[]`, buf.String())
+}
+
+func TestHTTPServer_ResultFunc(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("numbers", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(numbers(4))
+	})
+	httpServer := httptest.NewServer(jsonrpc.NewHTTPServer(server))
+	defer httpServer.Close()
+
+	response, err := http.Post(httpServer.URL, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"numbers","id":1}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":[1,11,111,1111]}`, string(body))
+}
+
+func TestResultFunc_EncodedOnce(t *testing.T) {
+	calls := 0
+	server := newTestServer()
+	server.SetHandler("numbers", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		n := 2
+		if request.Params() != nil {
+			n = 5
+		}
+
+		return request.NewSuccessResponse(jsonrpc.ResultFunc(func(w io.Writer) error {
+			calls++
+			return numbers(n)(w)
+		}))
+	})
+	server.SetResponseSigner(jsonrpc.HMACSigner("secret"))
+	server.SetMethodLimits("numbers", jsonrpc.MethodLimits{MaxResultSize: 10})
+	httpServer := httptest.NewServer(jsonrpc.NewHTTPServer(server))
+	defer httpServer.Close()
+
+	// The signature covers the bytes that are sent.
+	_, body := post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"numbers","id":1}`)
+	responses, err := jsonrpc.NewResponsesFromJSON([]byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{1.0, 11.0}, responses[0].Result())
+		assert.NoError(t, jsonrpc.VerifyResponse(responses[0], jsonrpc.HMACSigner("secret")))
+	}
+	assert.Equal(t, 1, calls)
+
+	// The size of the result is checked.
+	calls = 0
+	_, body = post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"numbers","params":[5],"id":2}`)
+	responses, err = jsonrpc.NewResponsesFromJSON([]byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, jsonrpc.ResultTooLarge, responses[0].ErrorCode())
+	}
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,174 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"time"
+)
+
+// The error codes of requests that exceeded their MethodLimits. They are in
+// the server error range.
+const (
+	TimeLimitExceeded   = -32011
+	MemoryLimitExceeded = -32012
+	ResultTooLarge      = -32013
+)
+
+// LimitExceededErrorType is the ErrorDetails type of a MemoryLimitExceeded or
+// ResultTooLarge error. A TimeLimitExceeded error uses the TimeoutErrorType.
+const LimitExceededErrorType = "urn:jsonrpc:error:limit-exceeded"
+
+// MethodLimits protect a server that is shared by many clients from methods
+// that are expensive. They are enforced by the server for every call to the
+// method, so a handler does not need to do anything:
+//
+//     server.SetMethodLimits("report.generate", jsonrpc.MethodLimits{
+//         MaxTime:       30 * time.Second,
+//         MaxMemory:     512 << 20,
+//         MaxResultSize: 1 << 20,
+//     })
+//
+// A zero field is not limited.
+type MethodLimits struct {
+	// MaxTime is how long the handler may run before the request is answered
+	// with a TimeLimitExceeded error. The handler is given a context that is
+	// cancelled at the same time (see ContextFromRequest) and its response is
+	// discarded, like the Timeout middleware.
+	MaxTime time.Duration
+
+	// MaxMemory is a hint of how many bytes the method may need. Go cannot
+	// measure the memory used by a single call, so the method is refused with
+	// a MemoryLimitExceeded error (that may be retried) when the memory used
+	// by the process plus MaxMemory would exceed the soft memory limit of the
+	// runtime (see debug.SetMemoryLimit and GOMEMLIMIT). It has no effect if
+	// there is no soft memory limit.
+	MaxMemory int64
+
+	// MaxResultSize is the largest JSON encoded result in bytes. A larger
+	// result is replaced with a ResultTooLarge error.
+	MaxResultSize int
+}
+
+// SetMethodLimits will register (or replace) the limits of a method. Zero
+// limits remove them.
+func (server *SimpleServer) SetMethodLimits(methodName string, limits MethodLimits) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if limits == (MethodLimits{}) {
+		delete(server.methodLimits, methodName)
+		return
+	}
+
+	server.methodLimits[methodName] = limits
+}
+
+// SetTimeout sets the MaxTime limit of a method, keeping its other limits. A
+// zero duration removes it, so the default timeout applies again (see
+// SetDefaultTimeout).
+func (server *SimpleServer) SetTimeout(methodName string, d time.Duration) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	limits := server.methodLimits[methodName]
+	limits.MaxTime = d
+	if limits == (MethodLimits{}) {
+		delete(server.methodLimits, methodName)
+		return
+	}
+
+	server.methodLimits[methodName] = limits
+}
+
+// SetDefaultTimeout sets the MaxTime of every method that does not have its
+// own (see SetTimeout and SetMethodLimits). A handler that runs for longer is
+// answered with a TimeLimitExceeded error and its context is cancelled. Each
+// member of a batch has its own timeout. Zero, the default, does not limit
+// the methods.
+func (server *SimpleServer) SetDefaultTimeout(d time.Duration) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.defaultTimeout = d
+}
+
+// GetMethodLimits returns the limits of a method, if they have been set.
+func (server *SimpleServer) GetMethodLimits(methodName string) (MethodLimits, bool) {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	limits, ok := server.methodLimits[methodName]
+	return limits, ok
+}
+
+// wrap returns a handler that calls the handler within the limits.
+func (limits MethodLimits) wrap(handler RequestHandler) RequestHandler {
+	return func(request RequestResponder) Response {
+		return limits.run(request, handler)
+	}
+}
+
+// run calls the handler within the limits.
+func (limits MethodLimits) run(request RequestResponder, handler RequestHandler) Response {
+	if limits.MaxMemory > 0 {
+		if available := availableMemory(); available < limits.MaxMemory {
+			return request.NewErrorResponseWithData(MemoryLimitExceeded,
+				"Memory limit exceeded", NewErrorDetails(LimitExceededErrorType).
+					WithDetail("The method needs "+strconv.FormatInt(limits.MaxMemory, 10)+
+						" bytes but only "+strconv.FormatInt(available, 10)+
+						" are available.").
+					WithRetryable(true))
+		}
+	}
+
+	var response Response
+	if limits.MaxTime > 0 {
+		response = runWithin(request, limits.MaxTime, handler, func(request RequestResponder) Response {
+			return timeLimitResponse(request, limits.MaxTime)
+		})
+	} else {
+		response = handler(request)
+	}
+
+	if limits.MaxResultSize > 0 && response != nil && response.ErrorCode() == Success {
+		response = encodeResult(request, response)
+		result, err := json.Marshal(response.Result())
+		if err == nil && len(result) > limits.MaxResultSize {
+			return request.NewErrorResponseWithData(ResultTooLarge,
+				"Result too large", NewErrorDetails(LimitExceededErrorType).
+					WithDetail("The result is "+strconv.Itoa(len(result))+
+						" bytes, the limit is "+strconv.Itoa(limits.MaxResultSize)+"."))
+		}
+	}
+
+	return response
+}
+
+// memoryMetrics are the runtime metrics that make up the memory counted
+// against the soft memory limit.
+var memoryMetrics = []string{
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// availableMemory is how far the process is from its soft memory limit. It is
+// math.MaxInt64 if there is no limit.
+func availableMemory() int64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return limit
+	}
+
+	samples := make([]metrics.Sample, len(memoryMetrics))
+	for i, name := range memoryMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	used := int64(samples[0].Value.Uint64() - samples[1].Value.Uint64())
+
+	return limit - used
+}
@@ -0,0 +1,145 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSetMethodLimits(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server := newTestServer()
+	server.SetHandler("slow", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		<-release
+		return request.NewSuccessResponse(nil)
+	})
+	server.SetMethodLimits("slow", jsonrpc.MethodLimits{MaxTime: time.Millisecond})
+	server.SetMethodLimits("get_data", jsonrpc.MethodLimits{MaxResultSize: 11})
+	server.SetMethodLimits("subtract", jsonrpc.MethodLimits{
+		MaxTime:       time.Minute,
+		MaxResultSize: 2,
+	})
+
+	slow := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "slow", "id": 1}`))
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, slow[0].ErrorCode())
+	assert.Equal(t, "Time limit exceeded", slow[0].ErrorMessage())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(slow[0])
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.TimeoutErrorType, details.Type)
+	assert.Equal(t, "The method did not finish within 1ms.", details.Detail)
+
+	// ["hello",5] is 11 bytes.
+	data := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 2}`))
+	assert.Equal(t, []interface{}{"hello", 5.0}, data[0].Result())
+
+	server.SetMethodLimits("get_data", jsonrpc.MethodLimits{MaxResultSize: 10})
+	data = server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 3}`))
+	assert.Equal(t, jsonrpc.ResultTooLarge, data[0].ErrorCode())
+
+	details, err = jsonrpc.ErrorDetailsFromResponse(data[0])
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.LimitExceededErrorType, details.Type)
+	assert.Equal(t, "The result is 11 bytes, the limit is 10.", details.Detail)
+
+	subtract := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 4}`))
+	assert.Equal(t, 19.0, subtract[0].Result())
+
+	// Zero limits remove them.
+	server.SetMethodLimits("get_data", jsonrpc.MethodLimits{})
+	_, ok := server.GetMethodLimits("get_data")
+	assert.False(t, ok)
+
+	limits, ok := server.GetMethodLimits("slow")
+	assert.True(t, ok)
+	assert.Equal(t, time.Millisecond, limits.MaxTime)
+}
+
+func TestSetMethodLimits_MaxMemory(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodLimits("get_data", jsonrpc.MethodLimits{MaxMemory: 1 << 40})
+
+	// Without a soft memory limit there is nothing to check.
+	request := []byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`)
+	assert.Equal(t, jsonrpc.Success, server.Handle(request)[0].ErrorCode())
+
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(1 << 34))
+
+	response := server.Handle(request)[0]
+	assert.Equal(t, jsonrpc.MemoryLimitExceeded, response.ErrorCode())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.LimitExceededErrorType, details.Type)
+	assert.True(t, details.Retryable)
+}
+
+func TestSetMethodLimits_Panic(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodLimits("panic", jsonrpc.MethodLimits{MaxTime: time.Minute})
+
+	response := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "panic", "id": 1}`))
+	assert.Equal(t, jsonrpc.InternalError, response[0].ErrorCode())
+}
+
+func TestSetDefaultTimeout(t *testing.T) {
+	cancelled := make(chan error, 2)
+	server := newTestServer()
+	server.SetHandler("wait", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		ctx := jsonrpc.ContextFromRequest(request)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return request.NewSuccessResponse(nil)
+	})
+	server.SetDefaultTimeout(time.Millisecond)
+
+	// Every member of a batch has its own timeout.
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "wait", "id": 1},
+		{"jsonrpc": "2.0", "method": "get_data", "id": 2},
+		{"jsonrpc": "2.0", "method": "wait", "id": 3}
+	]`))
+	assert.Len(t, responses, 3)
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, responses[0].ErrorCode())
+	assert.Equal(t, []interface{}{"hello", 5.0}, responses[1].Result())
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, responses[2].ErrorCode())
+	assert.Equal(t, context.DeadlineExceeded, <-cancelled)
+	assert.Equal(t, context.DeadlineExceeded, <-cancelled)
+
+	// The timeout of a method takes precedence.
+	server.SetDefaultTimeout(time.Hour)
+	server.SetMethodLimits("wait", jsonrpc.MethodLimits{MaxResultSize: 100})
+	server.SetTimeout("wait", 2*time.Millisecond)
+	assert.Equal(t, jsonrpc.MethodLimits{MaxTime: 2 * time.Millisecond, MaxResultSize: 100},
+		mustGetMethodLimits(t, server, "wait"))
+
+	responses = server.Handle([]byte(`{"jsonrpc": "2.0", "method": "wait", "id": 4}`))
+	details, err := jsonrpc.ErrorDetailsFromResponse(responses[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "The method did not finish within 2ms.", details.Detail)
+	<-cancelled
+
+	server.SetTimeout("wait", 0)
+	assert.Equal(t, jsonrpc.MethodLimits{MaxResultSize: 100},
+		mustGetMethodLimits(t, server, "wait"))
+
+	server.SetMethodLimits("wait", jsonrpc.MethodLimits{})
+	server.SetTimeout("wait", time.Second)
+	server.SetTimeout("wait", 0)
+	_, ok := server.GetMethodLimits("wait")
+	assert.False(t, ok)
+}
+
+func mustGetMethodLimits(t *testing.T, server *jsonrpc.SimpleServer,
+	method string) jsonrpc.MethodLimits {
+	limits, ok := server.GetMethodLimits(method)
+	assert.True(t, ok)
+
+	return limits
+}
@@ -0,0 +1,84 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ReusePortListenConfig is a net.ListenConfig that sets SO_REUSEPORT on the
+// socket, so that more than one socket can listen on the same address. The
+// kernel will distribute incoming connections between them.
+//
+// This also allows a new process to start listening before the old process
+// has stopped, which makes it possible to restart without refusing any
+// connections.
+var ReusePortListenConfig = net.ListenConfig{
+	Control: setReusePort,
+}
+
+// ListenReusePort opens n TCP listeners on the same address with SO_REUSEPORT
+// set, see ReusePortListenConfig. If the address has a zero port, all of the
+// listeners use the port chosen for the first listener.
+//
+// It returns an error on platforms that do not support SO_REUSEPORT, such as
+// Windows.
+func ListenReusePort(ctx context.Context, network, address string, n int) ([]net.Listener, error) {
+	if n < 1 {
+		return nil, errors.New("At least one listener is required.")
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for len(listeners) < n {
+		listener, err := ReusePortListenConfig.Listen(ctx, network, address)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+		address = listener.Addr().String()
+	}
+
+	return listeners, nil
+}
+
+// ServeListeners runs serve for each listener in its own goroutine, such as
+// the Serve method of an http.Server:
+//
+//     listeners, err := jsonrpc.ListenReusePort(ctx, "tcp", ":8080", runtime.NumCPU())
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     log.Fatal(jsonrpc.ServeListeners(listeners, httpServer.Serve))
+//
+// When the first serve returns, all of the listeners are closed and the error
+// of the first serve is returned once every serve has returned.
+func ServeListeners(listeners []net.Listener, serve func(net.Listener) error) error {
+	if len(listeners) == 0 {
+		return errors.New("At least one listener is required.")
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			errs <- serve(listener)
+		}(listener)
+	}
+
+	err := <-errs
+	closeListeners(listeners)
+
+	for i := 1; i < len(listeners); i++ {
+		<-errs
+	}
+
+	return err
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
@@ -0,0 +1,6 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package jsonrpc
+
+// The syscall package does not export SO_REUSEPORT on every architecture.
+const soReusePort = 0xf
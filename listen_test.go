@@ -0,0 +1,88 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported")
+	}
+
+	listeners, err := jsonrpc.ListenReusePort(context.Background(), "tcp", "127.0.0.1:0", 3)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, listeners, 3)
+	assert.Equal(t, listeners[0].Addr(), listeners[1].Addr())
+	assert.Equal(t, listeners[0].Addr(), listeners[2].Addr())
+
+	server := newTestServer()
+	httpServer := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(server.Handle(body).Bytes())
+		}),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- jsonrpc.ServeListeners(listeners, httpServer.Serve)
+	}()
+
+	url := "http://" + listeners[0].Addr().String()
+	for i := 0; i < 10; i++ {
+		response, err := http.Post(url, "application/json", bytes.NewBufferString(
+			`{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`))
+		if !assert.NoError(t, err) {
+			break
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":19}]`, string(body))
+	}
+
+	httpServer.Shutdown(context.Background())
+	assert.Equal(t, http.ErrServerClosed, <-done)
+}
+
+func TestListenReusePort_NoListeners(t *testing.T) {
+	_, err := jsonrpc.ListenReusePort(context.Background(), "tcp", "127.0.0.1:0", 0)
+	assert.EqualError(t, err, "At least one listener is required.")
+}
+
+func TestServeListeners_ClosesAll(t *testing.T) {
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	failure := errors.New("failed")
+	err = jsonrpc.ServeListeners([]net.Listener{first, second},
+		func(listener net.Listener) error {
+			if listener == first {
+				return failure
+			}
+
+			// Wait for the listener to be closed.
+			_, err := listener.Accept()
+			return err
+		})
+
+	assert.Equal(t, failure, err)
+}
@@ -0,0 +1,206 @@
+package jsonrpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SetLogger sets where the server logs problems that a handler never sees,
+// such as invalid requests and panics. Nothing is logged by default, or if the
+// logger is nil.
+//
+// The messages are constant and the details are attributes, so that the
+// logger may be wrapped with a SampledHandler to stop a misbehaving client
+// from flooding the logs:
+//
+//     server.SetLogger(slog.New(jsonrpc.NewSampledHandler(
+//         slog.Default().Handler(), 100, time.Minute)))
+//
+func (server *SimpleServer) SetLogger(logger *slog.Logger) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.logger = logger
+}
+
+// log logs the message with the identity of the client, if it is known.
+func (server *SimpleServer) log(level slog.Level, message string, client *ClientInfo,
+	args ...interface{}) {
+	server.mutex.RLock()
+	logger := server.logger
+	server.mutex.RUnlock()
+
+	if logger == nil {
+		return
+	}
+
+	if client != nil {
+		args = append(args, "client", client.ID())
+	}
+
+	logger.Log(context.Background(), level, message, args...)
+}
+
+// SuppressedMessage is the message of the record a SampledHandler logs for
+// the records it has dropped.
+const SuppressedMessage = "Log messages suppressed"
+
+// SampledHandler is a slog.Handler that limits how often identical messages
+// are logged. Records with the same level and message are counted in windows
+// of time. In each window the first record is logged, then only one in every
+// N. At the end of the window a single SuppressedMessage record is logged for
+// each message that had records dropped, with the "message" and the number
+// "suppressed".
+//
+// The attributes of a record are not compared, so a client sending many
+// different invalid requests is still sampled.
+type SampledHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+type sampler struct {
+	every  int
+	window time.Duration
+
+	// Clock is used to find the end of each window.
+	clock Clock
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	messages    map[sampleKey]*sampleCount
+}
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+type sampleCount struct {
+	seen       int
+	suppressed int
+
+	// handler is the handler that logged the first record, so the summary has
+	// the same attributes and groups.
+	handler slog.Handler
+}
+
+// NewSampledHandler creates a SampledHandler that logs to next. After the
+// first record of a message, one record in every is logged in each window. If
+// every is less than two only the first record of each message is logged.
+func NewSampledHandler(next slog.Handler, every int, window time.Duration) *SampledHandler {
+	return NewSampledHandlerWithClock(next, every, window, SystemClock)
+}
+
+// NewSampledHandlerWithClock is NewSampledHandler with a Clock, for testing.
+func NewSampledHandlerWithClock(next slog.Handler, every int, window time.Duration,
+	clock Clock) *SampledHandler {
+	return &SampledHandler{
+		next: next,
+		sampler: &sampler{
+			every:       every,
+			window:      window,
+			clock:       clock,
+			windowStart: clock.Now(),
+			messages:    map[sampleKey]*sampleCount{},
+		},
+	}
+}
+
+// Enabled reports whether the next handler handles records at the level.
+func (handler *SampledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.next.Enabled(ctx, level)
+}
+
+// Handle logs the record if it has not been sampled out.
+func (handler *SampledHandler) Handle(ctx context.Context, record slog.Record) error {
+	if err := handler.sampler.flushIfExpired(ctx); err != nil {
+		return err
+	}
+
+	if !handler.sampler.sample(handler.next, record) {
+		return nil
+	}
+
+	return handler.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler with the attributes that shares the counts of
+// this handler.
+func (handler *SampledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampledHandler{handler.next.WithAttrs(attrs), handler.sampler}
+}
+
+// WithGroup returns a handler with the group that shares the counts of this
+// handler.
+func (handler *SampledHandler) WithGroup(name string) slog.Handler {
+	return &SampledHandler{handler.next.WithGroup(name), handler.sampler}
+}
+
+// Flush logs the summaries of the current window now, rather than waiting for
+// it to end. It should be called before the program exits.
+func (handler *SampledHandler) Flush(ctx context.Context) error {
+	return handler.sampler.flush(ctx, handler.sampler.clock.Now())
+}
+
+func (s *sampler) sample(next slog.Handler, record slog.Record) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := sampleKey{record.Level, record.Message}
+	count := s.messages[key]
+	if count == nil {
+		count = &sampleCount{handler: next}
+		s.messages[key] = count
+	}
+
+	count.seen++
+	if count.seen == 1 || (s.every > 1 && (count.seen-1)%s.every == 0) {
+		return true
+	}
+
+	count.suppressed++
+
+	return false
+}
+
+func (s *sampler) flushIfExpired(ctx context.Context) error {
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	expired := now.Sub(s.windowStart) >= s.window
+	s.mutex.Unlock()
+
+	if !expired {
+		return nil
+	}
+
+	return s.flush(ctx, now)
+}
+
+func (s *sampler) flush(ctx context.Context, now time.Time) error {
+	s.mutex.Lock()
+	messages := s.messages
+	s.messages = map[sampleKey]*sampleCount{}
+	s.windowStart = now
+	s.mutex.Unlock()
+
+	// The next handlers are called without the lock, as they may be slow.
+	for key, count := range messages {
+		if count.suppressed == 0 {
+			continue
+		}
+
+		record := slog.NewRecord(now, key.level, SuppressedMessage, 0)
+		record.AddAttrs(slog.String("message", key.message),
+			slog.Int("suppressed", count.suppressed))
+
+		if err := count.handler.Handle(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// newTestLogger logs lines of "level message attrs" without a time.
+func newTestLogger(output *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(output, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+}
+
+func logLines(output *bytes.Buffer) []string {
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	output.Reset()
+
+	return lines
+}
+
+func TestSimpleServer_SetLogger(t *testing.T) {
+	var output bytes.Buffer
+	server := newTestServer()
+	server.SetLogger(slog.New(newTestLogger(&output)))
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method"`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"panic","id":1}`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`))
+
+	assert.Equal(t, []string{
+		`level=WARN msg="Invalid request" code=-32700 error="Parse error"`,
+		`level=ERROR msg="Handler panicked" method=panic panic=uh-oh!`,
+	}, logLines(&output))
+
+	server.SetLogger(nil)
+	server.Handle([]byte(`{"jsonrpc":"2.0","method"`))
+	assert.Empty(t, output.String())
+}
+
+func TestSampledHandler(t *testing.T) {
+	var output bytes.Buffer
+	clock := jsonrpc.NewFakeClock(epoch)
+	logger := slog.New(jsonrpc.NewSampledHandlerWithClock(newTestLogger(&output),
+		3, time.Minute, clock))
+
+	for i := 0; i < 8; i++ {
+		logger.Warn("Invalid request", "n", i)
+	}
+	logger.Info("Started")
+	logger.Error("Invalid request", "n", 8)
+
+	assert.Equal(t, []string{
+		`level=WARN msg="Invalid request" n=0`,
+		`level=WARN msg="Invalid request" n=3`,
+		`level=WARN msg="Invalid request" n=6`,
+		`level=INFO msg=Started`,
+		`level=ERROR msg="Invalid request" n=8`,
+	}, logLines(&output))
+
+	// The summary is logged when the window ends.
+	clock.Advance(time.Minute)
+	logger.Warn("Invalid request", "n", 9)
+
+	assert.Equal(t, []string{
+		`level=WARN msg="Log messages suppressed" message="Invalid request" suppressed=5`,
+		`level=WARN msg="Invalid request" n=9`,
+	}, logLines(&output))
+}
+
+func TestSampledHandler_Flush(t *testing.T) {
+	var output bytes.Buffer
+	handler := jsonrpc.NewSampledHandler(newTestLogger(&output), 0, time.Hour)
+	logger := slog.New(handler).With("client", "10.0.0.1")
+
+	logger.Warn("Invalid request")
+	logger.Warn("Invalid request")
+	logger.Warn("Invalid request")
+	assert.NoError(t, handler.Flush(context.Background()))
+
+	assert.Equal(t, []string{
+		`level=WARN msg="Invalid request" client=10.0.0.1`,
+		`level=WARN msg="Log messages suppressed" client=10.0.0.1 message="Invalid request" suppressed=2`,
+	}, logLines(&output))
+}
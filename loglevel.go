@@ -0,0 +1,250 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// LevelTrace is more verbose than slog.LevelDebug. The server logs every
+// request it receives at this level.
+const LevelTrace = slog.LevelDebug - 4
+
+// LogLevelMethod is the conventional name of the admin method returned by
+// SimpleServer.LogLevelHandler.
+const LogLevelMethod = "rpc.logLevel"
+
+// ParseLogLevel parses the name of a level, such as "trace", "debug" or
+// "warn+2". Case is ignored.
+func ParseLogLevel(name string) (slog.Level, error) {
+	if strings.EqualFold(name, "trace") {
+		return LevelTrace, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, errors.New("Log level " + name + " is not valid.")
+	}
+
+	return level, nil
+}
+
+// logLevelName is the opposite of ParseLogLevel.
+func logLevelName(level slog.Level) string {
+	if level == LevelTrace {
+		return "TRACE"
+	}
+
+	return level.String()
+}
+
+// validLogLevelPattern reports whether the pattern is a method name, a
+// namespace ending in ".*" or a single "*".
+func validLogLevelPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	name := strings.TrimSuffix(pattern, ".*")
+
+	return name != "" && !strings.Contains(name, "*")
+}
+
+// SetLogLevel sets the level of the records the server logs about the
+// methods that match pattern. The pattern is a method name, a namespace such
+// as "billing.*" (which includes "billing.invoice.create") or "*" for every
+// method. The most specific pattern that matches a method is used.
+//
+// The level replaces the level of the logger for these records, so that a
+// single namespace can be debugged in production without debug logging for
+// every method:
+//
+//     server.SetLogLevel("billing.*", jsonrpc.LevelTrace)
+//
+// Records about methods that do not match a pattern, and records that are not
+// about a method, are logged at the level of the logger. See also
+// LogLevelHandler and Config.LogLevels.
+func (server *SimpleServer) SetLogLevel(pattern string, level slog.Level) error {
+	if !validLogLevelPattern(pattern) {
+		return errors.New("Log level pattern " + pattern + " is not valid.")
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	levels := server.copyLogLevels()
+	levels[pattern] = level
+	server.logLevels = levels
+
+	return nil
+}
+
+// ResetLogLevel removes the level set for the pattern.
+func (server *SimpleServer) ResetLogLevel(pattern string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	levels := server.copyLogLevels()
+	delete(levels, pattern)
+	server.logLevels = levels
+}
+
+// LogLevels returns the levels that have been set, by pattern.
+func (server *SimpleServer) LogLevels() map[string]slog.Level {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	return server.copyLogLevels()
+}
+
+// LogLevel returns the level of the records logged about the method, and
+// false if no pattern matches it.
+func (server *SimpleServer) LogLevel(method string) (slog.Level, bool) {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	return methodLogLevel(server.logLevels, method)
+}
+
+// copyLogLevels must be called with the mutex held. The map is never changed
+// once it has been set, so that it can be read without holding the mutex.
+func (server *SimpleServer) copyLogLevels() map[string]slog.Level {
+	levels := make(map[string]slog.Level, len(server.logLevels))
+	for pattern, level := range server.logLevels {
+		levels[pattern] = level
+	}
+
+	return levels
+}
+
+// methodLogLevel finds the most specific pattern that matches the method.
+func methodLogLevel(levels map[string]slog.Level, method string) (slog.Level, bool) {
+	if len(levels) == 0 {
+		return 0, false
+	}
+
+	if level, ok := levels[method]; ok {
+		return level, true
+	}
+
+	for i := strings.LastIndexByte(method, '.'); i >= 0; i = strings.LastIndexByte(method[:i], '.') {
+		if level, ok := levels[method[:i]+".*"]; ok {
+			return level, true
+		}
+	}
+
+	level, ok := levels["*"]
+
+	return level, ok
+}
+
+// logMethod is log for a record about a method. If a level has been set for
+// the method it is used instead of the level of the logger.
+func (server *SimpleServer) logMethod(level slog.Level, message, method string,
+	client *ClientInfo, args ...interface{}) {
+	server.mutex.RLock()
+	logger := server.logger
+	clock := server.clock
+	methodLevel, ok := methodLogLevel(server.logLevels, method)
+	server.mutex.RUnlock()
+
+	if logger == nil || (ok && level < methodLevel) {
+		return
+	}
+
+	args = append([]interface{}{"method", method}, args...)
+	if client != nil {
+		args = append(args, "client", client.ID())
+	}
+
+	if !ok {
+		logger.Log(context.Background(), level, message, args...)
+		return
+	}
+
+	// The handler is called directly, as it would drop the records that are
+	// below its own level. The time is that of the clock of the server (see
+	// SetClock).
+	record := slog.NewRecord(clock.Now(), level, message, 0)
+	record.Add(args...)
+	logger.Handler().Handle(context.Background(), record)
+}
+
+// LogLevelHandler returns the handler of an admin method, usually registered
+// as LogLevelMethod, that changes the levels while the server is running. The
+// params are an object with a "pattern" and a "level":
+//
+//     {"jsonrpc": "2.0", "method": "rpc.logLevel",
+//      "params": {"pattern": "billing.*", "level": "trace"}, "id": 1}
+//
+// An empty level resets the pattern. Without params nothing is changed. The
+// result is always the levels that are set, by pattern. Admin methods should
+// usually be registered on a group that requires authorization:
+//
+//     admin.SetHandler(jsonrpc.LogLevelMethod, server.LogLevelHandler())
+func (server *SimpleServer) LogLevelHandler() RequestHandler {
+	return func(request RequestResponder) Response {
+		if request.Params() != nil {
+			params, _ := request.Params().(map[string]interface{})
+			pattern, _ := params["pattern"].(string)
+			name, ok := params["level"].(string)
+			if !ok {
+				return request.NewErrorResponse(InvalidParams, "Level must be a string.")
+			}
+
+			if name == "" {
+				server.ResetLogLevel(pattern)
+			} else {
+				level, err := ParseLogLevel(name)
+				if err != nil {
+					return request.NewErrorResponse(InvalidParams, err.Error())
+				}
+
+				if err := server.SetLogLevel(pattern, level); err != nil {
+					return request.NewErrorResponse(InvalidParams, err.Error())
+				}
+			}
+		}
+
+		levels := map[string]string{}
+		for pattern, level := range server.LogLevels() {
+			levels[pattern] = logLevelName(level)
+		}
+
+		return request.NewSuccessResponse(levels)
+	}
+}
+
+// applyLogLevels replaces the levels with those of a Config, which must have
+// been validated. It must be called with the mutex held.
+func (server *SimpleServer) applyLogLevels(names map[string]string) {
+	levels := make(map[string]slog.Level, len(names))
+	for pattern, name := range names {
+		levels[pattern], _ = ParseLogLevel(name)
+	}
+
+	server.logLevels = levels
+}
+
+// validateLogLevels checks the LogLevels of a Config.
+func validateLogLevels(names map[string]string) error {
+	patterns := make([]string, 0, len(names))
+	for pattern := range names {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if !validLogLevelPattern(pattern) {
+			return errors.New("Log level pattern " + pattern + " is not valid.")
+		}
+
+		if _, err := ParseLogLevel(names[pattern]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
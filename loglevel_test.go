@@ -0,0 +1,123 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for name, expected := range map[string]slog.Level{
+		"trace":  jsonrpc.LevelTrace,
+		"TRACE":  jsonrpc.LevelTrace,
+		"debug":  slog.LevelDebug,
+		"warn+2": slog.LevelWarn + 2,
+	} {
+		level, err := jsonrpc.ParseLogLevel(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, level, name)
+	}
+
+	_, err := jsonrpc.ParseLogLevel("loud")
+	assert.EqualError(t, err, "Log level loud is not valid.")
+}
+
+func TestSimpleServer_LogLevel(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	assert.NoError(t, server.SetLogLevel("*", slog.LevelWarn))
+	assert.NoError(t, server.SetLogLevel("billing.*", slog.LevelDebug))
+	assert.NoError(t, server.SetLogLevel("billing.invoice.*", jsonrpc.LevelTrace))
+	assert.NoError(t, server.SetLogLevel("billing.refund", slog.LevelError))
+	assert.EqualError(t, server.SetLogLevel("billing*", slog.LevelDebug),
+		"Log level pattern billing* is not valid.")
+
+	for method, expected := range map[string]slog.Level{
+		"sum":                   slog.LevelWarn,
+		"billing.charge":        slog.LevelDebug,
+		"billing.invoice.email": jsonrpc.LevelTrace,
+		"billing.refund":        slog.LevelError,
+		"billingcharge":         slog.LevelWarn,
+	} {
+		level, ok := server.LogLevel(method)
+		assert.True(t, ok, method)
+		assert.Equal(t, expected, level, method)
+	}
+
+	server.ResetLogLevel("*")
+	_, ok := server.LogLevel("sum")
+	assert.False(t, ok)
+	assert.Len(t, server.LogLevels(), 3)
+}
+
+func TestSimpleServer_SetLogLevel(t *testing.T) {
+	var output bytes.Buffer
+	server := newTestServer()
+	server.SetClock(jsonrpc.NewFakeClock(epoch))
+	server.SetLogger(slog.New(newTestLogger(&output)))
+
+	// The logger only logs warnings and errors, except about "sum".
+	assert.NoError(t, server.SetLogLevel("sum", jsonrpc.LevelTrace))
+	assert.NoError(t, server.SetLogLevel("panic", slog.LevelError+4))
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[2,1],"id":2}`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"panic","id":3}`))
+
+	assert.Equal(t, []string{
+		`level=DEBUG-4 msg="Request received" method=sum id=1`,
+		`level=DEBUG msg="Request handled" method=sum id=1 code=0 duration=0s`,
+	}, logLines(&output))
+
+	server.ResetLogLevel("panic")
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"panic","id":3}`))
+	assert.Equal(t, []string{
+		`level=ERROR msg="Handler panicked" method=panic panic=uh-oh!`,
+	}, logLines(&output))
+
+	// The records logged at the level of a pattern have the time of the clock
+	// of the server.
+	server.SetLogger(slog.New(slog.NewTextHandler(&output, nil)))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1],"id":1}`))
+	for _, line := range logLines(&output) {
+		assert.True(t, strings.HasPrefix(line, "time=2020-01-01T00:00:00.000Z "), line)
+	}
+}
+
+func TestSimpleServer_LogLevelHandler(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler(jsonrpc.LogLevelMethod, server.LogLevelHandler())
+
+	call := func(params string) string {
+		return server.Handle([]byte(`{"jsonrpc":"2.0","method":"rpc.logLevel",` +
+			params + `"id":1}`))[0].String()
+	}
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"billing.*":"TRACE"}}`,
+		call(`"params":{"pattern":"billing.*","level":"trace"},`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"billing.*":"TRACE","sum":"WARN"}}`,
+		call(`"params":{"pattern":"sum","level":"warn"},`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"sum":"WARN"}}`,
+		call(`"params":{"pattern":"billing.*","level":""},`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"sum":"WARN"}}`, call(``))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Log level loud is not valid."}}`,
+		call(`"params":{"pattern":"sum","level":"loud"},`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Level must be a string."}}`,
+		call(`"params":{"pattern":"sum"},`))
+}
+
+func TestSimpleServer_ApplyConfigLogLevels(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	assert.NoError(t, server.SetLogLevel("sum", slog.LevelDebug))
+
+	// A reload replaces the levels set while the server was running.
+	server.ApplyConfig(&jsonrpc.Config{LogLevels: map[string]string{"billing.*": "trace"}})
+	assert.Equal(t, map[string]slog.Level{"billing.*": jsonrpc.LevelTrace}, server.LogLevels())
+
+	server.ApplyConfig(&jsonrpc.Config{})
+	assert.Empty(t, server.LogLevels())
+}
+
@@ -0,0 +1,301 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LongPoller delivers notifications to clients that cannot use a persistent
+// connection. Each client polls with a session name and the poll is held open
+// until there are notifications for that session, or the timeout is reached.
+//
+//     poller := jsonrpc.NewLongPoller(30 * time.Second)
+//     http.Handle("/poll", poller)
+//
+//     // When the client signs in, give it its session and token:
+//     token := poller.Token("client-1")
+//
+//     // Somewhere else:
+//     poller.Notify("client-1", "orderShipped", map[string]interface{}{"id": 5})
+//
+// A poll is a GET (or POST) to the handler with the session and its token in
+// the query string, such as "/poll?session=client-1&token=...". A poll with
+// the wrong token is answered with 403 Forbidden, so a client cannot read the
+// notifications of another session by guessing its name. The response is a
+// JSON array of the notifications, which will be empty if the poll timed out.
+// Notifications that could not be written are kept for the next poll.
+//
+// The notifications of a session that has not polled for more than two
+// timeouts, such as a client that lost its network for a while, are dropped.
+// Its token is kept so that the client can carry on polling when it is back;
+// it should fetch whatever it missed some other way. A session is only
+// forgotten, along with its token, by Forget, such as when the client signs
+// out. A client whose poll is answered with 403 Forbidden must get a new token
+// (usually by signing in again) before it polls again.
+type LongPoller struct {
+	timeout time.Duration
+
+	// MaxQueue is the maximum number of notifications kept for each session
+	// between polls. The oldest notifications are dropped first. Zero means
+	// there is no limit.
+	MaxQueue int
+
+	// Clock is used for the poll timeout. SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex    sync.Mutex
+	sessions map[string]*pollSession
+
+	// expired is when sessions were last checked for expiry.
+	expired time.Time
+}
+
+type pollSession struct {
+	notifications []Request
+	lastPoll      time.Time
+
+	// token must be sent with every poll, see Token.
+	token string
+
+	// ready is closed (and replaced) when notifications are added.
+	ready chan struct{}
+}
+
+// NewLongPoller creates a LongPoller that holds each poll open for up to
+// timeout.
+func NewLongPoller(timeout time.Duration) *LongPoller {
+	return &LongPoller{
+		timeout:  timeout,
+		MaxQueue: 1000,
+		sessions: make(map[string]*pollSession),
+	}
+}
+
+// session must be called while holding the mutex.
+func (poller *LongPoller) session(name string) *pollSession {
+	now := clockOrSystem(poller.Clock).Now()
+	poller.expire(now)
+
+	session := poller.sessions[name]
+	if session == nil {
+		session = &pollSession{
+			lastPoll: now,
+			ready:    make(chan struct{}),
+		}
+		poller.sessions[name] = session
+	}
+
+	return session
+}
+
+// expire drops the notifications of the sessions that have not polled for
+// more than two timeouts, and forgets those that do not have a token as no
+// client can poll them. It only looks at them once per timeout. It must be
+// called while holding the mutex.
+func (poller *LongPoller) expire(now time.Time) {
+	if now.Sub(poller.expired) < poller.timeout {
+		return
+	}
+	poller.expired = now
+
+	for name, session := range poller.sessions {
+		if !poller.idle(session, now) {
+			continue
+		}
+
+		if session.token == "" {
+			delete(poller.sessions, name)
+		} else {
+			session.notifications = nil
+		}
+	}
+}
+
+// idle returns true if the session has not polled for more than two timeouts.
+func (poller *LongPoller) idle(session *pollSession, now time.Time) bool {
+	return session.lastPoll.Before(now.Add(-2 * poller.timeout))
+}
+
+// push must be called while holding the mutex.
+func (poller *LongPoller) push(session *pollSession, notifications ...Request) {
+	session.notifications = append(session.notifications, notifications...)
+	if poller.MaxQueue > 0 && len(session.notifications) > poller.MaxQueue {
+		session.notifications =
+			session.notifications[len(session.notifications)-poller.MaxQueue:]
+	}
+
+	close(session.ready)
+	session.ready = make(chan struct{})
+}
+
+// Token returns the token that a client must send to poll the session, and
+// creates the session if it does not exist. It should only be given to the
+// client that the session belongs to, such as in the response to signing in.
+// The token stays the same until the session is forgotten with Forget.
+func (poller *LongPoller) Token(session string) string {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	s := poller.session(session)
+	if s.token == "" {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			panic(err)
+		}
+		s.token = hex.EncodeToString(token)
+	}
+
+	return s.token
+}
+
+// authorized returns true if token is the token of the session.
+func (poller *LongPoller) authorized(session, token string) bool {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	s := poller.sessions[session]
+
+	return s != nil && s.token != "" &&
+		subtle.ConstantTimeCompare([]byte(s.token), []byte(token)) == 1
+}
+
+// Notify queues a notification for a single session. The session does not need
+// to have polled yet.
+func (poller *LongPoller) Notify(session, method string, params interface{}) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	poller.push(poller.session(session),
+		NewRequestResponder("2.0", nil, method, params))
+}
+
+// Broadcast queues a notification for every session that has polled recently.
+func (poller *LongPoller) Broadcast(method string, params interface{}) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	notification := NewRequestResponder("2.0", nil, method, params)
+	now := clockOrSystem(poller.Clock).Now()
+	poller.expire(now)
+	for _, session := range poller.sessions {
+		if !poller.idle(session, now) {
+			poller.push(session, notification)
+		}
+	}
+}
+
+// Forget removes the session, its notifications and its token. Polls with the
+// old token are answered with 403 Forbidden.
+func (poller *LongPoller) Forget(session string) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	if s := poller.sessions[session]; s != nil {
+		delete(poller.sessions, session)
+		close(s.ready)
+	}
+}
+
+// Poll waits for notifications for the session. It returns as soon as there is
+// at least one notification, or returns nil when the timeout is reached or ctx
+// is done. The notifications are removed from the session; see Requeue.
+func (poller *LongPoller) Poll(ctx context.Context, session string) []Request {
+	clock := clockOrSystem(poller.Clock)
+	timeout := clock.After(poller.timeout)
+
+	var s *pollSession
+	for {
+		poller.mutex.Lock()
+		if s == nil {
+			s = poller.session(session)
+		} else if poller.sessions[session] != s {
+			// The session was forgotten while the poll waited.
+			poller.mutex.Unlock()
+			return nil
+		}
+		s.lastPoll = clock.Now()
+		notifications := s.notifications
+		s.notifications = nil
+		ready := s.ready
+		poller.mutex.Unlock()
+
+		if len(notifications) > 0 {
+			return notifications
+		}
+
+		select {
+		case <-ready:
+		case <-timeout:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Requeue puts notifications returned by Poll back in front of those queued
+// for the session since, because they could not be delivered. They are dropped
+// if the session has been forgotten.
+func (poller *LongPoller) Requeue(session string, notifications []Request) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+
+	s := poller.sessions[session]
+	if s == nil {
+		return
+	}
+
+	requeued := append(append([]Request{}, notifications...), s.notifications...)
+	s.notifications = nil
+	poller.push(s, requeued...)
+}
+
+// ServeHTTP handles a single poll.
+func (poller *LongPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := r.URL.Query().Get("session")
+	if session == "" {
+		http.Error(w, "Missing session.", http.StatusBadRequest)
+		return
+	}
+
+	if !poller.authorized(session, r.URL.Query().Get("token")) {
+		http.Error(w, "Invalid token.", http.StatusForbidden)
+		return
+	}
+
+	notifications := poller.Poll(r.Context(), session)
+	if notifications == nil {
+		notifications = []Request{}
+	}
+
+	// The client may have gone away while the poll was held open.
+	err := r.Context().Err()
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		err = json.NewEncoder(w).Encode(notifications)
+	}
+	if err == nil {
+		err = http.NewResponseController(w).Flush()
+		if errors.Is(err, http.ErrNotSupported) {
+			err = nil
+		}
+	}
+
+	if err != nil && len(notifications) > 0 {
+		poller.Requeue(session, notifications)
+	}
+}
@@ -0,0 +1,157 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func poll(poller *jsonrpc.LongPoller, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	poller.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+
+	return recorder
+}
+
+// pollSession polls a session with its token.
+func pollSession(poller *jsonrpc.LongPoller, session string) *httptest.ResponseRecorder {
+	return poll(poller, "/poll?session="+session+"&token="+poller.Token(session))
+}
+
+// failingWriter is a ResponseWriter whose connection has gone away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("Connection reset.")
+}
+
+func TestLongPoller(t *testing.T) {
+	t.Run("QueuedNotification", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Second)
+		poller.Notify("a", "foo", []int{1})
+		recorder := pollSession(poller, "a")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"foo","params":[1]}]`+"\n",
+			recorder.Body.String())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(10 * time.Millisecond)
+		recorder := pollSession(poller, "a")
+
+		assert.Equal(t, "[]\n", recorder.Body.String())
+	})
+
+	t.Run("WaitForNotification", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Minute)
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- pollSession(poller, "a")
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		poller.Broadcast("foo", nil)
+		recorder := <-done
+
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"foo"}]`+"\n",
+			recorder.Body.String())
+	})
+
+	t.Run("OtherSession", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(10 * time.Millisecond)
+		poller.Notify("a", "foo", nil)
+
+		assert.Equal(t, "[]\n", pollSession(poller, "b").Body.String())
+	})
+
+	t.Run("MaxQueue", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Second)
+		poller.MaxQueue = 1
+		poller.Notify("a", "foo", nil)
+		poller.Notify("a", "bar", nil)
+
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"bar"}]`+"\n",
+			pollSession(poller, "a").Body.String())
+	})
+
+	t.Run("MissingSession", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Second)
+
+		assert.Equal(t, http.StatusBadRequest, poll(poller, "/poll").Code)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Second)
+		poller.Notify("a", "foo", nil)
+
+		assert.Equal(t, http.StatusForbidden, poll(poller, "/poll?session=a").Code)
+
+		token := poller.Token("a")
+		assert.Len(t, token, 32)
+		assert.Equal(t, token, poller.Token("a"))
+		assert.NotEqual(t, token, poller.Token("b"))
+		assert.Equal(t, http.StatusForbidden,
+			poll(poller, "/poll?session=a&token="+poller.Token("b")).Code)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		clock := jsonrpc.NewFakeClock(epoch)
+		poller := jsonrpc.NewLongPoller(time.Second)
+		poller.Clock = clock
+		token := poller.Token("a")
+		poller.Notify("a", "foo", nil)
+
+		// The notifications of a session that stopped polling are dropped, by
+		// Notify too, not only by Broadcast. The token is kept so the client
+		// can poll again.
+		clock.Advance(3 * time.Second)
+		poller.Notify("b", "bar", nil)
+		poller.Broadcast("baz", nil)
+		poller.Notify("a", "qux", nil)
+
+		recorder := poll(poller, "/poll?session=a&token="+token)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"qux"}]`+"\n", recorder.Body.String())
+		assert.Equal(t, token, poller.Token("a"))
+	})
+
+	t.Run("Forget", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Minute)
+		token := poller.Token("a")
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- poll(poller, "/poll?session=a&token="+token)
+		}()
+
+		// A poll that is waiting returns without notifications.
+		time.Sleep(10 * time.Millisecond)
+		poller.Forget("a")
+		assert.Equal(t, "[]\n", (<-done).Body.String())
+
+		assert.Equal(t, http.StatusForbidden,
+			poll(poller, "/poll?session=a&token="+token).Code)
+		assert.NotEqual(t, token, poller.Token("a"))
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		poller := jsonrpc.NewLongPoller(time.Second)
+		poller.Notify("a", "foo", nil)
+
+		poller.ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest(
+			http.MethodGet, "/poll?session=a&token="+poller.Token("a"), nil))
+		poller.Notify("a", "bar", nil)
+
+		// The notification that could not be written is delivered first.
+		assert.Equal(t, `[{"jsonrpc":"2.0","method":"foo"},{"jsonrpc":"2.0","method":"bar"}]`+"\n",
+			pollSession(poller, "a").Body.String())
+	})
+}
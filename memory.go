@@ -0,0 +1,185 @@
+package jsonrpc
+
+import (
+	"strconv"
+	"sync"
+)
+
+// memoryBudgetStateKey holds the MemoryBudget of a connection.
+const memoryBudgetStateKey = "jsonrpc.memory"
+
+// EstimateMemory returns roughly how many bytes decoding the payload into
+// interface{} values will allocate, from the number and size of the values it
+// contains. It does not decode the payload or check that it is valid JSON.
+// The estimate is for a 64-bit platform and errs on the high side.
+func EstimateMemory(data []byte) int64 {
+	var strings, stringBytes, scalars, objects, arrays, members int64
+	inString, escaped, inScalar := false, false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				continue
+			}
+
+			stringBytes++
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString, inScalar = true, false
+			strings++
+
+		case c == '{':
+			objects++
+
+		case c == '[':
+			arrays++
+
+		case c == ':':
+			members++
+			inScalar = false
+
+		case c == '}' || c == ']' || c == ',' || isSpace(c):
+			inScalar = false
+
+		case !inScalar:
+			inScalar = true
+			scalars++
+		}
+	}
+
+	// Every value except a member name is held in an interface. Strings have
+	// a header, numbers are boxed, objects are maps (whose buckets hold a key
+	// and a value for each member) and arrays are slices.
+	values := strings + scalars + objects + arrays - members
+
+	return 16*values + 16*strings + stringBytes + 8*scalars + 48*objects +
+		48*members + 24*arrays
+}
+
+// MemoryBudget limits the memory, as estimated by EstimateMemory, used by the
+// requests that are being handled at once. Use SetMemoryBudget for a budget
+// that is shared by every request of a server, or StateWithMemoryBudget for a
+// budget of a single connection:
+//
+//     server.SetMemoryBudget(jsonrpc.NewMemoryBudget(64 << 20))
+//
+// A payload that does not fit is answered with a MemoryLimitExceeded error
+// that may be retried. It is safe for concurrent use.
+type MemoryBudget struct {
+	limit int64
+
+	mutex sync.Mutex
+	inUse int64
+}
+
+// NewMemoryBudget creates a MemoryBudget of limit bytes.
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{limit: limit}
+}
+
+// Reserve takes n bytes from the budget, and returns false (without taking
+// them) if there is not enough left.
+func (budget *MemoryBudget) Reserve(n int64) bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	if budget.inUse+n > budget.limit {
+		return false
+	}
+
+	budget.inUse += n
+
+	return true
+}
+
+// Release gives back n bytes that were taken by Reserve.
+func (budget *MemoryBudget) Release(n int64) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	budget.inUse -= n
+}
+
+// InUse returns the number of bytes that are reserved.
+func (budget *MemoryBudget) InUse() int64 {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	return budget.inUse
+}
+
+// StateWithMemoryBudget returns a copy of state with a budget for the
+// requests that are handled with it, such as those of a single connection. It
+// applies as well as the budget of the server.
+func StateWithMemoryBudget(state State, budget *MemoryBudget) State {
+	copied := make(State, len(state)+1)
+	for key, value := range state {
+		copied[key] = value
+	}
+	copied[memoryBudgetStateKey] = budget
+
+	return copied
+}
+
+// SetMemoryBudget sets the budget shared by every payload the server handles.
+// Nil, the default, does not limit them.
+func (server *SimpleServer) SetMemoryBudget(budget *MemoryBudget) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.memoryBudget = budget
+}
+
+// reserveMemory takes the estimated memory of the payload from the budget of
+// the server and of the connection, either of which may be nil. It returns a
+// function that gives it back (or nil if nothing was taken), or an error if it
+// does not fit.
+func reserveMemory(data []byte, server, conn *MemoryBudget) (func(), *RPCError) {
+	if server == nil && conn == nil {
+		return nil, nil
+	}
+
+	estimate := EstimateMemory(data)
+	if server != nil && !server.Reserve(estimate) {
+		return nil, memoryError(estimate, server)
+	}
+
+	if conn != nil && !conn.Reserve(estimate) {
+		if server != nil {
+			server.Release(estimate)
+		}
+
+		return nil, memoryError(estimate, conn)
+	}
+
+	return func() {
+		if server != nil {
+			server.Release(estimate)
+		}
+		if conn != nil {
+			conn.Release(estimate)
+		}
+	}, nil
+}
+
+// memoryError is the error for a payload that does not fit in the budget. It
+// may only be retried if the payload would fit once the budget is free.
+func memoryError(estimate int64, budget *MemoryBudget) *RPCError {
+	return &RPCError{
+		Code:    MemoryLimitExceeded,
+		Message: "Memory limit exceeded",
+		Data: NewErrorDetails(LimitExceededErrorType).
+			WithDetail("The request needs about " + strconv.FormatInt(estimate, 10) +
+				" bytes but only " + strconv.FormatInt(budget.limit-budget.InUse(), 10) +
+				" are available.").
+			WithRetryable(estimate <= budget.limit),
+	}
+}
@@ -0,0 +1,117 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	assert.Equal(t, int64(0), jsonrpc.EstimateMemory(nil))
+	assert.Equal(t, int64(16+8), jsonrpc.EstimateMemory([]byte(`42`)))
+	assert.Equal(t, int64(16+16+5), jsonrpc.EstimateMemory([]byte(`"a\"bc"`)))
+	assert.Equal(t, int64(3*16+24+2*8), jsonrpc.EstimateMemory([]byte(`[1, true]`)))
+
+	// The estimate is at least what decoding actually allocates.
+	payload := []byte(`{"jsonrpc":"2.0","method":"import","id":1,"params":[` +
+		strings.Repeat(`{"name":"widget","price":1.5,"tags":["a","b"]},`, 1000) + `{}]}`)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	var value interface{}
+	assert.NoError(t, json.Unmarshal(payload, &value))
+	runtime.ReadMemStats(&after)
+
+	allocated := int64(after.TotalAlloc - before.TotalAlloc)
+	estimate := jsonrpc.EstimateMemory(payload)
+	assert.True(t, estimate >= allocated/2, "estimate %d, allocated %d", estimate, allocated)
+	assert.True(t, estimate <= allocated*4, "estimate %d, allocated %d", estimate, allocated)
+}
+
+func TestRequestLimits_MaxMemory(t *testing.T) {
+	server := newTestServer()
+	server.SetRequestLimits(jsonrpc.RequestLimits{MaxMemory: 1000})
+
+	assert.Equal(t, jsonrpc.Success, server.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`))[0].ErrorCode())
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[` +
+		strings.Repeat("1,", 50) + `1],"id":1}`))[0]
+	assert.Equal(t, jsonrpc.MemoryLimitExceeded, response.ErrorCode())
+
+	assert.Equal(t, 1.0, response.ID())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.LimitExceededErrorType, details.Type)
+	assert.False(t, details.Retryable)
+}
+
+func TestMemoryBudget(t *testing.T) {
+	budget := jsonrpc.NewMemoryBudget(100)
+	assert.True(t, budget.Reserve(60))
+	assert.False(t, budget.Reserve(50))
+	assert.Equal(t, int64(60), budget.InUse())
+	budget.Release(60)
+	assert.True(t, budget.Reserve(100))
+	budget.Release(100)
+
+	server := newTestServer()
+	server.SetMemoryBudget(jsonrpc.NewMemoryBudget(5000))
+	connection := jsonrpc.NewMemoryBudget(1000)
+	state := jsonrpc.StateWithMemoryBudget(nil, connection)
+
+	// The budget is only used while a payload is being handled.
+	inUse := int64(-1)
+	server.SetHandler("inUse", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		inUse = connection.InUse()
+		return request.NewSuccessResponse(nil)
+	})
+
+	request := []byte(`{"jsonrpc":"2.0","method":"inUse","id":1}`)
+	assert.Equal(t, jsonrpc.Success, server.HandleWithState(request, state)[0].ErrorCode())
+	assert.Equal(t, jsonrpc.EstimateMemory(request), inUse)
+	assert.Equal(t, int64(0), connection.InUse())
+
+	// A payload that does not fit in what is left of the budget of the
+	// connection may be retried once other requests have finished.
+	assert.True(t, connection.Reserve(900))
+	response := server.HandleWithState([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":"a"}`),
+		state)[0]
+	assert.Equal(t, jsonrpc.MemoryLimitExceeded, response.ErrorCode())
+	assert.Equal(t, "a", response.ID())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.True(t, details.Retryable)
+	connection.Release(900)
+
+	// A payload that is larger than the whole budget never fits.
+	response = server.HandleWithState([]byte(`{"jsonrpc":"2.0","method":"sum","params":[`+
+		strings.Repeat("1,", 50)+`1],"id":1}`), state)[0]
+	assert.Equal(t, jsonrpc.MemoryLimitExceeded, response.ErrorCode())
+	assert.Equal(t, 1.0, response.ID())
+
+	details, err = jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.False(t, details.Retryable)
+
+	// The id of a batch member is not used.
+	response = server.HandleWithState([]byte(`[{"jsonrpc":"2.0","method":"sum","params":[`+
+		strings.Repeat("1,", 50)+`1],"id":1}]`), state)[0]
+	assert.Equal(t, jsonrpc.MemoryLimitExceeded, response.ErrorCode())
+	assert.Nil(t, response.ID())
+
+	// Without the budget of the connection it fits in the budget of the
+	// server.
+	var buf bytes.Buffer
+	assert.NoError(t, server.HandleArena(&buf, []byte(`{"jsonrpc":"2.0","method":"sum","params":[`+
+		strings.Repeat("1,", 50)+`1],"id":1}`), nil))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":51}`, buf.String())
+}
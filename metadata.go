@@ -0,0 +1,208 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schema describes the shape of a JSON value. It is a subset of JSON Schema
+// so that it can be used as is in generated documentation.
+type Schema struct {
+	// Type is one of "string", "number", "integer", "boolean", "object",
+	// "array" or "null". An empty Type allows any value.
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
+
+// Validate returns a violation for each part of the value that does not match
+// the schema. The value is expected to be decoded JSON, other values are
+// encoded to JSON and decoded again before they are checked. A nil schema
+// accepts any value.
+func (schema *Schema) Validate(value interface{}) []FieldViolation {
+	validation := new(Validation)
+	schema.validate(normalizeJSON(value), "", validation)
+
+	return validation.Violations()
+}
+
+func (schema *Schema) validate(value interface{}, field string,
+	validation *Validation) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Type != "" && jsonType(value, schema.Type) != schema.Type {
+		validation.Add(field, "must be of type "+schema.Type)
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				validation.Add(joinField(field, name), "is required")
+			}
+		}
+
+		// Sorted so that the violations are always in the same order.
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property, ok := value[name]; ok {
+				schema.Properties[name].validate(property,
+					joinField(field, name), validation)
+			}
+		}
+
+	case []interface{}:
+		for i, item := range value {
+			schema.Items.validate(item, joinField(field, strconv.Itoa(i)),
+				validation)
+		}
+	}
+}
+
+// jsonType returns the schema type of a decoded JSON value. Numbers that are
+// whole are reported as "integer" only when that is the expected type, since
+// every integer is also a number.
+func jsonType(value interface{}, expected string) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+
+	case bool:
+		return "boolean"
+
+	case string:
+		return "string"
+
+	case float64:
+		if expected == "integer" && value == math.Trunc(value) {
+			return "integer"
+		}
+
+		return "number"
+
+	case map[string]interface{}:
+		return "object"
+
+	case []interface{}:
+		return "array"
+	}
+
+	return ""
+}
+
+// normalizeJSON converts a value into the types that encoding/json decodes
+// into, so that Go structs and typed slices (even when nested in a decoded
+// object) can be validated.
+func normalizeJSON(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, bool, string, float64:
+		return value
+	}
+
+	var normalized interface{}
+	encoded, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(encoded, &normalized)
+	}
+	if err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+func joinField(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+
+	return parent + "." + child
+}
+
+// MethodInfo describes a method that has been registered on the server. It is
+// optional and does not affect how requests are handled unless stated
+// otherwise. It is used to generate schemas, documentation and clients for the
+// server.
+type MethodInfo struct {
+	Name        string
+	Description string
+
+	// Params describes the params as an object of named params.
+	Params *Schema
+
+	// Result describes the result of a successful response.
+	Result *Schema
+
+	// ReadOnly methods do not modify any state. This is a hint to clients
+	// that the method is safe to retry or send to a replica.
+	ReadOnly bool
+
+	// Deprecated explains what should be used instead of the method. Calls to
+	// methods that are deprecated or have a Sunset are counted, see
+	// DeprecatedCalls.
+	Deprecated string
+
+	// Sunset is when the method is retired. After this time the server will
+	// respond to the method with a MethodRetired error (without calling the
+	// handler) that contains the Deprecated message in the ErrorDetails.
+	Sunset time.Time
+}
+
+// isDeprecated returns true if calls to the method should be counted.
+func (info MethodInfo) isDeprecated() bool {
+	return info.Deprecated != "" || !info.Sunset.IsZero()
+}
+
+// SetMethodInfo will register (or replace) the description of a method. The
+// handler for the method is registered separately with SetHandler.
+func (server *SimpleServer) SetMethodInfo(info MethodInfo) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.methodInfo[info.Name] = info
+}
+
+// GetMethodInfo returns the description of a method, if it has been set.
+func (server *SimpleServer) GetMethodInfo(methodName string) (MethodInfo, bool) {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	info, ok := server.methodInfo[methodName]
+	return info, ok
+}
+
+// Methods returns the description of every method that has a handler and
+// method info, sorted by name.
+func (server *SimpleServer) Methods() []MethodInfo {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	methods := []MethodInfo{}
+	for name, info := range server.methodInfo {
+		if server.requestHandlers[name] != nil {
+			methods = append(methods, info)
+		}
+	}
+
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+
+	return methods
+}
+
+// MethodRetiredErrorType is the ErrorDetails type of a MethodRetired error.
+const MethodRetiredErrorType = "urn:jsonrpc:error:method-retired"
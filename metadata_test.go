@@ -0,0 +1,136 @@
+package jsonrpc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_SetMethodInfo(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "sum", ReadOnly: true})
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "subtract", ReadOnly: true})
+	server.SetMethodInfo(jsonrpc.MethodInfo{Name: "noHandler"})
+
+	t.Run("GetMethodInfo", func(t *testing.T) {
+		info, ok := server.GetMethodInfo("sum")
+
+		assert.True(t, ok)
+		assert.Equal(t, jsonrpc.MethodInfo{Name: "sum", ReadOnly: true}, info)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, ok := server.GetMethodInfo("get_data")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Methods", func(t *testing.T) {
+		assert.Equal(t, []jsonrpc.MethodInfo{
+			{Name: "subtract", ReadOnly: true},
+			{Name: "sum", ReadOnly: true},
+		}, server.Methods())
+	})
+}
+
+func TestMethodInfo_Sunset(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:       "sum",
+		Deprecated: "Use add instead.",
+		Sunset:     time.Now().Add(-time.Hour),
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:       "subtract",
+		Deprecated: "Use minus instead.",
+		Sunset:     time.Now().Add(time.Hour),
+	})
+	server.SetMethodInfo(jsonrpc.MethodInfo{
+		Name:       "get_data",
+		Deprecated: "Use data.get instead.",
+	})
+
+	t.Run("Retired", func(t *testing.T) {
+		responses := server.Handle([]byte(
+			`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1}`))
+		details, _ := jsonrpc.ErrorDetailsFromResponse(responses[0])
+
+		assert.Equal(t, jsonrpc.MethodRetired, responses[0].ErrorCode())
+		assert.Equal(t, "Method retired", responses[0].ErrorMessage())
+		assert.Equal(t, jsonrpc.MethodRetiredErrorType, details.Type)
+		assert.Equal(t, "Use add instead.", details.Detail)
+	})
+
+	t.Run("BeforeSunset", func(t *testing.T) {
+		responses := server.Handle([]byte(
+			`{"jsonrpc": "2.0", "method": "subtract", "params": [3, 2], "id": 1}`))
+
+		assert.Equal(t, 1.0, responses[0].Result())
+	})
+
+	t.Run("DeprecatedCalls", func(t *testing.T) {
+		server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`))
+		server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data"}`))
+
+		assert.Equal(t, map[string]uint64{
+			"subtract": 1,
+			"get_data": 2,
+		}, server.DeprecatedCalls())
+	})
+}
+
+func TestSchema_Validate(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	schema := &jsonrpc.Schema{
+		Type:     "object",
+		Required: []string{"id", "name"},
+		Properties: map[string]*jsonrpc.Schema{
+			"id":      {Type: "integer"},
+			"name":    {Type: "string"},
+			"address": {Type: "object", Required: []string{"city", "zip"}},
+			"tags":    {Type: "array", Items: &jsonrpc.Schema{Type: "string"}},
+		},
+	}
+
+	for name, test := range map[string]struct {
+		value      interface{}
+		violations []jsonrpc.FieldViolation
+	}{
+		"valid": {
+			map[string]interface{}{"id": 1.0, "name": "Bob", "tags": []interface{}{}},
+			nil,
+		},
+		"wrong type": {
+			"Bob",
+			[]jsonrpc.FieldViolation{{Field: "", Message: "must be of type object"}},
+		},
+		"nested": {
+			map[string]interface{}{
+				"id":      1.5,
+				"address": address{City: "Paris"},
+				"tags":    []string{"a", "b"},
+			},
+			[]jsonrpc.FieldViolation{
+				{Field: "name", Message: "is required"},
+				{Field: "address.zip", Message: "is required"},
+				{Field: "id", Message: "must be of type integer"},
+			},
+		},
+		"items": {
+			map[string]interface{}{"id": 2, "name": "Bob", "tags": []interface{}{"a", 3.0}},
+			[]jsonrpc.FieldViolation{{Field: "tags.1", Message: "must be of type string"}},
+		},
+	} {
+		assert.Equal(t, test.violations, schema.Validate(test.value), name)
+	}
+
+	var nilSchema *jsonrpc.Schema
+	assert.Empty(t, nilSchema.Validate("anything"))
+	assert.Empty(t, (&jsonrpc.Schema{Type: "number"}).Validate(3))
+	assert.Empty(t, (&jsonrpc.Schema{Type: "null"}).Validate(nil))
+}
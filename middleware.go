@@ -0,0 +1,40 @@
+package jsonrpc
+
+// Chain combines middleware into a single Middleware. The first middleware is
+// the outermost, so Chain(a, b)(handler) is a(b(handler)). Chains can be built
+// once and shared:
+//
+//     standard := jsonrpc.Chain(logCalls, requireAuth, tracker.Middleware())
+//
+//     server.SetHandler("user.get", standard(getUser))
+//     admin := server.Group(standard, requireScope("admin"))
+//
+// Chain with no middleware returns the handler unchanged.
+func Chain(middleware ...Middleware) Middleware {
+	middleware = append([]Middleware(nil), middleware...)
+
+	return func(handler RequestHandler) RequestHandler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+
+		return handler
+	}
+}
+
+// Use adds middleware that intercepts every request the server dispatches to
+// a handler, whichever handler it is: those registered with SetHandler (before
+// or after Use is called), the next handlers of SetNextHandler and the
+// fallback. This is for cross-cutting concerns such as logging, metrics and
+// authentication that must not be forgotten when a method is added.
+//
+// The middleware runs after the request has been validated and its method
+// found, and outside of the MethodLimits of the method. The first middleware
+// given to the first call to Use is the outermost.
+func (server *SimpleServer) Use(middleware ...Middleware) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.middleware = append(server.middleware, middleware...)
+	server.interceptor = Chain(server.middleware...)
+}
@@ -0,0 +1,71 @@
+package jsonrpc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// tracer records the name of the middleware as each request passes through it.
+func tracer(trace *[]string, name string) jsonrpc.Middleware {
+	return func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			*trace = append(*trace, name+" "+request.Method())
+			return next(request)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var trace []string
+	chain := jsonrpc.Chain(tracer(&trace, "a"), tracer(&trace, "b"), tracer(&trace, "c"))
+
+	response := chain(getData)(jsonrpc.NewRequestResponder("2.0", 1, "get_data", nil))
+	assert.Equal(t, []interface{}{"hello", 5.0}, response.Result())
+	assert.Equal(t, []string{"a get_data", "b get_data", "c get_data"}, trace)
+
+	response = jsonrpc.Chain()(getData)(jsonrpc.NewRequestResponder("2.0", 1, "get_data", nil))
+	assert.Equal(t, []interface{}{"hello", 5.0}, response.Result())
+}
+
+func TestSimpleServer_Use(t *testing.T) {
+	var trace []string
+	server := newTestServer()
+	server.Use(tracer(&trace, "a"), tracer(&trace, "b"))
+	server.Use(tracer(&trace, "c"))
+
+	server.SetHandler("late", getData)
+	server.SetHandler("slow", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		time.Sleep(10 * time.Millisecond)
+		return request.NewSuccessResponse(nil)
+	})
+	server.SetMethodLimits("slow", jsonrpc.MethodLimits{MaxTime: time.Millisecond})
+
+	var codes []int
+	server.Use(func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			response := next(request)
+			codes = append(codes, response.ErrorCode())
+			return response
+		}
+	})
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "get_data", "id": 1},
+		{"jsonrpc": "2.0", "method": "late", "id": 2},
+		{"jsonrpc": "2.0", "method": "missing", "id": 3},
+		{"jsonrpc": "2.0", "method": "slow", "id": 4}
+	]`))
+	assert.Len(t, responses, 4)
+
+	assert.Equal(t, []string{
+		"a get_data", "b get_data", "c get_data",
+		"a late", "b late", "c late",
+		"a slow", "b slow", "c slow",
+	}, trace)
+
+	// The middleware sees the errors of the limits.
+	assert.Equal(t, []int{jsonrpc.Success, jsonrpc.Success, jsonrpc.TimeLimitExceeded}, codes)
+}
@@ -0,0 +1,436 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// maxMessagePackDepth is how deeply arrays and maps may be nested, the same as
+// encoding/json allows.
+const maxMessagePackDepth = 10000
+
+// MessagePackFramer reads and writes messages encoded as MessagePack
+// (https://msgpack.org) instead of JSON. Each message is a single MessagePack
+// value, one after the other with nothing in between.
+//
+// The messages are converted so that the rest of the server only ever sees
+// JSON: ReadFrame returns JSON and WriteFrame expects JSON. Binary strings are
+// converted to base64 strings, like encoding/json does for a []byte. A message
+// that uses extension types, has a map key that is not a string or a float
+// that is not a finite number is malformed and can be skipped. A message that
+// is too large, or that is not MessagePack at all, cannot be skipped and stops
+// the stream whatever the Recovery.
+type MessagePackFramer struct {
+	frameReader
+}
+
+// NewMessagePackFramer creates a MessagePackFramer on the stream. A maxSize of
+// zero uses DefaultMaxFrameSize.
+func NewMessagePackFramer(rw io.ReadWriter, recovery Recovery, maxSize int) *MessagePackFramer {
+	return &MessagePackFramer{newFrameReader(rw, recovery, maxSize)}
+}
+
+// ReadFrame returns the next message, converted to JSON.
+func (framer *MessagePackFramer) ReadFrame() ([]byte, error) {
+	if framer.failed != nil {
+		return nil, framer.failed
+	}
+
+	// Nothing has been read if the stream ends before the next message.
+	if _, err := framer.reader.Peek(1); err != nil {
+		return nil, err
+	}
+
+	decoder := &messagePackDecoder{reader: framer.reader, remaining: framer.maxSize}
+	value, err := decoder.decode(0)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		if fatal, ok := err.(messagePackError); ok {
+			framer.failed = &FrameError{Message: string(fatal)}
+			return nil, framer.failed
+		}
+
+		return nil, err
+	}
+
+	if decoder.invalid != "" {
+		return nil, framer.fail(decoder.invalid)
+	}
+
+	frame, err := json.Marshal(value)
+	if err != nil {
+		return nil, framer.fail("Message cannot be converted to JSON.")
+	}
+
+	return frame, nil
+}
+
+// WriteFrame converts the JSON message to MessagePack and writes it.
+func (framer *MessagePackFramer) WriteFrame(frame []byte) error {
+	encoded, err := JSONToMessagePack(frame)
+	if err != nil {
+		return err
+	}
+
+	return framer.write(encoded)
+}
+
+// JSONToMessagePack converts a JSON value to MessagePack. Numbers are encoded
+// as integers if they are integers, otherwise as 64 bit floats. The members of
+// objects are sorted by name.
+func JSONToMessagePack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if err := encodeMessagePack(&buffer, value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func encodeMessagePack(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+
+	case bool:
+		if value {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+
+	case json.Number:
+		if n, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			encodeMessagePackInt(buffer, n)
+			break
+		}
+
+		if n, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+			buffer.WriteByte(0xcf)
+			binary.Write(buffer, binary.BigEndian, n)
+			break
+		}
+
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, f)
+
+	case string:
+		n := len(value)
+		switch {
+		case n < 32:
+			buffer.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			buffer.Write([]byte{0xd9, byte(n)})
+		case n <= math.MaxUint16:
+			buffer.WriteByte(0xda)
+			binary.Write(buffer, binary.BigEndian, uint16(n))
+		default:
+			buffer.WriteByte(0xdb)
+			binary.Write(buffer, binary.BigEndian, uint32(n))
+		}
+		buffer.WriteString(value)
+
+	case []interface{}:
+		encodeMessagePackLength(buffer, len(value), 0x90, 0xdc)
+		for _, element := range value {
+			if err := encodeMessagePack(buffer, element); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		encodeMessagePackLength(buffer, len(value), 0x80, 0xde)
+		for _, name := range names {
+			encodeMessagePack(buffer, name)
+			if err := encodeMessagePack(buffer, value[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func encodeMessagePackInt(buffer *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		buffer.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buffer.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buffer.Write([]byte{0xd0, byte(int8(n))})
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(n))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, n)
+	}
+}
+
+// encodeMessagePackLength writes the header of an array or map. fix is the
+// header of the short form and long16 of the 16 bit form, which is followed by
+// the 32 bit form.
+func encodeMessagePackLength(buffer *bytes.Buffer, n int, fix, long16 byte) {
+	switch {
+	case n < 16:
+		buffer.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buffer.WriteByte(long16)
+		binary.Write(buffer, binary.BigEndian, uint16(n))
+	default:
+		buffer.WriteByte(long16 + 1)
+		binary.Write(buffer, binary.BigEndian, uint32(n))
+	}
+}
+
+// messagePackError is a malformed message that cannot be skipped.
+type messagePackError string
+
+func (err messagePackError) Error() string {
+	return string(err)
+}
+
+// messagePackDecoder decodes a single value. A value that is well formed but
+// cannot be converted to JSON is read to the end, so that the next value can
+// be read, and the reason is kept in invalid.
+type messagePackDecoder struct {
+	reader io.Reader
+
+	// remaining is how many more bytes the value may use.
+	remaining int
+	invalid   string
+}
+
+// read returns the next n bytes.
+func (decoder *messagePackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > decoder.remaining {
+		return nil, messagePackError("Message is too large.")
+	}
+	decoder.remaining -= n
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(decoder.reader, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// readUint reads a big-endian integer of size bytes.
+func (decoder *messagePackDecoder) readUint(size int) (uint64, error) {
+	data, err := decoder.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+
+	return n, nil
+}
+
+func (decoder *messagePackDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxMessagePackDepth {
+		return nil, messagePackError("Message is nested too deeply.")
+	}
+
+	header, err := decoder.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	b := header[0]
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b >= 0xa0 && b <= 0xbf:
+		return decoder.decodeString(int(b & 0x1f))
+	case b >= 0x90 && b <= 0x9f:
+		return decoder.decodeArray(int(b&0x0f), depth)
+	case b >= 0x80 && b <= 0x8f:
+		return decoder.decodeMap(int(b&0x0f), depth)
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := decoder.readUint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.read(int(n))
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := decoder.readUint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.skipExtension(int(n))
+
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decoder.skipExtension(1 << (b - 0xd4))
+
+	case 0xca:
+		n, err := decoder.readUint(4)
+		return decoder.float(float64(math.Float32frombits(uint32(n)))), err
+
+	case 0xcb:
+		n, err := decoder.readUint(8)
+		return decoder.float(math.Float64frombits(n)), err
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return decoder.readUint(1 << (b - 0xcc))
+
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := decoder.readUint(size)
+		// Sign extend.
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := decoder.readUint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeString(int(n))
+
+	case 0xdc, 0xdd:
+		n, err := decoder.readUint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeArray(int(n), depth)
+
+	case 0xde, 0xdf:
+		n, err := decoder.readUint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.decodeMap(int(n), depth)
+	}
+
+	return nil, messagePackError("Message is not valid MessagePack.")
+}
+
+func (decoder *messagePackDecoder) decodeString(n int) (interface{}, error) {
+	data, err := decoder.read(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (decoder *messagePackDecoder) decodeArray(n int, depth int) (interface{}, error) {
+	// Every element is at least one byte.
+	if n < 0 || n > decoder.remaining {
+		return nil, messagePackError("Message is too large.")
+	}
+
+	array := make([]interface{}, n)
+	for i := range array {
+		element, err := decoder.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		array[i] = element
+	}
+
+	return array, nil
+}
+
+func (decoder *messagePackDecoder) decodeMap(n int, depth int) (interface{}, error) {
+	// Every key and value is at least one byte.
+	if n < 0 || n > decoder.remaining/2 {
+		return nil, messagePackError("Message is too large.")
+	}
+
+	members := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := decoder.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := decoder.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			decoder.setInvalid("Map keys must be strings.")
+			continue
+		}
+		members[name] = value
+	}
+
+	return members, nil
+}
+
+func (decoder *messagePackDecoder) skipExtension(n int) (interface{}, error) {
+	// The type and the data.
+	if _, err := decoder.read(1 + n); err != nil {
+		return nil, err
+	}
+
+	decoder.setInvalid("Extension types are not supported.")
+	return nil, nil
+}
+
+func (decoder *messagePackDecoder) float(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		decoder.setInvalid("Floats must be finite numbers.")
+		return nil
+	}
+
+	return f
+}
+
+// setInvalid keeps the first reason the value cannot be converted.
+func (decoder *messagePackDecoder) setInvalid(message string) {
+	if decoder.invalid == "" {
+		decoder.invalid = message
+	}
+}
@@ -0,0 +1,85 @@
+package jsonrpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestJSONToMessagePack(t *testing.T) {
+	for data, expected := range map[string]string{
+		`null`:                              "\xc0",
+		`[true,false]`:                      "\x92\xc3\xc2",
+		`{"b":1,"a":-1}`:                    "\x82\xa1a\xff\xa1b\x01",
+		`[200,-100,70000]`:                  "\x93\xd1\x00\xc8\xd0\x9c\xd2\x00\x01\x11\x70",
+		`18446744073709551615`:              "\xcf\xff\xff\xff\xff\xff\xff\xff\xff",
+		`1.5`:                               "\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00",
+		`"` + strings.Repeat("x", 40) + `"`: "\xd9\x28" + strings.Repeat("x", 40),
+	} {
+		encoded, err := jsonrpc.JSONToMessagePack([]byte(data))
+		assert.NoError(t, err, data)
+		assert.Equal(t, expected, string(encoded), data)
+	}
+
+	_, err := jsonrpc.JSONToMessagePack([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestMessagePackFramer(t *testing.T) {
+	input := "\x83\xa7jsonrpc\xa32.0\xa6method\xa3sum\xa6params\x92\x01\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00" +
+		// Binary, float32, negative and unsigned integers.
+		"\x94\xc4\x02hi\xca\x3f\xc0\x00\x00\xd1\xff\x38\xcd\x01\x00" +
+		// An extension type and a map with an integer key are skipped.
+		"\x91\xd4\x01\x02" +
+		"\x81\x01\xa1x" +
+		"\x90" +
+		// 0xc1 is never used.
+		"\xc1" +
+		"\x90"
+
+	assert.Equal(t, []string{
+		`{"jsonrpc":"2.0","method":"sum","params":[1,1.5]}`,
+		`["aGk=",1.5,-200,256]`,
+		"error: Extension types are not supported.",
+		"error: Map keys must be strings.",
+		`[]`,
+		"error: Message is not valid MessagePack.",
+	}, readFrames(jsonrpc.NewMessagePackFramer(newStream(input), jsonrpc.RecoverySkip, 0)))
+
+	assert.Equal(t, []string{
+		`{"jsonrpc":"2.0","method":"sum","params":[1,1.5]}`,
+		`["aGk=",1.5,-200,256]`,
+		"error: Extension types are not supported.",
+	}, readFrames(jsonrpc.NewMessagePackFramer(newStream(input), jsonrpc.RecoveryClose, 0)))
+}
+
+func TestMessagePackFramer_Limits(t *testing.T) {
+	for input, expected := range map[string]string{
+		// A string, an array and a map that claim to be larger than the limit.
+		"\xdb\x00\x00\x10\x00": "error: Message is too large.",
+		"\xdd\x7f\xff\xff\xff": "error: Message is too large.",
+		"\xdf\x00\x00\x00\x11": "error: Message is too large.",
+		"\x91\x91\x91":         "error: unexpected EOF",
+	} {
+		assert.Equal(t, []string{expected},
+			readFrames(jsonrpc.NewMessagePackFramer(newStream(input), jsonrpc.RecoverySkip, 32)))
+	}
+
+	nested := strings.Repeat("\x91", 10002) + "\xc0"
+	assert.Equal(t, []string{"error: Message is nested too deeply."},
+		readFrames(jsonrpc.NewMessagePackFramer(newStream(nested), jsonrpc.RecoverySkip, 20000)))
+}
+
+func TestMessagePackFramer_WriteFrame(t *testing.T) {
+	s := newStream("")
+	framer := jsonrpc.NewMessagePackFramer(s, jsonrpc.RecoverySkip, 0)
+
+	assert.NoError(t, framer.WriteFrame([]byte(`{"jsonrpc":"2.0","id":1,"result":19}`)))
+	assert.Equal(t, "\x83\xa2id\x01\xa7jsonrpc\xa32.0\xa6result\x13", s.String())
+
+	// What is written can be read back.
+	assert.Equal(t, []string{`{"id":1,"jsonrpc":"2.0","result":19}`},
+		readFrames(jsonrpc.NewMessagePackFramer(newStream(s.String()), jsonrpc.RecoverySkip, 0)))
+}
@@ -0,0 +1,155 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// NDJSONContentType is the content type of a stream of newline delimited JSON
+// values, one request or response per line.
+const NDJSONContentType = "application/x-ndjson"
+
+// HandleNDJSON reads newline delimited requests from r and writes each
+// response to w as soon as it is ready, followed by a newline. Unlike a batch,
+// only one request is held in memory at a time, so a stream of any length can
+// be handled. Blank lines are ignored and a line that holds an array is handled
+// as a batch, with each of its responses written on a separate line. A line
+// longer than DefaultMaxFrameSize is answered with a ParseError (with a null
+// id) and skipped.
+//
+// It returns when r has been read to the end, or an error if reading or writing
+// fails.
+func (server *SimpleServer) HandleNDJSON(w io.Writer, r io.Reader, state State) error {
+	reader := bufio.NewReader(r)
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		line, tooLong, err := readLine(reader, DefaultMaxFrameSize)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var responses []Response
+		if tooLong {
+			atomic.AddUint64(&server.totalErrorResponses, 1)
+			responses = []Response{server.respond(nil,
+				NewErrorResponse(nil, ParseError, "Message is too large."))}
+		} else if line = bytes.TrimSpace(line); len(line) > 0 {
+			responses = server.HandleWithState(line, state)
+		} else {
+			continue
+		}
+
+		for _, response := range responses {
+			if _, err := WriteResponse(w, response); err != nil {
+				return err
+			}
+
+			if _, err := w.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// NDJSONHandler returns an HTTP handler that accepts a POST whose body is a
+// stream of newline delimited requests and streams the responses back in the
+// same format. The responses are flushed as they are written so the client can
+// read them before it has finished sending. The body may be of any length, but
+// each of its lines is limited to DefaultMaxFrameSize.
+func NDJSONHandler(server *SimpleServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Without this an HTTP/1 server stops reading the body once the first
+		// response has been flushed.
+		controller := http.NewResponseController(w)
+		controller.EnableFullDuplex()
+
+		// The headers are sent straight away so that a client that waits for
+		// them before it starts sending requests does not deadlock.
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.WriteHeader(http.StatusOK)
+		controller.Flush()
+
+		server.HandleNDJSON(w, r.Body, StateWithContext(nil, r.Context()))
+	})
+}
+
+// NDJSONWriter writes requests (or responses) as newline delimited JSON.
+type NDJSONWriter struct {
+	w io.Writer
+}
+
+// NewNDJSONWriter creates an NDJSONWriter that writes to w.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+// WriteRequest writes a single request followed by a newline.
+func (writer *NDJSONWriter) WriteRequest(request Request) error {
+	_, err := writer.w.Write(append(request.Bytes(), '\n'))
+	return err
+}
+
+// WriteResponse writes a single response followed by a newline.
+func (writer *NDJSONWriter) WriteResponse(response Response) error {
+	_, err := writer.w.Write(append(response.Bytes(), '\n'))
+	return err
+}
+
+// NDJSONReader reads responses from a stream of newline delimited JSON, such
+// as the body returned by an NDJSONHandler.
+type NDJSONReader struct {
+	reader *bufio.Reader
+}
+
+// NewNDJSONReader creates an NDJSONReader that reads from r.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{reader: bufio.NewReader(r)}
+}
+
+// ReadResponse returns the next response in the stream. It returns io.EOF
+// when there are no more responses. A line longer than DefaultMaxFrameSize is
+// skipped and a *FrameError is returned for it.
+func (reader *NDJSONReader) ReadResponse() (Response, error) {
+	for {
+		line, tooLong, err := readLine(reader.reader, DefaultMaxFrameSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if tooLong {
+			return nil, &FrameError{Message: "Message is too large.", Recovered: true}
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			responses, err := NewResponsesFromJSON(line)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(responses) != 1 {
+				return nil, errors.New("Expected a single response per line.")
+			}
+
+			return responses[0], nil
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_HandleNDJSON(t *testing.T) {
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`,
+		``,
+		`{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`,
+		`[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":2},` +
+			`{"jsonrpc":"2.0","method":"missing","id":3}]`,
+		`{"jsonrpc":"2.0","method"`,
+		`{"jsonrpc":"2.0","method":"subtract","params":[1,1],"id":4}`,
+	}, "\n")
+
+	var output bytes.Buffer
+	err := newTestServer().HandleNDJSON(&output, strings.NewReader(input), nil)
+	assert.NoError(t, err)
+
+	reader := jsonrpc.NewNDJSONReader(&output)
+	var ids []interface{}
+	var codes []int
+	for {
+		response, err := reader.ReadResponse()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		ids = append(ids, response.ID())
+		codes = append(codes, response.ErrorCode())
+	}
+
+	// The notification is not answered, the parse error is with a null id.
+	assert.Equal(t, []interface{}{1.0, 2.0, 3.0, nil, 4.0}, ids)
+	assert.Equal(t, []int{jsonrpc.Success, jsonrpc.Success, jsonrpc.MethodNotFound,
+		jsonrpc.ParseError, jsonrpc.Success}, codes)
+}
+
+func TestNDJSONReader_Batch(t *testing.T) {
+	reader := jsonrpc.NewNDJSONReader(strings.NewReader(
+		`[{"jsonrpc":"2.0","result":1,"id":1},{"jsonrpc":"2.0","result":2,"id":2}]`))
+
+	_, err := reader.ReadResponse()
+	assert.EqualError(t, err, "Expected a single response per line.")
+}
+
+func TestNDJSONHandler(t *testing.T) {
+	httpServer := httptest.NewServer(jsonrpc.NDJSONHandler(newTestServer()))
+	defer httpServer.Close()
+
+	body, requests := io.Pipe()
+	response, err := http.Post(httpServer.URL, jsonrpc.NDJSONContentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	assert.Equal(t, jsonrpc.NDJSONContentType, response.Header.Get("Content-Type"))
+
+	// Each response is read before the next request is sent.
+	writer := jsonrpc.NewNDJSONWriter(requests)
+	reader := jsonrpc.NewNDJSONReader(bufio.NewReader(response.Body))
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, writer.WriteRequest(jsonrpc.NewRequestResponder(
+			"2.0", i, "sum", []int{i, i})))
+
+		result, err := reader.ReadResponse()
+		assert.NoError(t, err)
+		assert.Equal(t, float64(i*2), result.Result())
+	}
+
+	requests.Close()
+	_, err = reader.ReadResponse()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNDJSONHandler_MethodNotAllowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	jsonrpc.NDJSONHandler(newTestServer()).ServeHTTP(recorder,
+		httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestSimpleServer_HandleNDJSON_TooLarge(t *testing.T) {
+	long := `"` + strings.Repeat("a", jsonrpc.DefaultMaxFrameSize) + "\"\n"
+	input := long + `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`
+
+	var output bytes.Buffer
+	err := newTestServer().HandleNDJSON(&output, strings.NewReader(input), nil)
+	assert.NoError(t, err)
+
+	reader := jsonrpc.NewNDJSONReader(&output)
+	response, err := reader.ReadResponse()
+	if assert.NoError(t, err) {
+		assert.Equal(t, jsonrpc.ParseError, response.ErrorCode())
+		assert.Nil(t, response.ID())
+	}
+
+	response, err = reader.ReadResponse()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1.0, response.ID())
+	}
+
+	// The reader skips a line that is too long.
+	reader = jsonrpc.NewNDJSONReader(strings.NewReader(long +
+		`{"jsonrpc":"2.0","result":1,"id":1}`))
+	_, err = reader.ReadResponse()
+	assert.EqualError(t, err, "Message is too large.")
+
+	response, err = reader.ReadResponse()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1.0, response.ID())
+	}
+}
@@ -0,0 +1,75 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// openRPCDocument is the part of an OpenRPC document that describes methods.
+// See https://spec.open-rpc.org.
+type openRPCDocument struct {
+	Methods []struct {
+		Name        string `json:"name"`
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Deprecated  bool   `json:"deprecated"`
+		Params      []struct {
+			Name     string  `json:"name"`
+			Required bool    `json:"required"`
+			Schema   *Schema `json:"schema"`
+		} `json:"params"`
+		Result *struct {
+			Schema *Schema `json:"schema"`
+		} `json:"result"`
+	} `json:"methods"`
+}
+
+// LoadOpenRPC reads the methods of an OpenRPC document. The params of each
+// method become the properties of an object Params schema. Only the parts of
+// JSON Schema supported by Schema are kept, and references ($ref) are not
+// resolved.
+func LoadOpenRPC(r io.Reader) ([]MethodInfo, error) {
+	var document openRPCDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	methods := make([]MethodInfo, len(document.Methods))
+	for i, method := range document.Methods {
+		if method.Name == "" {
+			return nil, errors.New("Every method must have a name.")
+		}
+
+		info := MethodInfo{
+			Name:        method.Name,
+			Description: method.Description,
+		}
+
+		if info.Description == "" {
+			info.Description = method.Summary
+		}
+
+		if method.Deprecated {
+			info.Deprecated = "Deprecated."
+		}
+
+		if len(method.Params) > 0 {
+			info.Params = &Schema{Type: "object", Properties: map[string]*Schema{}}
+			for _, param := range method.Params {
+				info.Params.Properties[param.Name] = param.Schema
+				if param.Required {
+					info.Params.Required = append(info.Params.Required, param.Name)
+				}
+			}
+		}
+
+		if method.Result != nil {
+			info.Result = method.Result.Schema
+		}
+
+		methods[i] = info
+	}
+
+	return methods, nil
+}
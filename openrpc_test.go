@@ -0,0 +1,70 @@
+package jsonrpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+const testOpenRPC = `{
+	"openrpc": "1.2.6",
+	"info": {"title": "Users", "version": "1.0.0"},
+	"methods": [
+		{
+			"name": "user.get",
+			"summary": "Gets a user.",
+			"params": [
+				{"name": "id", "required": true, "schema": {"type": "integer"}},
+				{"name": "fields", "schema": {"type": "array", "items": {"type": "string"}}}
+			],
+			"result": {
+				"name": "user",
+				"schema": {
+					"type": "object",
+					"required": ["id", "name"],
+					"properties": {"id": {"type": "integer"}, "name": {"type": "string"}}
+				}
+			}
+		},
+		{"name": "user.purge", "deprecated": true}
+	]
+}`
+
+func TestLoadOpenRPC(t *testing.T) {
+	methods, err := jsonrpc.LoadOpenRPC(strings.NewReader(testOpenRPC))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []jsonrpc.MethodInfo{
+		{
+			Name:        "user.get",
+			Description: "Gets a user.",
+			Params: &jsonrpc.Schema{
+				Type: "object",
+				Properties: map[string]*jsonrpc.Schema{
+					"id":     {Type: "integer"},
+					"fields": {Type: "array", Items: &jsonrpc.Schema{Type: "string"}},
+				},
+				Required: []string{"id"},
+			},
+			Result: &jsonrpc.Schema{
+				Type:     "object",
+				Required: []string{"id", "name"},
+				Properties: map[string]*jsonrpc.Schema{
+					"id":   {Type: "integer"},
+					"name": {Type: "string"},
+				},
+			},
+		},
+		{Name: "user.purge", Deprecated: "Deprecated."},
+	}, methods)
+}
+
+func TestLoadOpenRPC_Invalid(t *testing.T) {
+	_, err := jsonrpc.LoadOpenRPC(strings.NewReader(`{"methods": [{}]}`))
+	assert.EqualError(t, err, "Every method must have a name.")
+
+	_, err = jsonrpc.LoadOpenRPC(strings.NewReader(`{"methods": `))
+	assert.Error(t, err)
+}
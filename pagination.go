@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// PageParams are the conventional params for methods that return a list in
+// pages. They are usually part of a larger params object:
+//
+//     {"jsonrpc": "2.0", "method": "listUsers", "params": {"cursor": "abc", "limit": 50}, "id": 1}
+//
+type PageParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// PageMeta is sent in the "meta" extension of a page response. An empty
+// NextCursor means there are no more pages.
+type PageMeta struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ParsePageParams reads the cursor and limit from the params of the request. A
+// missing limit will use defaultLimit and a limit larger than maxLimit will be
+// reduced to maxLimit.
+//
+// The second return value is an InvalidParams response if the params are not
+// valid, otherwise it is nil.
+func ParsePageParams(request RequestResponder, defaultLimit,
+	maxLimit int) (PageParams, Response) {
+	var page PageParams
+	if request.Params() != nil {
+		if response := BindParams(request, &page); response != nil {
+			return PageParams{}, response
+		}
+	}
+
+	if page.Limit < 0 {
+		validation := new(Validation)
+		validation.Add("limit", "must not be negative")
+		return PageParams{}, validation.NewResponse(request)
+	}
+
+	if page.Limit == 0 {
+		page.Limit = defaultLimit
+	}
+	if maxLimit > 0 && page.Limit > maxLimit {
+		page.Limit = maxLimit
+	}
+
+	return page, nil
+}
+
+// NewPageResponse creates a successful response for a single page of items.
+// The nextCursor is sent in the "meta" extension, so the server must be in
+// lenient mode (see SetLenient).
+func NewPageResponse(request Responder, items interface{},
+	nextCursor string) Response {
+	return WithExtension(request.NewSuccessResponse(items), "meta", PageMeta{
+		NextCursor: nextCursor,
+	})
+}
+
+// NextCursor returns the cursor for the page following the response, or an
+// empty string if this is the last page.
+func NextCursor(response Response) string {
+	switch meta := Extension(response, "meta").(type) {
+	case PageMeta:
+		return meta.NextCursor
+
+	case map[string]interface{}:
+		cursor, _ := meta["nextCursor"].(string)
+		return cursor
+	}
+
+	return ""
+}
+
+// ForEachPage calls method until all pages have been fetched, calling fn with
+// the response for each page. params must be nil or encode to a JSON object,
+// the cursor and limit are added to it for each call. A limit of zero lets the
+// server decide the page size.
+//
+// Iteration stops at the first error, which may be returned from fn, the
+// invoker or an *RPCError from the server.
+func ForEachPage(ctx context.Context, invoker Invoker, method string,
+	params interface{}, limit int, fn func(response Response) error) error {
+	pageParams := map[string]interface{}{}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(b, &pageParams)
+		if err != nil {
+			return errors.New("Params must be an object.")
+		}
+	}
+
+	if limit > 0 {
+		pageParams["limit"] = limit
+	}
+
+	for cursor := ""; ; {
+		if cursor != "" {
+			pageParams["cursor"] = cursor
+		}
+
+		response, err := invoker.Invoke(ctx, method, pageParams)
+		if err != nil {
+			return err
+		}
+
+		if err := ErrorFromResponse(response); err != nil {
+			return err
+		}
+
+		if err := fn(response); err != nil {
+			return err
+		}
+
+		next := NextCursor(response)
+		if next == "" {
+			return nil
+		}
+
+		if next == cursor {
+			return errors.New("Server returned the same cursor.")
+		}
+
+		cursor = next
+	}
+}
@@ -0,0 +1,133 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// listNumbers returns the numbers 0 to 9 in pages.
+func listNumbers(request jsonrpc.RequestResponder) jsonrpc.Response {
+	page, response := jsonrpc.ParsePageParams(request, 3, 5)
+	if response != nil {
+		return response
+	}
+
+	start, _ := strconv.Atoi(page.Cursor)
+	items := []int{}
+	for i := start; i < 10 && i < start+page.Limit; i++ {
+		items = append(items, i)
+	}
+
+	next := ""
+	if start+page.Limit < 10 {
+		next = strconv.Itoa(start + page.Limit)
+	}
+
+	return jsonrpc.NewPageResponse(request, items, next)
+}
+
+// serverInvoker sends requests directly to a server through JSON so the
+// results are the same as a remote client would see.
+func serverInvoker(server *jsonrpc.SimpleServer) jsonrpc.Invoker {
+	return jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, method, params)
+		responses, err := jsonrpc.NewResponsesFromJSON(
+			server.Handle(request.Bytes())[0].Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		return responses[0], nil
+	})
+}
+
+func TestParsePageParams(t *testing.T) {
+	tests := map[string]struct {
+		params   interface{}
+		expected jsonrpc.PageParams
+	}{
+		"nil params":     {nil, jsonrpc.PageParams{Limit: 3}},
+		"default limit":  {map[string]interface{}{"cursor": "a"}, jsonrpc.PageParams{Cursor: "a", Limit: 3}},
+		"limit":          {map[string]interface{}{"limit": 4}, jsonrpc.PageParams{Limit: 4}},
+		"max limit":      {map[string]interface{}{"limit": 100}, jsonrpc.PageParams{Limit: 5}},
+		"other params":   {map[string]interface{}{"foo": "bar"}, jsonrpc.PageParams{Limit: 3}},
+		"cursor & limit": {map[string]interface{}{"cursor": "b", "limit": 1}, jsonrpc.PageParams{Cursor: "b", Limit: 1}},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			request := jsonrpc.NewRequestResponder("2.0", 1, "foo", test.params)
+			page, response := jsonrpc.ParsePageParams(request, 3, 5)
+
+			assert.Nil(t, response)
+			assert.Equal(t, test.expected, page)
+		})
+	}
+
+	t.Run("negative limit", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo",
+			map[string]interface{}{"limit": -1})
+		_, response := jsonrpc.ParsePageParams(request, 3, 5)
+
+		assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+	})
+}
+
+func TestForEachPage(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetLenient(true)
+	server.SetHandler("list", listNumbers)
+	invoker := serverInvoker(server)
+
+	t.Run("AllPages", func(t *testing.T) {
+		var pages [][]interface{}
+		err := jsonrpc.ForEachPage(context.Background(), invoker, "list", nil, 4,
+			func(response jsonrpc.Response) error {
+				pages = append(pages, response.Result().([]interface{}))
+				return nil
+			})
+
+		assert.NoError(t, err)
+		assert.Equal(t, [][]interface{}{
+			{0.0, 1.0, 2.0, 3.0},
+			{4.0, 5.0, 6.0, 7.0},
+			{8.0, 9.0},
+		}, pages)
+	})
+
+	t.Run("StopOnError", func(t *testing.T) {
+		calls := 0
+		err := jsonrpc.ForEachPage(context.Background(), invoker, "list", nil, 0,
+			func(response jsonrpc.Response) error {
+				calls++
+				return errors.New("stop")
+			})
+
+		assert.EqualError(t, err, "stop")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("RPCError", func(t *testing.T) {
+		err := jsonrpc.ForEachPage(context.Background(), invoker, "missing", nil, 0,
+			func(response jsonrpc.Response) error {
+				return nil
+			})
+
+		assert.EqualError(t, err, "Method not found (-32601)")
+	})
+
+	t.Run("BadParams", func(t *testing.T) {
+		err := jsonrpc.ForEachPage(context.Background(), invoker, "list",
+			[]int{1}, 0, func(response jsonrpc.Response) error {
+				return nil
+			})
+
+		assert.EqualError(t, err, "Params must be an object.")
+	})
+}
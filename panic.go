@@ -0,0 +1,53 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicErrorType is the ErrorDetails type of the InternalError sent for a
+// handler that panicked, in debug mode. See SetDebug.
+const PanicErrorType = "urn:jsonrpc:error:panic"
+
+// SetDebug controls if the InternalError sent for a handler that panics
+// includes the panic and the stack trace of the handler:
+//
+//     {"code": -32603, "message": "Internal error", "data": {
+//       "type": "urn:jsonrpc:error:panic",
+//       "detail": "uh-oh!",
+//       "stack": "goroutine 7 [running]:\n...",
+//       "retryable": false
+//     }}
+//
+// This helps while developing, but must not be enabled in production as a
+// panic may contain sensitive information. By default only the code and
+// message are sent.
+func (server *SimpleServer) SetDebug(debug bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.debug = debug
+}
+
+// SetDebug controls if the response to a handler that panics includes the
+// panic and the stack trace. See SimpleServer.SetDebug.
+func (router *Router) SetDebug(debug bool) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	router.debug = debug
+}
+
+// panicResponse is the response to a handler that panicked with recovered. It
+// must be called by the deferred function that recovered, so that the stack
+// trace is of the handler.
+func panicResponse(request RequestResponder, recovered interface{}, debugMode bool) Response {
+	if !debugMode {
+		return request.NewErrorResponse(InternalError, "")
+	}
+
+	details := NewErrorDetails(PanicErrorType).WithDetail(fmt.Sprint(recovered))
+	details.Stack = string(debug.Stack())
+
+	return request.NewErrorResponseWithData(InternalError, "", details)
+}
@@ -0,0 +1,44 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_SetDebug(t *testing.T) {
+	server := newTestServer()
+	request := []byte(`{"jsonrpc": "2.0", "method": "panic", "id": 1}`)
+
+	// The panic is not sent by default.
+	response := server.Handle(request)[0]
+	assert.Equal(t, jsonrpc.InternalError, response.ErrorCode())
+	assert.Equal(t, "Internal error", response.ErrorMessage())
+	assert.Nil(t, response.ErrorData())
+
+	server.SetDebug(true)
+	response = server.Handle(request)[0]
+	assert.Equal(t, jsonrpc.InternalError, response.ErrorCode())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.PanicErrorType, details.Type)
+	assert.Equal(t, "uh-oh!", details.Detail)
+	assert.Contains(t, details.Stack, "forcePanic")
+	assert.False(t, details.Retryable)
+}
+
+func TestRouter_SetDebug(t *testing.T) {
+	router := jsonrpc.NewRouter()
+	router.Handle("panic", forcePanic)
+	router.SetDebug(true)
+
+	response := router.Dispatch(jsonrpc.NewRequestResponder("2.0", 1, "panic", nil))
+	assert.Equal(t, jsonrpc.InternalError, response.ErrorCode())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, "uh-oh!", details.Detail)
+	assert.Contains(t, details.Stack, "forcePanic")
+}
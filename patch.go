@@ -0,0 +1,515 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PatchOperation is a single operation of a JSON Patch (RFC 6902).
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON only includes the members used by the operation, so that a
+// value such as false or null is not left out.
+func (operation PatchOperation) MarshalJSON() ([]byte, error) {
+	switch operation.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{operation.Op, operation.Path, operation.Value})
+
+	case "move", "copy":
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			From string `json:"from"`
+			Path string `json:"path"`
+		}{operation.Op, operation.From, operation.Path})
+	}
+
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+	}{operation.Op, operation.Path})
+}
+
+// CreatePatch returns the operations that turn the JSON encoding of from into
+// the JSON encoding of to. Only "add", "remove" and "replace" are used. Arrays
+// that change length are replaced as a whole, unless items were only added to
+// the end.
+func CreatePatch(from, to interface{}) ([]PatchOperation, error) {
+	fromValue, err := toJSONValue(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toValue, err := toJSONValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendPatch(nil, "", fromValue, toValue), nil
+}
+
+func appendPatch(patch []PatchOperation, path string, from, to interface{}) []PatchOperation {
+	switch to := to.(type) {
+	case map[string]interface{}:
+		from, ok := from.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(from)+len(to))
+		for key := range from {
+			keys = append(keys, key)
+		}
+		for key := range to {
+			if _, ok := from[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "/" + escapePointer(key)
+			oldValue, hadKey := from[key]
+			newValue, hasKey := to[key]
+
+			switch {
+			case !hasKey:
+				patch = append(patch, PatchOperation{Op: "remove", Path: childPath})
+
+			case !hadKey:
+				patch = append(patch, PatchOperation{Op: "add", Path: childPath, Value: newValue})
+
+			default:
+				patch = appendPatch(patch, childPath, oldValue, newValue)
+			}
+		}
+
+		return patch
+
+	case []interface{}:
+		from, ok := from.([]interface{})
+		if !ok || len(to) < len(from) ||
+			(len(to) > len(from) && !reflect.DeepEqual(from, to[:len(from)])) {
+			break
+		}
+
+		for i := range from {
+			patch = appendPatch(patch, path+"/"+strconv.Itoa(i), from[i], to[i])
+		}
+		for _, value := range to[len(from):] {
+			patch = append(patch, PatchOperation{Op: "add", Path: path + "/-", Value: value})
+		}
+
+		return patch
+	}
+
+	if reflect.DeepEqual(from, to) {
+		return patch
+	}
+
+	return append(patch, PatchOperation{Op: "replace", Path: path, Value: to})
+}
+
+// ApplyPatch applies the operations in order to the JSON value doc (as decoded
+// by encoding/json into an interface{}) and returns the result. All of the
+// operations of RFC 6902 are supported. The containers of doc may be modified,
+// so doc must not be used afterwards. If an operation fails the error says
+// which one.
+func ApplyPatch(doc interface{}, patch []PatchOperation) (interface{}, error) {
+	for i, operation := range patch {
+		var err error
+		doc, err = applyOperation(doc, operation)
+		if err != nil {
+			return nil, errors.New("Operation " + strconv.Itoa(i) + ": " + err.Error())
+		}
+	}
+
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, operation PatchOperation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := toJSONValue(operation.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add":
+		return patchAdd(doc, path, value)
+
+	case "remove":
+		doc, _, err = patchRemove(doc, path)
+		return doc, err
+
+	case "replace":
+		if doc, _, err = patchRemove(doc, path); err != nil {
+			return nil, err
+		}
+
+		return patchAdd(doc, path, value)
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == "move" {
+			doc, value, err = patchRemove(doc, from)
+		} else if value, err = patchGet(doc, from); err == nil {
+			value, err = toJSONValue(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return patchAdd(doc, path, value)
+
+	case "test":
+		actual, err := patchGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(actual, value) {
+			return nil, errors.New("Test failed at \"" + operation.Path + "\".")
+		}
+
+		return doc, nil
+	}
+
+	return nil, errors.New("Unknown operation \"" + operation.Op + "\".")
+}
+
+// patchAdd adds value at path, inserting it if the parent is an array.
+func patchAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return patchUpdate(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			parent[key] = value
+			return parent, nil
+
+		case []interface{}:
+			if key == "-" {
+				return append(parent, value), nil
+			}
+
+			i, err := arrayIndex(key, len(parent)+1)
+			if err != nil {
+				return nil, err
+			}
+
+			parent = append(parent, nil)
+			copy(parent[i+1:], parent[i:])
+			parent[i] = value
+
+			return parent, nil
+		}
+
+		return nil, errors.New("Cannot add to a value that is not an object or an array.")
+	})
+}
+
+// patchRemove removes the value at path and returns it.
+func patchRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	var removed interface{}
+	doc, err := patchUpdate(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			value, ok := parent[key]
+			if !ok {
+				return nil, errors.New("Member \"" + key + "\" does not exist.")
+			}
+
+			removed = value
+			delete(parent, key)
+
+			return parent, nil
+
+		case []interface{}:
+			i, err := arrayIndex(key, len(parent))
+			if err != nil {
+				return nil, err
+			}
+
+			removed = parent[i]
+
+			return append(parent[:i], parent[i+1:]...), nil
+		}
+
+		return nil, errors.New("Cannot remove from a value that is not an object or an array.")
+	})
+
+	return doc, removed, err
+}
+
+func patchGet(doc interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, errors.New("Member \"" + key + "\" does not exist.")
+			}
+
+			doc = value
+
+		case []interface{}:
+			i, err := arrayIndex(key, len(node))
+			if err != nil {
+				return nil, err
+			}
+
+			doc = node[i]
+
+		default:
+			return nil, errors.New("Path does not exist.")
+		}
+	}
+
+	return doc, nil
+}
+
+// patchUpdate calls update with the parent of the last key of the path and
+// stores the container it returns in place of the parent.
+func patchUpdate(doc interface{}, path []string,
+	update func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return update(doc, path[0])
+	}
+
+	child, err := patchGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	if child, err = patchUpdate(child, path[1:], update); err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(node))
+		node[i] = child
+	}
+
+	return doc, nil
+}
+
+func arrayIndex(key string, length int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= length || (len(key) > 1 && key[0] == '0') {
+		return 0, errors.New("Invalid array index \"" + key + "\".")
+	}
+
+	return i, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped keys.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, errors.New("Invalid path \"" + pointer + "\".")
+	}
+
+	keys := strings.Split(pointer[1:], "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+
+	return keys, nil
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// toJSONValue returns the value as it would be decoded from its JSON encoding.
+func toJSONValue(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	err = json.Unmarshal(encoded, &decoded)
+
+	return decoded, err
+}
+
+// StateUpdate is the params of a notification sent by a PatchEncoder. It holds
+// either the full State or a Patch to apply to the previous state.
+type StateUpdate struct {
+	// Seq increases by one with every update so that a missed update can be
+	// detected.
+	Seq uint64 `json:"seq"`
+
+	State interface{}      `json:"state,omitempty"`
+	Patch []PatchOperation `json:"patch,omitempty"`
+}
+
+// PatchEncoder reduces the bandwidth of a subscription that repeatedly sends
+// a large object that changes a little at a time. The first update holds the
+// full state and later updates only a JSON Patch from the previous state:
+//
+//     encoder := &jsonrpc.PatchEncoder{}
+//     for board := range changes {
+//         update, err := encoder.Update(board)
+//         if err == nil && update != nil {
+//             poller.Notify(session, "board.changed", update)
+//         }
+//     }
+//
+// The client applies the updates with a PatchDecoder. It is safe for
+// concurrent use, although updates must be sent in the order they were
+// created.
+type PatchEncoder struct {
+	mutex   sync.Mutex
+	seq     uint64
+	state   interface{}
+	started bool
+}
+
+// Update returns the update from the previous state to state, or nil if it
+// has not changed. The full state is sent instead of a patch when the patch
+// would not be smaller.
+func (encoder *PatchEncoder) Update(state interface{}) (*StateUpdate, error) {
+	value, err := toJSONValue(state)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder.mutex.Lock()
+	defer encoder.mutex.Unlock()
+
+	update := &StateUpdate{State: value}
+	if encoder.started {
+		patch := appendPatch(nil, "", encoder.state, value)
+		if len(patch) == 0 {
+			return nil, nil
+		}
+
+		encodedPatch, _ := json.Marshal(patch)
+		encodedState, _ := json.Marshal(value)
+		if len(encodedPatch) < len(encodedState) {
+			update = &StateUpdate{Patch: patch}
+		}
+	}
+
+	encoder.seq++
+	encoder.started = true
+	encoder.state = value
+	update.Seq = encoder.seq
+
+	return update, nil
+}
+
+// Reset causes the next update to hold the full state, such as when a client
+// has resubscribed after missing an update.
+func (encoder *PatchEncoder) Reset() {
+	encoder.mutex.Lock()
+	defer encoder.mutex.Unlock()
+
+	encoder.started = false
+}
+
+// PatchDecoder rebuilds the state sent by a PatchEncoder. It is safe for
+// concurrent use.
+type PatchDecoder struct {
+	mutex   sync.Mutex
+	seq     uint64
+	state   interface{}
+	started bool
+}
+
+// Apply applies an update, which may be a StateUpdate or the params of a
+// notification that holds one, and returns the new state. The state is
+// decoded as by encoding/json into an interface{}. It is updated in place by
+// later updates, so it must be copied to be kept, and must not be modified.
+//
+// An error is returned if an update was missed or could not be applied. The
+// decoder then waits for an update with the full state.
+func (decoder *PatchDecoder) Apply(params interface{}) (interface{}, error) {
+	var update StateUpdate
+	switch params := params.(type) {
+	case StateUpdate:
+		update = params
+
+	case *StateUpdate:
+		update = *params
+
+	default:
+		encoded, err := json.Marshal(params)
+		if err == nil {
+			err = json.Unmarshal(encoded, &update)
+		}
+		if err != nil {
+			return nil, errors.New("Params are not a state update.")
+		}
+	}
+
+	decoder.mutex.Lock()
+	defer decoder.mutex.Unlock()
+
+	if update.Patch == nil {
+		state, err := toJSONValue(update.State)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder.seq, decoder.state, decoder.started = update.Seq, state, true
+
+		return state, nil
+	}
+
+	if !decoder.started {
+		return nil, errors.New("Patch received before the full state.")
+	}
+
+	if update.Seq != decoder.seq+1 {
+		decoder.started = false
+		return nil, errors.New("Missed a state update.")
+	}
+
+	state, err := ApplyPatch(decoder.state, update.Patch)
+	if err != nil {
+		decoder.started = false
+		return nil, err
+	}
+
+	decoder.seq, decoder.state = update.Seq, state
+
+	return state, nil
+}
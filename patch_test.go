@@ -0,0 +1,133 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestCreatePatch(t *testing.T) {
+	from := decodeJSON(t, `{"name":"a","tags":["x"],"size":{"w":1,"h":2},"old":true,"a/b":1}`)
+	to := decodeJSON(t, `{"name":"b","tags":["x","y"],"size":{"w":1,"h":3},"new":false,"a/b":2}`)
+
+	patch, err := jsonrpc.CreatePatch(from, to)
+	assert.NoError(t, err)
+
+	encoded, _ := json.Marshal(patch)
+	assert.Equal(t, `[{"op":"replace","path":"/a~1b","value":2},`+
+		`{"op":"replace","path":"/name","value":"b"},`+
+		`{"op":"add","path":"/new","value":false},`+
+		`{"op":"remove","path":"/old"},`+
+		`{"op":"replace","path":"/size/h","value":3},`+
+		`{"op":"add","path":"/tags/-","value":"y"}]`, string(encoded))
+
+	result, err := jsonrpc.ApplyPatch(from, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, to, result)
+
+	patch, _ = jsonrpc.CreatePatch([]int{1, 2}, []int{2})
+	assert.Equal(t, []jsonrpc.PatchOperation{
+		{Op: "replace", Path: "", Value: []interface{}{2.0}},
+	}, patch)
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := decodeJSON(t, `{"a":[1,2,3],"b":{"c":"d"}}`)
+	result, err := jsonrpc.ApplyPatch(doc, []jsonrpc.PatchOperation{
+		{Op: "add", Path: "/a/1", Value: 9},
+		{Op: "remove", Path: "/a/0"},
+		{Op: "move", From: "/b/c", Path: "/e"},
+		{Op: "copy", From: "/a", Path: "/b/a"},
+		{Op: "test", Path: "/e", Value: "d"},
+		{Op: "replace", Path: "/a/2", Value: nil},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, decodeJSON(t, `{"a":[9,2,null],"b":{"a":[9,2,3]},"e":"d"}`), result)
+
+	for message, operation := range map[string]jsonrpc.PatchOperation{
+		`Operation 0: Test failed at "/a".`:           {Op: "test", Path: "/a", Value: 2},
+		`Operation 0: Member "x" does not exist.`:     {Op: "remove", Path: "/x"},
+		`Operation 0: Invalid array index "5".`:       {Op: "replace", Path: "/b/5", Value: 1},
+		`Operation 0: Invalid path "a".`:              {Op: "add", Path: "a"},
+		`Operation 0: Unknown operation "increment".`: {Op: "increment", Path: "/a"},
+	} {
+		_, err := jsonrpc.ApplyPatch(decodeJSON(t, `{"a":1,"b":[]}`),
+			[]jsonrpc.PatchOperation{operation})
+		assert.EqualError(t, err, message)
+	}
+}
+
+func TestPatchEncoder(t *testing.T) {
+	type board struct {
+		Title string   `json:"title"`
+		Cards []string `json:"cards"`
+	}
+
+	encoder := &jsonrpc.PatchEncoder{}
+	decoder := &jsonrpc.PatchDecoder{}
+
+	state := board{Title: "A long title that is not sent again", Cards: []string{"one"}}
+	update, err := encoder.Update(state)
+	assert.NoError(t, err)
+	assert.Nil(t, update.Patch)
+
+	// Updates are sent as notifications, so decode them like a client would.
+	send := func(update *jsonrpc.StateUpdate) (interface{}, error) {
+		encoded, _ := json.Marshal(update)
+		return decoder.Apply(decodeJSON(t, string(encoded)))
+	}
+
+	result, err := send(update)
+	assert.NoError(t, err)
+	assert.Equal(t, decodeJSON(t, `{"title":"A long title that is not sent again","cards":["one"]}`), result)
+
+	update, _ = encoder.Update(state)
+	assert.Nil(t, update)
+
+	state.Cards = append(state.Cards, "two")
+	update, _ = encoder.Update(state)
+	assert.Equal(t, []jsonrpc.PatchOperation{{Op: "add", Path: "/cards/-", Value: "two"}},
+		update.Patch)
+
+	result, err = send(update)
+	assert.NoError(t, err)
+	assert.Equal(t, decodeJSON(t, `{"title":"A long title that is not sent again","cards":["one","two"]}`), result)
+
+	// A missed update waits for the full state.
+	state.Cards = append(state.Cards, "three")
+	encoder.Update(state)
+	state.Cards = append(state.Cards, "four")
+	update, _ = encoder.Update(state)
+	_, err = send(update)
+	assert.EqualError(t, err, "Missed a state update.")
+
+	encoder.Reset()
+	update, _ = encoder.Update(state)
+	result, err = send(update)
+	assert.NoError(t, err)
+	assert.Equal(t, decodeJSON(t, `{"title":"A long title that is not sent again",`+
+		`"cards":["one","two","three","four"]}`), result)
+}
+
+func TestPatchDecoder_PatchFirst(t *testing.T) {
+	_, err := (&jsonrpc.PatchDecoder{}).Apply(&jsonrpc.StateUpdate{
+		Seq:   2,
+		Patch: []jsonrpc.PatchOperation{{Op: "remove", Path: "/a"}},
+	})
+	assert.EqualError(t, err, "Patch received before the full state.")
+
+	_, err = (&jsonrpc.PatchDecoder{}).Apply("nope")
+	assert.EqualError(t, err, "Params are not a state update.")
+}
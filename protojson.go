@@ -0,0 +1,71 @@
+//go:build protojson
+
+package jsonrpc
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoJSONCodec is a Codec for protobuf messages that uses protojson, so that
+// the params and results of a method follow the JSON mapping of its .proto
+// contract. It is only built with the protojson tag, which needs
+// google.golang.org/protobuf:
+//
+//     go build -tags protojson
+//
+// The zero value uses the default options of protojson.
+type ProtoJSONCodec struct {
+	MarshalOptions   protojson.MarshalOptions
+	UnmarshalOptions protojson.UnmarshalOptions
+}
+
+// Marshal calls protojson.Marshal. v must be a proto.Message.
+func (codec ProtoJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, errors.New("Result is not a proto.Message.")
+	}
+
+	return codec.MarshalOptions.Marshal(message)
+}
+
+// Unmarshal calls protojson.Unmarshal. v must be a proto.Message.
+func (codec ProtoJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("Params are not a proto.Message.")
+	}
+
+	return codec.UnmarshalOptions.Unmarshal(data, message)
+}
+
+// ProtoHandler creates a handler for a method whose params and result are
+// protobuf messages, encoded with ProtoJSONCodec. It is registered with
+// SetStreamHandler so that the params are decoded from the request that was
+// received:
+//
+//     server.SetStreamHandler("users.get", jsonrpc.ProtoHandler(
+//         func(request jsonrpc.RequestResponder, params *pb.GetUserRequest) (*pb.User, error) {
+//             return store.User(params.GetId())
+//         }))
+//
+// Errors are handled as they are by CodecHandler.
+func ProtoHandler[P, R proto.Message](fn func(request RequestResponder, params P) (R, error)) StreamHandler {
+	newParams := func() interface{} {
+		var params P
+		return params.ProtoReflect().New().Interface()
+	}
+
+	return codecHandler(ProtoJSONCodec{}, newParams,
+		func(request RequestResponder, params interface{}) (interface{}, error) {
+			result, err := fn(request, params.(P))
+			if err != nil {
+				return nil, err
+			}
+
+			return result, nil
+		})
+}
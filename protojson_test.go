@@ -0,0 +1,64 @@
+//go:build protojson
+
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoHandler(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetStreamHandler("next", jsonrpc.ProtoHandler(
+		func(request jsonrpc.RequestResponder, params *wrapperspb.Int64Value) (*wrapperspb.Int64Value, error) {
+			if params.GetValue() < 0 {
+				return nil, &jsonrpc.RPCError{Code: jsonrpc.InvalidParams, Message: "Negative"}
+			}
+
+			return wrapperspb.Int64(params.GetValue() + 1), nil
+		}))
+	server.SetCodecHandler("fields", jsonrpc.ProtoJSONCodec{},
+		func() interface{} { return new(structpb.Struct) },
+		func(request jsonrpc.RequestResponder, params interface{}) (interface{}, error) {
+			return structpb.NewValue(len(params.(*structpb.Struct).GetFields()))
+		})
+
+	tests := map[string]struct {
+		request  string
+		expected string
+	}{
+		"int64": {
+			`{"jsonrpc":"2.0","method":"next","params":9007199254740993,"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":"9007199254740994"}]`,
+		},
+		"string int64": {
+			`{"jsonrpc":"2.0","method":"next","params":"9007199254740993","id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":"9007199254740994"}]`,
+		},
+		"no params": {
+			`{"jsonrpc":"2.0","method":"next","id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":"1"}]`,
+		},
+		"rpc error": {
+			`{"jsonrpc":"2.0","method":"next","params":-1,"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Negative"}}]`,
+		},
+		"struct": {
+			`{"jsonrpc":"2.0","method":"fields","params":{"a":1,"b":[true]},"id":1}`,
+			`[{"jsonrpc":"2.0","id":1,"result":2}]`,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.expected, server.Handle([]byte(test.request)).String())
+		})
+	}
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"next","params":{"value":1},"id":1}`))
+	assert.Equal(t, jsonrpc.InvalidParams, response[0].ErrorCode())
+}
@@ -0,0 +1,306 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// newProxy listens for connections to a proxy, which are passed to serve.
+func newProxy(t *testing.T, serve func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// tunnel copies between the client and a new connection to the address.
+func tunnel(client net.Conn, address string) {
+	server, err := net.Dial("tcp", address)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+
+	go io.Copy(server, client)
+	io.Copy(client, server)
+}
+
+// newHTTPProxy is a proxy that allows CONNECT with the authorization.
+func newHTTPProxy(t *testing.T, authorization string) string {
+	return newProxy(t, func(conn net.Conn) {
+		request, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+
+		if request.Method != http.MethodConnect ||
+			request.Header.Get("Proxy-Authorization") != authorization {
+			conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			return
+		}
+
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		tunnel(conn, request.Host)
+	})
+}
+
+// newSOCKS5Proxy is a proxy that requires the username and password, if they
+// are not empty.
+func newSOCKS5Proxy(t *testing.T, username, password string) string {
+	return newProxy(t, func(conn net.Conn) {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		methods := make([]byte, header[1])
+		io.ReadFull(conn, methods)
+
+		if username == "" {
+			conn.Write([]byte{5, 0})
+		} else if !bytesContain(methods, 2) {
+			conn.Write([]byte{5, 0xff})
+			return
+		} else {
+			conn.Write([]byte{5, 2})
+
+			io.ReadFull(conn, header)
+			user := make([]byte, header[1])
+			io.ReadFull(conn, user)
+			io.ReadFull(conn, header[:1])
+			pass := make([]byte, header[0])
+			io.ReadFull(conn, pass)
+
+			if string(user) != username || string(pass) != password {
+				conn.Write([]byte{1, 1})
+				return
+			}
+			conn.Write([]byte{1, 0})
+		}
+
+		request := make([]byte, 4)
+		io.ReadFull(conn, request)
+
+		var host string
+		switch request[3] {
+		case 1:
+			ip := make([]byte, 4)
+			io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+
+		case 3:
+			io.ReadFull(conn, header[:1])
+			name := make([]byte, header[0])
+			io.ReadFull(conn, name)
+			host = string(name)
+			if host == "localhost" {
+				host = "127.0.0.1"
+			}
+		}
+
+		port := make([]byte, 2)
+		io.ReadFull(conn, port)
+		address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+		if host == "unreachable.invalid" {
+			conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+
+		conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+		tunnel(conn, address)
+	})
+}
+
+func bytesContain(data []byte, b byte) bool {
+	for _, c := range data {
+		if c == b {
+			return true
+		}
+	}
+
+	return false
+}
+
+func callThrough(t *testing.T, dialer jsonrpc.Dialer, address string) error {
+	client, err := jsonrpc.Dial(context.Background(), dialer, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var difference int
+	if err := jsonrpc.CallWith(context.Background(), client, "subtract", []int{42, 23},
+		&difference); err != nil {
+		return err
+	}
+	assert.Equal(t, 19, difference)
+
+	return nil
+}
+
+func TestProxyDialer_HTTP(t *testing.T) {
+	_, address := newTCPServer(t, newTestServer())
+	proxy := newHTTPProxy(t, "Basic dXNlcjpzZWNyZXQ=")
+
+	assert.NoError(t, callThrough(t, jsonrpc.NewProxyDialer(
+		&url.URL{Scheme: "http", User: url.UserPassword("user", "secret"), Host: proxy}, nil),
+		address))
+
+	err := callThrough(t, jsonrpc.NewProxyDialer(
+		&url.URL{Scheme: "http", Host: proxy}, nil), address)
+	assert.EqualError(t, err, "Proxy refused the connection: 407 Proxy Authentication Required.")
+}
+
+func TestProxyDialer_SOCKS5(t *testing.T) {
+	_, address := newTCPServer(t, newTestServer())
+	_, port, _ := net.SplitHostPort(address)
+	proxy := newSOCKS5Proxy(t, "user", "secret")
+
+	// The proxy resolves "localhost" with socks5h.
+	for _, scheme := range []string{"socks5", "socks5h"} {
+		assert.NoError(t, callThrough(t, jsonrpc.NewProxyDialer(
+			&url.URL{Scheme: scheme, User: url.UserPassword("user", "secret"), Host: proxy}, nil),
+			"localhost:"+port), scheme)
+	}
+
+	err := callThrough(t, jsonrpc.NewProxyDialer(
+		&url.URL{Scheme: "socks5", User: url.UserPassword("user", "wrong"), Host: proxy}, nil),
+		address)
+	assert.EqualError(t, err, "SOCKS5 proxy rejected the username or password.")
+
+	err = callThrough(t, jsonrpc.NewProxyDialer(
+		&url.URL{Scheme: "socks5", Host: proxy}, nil), address)
+	assert.EqualError(t, err, "SOCKS5 proxy requires an unsupported authentication method.")
+
+	err = callThrough(t, jsonrpc.NewProxyDialer(
+		&url.URL{Scheme: "socks5h", User: url.UserPassword("user", "secret"), Host: proxy}, nil),
+		"unreachable.invalid:4000")
+	assert.EqualError(t, err, "SOCKS5 proxy could not connect: host unreachable.")
+
+	err = callThrough(t, jsonrpc.NewProxyDialer(&url.URL{Scheme: "ftp", Host: proxy}, nil),
+		address)
+	assert.EqualError(t, err, "Proxy scheme ftp is not supported.")
+}
+
+func TestProxyDialer_Environment(t *testing.T) {
+	_, address := newTCPServer(t, newTestServer())
+	proxy := newSOCKS5Proxy(t, "", "")
+
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("ALL_PROXY", "socks5://"+proxy)
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	assert.NoError(t, callThrough(t, jsonrpc.NewEnvironmentProxyDialer(nil), address))
+
+	// A proxy that does not exist is not used for addresses in NO_PROXY.
+	t.Setenv("ALL_PROXY", "socks5://127.0.0.1:1")
+	assert.Error(t, callThrough(t, jsonrpc.NewEnvironmentProxyDialer(nil), address))
+
+	t.Setenv("NO_PROXY", "example.com, 127.0.0.0/8")
+	assert.NoError(t, callThrough(t, jsonrpc.NewEnvironmentProxyDialer(nil), address))
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "proxy.corp:3128")
+	t.Setenv("ALL_PROXY", "socks5://proxy.corp")
+	t.Setenv("NO_PROXY", "internal.corp, .example.com, 10.0.0.0/8, db.corp:5432, 192.168.1.1")
+
+	for address, expected := range map[string]string{
+		"rpc.example.org:4000":  "http://proxy.corp:3128",
+		"rpc.internal.corp:443": "",
+		"internal.corp:443":     "",
+		"example.com:80":        "",
+		"a.example.com:80":      "",
+		"10.1.2.3:4000":         "",
+		"db.corp:5432":          "",
+		"db.corp:5433":          "http://proxy.corp:3128",
+		"192.168.1.1:80":        "",
+		"192.168.1.2:80":        "http://proxy.corp:3128",
+	} {
+		proxy, err := jsonrpc.ProxyFromEnvironment(address)
+		assert.NoError(t, err, address)
+		if expected == "" {
+			assert.Nil(t, proxy, address)
+		} else if assert.NotNil(t, proxy, address) {
+			assert.Equal(t, expected, proxy.String(), address)
+		}
+	}
+
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	proxy, err := jsonrpc.ProxyFromEnvironment("rpc.example.org:4000")
+	assert.NoError(t, err)
+	assert.Equal(t, "socks5://proxy.corp", proxy.String())
+
+	t.Setenv("NO_PROXY", "*")
+	proxy, err = jsonrpc.ProxyFromEnvironment("rpc.example.org:4000")
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+}
+
+func TestProxyURLFromEnvironment(t *testing.T) {
+	t.Setenv("http_proxy", "http.corp:3128")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "https.corp:3128")
+	t.Setenv("ALL_PROXY", "")
+	t.Setenv("all_proxy", "")
+	t.Setenv("NO_PROXY", "internal.corp")
+	t.Setenv("REQUEST_METHOD", "")
+
+	for rawURL, expected := range map[string]string{
+		"ws://rpc.example.org/ws":        "http://http.corp:3128",
+		"http://rpc.example.org":         "http://http.corp:3128",
+		"wss://rpc.example.org/ws":       "http://https.corp:3128",
+		"https://rpc.example.org:8443":   "http://https.corp:3128",
+		"wss://rpc.internal.corp:443/ws": "",
+	} {
+		target, _ := url.Parse(rawURL)
+		proxy, err := jsonrpc.ProxyURLFromEnvironment(target)
+		assert.NoError(t, err, rawURL)
+		if expected == "" {
+			assert.Nil(t, proxy, rawURL)
+		} else if assert.NotNil(t, proxy, rawURL) {
+			assert.Equal(t, expected, proxy.String(), rawURL)
+		}
+	}
+
+	// HTTP_PROXY may be the Proxy header of a CGI request.
+	t.Setenv("REQUEST_METHOD", "GET")
+	t.Setenv("ALL_PROXY", "socks5://proxy.corp")
+	target, _ := url.Parse("ws://rpc.example.org/ws")
+	proxy, err := jsonrpc.ProxyURLFromEnvironment(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "socks5://proxy.corp", proxy.String())
+
+	target, _ = url.Parse("ftp://rpc.example.org")
+	_, err = jsonrpc.ProxyURLFromEnvironment(target)
+	assert.EqualError(t, err, "URL scheme ftp cannot be used with a proxy.")
+}
@@ -0,0 +1,171 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// QuickstartOptions configure Quickstart. The zero value of every option is a
+// sensible default for a production service.
+type QuickstartOptions struct {
+	// Addr is the address to listen on. The default is ":8080".
+	Addr string
+
+	// Logger receives the logs of the server, sampled so that a misbehaving
+	// client cannot flood them. The default is slog.Default().
+	Logger *slog.Logger
+
+	// Timeout is the longest a handler may run before it is answered with a
+	// timeout error. The default is 30 seconds.
+	Timeout time.Duration
+
+	// MaxBodySize is the largest request body in bytes. The default is
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// TrustedProxies are passed to NewClientExtractor.
+	TrustedProxies []string
+}
+
+// QuickstartServer is a SimpleServer with everything a service usually needs
+// wired up. See Quickstart.
+type QuickstartServer struct {
+	// Server is the underlying server. Handlers registered directly on it do
+	// not have the Timeout applied, use SetHandler instead.
+	Server *SimpleServer
+
+	// Handlers has the Timeout middleware. More middleware can be added with
+	// Use.
+	Handlers *HandlerGroup
+
+	// HTTP serves the endpoints, and may be adjusted before ListenAndServe is
+	// called.
+	HTTP *http.Server
+
+	// Mux routes the endpoints. Other endpoints may be added.
+	Mux *http.ServeMux
+
+	// WebSockets serves /ws. It can be used to Broadcast notifications.
+	WebSockets *WebSocketHandler
+
+	shuttingDown int32
+}
+
+// Quickstart returns a server that is ready for production with one call:
+//
+//     quickstart, err := jsonrpc.Quickstart(jsonrpc.QuickstartOptions{})
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     quickstart.SetHandler("sayHello", sayHello)
+//     log.Fatal(quickstart.ListenAndServe())
+//
+// It serves:
+//
+//   - POST / for requests and batches over HTTP (see HTTPServer).
+//   - POST /stream for a stream of newline delimited requests (see
+//     NDJSONHandler) for clients that need a persistent connection.
+//   - GET /ws for JSON-RPC over a WebSocket (see WebSocketHandler), which
+//     also lets the server send notifications.
+//   - GET /healthz which responds "ok" until Shutdown is called.
+//   - GET /metrics with the statistics of the server as JSON.
+//
+// Invalid requests and panics are logged, every handler has a timeout, the
+// size of bodies and headers is limited, slow clients are disconnected and the
+// identity of each client is available with ClientInfoFromRequest.
+func Quickstart(options QuickstartOptions) (*QuickstartServer, error) {
+	if options.Addr == "" {
+		options.Addr = ":8080"
+	}
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.Timeout == 0 {
+		options.Timeout = 30 * time.Second
+	}
+
+	extractor, err := NewClientExtractor(options.TrustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+
+	server := NewSimpleServer()
+	server.SetLogger(slog.New(NewSampledHandler(options.Logger.Handler(), 100, time.Minute)))
+
+	quickstart := &QuickstartServer{
+		Server:   server,
+		Handlers: server.Group(Timeout(options.Timeout)),
+		Mux:      http.NewServeMux(),
+		WebSockets: &WebSocketHandler{
+			Server:          server,
+			ClientExtractor: extractor,
+		},
+	}
+
+	quickstart.Mux.Handle("/", &HTTPServer{
+		Server:          server,
+		MaxBodySize:     options.MaxBodySize,
+		ClientExtractor: extractor,
+	})
+	quickstart.Mux.Handle("/stream", NDJSONHandler(server))
+	quickstart.Mux.Handle("/ws", quickstart.WebSockets)
+	quickstart.Mux.Handle("/metrics", StatsHandler(server))
+	quickstart.Mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&quickstart.shuttingDown) != 0 {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		io.WriteString(w, "ok")
+	})
+
+	quickstart.HTTP = &http.Server{
+		Addr:              options.Addr,
+		Handler:           quickstart.Mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    64 << 10,
+		ErrorLog:          slog.NewLogLogger(options.Logger.Handler(), slog.LevelWarn),
+	}
+
+	return quickstart, nil
+}
+
+// SetHandler registers a handler with the Timeout applied.
+func (quickstart *QuickstartServer) SetHandler(methodName string, handler RequestHandler) {
+	quickstart.Handlers.SetHandler(methodName, handler)
+}
+
+// ListenAndServe serves until Shutdown is called, when it returns nil.
+func (quickstart *QuickstartServer) ListenAndServe() error {
+	err := quickstart.HTTP.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown fails the health check, stops accepting connections and requests
+// (including on the WebSockets) and waits for the requests being handled (and
+// the goroutines they started with Go) to finish and their responses to be
+// sent, or for ctx to be done.
+func (quickstart *QuickstartServer) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&quickstart.shuttingDown, 1)
+
+	if err := quickstart.HTTP.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if err := quickstart.WebSockets.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return quickstart.Server.Shutdown(ctx)
+}
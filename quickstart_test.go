@@ -0,0 +1,95 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func post(t *testing.T, url, body string) (int, string) {
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	b, _ := io.ReadAll(response.Body)
+	return response.StatusCode, string(b)
+}
+
+func TestQuickstart(t *testing.T) {
+	var logs bytes.Buffer
+	quickstart, err := jsonrpc.Quickstart(jsonrpc.QuickstartOptions{
+		Logger:      slog.New(newTestLogger(&logs)),
+		Timeout:     10 * time.Millisecond,
+		MaxBodySize: 200,
+	})
+	assert.NoError(t, err)
+
+	quickstart.SetHandler("sum", sum)
+	quickstart.SetHandler("notify_hello", notifyHello)
+	quickstart.SetHandler("slow", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		time.Sleep(time.Second)
+		return request.NewSuccessResponse(nil)
+	})
+
+	httpServer := httptest.NewServer(quickstart.Mux)
+	defer httpServer.Close()
+
+	status, body := post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":3}`, body)
+
+	status, body = post(t, httpServer.URL, ` [{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1}]`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `[{"jsonrpc":"2.0","id":1,"result":3}]`, body)
+
+	status, _ = post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"notify_hello","params":[1]}`)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, body = post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"slow","id":1}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"message":"Time limit exceeded"`)
+
+	status, body = post(t, httpServer.URL, `{"jsonrpc":"2.0","method":"sum","params":[`+
+		strings.Repeat("1,", 200)+`1],"id":1}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Request is too large."}}`, body)
+
+	post(t, httpServer.URL, `{"jsonrpc"`)
+	assert.Contains(t, logs.String(), `msg="Invalid request"`)
+
+	response, err := http.Get(httpServer.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+
+	response, err = http.Get(httpServer.URL + "/metrics")
+	assert.NoError(t, err)
+	var metrics map[string]interface{}
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&metrics))
+	assert.Equal(t, 4.0, metrics["totalRequests"])
+
+	response, err = http.Get(httpServer.URL + "/healthz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	assert.NoError(t, quickstart.Shutdown(context.Background()))
+	recorder := httptest.NewRecorder()
+	quickstart.Mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestQuickstart_InvalidProxy(t *testing.T) {
+	_, err := jsonrpc.Quickstart(jsonrpc.QuickstartOptions{TrustedProxies: []string{"x"}})
+	assert.EqualError(t, err, "Invalid trusted proxy x.")
+}
@@ -0,0 +1,158 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+)
+
+// ReceiptAlgorithm identifies how a Receipt is calculated.
+const ReceiptAlgorithm = "sha256-merkle"
+
+// ReceiptHeader is the HTTP header in which an HTTPServer with Receipts sends
+// the root of the Receipt of each exchange, as ReceiptAlgorithm, a space and
+// the root.
+const ReceiptHeader = "JSONRPC-Receipt"
+
+// Receipt is a hash over everything that was exchanged in a payload, so that
+// both the client and server can prove what was sent and received.
+//
+// Each request (in the order they were sent) and then each response (in the
+// order they were received) is canonicalized and hashed to create the leaves
+// of a Merkle tree. The leaves and nodes are hashed with different prefixes, as
+// described in RFC 6962, and an odd node at any level is promoted without
+// hashing.
+//
+// The canonical form of a request is its JSON with sorted object keys and no
+// insignificant whitespace. Responses use CanonicalResponseBytes, so a
+// signature does not change the receipt.
+type Receipt struct {
+	Algorithm string   `json:"algorithm"`
+	Root      string   `json:"root"`
+	Leaves    []string `json:"leaves"`
+}
+
+// NewReceipt calculates the receipt for the raw requests and their responses.
+// A single request is a batch of one.
+func NewReceipt(requests [][]byte, responses Responses) (*Receipt, error) {
+	var leaves [][]byte
+
+	for _, request := range requests {
+		canonical, err := canonicalJSON(request)
+		if err != nil {
+			return nil, err
+		}
+
+		leaves = append(leaves, receiptLeaf(canonical))
+	}
+
+	for _, response := range responses {
+		canonical, err := CanonicalResponseBytes(response)
+		if err != nil {
+			return nil, err
+		}
+
+		leaves = append(leaves, receiptLeaf(canonical))
+	}
+
+	receipt := &Receipt{
+		Algorithm: ReceiptAlgorithm,
+		Leaves:    make([]string, len(leaves)),
+	}
+	for i, leaf := range leaves {
+		receipt.Leaves[i] = hex.EncodeToString(leaf)
+	}
+	receipt.Root = hex.EncodeToString(merkleRoot(leaves))
+
+	return receipt, nil
+}
+
+// NewReceiptFromJSON calculates the receipt for a raw payload (a single
+// request or a batch) and the responses that were sent for it.
+func NewReceiptFromJSON(payload []byte, responses Responses) (*Receipt, error) {
+	return NewReceipt(splitPayload(payload), responses)
+}
+
+// HandleWithReceipt handles the payload like HandleWithState and also returns
+// the receipt for the exchange. The receipt is not part of the JSON-RPC
+// response, the transport is responsible for sending it to the client, as an
+// HTTPServer with Receipts does in the ReceiptHeader.
+func (server *SimpleServer) HandleWithReceipt(jsonRequest []byte,
+	state State) (Responses, *Receipt, error) {
+	responses := server.HandleWithState(jsonRequest, state)
+	receipt, err := NewReceiptFromJSON(jsonRequest, responses)
+
+	return responses, receipt, err
+}
+
+// splitPayload returns the individual requests of a batch, or the payload
+// itself if it is not a batch.
+func splitPayload(payload []byte) [][]byte {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(payload, &batch); err != nil || len(batch) == 0 {
+		return [][]byte{payload}
+	}
+
+	requests := make([][]byte, len(batch))
+	for i := range batch {
+		requests[i] = batch[i]
+	}
+
+	return requests
+}
+
+// receiptHeaderValue returns the value of the ReceiptHeader for the receipt.
+func receiptHeaderValue(receipt *Receipt) string {
+	return receipt.Algorithm + " " + receipt.Root
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and without whitespace.
+// Numbers are kept exactly as they were written. Invalid JSON, including a
+// value followed by anything but whitespace, is used as is, since it must
+// still be part of the receipt.
+func canonicalJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return data, nil
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return data, nil
+	}
+
+	return json.Marshal(value)
+}
+
+func receiptLeaf(data []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0}, data...))
+	return hash[:]
+}
+
+func merkleRoot(nodes [][]byte) []byte {
+	if len(nodes) == 0 {
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	}
+
+	for len(nodes) > 1 {
+		var level [][]byte
+		for i := 0; i < len(nodes); i += 2 {
+			if i+1 == len(nodes) {
+				level = append(level, nodes[i])
+				continue
+			}
+
+			data := append([]byte{1}, nodes[i]...)
+			hash := sha256.Sum256(append(data, nodes[i+1]...))
+			level = append(level, hash[:])
+		}
+
+		nodes = level
+	}
+
+	return nodes[0]
+}
@@ -0,0 +1,112 @@
+package jsonrpc_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestNewReceipt(t *testing.T) {
+	payload := []byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1},
+		{"jsonrpc": "2.0", "method": "notify_hello"},
+		{"jsonrpc": "2.0", "method": "subtract", "params": [5, 2], "id": 2}
+	]`)
+
+	server := newTestServer()
+	responses, receipt, err := server.HandleWithReceipt(payload, jsonrpc.State{})
+	assert.NoError(t, err)
+
+	t.Run("Leaves", func(t *testing.T) {
+		assert.Equal(t, jsonrpc.ReceiptAlgorithm, receipt.Algorithm)
+		assert.Len(t, receipt.Leaves, 5)
+		assert.Len(t, receipt.Root, 64)
+	})
+
+	t.Run("ClientAgrees", func(t *testing.T) {
+		// The client has the requests it sent, formatted differently, and
+		// the responses that it decoded.
+		requests := [][]byte{
+			[]byte(`{"id":1,"jsonrpc":"2.0","method":"sum","params":[1,2]}`),
+			[]byte(`{"method":"notify_hello","jsonrpc":"2.0"}`),
+			[]byte(`{"params":[5,2],"method":"subtract","id":2,"jsonrpc":"2.0"}`),
+		}
+		decoded, err := jsonrpc.NewResponsesFromJSON(responses.Bytes())
+		assert.NoError(t, err)
+
+		clientReceipt, err := jsonrpc.NewReceipt(requests, decoded)
+
+		assert.NoError(t, err)
+		assert.Equal(t, receipt, clientReceipt)
+	})
+
+	t.Run("DifferentResponse", func(t *testing.T) {
+		other, err := jsonrpc.NewReceiptFromJSON(payload, jsonrpc.Responses{
+			jsonrpc.NewSuccessResponse(1, 3.0),
+			jsonrpc.NewSuccessResponse(2, 4.0),
+		})
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, receipt.Root, other.Root)
+	})
+
+	t.Run("SingleRequest", func(t *testing.T) {
+		single := []byte(`{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1}`)
+		_, receipt, err := server.HandleWithReceipt(single, jsonrpc.State{})
+
+		assert.NoError(t, err)
+		assert.Len(t, receipt.Leaves, 2)
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		_, receipt, err := server.HandleWithReceipt([]byte(`{`), jsonrpc.State{})
+
+		// The request and the parse error.
+		assert.NoError(t, err)
+		assert.Len(t, receipt.Leaves, 2)
+	})
+}
+
+func TestHTTPServer_Receipts(t *testing.T) {
+	payload := `[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1},
+		{"jsonrpc": "2.0", "method": "notify_hello"}
+	]`
+
+	server := httptest.NewServer(&jsonrpc.HTTPServer{Server: newTestServer(), Receipts: true})
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "application/json", strings.NewReader(payload))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	responses, err := jsonrpc.NewResponsesFromJSON(body)
+	assert.NoError(t, err)
+
+	receipt, err := jsonrpc.NewReceiptFromJSON([]byte(payload), responses)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.ReceiptAlgorithm+" "+receipt.Root,
+		response.Header.Get(jsonrpc.ReceiptHeader))
+}
+
+func TestNewReceipt_TrailingData(t *testing.T) {
+	receipt, err := jsonrpc.NewReceipt([][]byte{[]byte(`{"b":1, "a":2}`)}, nil)
+	assert.NoError(t, err)
+
+	// Anything after the request is part of its leaf.
+	trailing, err := jsonrpc.NewReceipt([][]byte{[]byte(`{"b":1, "a":2} {"c":3}`)}, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, receipt.Root, trailing.Root)
+
+	spaced, err := jsonrpc.NewReceipt([][]byte{[]byte(" {\"a\":2,\"b\":1}\n")}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.Root, spaced.Root)
+}
@@ -0,0 +1,81 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redacted replaces values that have been removed by a Redactor.
+const Redacted = "[REDACTED]"
+
+// Redactor removes sensitive information from a decoded JSON value (made up of
+// maps, slices and scalars) before it is recorded or logged. Redact must not
+// modify value, it should return a copy with the changes.
+type Redactor interface {
+	Redact(value interface{}) interface{}
+}
+
+// RedactorFunc allows an ordinary function to be used as a Redactor.
+type RedactorFunc func(value interface{}) interface{}
+
+// Redact calls f(value)
+func (f RedactorFunc) Redact(value interface{}) interface{} {
+	return f(value)
+}
+
+// RedactKeys returns a Redactor that replaces the value of any object member
+// named one of keys (case-insensitive), at any depth, with Redacted.
+func RedactKeys(keys ...string) Redactor {
+	names := map[string]bool{}
+	for _, key := range keys {
+		names[strings.ToLower(key)] = true
+	}
+
+	var redact func(value interface{}) interface{}
+	redact = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			copied := make(map[string]interface{}, len(v))
+			for key, member := range v {
+				if names[strings.ToLower(key)] {
+					copied[key] = Redacted
+				} else {
+					copied[key] = redact(member)
+				}
+			}
+
+			return copied
+
+		case []interface{}:
+			copied := make([]interface{}, len(v))
+			for i := range v {
+				copied[i] = redact(v[i])
+			}
+
+			return copied
+		}
+
+		return value
+	}
+
+	return RedactorFunc(redact)
+}
+
+// redactJSON decodes and redacts JSON. Invalid JSON is returned as a string
+// since it cannot be safely redacted.
+func redactJSON(redactor Redactor, data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		if redactor != nil {
+			return Redacted
+		}
+
+		return string(data)
+	}
+
+	if redactor == nil {
+		return value
+	}
+
+	return redactor.Redact(value)
+}
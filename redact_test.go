@@ -0,0 +1,30 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRedactKeys(t *testing.T) {
+	redactor := jsonrpc.RedactKeys("password", "Token")
+	value := map[string]interface{}{
+		"user":     "bob",
+		"Password": "hunter2",
+		"nested": []interface{}{
+			map[string]interface{}{"token": "abc", "id": 1.0},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"user":     "bob",
+		"Password": jsonrpc.Redacted,
+		"nested": []interface{}{
+			map[string]interface{}{"token": jsonrpc.Redacted, "id": 1.0},
+		},
+	}, redactor.Redact(value))
+
+	// The original is not modified.
+	assert.Equal(t, "hunter2", value["Password"])
+}
@@ -0,0 +1,82 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ReplicaRouter is an Invoker that sends read-only methods to replicas and
+// every other method to the primary. The replicas are used in turn. If a
+// replica cannot be reached the request is sent to the primary instead, so a
+// failed replica only costs time rather than failing the call.
+//
+//     router := jsonrpc.NewReplicaRouter(primary, replica1, replica2)
+//     router.SetReadOnly("user.get", true)
+//
+//     // Or use the methods published by the server:
+//     router.SetMethodInfo(methods...)
+//
+// Only errors returned by the replica Invoker (such as a refused connection)
+// cause a fallback. An error response from a replica is a valid answer and is
+// returned as is.
+//
+// It is safe for concurrent use.
+type ReplicaRouter struct {
+	primary  Invoker
+	replicas []Invoker
+	next     uint64
+
+	mutex    sync.RWMutex
+	readOnly map[string]bool
+}
+
+// NewReplicaRouter creates a ReplicaRouter. With no replicas every method is
+// sent to the primary.
+func NewReplicaRouter(primary Invoker, replicas ...Invoker) *ReplicaRouter {
+	return &ReplicaRouter{
+		primary:  primary,
+		replicas: replicas,
+		readOnly: map[string]bool{},
+	}
+}
+
+// SetReadOnly marks a method as safe to send to a replica.
+func (router *ReplicaRouter) SetReadOnly(method string, readOnly bool) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if readOnly {
+		router.readOnly[method] = true
+	} else {
+		delete(router.readOnly, method)
+	}
+}
+
+// SetMethodInfo marks each method as read-only if its MethodInfo says it is.
+func (router *ReplicaRouter) SetMethodInfo(methods ...MethodInfo) {
+	for _, info := range methods {
+		router.SetReadOnly(info.Name, info.ReadOnly)
+	}
+}
+
+// Invoke sends the request to a replica if the method is read-only, otherwise
+// to the primary.
+func (router *ReplicaRouter) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	router.mutex.RLock()
+	readOnly := router.readOnly[method]
+	router.mutex.RUnlock()
+
+	if readOnly && len(router.replicas) > 0 {
+		n := atomic.AddUint64(&router.next, 1)
+		replica := router.replicas[(n-1)%uint64(len(router.replicas))]
+
+		response, err := replica.Invoke(ctx, method, params)
+		if err == nil || ctx.Err() != nil {
+			return response, err
+		}
+	}
+
+	return router.primary.Invoke(ctx, method, params)
+}
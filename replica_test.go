@@ -0,0 +1,83 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// namedInvoker responds with its name, or fails if it is down.
+func namedInvoker(name string, down *bool) jsonrpc.Invoker {
+	return jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		if down != nil && *down {
+			return nil, errors.New(name + " is down")
+		}
+
+		return jsonrpc.NewSuccessResponse(1, name), nil
+	})
+}
+
+func routeTo(invoker jsonrpc.Invoker, method string) interface{} {
+	response, err := invoker.Invoke(context.Background(), method, nil)
+	if err != nil {
+		return err.Error()
+	}
+
+	return response.Result()
+}
+
+func TestReplicaRouter(t *testing.T) {
+	replica2Down := false
+	router := jsonrpc.NewReplicaRouter(namedInvoker("primary", nil),
+		namedInvoker("replica1", nil), namedInvoker("replica2", &replica2Down))
+	router.SetMethodInfo(
+		jsonrpc.MethodInfo{Name: "user.get", ReadOnly: true},
+		jsonrpc.MethodInfo{Name: "user.update"},
+	)
+
+	assert.Equal(t, "primary", routeTo(router, "user.update"))
+	assert.Equal(t, "primary", routeTo(router, "unknown"))
+
+	assert.Equal(t, "replica1", routeTo(router, "user.get"))
+	assert.Equal(t, "replica2", routeTo(router, "user.get"))
+	assert.Equal(t, "replica1", routeTo(router, "user.get"))
+
+	replica2Down = true
+	assert.Equal(t, "primary", routeTo(router, "user.get"))
+
+	router.SetReadOnly("user.get", false)
+	assert.Equal(t, "primary", routeTo(router, "user.get"))
+}
+
+func TestReplicaRouter_NoReplicas(t *testing.T) {
+	router := jsonrpc.NewReplicaRouter(namedInvoker("primary", nil))
+	router.SetReadOnly("user.get", true)
+
+	assert.Equal(t, "primary", routeTo(router, "user.get"))
+}
+
+func TestReplicaRouter_Cancelled(t *testing.T) {
+	primaryCalled := false
+	router := jsonrpc.NewReplicaRouter(
+		jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+			params interface{}) (jsonrpc.Response, error) {
+			primaryCalled = true
+			return nil, nil
+		}),
+		jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+			params interface{}) (jsonrpc.Response, error) {
+			return nil, ctx.Err()
+		}))
+	router.SetReadOnly("user.get", true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := router.Invoke(ctx, "user.get", nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, primaryCalled)
+}
@@ -1,7 +1,10 @@
 package jsonrpc
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -18,6 +21,15 @@ type Request interface {
 	ID() interface{}
 	State(key string) interface{}
 
+	// Hash returns a stable hash of the method and params, but not the id, so
+	// that repeats of the same call can be recognised. See RequestHash.
+	Hash() string
+
+	// IsNotification is true for a request without an id member. The server
+	// does not respond to notifications, even if they fail. A request with an
+	// id of null is not a notification and receives a response with a null id.
+	IsNotification() bool
+
 	// Serialization
 	fmt.Stringer
 	Bytes() []byte
@@ -31,6 +43,7 @@ type State map[string]interface{}
 type Responder interface {
 	NewSuccessResponse(result interface{}) Response
 	NewErrorResponse(code int, message string) Response
+	NewErrorResponseWithData(code int, message string, data interface{}) Response
 	NewServerErrorResponse(err error) Response
 }
 
@@ -47,6 +60,16 @@ type request struct {
 	RequestParams  interface{} `json:"params,omitempty"`
 	RequestID      interface{} `json:"id"`
 	requestState   State
+
+	// notification is true if there is no id member, see IsNotification.
+	notification bool
+
+	// The arena the request was decoded into, if any. Responses are allocated
+	// from the same arena.
+	arena *arena
+
+	// See Context and WithContext
+	ctx context.Context
 }
 
 // Version get the version
@@ -71,22 +94,65 @@ func (request *request) ID() interface{} {
 
 // State get state from key
 func (request *request) State(key string) interface{} {
+	if key == contextStateKey && request.ctx != nil {
+		return request.ctx
+	}
+
 	return request.requestState[key]
 }
 
+// Context returns the context of the request, see ContextFromRequest.
+func (request *request) Context() context.Context {
+	if request.ctx != nil {
+		return request.ctx
+	}
+
+	if ctx, ok := request.requestState[contextStateKey].(context.Context); ok {
+		return ctx
+	}
+
+	return context.Background()
+}
+
+// WithContext returns a copy of the request with a different context.
+func (request *request) WithContext(ctx context.Context) RequestResponder {
+	copied := *request
+	copied.ctx = ctx
+
+	return &copied
+}
+
+// IsNotification is true if the request has no id
+func (request *request) IsNotification() bool {
+	return request.notification
+}
+
+// Hash returns RequestHash of the method and params, or an empty string if
+// the params cannot be encoded.
+func (request *request) Hash() string {
+	hash, _ := RequestHash(request.RequestMethod, request.RequestParams)
+	return hash
+}
+
 // NewSuccessResponse new success response
 func (request *request) NewSuccessResponse(result interface{}) Response {
-	return NewSuccessResponse(request.ID(), result)
+	return request.arena.newSuccessResponse(request.ID(), result)
 }
 
 // NewErrorResponse new error response
 func (request *request) NewErrorResponse(code int, message string) Response {
-	return NewErrorResponse(request.ID(), code, message)
+	return request.arena.newErrorResponse(request.ID(), code, message, nil)
+}
+
+// NewErrorResponseWithData new error response with data
+func (request *request) NewErrorResponseWithData(code int, message string,
+	data interface{}) Response {
+	return request.arena.newErrorResponse(request.ID(), code, message, data)
 }
 
 // NewServerErrorResponse new server error response
 func (request *request) NewServerErrorResponse(err error) Response {
-	return NewServerErrorResponse(request.ID(), err)
+	return request.arena.newErrorResponse(request.ID(), ServerError, err.Error(), nil)
 }
 
 // String to string request
@@ -94,7 +160,8 @@ func (request *request) String() string {
 	return string(request.Bytes())
 }
 
-// NewRequestResponderWithState new request reponser with state
+// NewRequestResponderWithState new request reponser with state. A nil id
+// creates a notification.
 func NewRequestResponderWithState(version string, id interface{}, method string,
 	params interface{}, state State) RequestResponder {
 	return &request{
@@ -103,6 +170,7 @@ func NewRequestResponderWithState(version string, id interface{}, method string,
 		RequestMethod:  method,
 		RequestParams:  params,
 		requestState:   state,
+		notification:   id == nil,
 	}
 }
 
@@ -112,12 +180,148 @@ func NewRequestResponder(version string, id interface{}, method string,
 	return NewRequestResponderWithState(version, id, method, params, State{})
 }
 
+// A request that overrides a single state key of the request it wraps.
+type stateRequest struct {
+	RequestResponder
+	key   string
+	value interface{}
+}
+
+// State get state from key
+func (request *stateRequest) State(key string) interface{} {
+	if key == request.key {
+		return request.value
+	}
+
+	return request.RequestResponder.State(key)
+}
+
+// WithState returns a copy of the request that will return value for key. The
+// original request and its State are not modified so this is safe to use on
+// requests that share the same State, such as the members of a batch.
+func WithState(request RequestResponder, key string, value interface{}) RequestResponder {
+	return &stateRequest{
+		RequestResponder: request,
+		key:              key,
+		value:            value,
+	}
+}
+
+// RequestHash returns a hex encoded SHA-256 hash of a method and its params.
+// The params are encoded as canonical JSON, with sorted keys and without
+// whitespace, so the hash does not depend on how the request was written:
+//
+//     {"jsonrpc":"2.0","method":"sum","params":{"a":1,"b":2},"id":1}
+//     {"jsonrpc": "2.0", "id": 7, "method": "sum", "params": {"b": 2, "a": 1}}
+//
+// have the same hash. Omitted params are the same as null. The hash is used
+// to cache results and to recognise repeated calls, and is the same on the
+// client (from the method and params passed to an Invoker) and the server
+// (from Request.Hash).
+func RequestHash(method string, params interface{}) (string, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	canonical, err := canonicalJSON(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write(canonical)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // GenerateRequestID generate a request id
 func GenerateRequestID() string {
 	hash := md5.Sum([]byte(strconv.Itoa(rand.Int())))
 	return hex.EncodeToString(hash[:])
 }
 
+// requestFields are the members of a request, without its methods.
+type requestFields request
+
+// MarshalJSON leaves out the id member of a notification.
+func (request *request) MarshalJSON() ([]byte, error) {
+	if !request.notification {
+		return json.Marshal((*requestFields)(request))
+	}
+
+	return json.Marshal(struct {
+		*requestFields
+		RequestID interface{} `json:"id,omitempty"`
+	}{requestFields: (*requestFields)(request)})
+}
+
+// UnmarshalJSON decodes a request, which is a notification if it does not
+// have an id member.
+func (request *request) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*requestFields)(request)); err != nil {
+		return err
+	}
+
+	// Only a null id could be missing.
+	request.notification = request.RequestID == nil && !hasMember(data, "id")
+
+	return nil
+}
+
+// hasMember returns true if the JSON object in data has a member called name,
+// ignoring case like encoding/json. data must be valid JSON.
+func hasMember(data []byte, name string) bool {
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '{', '[':
+			depth++
+
+		case '}', ']':
+			depth--
+
+		case '"':
+			start := i
+			for i++; data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+
+			// A string is a member name if it is followed by a colon.
+			next := i + 1
+			for next < len(data) && isSpace(data[next]) {
+				next++
+			}
+			if depth != 1 || next == len(data) || data[next] != ':' {
+				continue
+			}
+
+			member := data[start+1 : i]
+			if bytes.IndexByte(member, '\\') >= 0 {
+				var unquoted string
+				if json.Unmarshal(data[start:i+1], &unquoted) != nil {
+					continue
+				}
+				member = []byte(unquoted)
+			}
+
+			if bytes.EqualFold(member, []byte(name)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
 // The bytes representation of a request will be the JSON encoded value. This
 // JSON is expected to be a perfectly valid JSON-RPC request.
 func (request *request) Bytes() []byte {
@@ -130,8 +334,28 @@ func (request *request) Bytes() []byte {
 
 func newRequestResponderFromJSON(jsonRequest []byte, isPartOfBatch bool,
 	state State) (RequestResponder, interface{}, int, string) {
+	return decodeRequest(jsonRequest, isPartOfBatch, state, nil, nil)
+}
+
+// decodeRequest is newRequestResponderFromJSON that allocates the request from
+// the arena. The arena may be nil. If rawParams is not nil, the params of the
+// methods it returns true for are left as a json.RawMessage (see
+// SetStreamHandler). It has no effect with an arena.
+func decodeRequest(jsonRequest []byte, isPartOfBatch bool, state State,
+	a *arena, rawParams func(method string) bool) (RequestResponder, interface{}, int, string) {
 	var requestMap map[string]interface{}
-	err := json.Unmarshal(jsonRequest, &requestMap)
+	var err error
+	switch {
+	case a != nil:
+		requestMap, err = a.decodeFields(jsonRequest)
+
+	case rawParams != nil:
+		requestMap, err = decodeFieldsWithRawParams(jsonRequest, rawParams)
+
+	default:
+		err = json.Unmarshal(jsonRequest, &requestMap)
+	}
+
 	if err != nil {
 		errCode := ParseError
 
@@ -152,13 +376,17 @@ func newRequestResponderFromJSON(jsonRequest []byte, isPartOfBatch bool,
 		return nil, requestMap["id"], InvalidRequest, "Method must be a string."
 	}
 
-	return NewRequestResponderWithState(
+	r := a.newRequest(
 		requestMap["jsonrpc"].(string),
 		requestMap["id"],
 		requestMap["method"].(string),
 		requestMap["params"],
-		state,
-	), requestMap["id"], Success, ""
+		withRequestExtensions(state, requestMap),
+	)
+	_, hasID := requestMap["id"]
+	r.notification = !hasID
+
+	return r, requestMap["id"], Success, ""
 }
 
 // NewRequestFromJSON request from json
@@ -205,3 +433,59 @@ func NewRequestsFromJSON(data []byte) ([]RequestResponder, error) {
 
 	return requests, err
 }
+
+// ParseResult is the outcome of parsing one member of a batch. Either Request
+// is set, or Err describes why the member is invalid.
+type ParseResult struct {
+	Request RequestResponder
+
+	// ID is the id of the member, if it could be found, so that an error can
+	// be correlated by the client.
+	ID interface{}
+
+	Err *RPCError
+}
+
+// Response returns the error response for an invalid member, or nil if it is
+// valid.
+func (result ParseResult) Response() Response {
+	if result.Err == nil {
+		return nil
+	}
+
+	return NewErrorResponseWithData(result.ID, result.Err.Code, result.Err.Message,
+		result.Err.Data)
+}
+
+// ParseBatch parses a single request or a batch, returning a result for each
+// member in order. Unlike NewRequestsFromJSON, an invalid member does not fail
+// the whole batch: the valid requests can be handled and each invalid member
+// answered with its own error, as the specification requires.
+//
+// An error is only returned if data is not JSON at all, or is an empty batch.
+func ParseBatch(data []byte) ([]ParseResult, error) {
+	var members []json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		if !json.Valid(data) {
+			return nil, &RPCError{Code: ParseError, Message: ErrorMessageForCode(ParseError)}
+		}
+
+		// A single request.
+		members = []json.RawMessage{data}
+	} else if len(members) == 0 {
+		return nil, &RPCError{Code: InvalidRequest, Message: "Batch is empty."}
+	}
+
+	results := make([]ParseResult, len(members))
+	for i, member := range members {
+		request, id, code, message := decodeRequest(member, true, State{}, nil, nil)
+		if code != Success {
+			results[i] = ParseResult{ID: id, Err: &RPCError{Code: code, Message: message}}
+			continue
+		}
+
+		results[i] = ParseResult{Request: request, ID: id}
+	}
+
+	return results, nil
+}
@@ -15,6 +15,58 @@ func TestRequest_Bytes(t *testing.T) {
 		string(request.Bytes()))
 }
 
+func TestRequest_Hash(t *testing.T) {
+	hash := func(data string) string {
+		request, err := jsonrpc.NewRequestFromJSON([]byte(data))
+		assert.NoError(t, err)
+		return request.Hash()
+	}
+
+	sum := hash(`{"jsonrpc":"2.0","method":"sum","params":{"a":1,"b":[2,3]},"id":1}`)
+	assert.Len(t, sum, 64)
+	assert.Equal(t, sum, hash(`{"jsonrpc": "2.0", "id": "x", "method": "sum",
+		"params": {"b": [2, 3], "a": 1}}`))
+	assert.NotEqual(t, sum, hash(`{"jsonrpc":"2.0","method":"sum","params":{"a":1,"b":[3,2]},"id":1}`))
+	assert.NotEqual(t, sum, hash(`{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":[2,3]},"id":1}`))
+
+	// The same hash is used by clients, which only know the method and params.
+	expected, err := jsonrpc.RequestHash("sum", map[string]interface{}{
+		"b": []int{2, 3},
+		"a": 1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, sum)
+
+	assert.Equal(t, hash(`{"jsonrpc":"2.0","method":"ping"}`),
+		jsonrpc.NewRequestResponder("2.0", 1, "ping", nil).Hash())
+
+	_, err = jsonrpc.RequestHash("sum", make(chan int))
+	assert.Error(t, err)
+}
+
+func TestRequest_IsNotification(t *testing.T) {
+	for data, expected := range map[string]bool{
+		`{"jsonrpc":"2.0","method":"a"}`:                   true,
+		`{"jsonrpc":"2.0","method":"a","id":null}`:         false,
+		`{"jsonrpc":"2.0","method":"a","params":{"id":1}}`: true,
+		`{"jsonrpc":"2.0","method":"a","id":0}`:            false,
+	} {
+		request, err := jsonrpc.NewRequestFromJSON([]byte(data))
+		assert.NoError(t, err, data)
+		assert.Equal(t, expected, request.IsNotification(), data)
+
+		// The same is true of a batch, and the id is kept when encoded.
+		requests, err := jsonrpc.NewRequestsFromJSON([]byte("[" + data + "]"))
+		assert.NoError(t, err, data)
+		assert.Equal(t, expected, requests[0].IsNotification(), data)
+		assert.JSONEq(t, data, requests[0].String(), data)
+	}
+
+	notification := jsonrpc.NewRequestResponder("2.0", nil, "tick", []int{1})
+	assert.True(t, notification.IsNotification())
+	assert.Equal(t, `{"jsonrpc":"2.0","method":"tick","params":[1]}`, notification.String())
+}
+
 func TestNewRequestFromJSON(t *testing.T) {
 	t.Run("Single", func(t *testing.T) {
 		request := jsonrpc.NewRequestResponder("2.0", 123, "foo", "bar")
@@ -131,3 +183,43 @@ func TestNewRequestsFromJSON(t *testing.T) {
 		assert.Nil(t, r)
 	})
 }
+
+func TestParseBatch(t *testing.T) {
+	results, err := jsonrpc.ParseBatch([]byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1},
+		1,
+		{"jsonrpc": "2.0", "method": 5, "id": "b"},
+		{"jsonrpc": "2.0", "method": "notify"}
+	]`))
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	assert.Equal(t, "sum", results[0].Request.Method())
+	assert.Equal(t, 1.0, results[0].ID)
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[0].Response())
+
+	assert.Nil(t, results[1].Request)
+	assert.Equal(t, &jsonrpc.RPCError{Code: jsonrpc.InvalidRequest,
+		Message: "Invalid request"}, results[1].Err)
+
+	assert.Nil(t, results[2].Request)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"b","error":{"code":-32600,"message":"Method must be a string."}}`,
+		results[2].Response().String())
+
+	assert.Equal(t, "notify", results[3].Request.Method())
+	assert.Nil(t, results[3].ID)
+}
+
+func TestParseBatch_Single(t *testing.T) {
+	results, err := jsonrpc.ParseBatch([]byte(`{"jsonrpc": "2.0", "method": "sum", "id": 1}`))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "sum", results[0].Request.Method())
+
+	_, err = jsonrpc.ParseBatch([]byte(`[{"jsonrpc": "2.0"`))
+	assert.EqualError(t, err, "Parse error (-32700)")
+
+	_, err = jsonrpc.ParseBatch([]byte(`[]`))
+	assert.EqualError(t, err, "Batch is empty. (-32600)")
+}
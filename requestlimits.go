@@ -0,0 +1,199 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+)
+
+// RequestLimits protect the server from hostile input. They are checked
+// before a payload is decoded, so a payload that exceeds them is rejected
+// without allocating memory for it:
+//
+//     server.SetRequestLimits(jsonrpc.RequestLimits{
+//         MaxBodySize:    1 << 20,
+//         MaxBatchSize:   100,
+//         MaxParamsDepth: 16,
+//     })
+//
+// A zero field is not limited. A payload that exceeds a limit is answered with
+// an InvalidRequest error with the LimitExceededErrorType. Transports still
+// limit the size of what they read, see DefaultMaxFrameSize and
+// DefaultMaxBodySize.
+type RequestLimits struct {
+	// MaxBodySize is the largest payload in bytes.
+	MaxBodySize int
+
+	// MaxBatchSize is the most members a batch may have.
+	MaxBatchSize int
+
+	// MaxParamsDepth is how deeply the params may be nested. Params that are
+	// an array or object of scalars have a depth of one.
+	MaxParamsDepth int
+
+	// MaxMemory is the most memory in bytes, as estimated by EstimateMemory,
+	// that decoding the payload may need. A payload that needs more is
+	// answered with a MemoryLimitExceeded error. See also MemoryBudget.
+	MaxMemory int64
+}
+
+// Check returns an error if the payload (a single request or a batch) exceeds
+// the limits. It does not check that the payload is valid JSON.
+func (limits RequestLimits) Check(data []byte) *RPCError {
+	if limits.MaxBodySize > 0 && len(data) > limits.MaxBodySize {
+		return limitError("Request is too large.")
+	}
+
+	if limits.MaxMemory > 0 {
+		if estimate := EstimateMemory(data); estimate > limits.MaxMemory {
+			return &RPCError{
+				Code:    MemoryLimitExceeded,
+				Message: "Memory limit exceeded",
+				Data: NewErrorDetails(LimitExceededErrorType).
+					WithDetail("The request needs about " + strconv.FormatInt(estimate, 10) +
+						" bytes but the limit is " + strconv.FormatInt(limits.MaxMemory, 10) + "."),
+			}
+		}
+	}
+
+	if limits.MaxBatchSize <= 0 && limits.MaxParamsDepth <= 0 {
+		return nil
+	}
+
+	// The depth of the params is counted from the request object, which is
+	// inside the array of a batch.
+	offset := 1
+	depth, members := 0, 0
+	batch, inString, escaped, expectMember := false, false, false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		if isSpace(c) {
+			continue
+		}
+
+		if expectMember && c != ']' {
+			expectMember = false
+			members++
+			if limits.MaxBatchSize > 0 && members > limits.MaxBatchSize {
+				return limitError("Batch has too many members.")
+			}
+		}
+
+		switch c {
+		case '"':
+			inString = true
+
+		case '[', '{':
+			if depth == 0 && c == '[' {
+				batch, offset, expectMember = true, 2, true
+			}
+
+			depth++
+			if limits.MaxParamsDepth > 0 && depth-offset > limits.MaxParamsDepth {
+				return limitError("Params are nested too deeply.")
+			}
+
+		case ']', '}':
+			depth--
+
+		case ',':
+			expectMember = batch && depth == 1
+		}
+	}
+
+	return nil
+}
+
+func limitError(message string) *RPCError {
+	return &RPCError{
+		Code:    InvalidRequest,
+		Message: message,
+		Data:    NewErrorDetails(LimitExceededErrorType),
+	}
+}
+
+// NewRequestsFromJSONWithLimits is NewRequestsFromJSON for a payload that
+// must not exceed the limits. The error is an *RPCError if it does.
+func NewRequestsFromJSONWithLimits(data []byte, limits RequestLimits) ([]RequestResponder, error) {
+	if err := limits.Check(data); err != nil {
+		return nil, err
+	}
+
+	return NewRequestsFromJSON(data)
+}
+
+// SetRequestLimits sets the limits of every payload the server handles.
+func (server *SimpleServer) SetRequestLimits(limits RequestLimits) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requestLimits = limits
+}
+
+// checkRequestLimits returns the error response to a payload that exceeds the
+// limits or the memory budgets of the server, or nil. The response has the id
+// of the request if the payload is not a batch. If memory was reserved
+// release gives it back, and must be called once the payload has been handled.
+func (server *SimpleServer) checkRequestLimits(jsonRequest []byte, state State,
+	a *arena) (response Response, release func()) {
+	server.mutex.RLock()
+	limits := server.requestLimits
+	budget := server.memoryBudget
+	server.mutex.RUnlock()
+
+	err := limits.Check(jsonRequest)
+	if err == nil {
+		connBudget, _ := state[memoryBudgetStateKey].(*MemoryBudget)
+		release, err = reserveMemory(jsonRequest, budget, connBudget)
+	}
+
+	if err == nil {
+		return nil, release
+	}
+
+	atomic.AddUint64(&server.totalErrorResponses, 1)
+	info, _ := state[clientInfoStateKey].(*ClientInfo)
+	server.log(slog.LevelWarn, "Invalid request", info, "code", err.Code,
+		"error", err.Message)
+
+	return server.respond(nil, a.newErrorResponse(payloadID(jsonRequest),
+		err.Code, err.Message, err.Data)), nil
+}
+
+// payloadID returns the id of a payload that is a single request, so that an
+// error about the whole payload can still be matched to it. It returns nil for
+// a batch or an id that cannot be found.
+func payloadID(data []byte) interface{} {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+
+	var fields struct {
+		ID interface{} `json:"id"`
+	}
+	if json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+
+	switch fields.ID.(type) {
+	case string, float64:
+		return fields.ID
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRequestLimits_Check(t *testing.T) {
+	limits := jsonrpc.RequestLimits{MaxBodySize: 200, MaxBatchSize: 2, MaxParamsDepth: 2}
+
+	for data, expected := range map[string]string{
+		`{"jsonrpc":"2.0","method":"a","params":[[1]],"id":1}`:                       "",
+		`{"jsonrpc":"2.0","method":"a","params":[[[1]]],"id":1}`:                     "Params are nested too deeply.",
+		`{"jsonrpc":"2.0","method":"a","params":["[[[[\"]"],"id":1}`:                 "",
+		`[{"jsonrpc":"2.0","method":"a","params":[[1]]}]`:                            "",
+		`[{"jsonrpc":"2.0","method":"a","params":[[[1]]]}]`:                          "Params are nested too deeply.",
+		`[{"method":"a"}, {"method":"b"}]`:                                           "",
+		`[{"method":"a"}, {"method":"b"}, 3]`:                                        "Batch has too many members.",
+		`[ ]`:                                                                        "",
+		`{"jsonrpc":"2.0","method":"a","params":"` + strings.Repeat("x", 200) + `"}`: "Request is too large.",
+	} {
+		err := limits.Check([]byte(data))
+		if expected == "" {
+			assert.Nil(t, err, data)
+			continue
+		}
+
+		if assert.NotNil(t, err, data) {
+			assert.Equal(t, jsonrpc.InvalidRequest, err.Code, data)
+			assert.Equal(t, expected, err.Message, data)
+			assert.Equal(t, jsonrpc.LimitExceededErrorType,
+				err.Data.(*jsonrpc.ErrorDetails).Type, data)
+		}
+	}
+
+	// Zero limits allow anything.
+	assert.Nil(t, jsonrpc.RequestLimits{}.Check([]byte(`[[[[[[1,2,3]]]]]]`)))
+}
+
+func TestNewRequestsFromJSONWithLimits(t *testing.T) {
+	limits := jsonrpc.RequestLimits{MaxBatchSize: 1}
+
+	requests, err := jsonrpc.NewRequestsFromJSONWithLimits(
+		[]byte(`[{"jsonrpc":"2.0","method":"a","id":1}]`), limits)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+
+	_, err = jsonrpc.NewRequestsFromJSONWithLimits(
+		[]byte(`[{"jsonrpc":"2.0","method":"a","id":1},{"jsonrpc":"2.0","method":"b"}]`), limits)
+	assert.EqualError(t, err, "Batch has too many members. (-32600)")
+}
+
+func TestSimpleServer_SetRequestLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetRequestLimits(jsonrpc.RequestLimits{MaxBatchSize: 2, MaxParamsDepth: 1})
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},
+		{"jsonrpc":"2.0","method":"sum","params":[3,4],"id":2}
+	]`))
+	assert.Equal(t, []interface{}{3.0, 7.0}, resultsOf(responses))
+
+	expected := `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,` +
+		`"message":"Batch has too many members.",` +
+		`"data":{"type":"urn:jsonrpc:error:limit-exceeded","retryable":false}}}`
+	batch := []byte(`[
+		{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},
+		{"jsonrpc":"2.0","method":"sum","params":[3,4],"id":2},
+		{"jsonrpc":"2.0","method":"sum","params":[5,6],"id":3}
+	]`)
+
+	responses = server.Handle(batch)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, expected, responses[0].String())
+
+	// HandleArena enforces the same limits.
+	var output bytes.Buffer
+	assert.NoError(t, server.HandleArena(&output, batch, nil))
+	assert.Equal(t, expected, output.String())
+
+	responses = server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[[1],2],"id":1}`))
+	assert.Equal(t, "Params are nested too deeply.", responses[0].ErrorMessage())
+}
@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// InvalidParamsError is returned by a RequestValidator when the params of a
+// call do not match the schema of the method. The request was not sent.
+type InvalidParamsError struct {
+	Method     string
+	Violations []FieldViolation
+}
+
+// Error describes every violation, such as
+// `Params of "user.get" violate its schema: id is required`.
+func (err *InvalidParamsError) Error() string {
+	return `Params of "` + err.Method + `" violate its schema: ` +
+		joinViolations(err.Violations)
+}
+
+// RequestValidator is an Invoker that checks the params of every call against
+// the schema of the method before it is sent. A call that the server would
+// answer with InvalidParams fails straight away, with an error that says what
+// is wrong, instead of after a round trip:
+//
+//     client := jsonrpc.NewRequestValidator(invoker)
+//     client.SetMethodInfo(methods...)
+//
+//     _, err := client.Invoke(ctx, "user.get", map[string]interface{}{})
+//     // Params of "user.get" violate its schema: id is required
+//
+// Positional params and methods without a schema are not checked. Missing
+// params are checked as an empty object. It is the opposite of a
+// ResponseValidator, and the two can wrap each other.
+//
+// It is safe for concurrent use.
+type RequestValidator struct {
+	invoker Invoker
+	mutex   sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewRequestValidator creates a RequestValidator that sends requests with
+// invoker.
+func NewRequestValidator(invoker Invoker) *RequestValidator {
+	return &RequestValidator{
+		invoker: invoker,
+		schemas: map[string]*Schema{},
+	}
+}
+
+// SetParamsSchema sets (or replaces) the schema of the params of a method. A
+// nil schema stops the method from being checked.
+func (validator *RequestValidator) SetParamsSchema(method string, schema *Schema) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	if schema == nil {
+		delete(validator.schemas, method)
+		return
+	}
+
+	validator.schemas[method] = schema
+}
+
+// SetMethodInfo sets the schema of the params of each method that has a
+// Params schema, such as the methods published by a server.
+func (validator *RequestValidator) SetMethodInfo(methods ...MethodInfo) {
+	for _, info := range methods {
+		if info.Params != nil {
+			validator.SetParamsSchema(info.Name, info.Params)
+		}
+	}
+}
+
+// Validate checks the params of a call without sending it. It returns an
+// *InvalidParamsError if they do not match the schema of the method.
+func (validator *RequestValidator) Validate(method string, params interface{}) error {
+	validator.mutex.RLock()
+	schema := validator.schemas[method]
+	validator.mutex.RUnlock()
+
+	if schema == nil {
+		return nil
+	}
+
+	params = normalizeJSON(params)
+	if _, positional := params.([]interface{}); positional {
+		return nil
+	}
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	validation := new(Validation)
+	schema.validate(params, "", validation)
+	if !validation.Valid() {
+		return &InvalidParamsError{Method: method, Violations: validation.Violations()}
+	}
+
+	return nil
+}
+
+// Invoke sends the request if its params are valid.
+func (validator *RequestValidator) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	if err := validator.Validate(method, params); err != nil {
+		return nil, err
+	}
+
+	return validator.invoker.Invoke(ctx, method, params)
+}
@@ -0,0 +1,71 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRequestValidator(t *testing.T) {
+	sent := 0
+	invoker := serverInvoker(newTestServer())
+	validator := jsonrpc.NewRequestValidator(jsonrpc.InvokerFunc(
+		func(ctx context.Context, method string, params interface{}) (jsonrpc.Response, error) {
+			sent++
+			return invoker.Invoke(ctx, method, params)
+		}))
+	validator.SetMethodInfo(
+		jsonrpc.MethodInfo{Name: "subtract", Params: &jsonrpc.Schema{
+			Type:     "object",
+			Required: []string{"minuend", "subtrahend"},
+			Properties: map[string]*jsonrpc.Schema{
+				"minuend":    {Type: "number"},
+				"subtrahend": {Type: "number"},
+			},
+		}},
+		jsonrpc.MethodInfo{Name: "get_data"},
+	)
+
+	type subtractParams struct {
+		Minuend    int `json:"minuend"`
+		Subtrahend int `json:"subtrahend"`
+	}
+
+	response, err := validator.Invoke(context.Background(), "subtract",
+		subtractParams{Minuend: 42, Subtrahend: 23})
+	assert.NoError(t, err)
+	assert.Equal(t, 19.0, response.Result())
+	assert.Equal(t, 1, sent)
+
+	// Invalid params are not sent.
+	response, err = validator.Invoke(context.Background(), "subtract",
+		map[string]interface{}{"minuend": "42"})
+	assert.Nil(t, response)
+	assert.EqualError(t, err, `Params of "subtract" violate its schema: `+
+		`subtrahend is required, minuend must be of type number`)
+	assert.Equal(t, 1, sent)
+
+	invalid, ok := err.(*jsonrpc.InvalidParamsError)
+	if assert.True(t, ok) {
+		assert.Equal(t, "subtract", invalid.Method)
+		assert.Len(t, invalid.Violations, 2)
+	}
+
+	// Missing params are an empty object.
+	assert.EqualError(t, validator.Validate("subtract", nil), `Params of "subtract" `+
+		`violate its schema: minuend is required, subtrahend is required`)
+
+	// Positional params and methods without a schema are not checked.
+	response, err = validator.Invoke(context.Background(), "subtract", []int{42, 23})
+	assert.NoError(t, err)
+	assert.Equal(t, 19.0, response.Result())
+
+	_, err = validator.Invoke(context.Background(), "get_data", "anything")
+	assert.NoError(t, err)
+
+	validator.SetParamsSchema("subtract", nil)
+	assert.NoError(t, validator.Validate("subtract", nil))
+	assert.Equal(t, 3, sent)
+}
@@ -34,6 +34,9 @@ const (
 	// -32099. These error codes would be understood by the receiver.
 	ServerError = -32000
 
+	// The method has passed its sunset date. See MethodInfo.
+	MethodRetired = -32010
+
 	// The lower bound for the server error range. You would probably not
 	// use this constant directly unless you had a special reason to, use
 	// jsonRpcServerError instead.
@@ -56,6 +59,11 @@ type Response interface {
 	Result() interface{}
 	ErrorCode() int
 	ErrorMessage() string
+	ErrorData() interface{}
+
+	// Extensions returns the non-standard top-level members of the response.
+	// See WithExtension.
+	Extensions() map[string]interface{}
 
 	// Serialization
 	fmt.Stringer
@@ -67,9 +75,12 @@ type Responses []Response
 // A JSON-RPC error is made up of a code and a message. It is acceptable for the
 // message to be empty - the server will replace it with the generic message
 // returned from ErrorMessageForCode().
+//
+// Data is optional and may contain any additional information about the error.
 type errorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 // A JSON-RPC response object.
@@ -78,6 +89,11 @@ type response struct {
 	ResponseID      interface{}    `json:"id"`
 	ResponseResult  interface{}    `json:"result,omitempty"`
 	ResponseError   *errorResponse `json:"error,omitempty"`
+	extensions      map[string]interface{}
+
+	// rawResult is the result as it was received, so that it can be decoded
+	// into a type without going through float64. See decodeResult.
+	rawResult json.RawMessage
 }
 
 func (response *response) Version() string {
@@ -108,6 +124,14 @@ func (response *response) ErrorMessage() string {
 	return response.ResponseError.Message
 }
 
+func (response *response) ErrorData() interface{} {
+	if response.ResponseError == nil {
+		return nil
+	}
+
+	return response.ResponseError.Data
+}
+
 // The string representation of a response will be the JSON encoded value. This
 // JSON is expected to be a perfectly valid JSON-RPC response.
 func (response *response) String() string {
@@ -156,6 +180,17 @@ func NewSuccessResponse(id interface{}, result interface{}) Response {
 // not contain sensitive details (such as passwords). You may provide an empty
 // string for message to use the message from ErrorMessageForCode() instead.
 func NewErrorResponse(id interface{}, code int, message string) Response {
+	return NewErrorResponseWithData(id, code, message, nil)
+}
+
+// Create a response containing an error with additional data. See
+// NewErrorResponse for the other arguments.
+//
+// The data can be of any type that can be encoded to JSON. A nil data will be
+// omitted from the response. ErrorDetails provides a standard structure for
+// the data.
+func NewErrorResponseWithData(id interface{}, code int, message string,
+	data interface{}) Response {
 	if message == "" {
 		message = ErrorMessageForCode(code)
 	}
@@ -166,6 +201,7 @@ func NewErrorResponse(id interface{}, code int, message string) Response {
 		ResponseError: &errorResponse{
 			Code:    code,
 			Message: message,
+			Data:    data,
 		},
 	}
 }
@@ -201,7 +237,7 @@ func ErrorMessageForCode(code int) string {
 		return "Internal error"
 	}
 
-	if code >= ServerErrorMin && code <= ServerError {
+	if IsServerError(code) {
 		return "Server error"
 	}
 
@@ -0,0 +1,214 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides when a Client sends a call again. A call is retried
+// when the transport could not connect to the server, when the server answers
+// with a 429 Too Many Requests or 503 Service Unavailable (see
+// HTTPStatusError), or when it responds with one of Codes:
+//
+//     client.SetRetryPolicy(&jsonrpc.RetryPolicy{
+//         MaxAttempts: 4,
+//         Backoff:     100 * time.Millisecond,
+//         MaxBackoff:  2 * time.Second,
+//         Jitter:      0.2,
+//         Codes:       []int{jsonrpc.ServerError},
+//     })
+//
+// Other network errors, such as a connection that is reset or times out, and
+// other 5xx statuses, are only retried for the methods that are Idempotent,
+// since the server may have received the call and acted on it. Errors that
+// sending the call again will not fix, such as a reply that is too large or
+// cannot be decoded, are never retried.
+//
+// The delay doubles after each attempt, unless the error has ErrorDetails with
+// a RetryAfter, which is used instead. A retry that would not start before
+// the deadline of the context is not made, and the last error or response is
+// returned. Notifications and batches are never retried, as the server may
+// have acted on them.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is sent, including the
+	// first. One or less disables retries.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. Zero uses 100
+	// milliseconds.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Zero does not cap it.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each delay that is random,
+	// so that clients that failed together do not all retry together.
+	Jitter float64
+
+	// Codes are the error codes of the responses that are retried.
+	Codes []int
+
+	// Idempotent are the methods that are safe to call more than once, which
+	// are retried after any network error or 5xx status.
+	Idempotent []string
+
+	// Clock is used to wait between attempts. SystemClock is used if it is
+	// nil.
+	Clock Clock
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context that makes a Client use the policy for
+// the calls made with it, instead of its own. A nil policy disables retries.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// SetRetryPolicy sets the policy for the calls of the client. Nil, the
+// default, does not retry them.
+func (client *Client) SetRetryPolicy(policy *RetryPolicy) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.retryPolicy = policy
+}
+
+// retryPolicyFor returns the policy of ctx, if it has one, or the client's.
+func (client *Client) retryPolicyFor(ctx context.Context) *RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(*RetryPolicy); ok {
+		return policy
+	}
+
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.retryPolicy
+}
+
+// retry calls attempt, which sends a call of the method, until it succeeds,
+// fails in a way that is not retried or runs out of attempts. A nil policy
+// calls it once.
+func (policy *RetryPolicy) retry(ctx context.Context, method string,
+	attempt func() (Response, error)) (Response, error) {
+	for attempts := 1; ; attempts++ {
+		response, err := attempt()
+		if policy == nil || attempts >= policy.MaxAttempts || ctx.Err() != nil {
+			return response, err
+		}
+
+		requested, ok := policy.retryable(method, response, err)
+		if !ok {
+			return response, err
+		}
+
+		delay := requested
+		if delay == 0 {
+			delay = policy.backoff(attempts)
+		}
+
+		clock := clockOrSystem(policy.Clock)
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(clock.Now()) < delay {
+			return response, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-clock.After(delay):
+		}
+	}
+}
+
+// retryable returns true if the outcome of an attempt may be retried, and the
+// delay the server asked for (if any).
+func (policy *RetryPolicy) retryable(method string, response Response,
+	err error) (time.Duration, bool) {
+	if err != nil {
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) {
+			switch {
+			case statusErr.StatusCode == http.StatusTooManyRequests,
+				statusErr.StatusCode == http.StatusServiceUnavailable:
+				return 0, true
+
+			case statusErr.StatusCode >= 500:
+				return 0, policy.idempotent(method)
+			}
+
+			return 0, false
+		}
+
+		// The call was not sent if the connection could not be made.
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return 0, true
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) || errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, policy.idempotent(method)
+		}
+
+		return 0, false
+	}
+
+	for _, code := range policy.Codes {
+		if response.ErrorCode() == code && code != Success {
+			delay, _ := retryDelay(response)
+
+			return delay, true
+		}
+	}
+
+	return 0, false
+}
+
+func (policy *RetryPolicy) idempotent(method string) bool {
+	for _, idempotent := range policy.Idempotent {
+		if idempotent == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay after the attempt, which counts from one.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.Backoff
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	for i := 1; i < attempt; i++ {
+		// Doubling a delay this long would overflow.
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+
+		delay *= 2
+		if policy.MaxBackoff > 0 && delay >= policy.MaxBackoff {
+			break
+		}
+	}
+
+	if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+
+	if policy.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * policy.Jitter * float64(delay))
+	}
+
+	return delay
+}
@@ -0,0 +1,279 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// flakyTransport fails with the errors in order, then answers with reply.
+func flakyTransport(attempts *int32, reply string, errs ...error) jsonrpc.Transport {
+	return jsonrpc.TransportFunc(func(ctx context.Context, message []byte,
+		notification bool) ([]byte, error) {
+		attempt := int(atomic.AddInt32(attempts, 1))
+		if attempt <= len(errs) {
+			return nil, errs[attempt-1]
+		}
+
+		return []byte(reply), nil
+	})
+}
+
+// connectionRefused is the error of a call that was never sent.
+var connectionRefused = &net.OpError{Op: "dial", Net: "tcp",
+	Err: errors.New("connection refused")}
+
+// waitFor advances the clock by d once a call is waiting on it, and checks
+// that the call was not released any earlier.
+func waitFor(t *testing.T, clock *jsonrpc.FakeClock, d time.Duration) {
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(d - time.Millisecond)
+	assert.Equal(t, 1, clock.Waiters())
+	clock.Advance(time.Millisecond)
+}
+
+func TestClient_RetryPolicy(t *testing.T) {
+	var attempts int32
+	refused := connectionRefused
+	client := jsonrpc.NewClient(flakyTransport(&attempts, `{"jsonrpc":"2.0","id":1,"result":3}`,
+		refused, &jsonrpc.HTTPStatusError{StatusCode: 503, Status: "503 Service Unavailable"}))
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	client.SetRetryPolicy(&jsonrpc.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+		MaxBackoff:  1500 * time.Millisecond,
+		Clock:       clock,
+	})
+
+	done := make(chan error, 1)
+	var result int
+	go func() {
+		done <- client.Call(context.Background(), "sum", []int{1, 2}, &result)
+	}()
+
+	waitFor(t, clock, time.Second)
+	waitFor(t, clock, 1500*time.Millisecond)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// The last error is returned when the attempts run out.
+	attempts = 0
+	client.SetTransport(flakyTransport(&attempts, "", refused, refused, refused))
+	go func() {
+		done <- client.Call(context.Background(), "sum", []int{1, 2}, nil)
+	}()
+
+	waitFor(t, clock, time.Second)
+	waitFor(t, clock, 1500*time.Millisecond)
+	assert.Equal(t, refused, <-done)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_RetryPolicy_Codes(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	policy := &jsonrpc.RetryPolicy{
+		MaxAttempts: 2,
+		Codes:       []int{jsonrpc.ServerError},
+		Clock:       clock,
+	}
+
+	var attempts int32
+	busy := jsonrpc.TransportFunc(func(ctx context.Context, message []byte,
+		notification bool) ([]byte, error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"Busy",` +
+				`"data":{"type":"urn:jsonrpc:error:busy","retryable":true,"retryAfter":5}}}`), nil
+		}
+
+		return []byte(`{"jsonrpc":"2.0","id":1,"result":true}`), nil
+	})
+	client := jsonrpc.NewClient(busy)
+	client.SetRetryPolicy(policy)
+
+	// The delay the server asks for is used.
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(context.Background(), "foo", nil, nil)
+	}()
+
+	waitFor(t, clock, 5*time.Second)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	for name, test := range map[string]struct {
+		ctx   context.Context
+		reply string
+		err   error
+	}{
+		"code": {
+			context.Background(),
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}}`,
+			nil,
+		},
+		"status": {
+			context.Background(),
+			"",
+			&jsonrpc.HTTPStatusError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"},
+		},
+		"disabled": {
+			jsonrpc.WithRetryPolicy(context.Background(), nil),
+			"",
+			connectionRefused,
+		},
+		"reset": {
+			context.Background(),
+			"",
+			&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")},
+		},
+		"internal error": {
+			context.Background(),
+			"",
+			&jsonrpc.HTTPStatusError{StatusCode: 500, Status: "500 Internal Server Error"},
+		},
+		"too large": {
+			context.Background(),
+			"",
+			errors.New("Response is too large."),
+		},
+		"mismatch": {
+			context.Background(),
+			`{"jsonrpc":"2.0","id":3,"result":true}`,
+			nil,
+		},
+	} {
+		attempts = 0
+		client := jsonrpc.NewClient(jsonrpc.TransportFunc(func(ctx context.Context,
+			message []byte, notification bool) ([]byte, error) {
+			atomic.AddInt32(&attempts, 1)
+			if test.err != nil {
+				return nil, test.err
+			}
+
+			return []byte(test.reply), nil
+		}))
+		client.SetIDGenerator(jsonrpc.IDGeneratorFunc(func() interface{} {
+			return 2
+		}))
+		client.SetRetryPolicy(policy)
+
+		client.Call(test.ctx, "foo", nil, nil)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts), name)
+	}
+}
+
+func TestClient_RetryPolicy_Idempotent(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	policy := &jsonrpc.RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		Idempotent:  []string{"get"},
+	}
+
+	for method, expected := range map[string]int32{"get": 2, "put": 1} {
+		for _, err := range []error{reset, io.ErrUnexpectedEOF,
+			&jsonrpc.HTTPStatusError{StatusCode: 502, Status: "502 Bad Gateway"}} {
+			var attempts int32
+			client := jsonrpc.NewClient(flakyTransport(&attempts,
+				`{"jsonrpc":"2.0","id":1,"result":true}`, err))
+			client.SetIDGenerator(&jsonrpc.SequentialIDGenerator{})
+			client.SetRetryPolicy(policy)
+
+			client.Call(context.Background(), method, nil, nil)
+			assert.Equal(t, expected, atomic.LoadInt32(&attempts), method)
+		}
+	}
+}
+
+func TestClient_RetryPolicy_Deadline(t *testing.T) {
+	var attempts int32
+	refused := connectionRefused
+	client := jsonrpc.NewClient(flakyTransport(&attempts, "", refused, refused))
+	client.SetRetryPolicy(&jsonrpc.RetryPolicy{MaxAttempts: 3, Backoff: time.Hour})
+
+	// The retry would not start before the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	assert.Equal(t, refused, client.Call(ctx, "foo", nil, nil))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// A call that is cancelled while it waits returns the error of ctx.
+	attempts = 0
+	ctx, cancel = context.WithCancel(context.Background())
+	ctx = jsonrpc.WithRetryPolicy(ctx, &jsonrpc.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Hour,
+		Jitter:      0.5,
+	})
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	assert.Equal(t, context.Canceled, client.Call(ctx, "foo", nil, nil))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// The deadline is compared with the time of the Clock of the policy.
+	attempts = 0
+	clock := jsonrpc.NewFakeClock(time.Now().Add(-2 * time.Hour))
+	client = jsonrpc.NewClient(flakyTransport(&attempts, `{"jsonrpc":"2.0","id":1,"result":true}`,
+		refused))
+	client.SetRetryPolicy(&jsonrpc.RetryPolicy{MaxAttempts: 2, Backoff: time.Hour, Clock: clock})
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(ctx, "foo", nil, nil)
+	}()
+
+	waitFor(t, clock, time.Hour)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_RetryPolicy_LongBackoff(t *testing.T) {
+	var attempts int32
+	refused := connectionRefused
+	client := jsonrpc.NewClient(flakyTransport(&attempts, `{"jsonrpc":"2.0","id":1,"result":true}`,
+		refused, refused))
+
+	// Doubling the delay would overflow into a negative delay, which would not
+	// wait at all.
+	clock := jsonrpc.NewFakeClock(epoch)
+	client.SetRetryPolicy(&jsonrpc.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     1 << 62,
+		Clock:       clock,
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(context.Background(), "foo", nil, nil)
+	}()
+
+	waitFor(t, clock, 1<<62)
+	for clock.Waiters() == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("The call was retried without waiting: %v", err)
+
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	waitFor(t, clock, math.MaxInt64)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
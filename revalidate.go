@@ -0,0 +1,222 @@
+package jsonrpc
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// The extension members used to revalidate results. See Revalidate.
+const (
+	// ETagExtension is the response member that holds the version tag of
+	// the result.
+	ETagExtension = "etag"
+
+	// IfNoneMatchExtension is the request member that holds the tag of the
+	// result the client already has.
+	IfNoneMatchExtension = "ifNoneMatch"
+
+	// NotModifiedExtension is the response member that is true when the
+	// result has not changed. The result itself is left out.
+	NotModifiedExtension = "notModified"
+)
+
+// WithETag returns a copy of the response with a version tag for its result,
+// such as the revision of a database row. Revalidate uses it instead of
+// hashing the result.
+func WithETag(response Response, tag string) Response {
+	return WithExtension(response, ETagExtension, tag)
+}
+
+// Revalidate returns a middleware that lets polling clients avoid transferring
+// a large result that has not changed, in the same way as an HTTP ETag. Every
+// successful response is tagged with the version of its result in the
+// ETagExtension, which is a hash of the result unless the handler set one with
+// WithETag. When a request sends the same tag back in the IfNoneMatchExtension
+// the result is left out and the NotModifiedExtension is sent instead:
+//
+//     // {"jsonrpc":"2.0","method":"report","id":1,"ifNoneMatch":"3f2a..."}
+//     // {"jsonrpc":"2.0","id":1,"etag":"3f2a...","notModified":true}
+//
+// The handler still runs, so this saves bandwidth rather than work. The server
+// must be lenient (see SetLenient) to send the extensions. RevalidatingInvoker
+// is the client side.
+func Revalidate() Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			response := next(request)
+			if response == nil || response.ErrorCode() != Success {
+				return response
+			}
+
+			tag, ok := Extension(response, ETagExtension).(string)
+			if !ok {
+				encoded, err := json.Marshal(response.Result())
+				if err != nil {
+					return response
+				}
+
+				sum := sha256.Sum256(encoded)
+				tag = hex.EncodeToString(sum[:16])
+				response = WithETag(response, tag)
+			}
+
+			if match, _ := RequestExtension(request, IfNoneMatchExtension).(string); match == tag {
+				notModified := WithETag(request.NewSuccessResponse(nil), tag)
+				return WithExtension(notModified, NotModifiedExtension, true)
+			}
+
+			return response
+		}
+	}
+}
+
+// DefaultRevalidatedResults is the number of results a RevalidatingInvoker
+// remembers when MaxResults is zero.
+const DefaultRevalidatedResults = 1000
+
+// RevalidatingInvoker is an Invoker that remembers the tagged results of
+// successful calls and sends their tag with the next call of the same method
+// and params. When the server says the result has not changed the remembered
+// result is returned instead, so the caller never sees the difference. See
+// Revalidate.
+//
+// The result is remembered as it was received, so it decodes (see Call) with
+// the same precision as the original response. The least recently used
+// results are forgotten once there are more than MaxResults.
+//
+// The Invoker must send the extension members of the context (see
+// WithRequestExtension). It is safe for concurrent use.
+type RevalidatingInvoker struct {
+	Invoker Invoker
+
+	// MaxResults is the number of results that are remembered. Zero uses
+	// DefaultRevalidatedResults.
+	MaxResults int
+
+	mutex   sync.Mutex
+	results map[string]*list.Element
+	recent  list.List
+}
+
+// revalidatedResult is a result remembered by a RevalidatingInvoker.
+type revalidatedResult struct {
+	key    string
+	tag    string
+	result json.RawMessage
+}
+
+// Invoke sends the request with the tag of the remembered result, if there is
+// one.
+func (invoker *RevalidatingInvoker) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	key, err := RequestHash(method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := invoker.get(key)
+	if cached != nil {
+		ctx = WithRequestExtension(ctx, IfNoneMatchExtension, cached.tag)
+	}
+
+	response, err := invoker.Invoker.Invoke(ctx, method, params)
+	if err != nil || response.ErrorCode() != Success {
+		return response, err
+	}
+
+	if notModified, _ := Extension(response, NotModifiedExtension).(bool); notModified &&
+		cached != nil {
+		return cached.response(response.ID())
+	}
+
+	if tag, ok := Extension(response, ETagExtension).(string); ok {
+		raw, err := rawResult(response)
+		if err == nil {
+			invoker.put(&revalidatedResult{key: key, tag: tag, result: raw})
+		}
+	}
+
+	return response, nil
+}
+
+// response returns a response with the remembered result, as if it had been
+// received with the id.
+func (cached *revalidatedResult) response(id interface{}) (Response, error) {
+	var result interface{}
+	if err := json.Unmarshal(cached.result, &result); err != nil {
+		return nil, err
+	}
+
+	return &response{
+		ResponseVersion: "2.0",
+		ResponseID:      id,
+		ResponseResult:  result,
+		rawResult:       cached.result,
+		extensions:      map[string]interface{}{ETagExtension: cached.tag},
+	}, nil
+}
+
+// rawResult returns the result of the response as it was received, or encoded
+// if it was not received as JSON.
+func rawResult(r Response) (json.RawMessage, error) {
+	if received, ok := r.(*response); ok && received.rawResult != nil {
+		return received.rawResult, nil
+	}
+
+	return json.Marshal(r.Result())
+}
+
+func (invoker *RevalidatingInvoker) get(key string) *revalidatedResult {
+	invoker.mutex.Lock()
+	defer invoker.mutex.Unlock()
+
+	element := invoker.results[key]
+	if element == nil {
+		return nil
+	}
+
+	invoker.recent.MoveToFront(element)
+
+	return element.Value.(*revalidatedResult)
+}
+
+func (invoker *RevalidatingInvoker) put(result *revalidatedResult) {
+	invoker.mutex.Lock()
+	defer invoker.mutex.Unlock()
+
+	if invoker.results == nil {
+		invoker.results = map[string]*list.Element{}
+	}
+
+	if element := invoker.results[result.key]; element != nil {
+		element.Value = result
+		invoker.recent.MoveToFront(element)
+		return
+	}
+
+	invoker.results[result.key] = invoker.recent.PushFront(result)
+
+	maxResults := invoker.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultRevalidatedResults
+	}
+
+	for invoker.recent.Len() > maxResults {
+		oldest := invoker.recent.Back()
+		invoker.recent.Remove(oldest)
+		delete(invoker.results, oldest.Value.(*revalidatedResult).key)
+	}
+}
+
+// Forget removes every remembered result.
+func (invoker *RevalidatingInvoker) Forget() {
+	invoker.mutex.Lock()
+	defer invoker.mutex.Unlock()
+
+	invoker.results = nil
+	invoker.recent.Init()
+}
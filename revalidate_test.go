@@ -0,0 +1,113 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRequestExtension(t *testing.T) {
+	var value interface{}
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler("a", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		value = jsonrpc.RequestExtension(request, "trace")
+		return nil
+	})
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"a","trace":{"span":1}}`))
+	assert.Equal(t, map[string]interface{}{"span": 1.0}, value)
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"a"}`))
+	assert.Nil(t, value)
+}
+
+func TestRevalidate(t *testing.T) {
+	report := "version 1"
+	calls := 0
+
+	server := jsonrpc.NewSimpleServer()
+	server.SetLenient(true)
+	server.Group(jsonrpc.Revalidate()).SetHandler("report",
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			calls++
+			return request.NewSuccessResponse(report)
+		})
+	server.Group(jsonrpc.Revalidate()).SetHandler("tagged",
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			return jsonrpc.WithETag(request.NewSuccessResponse(report), "v1")
+		})
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"tagged","id":1,"ifNoneMatch":"v1"}`))[0]
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"etag":"v1","notModified":true}`,
+		response.String())
+
+	wire := wireInvoker(server)
+	var sent []string
+	recording := jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		response, err := wire.Invoke(ctx, method, params)
+		if jsonrpc.Extension(response, jsonrpc.NotModifiedExtension) == true {
+			sent = append(sent, "not modified")
+		} else {
+			sent = append(sent, response.Result().(string))
+		}
+
+		return response, err
+	})
+	invoker := &jsonrpc.RevalidatingInvoker{Invoker: recording}
+
+	for _, expected := range []string{"version 1", "version 1", "version 2"} {
+		if expected == "version 2" {
+			report = expected
+		}
+
+		response, err := invoker.Invoke(context.Background(), "report", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, response.Result())
+	}
+
+	assert.Equal(t, []string{"version 1", "not modified", "version 2"}, sent)
+	assert.Equal(t, 3, calls)
+
+	// Different params are remembered separately.
+	invoker.Forget()
+	invoker.Invoke(context.Background(), "report", []int{1})
+	invoker.Invoke(context.Background(), "report", []int{2})
+	assert.Equal(t, []string{"version 1", "not modified", "version 2", "version 2", "version 2"}, sent)
+}
+
+func TestRevalidatingInvoker_MaxResults(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	server.SetLenient(true)
+	server.Group(jsonrpc.Revalidate()).SetHandler("echo",
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			return request.NewSuccessResponse(json.RawMessage(`9007199254740993`))
+		})
+
+	wire := wireInvoker(server)
+	var sent []bool
+	recording := jsonrpc.InvokerFunc(func(ctx context.Context, method string,
+		params interface{}) (jsonrpc.Response, error) {
+		response, err := wire.Invoke(ctx, method, params)
+		sent = append(sent, jsonrpc.Extension(response, jsonrpc.NotModifiedExtension) == true)
+
+		return response, err
+	})
+	invoker := &jsonrpc.RevalidatingInvoker{Invoker: recording, MaxResults: 2}
+
+	// The remembered result decodes as exactly as the one that was received.
+	var result int64
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, jsonrpc.CallWith(context.Background(), invoker, "echo", []int{1}, &result))
+		assert.Equal(t, int64(9007199254740993), result)
+	}
+
+	// The least recently used result is forgotten.
+	for _, params := range [][]int{{2}, {1}, {3}, {1}, {2}} {
+		jsonrpc.CallWith(context.Background(), invoker, "echo", params, nil)
+	}
+	assert.Equal(t, []bool{false, true, false, true, false, true, false}, sent)
+}
@@ -0,0 +1,295 @@
+package jsonrpc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Router dispatches requests to the handler registered for their method. It
+// is the lightweight core of a SimpleServer, without the parsing, batching,
+// statistics or configuration, for transports and tests that already have a
+// RequestResponder and only need it answered:
+//
+//     router := jsonrpc.NewRouter(logCalls)
+//     router.Handle("subtract", subtract)
+//     router.Handle("sum", sum)
+//
+//     response := router.Dispatch(request)
+//
+// Routers can be nested with Route, so that independent modules register
+// their methods on their own router and are composed under a namespace. A
+// Router can also be registered on a server with Mount. It is safe for
+// concurrent use.
+type Router struct {
+	mutex      sync.RWMutex
+	handlers   map[string]RequestHandler
+	routes     map[string]route
+	middleware []Middleware
+	debug      bool
+}
+
+// route is a router nested under a namespace.
+type route struct {
+	router *Router
+
+	// handler removes the namespace and dispatches to the router, with the
+	// middleware of the parent.
+	handler RequestHandler
+}
+
+// NewRouter creates a Router that wraps every handler with the middleware. The
+// first middleware is the outermost.
+func NewRouter(middleware ...Middleware) *Router {
+	return &Router{
+		handlers:   map[string]RequestHandler{},
+		routes:     map[string]route{},
+		middleware: middleware,
+	}
+}
+
+// Use adds middleware to the router. Like a HandlerGroup, it only applies to
+// handlers that are registered after it has been added.
+func (router *Router) Use(middleware ...Middleware) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	router.middleware = append(router.middleware, middleware...)
+}
+
+// Handle registers (or replaces) the handler for a method. A nil handler
+// removes the method. Handle panics if the method is in a namespace that has
+// been routed to another router, as the method would be ambiguous.
+func (router *Router) Handle(method string, handler RequestHandler) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if namespace, _, ok := router.route(method); ok {
+		panic("Method " + method + " is in the namespace " + namespace +
+			" of another router.")
+	}
+
+	if handler == nil {
+		delete(router.handlers, method)
+		return
+	}
+
+	router.handlers[method] = Chain(router.middleware...)(handler)
+}
+
+// Route sends the requests for every method in the namespace to another
+// router, with the namespace removed from the method:
+//
+//     invoices := jsonrpc.NewRouter()
+//     invoices.Handle("create", createInvoice)
+//
+//     billing := jsonrpc.NewRouter(requireScope("billing"))
+//     billing.Route("invoice", invoices)
+//
+//     api := jsonrpc.NewRouter()
+//     api.Route("billing", billing)
+//
+//     // "billing.invoice.create" calls createInvoice with a request for
+//     // "create", after the requireScope middleware.
+//
+// The middleware of this router applies to the routed requests (before the
+// namespace is removed), and then the middleware of the other router. Methods
+// registered on the other router later are routed too.
+//
+// An error is returned if this router has a method in the namespace, or if
+// the namespace is inside (or contains) a namespace that has already been
+// routed, as a method could then belong to either. An error is also returned
+// if the other router is this one, or routes to it, as its methods would never
+// end.
+func (router *Router) Route(namespace string, other *Router) error {
+	if namespace == "" || strings.HasPrefix(namespace, ".") ||
+		strings.HasSuffix(namespace, ".") {
+		return errors.New("Namespace " + namespace + " is not valid.")
+	}
+
+	routeMutex.Lock()
+	defer routeMutex.Unlock()
+
+	if other.routesTo(router) {
+		return errors.New("Namespace " + namespace + " would route the router to itself.")
+	}
+
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	for existing := range router.routes {
+		if inNamespace(existing, namespace) || inNamespace(namespace, existing) ||
+			existing == namespace {
+			return errors.New("Namespace " + namespace + " collides with the namespace " +
+				existing + ".")
+		}
+	}
+
+	for method := range router.handlers {
+		if inNamespace(method, namespace) {
+			return errors.New("Namespace " + namespace + " collides with the method " +
+				method + ".")
+		}
+	}
+
+	router.routes[namespace] = route{
+		router: other,
+		handler: Chain(router.middleware...)(func(request RequestResponder) Response {
+			method := request.Method()[len(namespace)+1:]
+			return other.serve(&methodRequest{request, method})
+		}),
+	}
+
+	return nil
+}
+
+// routeMutex is held by Route, so that two routers cannot be routed to each
+// other at the same time without the cycle being noticed.
+var routeMutex sync.Mutex
+
+// routesTo reports whether the router is target or routes to it, directly or
+// through other routers. It must be called with routeMutex held.
+func (router *Router) routesTo(target *Router) bool {
+	if router == target {
+		return true
+	}
+
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	for _, route := range router.routes {
+		if route.router.routesTo(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inNamespace reports whether the name is inside the namespace.
+func inNamespace(name, namespace string) bool {
+	return len(name) > len(namespace) && name[len(namespace)] == '.' &&
+		strings.HasPrefix(name, namespace)
+}
+
+// route finds the namespace that the method is routed by and the router for
+// it. It must be called with the mutex held.
+func (router *Router) route(method string) (string, route, bool) {
+	if len(router.routes) == 0 {
+		return "", route{}, false
+	}
+
+	for i := strings.IndexByte(method, '.'); i >= 0; i = nextDot(method, i) {
+		if found, ok := router.routes[method[:i]]; ok {
+			return method[:i], found, true
+		}
+	}
+
+	return "", route{}, false
+}
+
+func nextDot(method string, i int) int {
+	next := strings.IndexByte(method[i+1:], '.')
+	if next < 0 {
+		return -1
+	}
+
+	return i + 1 + next
+}
+
+// Methods returns the names of the registered methods in alphabetical order,
+// including the methods of routed namespaces with their namespace.
+func (router *Router) Methods() []string {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	methods := make([]string, 0, len(router.handlers))
+	for method := range router.handlers {
+		methods = append(methods, method)
+	}
+
+	for namespace, route := range router.routes {
+		for _, method := range route.router.Methods() {
+			methods = append(methods, namespace+"."+method)
+		}
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// Dispatch calls the handler for the method of the request and returns its
+// response. A request that is not version 2.0 is an InvalidRequest, a method
+// without a handler is a MethodNotFound and a panic in the handler is an
+// InternalError.
+//
+// A response is returned for notifications as well; it is up to the caller to
+// discard it. Dispatch has the signature of a RequestHandler, so the router
+// can be used anywhere a handler is expected.
+func (router *Router) Dispatch(request RequestResponder) (response Response) {
+	if request.Version() != "2.0" {
+		return request.NewErrorResponse(InvalidRequest, "Version is not 2.0.")
+	}
+
+	router.mutex.RLock()
+	debugMode := router.debug
+	router.mutex.RUnlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			response = panicResponse(request, r, debugMode)
+		}
+	}()
+
+	return router.serve(request)
+}
+
+// serve calls the handler for the method of the request, which may be in a
+// routed namespace.
+func (router *Router) serve(request RequestResponder) Response {
+	router.mutex.RLock()
+	handler := router.handlers[request.Method()]
+	if handler == nil {
+		if _, route, ok := router.route(request.Method()); ok {
+			handler = route.handler
+		}
+	}
+	router.mutex.RUnlock()
+
+	if handler == nil {
+		return request.NewErrorResponse(MethodNotFound, "")
+	}
+
+	return handler(request)
+}
+
+// Mount registers every handler of the router on the server, replacing any
+// handler the server already has for the same method. The methods of routed
+// namespaces are registered with their namespace. Handlers that are added to
+// the router afterwards are not registered.
+func (router *Router) Mount(server Server) {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	for method, handler := range router.handlers {
+		server.SetHandler(method, handler)
+	}
+
+	for namespace := range router.routes {
+		for _, method := range router.routes[namespace].router.Methods() {
+			server.SetHandler(namespace+"."+method, router.serve)
+		}
+	}
+}
+
+// methodRequest is a request routed to another router, without its
+// namespace.
+type methodRequest struct {
+	RequestResponder
+	method string
+}
+
+func (request *methodRequest) Method() string {
+	return request.method
+}
@@ -0,0 +1,175 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRouter_Dispatch(t *testing.T) {
+	router := jsonrpc.NewRouter()
+	router.Handle("subtract", newTestServer().GetHandler("subtract"))
+	router.Handle("panic", func(jsonrpc.RequestResponder) jsonrpc.Response {
+		panic("uh-oh!")
+	})
+
+	for name, test := range map[string]struct {
+		request  jsonrpc.RequestResponder
+		response string
+	}{
+		"success": {
+			jsonrpc.NewRequestResponder("2.0", 1, "subtract", []interface{}{42.0, 23.0}),
+			`{"jsonrpc":"2.0","id":1,"result":19}`,
+		},
+		"not found": {
+			jsonrpc.NewRequestResponder("2.0", 2, "missing", nil),
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found"}}`,
+		},
+		"panic": {
+			jsonrpc.NewRequestResponder("2.0", 3, "panic", nil),
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32603,"message":"Internal error"}}`,
+		},
+	} {
+		assert.Equal(t, test.response, router.Dispatch(test.request).String(), name)
+	}
+
+	assert.Equal(t, []string{"panic", "subtract"}, router.Methods())
+
+	router.Handle("panic", nil)
+	assert.Equal(t, []string{"subtract"}, router.Methods())
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) jsonrpc.Middleware {
+		return func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+			return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+				calls = append(calls, name)
+				return next(request)
+			}
+		}
+	}
+
+	router := jsonrpc.NewRouter(trace("outer"))
+	router.Use(trace("inner"))
+	router.Handle("hello", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		calls = append(calls, "handler")
+		return request.NewSuccessResponse("hi")
+	})
+
+	router.Dispatch(jsonrpc.NewRequestResponder("2.0", 1, "hello", nil))
+	assert.Equal(t, []string{"outer", "inner", "handler"}, calls)
+}
+
+func TestRouter_Mount(t *testing.T) {
+	router := jsonrpc.NewRouter()
+	router.Handle("hello", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse("hi")
+	})
+
+	server := jsonrpc.NewSimpleServer()
+	router.Mount(server)
+
+	responses := server.Handle([]byte(`{"jsonrpc":"2.0","method":"hello","id":1}`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"hi"}`, responses[0].String())
+}
+
+func TestRouter_Route(t *testing.T) {
+	var calls []string
+	trace := func(name string) jsonrpc.Middleware {
+		return func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+			return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+				calls = append(calls, name+" "+request.Method())
+				return next(request)
+			}
+		}
+	}
+
+	invoices := jsonrpc.NewRouter(trace("invoices"))
+	invoices.Handle("create", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Method())
+	})
+
+	billing := jsonrpc.NewRouter(trace("billing"))
+	assert.NoError(t, billing.Route("invoice", invoices))
+
+	api := jsonrpc.NewRouter()
+	api.Handle("ping", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse("pong")
+	})
+	assert.NoError(t, api.Route("billing", billing))
+
+	response := api.Dispatch(jsonrpc.NewRequestResponder("2.0", 1, "billing.invoice.create", nil))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"create"}`, response.String())
+	assert.Equal(t, []string{"billing invoice.create", "invoices create"}, calls)
+
+	// Methods added later are routed too.
+	invoices.Handle("void", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		panic("uh-oh!")
+	})
+	assert.Equal(t, []string{"billing.invoice.create", "billing.invoice.void", "ping"},
+		api.Methods())
+
+	response = api.Dispatch(jsonrpc.NewRequestResponder("2.0", 2, "billing.invoice.void", nil))
+	assert.Equal(t, jsonrpc.InternalError, response.ErrorCode())
+
+	response = api.Dispatch(jsonrpc.NewRequestResponder("2.0", 3, "billing.refund", nil))
+	assert.Equal(t, jsonrpc.MethodNotFound, response.ErrorCode())
+
+	// Mount registers the routed methods with their namespace.
+	server := jsonrpc.NewSimpleServer()
+	api.Mount(server)
+
+	responses := server.Handle([]byte(`{"jsonrpc":"2.0","method":"billing.invoice.create","id":4}`))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":4,"result":"create"}`, responses[0].String())
+}
+
+func TestRouter_RouteCollisions(t *testing.T) {
+	router := jsonrpc.NewRouter()
+	router.Handle("billing.charge", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(nil)
+	})
+	assert.NoError(t, router.Route("user", jsonrpc.NewRouter()))
+
+	for namespace, expected := range map[string]string{
+		"billing":       "Namespace billing collides with the method billing.charge.",
+		"user":          "Namespace user collides with the namespace user.",
+		"user.admin":    "Namespace user.admin collides with the namespace user.",
+		"":              "Namespace  is not valid.",
+		"billing.":      "Namespace billing. is not valid.",
+		"billingcharge": "",
+	} {
+		err := router.Route(namespace, jsonrpc.NewRouter())
+		if expected == "" {
+			assert.NoError(t, err, namespace)
+		} else {
+			assert.EqualError(t, err, expected, namespace)
+		}
+	}
+
+	// "users" is not in the namespace "user".
+	router.Handle("users", nil)
+	assert.PanicsWithValue(t, "Method user.get is in the namespace user of another router.",
+		func() { router.Handle("user.get", nil) })
+}
+
+func TestRouter_RouteCycle(t *testing.T) {
+	api := jsonrpc.NewRouter()
+	billing := jsonrpc.NewRouter()
+	invoices := jsonrpc.NewRouter()
+	invoices.Handle("create", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(nil)
+	})
+	assert.NoError(t, api.Route("billing", billing))
+	assert.NoError(t, billing.Route("invoice", invoices))
+
+	assert.EqualError(t, api.Route("api", api),
+		"Namespace api would route the router to itself.")
+	assert.EqualError(t, invoices.Route("api", api),
+		"Namespace api would route the router to itself.")
+
+	// The same router may be routed more than once without a cycle.
+	assert.NoError(t, api.Route("invoice", invoices))
+	assert.Equal(t, []string{"billing.invoice.create", "invoice.create"}, api.Methods())
+}
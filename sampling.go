@@ -0,0 +1,150 @@
+package jsonrpc
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestSample is a request forwarded by a RequestSampler.
+type RequestSample struct {
+	Time   time.Time
+	Method string
+
+	// Params are the params decoded as JSON, after they have been redacted.
+	Params interface{}
+
+	// Notification is true if the request was a notification.
+	Notification bool
+
+	// Client is the ID of the ClientInfo of the request, if it is known.
+	Client string
+}
+
+// RequestSampler forwards a percentage of the requests a server receives to
+// an analytics sink, to find out which methods and shapes of params are
+// popular. It is added to a server as a hook:
+//
+//     sampler := jsonrpc.NewRequestSampler(0.01, jsonrpc.RedactKeys("password"),
+//         1000, func(sample jsonrpc.RequestSample) {
+//             analytics.Track(sample.Method, sample.Params)
+//         })
+//     defer sampler.Close()
+//
+//     server.OnRequest(sampler.Observe)
+//
+// The sink is called on a goroutine of the sampler, one sample at a time, so a
+// slow sink does not delay the requests. Samples are dropped (and counted by
+// Dropped) when the sink falls behind by more than the size of the buffer.
+type RequestSampler struct {
+	// Clock is used to timestamp samples. SystemClock is used if it is nil.
+	Clock Clock
+
+	redactor Redactor
+	sink     func(sample RequestSample)
+	rate     uint64
+	dropped  uint64
+
+	mutex   sync.RWMutex
+	closed  bool
+	samples chan RequestSample
+	done    chan struct{}
+}
+
+// NewRequestSampler creates a RequestSampler that forwards a fraction of the
+// requests (from 0 to 1) to the sink, with their params redacted by redactor
+// (which may be nil). Up to bufferSize samples wait for the sink.
+func NewRequestSampler(rate float64, redactor Redactor, bufferSize int,
+	sink func(sample RequestSample)) *RequestSampler {
+	sampler := &RequestSampler{
+		redactor: redactor,
+		sink:     sink,
+		samples:  make(chan RequestSample, bufferSize),
+		done:     make(chan struct{}),
+	}
+	sampler.SetRate(rate)
+
+	go sampler.run()
+
+	return sampler
+}
+
+// SetRate changes the fraction of requests that are sampled.
+func (sampler *RequestSampler) SetRate(rate float64) {
+	switch {
+	case rate < 0:
+		rate = 0
+
+	case rate > 1:
+		rate = 1
+	}
+
+	atomic.StoreUint64(&sampler.rate, uint64(rate*(1<<53)))
+}
+
+// Observe samples the request. It has the signature of a RequestHook.
+func (sampler *RequestSampler) Observe(request RequestResponder) {
+	rate := atomic.LoadUint64(&sampler.rate)
+	if rate == 0 || uint64(rand.Int63n(1<<53)) >= rate {
+		return
+	}
+
+	// The params are copied now, as the request may be reused once it has
+	// been handled. They are redacted by the goroutine of the sampler.
+	sample := RequestSample{
+		Time:         clockOrSystem(sampler.Clock).Now(),
+		Method:       request.Method(),
+		Params:       normalizeJSON(request.Params()),
+		Notification: request.IsNotification(),
+	}
+
+	if client := ClientInfoFromRequest(request); client != nil {
+		sample.Client = client.ID()
+	}
+
+	sampler.mutex.RLock()
+	defer sampler.mutex.RUnlock()
+
+	if sampler.closed {
+		return
+	}
+
+	select {
+	case sampler.samples <- sample:
+
+	default:
+		atomic.AddUint64(&sampler.dropped, 1)
+	}
+}
+
+// Dropped returns the number of samples that were dropped because the sink
+// was too slow.
+func (sampler *RequestSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&sampler.dropped)
+}
+
+// Close stops sampling and waits for the samples that are buffered to be
+// given to the sink.
+func (sampler *RequestSampler) Close() {
+	sampler.mutex.Lock()
+	if !sampler.closed {
+		sampler.closed = true
+		close(sampler.samples)
+	}
+	sampler.mutex.Unlock()
+
+	<-sampler.done
+}
+
+func (sampler *RequestSampler) run() {
+	defer close(sampler.done)
+
+	for sample := range sampler.samples {
+		if sampler.redactor != nil {
+			sample.Params = sampler.redactor.Redact(sample.Params)
+		}
+
+		sampler.sink(sample)
+	}
+}
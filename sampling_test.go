@@ -0,0 +1,86 @@
+package jsonrpc_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestRequestSampler(t *testing.T) {
+	var samples []jsonrpc.RequestSample
+	sampler := jsonrpc.NewRequestSampler(1, jsonrpc.RedactKeys("password"), 10,
+		func(sample jsonrpc.RequestSample) {
+			samples = append(samples, sample)
+		})
+	sampler.Clock = jsonrpc.NewFakeClock(epoch)
+
+	server := newTestServer()
+	server.SetHandler("login", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(true)
+	})
+	server.OnRequest(sampler.Observe)
+
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"login",` +
+		`"params":{"user":"bob","password":"secret"},"id":1}`))
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"notify_hello","params":[7]}`))
+
+	// Nothing is sampled without a rate, or once the sampler is closed.
+	sampler.SetRate(0)
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":2}`))
+
+	sampler.Close()
+	sampler.SetRate(1)
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":3}`))
+
+	if assert.Len(t, samples, 2) {
+		assert.Equal(t, "login", samples[0].Method)
+		assert.Equal(t, map[string]interface{}{"user": "bob", "password": jsonrpc.Redacted},
+			samples[0].Params)
+		assert.False(t, samples[0].Notification)
+		assert.Equal(t, epoch, samples[0].Time)
+
+		assert.Equal(t, "notify_hello", samples[1].Method)
+		assert.Equal(t, []interface{}{7.0}, samples[1].Params)
+		assert.True(t, samples[1].Notification)
+	}
+	assert.Equal(t, uint64(0), sampler.Dropped())
+}
+
+func TestRequestSampler_Rate(t *testing.T) {
+	sampled := 0
+	sampler := jsonrpc.NewRequestSampler(0.5, nil, 1000, func(jsonrpc.RequestSample) {
+		sampled++
+	})
+
+	request := jsonrpc.NewRequestResponder("2.0", 1, "sum", []int{1, 2})
+	for i := 0; i < 1000; i++ {
+		sampler.Observe(request)
+	}
+	sampler.Close()
+
+	assert.True(t, sampled > 400 && sampled < 600, "sampled: %d", sampled)
+}
+
+func TestRequestSampler_Dropped(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	started := make(chan struct{})
+	sampler := jsonrpc.NewRequestSampler(1, nil, 2, func(jsonrpc.RequestSample) {
+		once.Do(func() { close(started) })
+		<-release
+	})
+
+	// The first sample is given to the sink, then two are buffered.
+	request := jsonrpc.NewRequestResponder("2.0", 1, "sum", []int{1, 2})
+	sampler.Observe(request)
+	<-started
+	for i := 0; i < 5; i++ {
+		sampler.Observe(request)
+	}
+
+	assert.Equal(t, uint64(3), sampler.Dropped())
+	close(release)
+	sampler.Close()
+}
@@ -1,7 +1,10 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -9,6 +12,10 @@ import (
 // RequestHandler is a function that is able to respond to a server request.
 type RequestHandler func(RequestResponder) Response
 
+// Middleware wraps a RequestHandler so that behaviour can be added before and
+// after the handler is called. The returned handler is what will be registered.
+type Middleware func(RequestHandler) RequestHandler
+
 // Server inteface
 type Server interface {
 	SetHandler(methodName string, handler RequestHandler)
@@ -19,8 +26,113 @@ type Server interface {
 }
 
 // SimpleServer struct
+//
+// A SimpleServer is safe for concurrent use. Handlers may be registered,
+// replaced and inspected while requests are being handled, and any number of
+// goroutines may call Handle, HandleWithState and HandleRequest at once.
+//
+// The invariants that make this true are:
+//
+//   - Every map and every field that can be changed by a Set* method is only
+//     read or written while holding mutex.
+//   - The stat counters are only ever touched through sync/atomic.
+//   - The mutex is never held while a handler (or any other user supplied
+//     function, such as a Signer or Clock) is running, so a handler is free to
+//     call back into the server.
+//
+// The State passed to HandleWithState is shared by every request in a batch
+// and must be treated as read only by handlers. Use WithState to derive a
+// request with an extra value instead.
 type SimpleServer struct {
+	mutex sync.RWMutex
+
 	requestHandlers map[string]RequestHandler
+	methodInfo      map[string]MethodInfo
+
+	// See SetStreamHandler
+	streamMethods map[string]bool
+
+	// See SetNextHandler
+	nextHandlers map[string]RequestHandler
+
+	// See SetLenient
+	lenient bool
+
+	// See SetDebug
+	debug bool
+
+	// See SetBatchWorkers
+	batchWorkers int
+
+	// See SetBatchOrderPreserving
+	batchOrderPreserving bool
+
+	// See SetRequestLimits
+	requestLimits RequestLimits
+
+	// See OnRequest, OnResponse and OnError
+	hooks *serverHooks
+
+	// See AddValidator
+	validators []Validator
+
+	// See SetMemoryBudget
+	memoryBudget *MemoryBudget
+
+	// See ApplyConfig
+	disabledMethods map[string]bool
+
+	// See SetFallback
+	fallback RequestHandler
+
+	// See SetLogger
+	logger *slog.Logger
+
+	// See SetLogLevel
+	logLevels map[string]slog.Level
+
+	// See SetIDPolicy
+	idPolicy IDPolicy
+
+	// See SetStateForwarder
+	stateForwarder *StateForwarder
+
+	// See SetMethodLimits and SetDefaultTimeout
+	methodLimits   map[string]MethodLimits
+	defaultTimeout time.Duration
+
+	// See Use. interceptor is the middleware chained together, or nil.
+	middleware  []Middleware
+	interceptor Middleware
+
+	// See DeprecatedCalls
+	deprecatedCalls map[string]uint64
+
+	// See SetResponseSigner
+	responseSigner Signer
+
+	// See SetExchangeBuffer and SetConnectionExchangeBuffers. connections
+	// counts the connections that have had a buffer.
+	exchangeBuffer      *ExchangeBuffer
+	newConnectionBuffer func() *ExchangeBuffer
+	connectionBuffers   map[*ExchangeBuffer]connectionBuffer
+	connections         uint64
+
+	// See SetSizeBuckets. sizeBounds is written while holding both mutex and
+	// sizeMutex so it may be read while holding either of them.
+	sizeBounds  []int
+	sizeMutex   sync.Mutex
+	methodSizes map[string]*MethodSizes
+
+	// See SetClock and SetIDGenerator
+	clock       Clock
+	idGenerator IDGenerator
+
+	// See Go and Wait
+	goroutines goroutineGroup
+
+	// See Shutdown
+	requests goroutineGroup
 
 	// See StatReporter
 	totalPayloads             uint64
@@ -35,11 +147,28 @@ type SimpleServer struct {
 
 // SetHandler will register (or replace) a handler for a method.
 func (server *SimpleServer) SetHandler(methodName string, handler RequestHandler) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
 	server.requestHandlers[methodName] = handler
+	delete(server.streamMethods, methodName)
+}
+
+// SetLenient controls if non-standard extensions (see WithExtension) will be
+// sent with responses. By default the server is strict and will remove any
+// extensions that a handler adds to its response.
+func (server *SimpleServer) SetLenient(lenient bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.lenient = lenient
 }
 
 // GetHandler resolv handler
 func (server *SimpleServer) GetHandler(methodName string) RequestHandler {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
 	return server.requestHandlers[methodName]
 }
 
@@ -75,35 +204,104 @@ func (server *SimpleServer) GetHandler(methodName string) RequestHandler {
 // The "Batch Requests" second explains this in more detail.
 
 // HandleRequest handler request
-func (server *SimpleServer) HandleRequest(request RequestResponder) (responses Responses) {
-	server.totalPayloads++
+func (server *SimpleServer) HandleRequest(request RequestResponder) Responses {
+	if !server.requests.tryAdd() {
+		atomic.AddUint64(&server.totalPayloads, 1)
+		return server.rejectRequest(request)
+	}
+	defer server.requests.done()
+
+	return server.handleRequest(request)
+}
+
+func (server *SimpleServer) handleRequest(request RequestResponder) (responses Responses) {
+	atomic.AddUint64(&server.totalPayloads, 1)
+
+	track := TrackFromRequest(request)
+
+	// Take a consistent view of the configuration so that the lock is not
+	// held while the handler runs.
+	server.mutex.RLock()
+	handler := server.requestHandlers[request.Method()]
+	if next := server.nextHandlers[request.Method()]; next != nil && track == NextTrack {
+		handler = next
+	}
+	info, hasInfo := server.methodInfo[request.Method()]
+	lenient := server.lenient
+	clock := server.clock
+	exchangeBuffer := server.exchangeBuffer
+	trackSizes := server.sizeBounds != nil
+	signing := server.responseSigner != nil
+	disabled := server.disabledMethods[request.Method()]
+	if handler == nil {
+		handler = server.fallback
+	}
+	idPolicy := server.idPolicy
+	limits, hasLimits := server.methodLimits[request.Method()]
+	if limits.MaxTime == 0 && server.defaultTimeout > 0 {
+		limits.MaxTime, hasLimits = server.defaultTimeout, true
+	}
+	interceptor := server.interceptor
+	debugMode := server.debug
+	logging := server.logger != nil
+	hooks := server.hooks
+	validators := server.validators
+	server.mutex.RUnlock()
+
+	hooks.request(request)
+
+	var received time.Time
+	if logging {
+		received = clock.Now()
+		server.logMethod(LevelTrace, "Request received", request.Method(),
+			ClientInfoFromRequest(request), "id", request.ID())
+	}
 
 	responses = make(Responses, 0)
 	var response Response
 
-	// Always recover from a panic and send it back as an error.
-	defer func(id interface{}) {
+	// Always recover from a panic and send it back as an InternalError.
+	defer func(notification bool) {
 		if r := recover(); r != nil {
-			response = request.NewErrorResponse(ServerError, "")
+			response = panicResponse(request, r, debugMode)
+			server.logMethod(slog.LevelError, "Handler panicked", request.Method(),
+				ClientInfoFromRequest(request), "panic", r)
+
+			if exchangeBuffer != nil {
+				exchangeBuffer.dumpPanic(request, r)
+			}
+			if buffer := ExchangeBufferFromRequest(request); buffer != nil {
+				buffer.dumpPanic(request, r)
+			}
 		}
 
-		// Track responses.
-		if id == nil {
+		if logging {
+			server.logMethod(slog.LevelDebug, "Request handled", request.Method(),
+				ClientInfoFromRequest(request), "id", request.ID(),
+				"code", response.ErrorCode(), "duration", clock.Now().Sub(received))
+		}
+
+		// Track responses. Notifications do not receive a response.
+		if notification {
 			if response.ErrorCode() == Success {
-				server.totalSuccessNotifications++
+				atomic.AddUint64(&server.totalSuccessNotifications, 1)
 			} else {
-				server.totalErrorNotifications++
+				atomic.AddUint64(&server.totalErrorNotifications, 1)
 			}
+			hooks.observe(request, response, false)
+			return
+		}
+
+		if response.ErrorCode() == Success {
+			atomic.AddUint64(&server.totalSuccessResponses, 1)
 		} else {
-			if response.ErrorCode() == Success {
-				server.totalSuccessResponses++
-			} else {
-				server.totalErrorResponses++
-			}
+			atomic.AddUint64(&server.totalErrorResponses, 1)
 		}
 
-		appendResponses(&responses, response)
-	}(request.ID())
+		response = server.signResponse(response)
+		hooks.observe(request, response, true)
+		responses = append(responses, response)
+	}(request.IsNotification())
 
 	// We only support 2.0 right now.
 	if request.Version() != "2.0" {
@@ -111,13 +309,41 @@ func (server *SimpleServer) HandleRequest(request RequestResponder) (responses R
 		return
 	}
 
-	handler := server.requestHandlers[request.Method()]
+	if id, message := idPolicy.apply(request.ID(), request.IsNotification()); message != "" {
+		response = request.NewErrorResponse(InvalidRequest, message)
+		return
+	} else if id != request.ID() {
+		request = &idRequest{request, id}
+	}
+
 	if handler == nil {
 		response = request.NewErrorResponse(MethodNotFound, "")
 		return
 	}
 
-	server.totalRequests++
+	if disabled {
+		response = request.NewErrorResponseWithData(MethodNotFound, "",
+			NewErrorDetails(MethodDisabledErrorType))
+		return
+	}
+
+	if hasInfo && info.isDeprecated() {
+		if !info.Sunset.IsZero() && !clock.Now().Before(info.Sunset) {
+			response = request.NewErrorResponseWithData(MethodRetired, "Method retired",
+				NewErrorDetails(MethodRetiredErrorType).WithDetail(info.Deprecated))
+			return
+		}
+
+		server.mutex.Lock()
+		server.deprecatedCalls[request.Method()]++
+		server.mutex.Unlock()
+	}
+
+	if response = validate(validators, request); response != nil {
+		return
+	}
+
+	atomic.AddUint64(&server.totalRequests, 1)
 
 	defer func() {
 		// I know this seems a little crazy, but it's the correct way to
@@ -126,36 +352,71 @@ func (server *SimpleServer) HandleRequest(request RequestResponder) (responses R
 	}()
 
 	atomic.AddUint64(&server.currentActiveRequests, 1)
-	response = handler(request)
+
+	// Goroutines started by the handler with Go end with the request.
+	ctx, cancel := context.WithCancel(ContextFromRequest(request))
+	defer cancel()
+	if hasLimits {
+		handler = limits.wrap(handler)
+	}
+	if interceptor != nil {
+		handler = interceptor(handler)
+	}
+	response = handler(WithContext(request, context.WithValue(ctx, goScopeKey{}, server)))
+
+	if trackSizes || signing {
+		response = encodeResult(request, response)
+	}
+
+	if trackSizes {
+		server.observeSizes(request, response)
+	}
+
+	if !lenient {
+		response = withoutExtensions(response)
+	}
 
 	return
 }
 
-func (server *SimpleServer) handleSingle(jsonRequest []byte, isPartOfBatch bool, state State) Responses {
+func (server *SimpleServer) handleSingle(jsonRequest []byte, isPartOfBatch bool,
+	state State, a *arena, accepted bool) Responses {
+	server.mutex.RLock()
+	forwarder := server.stateForwarder
+	var rawParams func(method string) bool
+	if len(server.streamMethods) > 0 {
+		rawParams = server.isStreamMethod
+	}
+	server.mutex.RUnlock()
+
+	if forwarder != nil {
+		state = forwarder.Restore(jsonRequest, state)
+	}
+
 	request, id, errCode, errMessage :=
-		newRequestResponderFromJSON(jsonRequest, isPartOfBatch, state)
+		decodeRequest(jsonRequest, isPartOfBatch, state, a, rawParams)
 
 	if errCode != Success {
-		server.totalErrorResponses++
+		atomic.AddUint64(&server.totalErrorResponses, 1)
+		info, _ := state[clientInfoStateKey].(*ClientInfo)
+		server.log(slog.LevelWarn, "Invalid request", info, "code", errCode,
+			"error", errMessage)
+
+		// An invalid request is answered even if it has no id, as it cannot be
+		// known if it was meant to be a notification.
+		return Responses{server.respond(nil, a.newErrorResponse(id, errCode, errMessage, nil))}
+	}
 
-		responses := Responses{}
-		appendResponses(&responses, NewErrorResponse(id, errCode, errMessage))
-		return responses
+	if !accepted {
+		return server.rejectRequest(request)
 	}
 
-	// HandleRequest will increment the totalPayloads because it is part of the
+	// handleRequest will increment the totalPayloads because it is part of the
 	// public API. However, here we are calling it from a private API so correct
 	// its value.
-	server.totalPayloads -= 1
+	atomic.AddUint64(&server.totalPayloads, ^uint64(0))
 
-	return server.HandleRequest(request)
-}
-
-func appendResponses(responses *Responses, response Response) {
-	// Notifications do not receive results.
-	if response.ID() != nil {
-		*responses = append(*responses, response)
-	}
+	return server.handleRequest(request)
 }
 
 // Batch Requests:
@@ -176,16 +437,33 @@ func appendResponses(responses *Responses, response Response) {
 //     // Hello, Bob
 //     // Hello, Jane
 //
-// You will get a Response for every non-notification (every request with a
-// non-nil ID). The order of the responses is not predictable against the order
-// of the requests. You should use the response IDs to correlate results in a
-// batch result.
+// You will get a Response for every non-notification (every request with an
+// ID). They are in the same order as the requests, unless the batch is handled
+// concurrently (see SetBatchWorkers and SetBatchOrderPreserving), so clients
+// should use the response IDs to correlate results in a batch result.
 //
 // It is also important to note that the order in which the requests are
 // processed (whether single requests or batch) in a are non-deterministic and
-// should be considered to be run all at the same time.
+// should be considered to be run all at the same time. See SetBatchWorkers.
 func (server *SimpleServer) HandleWithState(jsonRequest []byte, state State) Responses {
-	server.totalPayloads++
+	atomic.AddUint64(&server.totalPayloads, 1)
+
+	// Every member of a batch that is accepted is handled, even if Shutdown is
+	// called in the meantime.
+	accepted := server.requests.tryAdd()
+	if accepted {
+		defer server.requests.done()
+	}
+
+	response, release := server.checkRequestLimits(jsonRequest, state, nil)
+	if response != nil {
+		return Responses{response}
+	}
+	if release != nil {
+		var scope *payloadScope
+		state, scope = withPayloadScope(state, release)
+		defer scope.finish()
+	}
 
 	responses := make(Responses, 0)
 
@@ -197,39 +475,45 @@ func (server *SimpleServer) HandleWithState(jsonRequest []byte, state State) Res
 		// care and happily return an empty array of results back but the
 		// JSON-RPC spec says this is an invalid request.
 		if len(batchRequest) == 0 {
-			server.totalErrorResponses++
+			atomic.AddUint64(&server.totalErrorResponses, 1)
+
+			return Responses{server.respond(nil, NewErrorResponse(nil,
+				InvalidRequest, "Batch is empty."))}
+		}
 
-			return Responses{NewErrorResponse(nil, InvalidRequest,
-				"Batch is empty.")}
+		server.mutex.RLock()
+		executor := BatchExecutor{
+			Workers:         server.batchWorkers,
+			OrderPreserving: server.batchOrderPreserving,
 		}
+		server.mutex.RUnlock()
 
 		// Validate each of the requests because some of them may be good and
-		// some invalid.
-		for _, probableRequest := range batchRequest {
+		// some invalid. The members may be handled concurrently (see
+		// SetBatchWorkers).
+		assembler := NewBatchAssembler(len(batchRequest), executor.OrderPreserving)
+		executor.run(len(batchRequest), func(i int) {
 			// We have to marshall each request back to JSON, then treat each
 			// one as an independent request.
-			rawMessage, err := json.Marshal(probableRequest)
+			rawMessage, err := json.Marshal(batchRequest[i])
 			if err != nil {
 				// This condition should not be possible since we have already
 				// unmarshalled this object once. Still, better to be safe than
 				// sorry.
-				response := NewErrorResponse(nil, ParseError, err.Error())
-				responses = append(responses, response)
-				continue
+				assembler.Add(i, NewErrorResponse(nil, ParseError, err.Error()))
+				return
 			}
 
-			results := server.handleSingle(rawMessage, true, state)
-			for _, response := range results {
-				appendResponses(&responses, response)
-			}
-		}
+			assembler.Add(i, server.handleSingle(rawMessage, true, state, nil, accepted)...)
+		})
+
+		responses = assembler.Responses()
 	} else {
-		results := server.handleSingle(jsonRequest, false, state)
-		for _, response := range results {
-			appendResponses(&responses, response)
-		}
+		responses = server.handleSingle(jsonRequest, false, state, nil, accepted)
 	}
 
+	server.recordExchange(state, jsonRequest, responses)
+
 	return responses
 }
 
@@ -251,6 +535,12 @@ func (server *SimpleServer) Handle(jsonRequest []byte) Responses {
 func NewSimpleServer() *SimpleServer {
 	return &SimpleServer{
 		requestHandlers: make(map[string]RequestHandler),
+		methodInfo:      make(map[string]MethodInfo),
+		streamMethods:   make(map[string]bool),
+		nextHandlers:    make(map[string]RequestHandler),
+		methodLimits:    make(map[string]MethodLimits),
+		deprecatedCalls: make(map[string]uint64),
 		startTime:       time.Now(),
+		clock:           SystemClock,
 	}
 }
@@ -418,6 +418,20 @@ var specTests = map[string]struct {
 		statsSuccessNotifications: 0,
 		statsErrorNotifications:   1,
 	},
+	// A request with a null id is not a notification. Its response has a
+	// null id.
+	"rpc call with a null id": {
+		j: `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": null}`,
+		r: jsonrpc.Responses{
+			jsonrpc.NewSuccessResponse(nil, 19.0),
+		},
+		statsPayloads:             1,
+		statsRequests:             1,
+		statsSuccess:              1,
+		statsError:                0,
+		statsSuccessNotifications: 0,
+		statsErrorNotifications:   0,
+	},
 	"a panic notification": {
 		j:                         `{"jsonrpc": "2.0", "method": "panic"}`,
 		r:                         jsonrpc.Responses{},
@@ -434,9 +448,9 @@ var specTests = map[string]struct {
 	// Instead a generic Internal error will do.
 	"recover from panic": {
 		j: `{"jsonrpc": "2.0", "method": "panic", "id": 2}`,
-		// `{"jsonrpc": "2.0", "error": {"code": -32000, "message": "Server error"}, "id": 2}`,
+		// `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "Internal error"}, "id": 2}`,
 		r: jsonrpc.Responses{
-			jsonrpc.NewErrorResponse(float64(2), jsonrpc.ServerError, ""),
+			jsonrpc.NewErrorResponse(float64(2), jsonrpc.InternalError, ""),
 		},
 		statsPayloads:             1,
 		statsRequests:             1,
@@ -450,13 +464,11 @@ var specTests = map[string]struct {
 func TestJSONRPCSpecification(t *testing.T) {
 	for testName, test := range specTests {
 		t.Run(testName, func(t *testing.T) {
-			if testName == "rpc call with positional parameters 1" {
-				server := newTestServer()
-				responses := server.Handle([]byte(test.j))
+			server := newTestServer()
+			responses := server.Handle([]byte(test.j))
 
-				if !reflect.DeepEqual(responses, test.r) {
-					t.Errorf("TestJSONRPCSpecification:\n%v\n%v", responses, test.r)
-				}
+			if !reflect.DeepEqual(responses, test.r) {
+				t.Errorf("TestJSONRPCSpecification:\n%v\n%v", responses, test.r)
 			}
 		})
 	}
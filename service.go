@@ -0,0 +1,142 @@
+package jsonrpc
+
+import (
+	"errors"
+	"reflect"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	requestType = reflect.TypeOf((*RequestResponder)(nil)).Elem()
+)
+
+// Register exposes the exported methods of receiver as JSON-RPC methods named
+// "Type.Method", in the same way as net/rpc:
+//
+//     type Arith struct{}
+//
+//     func (*Arith) Multiply(args Args) (int, error) {
+//         return args.A * args.B, nil
+//     }
+//
+//     err := server.Register(&Arith{})
+//     // {"jsonrpc":"2.0","method":"Arith.Multiply","params":{"A":7,"B":8},"id":1}
+//
+// A method may take the request (a RequestResponder) as its first argument,
+// followed by at most one argument that the params are decoded into with
+// BindParams. A single positional param is unwrapped when the argument is not
+// a slice or an array. The method must return an error, optionally preceded
+// by a result that is sent back when the error is nil. An *RPCError is sent
+// with its code, message and data, and any other error is sent as a
+// ServerError. Methods that do not have a suitable signature are ignored.
+func (server *SimpleServer) Register(receiver interface{}) error {
+	t := reflect.TypeOf(receiver)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return errors.New("Receiver must be a named type.")
+	}
+
+	return server.RegisterName(t.Name(), receiver)
+}
+
+// RegisterName is like Register, but the methods are named "name.Method".
+func (server *SimpleServer) RegisterName(name string, receiver interface{}) error {
+	value := reflect.ValueOf(receiver)
+	if !value.IsValid() {
+		return errors.New("Receiver must not be nil.")
+	}
+
+	handlers := map[string]RequestHandler{}
+
+	for i := 0; i < value.NumMethod(); i++ {
+		method := value.Type().Method(i)
+		if handler := serviceHandler(value.Method(i)); handler != nil && method.PkgPath == "" {
+			handlers[name+"."+method.Name] = handler
+		}
+	}
+
+	if len(handlers) == 0 {
+		return errors.New(name + " has no exported methods of a suitable type.")
+	}
+
+	for methodName, handler := range handlers {
+		server.SetHandler(methodName, handler)
+	}
+
+	return nil
+}
+
+// serviceHandler returns a handler that calls the method, or nil if it does
+// not have a suitable signature.
+func serviceHandler(method reflect.Value) RequestHandler {
+	t := method.Type()
+
+	args := 0
+	takesRequest := t.NumIn() > 0 && t.In(0) == requestType
+	if takesRequest {
+		args++
+	}
+
+	var paramsType reflect.Type
+	switch t.NumIn() - args {
+	case 0:
+
+	case 1:
+		paramsType = t.In(args)
+
+	default:
+		return nil
+	}
+
+	if t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		return nil
+	}
+
+	return func(request RequestResponder) Response {
+		in := make([]reflect.Value, 0, 2)
+		if takesRequest {
+			in = append(in, reflect.ValueOf(request))
+		}
+
+		if paramsType != nil {
+			target := paramsType
+			if target.Kind() == reflect.Ptr {
+				target = target.Elem()
+			}
+
+			params := request.Params()
+			if positional, ok := params.([]interface{}); ok && len(positional) == 1 &&
+				target.Kind() != reflect.Slice && target.Kind() != reflect.Array {
+				params = positional[0]
+			}
+
+			arg := reflect.New(target)
+			if response := bindParams(request, params, arg.Interface()); response != nil {
+				return response
+			}
+
+			if paramsType.Kind() != reflect.Ptr {
+				arg = arg.Elem()
+			}
+			in = append(in, arg)
+		}
+
+		out := method.Call(in)
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			var rpcErr *RPCError
+			if errors.As(err, &rpcErr) {
+				return request.NewErrorResponseWithData(rpcErr.Code, rpcErr.Message, rpcErr.Data)
+			}
+
+			return request.NewServerErrorResponse(err)
+		}
+
+		if len(out) == 1 {
+			return request.NewSuccessResponse(nil)
+		}
+
+		return request.NewSuccessResponse(out[0].Interface())
+	}
+}
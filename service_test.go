@@ -0,0 +1,85 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type Arith struct{}
+
+type ArithArgs struct {
+	A, B int
+}
+
+func (arith *Arith) Multiply(args ArithArgs) (int, error) {
+	return args.A * args.B, nil
+}
+
+func (arith *Arith) Divide(args *ArithArgs) (float64, error) {
+	if args.B == 0 {
+		return 0, &jsonrpc.RPCError{Code: 100, Message: "Division by zero"}
+	}
+
+	return float64(args.A) / float64(args.B), nil
+}
+
+func (arith *Arith) Sum(numbers []int) (int, error) {
+	total := 0
+	for _, n := range numbers {
+		total += n
+	}
+
+	return total, nil
+}
+
+func (arith *Arith) Negate(n int) (int, error) {
+	return -n, nil
+}
+
+func (arith *Arith) Echo(request jsonrpc.RequestResponder, s string) (interface{}, error) {
+	return []interface{}{request.ID(), s}, nil
+}
+
+func (arith *Arith) Reset() error {
+	return errors.New("Not allowed.")
+}
+
+// Not registered: the signature is not suitable.
+func (arith *Arith) Add(a, b int) int {
+	return a + b
+}
+
+func TestSimpleServer_Register(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	assert.NoError(t, server.Register(&Arith{}))
+	assert.Nil(t, server.GetHandler("Arith.Add"))
+
+	for request, response := range map[string]string{
+		`{"jsonrpc":"2.0","method":"Arith.Multiply","params":{"A":7,"B":8},"id":1}`: `{"jsonrpc":"2.0","id":1,"result":56}`,
+		`{"jsonrpc":"2.0","method":"Arith.Divide","params":{"A":1,"B":4},"id":2}`:   `{"jsonrpc":"2.0","id":2,"result":0.25}`,
+		`{"jsonrpc":"2.0","method":"Arith.Divide","params":{"A":1,"B":0},"id":3}`:   `{"jsonrpc":"2.0","id":3,"error":{"code":100,"message":"Division by zero"}}`,
+		`{"jsonrpc":"2.0","method":"Arith.Sum","params":[1,2,3],"id":4}`:            `{"jsonrpc":"2.0","id":4,"result":6}`,
+		`{"jsonrpc":"2.0","method":"Arith.Negate","params":[5],"id":5}`:             `{"jsonrpc":"2.0","id":5,"result":-5}`,
+		`{"jsonrpc":"2.0","method":"Arith.Echo","params":["a"],"id":6}`:             `{"jsonrpc":"2.0","id":6,"result":[6,"a"]}`,
+		`{"jsonrpc":"2.0","method":"Arith.Reset","id":7}`:                           `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"Not allowed."}}`,
+	} {
+		assert.Equal(t, response, server.Handle([]byte(request))[0].String(), request)
+	}
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"Arith.Negate","params":"x","id":8}`))[0]
+	assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+}
+
+func TestSimpleServer_RegisterName(t *testing.T) {
+	server := jsonrpc.NewSimpleServer()
+	assert.NoError(t, server.RegisterName("math", &Arith{}))
+	assert.NotNil(t, server.GetHandler("math.Multiply"))
+
+	assert.EqualError(t, server.Register(nil), "Receiver must be a named type.")
+	assert.EqualError(t, server.Register(struct{}{}), "Receiver must be a named type.")
+	assert.EqualError(t, server.Register(ArithArgs{}),
+		"ArithArgs has no exported methods of a suitable type.")
+}
@@ -0,0 +1,146 @@
+package jsonrpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShuttingDownErrorType is the ErrorDetails type of the ServerError sent for
+// requests that arrive after Shutdown has been called. They may be retried,
+// such as on another instance of the server.
+const ShuttingDownErrorType = "urn:jsonrpc:error:shutting-down"
+
+// ShutdownMethod is the method of the notification that is sent to the
+// clients of a connection before it is closed by a shutdown. Its params are a
+// ShutdownNotice.
+const ShutdownMethod = "rpc.shutdown"
+
+// ShutdownNotice tells the clients of a server that is shutting down why, and
+// when they should reconnect, so they can move to another instance before
+// their connection is closed:
+//
+//     tcpServer.ShutdownNotice = jsonrpc.NewShutdownNotice("Deploying.", 5*time.Second)
+//
+// A client receives it as a notification, see TCPClient.SetNotificationHandler.
+type ShutdownNotice struct {
+	Reason string `json:"reason,omitempty"`
+
+	// RetryAfter is the number of seconds the client should wait before it
+	// reconnects. Zero means it may reconnect straight away.
+	RetryAfter float64 `json:"retryAfter,omitempty"`
+}
+
+// NewShutdownNotice creates a ShutdownNotice.
+func NewShutdownNotice(reason string, retryAfter time.Duration) *ShutdownNotice {
+	return &ShutdownNotice{Reason: reason, RetryAfter: retryAfter.Seconds()}
+}
+
+// send writes the notice to every framer in parallel, so a slow connection
+// does not hold up the others. It returns once they have all been written, or
+// the error of ctx if it is done first.
+func (notice *ShutdownNotice) send(ctx context.Context, framers []Framer) error {
+	if notice == nil || len(framers) == 0 {
+		return nil
+	}
+
+	frame := NewRequestResponder("2.0", nil, ShutdownMethod, notice).Bytes()
+
+	var wg sync.WaitGroup
+	wg.Add(len(framers))
+	for _, framer := range framers {
+		go func(framer Framer) {
+			defer wg.Done()
+			framer.WriteFrame(frame)
+		}(framer)
+	}
+
+	written := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops the server from accepting new requests and waits for the
+// requests being handled (including every member of a batch that was
+// accepted) and the goroutines they started with Go to finish, or for ctx to
+// be done, in which case its error is returned.
+//
+// Requests that arrive after Shutdown has been called are answered with a
+// ServerError that has the ShuttingDownErrorType. Transports should be shut
+// down first (see TCPServer.Shutdown and http.Server.Shutdown) so that the
+// responses of the requests being handled can still be sent.
+func (server *SimpleServer) Shutdown(ctx context.Context) error {
+	server.requests.stop()
+
+	select {
+	case <-server.requests.wait():
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return server.Wait(ctx)
+}
+
+// rejectRequest answers a request that arrived after Shutdown.
+func (server *SimpleServer) rejectRequest(request RequestResponder) Responses {
+	response := request.NewErrorResponseWithData(ServerError, "Server is shutting down",
+		NewErrorDetails(ShuttingDownErrorType).WithRetryable(true))
+
+	if request.IsNotification() {
+		atomic.AddUint64(&server.totalErrorNotifications, 1)
+
+		server.mutex.RLock()
+		hooks := server.hooks
+		server.mutex.RUnlock()
+
+		hooks.observe(request, response, false)
+
+		return Responses{}
+	}
+
+	atomic.AddUint64(&server.totalErrorResponses, 1)
+
+	return Responses{server.respond(request, response)}
+}
+
+// drainingFramer stops reading once draining is closed. An error while
+// draining (such as from a read deadline set to interrupt the read) is the end
+// of the stream.
+type drainingFramer struct {
+	Framer
+	draining <-chan struct{}
+}
+
+func (framer *drainingFramer) ReadFrame() ([]byte, error) {
+	select {
+	case <-framer.draining:
+		return nil, io.EOF
+
+	default:
+	}
+
+	frame, err := framer.Framer.ReadFrame()
+	if err != nil {
+		select {
+		case <-framer.draining:
+			return nil, io.EOF
+
+		default:
+		}
+	}
+
+	return frame, err
+}
@@ -0,0 +1,200 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// newHangingServer has a "hang" method that blocks until release is closed.
+func newHangingServer() (server *jsonrpc.SimpleServer, started, release chan struct{}) {
+	server = newTestServer()
+	started = make(chan struct{}, 1)
+	release = make(chan struct{})
+	server.SetHandler("hang", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		started <- struct{}{}
+		<-release
+		return request.NewSuccessResponse("done")
+	})
+
+	return server, started, release
+}
+
+func TestSimpleServer_Shutdown(t *testing.T) {
+	server, started, release := newHangingServer()
+
+	batch := make(chan jsonrpc.Responses)
+	go func() {
+		batch <- server.Handle([]byte(`[
+			{"jsonrpc": "2.0", "method": "hang", "id": 1},
+			{"jsonrpc": "2.0", "method": "get_data", "id": 2}
+		]`))
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	// New requests are rejected once Shutdown has been called.
+	var rejected jsonrpc.Responses
+	for {
+		rejected = server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 3}`))
+		if rejected[0].ErrorCode() != jsonrpc.Success {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, jsonrpc.ServerError, rejected[0].ErrorCode())
+	assert.Equal(t, "Server is shutting down", rejected[0].ErrorMessage())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(rejected[0])
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.ShuttingDownErrorType, details.Type)
+	assert.True(t, details.Retryable)
+
+	assert.Empty(t, server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data"}`)))
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the batch finished.")
+	default:
+	}
+
+	// The rest of the accepted batch is still handled.
+	close(release)
+	responses := <-batch
+	assert.Equal(t, "done", responses[0].Result())
+	assert.Equal(t, []interface{}{"hello", 5.0}, responses[1].Result())
+	assert.NoError(t, <-shutdown)
+}
+
+func TestSimpleServer_ShutdownTimeout(t *testing.T) {
+	server, started, release := newHangingServer()
+	defer close(release)
+
+	go server.Handle([]byte(`{"jsonrpc": "2.0", "method": "hang", "id": 1}`))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.Shutdown(ctx))
+}
+
+func TestTCPServer_Shutdown(t *testing.T) {
+	server, started, release := newHangingServer()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpServer := jsonrpc.NewTCPServer(server)
+	served := make(chan error, 1)
+	go func() {
+		served <- tcpServer.Serve(listener)
+	}()
+
+	busy, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	idle, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+
+	busy.Write([]byte(`{"jsonrpc":"2.0","method":"hang","id":1}` + "\n"))
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- tcpServer.Shutdown(context.Background())
+	}()
+
+	// The idle connection is closed straight away.
+	_, err = bufio.NewReader(idle).ReadString('\n')
+	assert.Error(t, err)
+	assert.NoError(t, <-served)
+
+	// The response of the busy connection is sent before it is closed.
+	close(release)
+	reader := bufio.NewReader(busy)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":"done","id":1}`, line)
+
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+	assert.NoError(t, <-shutdown)
+	assert.Equal(t, 0, tcpServer.Connections())
+}
+
+func TestWebSocketHandler_Shutdown(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	client := dialWebSocket(t, url)
+
+	// Wait for the connection to be registered.
+	for handler.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.NoError(t, handler.Shutdown(context.Background()))
+
+	opcode, message := client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, "\x03\xe9", message)
+	assert.Equal(t, 0, handler.Connections())
+}
+
+func TestTCPServer_ShutdownNotice(t *testing.T) {
+	tcpServer, address := newTCPServer(t, newTestServer())
+	tcpServer.ShutdownNotice = jsonrpc.NewShutdownNotice("Deploying.", 5*time.Second)
+
+	client, err := jsonrpc.DialTCP(context.Background(), address, jsonrpc.TCPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	notices := make(chan string, 1)
+	client.SetNotificationHandler(func(method string, params json.RawMessage, state jsonrpc.State) {
+		notices <- method + " " + string(params)
+	})
+
+	// The connection is being served once it has answered a request.
+	_, err = client.Invoke(context.Background(), "get_data", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tcpServer.Shutdown(context.Background()))
+	assert.Equal(t, `rpc.shutdown {"reason":"Deploying.","retryAfter":5}`, <-notices)
+	<-client.Done()
+}
+
+func TestWebSocketHandler_ShutdownNotice(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	handler.ShutdownNotice = jsonrpc.NewShutdownNotice("", 0)
+	client := dialWebSocket(t, url)
+
+	for handler.Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.NoError(t, handler.Shutdown(context.Background()))
+
+	opcode, message := client.receive()
+	assert.Equal(t, byte(0x1), opcode)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"rpc.shutdown","params":{}}`, message)
+
+	opcode, _ = client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+}
@@ -0,0 +1,159 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// SignatureExtension is the extension member that holds the signature of a
+// signed response.
+const SignatureExtension = "signature"
+
+// Signer creates a detached signature of data.
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier checks a signature created by a Signer.
+type Verifier interface {
+	Verify(data, signature []byte) error
+}
+
+var errInvalidSignature = errors.New("Invalid signature.")
+
+// HMACSigner signs and verifies with HMAC-SHA256 using a shared key.
+type HMACSigner []byte
+
+// Sign returns the HMAC of data.
+func (key HMACSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// Verify checks the HMAC of data in constant time.
+func (key HMACSigner) Verify(data, signature []byte) error {
+	expected, _ := key.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+// Ed25519Signer signs with an Ed25519 private key.
+type Ed25519Signer ed25519.PrivateKey
+
+// Sign returns the Ed25519 signature of data.
+func (key Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(key), data), nil
+}
+
+// Ed25519Verifier verifies with an Ed25519 public key.
+type Ed25519Verifier ed25519.PublicKey
+
+// Verify checks the Ed25519 signature of data.
+func (key Ed25519Verifier) Verify(data, signature []byte) error {
+	if !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+// CanonicalResponseBytes returns the bytes of a response that are signed. This
+// is the JSON of the response, without the signature, with object keys sorted
+// and no insignificant whitespace. A response decoded by NewResponsesFromJSON
+// has the same canonical bytes as the response that was sent, with the
+// exception of integers that cannot be represented exactly as a float64.
+func CanonicalResponseBytes(response Response) ([]byte, error) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&members); err != nil {
+		return nil, err
+	}
+
+	delete(members, SignatureExtension)
+
+	return json.Marshal(members)
+}
+
+// SignResponse returns a copy of the response that contains the base64
+// encoded signature of its canonical bytes in the "signature" extension.
+func SignResponse(response Response, signer Signer) (Response, error) {
+	data, err := CanonicalResponseBytes(response)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithExtension(response, SignatureExtension,
+		base64.StdEncoding.EncodeToString(signature)), nil
+}
+
+// VerifyResponse checks the signature of a response that was signed with
+// SignResponse. An error is returned if the response is not signed, or the
+// signature does not match.
+func VerifyResponse(response Response, verifier Verifier) error {
+	encoded, ok := Extension(response, SignatureExtension).(string)
+	if !ok {
+		return errors.New("Response is not signed.")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidSignature
+	}
+
+	data, err := CanonicalResponseBytes(response)
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify(data, signature)
+}
+
+// SetResponseSigner will sign every response sent by the server with signer.
+// The signature is sent even if the server is not lenient. Use nil to stop
+// signing responses.
+func (server *SimpleServer) SetResponseSigner(signer Signer) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.responseSigner = signer
+}
+
+// signResponse signs the response if the server has a signer. A response that
+// cannot be signed is replaced with an InternalError.
+func (server *SimpleServer) signResponse(response Response) Response {
+	server.mutex.RLock()
+	signer := server.responseSigner
+	server.mutex.RUnlock()
+
+	if signer == nil {
+		return response
+	}
+
+	signed, err := SignResponse(response, signer)
+	if err != nil {
+		return NewErrorResponse(response.ID(), InternalError, "")
+	}
+
+	return signed
+}
@@ -0,0 +1,76 @@
+package jsonrpc_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSignResponse(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+
+	signers := map[string]struct {
+		signer   jsonrpc.Signer
+		verifier jsonrpc.Verifier
+	}{
+		"HMAC":    {jsonrpc.HMACSigner("secret"), jsonrpc.HMACSigner("secret")},
+		"Ed25519": {jsonrpc.Ed25519Signer(privateKey), jsonrpc.Ed25519Verifier(publicKey)},
+	}
+
+	for name, test := range signers {
+		t.Run(name, func(t *testing.T) {
+			response := jsonrpc.NewSuccessResponse(1, map[string]interface{}{
+				"b": 2, "a": []int{1, 2}, "c": 0.5,
+			})
+			signed, err := jsonrpc.SignResponse(response, test.signer)
+			assert.NoError(t, err)
+
+			t.Run("Verify", func(t *testing.T) {
+				assert.NoError(t, jsonrpc.VerifyResponse(signed, test.verifier))
+			})
+
+			t.Run("VerifyFromJSON", func(t *testing.T) {
+				responses, err := jsonrpc.NewResponsesFromJSON(signed.Bytes())
+				assert.NoError(t, err)
+
+				assert.NoError(t, jsonrpc.VerifyResponse(responses[0], test.verifier))
+			})
+
+			t.Run("Tampered", func(t *testing.T) {
+				tampered := jsonrpc.NewSuccessResponse(1, "foo")
+				tampered = jsonrpc.WithExtension(tampered, jsonrpc.SignatureExtension,
+					jsonrpc.Extension(signed, jsonrpc.SignatureExtension))
+
+				assert.EqualError(t, jsonrpc.VerifyResponse(tampered, test.verifier),
+					"Invalid signature.")
+			})
+		})
+	}
+
+	t.Run("NotSigned", func(t *testing.T) {
+		err := jsonrpc.VerifyResponse(jsonrpc.NewSuccessResponse(1, "foo"),
+			jsonrpc.HMACSigner("secret"))
+
+		assert.EqualError(t, err, "Response is not signed.")
+	})
+}
+
+func TestSimpleServer_SetResponseSigner(t *testing.T) {
+	server := newTestServer()
+	server.SetResponseSigner(jsonrpc.HMACSigner("secret"))
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 1},
+		{"jsonrpc": "2.0", "method": "foo", "id": 2},
+		{"jsonrpc": 2, "method": "foo", "id": 3}
+	]`))
+	decoded, err := jsonrpc.NewResponsesFromJSON(responses.Bytes())
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 3)
+
+	for _, response := range decoded {
+		assert.NoError(t, jsonrpc.VerifyResponse(response, jsonrpc.HMACSigner("secret")))
+	}
+}
@@ -0,0 +1,316 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SLOMethod is the conventional name of the admin method of an SLOTracker, see
+// SLOTracker.Handler.
+const SLOMethod = "rpc.slo"
+
+// DefaultSLOObjective is the objective of an SLOTracker when Objective is
+// zero: 99.9% of requests must not fail because of the server.
+const DefaultSLOObjective = 0.999
+
+// DefaultSLOWindow is the rolling window of an SLOTracker when Window is zero.
+const DefaultSLOWindow = time.Hour
+
+// sloSlots is the number of slots that a window is divided into. Counts
+// expire one slot at a time.
+const sloSlots = 60
+
+// Outcome classifies a response for an SLOTracker.
+type Outcome int
+
+const (
+	// OutcomeSuccess is a response without an error.
+	OutcomeSuccess Outcome = iota
+
+	// OutcomeClientError is an error caused by the request, such as
+	// InvalidParams or MethodNotFound, and application errors with codes
+	// outside the reserved range. It does not use up the error budget.
+	OutcomeClientError
+
+	// OutcomeServerError is an InternalError or an error in the server
+	// error range.
+	OutcomeServerError
+
+	// OutcomeTimeout is an error with the TimeoutErrorType, such as the
+	// errors sent by the Timeout middleware.
+	OutcomeTimeout
+)
+
+// String returns "success", "clientError", "serverError" or "timeout".
+func (outcome Outcome) String() string {
+	switch outcome {
+	case OutcomeSuccess:
+		return "success"
+
+	case OutcomeClientError:
+		return "clientError"
+
+	case OutcomeServerError:
+		return "serverError"
+	}
+
+	return "timeout"
+}
+
+// ClassifyResponse returns the Outcome of a response.
+func ClassifyResponse(response Response) Outcome {
+	code := response.ErrorCode()
+	if code == Success {
+		return OutcomeSuccess
+	}
+
+	if details, err := ErrorDetailsFromResponse(response); err == nil &&
+		details != nil && details.Type == TimeoutErrorType {
+		return OutcomeTimeout
+	}
+
+	if code == InternalError || (IsServerError(code) && code != MethodRetired) {
+		return OutcomeServerError
+	}
+
+	return OutcomeClientError
+}
+
+// SLOReport is the state of the error budget of a method over the window of
+// an SLOTracker.
+type SLOReport struct {
+	Success      uint64 `json:"success"`
+	ClientErrors uint64 `json:"clientErrors"`
+	ServerErrors uint64 `json:"serverErrors"`
+	Timeouts     uint64 `json:"timeouts"`
+
+	// ErrorRate is the fraction of requests that were server errors or
+	// timeouts.
+	ErrorRate float64 `json:"errorRate"`
+
+	// BurnRate is how fast the error budget is being used: the ErrorRate
+	// divided by the rate that the objective allows. A burn rate of 1 uses
+	// exactly the whole budget by the end of the window.
+	BurnRate float64 `json:"burnRate"`
+
+	// BudgetRemaining is the fraction of the error budget of the window that
+	// has not been used. It is negative once the objective has been missed.
+	BudgetRemaining float64 `json:"budgetRemaining"`
+}
+
+// Total is the number of requests in the report.
+func (report SLOReport) Total() uint64 {
+	return report.Success + report.ClientErrors + report.ServerErrors + report.Timeouts
+}
+
+// SLOTracker classifies the responses of each method (see Outcome) and
+// computes how fast its error budget is burning over a rolling window, for
+// alerting on the reliability of a service:
+//
+//     tracker := &jsonrpc.SLOTracker{Objective: 0.999, Window: time.Hour}
+//     server.Group(tracker.Middleware(), jsonrpc.Timeout(time.Second)).
+//         SetHandler("user.get", getUser)
+//
+//     if tracker.BurnRate("user.get", 5*time.Minute) > 14.4 {
+//         page()
+//     }
+//
+// The middleware must be outside of any Timeout middleware to see timeouts.
+// The reports can be read with Reports, served as JSON over HTTP (it is an
+// http.Handler) or with the SLOMethod admin method (see Handler).
+//
+// It is safe for concurrent use once it is in use; the fields must not be
+// changed after that.
+type SLOTracker struct {
+	// Objective is the fraction of requests that must not be server errors
+	// or timeouts. Zero uses DefaultSLOObjective.
+	Objective float64
+
+	// Window is the rolling window that reports cover. Zero uses
+	// DefaultSLOWindow.
+	Window time.Duration
+
+	// Clock is used to place outcomes in the window. SystemClock is used if
+	// it is nil.
+	Clock Clock
+
+	mutex   sync.Mutex
+	methods map[string]*sloWindow
+}
+
+// sloWindow is a ring of slots, each counting the outcomes of one
+// Window/sloSlots period.
+type sloWindow struct {
+	slots [sloSlots]sloSlot
+}
+
+type sloSlot struct {
+	period int64
+	counts [4]uint64
+}
+
+// Middleware returns a middleware that observes the outcome of every request.
+// A handler that panics is observed as a server error.
+func (tracker *SLOTracker) Middleware() Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			outcome := OutcomeServerError
+			defer func() {
+				tracker.Observe(request.Method(), outcome)
+			}()
+
+			response := next(request)
+			outcome = ClassifyResponse(response)
+
+			return response
+		}
+	}
+}
+
+// Observe counts an outcome of a method.
+func (tracker *SLOTracker) Observe(method string, outcome Outcome) {
+	period := tracker.period(clockOrSystem(tracker.Clock).Now())
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.methods == nil {
+		tracker.methods = map[string]*sloWindow{}
+	}
+
+	window := tracker.methods[method]
+	if window == nil {
+		window = &sloWindow{}
+		tracker.methods[method] = window
+	}
+
+	slot := &window.slots[period%sloSlots]
+	if slot.period != period {
+		*slot = sloSlot{period: period}
+	}
+	slot.counts[outcome]++
+}
+
+// Report returns the report of a method over the whole window. An empty
+// method reports on every method together.
+func (tracker *SLOTracker) Report(method string) SLOReport {
+	return tracker.report(method, tracker.window())
+}
+
+// Reports returns the report of every method that has been observed within
+// the window.
+func (tracker *SLOTracker) Reports() map[string]SLOReport {
+	tracker.mutex.Lock()
+	methods := make([]string, 0, len(tracker.methods))
+	for method := range tracker.methods {
+		methods = append(methods, method)
+	}
+	tracker.mutex.Unlock()
+
+	reports := map[string]SLOReport{}
+	for _, method := range methods {
+		if report := tracker.Report(method); report.Total() > 0 {
+			reports[method] = report
+		}
+	}
+
+	return reports
+}
+
+// BurnRate is the burn rate of a method (see SLOReport) over the most recent
+// part of the window, such as the last five minutes. The duration is rounded
+// up to a whole number of slots and is limited to the window. An empty method
+// covers every method.
+func (tracker *SLOTracker) BurnRate(method string, d time.Duration) float64 {
+	return tracker.report(method, d).BurnRate
+}
+
+func (tracker *SLOTracker) report(method string, d time.Duration) SLOReport {
+	slotSize := tracker.window() / sloSlots
+	slots := int64((d + slotSize - 1) / slotSize)
+	if slots > sloSlots {
+		slots = sloSlots
+	}
+	oldest := tracker.period(clockOrSystem(tracker.Clock).Now()) - slots + 1
+
+	var counts [4]uint64
+	tracker.mutex.Lock()
+	for name, window := range tracker.methods {
+		if method != "" && name != method {
+			continue
+		}
+
+		for _, slot := range window.slots {
+			if slot.period >= oldest {
+				for i, count := range slot.counts {
+					counts[i] += count
+				}
+			}
+		}
+	}
+	tracker.mutex.Unlock()
+
+	report := SLOReport{
+		Success:         counts[OutcomeSuccess],
+		ClientErrors:    counts[OutcomeClientError],
+		ServerErrors:    counts[OutcomeServerError],
+		Timeouts:        counts[OutcomeTimeout],
+		BudgetRemaining: 1,
+	}
+
+	if total := report.Total(); total > 0 {
+		budget := 1 - tracker.objective()
+		report.ErrorRate = float64(report.ServerErrors+report.Timeouts) / float64(total)
+		report.BurnRate = report.ErrorRate / budget
+		report.BudgetRemaining = 1 - report.BurnRate
+	}
+
+	return report
+}
+
+func (tracker *SLOTracker) objective() float64 {
+	if tracker.Objective == 0 {
+		return DefaultSLOObjective
+	}
+
+	return tracker.Objective
+}
+
+func (tracker *SLOTracker) window() time.Duration {
+	if tracker.Window == 0 {
+		return DefaultSLOWindow
+	}
+
+	return tracker.Window
+}
+
+// period is the number of the slot that t falls in, counted from the epoch.
+func (tracker *SLOTracker) period(t time.Time) int64 {
+	return t.UnixNano() / int64(tracker.window()/sloSlots)
+}
+
+// ServeHTTP responds with the Reports as a JSON object, for monitoring
+// systems that scrape JSON.
+func (tracker *SLOTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tracker.Reports())
+}
+
+// Handler returns the handler of an admin method, usually registered as
+// SLOMethod. It responds with the Reports, or the report of a single method if
+// the params are an object with a "method". Admin methods should usually be
+// registered on a group that requires authorization:
+//
+//     admin.SetHandler(jsonrpc.SLOMethod, tracker.Handler())
+func (tracker *SLOTracker) Handler() RequestHandler {
+	return func(request RequestResponder) Response {
+		params, _ := request.Params().(map[string]interface{})
+		if method, ok := params["method"].(string); ok {
+			return request.NewSuccessResponse(tracker.Report(method))
+		}
+
+		return request.NewSuccessResponse(tracker.Reports())
+	}
+}
@@ -0,0 +1,99 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	for outcome, response := range map[jsonrpc.Outcome]jsonrpc.Response{
+		jsonrpc.OutcomeSuccess:     jsonrpc.NewSuccessResponse(1, "ok"),
+		jsonrpc.OutcomeClientError: jsonrpc.NewErrorResponse(1, jsonrpc.InvalidParams, ""),
+		jsonrpc.OutcomeServerError: jsonrpc.NewErrorResponse(1, jsonrpc.ServerError, ""),
+		jsonrpc.OutcomeTimeout: jsonrpc.NewErrorResponseWithData(1, jsonrpc.ServerError,
+			"Timeout", jsonrpc.NewErrorDetails(jsonrpc.TimeoutErrorType)),
+	} {
+		assert.Equal(t, outcome, jsonrpc.ClassifyResponse(response), outcome.String())
+	}
+
+	assert.Equal(t, jsonrpc.OutcomeClientError, jsonrpc.ClassifyResponse(
+		jsonrpc.NewErrorResponse(1, jsonrpc.MethodRetired, "")))
+	assert.Equal(t, jsonrpc.OutcomeClientError, jsonrpc.ClassifyResponse(
+		jsonrpc.NewErrorResponse(1, 404, "Not found")))
+	assert.Equal(t, jsonrpc.OutcomeServerError, jsonrpc.ClassifyResponse(
+		jsonrpc.NewErrorResponse(1, jsonrpc.InternalError, "")))
+}
+
+func TestSLOTracker(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	tracker := &jsonrpc.SLOTracker{Objective: 0.9, Window: time.Hour, Clock: clock}
+
+	server := newTestServer()
+	group := server.Group(tracker.Middleware())
+	group.SetHandler("subtract", server.GetHandler("subtract"))
+	group.SetHandler("panic", server.GetHandler("panic"))
+	group.SetHandler("reject", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewErrorResponse(jsonrpc.InvalidParams, "")
+	})
+
+	call := func(method, params string) {
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"` + method +
+			`","params":` + params + `,"id":1}`))
+	}
+
+	for i := 0; i < 8; i++ {
+		call("subtract", "[3, 2]")
+	}
+	call("reject", "[]")
+	call("panic", "[]")
+
+	report := tracker.Report("subtract")
+	assert.Equal(t, uint64(8), report.Success)
+	assert.Equal(t, 0.0, report.BurnRate)
+	assert.Equal(t, uint64(1), tracker.Report("reject").ClientErrors)
+
+	report = tracker.Report("panic")
+	assert.Equal(t, uint64(1), report.ServerErrors)
+	assert.InDelta(t, 10.0, report.BurnRate, 0.001)
+	assert.InDelta(t, -9.0, report.BudgetRemaining, 0.001)
+
+	// One server error in ten requests is exactly the budget.
+	assert.InDelta(t, 1.0, tracker.BurnRate("", time.Hour), 0.001)
+
+	// Errors leave the shorter windows first.
+	clock.Advance(30 * time.Minute)
+	call("subtract", "[3, 2]")
+	assert.Equal(t, 0.0, tracker.BurnRate("", 5*time.Minute))
+	assert.InDelta(t, 10.0/11, tracker.BurnRate("", time.Hour), 0.001)
+
+	clock.Advance(time.Hour)
+	assert.Empty(t, tracker.Reports())
+	assert.Equal(t, 1.0, tracker.Report("panic").BudgetRemaining)
+}
+
+func TestSLOTracker_Handler(t *testing.T) {
+	tracker := &jsonrpc.SLOTracker{Objective: 0.5}
+	tracker.Observe("a", jsonrpc.OutcomeSuccess)
+	tracker.Observe("b", jsonrpc.OutcomeTimeout)
+
+	server := jsonrpc.NewSimpleServer()
+	server.SetHandler(jsonrpc.SLOMethod, tracker.Handler())
+
+	response := server.Handle([]byte(`{"jsonrpc":"2.0","method":"rpc.slo","params":{"method":"b"},"id":1}`))[0]
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"success":0,"clientErrors":0,`+
+		`"serverErrors":0,"timeouts":1,"errorRate":1,"burnRate":2,"budgetRemaining":-1}}`,
+		response.String())
+
+	recorder := httptest.NewRecorder()
+	tracker.ServeHTTP(recorder, httptest.NewRequest("GET", "/slo", nil))
+
+	var reports map[string]jsonrpc.SLOReport
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reports))
+	assert.Equal(t, uint64(1), reports["a"].Success)
+	assert.Equal(t, uint64(1), reports["b"].Timeouts)
+}
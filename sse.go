@@ -0,0 +1,246 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventStreamContentType is the content type of Server-Sent Events.
+const EventStreamContentType = "text/event-stream"
+
+// DefaultEventBufferSize is the BufferSize of an EventStream when it is zero.
+const DefaultEventBufferSize = 100
+
+// EventStream pushes notifications to clients as Server-Sent Events, for
+// clients that post their calls over regular HTTP (such as browsers with
+// EventSource). It is usually served by an HTTPServer, which sends a GET that
+// accepts text/event-stream to it:
+//
+//     events := jsonrpc.NewEventStream()
+//     httpServer := jsonrpc.NewHTTPServer(server)
+//     httpServer.Events = events
+//     http.Handle("/rpc", httpServer)
+//
+//     // Somewhere else:
+//     events.Notify("client-1", "orderShipped", map[string]interface{}{"id": 5})
+//
+// A client subscribes with the session in the query string, such as
+// "/rpc?session=client-1". Each notification is sent as the data of an event
+// without a type, so it is received by the onmessage handler of an
+// EventSource. A session may have several streams open, and every one of them
+// receives its notifications. Notifications are not kept for sessions without
+// a stream, see LongPoller for that.
+//
+// The zero value is ready to use, without keep-alives. Open streams are not
+// ended by http.Server.Shutdown, so call Close first.
+type EventStream struct {
+	// KeepAlive is how often a comment is sent on a stream that has no
+	// notifications, so that proxies do not close it. Zero disables it.
+	KeepAlive time.Duration
+
+	// BufferSize is the number of notifications that may wait for a slow
+	// client. A client that falls further behind is disconnected, and its
+	// EventSource will reconnect. Zero uses DefaultEventBufferSize.
+	BufferSize int
+
+	// Clock is used for KeepAlive. SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex       sync.Mutex
+	subscribers map[string]map[*eventSubscriber]struct{}
+	closed      bool
+}
+
+type eventSubscriber struct {
+	notifications chan Request
+}
+
+// NewEventStream creates an EventStream that keeps streams alive every 30
+// seconds and buffers up to 100 notifications for each of them.
+func NewEventStream() *EventStream {
+	return &EventStream{
+		KeepAlive:  30 * time.Second,
+		BufferSize: DefaultEventBufferSize,
+	}
+}
+
+// Notify sends a notification to the open streams of a session.
+func (stream *EventStream) Notify(session, method string, params interface{}) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	notification := NewRequestResponder("2.0", nil, method, params)
+	for subscriber := range stream.subscribers[session] {
+		stream.push(session, subscriber, notification)
+	}
+}
+
+// Broadcast sends a notification to every open stream.
+func (stream *EventStream) Broadcast(method string, params interface{}) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	notification := NewRequestResponder("2.0", nil, method, params)
+	for session, subscribers := range stream.subscribers {
+		for subscriber := range subscribers {
+			stream.push(session, subscriber, notification)
+		}
+	}
+}
+
+// Streams returns the number of open streams.
+func (stream *EventStream) Streams() int {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	streams := 0
+	for _, subscribers := range stream.subscribers {
+		streams += len(subscribers)
+	}
+
+	return streams
+}
+
+// Close ends every open stream and refuses new ones with a 503 Service
+// Unavailable.
+func (stream *EventStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.closed = true
+	for session, subscribers := range stream.subscribers {
+		for subscriber := range subscribers {
+			stream.unsubscribe(session, subscriber)
+		}
+	}
+}
+
+// push must be called while holding the mutex. A subscriber whose buffer is
+// full is removed, which ends its stream.
+func (stream *EventStream) push(session string, subscriber *eventSubscriber,
+	notification Request) {
+	select {
+	case subscriber.notifications <- notification:
+
+	default:
+		stream.unsubscribe(session, subscriber)
+	}
+}
+
+// subscribe returns nil if the stream has been closed.
+func (stream *EventStream) subscribe(session string) *eventSubscriber {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if stream.closed {
+		return nil
+	}
+
+	bufferSize := stream.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+
+	subscriber := &eventSubscriber{
+		notifications: make(chan Request, bufferSize),
+	}
+
+	if stream.subscribers == nil {
+		stream.subscribers = make(map[string]map[*eventSubscriber]struct{})
+	}
+	if stream.subscribers[session] == nil {
+		stream.subscribers[session] = make(map[*eventSubscriber]struct{})
+	}
+	stream.subscribers[session][subscriber] = struct{}{}
+
+	return subscriber
+}
+
+// unsubscribe must be called while holding the mutex.
+func (stream *EventStream) unsubscribe(session string, subscriber *eventSubscriber) {
+	subscribers := stream.subscribers[session]
+	if _, ok := subscribers[subscriber]; !ok {
+		return
+	}
+
+	delete(subscribers, subscriber)
+	if len(subscribers) == 0 {
+		delete(stream.subscribers, session)
+	}
+
+	close(subscriber.notifications)
+}
+
+// ServeHTTP streams the notifications of the session in the query string until
+// the client disconnects.
+func (stream *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := r.URL.Query().Get("session")
+	if session == "" {
+		http.Error(w, "Missing session.", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported.", http.StatusInternalServerError)
+		return
+	}
+
+	subscriber := stream.subscribe(session)
+	if subscriber == nil {
+		http.Error(w, "Event stream is closed.", http.StatusServiceUnavailable)
+		return
+	}
+	defer func() {
+		stream.mutex.Lock()
+		stream.unsubscribe(session, subscriber)
+		stream.mutex.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	clock := clockOrSystem(stream.Clock)
+	for {
+		var keepAlive <-chan time.Time
+		if stream.KeepAlive > 0 {
+			keepAlive = clock.After(stream.KeepAlive)
+		}
+
+		select {
+		case notification, ok := <-subscriber.notifications:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+
+			if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+
+		case <-keepAlive:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+
+		case <-r.Context().Done():
+			return
+		}
+
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,156 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// subscribe opens an event stream and waits until the server has registered
+// it.
+func subscribe(t *testing.T, events *jsonrpc.EventStream, url string) (*bufio.Reader, func()) {
+	streams := events.Streams()
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("Accept", "text/event-stream")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	for events.Streams() == streams {
+		time.Sleep(time.Millisecond)
+	}
+
+	return bufio.NewReader(response.Body), func() { response.Body.Close() }
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	event := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line == "\n" {
+			return event
+		}
+
+		event += line
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	events := jsonrpc.NewEventStream()
+	httpServer := jsonrpc.NewHTTPServer(newTestServer())
+	httpServer.Events = events
+	server := httptest.NewServer(httpServer)
+	defer server.Close()
+
+	a, closeA := subscribe(t, events, server.URL+"?session=a")
+	defer closeA()
+	b, closeB := subscribe(t, events, server.URL+"?session=b")
+	defer closeB()
+	assert.Equal(t, 2, events.Streams())
+
+	events.Notify("a", "foo", []int{1})
+	events.Broadcast("bar", nil)
+
+	assert.Equal(t, `data: {"jsonrpc":"2.0","method":"foo","params":[1]}`+"\n",
+		readEvent(t, a))
+	assert.Equal(t, `data: {"jsonrpc":"2.0","method":"bar"}`+"\n", readEvent(t, a))
+	assert.Equal(t, `data: {"jsonrpc":"2.0","method":"bar"}`+"\n", readEvent(t, b))
+
+	// Calls are still posted to the same URL.
+	response, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		response.Body.Close()
+	}
+
+	closeA()
+	for events.Streams() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEventStream_KeepAlive(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	events := jsonrpc.NewEventStream()
+	events.Clock = clock
+	server := httptest.NewServer(events)
+	defer server.Close()
+
+	reader, closeStream := subscribe(t, events, server.URL+"?session=a")
+	defer closeStream()
+
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(30 * time.Second)
+
+	assert.Equal(t, ": keep-alive\n", readEvent(t, reader))
+}
+
+func TestEventStream_Errors(t *testing.T) {
+	events := jsonrpc.NewEventStream()
+	httpServer := jsonrpc.NewHTTPServer(newTestServer())
+	httpServer.Events = events
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/rpc", nil)
+	request.Header.Set("Accept", "text/event-stream")
+	httpServer.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// A GET that does not accept events is not allowed.
+	recorder = httptest.NewRecorder()
+	httpServer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rpc?session=a", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, "GET, POST", recorder.Header().Get("Allow"))
+
+	recorder = httptest.NewRecorder()
+	events.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rpc?session=a", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestEventStream_ZeroValue(t *testing.T) {
+	events := &jsonrpc.EventStream{}
+	server := httptest.NewServer(events)
+	defer server.Close()
+
+	reader, closeStream := subscribe(t, events, server.URL+"?session=a")
+	defer closeStream()
+
+	events.Notify("a", "foo", nil)
+	events.Notify("a", "bar", nil)
+	assert.Equal(t, `data: {"jsonrpc":"2.0","method":"foo"}`+"\n", readEvent(t, reader))
+	assert.Equal(t, `data: {"jsonrpc":"2.0","method":"bar"}`+"\n", readEvent(t, reader))
+
+	// Close ends the open streams and refuses new ones.
+	events.Close()
+	_, err := reader.ReadString('\n')
+	assert.Error(t, err)
+	assert.Equal(t, 0, events.Streams())
+
+	status, _ := post(t, server.URL+"?session=a", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"?session=a", nil)
+	response, err := http.DefaultClient.Do(request)
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	}
+}
@@ -1,6 +1,8 @@
 package jsonrpc
 
 import (
+	"encoding/json"
+	"net/http"
 	"sync/atomic"
 	"time"
 )
@@ -58,6 +60,15 @@ type StatReporter interface {
 	// CurrentActiveRequests returns the number of requests that are inflight.
 	// This does not include requests that are queued.
 	CurrentActiveRequests() uint64
+
+	// DeprecatedCalls returns the number of calls to each deprecated method
+	// that have been handled. Calls after the sunset of a method are not
+	// counted.
+	DeprecatedCalls() map[string]uint64
+
+	// MethodSizes returns the distribution of the params and result sizes for
+	// each method. It is empty unless SetSizeBuckets has been called.
+	MethodSizes() map[string]MethodSizes
 }
 
 // TotalPayloads get total pay load
@@ -92,10 +103,46 @@ func (server *SimpleServer) TotalNotificationErrors() uint64 {
 
 // Uptime get a uptime of server
 func (server *SimpleServer) Uptime() time.Duration {
-	return time.Now().Sub(server.startTime)
+	server.mutex.RLock()
+	clock, startTime := server.clock, server.startTime
+	server.mutex.RUnlock()
+
+	return clock.Now().Sub(startTime)
 }
 
 // CurrentActiveRequests get current activity requests
 func (server *SimpleServer) CurrentActiveRequests() uint64 {
 	return atomic.LoadUint64(&server.currentActiveRequests)
 }
+
+// DeprecatedCalls get the calls to deprecated methods
+func (server *SimpleServer) DeprecatedCalls() map[string]uint64 {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	calls := map[string]uint64{}
+	for method, count := range server.deprecatedCalls {
+		calls[method] = count
+	}
+
+	return calls
+}
+
+// StatsHandler returns an HTTP handler that responds with the statistics as a
+// JSON object, for monitoring systems that scrape JSON.
+func StatsHandler(stats StatReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"totalPayloads":              stats.TotalPayloads(),
+			"totalRequests":              stats.TotalRequests(),
+			"totalSuccessResponses":      stats.TotalSuccessResponses(),
+			"totalErrorResponses":        stats.TotalErrorResponses(),
+			"totalNotificationSuccesses": stats.TotalNotificationSuccesses(),
+			"totalNotificationErrors":    stats.TotalNotificationErrors(),
+			"uptimeSeconds":              stats.Uptime().Seconds(),
+			"currentActiveRequests":      stats.CurrentActiveRequests(),
+			"deprecatedCalls":            stats.DeprecatedCalls(),
+		})
+	})
+}
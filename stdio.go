@@ -0,0 +1,126 @@
+package jsonrpc
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// StdioServer serves JSON-RPC over stdin and stdout with each message framed
+// by a Content-Length header (see ContentLengthFramer), as language servers and
+// editor plugins do:
+//
+//     func main() {
+//         server := jsonrpc.NewSimpleServer()
+//         server.SetHandler("initialize", initialize)
+//
+//         stdio := jsonrpc.NewStdioServer(server)
+//         if err := stdio.Serve(context.Background()); err != nil {
+//             log.Fatal(err)
+//         }
+//     }
+//
+// Nothing else may be written to stdout once Serve has been called. Logs should
+// be written to stderr instead.
+type StdioServer struct {
+	Server *SimpleServer
+
+	// In and Out are the streams to use instead of os.Stdin and os.Stdout.
+	In  io.Reader
+	Out io.Writer
+
+	// MaxMessageSize is the largest message in bytes. Larger messages are
+	// answered with a ParseError and skipped. Zero uses DefaultMaxFrameSize.
+	MaxMessageSize int
+
+	once   sync.Once
+	framer *ContentLengthFramer
+
+	// draining is closed by Shutdown, and serving is running while Serve is.
+	draining     chan struct{}
+	drainingOnce sync.Once
+	serving      goroutineGroup
+}
+
+// NewStdioServer creates a StdioServer that uses os.Stdin and os.Stdout.
+func NewStdioServer(server *SimpleServer) *StdioServer {
+	return &StdioServer{Server: server}
+}
+
+// stdio joins the input and output into one stream.
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioServer *StdioServer) getFramer() *ContentLengthFramer {
+	stdioServer.once.Do(func() {
+		stream := stdio{stdioServer.In, stdioServer.Out}
+		if stream.Reader == nil {
+			stream.Reader = os.Stdin
+		}
+		if stream.Writer == nil {
+			stream.Writer = os.Stdout
+		}
+
+		stdioServer.framer = NewContentLengthFramer(stream, RecoverySkip,
+			stdioServer.MaxMessageSize)
+		stdioServer.draining = make(chan struct{})
+	})
+
+	return stdioServer.framer
+}
+
+// Serve handles the requests in order until the input ends, when it returns
+// nil. The context of every request (see ContextFromRequest) is cancelled when
+// ctx is done or Serve returns.
+func (stdioServer *StdioServer) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stdioServer.serving.add()
+	defer stdioServer.serving.done()
+
+	framer := stdioServer.getFramer()
+
+	return stdioServer.Server.ServeFramerWithContext(ctx,
+		&drainingFramer{framer, stdioServer.draining}, nil)
+}
+
+// Shutdown stops Serve from reading more requests and waits for the request
+// being handled to finish and its response to be sent, or for ctx to be done,
+// in which case its error is returned. A read that is already waiting for the
+// next request is only interrupted if the input supports deadlines, such as
+// os.Stdin when it is a pipe. Otherwise Serve returns after the next request
+// has been read, without handling it.
+func (stdioServer *StdioServer) Shutdown(ctx context.Context) error {
+	stdioServer.getFramer()
+	stdioServer.drainingOnce.Do(func() {
+		close(stdioServer.draining)
+	})
+
+	in := stdioServer.In
+	if in == nil {
+		in = os.Stdin
+	}
+	if deadliner, ok := in.(interface{ SetReadDeadline(time.Time) error }); ok {
+		deadliner.SetReadDeadline(time.Now())
+	}
+
+	select {
+	case <-stdioServer.serving.wait():
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification to the client, such as a progress report or a
+// log message. It is safe to call from handlers while Serve is running.
+func (stdioServer *StdioServer) Notify(method string, params interface{}) error {
+	return stdioServer.getFramer().WriteFrame(
+		NewRequestResponder("2.0", nil, method, params).Bytes())
+}
@@ -0,0 +1,37 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func contentLength(message string) string {
+	return "Content-Length: " + strconv.Itoa(len(message)) + "\r\n\r\n" + message
+}
+
+func TestStdioServer(t *testing.T) {
+	server := newTestServer()
+	s := newStream(
+		contentLength(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`) +
+			contentLength(`{"jsonrpc":"2.0","method":"progress"}`) +
+			"Content-Length: 3\r\n\r\n{\"a" +
+			contentLength(`{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":2}`))
+
+	stdio := &jsonrpc.StdioServer{Server: server, In: s, Out: s}
+	server.SetHandler("progress", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		assert.NoError(t, stdio.Notify("$/progress", map[string]interface{}{"done": true}))
+		return request.NewSuccessResponse(nil)
+	})
+
+	assert.NoError(t, stdio.Serve(context.Background()))
+	assert.Equal(t,
+		contentLength(`{"jsonrpc":"2.0","id":1,"result":19}`)+
+			contentLength(`{"jsonrpc":"2.0","method":"$/progress","params":{"done":true}}`)+
+			contentLength(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Message is not valid JSON."}}`)+
+			contentLength(`{"jsonrpc":"2.0","id":2,"result":3}`),
+		s.String())
+}
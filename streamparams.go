@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// StreamHandler is a handler that decodes its params itself, from params,
+// instead of receiving them as a fully decoded value. A method that is sent a
+// large array can process one element at a time without holding the whole of
+// it in memory as a []interface{}:
+//
+//     server.SetStreamHandler("points.import", func(request jsonrpc.RequestResponder,
+//         params *json.Decoder) jsonrpc.Response {
+//         if _, err := params.Token(); err != nil { // [
+//             return request.NewErrorResponse(jsonrpc.InvalidParams, err.Error())
+//         }
+//
+//         imported := 0
+//         for params.More() {
+//             var point Point
+//             if err := params.Decode(&point); err != nil {
+//                 return request.NewErrorResponse(jsonrpc.InvalidParams, err.Error())
+//             }
+//             store(point)
+//             imported++
+//         }
+//
+//         return request.NewSuccessResponse(imported)
+//     })
+//
+// A request without params is decoded as null.
+type StreamHandler func(request RequestResponder, params *json.Decoder) Response
+
+// RequestHandler returns a RequestHandler that calls the StreamHandler with
+// the params of the request. If the params have already been decoded they are
+// encoded again, so this only saves memory for methods registered with
+// SetStreamHandler.
+func (handler StreamHandler) RequestHandler() RequestHandler {
+	return func(request RequestResponder) Response {
+		return handler(request, ParamsDecoder(request))
+	}
+}
+
+// ParamsDecoder returns a json.Decoder that reads the params of the request.
+// The params are not copied if they were left undecoded (see
+// SetStreamHandler).
+func ParamsDecoder(request Request) *json.Decoder {
+	raw, ok := request.Params().(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(request.Params()); err != nil {
+			// The params did not come from JSON, so the handler is given what
+			// it would have been sent.
+			raw = json.RawMessage("null")
+		}
+	}
+
+	return json.NewDecoder(bytes.NewReader(raw))
+}
+
+// SetStreamHandler will register (or replace) a StreamHandler for a method.
+// The params of requests for the method are not decoded by the server, so the
+// handler reads them straight from the request that was received. Handlers
+// and middleware that call Params see a json.RawMessage.
+//
+// Requests handled with HandleArena are decoded as usual.
+func (server *SimpleServer) SetStreamHandler(methodName string, handler StreamHandler) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requestHandlers[methodName] = handler.RequestHandler()
+	server.streamMethods[methodName] = true
+}
+
+// isStreamMethod returns true if the method was registered with
+// SetStreamHandler.
+func (server *SimpleServer) isStreamMethod(method string) bool {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	return server.streamMethods[method]
+}
+
+// decodeFieldsWithRawParams decodes a request object like json.Unmarshal,
+// except that the params are left as a json.RawMessage if rawParams returns
+// true for the method.
+func decodeFieldsWithRawParams(data []byte,
+	rawParams func(method string) bool) (map[string]interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var method string
+	raw := json.Unmarshal(fields["method"], &method) == nil && rawParams(method)
+
+	members := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if name == "params" && raw {
+			members[name] = value
+			continue
+		}
+
+		var decoded interface{}
+		if err := json.Unmarshal(value, &decoded); err != nil {
+			return nil, err
+		}
+		members[name] = decoded
+	}
+
+	return members, nil
+}
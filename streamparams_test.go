@@ -0,0 +1,94 @@
+package jsonrpc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// streamSum adds up an array of numbers one element at a time.
+func streamSum(request jsonrpc.RequestResponder, params *json.Decoder) jsonrpc.Response {
+	var total float64
+	if token, err := params.Token(); err != nil || token != json.Delim('[') {
+		return request.NewErrorResponse(jsonrpc.InvalidParams, "")
+	}
+
+	for params.More() {
+		var n float64
+		if err := params.Decode(&n); err != nil {
+			return request.NewErrorResponse(jsonrpc.InvalidParams, err.Error())
+		}
+		total += n
+	}
+
+	return request.NewSuccessResponse(total)
+}
+
+func TestSimpleServer_SetStreamHandler(t *testing.T) {
+	server := newTestServer()
+	var params []interface{}
+	server.SetStreamHandler("stream_sum", func(request jsonrpc.RequestResponder,
+		decoder *json.Decoder) jsonrpc.Response {
+		params = append(params, request.Params())
+		return streamSum(request, decoder)
+	})
+
+	responses := server.Handle([]byte(`[
+		{"jsonrpc": "2.0", "method": "stream_sum", "params": [1, 2, 3.5], "id": 1},
+		{"jsonrpc": "2.0", "method": "sum", "params": [1, 2], "id": 2},
+		{"jsonrpc": "2.0", "method": "stream_sum", "params": {"a": 1}, "id": 3},
+		{"jsonrpc": "2.0", "method": "stream_sum", "id": 4}
+	]`))
+	assert.Len(t, responses, 4)
+	assert.Equal(t, 6.5, responses[0].Result())
+	assert.Equal(t, 3.0, responses[1].Result())
+	assert.Equal(t, jsonrpc.InvalidParams, responses[2].ErrorCode())
+	assert.Equal(t, jsonrpc.InvalidParams, responses[3].ErrorCode())
+
+	// The params are not decoded by the server.
+	assert.Equal(t, []interface{}{
+		json.RawMessage(`[1,2,3.5]`),
+		json.RawMessage(`{"a":1}`),
+		nil,
+	}, params)
+
+	// A single request is not reencoded.
+	params = nil
+	responses = server.Handle([]byte(`{"jsonrpc": "2.0", "method": "stream_sum",
+		"params": [10, 20], "id": 5}`))
+	assert.Equal(t, 30.0, responses[0].Result())
+	assert.Equal(t, []interface{}{json.RawMessage(`[10, 20]`)}, params)
+
+	// Replacing it with a normal handler decodes the params again.
+	server.SetHandler("stream_sum", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(request.Params())
+	})
+	responses = server.Handle([]byte(`{"jsonrpc": "2.0", "method": "stream_sum",
+		"params": [10, 20], "id": 6}`))
+	assert.Equal(t, []interface{}{10.0, 20.0}, responses[0].Result())
+}
+
+func TestStreamHandler_RequestHandler(t *testing.T) {
+	handler := jsonrpc.StreamHandler(streamSum).RequestHandler()
+
+	response := handler(jsonrpc.NewRequestResponder("2.0", 1, "sum", []int{1, 2, 3}))
+	assert.Equal(t, 6.0, response.Result())
+
+	response = handler(jsonrpc.NewRequestResponder("2.0", 1, "sum", make(chan int)))
+	assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+}
+
+func TestParamsDecoder(t *testing.T) {
+	var params map[string]int
+	decoder := jsonrpc.ParamsDecoder(jsonrpc.NewRequestResponder("2.0", 1, "a",
+		json.RawMessage(`{"x": 1}`)))
+	assert.NoError(t, decoder.Decode(&params))
+	assert.Equal(t, map[string]int{"x": 1}, params)
+
+	var missing interface{} = "unchanged"
+	decoder = jsonrpc.ParamsDecoder(jsonrpc.NewRequestResponder("2.0", 1, "a", nil))
+	assert.NoError(t, decoder.Decode(&missing))
+	assert.Nil(t, missing)
+}
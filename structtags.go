@@ -0,0 +1,137 @@
+package jsonrpc
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TagOption creates the middleware for an option of a jsonrpc struct tag from
+// the value of the option. See RegisterStruct.
+type TagOption func(value string) (Middleware, error)
+
+// DefaultTagOptions are the options that RegisterStruct understands without
+// being told about them:
+//
+//     timeout=5s   Wraps the handler with Timeout.
+var DefaultTagOptions = map[string]TagOption{
+	"timeout": func(value string) (Middleware, error) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return Timeout(d), nil
+	},
+}
+
+// RegisterStruct registers every exported field of the struct (or pointer to a
+// struct) api that is a RequestHandler and has a jsonrpc tag. The tag contains
+// the method name followed by options:
+//
+//     type UserAPI struct {
+//         Get    jsonrpc.RequestHandler `jsonrpc:"user.get,timeout=2s"`
+//         Delete jsonrpc.RequestHandler `jsonrpc:"user.delete,auth=admin,timeout=5s"`
+//         Ignore jsonrpc.RequestHandler `jsonrpc:"-"`
+//     }
+//
+//     err := server.RegisterStruct(UserAPI{Get: getUser, Delete: deleteUser},
+//         map[string]jsonrpc.TagOption{
+//             "auth": func(scope string) (jsonrpc.Middleware, error) {
+//                 return requireScope(scope), nil
+//             },
+//         })
+//
+// The field name is used if the tag does not contain a method name. Each
+// option is turned into middleware by the options given here or
+// DefaultTagOptions, the first option is the outermost middleware.
+//
+// Every field is checked before any handler is registered, so nothing is
+// registered if an error is returned.
+func (server *SimpleServer) RegisterStruct(api interface{},
+	options map[string]TagOption) error {
+	value := reflect.ValueOf(api)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return errors.New("API must be a struct.")
+	}
+
+	handlers := map[string]RequestHandler{}
+	handlerType := reflect.TypeOf(RequestHandler(nil))
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, ok := field.Tag.Lookup("jsonrpc")
+		if !ok || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		if !field.Type.ConvertibleTo(handlerType) {
+			return errors.New(field.Name + " is not a RequestHandler.")
+		}
+
+		handler := value.Field(i).Convert(handlerType).Interface().(RequestHandler)
+		if handler == nil {
+			return errors.New(field.Name + " does not have a handler.")
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		middleware, err := tagMiddleware(parts[1:], options)
+		if err != nil {
+			return errors.New(field.Name + ": " + err.Error())
+		}
+
+		for j := len(middleware) - 1; j >= 0; j-- {
+			handler = middleware[j](handler)
+		}
+
+		if _, ok := handlers[name]; ok {
+			return errors.New(field.Name + ": " + name + " is registered twice.")
+		}
+
+		handlers[name] = handler
+	}
+
+	for name, handler := range handlers {
+		server.SetHandler(name, handler)
+	}
+
+	return nil
+}
+
+// tagMiddleware creates the middleware for each of the "name=value" options.
+func tagMiddleware(tagOptions []string,
+	options map[string]TagOption) ([]Middleware, error) {
+	middleware := make([]Middleware, 0, len(tagOptions))
+	for _, tagOption := range tagOptions {
+		name, value := tagOption, ""
+		if i := strings.Index(tagOption, "="); i >= 0 {
+			name, value = tagOption[:i], tagOption[i+1:]
+		}
+
+		option, ok := options[name]
+		if !ok {
+			option, ok = DefaultTagOptions[name]
+		}
+		if !ok {
+			return nil, errors.New("Unknown option " + name + ".")
+		}
+
+		m, err := option(value)
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+
+		middleware = append(middleware, m)
+	}
+
+	return middleware, nil
+}
@@ -0,0 +1,84 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type testAPI struct {
+	Data     jsonrpc.RequestHandler                          `jsonrpc:"api.data,timeout=1m"`
+	Sum      func(jsonrpc.RequestResponder) jsonrpc.Response `jsonrpc:",auth=admin,auth=root"`
+	Ignored  jsonrpc.RequestHandler                          `jsonrpc:"-"`
+	Untagged jsonrpc.RequestHandler
+	private  jsonrpc.RequestHandler `jsonrpc:"private"`
+}
+
+func TestSimpleServer_RegisterStruct(t *testing.T) {
+	var scopes []string
+	server := jsonrpc.NewSimpleServer()
+
+	err := server.RegisterStruct(&testAPI{
+		Data:     getData,
+		Sum:      sum,
+		Ignored:  getData,
+		Untagged: getData,
+		private:  getData,
+	}, map[string]jsonrpc.TagOption{
+		"auth": func(scope string) (jsonrpc.Middleware, error) {
+			return trace(&scopes, scope), nil
+		},
+	})
+	assert.NoError(t, err)
+
+	for _, method := range []string{"Ignored", "-", "Untagged", "private"} {
+		assert.Nil(t, server.GetHandler(method), method)
+	}
+
+	data := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "api.data", "id": 1}`))
+	assert.Equal(t, []interface{}{"hello", 5.0}, data[0].Result())
+
+	total := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "Sum", "params": [1, 2], "id": 1}`))
+	assert.Equal(t, 3.0, total[0].Result())
+	assert.Equal(t, []string{"admin", "root"}, scopes)
+}
+
+func TestSimpleServer_RegisterStructErrors(t *testing.T) {
+	fail := map[string]jsonrpc.TagOption{
+		"fail": func(value string) (jsonrpc.Middleware, error) {
+			return nil, errors.New("bad value " + value)
+		},
+	}
+
+	for expected, api := range map[string]interface{}{
+		"API must be a struct.": getData,
+		"Data does not have a handler.": struct {
+			Data jsonrpc.RequestHandler `jsonrpc:"data"`
+		}{},
+		"Data is not a RequestHandler.": struct {
+			Data string `jsonrpc:"data"`
+		}{"x"},
+		"Data: Unknown option auth.": struct {
+			Data jsonrpc.RequestHandler `jsonrpc:"data,auth=x"`
+		}{getData},
+		"Data: fail: bad value x": struct {
+			Data jsonrpc.RequestHandler `jsonrpc:"data,fail=x"`
+		}{getData},
+		"Data: timeout: time: invalid duration \"soon\"": struct {
+			Data jsonrpc.RequestHandler `jsonrpc:"data,timeout=soon"`
+		}{getData},
+		"B: data is registered twice.": struct {
+			A jsonrpc.RequestHandler `jsonrpc:"data"`
+			B jsonrpc.RequestHandler `jsonrpc:"data"`
+		}{getData, getData},
+	} {
+		server := jsonrpc.NewSimpleServer()
+		err := server.RegisterStruct(api, fail)
+		assert.EqualError(t, err, expected)
+
+		// Nothing is registered when there is an error.
+		assert.Nil(t, server.GetHandler("data"), expected)
+	}
+}
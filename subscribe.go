@@ -0,0 +1,447 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// SubscriptionMethod is the method of the notifications that carry the events
+// of a subscription. Their params are a SubscriptionEvent.
+const SubscriptionMethod = "rpc.subscription"
+
+// UnsubscribeMethod is called with the id of a subscription, as the only
+// member of the params, to end it. See Subscription.Unsubscribe.
+const UnsubscribeMethod = "rpc.unsubscribe"
+
+// DefaultSubscriptionBuffer is the number of events a subscription holds for
+// its reader when SubscriptionBuffer is not used.
+const DefaultSubscriptionBuffer = 16
+
+// maxEarlyEvents is the number of events that are kept for each subscription
+// whose id has not been returned yet.
+const maxEarlyEvents = 64
+
+// SubscriptionEvent is the params of a notification sent by a server for a
+// subscription. The id is the result of the call that started it:
+//
+//     conn.Notify(jsonrpc.SubscriptionMethod, jsonrpc.SubscriptionEvent{
+//         Subscription: id,
+//         Result:       price,
+//     })
+//
+type SubscriptionEvent struct {
+	Subscription interface{} `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// DropPolicy decides what happens to an event when the buffer of its
+// subscription is full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the event that does not fit. This is the default.
+	DropNewest DropPolicy = iota
+
+	// DropOldest discards the oldest event in the buffer to make room, so the
+	// reader always gets the latest events.
+	DropOldest
+
+	// BlockWhenFull waits for the reader to make room. This holds up every
+	// message of the connection, including responses, until it does.
+	BlockWhenFull
+)
+
+// SubscribeOption changes how the events of a subscription are delivered.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	buffer int
+	policy DropPolicy
+}
+
+// SubscriptionBuffer sets the number of events that are held for the reader.
+func SubscriptionBuffer(size int) SubscribeOption {
+	return func(options *subscribeOptions) {
+		options.buffer = size
+	}
+}
+
+// SubscriptionDropPolicy sets what happens to an event when the buffer is
+// full.
+func SubscriptionDropPolicy(policy DropPolicy) SubscribeOption {
+	return func(options *subscribeOptions) {
+		options.policy = policy
+	}
+}
+
+// SubscriptionInvoker is an Invoker that receives the notifications of its
+// peer, and passes those of subscriptions to its Subscriptions. TCPClient and
+// WebSocketConn implement it.
+type SubscriptionInvoker interface {
+	Invoker
+	Subscriptions() *Subscriptions
+}
+
+// Subscriptions holds the subscriptions of a connection and routes their
+// events to them. A client that implements SubscriptionInvoker passes the
+// params of every SubscriptionMethod notification to Deliver. The zero value
+// is ready to use and it is safe for concurrent use.
+type Subscriptions struct {
+	mutex sync.Mutex
+	byID  map[string]*Subscription
+	err   error
+
+	// subscribing counts the calls that are waiting for the id of a
+	// subscription. While there are any, events with an unknown id are kept
+	// in early, as they may arrive before the response with the id is read.
+	subscribing int
+	early       []subscriptionMessage
+}
+
+type subscriptionMessage struct {
+	Subscription json.RawMessage `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// Deliver gives the event in the params of a SubscriptionMethod notification
+// to its subscription. It returns false if the params are not an event or the
+// subscription is not known.
+func (set *Subscriptions) Deliver(params json.RawMessage) bool {
+	var message subscriptionMessage
+	if json.Unmarshal(params, &message) != nil || message.Subscription == nil {
+		return false
+	}
+
+	var id interface{}
+	if json.Unmarshal(message.Subscription, &id) != nil {
+		return false
+	}
+
+	set.mutex.Lock()
+	subscription := set.byID[idKey(id)]
+	if subscription == nil {
+		if len(set.early) < maxEarlyEvents*set.subscribing {
+			set.early = append(set.early, message)
+			set.mutex.Unlock()
+
+			return true
+		}
+
+		set.mutex.Unlock()
+
+		return false
+	}
+	set.mutex.Unlock()
+
+	subscription.deliver(message.Result)
+
+	return true
+}
+
+// deliverFrame delivers a frame that is a SubscriptionMethod notification. It
+// only decodes the frame if there are subscriptions.
+func (set *Subscriptions) deliverFrame(frame []byte) bool {
+	set.mutex.Lock()
+	active := len(set.byID) > 0 || set.subscribing > 0
+	set.mutex.Unlock()
+
+	if !active {
+		return false
+	}
+
+	var notification struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(frame, &notification) != nil ||
+		notification.Method != SubscriptionMethod || notification.ID != nil {
+		return false
+	}
+
+	return set.Deliver(notification.Params)
+}
+
+// closeAll ends every subscription with err, such as when the connection is
+// closed. Later subscriptions end straight away.
+func (set *Subscriptions) closeAll(err error) {
+	set.mutex.Lock()
+	subscriptions := set.byID
+	set.byID = nil
+	set.early = nil
+	set.err = err
+	set.mutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.close(err)
+	}
+}
+
+func (set *Subscriptions) begin() {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	set.subscribing++
+}
+
+func (set *Subscriptions) end() {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	set.subscribing--
+	if set.subscribing == 0 {
+		set.early = nil
+	}
+}
+
+// add registers the subscription and delivers the events that arrived before
+// it, in order.
+func (set *Subscriptions) add(subscription *Subscription) {
+	set.mutex.Lock()
+	if set.err != nil {
+		err := set.err
+		set.mutex.Unlock()
+		subscription.close(err)
+
+		return
+	}
+
+	if set.byID == nil {
+		set.byID = map[string]*Subscription{}
+	}
+	set.byID[subscription.key] = subscription
+
+	var early []json.RawMessage
+	kept := set.early[:0]
+	for _, message := range set.early {
+		var id interface{}
+		json.Unmarshal(message.Subscription, &id)
+		if idKey(id) == subscription.key {
+			early = append(early, message.Result)
+		} else {
+			kept = append(kept, message)
+		}
+	}
+	set.early = kept
+
+	// The events are delivered before any that arrive later, which wait for
+	// the mutex of the subscription. They cannot wait for room, as the reader
+	// does not have the channel yet.
+	subscription.mutex.Lock()
+	set.mutex.Unlock()
+	defer subscription.mutex.Unlock()
+
+	for _, result := range early {
+		subscription.push(result, false)
+	}
+}
+
+func (set *Subscriptions) remove(subscription *Subscription) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	if set.byID[subscription.key] == subscription {
+		delete(set.byID, subscription.key)
+	}
+}
+
+// Subscription is a subscription started by Subscribe. Its events are read
+// from the channel returned with it, which is closed when it ends.
+type Subscription struct {
+	id      interface{}
+	key     string
+	invoker Invoker
+	set     *Subscriptions
+
+	// push and closeChannel use the typed channel. push is called while
+	// holding mutex, and only waits for room if wait is true.
+	push         func(result json.RawMessage, wait bool)
+	closeChannel func()
+
+	mutex  sync.Mutex
+	closed bool
+	err    error
+
+	closeOnce sync.Once
+	done      chan struct{}
+	dropped   uint64
+}
+
+// Subscribe calls a method of the server that starts a subscription, and
+// returns a channel of its events decoded into T. The result of the call is
+// the id of the subscription, which the server puts in every event it sends
+// as a SubscriptionMethod notification:
+//
+//     prices, subscription, err := jsonrpc.Subscribe[Price](ctx, client,
+//         "prices.subscribe", []string{"EUR"}, jsonrpc.SubscriptionBuffer(100))
+//     if err != nil {
+//         return err
+//     }
+//     defer subscription.Unsubscribe(ctx)
+//
+//     for price := range prices {
+//         update(price)
+//     }
+//
+// The channel holds DefaultSubscriptionBuffer events unless another buffer is
+// given. What happens to events that do not fit is set by the DropPolicy. An
+// event that cannot be decoded into T is dropped.
+func Subscribe[T any](ctx context.Context, client SubscriptionInvoker, method string,
+	params interface{}, options ...SubscribeOption) (<-chan T, *Subscription, error) {
+	config := subscribeOptions{buffer: DefaultSubscriptionBuffer}
+	for _, option := range options {
+		option(&config)
+	}
+
+	set := client.Subscriptions()
+	set.begin()
+	defer set.end()
+
+	var id interface{}
+	if err := CallWith(ctx, client, method, params, &id); err != nil {
+		return nil, nil, err
+	}
+
+	if id == nil {
+		return nil, nil, errors.New("Server did not return a subscription id.")
+	}
+
+	events := make(chan T, config.buffer)
+	subscription := &Subscription{
+		id:           id,
+		key:          idKey(id),
+		invoker:      client,
+		set:          set,
+		closeChannel: func() { close(events) },
+		done:         make(chan struct{}),
+	}
+
+	subscription.push = func(result json.RawMessage, wait bool) {
+		var event T
+		if json.Unmarshal(result, &event) != nil {
+			atomic.AddUint64(&subscription.dropped, 1)
+			return
+		}
+
+		select {
+		case events <- event:
+			return
+
+		default:
+		}
+
+		switch config.policy {
+		case DropOldest:
+			if cap(events) == 0 {
+				atomic.AddUint64(&subscription.dropped, 1)
+				return
+			}
+
+			// The oldest events are taken until the new one fits.
+			for {
+				select {
+				case <-events:
+					atomic.AddUint64(&subscription.dropped, 1)
+
+				default:
+				}
+
+				select {
+				case events <- event:
+					return
+
+				default:
+				}
+			}
+
+		case BlockWhenFull:
+			if !wait {
+				atomic.AddUint64(&subscription.dropped, 1)
+				return
+			}
+
+			select {
+			case events <- event:
+
+			case <-subscription.done:
+			}
+
+		default:
+			atomic.AddUint64(&subscription.dropped, 1)
+		}
+	}
+
+	set.add(subscription)
+
+	return events, subscription, nil
+}
+
+// ID returns the id of the subscription given by the server.
+func (subscription *Subscription) ID() interface{} {
+	return subscription.id
+}
+
+// Dropped returns the number of events that were dropped, because they did
+// not fit in the buffer or could not be decoded.
+func (subscription *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&subscription.dropped)
+}
+
+// Done returns a channel that is closed when the subscription ends.
+func (subscription *Subscription) Done() <-chan struct{} {
+	return subscription.done
+}
+
+// Err returns why the subscription ended, such as the connection being
+// closed. It is nil while the subscription is active and after Unsubscribe.
+func (subscription *Subscription) Err() error {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	return subscription.err
+}
+
+// Unsubscribe ends the subscription and calls UnsubscribeMethod so the server
+// stops sending its events. The channel is closed even if the call fails.
+func (subscription *Subscription) Unsubscribe(ctx context.Context) error {
+	select {
+	case <-subscription.done:
+		return subscription.Err()
+
+	default:
+	}
+
+	subscription.set.remove(subscription)
+	subscription.close(nil)
+
+	return CallWith(ctx, subscription.invoker, UnsubscribeMethod,
+		[]interface{}{subscription.id}, nil)
+}
+
+// deliver pushes an event unless the subscription has ended.
+func (subscription *Subscription) deliver(result json.RawMessage) {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	if !subscription.closed {
+		subscription.push(result, true)
+	}
+}
+
+// close ends the subscription with err and closes the channel. An event that
+// is waiting for room is given up first.
+func (subscription *Subscription) close(err error) {
+	subscription.closeOnce.Do(func() {
+		close(subscription.done)
+
+		subscription.mutex.Lock()
+		defer subscription.mutex.Unlock()
+
+		subscription.closed = true
+		subscription.err = err
+		subscription.closeChannel()
+	})
+}
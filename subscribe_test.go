@@ -0,0 +1,126 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSubscribe(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	unsubscribed := make(chan interface{}, 1)
+	handler.Server.SetHandler("ticks.subscribe", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		conn := jsonrpc.WebSocketConnFromRequest(request)
+
+		// An event sent before the id is received is not lost.
+		conn.Notify(jsonrpc.SubscriptionMethod, jsonrpc.SubscriptionEvent{Subscription: 7, Result: 1})
+		go func() {
+			conn.Notify(jsonrpc.SubscriptionMethod, jsonrpc.SubscriptionEvent{Subscription: 8, Result: 0})
+			conn.Notify(jsonrpc.SubscriptionMethod, jsonrpc.SubscriptionEvent{Subscription: 7, Result: 2})
+			conn.Notify(jsonrpc.SubscriptionMethod, jsonrpc.SubscriptionEvent{Subscription: 7, Result: 3})
+		}()
+
+		return request.NewSuccessResponse(7)
+	})
+	handler.Server.SetHandler(jsonrpc.UnsubscribeMethod, func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		unsubscribed <- request.Params()
+		return request.NewSuccessResponse(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := jsonrpc.DialWebSocket(ctx, strings.Replace(url, "http", "ws", 1)+"/ws", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	ticks, subscription, err := jsonrpc.Subscribe[int](ctx, client, "ticks.subscribe", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, float64(7), subscription.ID())
+
+	assert.Equal(t, 1, <-ticks)
+	assert.Equal(t, 2, <-ticks)
+	assert.Equal(t, 3, <-ticks)
+
+	assert.NoError(t, subscription.Unsubscribe(ctx))
+	assert.Equal(t, []interface{}{7.0}, <-unsubscribed)
+	_, open := <-ticks
+	assert.False(t, open)
+	assert.NoError(t, subscription.Err())
+
+	_, _, err = jsonrpc.Subscribe[int](ctx, client, "missing", nil)
+	assert.EqualError(t, err, "Method not found (-32601)")
+}
+
+func TestSubscribe_DropPolicies(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	client := jsonrpc.NewTCPClient(clientConn)
+
+	flushed := make(chan struct{}, 1)
+	client.SetNotificationHandler(func(method string, params json.RawMessage, state jsonrpc.State) {
+		flushed <- struct{}{}
+	})
+
+	// The server answers each subscribe with the name of the method.
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			var request map[string]interface{}
+			json.Unmarshal(line, &request)
+			response, _ := json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"result":  strings.TrimSuffix(request["method"].(string), ".subscribe"),
+				"id":      request["id"],
+			})
+			serverConn.Write(append(response, '\n'))
+		}
+	}()
+
+	ctx := context.Background()
+	newest, newestSubscription, err := jsonrpc.Subscribe[int](ctx, client, "newest.subscribe",
+		nil, jsonrpc.SubscriptionBuffer(2))
+	assert.NoError(t, err)
+	oldest, oldestSubscription, err := jsonrpc.Subscribe[int](ctx, client, "oldest.subscribe",
+		nil, jsonrpc.SubscriptionBuffer(2), jsonrpc.SubscriptionDropPolicy(jsonrpc.DropOldest))
+	assert.NoError(t, err)
+
+	for _, event := range []string{"1", "2", "3", `"four"`, "5"} {
+		for _, id := range []string{"newest", "oldest"} {
+			serverConn.Write([]byte(`{"jsonrpc":"2.0","method":"rpc.subscription",` +
+				`"params":{"subscription":"` + id + `","result":` + event + `}}` + "\n"))
+		}
+	}
+	serverConn.Write([]byte(`{"jsonrpc":"2.0","method":"flush"}` + "\n"))
+	<-flushed
+
+	assert.Equal(t, 1, <-newest)
+	assert.Equal(t, 2, <-newest)
+	assert.Equal(t, uint64(3), newestSubscription.Dropped())
+
+	assert.Equal(t, 3, <-oldest)
+	assert.Equal(t, 5, <-oldest)
+	assert.Equal(t, uint64(3), oldestSubscription.Dropped())
+
+	// The subscriptions end with the connection.
+	client.Close()
+	<-newestSubscription.Done()
+	_, open := <-newest
+	assert.False(t, open)
+	assert.Error(t, oldestSubscription.Err())
+}
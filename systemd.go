@@ -0,0 +1,81 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ListenersFromSystemd returns the listening sockets passed to the process by
+// systemd socket activation, in the order of the ListenStream= lines of the
+// socket unit. It returns no listeners if the process was not started by
+// socket activation.
+//
+// The LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES environment variables are
+// removed so that they are not passed on to other processes.
+//
+// A service that is rarely used can exit when it has been idle for a while,
+// systemd will keep the socket open and start the service again when the next
+// connection arrives:
+//
+//     listeners, err := jsonrpc.ListenersFromSystemd()
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     go jsonrpc.ServeListeners(listeners, httpServer.Serve)
+//
+//     jsonrpc.WaitIdle(ctx, server, 10*time.Minute, nil)
+//     httpServer.Shutdown(ctx)
+func ListenersFromSystemd() ([]net.Listener, error) {
+	pid := os.Getenv("LISTEN_PID")
+	fds := os.Getenv("LISTEN_FDS")
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	// The variables were meant for another process.
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, errors.New("Invalid LISTEN_FDS: " + fds)
+	}
+
+	return listenersFromFDs(firstInheritedFD, n)
+}
+
+// WaitIdle blocks until the server has not received a payload and has had no
+// active requests for at least timeout, or the context is done. It returns the
+// error of the context if it is done first.
+//
+// Activity is checked once every timeout, so the server will have been idle
+// for between one and two timeouts when WaitIdle returns. A nil clock uses
+// SystemClock.
+func WaitIdle(ctx context.Context, stats StatReporter, timeout time.Duration,
+	clock Clock) error {
+	clock = clockOrSystem(clock)
+	payloads := stats.TotalPayloads()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-clock.After(timeout):
+		}
+
+		latest := stats.TotalPayloads()
+		if latest == payloads && stats.CurrentActiveRequests() == 0 {
+			return nil
+		}
+
+		payloads = latest
+	}
+}
@@ -0,0 +1,150 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// TestSystemdHelperProcess is not a real test. It is the process that is
+// started by TestListenersFromSystemd. Since the pid is not known until the
+// process has started it sets LISTEN_PID itself, like systemd would.
+func TestSystemdHelperProcess(t *testing.T) {
+	if os.Getenv("JSONRPC_SYSTEMD_HELPER") != "1" {
+		return
+	}
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	listeners, err := jsonrpc.ListenersFromSystemd()
+	if err != nil || len(listeners) != 1 || os.Getenv("LISTEN_FDS") != "" {
+		os.Exit(2)
+	}
+
+	os.Exit(serveOneHTTPRequest(listeners[0]))
+}
+
+func TestListenersFromSystemd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd is not available on windows")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+
+	file, err := listener.(*net.TCPListener).File()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSystemdHelperProcess$")
+	cmd.Env = append(os.Environ(), "JSONRPC_SYSTEMD_HELPER=1", "LISTEN_FDS=1",
+		"LISTEN_FDNAMES=jsonrpc")
+	cmd.ExtraFiles = []*os.File{file}
+	if !assert.NoError(t, cmd.Start()) {
+		return
+	}
+
+	assertSubtractOverHTTP(t, listener.Addr().String())
+	assert.NoError(t, cmd.Wait())
+}
+
+func TestListenersFromSystemd_OtherProcess(t *testing.T) {
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	os.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := jsonrpc.ListenersFromSystemd()
+	assert.NoError(t, err)
+	assert.Empty(t, listeners)
+
+	_, ok := os.LookupEnv("LISTEN_FDS")
+	assert.False(t, ok)
+}
+
+func TestListenersFromSystemd_Invalid(t *testing.T) {
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "x")
+
+	_, err := jsonrpc.ListenersFromSystemd()
+	assert.EqualError(t, err, "Invalid LISTEN_FDS: x")
+}
+
+// waitForWaiter blocks until WaitIdle is waiting on the clock.
+func waitForWaiter(clock *jsonrpc.FakeClock) {
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWaitIdle(t *testing.T) {
+	server := newTestServer()
+	clock := jsonrpc.NewFakeClock(epoch)
+	done := make(chan error)
+
+	go func() {
+		done <- jsonrpc.WaitIdle(context.Background(), server, time.Minute, clock)
+	}()
+
+	// Activity during the first minute keeps the server alive.
+	waitForWaiter(clock)
+	server.Handle([]byte(`{"jsonrpc": "2.0", "method": "get_data", "id": 1}`))
+	clock.Advance(time.Minute)
+
+	waitForWaiter(clock)
+	clock.Advance(time.Minute)
+	assert.NoError(t, <-done)
+	assert.Equal(t, epoch.Add(2*time.Minute), clock.Now())
+}
+
+func TestWaitIdle_ActiveRequest(t *testing.T) {
+	server := newTestServer()
+	clock := jsonrpc.NewFakeClock(epoch)
+	done := make(chan error)
+	release := make(chan struct{})
+
+	server.SetHandler("wait", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		<-release
+		return request.NewSuccessResponse(nil)
+	})
+	go server.Handle([]byte(`{"jsonrpc": "2.0", "method": "wait", "id": 1}`))
+	for server.CurrentActiveRequests() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		done <- jsonrpc.WaitIdle(context.Background(), server, time.Minute, clock)
+	}()
+
+	// A request that takes a long time is not idle.
+	waitForWaiter(clock)
+	clock.Advance(time.Minute)
+
+	waitForWaiter(clock)
+	close(release)
+	for server.CurrentActiveRequests() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+
+	assert.NoError(t, <-done)
+}
+
+func TestWaitIdle_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := jsonrpc.WaitIdle(ctx, newTestServer(), time.Minute, nil)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,608 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// TCPServer serves JSON-RPC over plain TCP connections, with each message
+// (request, response or batch) on a single line (see LineFramer). This is what
+// many embedded and legacy systems speak:
+//
+//     tcpServer := jsonrpc.NewTCPServer(server)
+//     log.Fatal(tcpServer.ListenAndServe(":4000"))
+//
+// It can also serve Unix domain sockets, see ListenUnix. Requests on a
+// connection are handled in order. The ClientInfo of every request has the
+// address of the peer, and the context of the request (see
+// ContextFromRequest) is cancelled when the connection is closed.
+type TCPServer struct {
+	Server *SimpleServer
+
+	// MaxMessageSize is the largest message in bytes. Larger messages are
+	// answered with a ParseError and skipped. Zero uses DefaultMaxFrameSize.
+	MaxMessageSize int
+
+	// Options are applied to every accepted connection.
+	Options TCPOptions
+
+	// Detectors, if not empty, choose the framing of each connection from its
+	// first byte (see DetectingFramer) instead of always using LineFramer. Use
+	// DefaultFramerDetectors to accept every built-in framing on one port.
+	Detectors []FramerDetector
+
+	// IdleReaper closes connections that are idle, if it is not nil.
+	IdleReaper *IdleReaper
+
+	// ShutdownNotice, if it is not nil, is sent to every open connection by
+	// Shutdown before it stops reading requests.
+	ShutdownNotice *ShutdownNotice
+
+	mutex     sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]Framer
+	closed    bool
+
+	// draining is closed by Shutdown, and serving counts the connections
+	// being served.
+	draining chan struct{}
+	serving  goroutineGroup
+}
+
+// NewTCPServer creates a TCPServer with the default options.
+func NewTCPServer(server *SimpleServer) *TCPServer {
+	return &TCPServer{Server: server}
+}
+
+// ListenAndServe listens on the TCP address and calls Serve.
+func (tcpServer *TCPServer) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return tcpServer.Serve(listener)
+}
+
+// Serve accepts connections on the listener and serves each of them in its
+// own goroutine. A temporary error from Accept, such as running out of file
+// descriptors, is retried after a delay that grows from 5 milliseconds to a
+// second, as net/http does. It returns nil once Close has been called,
+// otherwise the error that stopped it. The listener is closed when Serve
+// returns.
+func (tcpServer *TCPServer) Serve(listener net.Listener) error {
+	defer listener.Close()
+
+	if !tcpServer.track(listener, nil) {
+		return nil
+	}
+	defer tcpServer.untrack(listener, nil)
+
+	listener = tcpServer.Options.Listener(listener)
+
+	var delay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if tcpServer.isClosed() {
+				return nil
+			}
+
+			if temporary, ok := err.(interface{ Temporary() bool }); ok && temporary.Temporary() {
+				delay = acceptDelay(delay)
+				tcpServer.Server.log(slog.LevelWarn, "Accept failed", nil,
+					"error", err, "retry", delay)
+				time.Sleep(delay)
+				continue
+			}
+
+			return err
+		}
+		delay = 0
+
+		if !tcpServer.track(nil, conn) {
+			conn.Close()
+			return nil
+		}
+
+		go func() {
+			defer tcpServer.serving.done()
+			tcpServer.serveConn(conn)
+		}()
+	}
+}
+
+// acceptDelay returns the delay before the next Accept after a temporary error,
+// given the previous delay.
+func acceptDelay(previous time.Duration) time.Duration {
+	if previous == 0 {
+		return 5 * time.Millisecond
+	}
+
+	if previous *= 2; previous > time.Second {
+		return time.Second
+	}
+
+	return previous
+}
+
+func (tcpServer *TCPServer) serveConn(conn net.Conn) {
+	defer tcpServer.untrack(nil, conn)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	address := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	state := StateWithContext(State{clientInfoStateKey: &ClientInfo{Address: address}}, ctx)
+	var connFramer Framer = NewLineFramer(conn, RecoverySkip, tcpServer.MaxMessageSize)
+	if len(tcpServer.Detectors) > 0 {
+		connFramer = NewDetectingFramer(conn, RecoverySkip, tcpServer.MaxMessageSize,
+			tcpServer.Detectors...)
+	}
+
+	tcpServer.mutex.Lock()
+	if _, ok := tcpServer.conns[conn]; ok {
+		tcpServer.conns[conn] = connFramer
+	}
+	tcpServer.mutex.Unlock()
+
+	framer := &drainingFramer{
+		Framer:   connFramer,
+		draining: tcpServer.drainingChan(),
+	}
+
+	if tcpServer.IdleReaper != nil {
+		tcpServer.IdleReaper.Serve(tcpServer.Server, conn, framer, state)
+	} else {
+		tcpServer.Server.ServeFramer(framer, state)
+	}
+}
+
+// Connections is the number of open connections.
+func (tcpServer *TCPServer) Connections() int {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	return len(tcpServer.conns)
+}
+
+// Close stops every Serve and closes every open connection. Requests that are
+// being handled finish, but their responses are not sent.
+func (tcpServer *TCPServer) Close() error {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	tcpServer.closed = true
+
+	var err error
+	for listener := range tcpServer.listeners {
+		if e := listener.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	for conn := range tcpServer.conns {
+		conn.Close()
+	}
+
+	return err
+}
+
+// Shutdown stops accepting connections and stops reading requests from the
+// open connections, after sending them the ShutdownNotice. The requests being
+// handled finish and their responses are sent before each connection is
+// closed. If ctx is done first, the connections are closed anyway and its
+// error is returned.
+//
+// Shutdown the SimpleServer afterwards to wait for the goroutines that the
+// requests started.
+func (tcpServer *TCPServer) Shutdown(ctx context.Context) error {
+	tcpServer.mutex.Lock()
+	tcpServer.closed = true
+	for listener := range tcpServer.listeners {
+		listener.Close()
+	}
+
+	var framers []Framer
+	for _, framer := range tcpServer.conns {
+		if framer != nil {
+			framers = append(framers, framer)
+		}
+	}
+	tcpServer.mutex.Unlock()
+
+	if err := tcpServer.ShutdownNotice.send(ctx, framers); err != nil {
+		tcpServer.Close()
+		return err
+	}
+
+	tcpServer.mutex.Lock()
+	if tcpServer.draining == nil {
+		tcpServer.draining = make(chan struct{})
+	}
+	select {
+	case <-tcpServer.draining:
+	default:
+		close(tcpServer.draining)
+	}
+
+	// Interrupt the connections that are waiting for a request.
+	for conn := range tcpServer.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	tcpServer.mutex.Unlock()
+
+	select {
+	case <-tcpServer.serving.wait():
+		return nil
+
+	case <-ctx.Done():
+		tcpServer.Close()
+		return ctx.Err()
+	}
+}
+
+func (tcpServer *TCPServer) drainingChan() <-chan struct{} {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	if tcpServer.draining == nil {
+		tcpServer.draining = make(chan struct{})
+	}
+
+	return tcpServer.draining
+}
+
+// track adds a listener or a connection, unless the server is closed. A
+// connection is counted as being served until serveConn returns.
+func (tcpServer *TCPServer) track(listener net.Listener, conn net.Conn) bool {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	if tcpServer.closed {
+		return false
+	}
+
+	if listener != nil {
+		if tcpServer.listeners == nil {
+			tcpServer.listeners = map[net.Listener]struct{}{}
+		}
+		tcpServer.listeners[listener] = struct{}{}
+	}
+
+	if conn != nil {
+		if tcpServer.conns == nil {
+			tcpServer.conns = map[net.Conn]Framer{}
+		}
+		tcpServer.conns[conn] = nil
+		tcpServer.serving.add()
+	}
+
+	return true
+}
+
+func (tcpServer *TCPServer) untrack(listener net.Listener, conn net.Conn) {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	delete(tcpServer.listeners, listener)
+	delete(tcpServer.conns, conn)
+}
+
+func (tcpServer *TCPServer) isClosed() bool {
+	tcpServer.mutex.Lock()
+	defer tcpServer.mutex.Unlock()
+
+	return tcpServer.closed
+}
+
+// TCPClient is an Invoker that sends requests over a single TCP connection
+// with each message on its own line, such as to a TCPServer:
+//
+//     client, err := jsonrpc.DialTCP(ctx, "device.local:4000", jsonrpc.TCPOptions{})
+//     if err != nil {
+//         return err
+//     }
+//     defer client.Close()
+//
+//     var difference int
+//     err = jsonrpc.CallWith(ctx, client, "subtract", []int{42, 23}, &difference)
+//
+// Any number of requests may be in flight at once; responses are matched to
+// their request by id. Notifications sent by the server are passed, in order,
+// to the handler set with SetNotificationHandler. It is safe for concurrent
+// use.
+type TCPClient struct {
+	conn   net.Conn
+	framer *LineFramer
+	ids    SequentialIDGenerator
+
+	mutex   sync.Mutex
+	pending map[string]chan Response
+	handler EventHandler
+
+	subscriptions Subscriptions
+
+	// See SetLenientIDs
+	lenientIDs bool
+	mismatch   IDMismatchHook
+
+	done chan struct{}
+	err  error
+}
+
+// DialTCP connects to the address and returns a client for the connection.
+// See TCPOptions.Dialer, or Dial, to connect through a proxy or with TLS.
+func DialTCP(ctx context.Context, address string, options TCPOptions) (*TCPClient, error) {
+	return Dial(ctx, options, "tcp", address)
+}
+
+// NewTCPClient creates a client that uses an open connection, which is closed
+// by Close.
+func NewTCPClient(conn net.Conn) *TCPClient {
+	client := &TCPClient{
+		conn:    conn,
+		framer:  NewLineFramer(conn, RecoverySkip, 0),
+		pending: map[string]chan Response{},
+		done:    make(chan struct{}),
+	}
+
+	go client.read()
+
+	return client
+}
+
+// SetNotificationHandler sets the handler that is called (with a nil State)
+// for every notification sent by the server, except the events of
+// subscriptions (see Subscribe). Notifications are ignored if it is nil.
+func (client *TCPClient) SetNotificationHandler(handler EventHandler) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.handler = handler
+}
+
+// Subscriptions returns the subscriptions of the connection, see Subscribe.
+func (client *TCPClient) Subscriptions() *Subscriptions {
+	return &client.subscriptions
+}
+
+// IDMismatchHook is called when a response is matched to a request that has
+// a different id, see SetLenientIDs.
+type IDMismatchHook func(id interface{}, response Response)
+
+// SetLenientIDs makes the client tolerate broken servers that send the
+// response to a call without an id, or with an id that does not match. When
+// lenient, such a response is given to the only call that is waiting for a
+// response, and hook (which may be nil) is called with the id of that call so
+// the server can be reported:
+//
+//     client.SetLenientIDs(true, func(id interface{}, response jsonrpc.Response) {
+//         logger.Warn("Response has the wrong id", "id", id, "got", response.ID())
+//     })
+//
+// The response is still dropped when several calls (or a batch) are waiting,
+// as there is no way to tell which of them it belongs to.
+func (client *TCPClient) SetLenientIDs(lenient bool, hook IDMismatchHook) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.lenientIDs = lenient
+	client.mismatch = hook
+}
+
+// Invoke sends a request and waits for its response, for ctx to be done or for
+// the connection to be closed. The RequestExtensions of ctx are sent with the
+// request.
+func (client *TCPClient) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	id := client.ids.NextID()
+	key := idKey(id)
+	responses := make(chan Response, 1)
+
+	client.mutex.Lock()
+	client.pending[key] = responses
+	client.mutex.Unlock()
+
+	defer func() {
+		client.mutex.Lock()
+		delete(client.pending, key)
+		client.mutex.Unlock()
+	}()
+
+	if err := client.send(ctx, method, params, id); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-responses:
+		return response, nil
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-client.done:
+		return nil, client.err
+	}
+}
+
+// Notify sends a notification. It returns once the notification is written.
+func (client *TCPClient) Notify(ctx context.Context, method string, params interface{}) error {
+	return client.send(ctx, method, params, nil)
+}
+
+func (client *TCPClient) send(ctx context.Context, method string,
+	params interface{}, id interface{}) error {
+	data, err := json.Marshal(requestMessage(ctx, method, params, id))
+	if err != nil {
+		return err
+	}
+
+	return client.write(data)
+}
+
+// requestMessage is a request with the RequestExtensions of ctx. A nil id is a
+// notification.
+func requestMessage(ctx context.Context, method string, params interface{},
+	id interface{}) map[string]interface{} {
+	message := map[string]interface{}{}
+	for key, value := range RequestExtensions(ctx) {
+		message[key] = value
+	}
+
+	message["jsonrpc"] = "2.0"
+	message["method"] = method
+	if params != nil {
+		message["params"] = params
+	}
+	if id != nil {
+		message["id"] = id
+	}
+
+	return message
+}
+
+func (client *TCPClient) write(data []byte) error {
+	select {
+	case <-client.done:
+		return client.err
+
+	default:
+	}
+
+	return client.framer.WriteFrame(data)
+}
+
+// Done returns a channel that is closed when the connection is closed.
+func (client *TCPClient) Done() <-chan struct{} {
+	return client.done
+}
+
+// Close closes the connection. Requests that are waiting for a response fail.
+func (client *TCPClient) Close() error {
+	err := client.conn.Close()
+	<-client.done
+
+	return err
+}
+
+// read delivers every message from the server until the connection fails.
+func (client *TCPClient) read() {
+	for {
+		frame, err := client.framer.ReadFrame()
+		if err != nil {
+			if frameErr, ok := err.(*FrameError); ok && frameErr.Recovered {
+				continue
+			}
+
+			if err == io.EOF || errors.Is(err, net.ErrClosed) {
+				err = errors.New("Connection is closed.")
+			}
+
+			client.err = err
+			client.subscriptions.closeAll(err)
+			close(client.done)
+
+			return
+		}
+
+		var message struct {
+			Method *string         `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(frame, &message) == nil && message.Method != nil {
+			if *message.Method == SubscriptionMethod &&
+				client.subscriptions.Deliver(message.Params) {
+				continue
+			}
+
+			client.mutex.Lock()
+			handler := client.handler
+			client.mutex.Unlock()
+
+			if handler != nil {
+				handler(*message.Method, message.Params, nil)
+			}
+
+			continue
+		}
+
+		responses, err := NewResponsesFromJSON(frame)
+		if err != nil {
+			continue
+		}
+
+		client.mutex.Lock()
+		if pending, hook := client.mismatched(responses); pending != nil {
+			client.mutex.Unlock()
+
+			// The hook is called first, so it has finished when the call
+			// returns.
+			if hook != nil {
+				hook()
+			}
+			select {
+			case pending <- responses[0]:
+			default:
+			}
+
+			continue
+		}
+
+		for _, response := range responses {
+			select {
+			case client.pending[idKey(response.ID())] <- response:
+			default:
+			}
+		}
+		client.mutex.Unlock()
+	}
+}
+
+// mismatched returns the call that a single response with an unknown id is
+// given to when the client is lenient, and a function that calls the
+// IDMismatchHook (or nil). It must be called while holding the mutex.
+func (client *TCPClient) mismatched(responses Responses) (chan Response, func()) {
+	if !client.lenientIDs || len(responses) != 1 || len(client.pending) != 1 {
+		return nil, nil
+	}
+
+	if _, ok := client.pending[idKey(responses[0].ID())]; ok {
+		return nil, nil
+	}
+
+	for key, pending := range client.pending {
+		hook := client.mismatch
+		if hook == nil {
+			return pending, nil
+		}
+
+		return pending, func() {
+			var id interface{}
+			json.Unmarshal([]byte(key), &id)
+
+			hook(id, responses[0])
+		}
+	}
+
+	return nil, nil
+}
+
+// idKey is the JSON encoding of an id, so that an int64 that was sent matches
+// the float64 that is received.
+func idKey(id interface{}) string {
+	data, _ := json.Marshal(id)
+
+	return string(data)
+}
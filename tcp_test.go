@@ -0,0 +1,247 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func newTCPServer(t *testing.T, server *jsonrpc.SimpleServer) (*jsonrpc.TCPServer, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpServer := jsonrpc.NewTCPServer(server)
+	served := make(chan error, 1)
+	go func() {
+		served <- tcpServer.Serve(listener)
+	}()
+
+	t.Cleanup(func() {
+		tcpServer.Close()
+		assert.NoError(t, <-served)
+	})
+
+	return tcpServer, listener.Addr().String()
+}
+
+func TestTCPServer(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("client", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(jsonrpc.ClientInfoFromRequest(request).Address)
+	})
+	_, address := newTCPServer(t, server)
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notify_hello"}` + "\n" +
+		`{"jsonrpc"` + "\n" +
+		`[{"jsonrpc":"2.0","method":"client","id":2}]` + "\n"))
+
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{
+		`{"jsonrpc":"2.0","result":19,"id":1}`,
+		`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Message is not valid JSON."},"id":null}`,
+		`[{"jsonrpc":"2.0","result":"127.0.0.1","id":2}]`,
+	} {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.JSONEq(t, expected, line)
+	}
+}
+
+func TestTCPClient(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	server := newTestServer()
+	server.SetHandler("hang", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		close(started)
+		<-release
+		return request.NewSuccessResponse(nil)
+	})
+	tcpServer, address := newTCPServer(t, server)
+
+	client, err := jsonrpc.DialTCP(context.Background(), address, jsonrpc.TCPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), client, "subtract",
+		[]int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+
+	err = jsonrpc.CallWith(context.Background(), client, "missing", nil, nil)
+	assert.EqualError(t, err, "Method not found (-32601)")
+
+	assert.NoError(t, client.Notify(context.Background(), "notify_hello", nil))
+	assert.Equal(t, 1, tcpServer.Connections())
+
+	// Closing the server fails the requests that are waiting.
+	done := make(chan error)
+	go func() {
+		_, err := client.Invoke(context.Background(), "hang", nil)
+		done <- err
+	}()
+
+	<-started
+	tcpServer.Close()
+	assert.EqualError(t, <-done, "Connection is closed.")
+	<-client.Done()
+}
+
+func TestTCPClient_Notifications(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	client := jsonrpc.NewTCPClient(clientConn)
+	defer client.Close()
+
+	notifications := make(chan string, 1)
+	client.SetNotificationHandler(func(method string, params json.RawMessage, state jsonrpc.State) {
+		notifications <- method + " " + string(params)
+	})
+
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		line, _ := reader.ReadBytes('\n')
+
+		var request map[string]interface{}
+		json.Unmarshal(line, &request)
+		response, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"result":  request["method"],
+			"id":      request["id"],
+		})
+
+		serverConn.Write([]byte(`{"jsonrpc":"2.0","method":"tick","params":[1]}` + "\n"))
+		serverConn.Write(append(response, '\n'))
+
+		// Ignore the rest.
+		for {
+			if _, err := reader.ReadBytes('\n'); err != nil {
+				return
+			}
+		}
+	}()
+
+	response, err := client.Invoke(context.Background(), "echo", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "echo", response.Result())
+	assert.Equal(t, "tick [1]", <-notifications)
+
+	// The context ends a request that is never answered.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err = client.Invoke(ctx, "echo", nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTCPClient_LenientIDs(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	client := jsonrpc.NewTCPClient(clientConn)
+	defer client.Close()
+
+	// The server answers "none" without an id and "wrong" with the wrong id.
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			var request map[string]interface{}
+			json.Unmarshal(line, &request)
+			response := map[string]interface{}{"jsonrpc": "2.0", "result": request["method"]}
+			if request["method"] == "wrong" {
+				response["id"] = 999
+			}
+
+			data, _ := json.Marshal(response)
+			serverConn.Write(append(data, '\n'))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Invoke(ctx, "none", nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	var mismatches []interface{}
+	client.SetLenientIDs(true, func(id interface{}, response jsonrpc.Response) {
+		mismatches = append(mismatches, response.ID())
+	})
+
+	for _, method := range []string{"none", "wrong"} {
+		response, err := client.Invoke(context.Background(), method, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, method, response.Result())
+	}
+
+	assert.Equal(t, []interface{}{nil, float64(999)}, mismatches)
+}
+
+// temporaryError is an Accept error that net/http would retry.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "Too many open files." }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+// flakyListener fails the first Accept calls with a temporaryError.
+type flakyListener struct {
+	net.Listener
+	failures int32
+}
+
+func (listener *flakyListener) Accept() (net.Conn, error) {
+	if atomic.AddInt32(&listener.failures, -1) >= 0 {
+		return nil, temporaryError{}
+	}
+
+	return listener.Listener.Accept()
+}
+
+func TestTCPServer_TemporaryAcceptError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpServer := jsonrpc.NewTCPServer(newTestServer())
+	served := make(chan error, 1)
+	go func() {
+		served <- tcpServer.Serve(&flakyListener{Listener: listener, failures: 3})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := jsonrpc.DialTCP(ctx, listener.Addr().String(), jsonrpc.TCPOptions{})
+	if assert.NoError(t, err) {
+		var sum int
+		assert.NoError(t, jsonrpc.CallWith(ctx, client, "sum", []int{1, 2}, &sum))
+		assert.Equal(t, 3, sum)
+		client.Close()
+	}
+
+	tcpServer.Close()
+	assert.NoError(t, <-served)
+}
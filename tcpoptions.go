@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// TCPOptions tune the sockets used by a transport. The zero value leaves the
+// defaults of the operating system and the net package unchanged.
+type TCPOptions struct {
+	// KeepAlive is how long a connection must be idle before TCP keep-alive
+	// probes are sent. Zero uses the default of the net package (15 seconds)
+	// and a negative value disables keep-alives.
+	KeepAlive time.Duration
+
+	// KeepAliveInterval is the time between keep-alive probes once they have
+	// started. Zero uses the operating system default. It is only supported on
+	// Linux, FreeBSD, NetBSD and DragonFly and is ignored elsewhere.
+	KeepAliveInterval time.Duration
+
+	// Nagle enables Nagle's algorithm (turns off TCP_NODELAY) so that small
+	// writes are combined into fewer packets. This improves throughput for
+	// bulk transfers at the cost of latency. The net package disables it by
+	// default.
+	Nagle bool
+
+	// ReadBuffer and WriteBuffer are the sizes of the socket buffers in bytes.
+	// Zero uses the operating system default.
+	ReadBuffer  int
+	WriteBuffer int
+
+	// Linger is how long Close will wait for unsent data to be delivered.
+	// Zero uses the operating system default (Close returns immediately and
+	// the data is sent in the background). A negative value discards unsent
+	// data and resets the connection on Close.
+	Linger time.Duration
+
+	// Dialer makes the connections of DialContext, such as through a proxy.
+	// The options are applied to the connections it returns, when they are
+	// TCP connections. A net.Dialer with KeepAlive is used if it is nil.
+	Dialer Dialer
+}
+
+// LowLatencyTCPOptions are suitable for small requests that must be answered
+// as quickly as possible, where a dead peer must be noticed quickly.
+var LowLatencyTCPOptions = TCPOptions{
+	KeepAlive:         5 * time.Second,
+	KeepAliveInterval: time.Second,
+	Linger:            -1,
+}
+
+// BulkTCPOptions are suitable for large requests and responses where
+// throughput matters more than latency.
+var BulkTCPOptions = TCPOptions{
+	Nagle:       true,
+	ReadBuffer:  4 << 20,
+	WriteBuffer: 4 << 20,
+}
+
+// Apply sets the options on a connection. Options that do not apply to the
+// type of connection (such as KeepAlive on a unix socket) are ignored.
+func (options TCPOptions) Apply(conn net.Conn) error {
+	if options.ReadBuffer > 0 {
+		if c, ok := conn.(interface{ SetReadBuffer(int) error }); ok {
+			if err := c.SetReadBuffer(options.ReadBuffer); err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.WriteBuffer > 0 {
+		if c, ok := conn.(interface{ SetWriteBuffer(int) error }); ok {
+			if err := c.SetWriteBuffer(options.WriteBuffer); err != nil {
+				return err
+			}
+		}
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case options.KeepAlive < 0:
+		if err := tcpConn.SetKeepAlive(false); err != nil {
+			return err
+		}
+
+	case options.KeepAlive > 0:
+		if err := tcpConn.SetKeepAlive(true); err != nil {
+			return err
+		}
+		if err := tcpConn.SetKeepAlivePeriod(options.KeepAlive); err != nil {
+			return err
+		}
+	}
+
+	if options.KeepAlive >= 0 && options.KeepAliveInterval > 0 {
+		if err := setKeepAliveInterval(tcpConn, options.KeepAliveInterval); err != nil {
+			return err
+		}
+	}
+
+	if options.Nagle {
+		if err := tcpConn.SetNoDelay(false); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case options.Linger < 0:
+		return tcpConn.SetLinger(0)
+
+	case options.Linger > 0:
+		// Round up so that a linger of less than a second is not treated as
+		// zero.
+		return tcpConn.SetLinger(int((options.Linger + time.Second - 1) / time.Second))
+	}
+
+	return nil
+}
+
+// Listener returns a listener that applies the options to every connection it
+// accepts. A connection that the options cannot be applied to is closed and
+// the next connection is accepted instead.
+func (options TCPOptions) Listener(listener net.Listener) net.Listener {
+	return &tcpOptionsListener{listener, options}
+}
+
+// DialContext connects to the address and applies the options to the
+// connection.
+func (options TCPOptions) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{KeepAlive: options.KeepAlive}
+	}
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := options.Apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+type tcpOptionsListener struct {
+	net.Listener
+	options TCPOptions
+}
+
+func (listener *tcpOptionsListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := listener.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := listener.options.Apply(conn); err != nil {
+			conn.Close()
+			continue
+		}
+
+		return conn, nil
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux || freebsd || netbsd || dragonfly
+
+package jsonrpc
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+func setKeepAliveInterval(conn *net.TCPConn, interval time.Duration) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// Round up so that an interval of less than a second is not treated as
+	// zero.
+	seconds := int((interval + time.Second - 1) / time.Second)
+
+	controlErr := raw.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP,
+			syscall.TCP_KEEPINTVL, seconds)
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !freebsd && !netbsd && !dragonfly
+
+package jsonrpc
+
+import (
+	"net"
+	"time"
+)
+
+// The interval cannot be set on this platform, see TCPOptions.
+func setKeepAliveInterval(conn *net.TCPConn, interval time.Duration) error {
+	return nil
+}
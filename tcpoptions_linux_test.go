@@ -0,0 +1,63 @@
+package jsonrpc_test
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func getsockopt(t *testing.T, conn net.Conn, level, option int) int {
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	raw.Control(func(fd uintptr) {
+		value, err = syscall.GetsockoptInt(int(fd), level, option)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestTCPOptions_Linux(t *testing.T) {
+	options := jsonrpc.TCPOptions{
+		KeepAlive:         7 * time.Second,
+		KeepAliveInterval: 1500 * time.Millisecond,
+		Nagle:             true,
+		ReadBuffer:        64 << 10,
+		WriteBuffer:       32 << 10,
+	}
+
+	server, client := tcpPair(t, options.Listener, options.DialContext)
+	defer server.Close()
+	defer client.Close()
+
+	for _, conn := range []net.Conn{server, client} {
+		assert.Equal(t, 1, getsockopt(t, conn, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE))
+		assert.Equal(t, 7, getsockopt(t, conn, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE))
+		assert.Equal(t, 2, getsockopt(t, conn, syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL))
+		assert.Equal(t, 0, getsockopt(t, conn, syscall.IPPROTO_TCP, syscall.TCP_NODELAY))
+
+		// Linux doubles the requested buffer sizes.
+		assert.Equal(t, 2*options.ReadBuffer,
+			getsockopt(t, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF))
+		assert.Equal(t, 2*options.WriteBuffer,
+			getsockopt(t, conn, syscall.SOL_SOCKET, syscall.SO_SNDBUF))
+	}
+
+	options = jsonrpc.TCPOptions{KeepAlive: -1}
+	server, client = tcpPair(t, options.Listener, options.DialContext)
+	defer server.Close()
+	defer client.Close()
+
+	assert.Equal(t, 0, getsockopt(t, server, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE))
+	assert.Equal(t, 1, getsockopt(t, server, syscall.IPPROTO_TCP, syscall.TCP_NODELAY))
+}
@@ -0,0 +1,69 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// tcpPair returns both ends of a TCP connection. The server end is accepted
+// through the listener returned by wrap.
+func tcpPair(t *testing.T, wrap func(net.Listener) net.Listener,
+	dial func(ctx context.Context, network, address string) (net.Conn, error)) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, _ := wrap(listener).Accept()
+		accepted <- conn
+	}()
+
+	client, err := dial(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return <-accepted, client
+}
+
+func TestTCPOptions(t *testing.T) {
+	for name, options := range map[string]jsonrpc.TCPOptions{
+		"default":     {},
+		"low latency": jsonrpc.LowLatencyTCPOptions,
+		"bulk":        jsonrpc.BulkTCPOptions,
+		"no keepalive and linger": {
+			KeepAlive: -1,
+			Linger:    1500 * time.Millisecond,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server, client := tcpPair(t, options.Listener, options.DialContext)
+			defer server.Close()
+			defer client.Close()
+
+			_, err := client.Write([]byte("ping"))
+			assert.NoError(t, err)
+
+			buffer := make([]byte, 4)
+			_, err = server.Read(buffer)
+			assert.NoError(t, err)
+			assert.Equal(t, "ping", string(buffer))
+		})
+	}
+}
+
+func TestTCPOptions_ApplyIgnoresOtherConnections(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	assert.NoError(t, jsonrpc.BulkTCPOptions.Apply(server))
+}
@@ -0,0 +1,311 @@
+package jsonrpc
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits a rate of bytes. Up to Burst bytes may be used at once,
+// after which bytes become available at Rate per second. It is safe for
+// concurrent use, and concurrent waiters are served in the order they ask.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+	clock Clock
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket. A burst less than rate is set to
+// rate, so that a second's worth of bytes can always be sent at once. The clock
+// may be nil to use SystemClock.
+func NewTokenBucket(rate, burst int, clock Clock) *TokenBucket {
+	if burst < rate {
+		burst = rate
+	}
+
+	clock = clockOrSystem(clock)
+
+	return &TokenBucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		clock:  clock,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// reserve takes n bytes from the bucket and returns how long the caller must
+// wait before using them.
+func (bucket *TokenBucket) reserve(n int) time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	now := bucket.clock.Now()
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+
+	// The bucket may go negative, which makes later callers wait their turn.
+	bucket.tokens -= float64(n)
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+// refund gives back n bytes that were reserved but not used.
+func (bucket *TokenBucket) refund(n int) {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.tokens += float64(n)
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// Wait blocks until n bytes may be used.
+func (bucket *TokenBucket) Wait(n int) {
+	bucket.wait(n, time.Time{}, nil)
+}
+
+// wait is Wait that gives up when done is closed, with net.ErrClosed, or when
+// the deadline passes first, with os.ErrDeadlineExceeded. A zero deadline
+// does not limit it. The bytes are given back if it gives up.
+func (bucket *TokenBucket) wait(n int, deadline time.Time, done <-chan struct{}) error {
+	delay := bucket.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	var err error
+	if !deadline.IsZero() {
+		if remaining := deadline.Sub(bucket.clock.Now()); remaining < delay {
+			delay, err = remaining, os.ErrDeadlineExceeded
+		}
+	}
+
+	if delay > 0 {
+		select {
+		case <-bucket.clock.After(delay):
+
+		case <-done:
+			err = net.ErrClosed
+		}
+	}
+
+	if err != nil {
+		bucket.refund(n)
+	}
+
+	return err
+}
+
+// maxChunk is the most bytes a single read or write may use, so that one large
+// write does not stall other connections sharing the bucket.
+func (bucket *TokenBucket) maxChunk() int {
+	return int(bucket.burst)
+}
+
+// ThrottleConn limits the rate of bytes read from and written to conn. Either
+// bucket may be nil to leave that direction unlimited. A bucket may be shared
+// by many connections to limit them together. A read or write that is waiting
+// for the bucket stops when the connection is closed, or when its deadline
+// passes (measured with the Clock of the bucket).
+func ThrottleConn(conn net.Conn, read, write *TokenBucket) net.Conn {
+	return &throttledConn{Conn: conn, read: read, write: write, done: make(chan struct{})}
+}
+
+type throttledConn struct {
+	net.Conn
+	read, write *TokenBucket
+	release     func()
+	closeOnce   sync.Once
+
+	// done is closed by Close, to stop waiting for the buckets.
+	done chan struct{}
+
+	mutex                       sync.Mutex
+	readDeadline, writeDeadline time.Time
+}
+
+func (conn *throttledConn) SetDeadline(t time.Time) error {
+	conn.mutex.Lock()
+	conn.readDeadline, conn.writeDeadline = t, t
+	conn.mutex.Unlock()
+
+	return conn.Conn.SetDeadline(t)
+}
+
+func (conn *throttledConn) SetReadDeadline(t time.Time) error {
+	conn.mutex.Lock()
+	conn.readDeadline = t
+	conn.mutex.Unlock()
+
+	return conn.Conn.SetReadDeadline(t)
+}
+
+func (conn *throttledConn) SetWriteDeadline(t time.Time) error {
+	conn.mutex.Lock()
+	conn.writeDeadline = t
+	conn.mutex.Unlock()
+
+	return conn.Conn.SetWriteDeadline(t)
+}
+
+// deadlines returns the read and write deadlines.
+func (conn *throttledConn) deadlines() (time.Time, time.Time) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	return conn.readDeadline, conn.writeDeadline
+}
+
+func (conn *throttledConn) Read(p []byte) (int, error) {
+	if conn.read == nil {
+		return conn.Conn.Read(p)
+	}
+
+	if len(p) > conn.read.maxChunk() {
+		p = p[:conn.read.maxChunk()]
+	}
+
+	// The bytes are paid for after they have been read, as it is not known
+	// how many there will be.
+	n, err := conn.Conn.Read(p)
+	deadline, _ := conn.deadlines()
+	if waitErr := conn.read.wait(n, deadline, conn.done); err == nil {
+		err = waitErr
+	}
+
+	return n, err
+}
+
+func (conn *throttledConn) Write(p []byte) (int, error) {
+	if conn.write == nil {
+		return conn.Conn.Write(p)
+	}
+
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > conn.write.maxChunk() {
+			chunk = chunk[:conn.write.maxChunk()]
+		}
+
+		_, deadline := conn.deadlines()
+		if err := conn.write.wait(len(chunk), deadline, conn.done); err != nil {
+			return written, err
+		}
+
+		n, err := conn.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+func (conn *throttledConn) Close() error {
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		if conn.release != nil {
+			conn.release()
+		}
+	})
+
+	return conn.Conn.Close()
+}
+
+// Throttle limits the bandwidth of connections by identity, such as the
+// address or ClientInfo ID of the client. Every connection with the same
+// identity shares the same limits, so a client cannot get more bandwidth by
+// opening more connections.
+//
+//     throttle := &jsonrpc.Throttle{ReadRate: 64 << 10, WriteRate: 256 << 10}
+//
+//     conn, err := listener.Accept()
+//     host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+//     conn = throttle.Conn(host, conn)
+//
+// It is safe for concurrent use.
+type Throttle struct {
+	// ReadRate and WriteRate are in bytes per second. Zero is unlimited.
+	ReadRate  int
+	WriteRate int
+
+	// Burst is the most bytes that may be used at once in each direction.
+	// Zero uses one second of the rate.
+	Burst int
+
+	// Clock is used to wait for tokens. SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex      sync.Mutex
+	identities map[string]*throttledIdentity
+}
+
+type throttledIdentity struct {
+	read, write *TokenBucket
+	conns       int
+}
+
+// Conn limits conn together with every other open connection of the identity.
+// The limits of an identity are forgotten when all of its connections have
+// been closed.
+func (throttle *Throttle) Conn(identity string, conn net.Conn) net.Conn {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	if throttle.identities == nil {
+		throttle.identities = map[string]*throttledIdentity{}
+	}
+
+	buckets := throttle.identities[identity]
+	if buckets == nil {
+		buckets = &throttledIdentity{}
+		if throttle.ReadRate > 0 {
+			buckets.read = NewTokenBucket(throttle.ReadRate, throttle.Burst, throttle.Clock)
+		}
+		if throttle.WriteRate > 0 {
+			buckets.write = NewTokenBucket(throttle.WriteRate, throttle.Burst, throttle.Clock)
+		}
+		throttle.identities[identity] = buckets
+	}
+	buckets.conns++
+
+	return &throttledConn{
+		Conn:  conn,
+		read:  buckets.read,
+		write: buckets.write,
+		done:  make(chan struct{}),
+		release: func() {
+			throttle.mutex.Lock()
+			defer throttle.mutex.Unlock()
+
+			if buckets.conns--; buckets.conns == 0 {
+				delete(throttle.identities, identity)
+			}
+		},
+	}
+}
+
+// Identities returns the number of identities with open connections.
+func (throttle *Throttle) Identities() int {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	return len(throttle.identities)
+}
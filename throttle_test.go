@@ -0,0 +1,173 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := jsonrpc.NewFakeClock(epoch)
+	bucket := jsonrpc.NewTokenBucket(100, 0, clock)
+
+	// The bucket starts full.
+	bucket.Wait(100)
+	assert.Equal(t, 0, clock.Waiters())
+
+	done := make(chan struct{})
+	go func() {
+		bucket.Wait(50)
+		close(done)
+	}()
+
+	waitForWaiter(clock)
+	clock.Advance(499 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Wait returned early.")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	<-done
+
+	// Unused tokens accumulate up to the burst.
+	clock.Advance(time.Hour)
+	bucket.Wait(100)
+	assert.Equal(t, 0, clock.Waiters())
+}
+
+func TestThrottleConn(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	conn := jsonrpc.ThrottleConn(serverConn, nil, jsonrpc.NewTokenBucket(100, 100, clock))
+	defer conn.Close()
+
+	go conn.Write(make([]byte, 250))
+
+	// The write is split into chunks of the burst.
+	buffer := make([]byte, 250)
+	n, err := io.ReadAtLeast(clientConn, buffer, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+
+	waitForWaiter(clock)
+	clock.Advance(time.Second)
+	n, err = io.ReadFull(clientConn, buffer[:100])
+	assert.NoError(t, err)
+
+	waitForWaiter(clock)
+	clock.Advance(500 * time.Millisecond)
+	n, err = io.ReadFull(clientConn, buffer[:50])
+	assert.NoError(t, err)
+	assert.Equal(t, 50, n)
+}
+
+func TestThrottleConn_Read(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	conn := jsonrpc.ThrottleConn(serverConn, jsonrpc.NewTokenBucket(10, 10, clock), nil)
+	defer conn.Close()
+
+	go clientConn.Write(make([]byte, 30))
+
+	buffer := make([]byte, 30)
+	n, err := conn.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	done := make(chan int)
+	go func() {
+		n, _ := conn.Read(buffer)
+		done <- n
+	}()
+
+	// The second read is paid for after it has happened.
+	waitForWaiter(clock)
+	clock.Advance(time.Second)
+	assert.Equal(t, 10, <-done)
+}
+
+func TestThrottleConn_Close(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	bucket := jsonrpc.NewTokenBucket(100, 100, clock)
+	conn := jsonrpc.ThrottleConn(serverConn, nil, bucket)
+	bucket.Wait(100)
+
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(make([]byte, 100))
+		done <- err
+	}()
+
+	// Closing the connection stops the write from waiting for the bucket.
+	waitForWaiter(clock)
+	conn.Close()
+	assert.True(t, errors.Is(<-done, net.ErrClosed))
+}
+
+func TestThrottleConn_Deadline(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	bucket := jsonrpc.NewTokenBucket(100, 100, clock)
+	conn := jsonrpc.ThrottleConn(serverConn, nil, bucket)
+	defer conn.Close()
+	bucket.Wait(100)
+
+	// The bytes are not available until a second from now.
+	conn.SetWriteDeadline(epoch.Add(500 * time.Millisecond))
+
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(make([]byte, 100))
+		done <- err
+	}()
+
+	waitForWaiter(clock)
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, errors.Is(<-done, os.ErrDeadlineExceeded))
+
+	// The bytes that were not written are given back.
+	bucket.Wait(50)
+	assert.Equal(t, 0, clock.Waiters())
+}
+
+func TestThrottle(t *testing.T) {
+	throttle := &jsonrpc.Throttle{WriteRate: 100, Clock: jsonrpc.NewFakeClock(epoch)}
+
+	a1, _ := net.Pipe()
+	a2, _ := net.Pipe()
+	b, _ := net.Pipe()
+
+	conns := []net.Conn{
+		throttle.Conn("alice", a1),
+		throttle.Conn("alice", a2),
+		throttle.Conn("bob", b),
+	}
+	assert.Equal(t, 2, throttle.Identities())
+
+	conns[0].Close()
+	conns[0].Close()
+	assert.Equal(t, 2, throttle.Identities())
+
+	conns[1].Close()
+	assert.Equal(t, 1, throttle.Identities())
+
+	conns[2].Close()
+	assert.Equal(t, 0, throttle.Identities())
+}
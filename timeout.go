@@ -0,0 +1,161 @@
+package jsonrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutErrorType is the ErrorDetails type of a request that took too long.
+const TimeoutErrorType = "urn:jsonrpc:error:timeout"
+
+// Timeout returns a middleware that responds with a TimeLimitExceeded error, like
+// MethodLimits.MaxTime, if the handler has not returned within d, as told by
+// the Clock of the server (see SetClock). The handler is given a context (see
+// ContextFromRequest) that is cancelled at the same time, so a handler that
+// uses it can give up. The handler keeps running in the background and its
+// response is discarded. A request that is cancelled before then, such as when
+// the client goes away, is not a TimeLimitExceeded error; the handler sees the
+// cancellation through its context and answers as it sees fit. A panic in the
+// handler is passed on as if the middleware was not there.
+func Timeout(d time.Duration) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			return runWithin(request, d, next, func(request RequestResponder) Response {
+				return timeLimitResponse(request, d)
+			})
+		}
+	}
+}
+
+// timeLimitResponse is the response to a request whose handler did not finish
+// within d. It is not marked as retryable, since the handler may still be
+// running and a retry would do the work twice.
+func timeLimitResponse(request RequestResponder, d time.Duration) Response {
+	return request.NewErrorResponseWithData(TimeLimitExceeded, "Time limit exceeded",
+		NewErrorDetails(TimeoutErrorType).
+			WithDetail("The method did not finish within "+d.String()+"."))
+}
+
+// runWithin calls next with a context that is cancelled once d has passed on
+// the Clock of the server. If next has not returned by then the response of
+// expired is returned instead. If the request is cancelled, or its own
+// deadline passes, first, the handler learns of it through its context and
+// its response is returned as normal.
+//
+// A handler that is still running when the time is up is left to finish in
+// the background. The server keeps track of it like a goroutine started with
+// Go, so that Shutdown waits for it, and the payload it came from holds on to
+// its memory until it returns.
+func runWithin(request RequestResponder, d time.Duration, next RequestHandler,
+	expired func(request RequestResponder) Response) Response {
+	parent := ContextFromRequest(request)
+	server, _ := parent.Value(goScopeKey{}).(*SimpleServer)
+	scope, _ := parent.Value(payloadScopeKey{}).(*payloadScope)
+
+	var clock Clock
+	if server != nil {
+		server.mutex.RLock()
+		clock = server.clock
+		server.mutex.RUnlock()
+	}
+	clock = clockOrSystem(clock)
+
+	ctx := withTimeLimit(parent, clock.Now().Add(d))
+	defer ctx.cancel(context.Canceled)
+	request = WithContext(request, ctx)
+
+	done := make(chan Response, 1)
+	panics := make(chan interface{}, 1)
+
+	if server != nil {
+		server.goroutines.add()
+	}
+	if scope != nil {
+		scope.add()
+		if scope.arena != nil {
+			scope.arena.share()
+		}
+	}
+	go func() {
+		defer func() {
+			if scope != nil {
+				scope.done()
+			}
+			if server != nil {
+				server.goroutines.done()
+			}
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				panics <- r
+			}
+		}()
+
+		done <- next(request)
+	}()
+
+	select {
+	case response := <-done:
+		return response
+
+	case r := <-panics:
+		panic(r)
+
+	case <-clock.After(d):
+		ctx.expire()
+		return expired(request)
+	}
+}
+
+// timeLimitContext is the context of a handler run by runWithin. It is like
+// the context of context.WithDeadline, except that it is cancelled by
+// runWithin when the Clock of the server says the time is up.
+type timeLimitContext struct {
+	context.Context
+
+	deadline time.Time
+	cancel   context.CancelCauseFunc
+
+	// expired is set to 1 by expire.
+	expired uint32
+}
+
+func withTimeLimit(parent context.Context, deadline time.Time) *timeLimitContext {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	return &timeLimitContext{
+		Context:  ctx,
+		deadline: deadline,
+		cancel:   cancel,
+	}
+}
+
+// Deadline returns the time limit, or the deadline of the parent if that is
+// earlier.
+func (ctx *timeLimitContext) Deadline() (time.Time, bool) {
+	if deadline, ok := ctx.Context.Deadline(); ok && deadline.Before(ctx.deadline) {
+		return deadline, true
+	}
+
+	return ctx.deadline, true
+}
+
+// Err returns context.DeadlineExceeded once the time is up, and otherwise the
+// error of the parent.
+func (ctx *timeLimitContext) Err() error {
+	if atomic.LoadUint32(&ctx.expired) == 1 {
+		return context.DeadlineExceeded
+	}
+
+	return ctx.Context.Err()
+}
+
+// expire cancels the context because the time is up. context.Cause returns
+// context.DeadlineExceeded for it, unless the parent was done first.
+func (ctx *timeLimitContext) expire() {
+	if ctx.Context.Err() == nil {
+		atomic.StoreUint32(&ctx.expired, 1)
+	}
+	ctx.cancel(context.DeadlineExceeded)
+}
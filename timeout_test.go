@@ -0,0 +1,101 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server := newTestServer()
+	server.SetHandler("fast", jsonrpc.Timeout(time.Minute)(getData))
+	server.SetHandler("slow", jsonrpc.Timeout(time.Millisecond)(
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			<-release
+			return request.NewSuccessResponse(nil)
+		}))
+	server.SetHandler("panic", jsonrpc.Timeout(time.Minute)(forcePanic))
+
+	fast := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "fast", "id": 1}`))
+	assert.Equal(t, []interface{}{"hello", 5.0}, fast[0].Result())
+
+	slow := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "slow", "id": 2}`))
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, slow[0].ErrorCode())
+	assert.Equal(t, "Time limit exceeded", slow[0].ErrorMessage())
+
+	details, err := jsonrpc.ErrorDetailsFromResponse(slow[0])
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.TimeoutErrorType, details.Type)
+	assert.Equal(t, "The method did not finish within 1ms.", details.Detail)
+	assert.False(t, details.Retryable)
+
+	// The server still recovers from the panic.
+	panicked := server.Handle([]byte(`{"jsonrpc": "2.0", "method": "panic", "id": 3}`))
+	assert.Equal(t, jsonrpc.InternalError, panicked[0].ErrorCode())
+	assert.Equal(t, "Internal error", panicked[0].ErrorMessage())
+}
+
+func TestTimeout_Clock(t *testing.T) {
+	release := make(chan struct{})
+	causes := make(chan error, 1)
+
+	clock := jsonrpc.NewFakeClock(epoch)
+	budget := jsonrpc.NewMemoryBudget(1 << 20)
+	server := newTestServer()
+	server.SetClock(clock)
+	server.SetMemoryBudget(budget)
+	server.SetHandler("slow", jsonrpc.Timeout(time.Minute)(
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			ctx := jsonrpc.ContextFromRequest(request)
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			<-release
+			return request.NewSuccessResponse(nil)
+		}))
+
+	responses := make(chan jsonrpc.Responses, 1)
+	go func() {
+		responses <- server.Handle([]byte(`{"jsonrpc": "2.0", "method": "slow", "id": 1}`))
+	}()
+
+	waitFor(t, clock, time.Minute)
+	assert.Equal(t, jsonrpc.TimeLimitExceeded, (<-responses)[0].ErrorCode())
+	assert.Equal(t, context.DeadlineExceeded, <-causes)
+
+	// The handler is still running, so it keeps its memory and Shutdown waits
+	// for it.
+	assert.True(t, budget.InUse() > 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.Shutdown(ctx))
+
+	close(release)
+	assert.NoError(t, server.Shutdown(context.Background()))
+	assert.Equal(t, int64(0), budget.InUse())
+}
+
+func TestTimeout_Cancelled(t *testing.T) {
+	server := newTestServer()
+	server.SetHandler("slow", jsonrpc.Timeout(time.Minute)(
+		func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			ctx := jsonrpc.ContextFromRequest(request)
+			<-ctx.Done()
+			return request.NewErrorResponse(jsonrpc.ServerError, ctx.Err().Error())
+		}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancelled request is not a TimeLimitExceeded error.
+	responses := server.HandleWithContext(ctx,
+		[]byte(`{"jsonrpc": "2.0", "method": "slow", "id": 1}`), jsonrpc.State{})
+	assert.Equal(t, jsonrpc.ServerError, responses[0].ErrorCode())
+	assert.Equal(t, "context canceled", responses[0].ErrorMessage())
+}
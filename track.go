@@ -0,0 +1,100 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// TrackExtension is the request member that selects the Track of a call:
+//
+//     {"jsonrpc":"2.0","method":"user.get","params":[1],"id":1,"track":"next"}
+//
+const TrackExtension = "track"
+
+// TrackHeader is the HTTP header that selects the Track of every request in
+// the body (or on the WebSocket), for clients that cannot add members to their
+// requests. The TrackExtension of a request takes precedence.
+const TrackHeader = "Jsonrpc-Track"
+
+// trackStateKey holds the Track from the TrackHeader.
+const trackStateKey = "jsonrpc.track"
+
+// Track is the set of handlers that a call is routed to. During a migration
+// the new behaviour of a method is registered with SetNextHandler, and callers
+// opt into it one call at a time with the TrackExtension or TrackHeader. When
+// the migration is over the next handler replaces the stable one.
+type Track string
+
+const (
+	// StableTrack is used by calls that do not ask for a track, or that ask
+	// for a track that does not exist.
+	StableTrack Track = "stable"
+
+	// NextTrack calls the handler registered with SetNextHandler, or the
+	// stable handler if the method has none.
+	NextTrack Track = "next"
+)
+
+// SetNextHandler will register (or replace) the handler of a method for calls
+// on the NextTrack. A nil handler removes it, so that every call uses the
+// handler registered with SetHandler. A method may have a next handler without
+// a stable one, in which case it only exists on the NextTrack.
+func (server *SimpleServer) SetNextHandler(methodName string, handler RequestHandler) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if handler == nil {
+		delete(server.nextHandlers, methodName)
+		return
+	}
+
+	server.nextHandlers[methodName] = handler
+}
+
+// SetNextHandler wraps the handler with the middleware of the group and
+// registers it on the server with SetNextHandler.
+func (group *HandlerGroup) SetNextHandler(methodName string, handler RequestHandler) {
+	if handler != nil {
+		handler = group.wrap(handler)
+	}
+
+	group.server.SetNextHandler(methodName, handler)
+}
+
+// TrackFromRequest returns the track that the caller asked for, from the
+// TrackExtension of the request or the TrackHeader of the HTTP request it was
+// sent with.
+func TrackFromRequest(request Request) Track {
+	track, _ := RequestExtension(request, TrackExtension).(string)
+	if track == "" {
+		track = string(stateTrack(request))
+	}
+
+	if Track(track) == NextTrack {
+		return NextTrack
+	}
+
+	return StableTrack
+}
+
+func stateTrack(request Request) Track {
+	track, _ := request.State(trackStateKey).(Track)
+
+	return track
+}
+
+// WithTrack returns a context that sends calls made with it on the track, for
+// transports that send the RequestExtensions of the context.
+func WithTrack(ctx context.Context, track Track) context.Context {
+	return WithRequestExtension(ctx, TrackExtension, string(track))
+}
+
+// withHeaderTrack adds the TrackHeader of r to the state, which must not be
+// shared.
+func withHeaderTrack(state State, r *http.Request) State {
+	if track := r.Header.Get(TrackHeader); track != "" {
+		state[trackStateKey] = Track(track)
+	}
+
+	return state
+}
@@ -0,0 +1,106 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func newTrackServer() *jsonrpc.SimpleServer {
+	server := newTestServer()
+	server.SetHandler("version", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse("v1")
+	})
+	server.SetNextHandler("version", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse("v2")
+	})
+	server.SetNextHandler("preview", getData)
+
+	return server
+}
+
+func TestSetNextHandler(t *testing.T) {
+	server := newTrackServer()
+
+	for request, expected := range map[string]interface{}{
+		`{"jsonrpc":"2.0","method":"version","id":1}`:                  "v1",
+		`{"jsonrpc":"2.0","method":"version","id":1,"track":"stable"}`: "v1",
+		`{"jsonrpc":"2.0","method":"version","id":1,"track":"beta"}`:   "v1",
+		`{"jsonrpc":"2.0","method":"version","id":1,"track":"next"}`:   "v2",
+		`{"jsonrpc":"2.0","method":"get_data","id":1,"track":"next"}`:  []interface{}{"hello", 5.0},
+		`{"jsonrpc":"2.0","method":"preview","id":1,"track":"next"}`:   []interface{}{"hello", 5.0},
+	} {
+		responses := server.Handle([]byte(request))
+		assert.Equal(t, expected, responses[0].Result(), request)
+	}
+
+	// A method that only exists on the next track.
+	responses := server.Handle([]byte(`{"jsonrpc":"2.0","method":"preview","id":1}`))
+	assert.Equal(t, jsonrpc.MethodNotFound, responses[0].ErrorCode())
+
+	server.SetNextHandler("version", nil)
+	responses = server.Handle([]byte(`{"jsonrpc":"2.0","method":"version","id":1,"track":"next"}`))
+	assert.Equal(t, "v1", responses[0].Result())
+}
+
+func TestSetNextHandler_Group(t *testing.T) {
+	server := newTestServer()
+	group := server.Group(func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			response := next(request)
+			return request.NewSuccessResponse(response.Result().(string) + "!")
+		}
+	})
+	group.SetNextHandler("version", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		return request.NewSuccessResponse(string(jsonrpc.TrackFromRequest(request)))
+	})
+
+	responses := server.Handle([]byte(`{"jsonrpc":"2.0","method":"version","id":1,"track":"next"}`))
+	assert.Equal(t, "next!", responses[0].Result())
+}
+
+func TestTrackHeader(t *testing.T) {
+	httpServer := httptest.NewServer(jsonrpc.NewHTTPServer(newTrackServer()))
+	defer httpServer.Close()
+
+	for name, test := range map[string]struct {
+		header, body, expected string
+	}{
+		"none":     {"", `{"jsonrpc":"2.0","method":"version","id":1}`, "v1"},
+		"header":   {"next", `{"jsonrpc":"2.0","method":"version","id":1}`, "v2"},
+		"override": {"next", `{"jsonrpc":"2.0","method":"version","id":1,"track":"stable"}`, "v1"},
+	} {
+		request, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(test.body))
+		request.Header.Set("Content-Type", "application/json")
+		if test.header != "" {
+			request.Header.Set(jsonrpc.TrackHeader, test.header)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		responses, err := jsonrpc.NewResponsesFromJSON(body)
+		assert.NoError(t, err, name)
+		assert.Equal(t, test.expected, responses[0].Result(), name)
+	}
+}
+
+func TestWithTrack(t *testing.T) {
+	invoker := wireInvoker(newTrackServer())
+
+	var version string
+	assert.NoError(t, jsonrpc.CallWith(jsonrpc.WithTrack(context.Background(), jsonrpc.NextTrack),
+		invoker, "version", nil, &version))
+	assert.Equal(t, "v2", version)
+}
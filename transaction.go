@@ -0,0 +1,97 @@
+package jsonrpc
+
+import "context"
+
+// Tx is a transaction that is opened for a single request. It is satisfied by
+// *sql.Tx.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner opens a new transaction for each request.
+type TxBeginner interface {
+	Begin() (Tx, error)
+}
+
+// TxBeginnerFunc allows an ordinary function to be used as a TxBeginner. A
+// *sql.DB can be used with:
+//
+//     jsonrpc.TxBeginnerFunc(func() (jsonrpc.Tx, error) {
+//         return db.Begin()
+//     })
+//
+type TxBeginnerFunc func() (Tx, error)
+
+// Begin calls f()
+func (f TxBeginnerFunc) Begin() (Tx, error) {
+	return f()
+}
+
+// The State key that holds the transaction of the request.
+const txStateKey = "jsonrpc.tx"
+
+// txKey is the context key of the transaction of the request.
+type txKey struct{}
+
+// Transaction returns a middleware that binds a transaction to the lifecycle of
+// each request. The transaction is committed when the handler returns a
+// successful response and rolled back if the handler returns an error or
+// panics. The panic is passed on after the rollback so that the server can
+// still recover from it.
+//
+// The transaction is in the State of the request (see TxFromRequest) and in its
+// context (see TxFromContext), for code that is only given the context:
+//
+//     func (repo *Users) Create(ctx context.Context, user User) error {
+//         tx := jsonrpc.TxFromContext(ctx).(*sql.Tx)
+//         ...
+//     }
+//
+// If the transaction cannot be started or committed the handler will respond
+// with a ServerError. The error itself is not sent, as it may describe the
+// database.
+func Transaction(beginner TxBeginner) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(request RequestResponder) Response {
+			tx, err := beginner.Begin()
+			if err != nil {
+				return request.NewErrorResponse(ServerError, "Transaction failed")
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					tx.Rollback()
+					panic(r)
+				}
+			}()
+
+			ctx := context.WithValue(ContextFromRequest(request), txKey{}, tx)
+			response := next(WithContext(WithState(request, txStateKey, tx), ctx))
+			if response.ErrorCode() != Success {
+				tx.Rollback()
+				return response
+			}
+
+			if err := tx.Commit(); err != nil {
+				return request.NewErrorResponse(ServerError, "Transaction failed")
+			}
+
+			return response
+		}
+	}
+}
+
+// TxFromRequest returns the transaction opened by the Transaction middleware,
+// or nil if there is none.
+func TxFromRequest(request Request) Tx {
+	tx, _ := request.State(txStateKey).(Tx)
+	return tx
+}
+
+// TxFromContext returns the transaction opened by the Transaction middleware
+// for the request that ctx belongs to, or nil if there is none.
+func TxFromContext(ctx context.Context) Tx {
+	tx, _ := ctx.Value(txKey{}).(Tx)
+	return tx
+}
@@ -0,0 +1,103 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type fakeTx struct {
+	committed  bool
+	rolledBack bool
+	commitErr  error
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+func newTransactionServer(tx *fakeTx, handler jsonrpc.RequestHandler) *jsonrpc.SimpleServer {
+	server := jsonrpc.NewSimpleServer()
+	middleware := jsonrpc.Transaction(jsonrpc.TxBeginnerFunc(func() (jsonrpc.Tx, error) {
+		if tx == nil {
+			return nil, errors.New("no connection")
+		}
+
+		return tx, nil
+	}))
+	server.SetHandler("foo", middleware(handler))
+
+	return server
+}
+
+func TestTransaction(t *testing.T) {
+	r := `{"jsonrpc": "2.0", "method": "foo", "id": 1}`
+
+	t.Run("CommitOnSuccess", func(t *testing.T) {
+		tx := &fakeTx{}
+		server := newTransactionServer(tx, func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			assert.Equal(t, tx, jsonrpc.TxFromRequest(request))
+			assert.Equal(t, tx, jsonrpc.TxFromContext(jsonrpc.ContextFromRequest(request)))
+			return request.NewSuccessResponse("ok")
+		})
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, "ok", responses[0].Result())
+		assert.True(t, tx.committed)
+		assert.False(t, tx.rolledBack)
+	})
+
+	t.Run("RollbackOnError", func(t *testing.T) {
+		tx := &fakeTx{}
+		server := newTransactionServer(tx, func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			return request.NewErrorResponse(jsonrpc.InvalidParams, "")
+		})
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, jsonrpc.InvalidParams, responses[0].ErrorCode())
+		assert.False(t, tx.committed)
+		assert.True(t, tx.rolledBack)
+	})
+
+	t.Run("RollbackOnPanic", func(t *testing.T) {
+		tx := &fakeTx{}
+		server := newTransactionServer(tx, forcePanic)
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, jsonrpc.InternalError, responses[0].ErrorCode())
+		assert.False(t, tx.committed)
+		assert.True(t, tx.rolledBack)
+	})
+
+	t.Run("CommitFailure", func(t *testing.T) {
+		tx := &fakeTx{commitErr: errors.New("commit failed")}
+		server := newTransactionServer(tx, getData)
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, jsonrpc.ServerError, responses[0].ErrorCode())
+		assert.Equal(t, "Transaction failed", responses[0].ErrorMessage())
+	})
+
+	t.Run("BeginFailure", func(t *testing.T) {
+		server := newTransactionServer(nil, getData)
+		responses := server.Handle([]byte(r))
+
+		assert.Equal(t, jsonrpc.ServerError, responses[0].ErrorCode())
+		assert.Equal(t, "Transaction failed", responses[0].ErrorMessage())
+	})
+
+	t.Run("NoTransaction", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo", nil)
+		assert.Nil(t, jsonrpc.TxFromRequest(request))
+		assert.Nil(t, jsonrpc.TxFromContext(context.Background()))
+	})
+}
@@ -0,0 +1,225 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The runtime part of the generated TypeScript client.
+const typeScriptRuntime = `export class RPCError extends Error {
+  constructor(public code: number, message: string, public data?: unknown) {
+    super(message);
+  }
+}
+
+export type Transport = (method: string, params: unknown) => Promise<unknown>;
+
+let nextID = 0;
+
+function unwrap(response: any): unknown {
+  if (response.error) {
+    throw new RPCError(response.error.code, response.error.message, response.error.data);
+  }
+  return response.result;
+}
+
+export function httpTransport(url: string, init: RequestInit = {}): Transport {
+  return async (method, params) => {
+    const response = await fetch(url, {
+      ...init,
+      method: "POST",
+      headers: { "Content-Type": "application/json", ...init.headers },
+      body: JSON.stringify({ jsonrpc: "2.0", method, params, id: ++nextID }),
+    });
+    return unwrap(await response.json());
+  };
+}
+
+export function webSocketTransport(socket: WebSocket): Transport {
+  const pending = new Map<number, { resolve: (v: unknown) => void; reject: (e: unknown) => void }>();
+  socket.addEventListener("message", (event) => {
+    const response = JSON.parse(event.data);
+    const call = pending.get(response.id);
+    if (call) {
+      pending.delete(response.id);
+      try {
+        call.resolve(unwrap(response));
+      } catch (e) {
+        call.reject(e);
+      }
+    }
+  });
+  return (method, params) =>
+    new Promise((resolve, reject) => {
+      const id = ++nextID;
+      pending.set(id, { resolve, reject });
+      socket.send(JSON.stringify({ jsonrpc: "2.0", method, params, id }));
+    });
+}
+`
+
+var (
+	typeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	typeScriptSeparator  = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// GenerateTypeScript generates TypeScript definitions for the params and
+// results of methods, and a Client class with a function for each method. The
+// client can use HTTP (fetch) or a WebSocket:
+//
+//     ts, err := jsonrpc.GenerateTypeScript(server.Methods())
+//     if err != nil {
+//         return err
+//     }
+//
+//     ioutil.WriteFile("client.ts", []byte(ts), 0644)
+//
+//     // In TypeScript:
+//     const client = new Client(httpTransport("/rpc"));
+//     const user = await client.userGet({ id: 5 });
+//
+// Method names are converted to camel case, so "user.get" becomes "userGet"
+// and its types are UserGetParams and UserGetResult. It fails if two methods
+// have the same name in TypeScript, such as "user.get" and "user_get".
+func GenerateTypeScript(methods []MethodInfo) (string, error) {
+	names := map[string]string{}
+	for _, info := range methods {
+		name := typeScriptName(info.Name)
+		if other, ok := names[name]; ok {
+			return "", fmt.Errorf("Methods %q and %q are both called %q in TypeScript.",
+				other, info.Name, name)
+		}
+
+		names[name] = info.Name
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by jsonrpc. DO NOT EDIT.\n\n")
+	buf.WriteString(typeScriptRuntime)
+
+	for _, info := range methods {
+		name := typeScriptName(info.Name)
+		typeName := strings.ToUpper(name[:1]) + name[1:]
+
+		buf.WriteString("\nexport type " + typeName + "Params = " +
+			typeScriptType(info.Params, "") + ";\n")
+		buf.WriteString("\nexport type " + typeName + "Result = " +
+			typeScriptType(info.Result, "") + ";\n")
+	}
+
+	buf.WriteString("\nexport class Client {\n")
+	buf.WriteString("  constructor(private transport: Transport) {}\n")
+
+	for _, info := range methods {
+		name := typeScriptName(info.Name)
+		typeName := strings.ToUpper(name[:1]) + name[1:]
+
+		buf.WriteString("\n")
+		if info.Description != "" {
+			buf.WriteString("  /** " + typeScriptComment(info.Description) + " */\n")
+		}
+
+		params := "params: " + typeName + "Params"
+		if info.Params == nil || len(info.Params.Required) == 0 {
+			params = "params?: " + typeName + "Params"
+		}
+
+		buf.WriteString("  " + name + "(" + params + "): Promise<" + typeName +
+			"Result> {\n")
+		buf.WriteString("    return this.transport(" + strconv.Quote(info.Name) +
+			", params) as Promise<" + typeName + "Result>;\n")
+		buf.WriteString("  }\n")
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.String(), nil
+}
+
+// typeScriptComment escapes the end of a comment in text, so that it cannot
+// end the comment it is written in.
+func typeScriptComment(text string) string {
+	return strings.ReplaceAll(text, "*/", "*\\/")
+}
+
+// typeScriptName converts a method name to a camel case identifier.
+func typeScriptName(method string) string {
+	parts := typeScriptSeparator.Split(method, -1)
+
+	name := ""
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(part[:1]) + part[1:]
+		} else {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	return name
+}
+
+func typeScriptType(schema *Schema, indent string) string {
+	if schema == nil {
+		return "unknown"
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+
+	case "integer", "number":
+		return "number"
+
+	case "boolean":
+		return "boolean"
+
+	case "null":
+		return "null"
+
+	case "array":
+		return "Array<" + typeScriptType(schema.Items, indent) + ">"
+
+	case "object":
+		if len(schema.Properties) == 0 {
+			return "Record<string, unknown>"
+		}
+
+		buf := &bytes.Buffer{}
+		buf.WriteString("{\n")
+		for _, property := range sortedProperties(schema) {
+			key := property
+			if !typeScriptIdentifier.MatchString(key) {
+				key = strconv.Quote(key)
+			}
+
+			optional := "?"
+			if isRequired(schema, property) {
+				optional = ""
+			}
+
+			p := schema.Properties[property]
+			if p != nil && p.Description != "" {
+				buf.WriteString(indent + "  /** " + typeScriptComment(p.Description) + " */\n")
+			}
+
+			buf.WriteString(indent + "  " + key + optional + ": " +
+				typeScriptType(p, indent+"  ") + ";\n")
+		}
+		buf.WriteString(indent + "}")
+
+		return buf.String()
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,109 @@
+package jsonrpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	ts, err := jsonrpc.GenerateTypeScript([]jsonrpc.MethodInfo{
+		{
+			Name:        "user.get",
+			Description: "Get a user by id.",
+			Params: &jsonrpc.Schema{
+				Type: "object",
+				Properties: map[string]*jsonrpc.Schema{
+					"id": {Type: "integer"},
+				},
+				Required: []string{"id"},
+			},
+			Result: &jsonrpc.Schema{
+				Type: "object",
+				Properties: map[string]*jsonrpc.Schema{
+					"name":      {Type: "string", Description: "Full name."},
+					"tags":      {Type: "array", Items: &jsonrpc.Schema{Type: "string"}},
+					"home-city": {Type: "string"},
+					"address": {
+						Type: "object",
+						Properties: map[string]*jsonrpc.Schema{
+							"city": {Type: "string"},
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		{
+			Name: "get_data",
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(ts, "// Code generated by jsonrpc. DO NOT EDIT."))
+	assert.Contains(t, ts, "export function httpTransport(")
+	assert.Contains(t, ts, "export function webSocketTransport(")
+
+	assert.Contains(t, ts, `
+export type UserGetParams = {
+  id: number;
+};
+
+export type UserGetResult = {
+  address?: {
+    city?: string;
+  };
+  "home-city"?: string;
+  /** Full name. */
+  name: string;
+  tags?: Array<string>;
+};
+
+export type GetDataParams = unknown;
+
+export type GetDataResult = unknown;
+
+export class Client {
+  constructor(private transport: Transport) {}
+
+  /** Get a user by id. */
+  userGet(params: UserGetParams): Promise<UserGetResult> {
+    return this.transport("user.get", params) as Promise<UserGetResult>;
+  }
+
+  getData(params?: GetDataParams): Promise<GetDataResult> {
+    return this.transport("get_data", params) as Promise<GetDataResult>;
+  }
+}
+`)
+}
+
+func TestGenerateTypeScript_SameName(t *testing.T) {
+	_, err := jsonrpc.GenerateTypeScript([]jsonrpc.MethodInfo{
+		{Name: "user.get"},
+		{Name: "user_get"},
+	})
+	assert.EqualError(t, err, `Methods "user.get" and "user_get" are both called "userGet" in TypeScript.`)
+}
+
+func TestGenerateTypeScript_Comments(t *testing.T) {
+	ts, err := jsonrpc.GenerateTypeScript([]jsonrpc.MethodInfo{
+		{
+			Name:        "user.get",
+			Description: "Ends here */ alert(1); /*",
+			Result: &jsonrpc.Schema{
+				Type: "object",
+				Properties: map[string]*jsonrpc.Schema{
+					"name": {Type: "string", Description: "*/"},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Contains(t, ts, `  /** Ends here *\/ alert(1); /* */`)
+	assert.Contains(t, ts, `  /** *\/ */`)
+	assert.NotContains(t, ts, "*/ alert")
+}
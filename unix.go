@@ -0,0 +1,99 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ListenUnix listens on a Unix domain socket at path that can only be used by
+// those with mode permission, such as 0600 for the current user only or 0660
+// for its group. It is useful for local daemons, such as wallets and node
+// control interfaces, that should not open a TCP port:
+//
+//     listener, err := jsonrpc.ListenUnix("/run/wallet/rpc.sock", 0660)
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//
+//     log.Fatal(jsonrpc.NewTCPServer(server).Serve(listener))
+//
+// The socket is created with mode before it appears at path, so it is never
+// open to anyone else. A socket left at path by a process that has exited is
+// replaced, but it is an error if another process is still listening on it.
+// The socket is removed when the listener is closed.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New(path + " exists and is not a socket.")
+		}
+
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.New(path + " is already in use.")
+		}
+	}
+
+	// The socket is created in a directory that only this process can use and
+	// moved into place once it has its permissions.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".jsonrpc-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{
+		Name: filepath.Join(dir, "socket"),
+		Net:  "unix",
+	})
+	if err != nil {
+		return nil, err
+	}
+	keepSocketOnClose(listener)
+
+	if err := os.Chmod(filepath.Join(dir, "socket"), mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	if err := os.Rename(filepath.Join(dir, "socket"), path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return &unixListener{listener, path}, nil
+}
+
+// unixListener removes the socket when it is closed.
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (listener *unixListener) Close() error {
+	err := listener.UnixListener.Close()
+	if err == nil {
+		os.Remove(listener.path)
+	}
+
+	return err
+}
+
+// ListenAndServeUnix listens on a Unix domain socket (see ListenUnix) and calls
+// Serve.
+func (tcpServer *TCPServer) ListenAndServeUnix(path string, mode os.FileMode) error {
+	listener, err := ListenUnix(path, mode)
+	if err != nil {
+		return err
+	}
+
+	return tcpServer.Serve(listener)
+}
+
+// DialUnix connects to a Unix domain socket and returns a client for the
+// connection.
+func DialUnix(ctx context.Context, path string) (*TCPClient, error) {
+	return Dial(ctx, &net.Dialer{}, "unix", path)
+}
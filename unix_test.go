@@ -0,0 +1,75 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	listener, err := jsonrpc.ListenUnix(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Only the socket is left in the directory.
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+
+	_, err = jsonrpc.ListenUnix(path, 0600)
+	assert.EqualError(t, err, path+" is already in use.")
+
+	tcpServer := jsonrpc.NewTCPServer(newTestServer())
+	served := make(chan error, 1)
+	go func() {
+		served <- tcpServer.Serve(listener)
+	}()
+
+	client, err := jsonrpc.DialUnix(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(context.Background(), client, "subtract",
+		[]int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+
+	tcpServer.Close()
+	assert.NoError(t, <-served)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListenUnix_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	assert.NoError(t, os.WriteFile(path, nil, 0600))
+
+	_, err := jsonrpc.ListenUnix(path, 0600)
+	assert.EqualError(t, err, path+" exists and is not a socket.")
+	os.Remove(path)
+
+	// A socket whose listener is gone is replaced.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := jsonrpc.ListenUnix(path, 0600)
+	assert.NoError(t, err)
+	listener.Close()
+}
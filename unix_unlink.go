@@ -0,0 +1,11 @@
+//go:build !plan9
+
+package jsonrpc
+
+import "net"
+
+// keepSocketOnClose stops the listener from removing its socket when it is
+// closed, since the socket is moved to another path (see ListenUnix).
+func keepSocketOnClose(listener *net.UnixListener) {
+	listener.SetUnlinkOnClose(false)
+}
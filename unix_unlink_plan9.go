@@ -0,0 +1,9 @@
+//go:build plan9
+
+package jsonrpc
+
+import "net"
+
+// Unix domain sockets are not supported on this platform, so there is never a
+// listener to keep the socket of.
+func keepSocketOnClose(listener *net.UnixListener) {}
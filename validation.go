@@ -0,0 +1,232 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ValidationErrorType is the ErrorDetails type for requests with invalid
+// params.
+const ValidationErrorType = "urn:jsonrpc:error:validation"
+
+// Validation collects all of the problems found with the params of a request.
+// Returning every violation at once saves the client developer from fixing one
+// field at a time.
+//
+//     v := new(jsonrpc.Validation)
+//     v.Check(p.Name != "", "name", "is required")
+//     v.Check(p.Age > 0, "age", "must be positive")
+//     if !v.Valid() {
+//         return v.NewResponse(request)
+//     }
+//
+type Validation struct {
+	violations []FieldViolation
+}
+
+// Add records a violation for the field.
+func (validation *Validation) Add(field, message string) {
+	validation.violations = append(validation.violations, FieldViolation{
+		Field:   field,
+		Message: message,
+	})
+}
+
+// Check records a violation for the field when ok is false. The ok value is
+// returned so that checks may be chained with other logic.
+func (validation *Validation) Check(ok bool, field, message string) bool {
+	if !ok {
+		validation.Add(field, message)
+	}
+
+	return ok
+}
+
+// Valid returns true if there are no violations.
+func (validation *Validation) Valid() bool {
+	return len(validation.violations) == 0
+}
+
+// Violations returns all of the violations in the order they were found.
+func (validation *Validation) Violations() []FieldViolation {
+	return validation.violations
+}
+
+// NewResponse creates an InvalidParams response containing all of the
+// violations as ErrorDetails.
+func (validation *Validation) NewResponse(request Responder) Response {
+	details := NewErrorDetails(ValidationErrorType)
+	details.Violations = validation.violations
+
+	return request.NewErrorResponseWithData(InvalidParams, "", details)
+}
+
+// ParamsValidator can be implemented by params types to validate themselves
+// after they have been decoded by BindParams.
+type ParamsValidator interface {
+	ValidateParams(validation *Validation)
+}
+
+// BindParams decodes the params of the request into target, which must be a
+// pointer. Every field of a struct that cannot be decoded is reported, by the
+// name of its JSON member. If target implements ParamsValidator then it will
+// be validated after decoding.
+//
+// nil is returned when the params are valid. Otherwise an InvalidParams
+// response is returned that the handler should send back as is:
+//
+//     var p sayHelloParams
+//     if response := jsonrpc.BindParams(request, &p); response != nil {
+//         return response
+//     }
+//
+func BindParams(request RequestResponder, target interface{}) Response {
+	return bindParams(request, request.Params(), target)
+}
+
+func bindParams(request RequestResponder, params interface{}, target interface{}) Response {
+	validation := new(Validation)
+
+	b, err := json.Marshal(params)
+	if err == nil {
+		err = json.Unmarshal(b, target)
+	}
+	if err != nil {
+		bindFields(validation, b, target)
+		if validation.Valid() {
+			validation.Add(bindFieldName("", err), bindMessage(err))
+		}
+
+		return validation.NewResponse(request)
+	}
+
+	if validator, ok := target.(ParamsValidator); ok {
+		validator.ValidateParams(validation)
+	}
+
+	if !validation.Valid() {
+		return validation.NewResponse(request)
+	}
+
+	return nil
+}
+
+// bindFields decodes each member of the params object into its field of the
+// struct that target points to, so that every field that cannot be decoded is
+// reported rather than only the first.
+func bindFields(validation *Validation, params []byte, target interface{}) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	var members map[string]json.RawMessage
+	if json.Unmarshal(params, &members) != nil {
+		validation.Add("", "must be an object")
+		return
+	}
+
+	for _, field := range jsonFields(value.Elem()) {
+		raw, ok := members[field.name]
+		if !ok {
+			for name, member := range members {
+				if strings.EqualFold(name, field.name) {
+					raw, ok = member, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if err := json.Unmarshal(raw, field.value.Addr().Interface()); err != nil {
+			validation.Add(bindFieldName(field.name, err), bindMessage(err))
+		}
+	}
+}
+
+// jsonField is a field of a struct and the name of its JSON member.
+type jsonField struct {
+	name  string
+	value reflect.Value
+}
+
+// jsonFields returns the fields of the struct that encoding/json decodes,
+// including those of embedded structs.
+func jsonFields(value reflect.Value) []jsonField {
+	var fields []jsonField
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(value.Field(i))...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, jsonField{name, value.Field(i)})
+	}
+
+	return fields
+}
+
+// bindFieldName returns the name of the field that could not be decoded, which
+// includes the path of a nested field.
+func bindFieldName(name string, err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field == "" {
+		return name
+	}
+
+	if name == "" {
+		return typeErr.Field
+	}
+
+	return name + "." + typeErr.Field
+}
+
+// bindMessage describes why a value could not be decoded without the details
+// of the decoder.
+func bindMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return "is invalid"
+	}
+
+	switch typeErr.Type.Kind() {
+	case reflect.String:
+		return "must be a string"
+
+	case reflect.Bool:
+		return "must be a boolean"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "must be an integer"
+
+	case reflect.Float32, reflect.Float64:
+		return "must be a number"
+
+	case reflect.Slice, reflect.Array:
+		return "must be an array"
+
+	case reflect.Map, reflect.Struct:
+		return "must be an object"
+	}
+
+	return "is invalid"
+}
@@ -0,0 +1,96 @@
+package jsonrpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type personParams struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (p *personParams) ValidateParams(v *jsonrpc.Validation) {
+	v.Check(p.Name != "", "name", "is required")
+	v.Check(p.Age > 0, "age", "must be positive")
+}
+
+func TestValidation(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		v := new(jsonrpc.Validation)
+		assert.True(t, v.Check(true, "name", "is required"))
+
+		assert.True(t, v.Valid())
+		assert.Len(t, v.Violations(), 0)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		v := new(jsonrpc.Validation)
+		v.Add("name", "is required")
+		assert.False(t, v.Check(false, "age", "must be positive"))
+
+		assert.False(t, v.Valid())
+		assert.Equal(t, []jsonrpc.FieldViolation{
+			{Field: "name", Message: "is required"},
+			{Field: "age", Message: "must be positive"},
+		}, v.Violations())
+	})
+}
+
+func TestBindParams(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo",
+			map[string]interface{}{"name": "Bob", "age": 30})
+
+		var p personParams
+		response := jsonrpc.BindParams(request, &p)
+
+		assert.Nil(t, response)
+		assert.Equal(t, personParams{Name: "Bob", Age: 30}, p)
+	})
+
+	t.Run("AllViolations", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo",
+			map[string]interface{}{"age": -1})
+
+		var p personParams
+		response := jsonrpc.BindParams(request, &p)
+		details, err := jsonrpc.ErrorDetailsFromResponse(response)
+
+		assert.NoError(t, err)
+		assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+		assert.Equal(t, jsonrpc.ValidationErrorType, details.Type)
+		assert.Equal(t, []jsonrpc.FieldViolation{
+			{Field: "name", Message: "is required"},
+			{Field: "age", Message: "must be positive"},
+		}, details.Violations)
+	})
+
+	t.Run("WrongType", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo", []interface{}{1})
+
+		var p personParams
+		response := jsonrpc.BindParams(request, &p)
+		details, _ := jsonrpc.ErrorDetailsFromResponse(response)
+
+		assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+		assert.Len(t, details.Violations, 1)
+	})
+	t.Run("WrongFields", func(t *testing.T) {
+		request := jsonrpc.NewRequestResponder("2.0", 1, "foo",
+			map[string]interface{}{"name": 5, "age": "thirty"})
+
+		var p personParams
+		response := jsonrpc.BindParams(request, &p)
+		details, err := jsonrpc.ErrorDetailsFromResponse(response)
+
+		assert.NoError(t, err)
+		assert.Equal(t, jsonrpc.InvalidParams, response.ErrorCode())
+		assert.Equal(t, []jsonrpc.FieldViolation{
+			{Field: "name", Message: "must be a string"},
+			{Field: "age", Message: "must be an integer"},
+		}, details.Violations)
+	})
+}
@@ -0,0 +1,74 @@
+package jsonrpc
+
+import (
+	"errors"
+)
+
+// Validator checks a request after it has been parsed and before it is
+// dispatched to its handler. It allows policies that apply to every method
+// to be enforced in one place rather than in each handler.
+//
+// A request is rejected by returning an error. An *RPCError is sent with its
+// code, message and data. Any other error is sent as InvalidParams with the
+// message of the error.
+type Validator interface {
+	Validate(request RequestResponder) error
+}
+
+// ValidatorFunc allows an ordinary function to be used as a Validator.
+type ValidatorFunc func(request RequestResponder) error
+
+// Validate calls f(request).
+func (f ValidatorFunc) Validate(request RequestResponder) error {
+	return f(request)
+}
+
+// AddValidator adds a validator that every request must pass, such as a
+// policy that no params contain a card number:
+//
+//     server.AddValidator(jsonrpc.ValidatorFunc(func(request jsonrpc.RequestResponder) error {
+//         if containsCardNumber(request.Params()) {
+//             return &jsonrpc.RPCError{
+//                 Code:    jsonrpc.InvalidParams,
+//                 Message: "Params must not contain card numbers.",
+//             }
+//         }
+//
+//         return nil
+//     }))
+//
+// Validators are called in the order they were added, after the method has
+// been found and before any middleware, so they see the request as it was
+// sent. The first error rejects the request. A notification that is rejected
+// is not answered, but it is seen by the OnError hooks. Validators must be
+// safe for concurrent use.
+func (server *SimpleServer) AddValidator(validator Validator) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	// The slice is replaced, rather than appended to, so that it can be used
+	// without holding the mutex.
+	validators := make([]Validator, len(server.validators), len(server.validators)+1)
+	copy(validators, server.validators)
+	server.validators = append(validators, validator)
+}
+
+// validate returns the error response of the first validator that rejects the
+// request, or nil.
+func validate(validators []Validator, request RequestResponder) Response {
+	for _, validator := range validators {
+		err := validator.Validate(request)
+		if err == nil {
+			continue
+		}
+
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			return request.NewErrorResponseWithData(rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		}
+
+		return request.NewErrorResponse(InvalidParams, err.Error())
+	}
+
+	return nil
+}
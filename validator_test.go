@@ -0,0 +1,65 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestSimpleServer_AddValidator(t *testing.T) {
+	server := newTestServer()
+	recorder := &hookRecorder{}
+	recorder.install(server)
+
+	var validated []string
+	server.AddValidator(jsonrpc.ValidatorFunc(func(request jsonrpc.RequestResponder) error {
+		validated = append(validated, request.Method())
+		if params, ok := request.Params().([]interface{}); ok && len(params) > 2 {
+			return &jsonrpc.RPCError{
+				Code:    jsonrpc.ServerError,
+				Message: "Too many params.",
+				Data:    len(params),
+			}
+		}
+
+		return nil
+	}))
+	server.AddValidator(jsonrpc.ValidatorFunc(func(request jsonrpc.RequestResponder) error {
+		if request.Method() == "get_data" {
+			return errors.New("Method is not allowed by policy.")
+		}
+
+		return nil
+	}))
+
+	// Middleware runs after the validators.
+	server.Use(func(next jsonrpc.RequestHandler) jsonrpc.RequestHandler {
+		return func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			validated = append(validated, "middleware")
+
+			return next(request)
+		}
+	})
+
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":1,"result":19}`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`))[0].String())
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"Too many params.","data":3}}`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"sum","params":[1,2,4],"id":2}`))[0].String())
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"Method is not allowed by policy."}}`,
+		server.Handle([]byte(`{"jsonrpc":"2.0","method":"get_data","id":3}`))[0].String())
+
+	// A notification that is rejected is not answered.
+	assert.Empty(t, server.Handle([]byte(`{"jsonrpc":"2.0","method":"notify_hello","params":[1,2,3]}`)))
+
+	// Unknown methods are not validated.
+	server.Handle([]byte(`{"jsonrpc":"2.0","method":"missing","id":4}`))
+
+	assert.Equal(t, []string{"subtract", "middleware", "sum", "get_data", "notify_hello"},
+		validated)
+	assert.Contains(t, recorder.calls, "error notify_hello -32000")
+}
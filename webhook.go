@@ -0,0 +1,280 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WebhookSignatureHeader is the HTTP header that contains the signature of a
+// webhook body. See SignWebhook.
+const WebhookSignatureHeader = "X-JSONRPC-Signature"
+
+// WebhookTimestampHeader is the HTTP header that contains the time a webhook
+// was signed, in Unix seconds. See SignWebhook.
+const WebhookTimestampHeader = "X-JSONRPC-Timestamp"
+
+// WebhookTolerance is how far the timestamp of a webhook may be from the time
+// it is verified, to allow for clock skew and retries in transit.
+const WebhookTolerance = 5 * time.Minute
+
+// WebhookDispatcher delivers notifications as HTTP POSTs to registered
+// callback URLs. Each body is a JSON-RPC notification signed with the secret
+// of the callback.
+//
+// Failed deliveries are retried with an exponential backoff. Deliveries that
+// still fail after MaxAttempts, or are rejected by the receiver, are kept as
+// dead letters so that they can be inspected or sent again later.
+//
+// The zero value is ready to use, but only attempts each delivery once and
+// keeps no dead letters. NewWebhookDispatcher has better defaults.
+type WebhookDispatcher struct {
+	// Client is used to send the webhooks. If it is nil a client with a 10
+	// second timeout is used.
+	Client *http.Client
+
+	// MaxAttempts is the number of times a delivery is attempted. Zero (or
+	// less) attempts it once.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles for each retry
+	// after that.
+	Backoff time.Duration
+
+	// MaxDeadLetters is the number of dead letters kept. The oldest are
+	// dropped first. Zero keeps none.
+	MaxDeadLetters int
+
+	// Clock is used for the backoff and to timestamp dead letters.
+	// SystemClock is used if it is nil.
+	Clock Clock
+
+	mutex       sync.Mutex
+	callbacks   map[string][]byte
+	deadLetters []DeadLetter
+	inflight    sync.WaitGroup
+}
+
+// DeadLetter is a webhook that could not be delivered.
+type DeadLetter struct {
+	URL      string
+	Body     []byte
+	Attempts int
+	Err      error
+	Time     time.Time
+}
+
+// defaultWebhookClient is used by a WebhookDispatcher without a Client.
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewWebhookDispatcher creates a WebhookDispatcher with the default settings.
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:    5,
+		Backoff:        time.Second,
+		MaxDeadLetters: 1000,
+		callbacks:      make(map[string][]byte),
+	}
+}
+
+// Register will add (or replace the secret of) a callback URL.
+func (dispatcher *WebhookDispatcher) Register(url string, secret []byte) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if dispatcher.callbacks == nil {
+		dispatcher.callbacks = make(map[string][]byte)
+	}
+	dispatcher.callbacks[url] = secret
+}
+
+// Unregister removes a callback URL. Deliveries that have already started will
+// continue.
+func (dispatcher *WebhookDispatcher) Unregister(url string) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	delete(dispatcher.callbacks, url)
+}
+
+// Notify sends the notification to every registered callback in the
+// background. Use Wait to wait for the deliveries to finish.
+func (dispatcher *WebhookDispatcher) Notify(method string, params interface{}) {
+	body := NewRequestResponder("2.0", nil, method, params).Bytes()
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	for url, secret := range dispatcher.callbacks {
+		dispatcher.inflight.Add(1)
+		go func(url string, secret []byte) {
+			defer dispatcher.inflight.Done()
+			dispatcher.Deliver(context.Background(), url, secret, body)
+		}(url, secret)
+	}
+}
+
+// Wait blocks until all of the deliveries started by Notify have finished.
+func (dispatcher *WebhookDispatcher) Wait() {
+	dispatcher.inflight.Wait()
+}
+
+// Deliver sends a single webhook, retrying until it succeeds, is rejected, or
+// runs out of attempts. If it is not delivered it is added to the dead letters
+// and the last error is returned.
+func (dispatcher *WebhookDispatcher) Deliver(ctx context.Context, url string,
+	secret []byte, body []byte) error {
+	backoff := dispatcher.Backoff
+	attempts := 0
+	maxAttempts := dispatcher.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var err error
+retries:
+	for attempts < maxAttempts {
+		if attempts > 0 {
+			select {
+			case <-clockOrSystem(dispatcher.Clock).After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				err = ctx.Err()
+				break retries
+			}
+		}
+
+		attempts++
+
+		var retry bool
+		retry, err = dispatcher.post(ctx, url, secret, body)
+		if err == nil || !retry {
+			break
+		}
+	}
+
+	if err != nil {
+		dispatcher.addDeadLetter(DeadLetter{
+			URL:      url,
+			Body:     body,
+			Attempts: attempts,
+			Err:      err,
+			Time:     clockOrSystem(dispatcher.Clock).Now(),
+		})
+	}
+
+	return err
+}
+
+// post sends the webhook once. The bool is true when a failure may be
+// retried.
+func (dispatcher *WebhookDispatcher) post(ctx context.Context, url string,
+	secret []byte, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := clockOrSystem(dispatcher.Clock).Now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+
+	client := dispatcher.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("Webhook returned status %d.", response.StatusCode)
+
+	// Client errors will not be fixed by sending the same webhook again,
+	// except for timeouts and rate limits.
+	retry := response.StatusCode >= 500 ||
+		response.StatusCode == http.StatusRequestTimeout ||
+		response.StatusCode == http.StatusTooManyRequests
+
+	return retry, err
+}
+
+func (dispatcher *WebhookDispatcher) addDeadLetter(deadLetter DeadLetter) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	dispatcher.deadLetters = append(dispatcher.deadLetters, deadLetter)
+	if len(dispatcher.deadLetters) > dispatcher.MaxDeadLetters {
+		dispatcher.deadLetters =
+			dispatcher.deadLetters[len(dispatcher.deadLetters)-dispatcher.MaxDeadLetters:]
+	}
+}
+
+// DeadLetters removes and returns all of the dead letters.
+func (dispatcher *WebhookDispatcher) DeadLetters() []DeadLetter {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	deadLetters := dispatcher.deadLetters
+	dispatcher.deadLetters = nil
+
+	return deadLetters
+}
+
+// SignWebhook returns the signature of a webhook body sent at timestamp, which
+// is the hex encoded HMAC-SHA256 of the timestamp in Unix seconds, a "." and
+// the body, prefixed with "sha256=". The timestamp is sent in the
+// WebhookTimestampHeader. Since it is signed, a webhook that is captured
+// cannot be replayed once it is older than WebhookTolerance.
+func SignWebhook(secret []byte, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature of a webhook body in constant time, and
+// that its timestamp (the value of the WebhookTimestampHeader) is within
+// WebhookTolerance of now.
+func VerifyWebhook(secret []byte, body []byte, timestamp, signature string,
+	now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	signed := time.Unix(seconds, 0)
+	if signed.Before(now.Add(-WebhookTolerance)) || signed.After(now.Add(WebhookTolerance)) {
+		return false
+	}
+
+	return hmac.Equal([]byte(SignWebhook(secret, signed, body)), []byte(signature))
+}
+
+// VerifyWebhookBody checks a signature of the body alone in constant time, in
+// the format of SignWebhook but without a timestamp. This is how GitHub signs
+// webhooks in "X-Hub-Signature-256". Such a webhook can be replayed, so
+// VerifyWebhook should be used where the sender allows it.
+func VerifyWebhookBody(secret []byte, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hmac.Equal([]byte("sha256="+hex.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
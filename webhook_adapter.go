@@ -0,0 +1,195 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// WebhookRule maps an inbound HTTP webhook to a method on the server.
+type WebhookRule struct {
+	// Path must match the URL path exactly. An empty path matches any path.
+	Path string
+
+	// Header and HeaderValue, if set, must match a header of the webhook. For
+	// example "X-GitHub-Event" and "push".
+	Header      string
+	HeaderValue string
+
+	// Method is the method that will be called.
+	Method string
+
+	// Params builds the params for the method. If it is nil the decoded JSON
+	// body will be used. The params are encoded as JSON and handled like
+	// those of any other request, so the handler receives them decoded from
+	// JSON rather than the value returned by Params.
+	Params func(r *http.Request, body []byte) (interface{}, error)
+
+	// Secret, if set, is used to verify the HMAC-SHA256 signature of the body.
+	// The signature must be in the SignatureHeader, and the time it was
+	// signed in the WebhookTimestampHeader, as sent by a WebhookDispatcher
+	// (see VerifyWebhook). SignatureHeader defaults to WebhookSignatureHeader.
+	Secret          []byte
+	SignatureHeader string
+
+	// BodySignature verifies a signature of the body alone, without a
+	// timestamp (see VerifyWebhookBody). GitHub webhooks can be verified by
+	// setting it and using "X-Hub-Signature-256" as the SignatureHeader.
+	BodySignature bool
+}
+
+func (rule *WebhookRule) matches(r *http.Request) bool {
+	if rule.Path != "" && rule.Path != r.URL.Path {
+		return false
+	}
+
+	if rule.Header != "" && r.Header.Get(rule.Header) != rule.HeaderValue {
+		return false
+	}
+
+	return true
+}
+
+// WebhookAdapter accepts arbitrary HTTP webhooks and calls methods on a server
+// according to its rules, so webhooks go through the same handlers (and
+// middleware) as JSON-RPC requests.
+//
+// The first matching rule is used. The HTTP status returned to the sender
+// is based on the response from the handler:
+//
+//     200 - Success
+//     202 - There is no response, because the IDGenerator of the server
+//           returned a nil id and the request was sent as a notification.
+//     400 - The body could not be read, or the handler returned InvalidParams
+//           or InvalidRequest.
+//     401 - The signature is not valid.
+//     404 - There is no matching rule, or the method does not exist.
+//     500 - Any other error.
+//
+// The body of the HTTP response is the JSON-RPC response.
+type WebhookAdapter struct {
+	// MaxBodyBytes limits the size of a webhook body. A larger body is
+	// answered with 413 Request Entity Too Large. Zero uses
+	// DefaultMaxBodySize.
+	MaxBodyBytes int64
+
+	// ClientExtractor puts the ClientInfo of every webhook in the State of
+	// its request. A ClientExtractor without trusted proxies is used if it is
+	// nil.
+	ClientExtractor *ClientExtractor
+
+	server *SimpleServer
+	rules  []WebhookRule
+}
+
+// NewWebhookAdapter creates an adapter that calls methods on server.
+func NewWebhookAdapter(server *SimpleServer) *WebhookAdapter {
+	return &WebhookAdapter{
+		MaxBodyBytes: DefaultMaxBodySize,
+		server:       server,
+	}
+}
+
+// AddRule adds a rule. Rules are checked in the order they were added.
+func (adapter *WebhookAdapter) AddRule(rule WebhookRule) {
+	adapter.rules = append(adapter.rules, rule)
+}
+
+// ServeHTTP handles a single webhook.
+func (adapter *WebhookAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var rule *WebhookRule
+	for i := range adapter.rules {
+		if adapter.rules[i].matches(r) {
+			rule = &adapter.rules[i]
+			break
+		}
+	}
+
+	if rule == nil {
+		http.Error(w, "No rule for webhook", http.StatusNotFound)
+		return
+	}
+
+	maxBodyBytes := adapter.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if errors.As(err, new(*http.MaxBytesError)) {
+		http.Error(w, "Request is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rule.Secret != nil {
+		header := rule.SignatureHeader
+		if header == "" {
+			header = WebhookSignatureHeader
+		}
+
+		adapter.server.mutex.RLock()
+		clock := adapter.server.clock
+		adapter.server.mutex.RUnlock()
+
+		var verified bool
+		if rule.BodySignature {
+			verified = VerifyWebhookBody(rule.Secret, body, r.Header.Get(header))
+		} else {
+			verified = VerifyWebhook(rule.Secret, body, r.Header.Get(WebhookTimestampHeader),
+				r.Header.Get(header), clockOrSystem(clock).Now())
+		}
+
+		if !verified {
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var params interface{}
+	if rule.Params != nil {
+		params, err = rule.Params(r, body)
+	} else if len(body) > 0 {
+		err = json.Unmarshal(body, &params)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	extractor := adapter.ClientExtractor
+	if extractor == nil {
+		extractor = &ClientExtractor{}
+	}
+
+	response := adapter.server.call(rule.Method, params,
+		StateWithContext(extractor.State(r), r.Context()))
+	if response == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(webhookStatus(response.ErrorCode()))
+	w.Write(response.Bytes())
+}
+
+func webhookStatus(code int) int {
+	switch code {
+	case Success:
+		return http.StatusOK
+
+	case InvalidParams, InvalidRequest, ParseError:
+		return http.StatusBadRequest
+
+	case MethodNotFound:
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,203 @@
+package jsonrpc_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func newTestWebhookAdapter() *jsonrpc.WebhookAdapter {
+	adapter := jsonrpc.NewWebhookAdapter(newTestServer())
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:            "/github",
+		Header:          "X-GitHub-Event",
+		HeaderValue:     "push",
+		Method:          "handlerWithState",
+		Secret:          []byte("It's a Secret to Everybody"),
+		SignatureHeader: "X-Hub-Signature-256",
+		BodySignature:   true,
+		Params: func(r *http.Request, body []byte) (interface{}, error) {
+			return string(body), nil
+		},
+	})
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:   "/signed",
+		Method: "handlerWithState",
+		Secret: []byte("secret"),
+	})
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:   "/sum",
+		Method: "sum",
+	})
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:   "/subtract",
+		Method: "subtract",
+		Params: func(r *http.Request, body []byte) (interface{}, error) {
+			return []interface{}{10.0, float64(len(body))}, nil
+		},
+	})
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:   "/panic",
+		Method: "panic",
+	})
+	adapter.AddRule(jsonrpc.WebhookRule{
+		Path:   "/missing",
+		Method: "missing",
+	})
+
+	return adapter
+}
+
+func sendWebhook(adapter *jsonrpc.WebhookAdapter, path, body string,
+	headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	adapter.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestWebhookAdapter(t *testing.T) {
+	adapter := newTestWebhookAdapter()
+
+	t.Run("JSONBody", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/sum", `[1, 2, 3]`, nil)
+		responses, _ := jsonrpc.NewResponsesFromJSON(recorder.Body.Bytes())
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 6.0, responses[0].Result())
+	})
+
+	t.Run("CustomParams", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/subtract", `abc`, nil)
+		responses, _ := jsonrpc.NewResponsesFromJSON(recorder.Body.Bytes())
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, 7.0, responses[0].Result())
+	})
+
+	t.Run("Signed", func(t *testing.T) {
+		body := `{"ref": "main"}`
+		now := time.Now()
+		recorder := sendWebhook(adapter, "/signed", body, map[string]string{
+			jsonrpc.WebhookTimestampHeader: strconv.FormatInt(now.Unix(), 10),
+			jsonrpc.WebhookSignatureHeader: jsonrpc.SignWebhook([]byte("secret"), now, []byte(body)),
+		})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("Replayed", func(t *testing.T) {
+		body := `{"ref": "main"}`
+		signed := time.Now().Add(-time.Hour)
+		recorder := sendWebhook(adapter, "/signed", body, map[string]string{
+			jsonrpc.WebhookTimestampHeader: strconv.FormatInt(signed.Unix(), 10),
+			jsonrpc.WebhookSignatureHeader: jsonrpc.SignWebhook([]byte("secret"), signed, []byte(body)),
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("GitHub", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/github", "Hello, World!", map[string]string{
+			"X-GitHub-Event":      "push",
+			"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("BadSignature", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/github", `{}`, map[string]string{
+			"X-GitHub-Event":               "push",
+			jsonrpc.WebhookSignatureHeader: "sha256=00",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("NoRule", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/github", `{}`, map[string]string{
+			"X-GitHub-Event": "issues",
+		})
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/sum", `[1,`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		adapter := newTestWebhookAdapter()
+		adapter.MaxBodyBytes = 8
+
+		recorder := sendWebhook(adapter, "/sum", `[1, 2, 3]`, nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+		recorder = sendWebhook(adapter, "/sum", `[1, 2]`, nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("ZeroMaxBodyBytes", func(t *testing.T) {
+		adapter := newTestWebhookAdapter()
+		adapter.MaxBodyBytes = 0
+
+		recorder := sendWebhook(adapter, "/sum", `[1, 2, 3]`, nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		recorder = sendWebhook(adapter, "/subtract",
+			strings.Repeat(" ", jsonrpc.DefaultMaxBodySize+1), nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		server := newTestServer()
+		server.SetIDGenerator(jsonrpc.IDGeneratorFunc(func() interface{} {
+			return nil
+		}))
+		adapter := jsonrpc.NewWebhookAdapter(server)
+		adapter.AddRule(jsonrpc.WebhookRule{Method: "sum"})
+
+		recorder := sendWebhook(adapter, "/sum", `[1, 2, 3]`, nil)
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		assert.Equal(t, "", recorder.Body.String())
+	})
+
+	t.Run("ClientInfo", func(t *testing.T) {
+		server := newTestServer()
+		server.SetHandler("whoami", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+			return request.NewSuccessResponse(jsonrpc.ClientInfoFromRequest(request).Address)
+		})
+		adapter := jsonrpc.NewWebhookAdapter(server)
+		adapter.AddRule(jsonrpc.WebhookRule{Method: "whoami"})
+
+		recorder := sendWebhook(adapter, "/", ``, nil)
+		responses, _ := jsonrpc.NewResponsesFromJSON(recorder.Body.Bytes())
+		assert.Equal(t, "192.0.2.1", responses[0].Result())
+	})
+
+	t.Run("MethodNotFound", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/missing", ``, nil)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		recorder := sendWebhook(adapter, "/panic", ``, nil)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+}
@@ -0,0 +1,175 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+type webhookReceiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	bodies   []string
+	verified bool
+}
+
+func (receiver *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	receiver.bodies = append(receiver.bodies, string(body))
+	receiver.verified = jsonrpc.VerifyWebhook([]byte("secret"), body,
+		r.Header.Get(jsonrpc.WebhookTimestampHeader),
+		r.Header.Get(jsonrpc.WebhookSignatureHeader), time.Now())
+
+	status := http.StatusOK
+	if len(receiver.statuses) > 0 {
+		status = receiver.statuses[0]
+		receiver.statuses = receiver.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestDispatcher() *jsonrpc.WebhookDispatcher {
+	dispatcher := jsonrpc.NewWebhookDispatcher()
+	dispatcher.MaxAttempts = 3
+	dispatcher.Backoff = time.Millisecond
+
+	return dispatcher
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	t.Run("Notify", func(t *testing.T) {
+		receiver := &webhookReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := newTestDispatcher()
+		dispatcher.Register(server.URL, []byte("secret"))
+		dispatcher.Notify("foo", []int{1})
+		dispatcher.Wait()
+
+		assert.Equal(t, []string{`{"jsonrpc":"2.0","method":"foo","params":[1]}`},
+			receiver.bodies)
+		assert.True(t, receiver.verified)
+		assert.Len(t, dispatcher.DeadLetters(), 0)
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{500, 503}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := newTestDispatcher()
+		err := dispatcher.Deliver(context.Background(), server.URL,
+			[]byte("secret"), []byte(`{}`))
+
+		assert.NoError(t, err)
+		assert.Len(t, receiver.bodies, 3)
+	})
+
+	t.Run("DeadLetter", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{500, 500, 500}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := newTestDispatcher()
+		err := dispatcher.Deliver(context.Background(), server.URL,
+			[]byte("secret"), []byte(`{}`))
+		deadLetters := dispatcher.DeadLetters()
+
+		assert.EqualError(t, err, "Webhook returned status 500.")
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, 3, deadLetters[0].Attempts)
+		assert.Equal(t, server.URL, deadLetters[0].URL)
+		assert.Len(t, dispatcher.DeadLetters(), 0)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{400}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := newTestDispatcher()
+		err := dispatcher.Deliver(context.Background(), server.URL,
+			[]byte("secret"), []byte(`{}`))
+
+		assert.EqualError(t, err, "Webhook returned status 400.")
+		assert.Len(t, receiver.bodies, 1)
+		assert.Len(t, dispatcher.DeadLetters(), 1)
+	})
+
+	t.Run("ZeroValue", func(t *testing.T) {
+		receiver := &webhookReceiver{statuses: []int{500}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := &jsonrpc.WebhookDispatcher{}
+		dispatcher.Register(server.URL, []byte("secret"))
+		dispatcher.Notify("foo", nil)
+		dispatcher.Wait()
+		dispatcher.Notify("foo", nil)
+		dispatcher.Wait()
+
+		assert.Len(t, receiver.bodies, 2)
+		assert.True(t, receiver.verified)
+		assert.Len(t, dispatcher.DeadLetters(), 0)
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		receiver := &webhookReceiver{}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := newTestDispatcher()
+		dispatcher.Register(server.URL, []byte("secret"))
+		dispatcher.Unregister(server.URL)
+		dispatcher.Notify("foo", nil)
+		dispatcher.Wait()
+
+		assert.Len(t, receiver.bodies, 0)
+	})
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	signature := jsonrpc.SignWebhook([]byte("secret"), epoch, []byte("body"))
+	timestamp := strconv.FormatInt(epoch.Unix(), 10)
+
+	assert.True(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"), timestamp,
+		signature, epoch))
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("other"), []byte("body"), timestamp,
+		signature, epoch))
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("bodies"), timestamp,
+		signature, epoch))
+
+	// The timestamp is signed and must be recent.
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"),
+		strconv.FormatInt(epoch.Unix()+1, 10), signature, epoch))
+	assert.True(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"), timestamp,
+		signature, epoch.Add(jsonrpc.WebhookTolerance)))
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"), timestamp,
+		signature, epoch.Add(jsonrpc.WebhookTolerance+time.Second)))
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"), timestamp,
+		signature, epoch.Add(-jsonrpc.WebhookTolerance-time.Second)))
+	assert.False(t, jsonrpc.VerifyWebhook([]byte("secret"), []byte("body"), "",
+		signature, epoch))
+}
+
+func TestVerifyWebhookBody(t *testing.T) {
+	// The example from the GitHub documentation.
+	signature := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	assert.True(t, jsonrpc.VerifyWebhookBody([]byte("It's a Secret to Everybody"),
+		[]byte("Hello, World!"), signature))
+	assert.False(t, jsonrpc.VerifyWebhookBody([]byte("It's a Secret to Everybody"),
+		[]byte("Hello, World"), signature))
+}
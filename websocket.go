@@ -0,0 +1,750 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webSocketGUID is appended to the key of a handshake (RFC 6455).
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// webSocketStateKey holds the connection of a request.
+const webSocketStateKey = "jsonrpc.websocket"
+
+// WebSocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// WebSocket close codes.
+const (
+	wsNormalClosure   = 1000
+	wsGoingAway       = 1001
+	wsProtocolError   = 1002
+	wsMessageTooLarge = 1009
+)
+
+// WebSocketHandler is an http.Handler that upgrades each request to a
+// WebSocket and serves JSON-RPC over it, one message per request, response or
+// batch:
+//
+//     websockets := jsonrpc.NewWebSocketHandler(server)
+//     websockets.OnConnect = func(conn *jsonrpc.WebSocketConn) {
+//         subscribe(conn)
+//     }
+//     http.Handle("/ws", websockets)
+//
+// Requests on a connection are handled in order (see ServeFramer). The server
+// can send notifications at any time with WebSocketConn.Notify or Broadcast,
+// or call the methods of a client with WebSocketConn.Invoke, and a handler can
+// find its connection with WebSocketConnFromRequest. Pings are answered and
+// writes are serialized by the package.
+type WebSocketHandler struct {
+	Server *SimpleServer
+
+	// MaxMessageSize is the largest message in bytes. The connection is
+	// closed if it is exceeded. Zero uses DefaultMaxFrameSize.
+	MaxMessageSize int
+
+	// WriteTimeout is the longest a write may take before the connection is
+	// considered dead. Zero uses 10 seconds.
+	WriteTimeout time.Duration
+
+	// CheckOrigin decides if a browser on another origin may connect. If it
+	// is nil, only requests without an Origin or from the same host are
+	// accepted.
+	CheckOrigin func(r *http.Request) bool
+
+	// ClientExtractor puts the ClientInfo of the connection in the State of
+	// its requests. A ClientExtractor without trusted proxies is used if it
+	// is nil.
+	ClientExtractor *ClientExtractor
+
+	// OnConnect is called with every new connection before its first request
+	// is read. It may be nil.
+	OnConnect func(conn *WebSocketConn)
+
+	// ShutdownNotice, if it is not nil, is sent to every open connection by
+	// Shutdown before it stops reading requests.
+	ShutdownNotice *ShutdownNotice
+
+	mutex        sync.Mutex
+	conns        map[*WebSocketConn]struct{}
+	shuttingDown bool
+
+	// draining is closed by Shutdown, and serving counts the connections
+	// being served.
+	draining chan struct{}
+	serving  goroutineGroup
+}
+
+// NewWebSocketHandler creates a WebSocketHandler with the default options.
+func NewWebSocketHandler(server *SimpleServer) *WebSocketHandler {
+	return &WebSocketHandler{Server: server}
+}
+
+// ServeHTTP performs the handshake and serves the connection until it is
+// closed by either side.
+func (handler *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "Not a WebSocket handshake.", http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version.", http.StatusUpgradeRequired)
+		return
+	}
+
+	checkOrigin := handler.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "Origin not allowed.", http.StatusForbidden)
+		return
+	}
+
+	netConn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "Connection cannot be upgraded.", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + webSocketGUID))
+	_, err = io.WriteString(netConn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: "+base64.StdEncoding.EncodeToString(sum[:])+"\r\n\r\n")
+	if err != nil {
+		netConn.Close()
+		return
+	}
+
+	conn := &WebSocketConn{
+		conn:         netConn,
+		reader:       buffered.Reader,
+		maxSize:      handler.MaxMessageSize,
+		writeTimeout: handler.WriteTimeout,
+		handler:      handler,
+		done:         make(chan struct{}),
+	}
+	if conn.maxSize == 0 {
+		conn.maxSize = DefaultMaxFrameSize
+	}
+	if conn.writeTimeout == 0 {
+		conn.writeTimeout = 10 * time.Second
+	}
+
+	handler.mutex.Lock()
+	if handler.shuttingDown {
+		handler.mutex.Unlock()
+		conn.closeWith(wsGoingAway)
+		return
+	}
+	if handler.conns == nil {
+		handler.conns = map[*WebSocketConn]struct{}{}
+	}
+	handler.conns[conn] = struct{}{}
+	handler.serving.add()
+	handler.mutex.Unlock()
+
+	defer handler.serving.done()
+
+	if handler.OnConnect != nil {
+		handler.OnConnect(conn)
+	}
+
+	extractor := handler.ClientExtractor
+	if extractor == nil {
+		extractor = &ClientExtractor{}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	state := StateWithContext(withHeaderTrack(extractor.State(r), r), ctx)
+	state[webSocketStateKey] = conn
+
+	handler.Server.ServeFramer(&drainingFramer{conn, handler.drainingChan()}, state)
+	conn.closeWith(conn.closeCode())
+}
+
+// Broadcast sends a notification to every open connection. Connections that
+// fail to receive it are closed.
+func (handler *WebSocketHandler) Broadcast(method string, params interface{}) {
+	notification := NewRequestResponder("2.0", nil, method, params).Bytes()
+
+	for _, conn := range handler.connections() {
+		if conn.WriteFrame(notification) != nil {
+			conn.Close()
+		}
+	}
+}
+
+// Connections is the number of open connections.
+func (handler *WebSocketHandler) Connections() int {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	return len(handler.conns)
+}
+
+// Close closes every open connection with the "going away" status without
+// waiting for the requests being handled, see Shutdown. http.Server.Shutdown
+// does not close WebSockets because they have been taken over from the HTTP
+// server.
+func (handler *WebSocketHandler) Close() {
+	for _, conn := range handler.connections() {
+		conn.closeWith(wsGoingAway)
+	}
+}
+
+// Shutdown stops accepting connections and stops reading requests from the
+// open connections, after sending them the ShutdownNotice. The requests being
+// handled finish and their responses are sent before each connection is
+// closed with the "going away" status. If ctx is done first, the connections
+// are closed anyway and its error is returned.
+func (handler *WebSocketHandler) Shutdown(ctx context.Context) error {
+	handler.mutex.Lock()
+	handler.shuttingDown = true
+	framers := make([]Framer, 0, len(handler.conns))
+	for conn := range handler.conns {
+		framers = append(framers, conn)
+	}
+	handler.mutex.Unlock()
+
+	if err := handler.ShutdownNotice.send(ctx, framers); err != nil {
+		handler.Close()
+		return err
+	}
+
+	handler.mutex.Lock()
+	if handler.draining == nil {
+		handler.draining = make(chan struct{})
+	}
+	select {
+	case <-handler.draining:
+	default:
+		close(handler.draining)
+	}
+
+	// Interrupt the connections that are waiting for a request.
+	for conn := range handler.conns {
+		conn.setCode(wsGoingAway)
+		conn.conn.SetReadDeadline(time.Now())
+	}
+	handler.mutex.Unlock()
+
+	select {
+	case <-handler.serving.wait():
+		return nil
+
+	case <-ctx.Done():
+		handler.Close()
+		return ctx.Err()
+	}
+}
+
+func (handler *WebSocketHandler) drainingChan() <-chan struct{} {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	if handler.draining == nil {
+		handler.draining = make(chan struct{})
+	}
+
+	return handler.draining
+}
+
+func (handler *WebSocketHandler) connections() []*WebSocketConn {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	conns := make([]*WebSocketConn, 0, len(handler.conns))
+	for conn := range handler.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+// WebSocketConn is a single WebSocket connection, accepted by a
+// WebSocketHandler or made by DialWebSocket. It is a Framer, where each frame
+// is a whole WebSocket message, and a BatchInvoker that calls the methods of
+// the peer. It is safe for concurrent use.
+type WebSocketConn struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	maxSize      int
+	writeTimeout time.Duration
+	handler      *WebSocketHandler
+
+	// client masks the frames that are written, and expects the frames that
+	// are read not to be masked.
+	client bool
+
+	// pending holds the calls waiting for a response, see Invoke. It is nil
+	// until the first call on a connection of a WebSocketHandler.
+	callsMutex sync.Mutex
+	ids        SequentialIDGenerator
+	pending    map[string]chan Response
+
+	subscriptions Subscriptions
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+	done       chan struct{}
+
+	// code is the close code to use once reading has failed.
+	code int
+}
+
+// WebSocketConnFromRequest returns the connection that the request was
+// received on, or nil if it was not received on a WebSocket.
+func WebSocketConnFromRequest(request Request) *WebSocketConn {
+	conn, _ := request.State(webSocketStateKey).(*WebSocketConn)
+
+	return conn
+}
+
+// RemoteAddr is the network address of the peer.
+func (conn *WebSocketConn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+// Done returns a channel that is closed when the connection is closed.
+func (conn *WebSocketConn) Done() <-chan struct{} {
+	return conn.done
+}
+
+// Notify sends a notification to the peer.
+func (conn *WebSocketConn) Notify(method string, params interface{}) error {
+	return conn.WriteFrame(NewRequestResponder("2.0", nil, method, params).Bytes())
+}
+
+// Invoke calls a method of the peer and waits for its response, for ctx to be
+// done or for the connection to be closed. The RequestExtensions of ctx are
+// sent with the request.
+//
+// On a connection of a WebSocketHandler the response is read by the same loop
+// that reads the requests, which waits while a request is handled. Calling
+// Invoke from a handler of the same connection would wait for ever, so call
+// it from another goroutine, such as one started by OnConnect.
+func (conn *WebSocketConn) Invoke(ctx context.Context, method string,
+	params interface{}) (Response, error) {
+	responses, err := conn.call(ctx, []BatchCall{{Method: method, Params: params}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return responses[0], nil
+}
+
+// InvokeBatch calls methods of the peer as a single batch, see Invoke.
+func (conn *WebSocketConn) InvokeBatch(ctx context.Context,
+	calls []BatchCall) ([]Response, error) {
+	return conn.call(ctx, calls, true)
+}
+
+func (conn *WebSocketConn) call(ctx context.Context, calls []BatchCall,
+	batch bool) ([]Response, error) {
+	messages := make([]map[string]interface{}, len(calls))
+	keys := make([]string, len(calls))
+	channels := make([]chan Response, len(calls))
+
+	conn.callsMutex.Lock()
+	if conn.pending == nil {
+		conn.pending = map[string]chan Response{}
+	}
+	for i, call := range calls {
+		var id interface{}
+		if !call.Notification {
+			id = conn.ids.NextID()
+			keys[i] = idKey(id)
+			channels[i] = make(chan Response, 1)
+			conn.pending[keys[i]] = channels[i]
+		}
+
+		messages[i] = requestMessage(ctx, call.Method, call.Params, id)
+	}
+	conn.callsMutex.Unlock()
+
+	defer func() {
+		conn.callsMutex.Lock()
+		for i, key := range keys {
+			if channels[i] != nil {
+				delete(conn.pending, key)
+			}
+		}
+		conn.callsMutex.Unlock()
+	}()
+
+	var message interface{} = messages
+	if !batch {
+		message = messages[0]
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.WriteFrame(data); err != nil {
+		return nil, err
+	}
+
+	responses := make([]Response, len(calls))
+	for i, channel := range channels {
+		if channel == nil {
+			continue
+		}
+
+		select {
+		case responses[i] = <-channel:
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-conn.done:
+			return nil, errors.New("Connection is closed.")
+		}
+	}
+
+	return responses, nil
+}
+
+// deliver gives a message that is a response, or a batch of responses, to the
+// calls waiting for it, and an event to its subscription. It returns false if
+// the message has requests, or if no call has been made on the connection.
+func (conn *WebSocketConn) deliver(message []byte) bool {
+	if conn.subscriptions.deliverFrame(message) {
+		return true
+	}
+
+	conn.callsMutex.Lock()
+	calling := conn.pending != nil
+	conn.callsMutex.Unlock()
+
+	if !calling {
+		return false
+	}
+
+	type member struct {
+		Method *string `json:"method"`
+	}
+
+	var members []member
+	message = bytes.TrimSpace(message)
+	if message[0] == '[' {
+		if json.Unmarshal(message, &members) != nil || len(members) == 0 {
+			return false
+		}
+	} else {
+		members = make([]member, 1)
+		if json.Unmarshal(message, &members[0]) != nil {
+			return false
+		}
+	}
+
+	for _, member := range members {
+		if member.Method != nil {
+			return false
+		}
+	}
+
+	responses, err := NewResponsesFromJSON(message)
+	if err != nil {
+		return true
+	}
+
+	conn.callsMutex.Lock()
+	defer conn.callsMutex.Unlock()
+
+	for _, response := range responses {
+		select {
+		case conn.pending[idKey(response.ID())] <- response:
+		default:
+		}
+	}
+
+	return true
+}
+
+// Subscriptions returns the subscriptions of the connection, see Subscribe.
+func (conn *WebSocketConn) Subscriptions() *Subscriptions {
+	return &conn.subscriptions
+}
+
+// Close sends a normal close and closes the connection.
+func (conn *WebSocketConn) Close() error {
+	return conn.closeWith(wsNormalClosure)
+}
+
+func (conn *WebSocketConn) closeWith(code int) error {
+	err := net.ErrClosed
+	conn.closeOnce.Do(func() {
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		conn.writeMessage(wsClose, payload)
+
+		err = conn.conn.Close()
+		conn.subscriptions.closeAll(errors.New("Connection is closed."))
+		close(conn.done)
+
+		if conn.handler != nil {
+			conn.handler.mutex.Lock()
+			delete(conn.handler.conns, conn)
+			conn.handler.mutex.Unlock()
+		}
+	})
+
+	return err
+}
+
+func (conn *WebSocketConn) closeCode() int {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	if conn.code == 0 {
+		return wsNormalClosure
+	}
+
+	return conn.code
+}
+
+// WriteFrame sends the frame as a single text message.
+func (conn *WebSocketConn) WriteFrame(frame []byte) error {
+	return conn.writeMessage(wsText, frame)
+}
+
+func (conn *WebSocketConn) writeMessage(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	if conn.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+
+		header[1] |= 0x80
+		header = append(header, mask[:]...)
+
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	conn.conn.SetWriteDeadline(time.Now().Add(conn.writeTimeout))
+	_, err := (&net.Buffers{header, payload}).WriteTo(conn.conn)
+
+	return err
+}
+
+// ReadFrame returns the next text or binary message. Pings are answered while
+// waiting for it, as are the responses to calls made with Invoke.
+// io.EOF is returned once the peer has closed the connection.
+func (conn *WebSocketConn) ReadFrame() ([]byte, error) {
+	var message []byte
+	inMessage := false
+
+	for {
+		fin, opcode, payload, err := conn.readFrame(len(message))
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := conn.writeMessage(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+
+		case wsPong:
+			continue
+
+		case wsClose:
+			return nil, io.EOF
+
+		case wsText, wsBinary:
+			if inMessage {
+				return nil, conn.protocolError("Expected a continuation frame.")
+			}
+			inMessage = true
+
+		case wsContinuation:
+			if !inMessage {
+				return nil, conn.protocolError("Unexpected continuation frame.")
+			}
+
+		default:
+			return nil, conn.protocolError("Unknown opcode.")
+		}
+
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+
+		if !json.Valid(message) {
+			return nil, &FrameError{Message: "Message is not valid JSON.", Recovered: true}
+		}
+
+		if conn.deliver(message) {
+			message, inMessage = nil, false
+			continue
+		}
+
+		return message, nil
+	}
+}
+
+// readFrame reads a single frame. read is the size of the message so far,
+// which counts towards the limit.
+func (conn *WebSocketConn) readFrame(read int) (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(conn.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin, opcode := header[0]&0x80 != 0, header[0]&0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, conn.protocolError("Reserved bits must not be set.")
+	}
+	masked := header[1]&0x80 != 0
+	if !masked && !conn.client {
+		return false, 0, nil, conn.protocolError("Frames from a client must be masked.")
+	}
+	if masked && conn.client {
+		return false, 0, nil, conn.protocolError("Frames from a server must not be masked.")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(conn.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(conn.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	// Control frames are capped by the protocol and may arrive between the
+	// fragments of a message, so only data frames count towards the limit.
+	if opcode >= wsClose {
+		if length > 125 || !fin {
+			return false, 0, nil, conn.protocolError("Invalid control frame.")
+		}
+	} else if length > uint64(conn.maxSize-read) {
+		conn.setCode(wsMessageTooLarge)
+
+		return false, 0, nil, &FrameError{Message: "Message is too large."}
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(conn.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (conn *WebSocketConn) protocolError(message string) error {
+	conn.setCode(wsProtocolError)
+
+	return errors.New(message)
+}
+
+// setCode sets the close code to use once reading has failed.
+func (conn *WebSocketConn) setCode(code int) {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	conn.code = code
+}
+
+// headerContains returns true if the comma separated header contains token,
+// ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,210 @@
+package jsonrpc_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+// wsClient is a minimal WebSocket client that sends masked frames.
+type wsClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, url string) *wsClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+strings.TrimPrefix(url, "http://")+
+		"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", response.Header.Get("Sec-WebSocket-Accept"))
+
+	return &wsClient{t, conn, reader}
+}
+
+func (client *wsClient) send(opcode byte, fin bool, payload string) {
+	header := []byte{opcode, 0x80 | byte(len(payload))}
+	if fin {
+		header[0] |= 0x80
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	masked := []byte(payload)
+	for i := range masked {
+		masked[i] ^= mask[i%4]
+	}
+
+	client.conn.Write(append(append(header, mask...), masked...))
+}
+
+func (client *wsClient) receive() (byte, string) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(client.reader, header); err != nil {
+		client.t.Fatal(err)
+	}
+
+	length := int(header[1])
+	if length == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(client.reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+
+	payload := make([]byte, length)
+	io.ReadFull(client.reader, payload)
+
+	return header[0] & 0x0F, string(payload)
+}
+
+func newWebSocketServer(t *testing.T) (*jsonrpc.WebSocketHandler, string) {
+	handler := jsonrpc.NewWebSocketHandler(newTestServer())
+	mux := http.NewServeMux()
+	mux.Handle("/ws", handler)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		handler.Close()
+		server.Close()
+	})
+
+	return handler, server.URL
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	_, url := newWebSocketServer(t)
+	client := dialWebSocket(t, url)
+
+	client.send(0x1, true, `{"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}`)
+	opcode, message := client.receive()
+	assert.Equal(t, byte(0x1), opcode)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":19,"id":1}`, message)
+
+	// A fragmented batch.
+	client.send(0x1, false, `[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":2},`)
+	client.send(0x9, true, "ping")
+	client.send(0x0, true, `{"jsonrpc":"2.0","method":"notify_hello","params":[7]}]`)
+
+	opcode, message = client.receive()
+	assert.Equal(t, byte(0xA), opcode)
+	assert.Equal(t, "ping", message)
+
+	_, message = client.receive()
+	assert.JSONEq(t, `[{"jsonrpc":"2.0","result":3,"id":2}]`, message)
+
+	client.send(0x1, true, `{"jsonrpc"`)
+	_, message = client.receive()
+	assert.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Message is not valid JSON."},"id":null}`, message)
+
+	client.send(0x8, true, "\x03\xe8")
+	opcode, message = client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, "\x03\xe8", message)
+}
+
+func TestWebSocketHandler_Notify(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	connected := make(chan *jsonrpc.WebSocketConn, 1)
+	handler.OnConnect = func(conn *jsonrpc.WebSocketConn) {
+		connected <- conn
+	}
+
+	client := dialWebSocket(t, url)
+	conn := <-connected
+	assert.Equal(t, 1, handler.Connections())
+
+	assert.NoError(t, conn.Notify("tick", []int{1}))
+	_, message := client.receive()
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"tick","params":[1]}`, message)
+
+	handler.Broadcast("tick", []int{2})
+	_, message = client.receive()
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"tick","params":[2]}`, message)
+
+	handler.Close()
+	opcode, message := client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, "\x03\xe9", message)
+
+	<-conn.Done()
+	assert.Equal(t, 0, handler.Connections())
+}
+
+func TestWebSocketHandler_ProtocolErrors(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	handler.MaxMessageSize = 16
+
+	// Control frames do not count towards the limit.
+	client := dialWebSocket(t, url)
+	client.send(0x1, false, `[{"jsonrpc":`)
+	client.send(0x9, true, "a ping longer than the limit")
+	opcode, message := client.receive()
+	assert.Equal(t, byte(0xA), opcode)
+	assert.Equal(t, "a ping longer than the limit", message)
+
+	client.send(0x0, true, `"2.0","method":"sum"}]`)
+
+	_, message = client.receive()
+	assert.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Message is too large."},"id":null}`, message)
+
+	opcode, message = client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, "\x03\xf1", message)
+
+	// Frames from a client must be masked.
+	client = dialWebSocket(t, url)
+	client.conn.Write([]byte{0x81, 0x02, '{', '}'})
+
+	opcode, message = client.receive()
+	assert.Equal(t, byte(0x8), opcode)
+	assert.Equal(t, "\x03\xea", message)
+}
+
+func TestWebSocketHandler_Handshake(t *testing.T) {
+	_, url := newWebSocketServer(t)
+
+	status, _ := post(t, url+"/ws", "{}")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	for name, test := range map[string]struct {
+		headers map[string]string
+		status  int
+	}{
+		"version": {map[string]string{"Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+		"origin":  {map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+	} {
+		request, _ := http.NewRequest(http.MethodGet, url+"/ws", nil)
+		request.Header.Set("Upgrade", "websocket")
+		request.Header.Set("Connection", "Upgrade")
+		request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		request.Header.Set("Sec-WebSocket-Version", "13")
+		for key, value := range test.headers {
+			request.Header.Set(key, value)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		assert.NoError(t, err, name)
+		response.Body.Close()
+		assert.Equal(t, test.status, response.StatusCode, name)
+	}
+}
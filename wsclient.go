@@ -0,0 +1,211 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebSocketDialer connects to a WebSocket, such as one served by a
+// WebSocketHandler. Both peers can call the methods of the other on the
+// connection: the client with WebSocketConn.Invoke, and the server with the
+// Invoke of the connection it was given. The calls of the server are handled
+// by Server:
+//
+//     handlers := jsonrpc.NewSimpleServer()
+//     handlers.SetHandler("confirm", confirm)
+//
+//     dialer := &jsonrpc.WebSocketDialer{Server: handlers}
+//     conn, err := dialer.Dial(ctx, "wss://example.com/ws")
+//     if err != nil {
+//         return err
+//     }
+//     defer conn.Close()
+//
+//     var difference int
+//     err = jsonrpc.CallWith(ctx, conn, "subtract", []int{42, 23}, &difference)
+//
+// Unlike the requests read by a WebSocketHandler, the requests of the server
+// are handled concurrently, so a handler can call the server and wait for the
+// response.
+type WebSocketDialer struct {
+	// Server handles the requests and notifications sent by the server. If it
+	// is nil the requests are answered with MethodNotFound.
+	Server *SimpleServer
+
+	// Dialer makes the connection. If it is nil the connection is made through
+	// the proxy given by the environment (see ProxyURLFromEnvironment), or
+	// directly if there is none. TLS is started on the connection for a wss
+	// URL.
+	Dialer Dialer
+
+	// Header is added to the handshake, such as for authorization.
+	Header http.Header
+
+	// MaxMessageSize is the largest message in bytes. The connection is
+	// closed if it is exceeded. Zero uses DefaultMaxFrameSize.
+	MaxMessageSize int
+
+	// WriteTimeout is the longest a write may take before the connection is
+	// considered dead. Zero uses 10 seconds.
+	WriteTimeout time.Duration
+}
+
+// DialWebSocket connects to a WebSocket URL with the default options, see
+// WebSocketDialer. The server, which may be nil, handles the requests sent by
+// the peer.
+func DialWebSocket(ctx context.Context, rawURL string, server *SimpleServer) (*WebSocketConn, error) {
+	return (&WebSocketDialer{Server: server}).Dial(ctx, rawURL)
+}
+
+// Dial connects to a ws or wss URL and completes the handshake. The context
+// is used for both. The connection is served until it is closed.
+func (dialer *WebSocketDialer) Dial(ctx context.Context, rawURL string) (*WebSocketConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	netDialer := dialer.Dialer
+	if netDialer == nil {
+		netDialer = newProxyDialer(func(string) (*url.URL, error) {
+			return ProxyURLFromEnvironment(u)
+		}, nil)
+	}
+
+	address := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+
+	case "wss":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+		netDialer = NewTLSDialer(netDialer, nil)
+
+	default:
+		return nil, errors.New("URL must use the ws or wss scheme.")
+	}
+
+	netConn, err := netDialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := dialer.handshake(ctx, netConn, u)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	conn := &WebSocketConn{
+		conn:         netConn,
+		reader:       reader,
+		maxSize:      dialer.MaxMessageSize,
+		writeTimeout: dialer.WriteTimeout,
+		client:       true,
+		pending:      map[string]chan Response{},
+		done:         make(chan struct{}),
+	}
+	if conn.maxSize == 0 {
+		conn.maxSize = DefaultMaxFrameSize
+	}
+	if conn.writeTimeout == 0 {
+		conn.writeTimeout = 10 * time.Second
+	}
+
+	server := dialer.Server
+	if server == nil {
+		server = NewSimpleServer()
+	}
+
+	go conn.serve(server)
+
+	return conn, nil
+}
+
+// handshake upgrades the connection and returns the reader of the frames that
+// follow the response.
+func (dialer *WebSocketDialer) handshake(ctx context.Context, netConn net.Conn,
+	u *url.URL) (*bufio.Reader, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+		defer netConn.SetDeadline(time.Time{})
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://"+u.Host+u.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range dialer.Header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := request.Write(netConn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return nil, &HTTPStatusError{StatusCode: response.StatusCode, Status: response.Status}
+	}
+
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	if response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, errors.New("Server did not accept the WebSocket handshake.")
+	}
+
+	return reader, nil
+}
+
+// serve handles every request from the peer, each in its own goroutine, until
+// the connection is closed.
+func (conn *WebSocketConn) serve(server *SimpleServer) {
+	for {
+		frame, err := conn.ReadFrame()
+		if err != nil {
+			if frameErr, ok := err.(*FrameError); ok && frameErr.Recovered {
+				continue
+			}
+
+			conn.closeWith(conn.closeCode())
+
+			return
+		}
+
+		go func() {
+			responses := server.HandleWithState(frame, State{webSocketStateKey: conn})
+			if len(responses) > 0 {
+				conn.WriteFrame(responseFrame(frame, responses))
+			}
+		}()
+	}
+}
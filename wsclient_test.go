@@ -0,0 +1,125 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thiagozs/jsonrpc"
+)
+
+func TestDialWebSocket(t *testing.T) {
+	handler, url := newWebSocketServer(t)
+	connected := make(chan *jsonrpc.WebSocketConn, 1)
+	handler.OnConnect = func(conn *jsonrpc.WebSocketConn) {
+		connected <- conn
+	}
+
+	local := jsonrpc.NewSimpleServer()
+	notified := make(chan string, 1)
+	local.SetHandler("confirm", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		// A handler of the client can call the server while it runs.
+		conn := jsonrpc.WebSocketConnFromRequest(request)
+		var difference int
+		err := jsonrpc.CallWith(context.Background(), conn, "subtract", []int{10, 3}, &difference)
+		if err != nil {
+			return request.NewServerErrorResponse(err)
+		}
+
+		return request.NewSuccessResponse(difference)
+	})
+	local.SetHandler("tick", func(request jsonrpc.RequestResponder) jsonrpc.Response {
+		notified <- request.Method()
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := jsonrpc.DialWebSocket(ctx, strings.Replace(url, "http", "ws", 1)+"/ws", local)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+	server := <-connected
+
+	var difference int
+	assert.NoError(t, jsonrpc.CallWith(ctx, client, "subtract", []int{42, 23}, &difference))
+	assert.Equal(t, 19, difference)
+
+	responses, err := client.InvokeBatch(ctx, []jsonrpc.BatchCall{
+		{Method: "sum", Params: []int{1, 2}},
+		{Method: "notify_hello", Params: []int{7}, Notification: true},
+		{Method: "sum", Params: []int{3, 4}},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, float64(3), responses[0].Result())
+		assert.Nil(t, responses[1])
+		assert.Equal(t, float64(7), responses[2].Result())
+	}
+
+	// The server calls the client, whose handler calls the server.
+	var confirmed int
+	assert.NoError(t, jsonrpc.CallWith(ctx, server, "confirm", nil, &confirmed))
+	assert.Equal(t, 7, confirmed)
+
+	assert.NoError(t, server.Notify("tick", nil))
+	assert.Equal(t, "tick", <-notified)
+
+	// Methods the client does not have.
+	response, err := server.Invoke(ctx, "missing", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonrpc.MethodNotFound, response.ErrorCode())
+
+	client.Close()
+	<-server.Done()
+	_, err = client.Invoke(ctx, "subtract", []int{1, 1})
+	assert.Error(t, err)
+}
+
+func TestDialWebSocket_Errors(t *testing.T) {
+	_, url := newWebSocketServer(t)
+	ctx := context.Background()
+
+	_, err := jsonrpc.DialWebSocket(ctx, url+"/ws", nil)
+	assert.EqualError(t, err, "URL must use the ws or wss scheme.")
+
+	_, err = jsonrpc.DialWebSocket(ctx, strings.Replace(url, "http", "ws", 1)+"/missing", nil)
+	statusErr, ok := err.(*jsonrpc.HTTPStatusError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	}
+}
+
+func TestDialWebSocket_Proxy(t *testing.T) {
+	_, url := newWebSocketServer(t)
+	url = strings.Replace(url, "http", "ws", 1) + "/ws"
+	ctx := context.Background()
+
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("ALL_PROXY", "")
+	t.Setenv("all_proxy", "")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	t.Setenv("HTTP_PROXY", "http://"+newHTTPProxy(t, ""))
+	conn, err := jsonrpc.DialWebSocket(ctx, url, nil)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+
+	// A proxy that does not exist is used for ws URLs, unless the host is in
+	// NO_PROXY.
+	t.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+	_, err = jsonrpc.DialWebSocket(ctx, url, nil)
+	assert.Error(t, err)
+
+	t.Setenv("NO_PROXY", "127.0.0.0/8")
+	conn, err = jsonrpc.DialWebSocket(ctx, url, nil)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}